@@ -2,20 +2,28 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
 	"encoding/base64"
 	"fmt"
 	"haya-tab/pkg/coverpool"
+	"haya-tab/pkg/enrich"
+	"haya-tab/pkg/jobpool"
+	"haya-tab/pkg/locale"
 	"haya-tab/pkg/logger"
 	"haya-tab/pkg/metadata"
+	"haya-tab/pkg/pedal"
+	"haya-tab/pkg/storage"
 	"haya-tab/pkg/store"
 	syncpkg "haya-tab/pkg/sync"
 	"haya-tab/pkg/watcher"
-	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
@@ -28,6 +36,12 @@ type TabsResponse struct {
 	Page     int         `json:"page"`
 	PageSize int         `json:"pageSize"`
 	HasMore  bool        `json:"hasMore"`
+
+	// A search finding nothing suggests DidYouMean, the query with its
+	// misspelled words corrected. With the fuzzySearch setting the tabs are
+	// those of DidYouMean and Corrected is set.
+	DidYouMean string `json:"didYouMean,omitempty"`
+	Corrected  bool   `json:"corrected,omitempty"`
 }
 
 // getAppDir returns the directory where the executable is located
@@ -70,15 +84,60 @@ type App struct {
 	ctx            context.Context
 	store          *store.DBStore
 	fileWatcher    *watcher.FileWatcher
+	inboxWatcher   *watcher.FileWatcher
+	pedals         *pedal.Listener
+	pedalLearn     atomic.Pointer[pedalLearning]
 	logger         *logger.Logger
 	fileServerPort int
+	serverToken    string // Required by the file server, see StartFileServer
 	coverPool      *coverpool.CoverPool
+	jobPool        *jobpool.Pool
 	syncService    *syncpkg.SyncService
+	storage        storage.Backend // Files of managed tabs
+	schedulerStop  chan struct{}
+	autoSyncPaused atomic.Bool
+	serverMetrics  serverMetrics
+	accessLog      accessLog
+	locale         atomic.Pointer[locale.Formatter]
+	collages       sync.Map // Collage paths being composed or that failed, see composeCollage
+
+	// Backups to the cloud, see BackupToCloud
+	cloudBusy        atomic.Bool
+	cloudLastAttempt atomic.Int64 // Unix timestamp
+
+	maintenanceBusy atomic.Bool // See MaintainDatabase
+
+	// Library attached read-only, see applySharedLibrary
+	sharedMu sync.RWMutex
+	shared   sharedLibrary
+
+	// Read-only file server on the LAN, see applyLANServer
+	lanMu sync.Mutex
+	lan   lanServer
+
+	// Metadata enrichment provider, see applyEnrichment
+	enrichMu     sync.Mutex
+	enricher     enrich.Provider
+	enrichConfig enrich.Config
+
+	// Pages the frontend renders for PDF exports, by request, see
+	// ExportTabAsPDF
+	pdfRendersMu sync.Mutex
+	pdfRenders   map[string]chan pdfRenderResult
+
+	// Files to open once the frontend is ready, see openFile
+	openFilesMu      sync.Mutex
+	openFilesReady   bool
+	pendingOpenFiles []string
 }
 
+// syncSchedulerTick is how often the scheduler checks whether a sync or
+// another scheduled task, such as a practice reminder, is due
+const syncSchedulerTick = time.Minute
+
 // NewApp creates a new App application struct
 func NewApp() *App {
-	return &App{}
+	return &App{serverToken: rand.Text()}
 }
 
 // SetFileServerPort sets the port of the local file server
@@ -91,6 +150,12 @@ func (a *App) GetFileServerPort() int {
 	return a.fileServerPort
 }
 
+// GetFileServerToken returns the token file server requests need, new at
+// each start of the app
+func (a *App) GetFileServerToken() string {
+	return a.serverToken
+}
+
 // startup is called when the app starts. The context is saved
 // so we can call the runtime methods
 func (a *App) startup(ctx context.Context) {
@@ -107,6 +172,7 @@ func (a *App) startup(ctx context.Context) {
 		filepath.Join(appDir, "data"),
 		filepath.Join(appDir, "storage"),
 		filepath.Join(appDir, "covers"),
+		inboxDir(),
 	}
 	for _, dir := range requiredDirs {
 		if err := os.MkdirAll(dir, 0755); err != nil {
@@ -116,15 +182,25 @@ func (a *App) startup(ctx context.Context) {
 		}
 	}
 
+	// Remove the partial copies of a crash during a copy to storage
+	if n := syncpkg.RemoveStaleCopies(filepath.Join(appDir, "storage")); n > 0 {
+		a.logger.Info("Removed %d interrupted copies from storage", n)
+	}
+	a.storage = storage.NewLocal(filepath.Join(appDir, "storage"))
+
 	dbPath := filepath.Join(appDir, "data", "haya-tab.db")
 	jsonPath := filepath.Join(appDir, "data", "tabs.json")
 	a.logger.Info("Database path: %s", dbPath)
+	restored := a.applyCloudRestore(dbPath)
 
 	a.store = store.NewDBStore(dbPath)
 	if err := a.store.Initialize(); err != nil {
 		a.logger.Error("Error initializing database: %v", err)
 		return
 	}
+	if restored != nil {
+		a.relocateRestoredFiles(restored)
+	}
 
 	// Migrate from JSON if database is empty and JSON exists
 	if !a.store.HasData() {
@@ -133,15 +209,36 @@ func (a *App) startup(ctx context.Context) {
 		}
 	}
 
+	a.applyCoverRegions()
+	a.applyNetwork()
+	a.applyAccessLog()
+	a.applyLocale()
+	a.applyCacheLimit()
+	a.applyLANServer()
+	a.applySharedLibrary()
+
+	// Read foot controllers when enabled in the settings
+	a.pedals = pedal.NewListener(a.handlePedalPress)
+	a.pedals.SetLogger(a.logger)
+	a.applyPedals()
+
 	// Initialize cover download worker pool (3 concurrent downloads max)
 	a.coverPool = coverpool.NewCoverPool(3, metadata.DownloadCover)
 	a.coverPool.Start()
 	a.logger.Info("Cover download pool started with 3 workers")
+	a.applyCoverEncoding()
+	a.applyOfflineMode()
+	a.applyEnrichment()
+
+	// Initialize background job pool (hash backfill, ...)
+	a.jobPool = jobpool.NewPool(2)
+	a.jobPool.Start()
 
 	// Initialize SyncService
 	emitter := &WailsEventEmitter{ctx: a.ctx}
-	a.syncService = syncpkg.NewSyncService(a.store, a.logger, a.coverPool, emitter, appDir)
+	a.syncService = syncpkg.NewSyncService(a.store, a.storage, a.logger, a.coverPool, a.jobPool, emitter, appDir)
 	a.logger.Info("SyncService initialized")
+	a.writeServerInfo()
 
 	// Auto Sync Logic
 	go func() {
@@ -174,49 +271,78 @@ func (a *App) startup(ctx context.Context) {
 			if lastSync.Year() != now.Year() {
 				shouldSync = true
 			}
+		case "interval":
+			// Handled by the sync scheduler
 		default: // Fallback
 			shouldSync = true
 		}
 
 		if shouldSync {
 			a.logger.Info("Auto-sync triggered due to schedule.")
-			a.TriggerSync()
+			if _, err := a.TriggerSync(); err != nil {
+				a.logger.Info("Auto-sync failed: %v", err)
+			}
 		}
 	}()
 
+	a.startSyncScheduler()
+
+	// Compute scripts, file sizes, cover colors, file hashes, track lists and
+	// contents missing from existing libraries, then resume an interrupted cover sweep
+	go func() {
+		time.Sleep(5 * time.Second)
+		a.syncService.BackfillScripts()
+		a.syncService.BackfillFileSizes()
+		a.syncService.BackfillCoverColors()
+		a.syncService.BackfillHashes()
+		a.syncService.BackfillTracks()
+		a.syncService.BackfillContent()
+		a.syncService.ResumeCoverSweep()
+	}()
+
+	a.startInbox()
+
 	// Initialize file watcher if sync paths are configured
-	settings := a.store.GetSettings()
-	if len(settings.SyncPaths) > 0 {
-		a.fileWatcher = watcher.NewFileWatcher(func() {
-			// Emit event to frontend when changes detected
-			wailsRuntime.EventsEmit(a.ctx, "file-changes-detected", "Files have changed in sync directories")
-		})
-		a.fileWatcher.SetLogger(a.logger)
-
-		if err := a.fileWatcher.Start(); err != nil {
-			a.logger.Error("Failed to start file watcher: %v", err)
-		} else {
-			// Add all sync paths to watcher
-			for _, path := range settings.SyncPaths {
-				if err := a.fileWatcher.AddPath(path); err != nil {
-					a.logger.Error("Failed to watch path %s: %v", path, err)
-				}
-			}
-		}
-	}
+	a.applySyncPaths()
 }
 
 // shutdown is called when the app is closing
 func (a *App) shutdown(ctx context.Context) {
+	// The command line mode works on the database once the app is closed
+	os.Remove(serverInfoPath())
+
+	// Stop sync scheduler
+	if a.schedulerStop != nil {
+		close(a.schedulerStop)
+	}
+
 	// Stop cover download pool
 	if a.coverPool != nil {
 		a.coverPool.Stop()
 	}
 
-	// Stop file watcher
+	// Stop background job pool
+	if a.jobPool != nil {
+		a.jobPool.Stop()
+	}
+
+	a.stopLANServer()
+	a.closeSharedLibrary()
+
+	// Stop file watchers
 	if a.fileWatcher != nil {
 		a.fileWatcher.Stop()
 	}
+	if a.inboxWatcher != nil {
+		a.inboxWatcher.Stop()
+	}
+
+	// Stop reading foot controllers
+	if a.pedals != nil {
+		a.pedals.Stop()
+	}
+
+	a.accessLog.Close()
 
 	if a.store != nil {
 		a.store.Close()
@@ -232,61 +358,348 @@ func (a *App) GetSettings() store.Settings {
 	return a.store.GetSettings()
 }
 
-// SaveSettings updates the settings
+// GetSetting returns a single setting by key, e.g. "theme" or
+// "keyBindings.playPause"
+func (a *App) GetSetting(key string) (interface{}, error) {
+	return a.store.GetSetting(key)
+}
+
+// SetSetting saves a single setting by key. Unlike SaveSettings it does not
+// update the file watcher, so use SaveSettings for sync paths.
+func (a *App) SetSetting(key string, value interface{}) error {
+	if err := a.store.SetSetting(key, value); err != nil {
+		return err
+	}
+	a.applyCoverRegions()
+	a.applyCoverEncoding()
+	a.applyNetwork()
+	a.applyAccessLog()
+	a.applyLocale()
+	a.applyPedals()
+	a.applyCacheLimit()
+	a.applyOfflineMode()
+	a.applyEnrichment()
+	a.applyLANServer()
+	a.applySharedLibrary()
+	return nil
+}
+
+// GetKeyActions returns the actions that can be bound to keys, with their
+// labels and default keys
+func (a *App) GetKeyActions() []store.KeyAction {
+	return store.KeyActions()
+}
+
+// ResetKeyBindings restores the default key bindings and returns them
+func (a *App) ResetKeyBindings() (store.KeyBindings, error) {
+	settings := a.store.GetSettings()
+	settings.KeyBindings = store.DefaultKeyBindings()
+	if err := a.store.UpdateSettings(settings); err != nil {
+		return nil, err
+	}
+	return settings.KeyBindings, nil
+}
+
+// SaveSettings updates the settings. Changed key bindings with conflicts
+// are rejected with a *store.KeyBindingsError.
 func (a *App) SaveSettings(s store.Settings) error {
-	// Update file watcher paths if they changed
-	oldSettings := a.store.GetSettings()
 	if err := a.store.UpdateSettings(s); err != nil {
 		return err
 	}
+	a.applyCoverRegions()
+	a.applyCoverEncoding()
+	a.applyNetwork()
+	a.applyAccessLog()
+	a.applyLocale()
+	a.applyPedals()
+	a.applyCacheLimit()
+	a.applyOfflineMode()
+	a.applyEnrichment()
+	a.applyLANServer()
+	a.applySharedLibrary()
+	return nil
+}
 
-	// Update file watcher if sync paths changed
-	if len(s.SyncPaths) > 0 {
-		if a.fileWatcher == nil {
-			// Create new watcher
-			a.fileWatcher = watcher.NewFileWatcher(func() {
-				wailsRuntime.EventsEmit(a.ctx, "file-changes-detected", "Files have changed in sync directories")
-			})
-			a.fileWatcher.SetLogger(a.logger)
-
-			if err := a.fileWatcher.Start(); err != nil {
-				a.logger.Error("Failed to start file watcher: %v", err)
-			}
+// handleFileChanges ingests the files changed in sync directories
+func (a *App) handleFileChanges(changes []watcher.Change) {
+	var changed, removed []string
+	for _, c := range changes {
+		if c.Removed {
+			removed = append(removed, c.Path)
+		} else {
+			changed = append(changed, c.Path)
 		}
+	}
+	a.syncService.ProcessChanges(changed, removed)
+}
 
-		// Update watched paths
-		if a.fileWatcher != nil && a.fileWatcher.IsRunning() {
-			if err := a.fileWatcher.SetPaths(s.SyncPaths); err != nil {
-				a.logger.Error("Failed to update watcher paths: %v", err)
-			}
-		}
-	} else if a.fileWatcher != nil {
-		// No sync paths, stop watcher
-		a.fileWatcher.Stop()
-		a.fileWatcher = nil
+// TriggerSync snapshots the database and delegates to SyncService for file
+// synchronization
+func (a *App) TriggerSync() (string, error) {
+	// A sync changes many tabs at once; keep the library as it was before
+	if _, err := a.takeDbSnapshot("sync"); err != nil {
+		a.logger.Error("Failed to snapshot the database before syncing: %v", err)
+	}
+	result, err := a.syncService.TriggerSync()
+	if err == nil {
+		// Hash and index the newly added files in the background
+		go a.syncService.BackfillHashes()
+		go a.syncService.BackfillContent()
 	}
+	return result, err
+}
 
-	// Check if paths changed to emit notification
-	pathsChanged := len(oldSettings.SyncPaths) != len(s.SyncPaths)
-	if !pathsChanged {
-		for i := range oldSettings.SyncPaths {
-			if oldSettings.SyncPaths[i] != s.SyncPaths[i] {
-				pathsChanged = true
-				break
+// TriggerSyncPreview scans the sync paths without changing the library and
+// returns the files a sync would add, skip or restore, so a large import can
+// be confirmed first
+func (a *App) TriggerSyncPreview() (syncpkg.SyncPreview, error) {
+	return a.syncService.PreviewSync()
+}
+
+// GetSyncHistory returns the latest limit full syncs, newest first, with
+// their counts and the files they failed on
+func (a *App) GetSyncHistory(limit int) ([]store.SyncRun, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	return a.store.GetSyncHistory(limit)
+}
+
+// GetPendingConflicts returns the title conflicts of the "ask" sync strategy
+// waiting for the user, oldest first
+func (a *App) GetPendingConflicts() ([]store.PendingConflict, error) {
+	return a.store.GetPendingConflicts()
+}
+
+// ResolveConflict resolves a pending conflict: "merge" into the tab holding
+// the title, "rename" to add the file as title, or "skip"
+func (a *App) ResolveConflict(path, action, title string) error {
+	return a.syncService.ResolveConflict(path, action, title)
+}
+
+// GetConflictReports returns the reports of the new files syncs skipped or
+// retitled for their title, newest first
+func (a *App) GetConflictReports() []syncpkg.ConflictReport {
+	return a.syncService.ConflictReports()
+}
+
+// GetConflictReport returns the conflicts listed in a report
+func (a *App) GetConflictReport(name string) ([]syncpkg.Conflict, error) {
+	return a.syncService.ReadConflictReport(name)
+}
+
+// OpenConflictReport opens a conflict report with the default application
+func (a *App) OpenConflictReport(name string) error {
+	path, err := a.syncService.ConflictReportPath(name)
+	if err != nil {
+		return err
+	}
+	return openWithSystem(path)
+}
+
+// startSyncScheduler runs syncs in the background when AutoSyncFrequency is
+// "interval", the cover bootstrap, the cover refresh and the metadata
+// enrichment. Settings are read on every tick, so changes apply immediately.
+func (a *App) startSyncScheduler() {
+	a.schedulerStop = make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(syncSchedulerTick)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-a.schedulerStop:
+				return
+			case <-ticker.C:
+				a.runScheduledSync()
+				a.runCoverBootstrap()
+				a.runCoverRetry()
+				a.runCoverRefresh()
+				a.runEnrichment()
+				a.runCloudBackup()
+				a.runMaintenance()
+				a.runDbSnapshot()
+				a.runReminders()
 			}
 		}
+	}()
+}
+
+// runScheduledSync syncs if the configured interval has elapsed since the last sync
+func (a *App) runScheduledSync() {
+	if a.autoSyncPaused.Load() {
+		return
+	}
+
+	settings := a.store.GetSettings()
+	if !settings.AutoSyncEnabled || settings.AutoSyncFrequency != "interval" || settings.AutoSyncInterval <= 0 {
+		return
+	}
+	if paths, err := a.store.GetSyncPaths(); err != nil || len(paths) == 0 {
+		return
 	}
 
-	if pathsChanged && len(s.SyncPaths) > 0 {
-		a.logger.Info("File watcher updated with %d paths", len(s.SyncPaths))
+	interval := time.Duration(settings.AutoSyncInterval) * time.Hour
+	if time.Since(time.Unix(settings.LastSyncTime, 0)) < interval {
+		return
 	}
 
-	return nil
+	a.logger.Info("Scheduled sync triggered (every %d hours)", settings.AutoSyncInterval)
+	wailsRuntime.EventsEmit(a.ctx, "sync-scheduled", map[string]interface{}{
+		"interval": settings.AutoSyncInterval,
+	})
+	if _, err := a.TriggerSync(); err != nil {
+		a.logger.Info("Scheduled sync failed: %v", err)
+	}
 }
 
-// TriggerSync delegates to SyncService for file synchronization
-func (a *App) TriggerSync() (string, error) {
-	return a.syncService.TriggerSync()
+// PauseAutoSync stops scheduled syncs until ResumeAutoSync is called
+func (a *App) PauseAutoSync() {
+	a.autoSyncPaused.Store(true)
+	a.logger.Info("Scheduled sync paused")
+}
+
+// ResumeAutoSync re-enables scheduled syncs
+func (a *App) ResumeAutoSync() {
+	a.autoSyncPaused.Store(false)
+	a.logger.Info("Scheduled sync resumed")
+}
+
+// IsAutoSyncPaused reports whether scheduled syncs are paused
+func (a *App) IsAutoSyncPaused() bool {
+	return a.autoSyncPaused.Load()
+}
+
+// runCoverBootstrap fetches some of the covers queued by a large sync,
+// unless background jobs are paused
+func (a *App) runCoverBootstrap() {
+	if a.jobPool.IsPaused() {
+		return
+	}
+	a.syncService.RunCoverBootstrap()
+}
+
+// runCoverRetry downloads again some covers that failed on a network
+// error, unless background jobs are paused
+func (a *App) runCoverRetry() {
+	if a.jobPool.IsPaused() {
+		return
+	}
+	a.syncService.RetryCovers()
+}
+
+// runCoverRefresh re-fetches some stale covers when CoverRefreshMonths is
+// set, unless background jobs are paused
+func (a *App) runCoverRefresh() {
+	months := a.store.GetSettings().CoverRefreshMonths
+	if months <= 0 || a.jobPool.IsPaused() {
+		return
+	}
+	a.syncService.RefreshStaleCovers(months)
+}
+
+// GetCoverBootstrapStatus returns the progress of fetching the covers of a
+// large sync over days
+func (a *App) GetCoverBootstrapStatus() syncpkg.CoverBootstrapStatus {
+	return a.syncService.CoverBootstrapStatus()
+}
+
+// RetryFailedCovers downloads again the covers that failed on a network
+// error, including those given up after repeated failures. Returns their
+// number.
+func (a *App) RetryFailedCovers() (int, error) {
+	return a.syncService.RetryFailedCovers()
+}
+
+// FetchMissingCovers searches the covers of every tab having an artist but
+// no cover, e.g. for a library synced before covers were fetched. Progress
+// is emitted as "cover-sweep-progress"; an interrupted sweep resumes at the
+// next start.
+func (a *App) FetchMissingCovers() (syncpkg.CoverSweepStatus, error) {
+	return a.syncService.FetchMissingCovers()
+}
+
+// GetCoverSweepStatus returns the progress of FetchMissingCovers
+func (a *App) GetCoverSweepStatus() syncpkg.CoverSweepStatus {
+	return a.syncService.CoverSweepStatus()
+}
+
+// PauseBackgroundJobs pauses the background job pool, which runs the hash
+// and track backfills. Jobs already running are finished.
+func (a *App) PauseBackgroundJobs() {
+	a.jobPool.Pause()
+	a.logger.Info("Background jobs paused")
+}
+
+// ResumeBackgroundJobs resumes the background job pool
+func (a *App) ResumeBackgroundJobs() {
+	a.jobPool.Resume()
+	a.logger.Info("Background jobs resumed")
+}
+
+// IsBackgroundJobsPaused reports whether the background job pool is paused
+func (a *App) IsBackgroundJobsPaused() bool {
+	return a.jobPool.IsPaused()
+}
+
+// GetDatabaseMetrics returns how often writes found the database locked
+// since startup
+func (a *App) GetDatabaseMetrics() store.Metrics {
+	return a.store.Metrics()
+}
+
+// applyCoverRegions passes the configured cover search regions to the
+// cover provider
+func (a *App) applyCoverRegions() {
+	var regions []metadata.CoverRegion
+	for _, r := range a.store.GetSettings().CoverRegions {
+		regions = append(regions, metadata.CoverRegion{Country: r.Country, Lang: r.Lang})
+	}
+	metadata.SetCoverRegions(regions)
+}
+
+// applyCoverEncoding passes the configured size and quality of covers to
+// the cover download pool
+func (a *App) applyCoverEncoding() {
+	if a.coverPool == nil {
+		return
+	}
+	s := a.store.GetSettings()
+	a.coverPool.SetEncoding(coverpool.Encoding{MaxSize: s.CoverMaxSize, Quality: s.CoverJPEGQuality})
+}
+
+// applyNetwork configures the proxy, timeout and TLS settings of metadata
+// requests. Invalid settings keep the previous ones.
+func (a *App) applyNetwork() {
+	s := a.store.GetSettings()
+	err := metadata.SetNetworkConfig(metadata.NetworkConfig{
+		ProxyURL:       s.HTTPProxy,
+		TimeoutSeconds: s.HTTPTimeout,
+		CAFile:         s.TLSCAFile,
+		SkipTLSVerify:  s.TLSSkipVerify,
+	})
+	if err != nil {
+		a.logger.Error("Failed to apply the network settings: %v", err)
+	}
+}
+
+// applyLocale sets the formatter for dates and numbers in reports and
+// exports according to the settings
+func (a *App) applyLocale() {
+	a.locale.Store(locale.New(a.store.GetSettings().Locale))
+}
+
+// formatter returns the formatter of the configured locale
+func (a *App) formatter() *locale.Formatter {
+	if f := a.locale.Load(); f != nil {
+		return f
+	}
+	return locale.New("")
+}
+
+// GetCoverRegionHealth returns the timeout history of the cover search
+// regions; regions with a SkipUntil in the future are currently skipped
+func (a *App) GetCoverRegionHealth() []metadata.RegionStatus {
+	return metadata.CoverRegionHealth()
 }
 
 // fetchCoverAsync delegates to SyncService for async cover download
@@ -305,7 +718,7 @@ func (a *App) GetTabs() []store.Tab {
 }
 
 // GetTabsPaginated returns a paginated list of tabs with optional search
-func (a *App) GetTabsPaginated(categoryId string, page, pageSize int, searchQuery string, filterBy []string, isGlobal bool, sortBy string, sortDesc bool) TabsResponse {
+func (a *App) GetTabsPaginated(categoryId string, page, pageSize int, searchQuery string, filterBy []string, isGlobal bool, sortBy string, sortDesc bool, filters store.TabFilters) TabsResponse {
 	if page < 1 {
 		page = 1
 	}
@@ -318,7 +731,16 @@ func (a *App) GetTabsPaginated(categoryId string, page, pageSize int, searchQuer
 	}
 	searchQuery = strings.ToLower(strings.TrimSpace(searchQuery))
 
-	tabs, total, err := a.store.GetTabsPaginated(categoryId, page, pageSize, searchQuery, filterBy, isGlobal, sortBy, sortDesc)
+	tabs, total, err := a.getTabsPage(categoryId, page, pageSize, searchQuery, filterBy, isGlobal, sortBy, sortDesc, filters)
+	var didYouMean string
+	corrected := false
+	if err == nil && total == 0 && searchQuery != "" {
+		didYouMean, err = a.store.SuggestCorrection(searchQuery, filterBy)
+		if err == nil && didYouMean != "" && a.store.GetSettings().FuzzySearch {
+			tabs, total, err = a.getTabsPage(categoryId, page, pageSize, didYouMean, filterBy, isGlobal, sortBy, sortDesc, filters)
+			corrected = true
+		}
+	}
 	if err != nil {
 		a.logger.Error("Error getting paginated tabs: %v", err)
 		return TabsResponse{
@@ -331,12 +753,25 @@ func (a *App) GetTabsPaginated(categoryId string, page, pageSize int, searchQuer
 	}
 
 	return TabsResponse{
-		Tabs:     tabs,
-		Total:    total,
-		Page:     page,
-		PageSize: pageSize,
-		HasMore:  (page * pageSize) < total,
+		Tabs:       tabs,
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+		HasMore:    (page * pageSize) < total,
+		DidYouMean: didYouMean,
+		Corrected:  corrected,
+	}
+}
+
+// GetTabsByTrack returns tabs containing a part of the given kind (e.g. "bass")
+// and/or string count (e.g. 7 for 7-string guitar)
+func (a *App) GetTabsByTrack(kind string, stringCount int) []store.Tab {
+	tabs, err := a.store.GetTabsByTrack(kind, stringCount)
+	if err != nil {
+		a.logger.Error("Error getting tabs by track: %v", err)
+		return []store.Tab{}
 	}
+	return tabs
 }
 
 // ProcessFile delegates to SyncService for file processing
@@ -351,9 +786,55 @@ func (a *App) GetCategories() []store.Category {
 		a.logger.Error("Error getting categories: %v", err)
 		return []store.Category{}
 	}
+	a.applyCategoryCollages(categories)
 	return categories
 }
 
+// GetCategoryStats returns the tab counts, sizes and latest activity of
+// the categories with tabs
+func (a *App) GetCategoryStats() []store.CategoryStats {
+	stats, err := a.store.GetCategoryStats()
+	if err != nil {
+		a.logger.Error("Error getting category stats: %v", err)
+		return []store.CategoryStats{}
+	}
+	f := a.formatter()
+	for i := range stats {
+		stats[i].TotalSize = f.Bytes(stats[i].TotalBytes)
+	}
+	return stats
+}
+
+// GetLibraryStats returns the statistics of the whole library
+func (a *App) GetLibraryStats() (store.LibraryStats, error) {
+	now := time.Now()
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	stats, err := a.store.GetLibraryStats(monthStart.Unix())
+	if err != nil {
+		return stats, fmt.Errorf("failed to compute library stats: %w", err)
+	}
+	f := a.formatter()
+	stats.ManagedSize = f.Bytes(stats.ManagedBytes)
+	stats.LinkedSize = f.Bytes(stats.LinkedBytes)
+	return stats, nil
+}
+
+// GetGenres returns the genres of the library with their number of tabs,
+// most tabs first
+func (a *App) GetGenres() ([]store.GenreCount, error) {
+	return a.store.GetGenres()
+}
+
+// searchSuggestionLimit is how many suggestions GetSearchSuggestions returns
+const searchSuggestionLimit = 8
+
+// GetSearchSuggestions returns the titles or artists (field "title" or
+// "artist", both if empty) completing prefix, best first, for the search
+// box as the user types
+func (a *App) GetSearchSuggestions(prefix, field string) ([]store.SearchSuggestion, error) {
+	return a.store.GetSearchSuggestions(prefix, field, searchSuggestionLimit)
+}
+
 // GetRecentCategories returns the list of recently accessed categories
 func (a *App) GetRecentCategories(limit int) []store.Category {
 	categories, err := a.store.GetRecentCategories(limit)
@@ -361,6 +842,7 @@ func (a *App) GetRecentCategories(limit int) []store.Category {
 		a.logger.Error("Error getting recent categories: %v", err)
 		return []store.Category{}
 	}
+	a.applyCategoryCollages(categories)
 	return categories
 }
 
@@ -374,22 +856,62 @@ func (a *App) GetRecentTabs(limit int) []store.Tab {
 	return tabs
 }
 
-// AddCategory adds a new category
+// randomTabsLimit caps the tabs GetRandomTabs returns
+const randomTabsLimit = 50
+
+// GetRandomTabs returns up to count random tabs matching filters for
+// practice roulette, favoring tabs not opened for a long time if
+// preferStale is set
+func (a *App) GetRandomTabs(count int, filters store.TabFilters, preferStale bool) []store.Tab {
+	if count < 1 {
+		count = 1
+	}
+	count = min(count, randomTabsLimit)
+	tabs, err := a.store.GetRandomTabs(count, filters, preferStale)
+	if err != nil {
+		a.logger.Error("Error getting random tabs: %v", err)
+		return []store.Tab{}
+	}
+	return tabs
+}
+
+// categoryColorPattern and categoryIconPattern are the valid values of
+// Category.Color and Category.Icon, besides empty
+var (
+	categoryColorPattern = regexp.MustCompile(`^#[0-9a-f]{6}$`)
+	categoryIconPattern  = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+)
+
+// AddCategory adds a new category, or updates it if cat.ID exists
 func (a *App) AddCategory(cat store.Category) error {
 	// Generate ID if missing (though frontend might handle it, safer here or ensure uniqueness)
 	if cat.ID == "" {
 		cat.ID = fmt.Sprintf("cat_%d", time.Now().UnixNano())
 	}
+	cat.Color = strings.ToLower(strings.TrimSpace(cat.Color))
+	if cat.Color != "" && !categoryColorPattern.MatchString(cat.Color) {
+		return fmt.Errorf("invalid color: %s (expected #rrggbb)", cat.Color)
+	}
+	if cat.Icon != "" && !categoryIconPattern.MatchString(cat.Icon) {
+		return fmt.Errorf("invalid icon: %s", cat.Icon)
+	}
 	return a.store.AddCategory(cat)
 }
 
 // DeleteCategory deletes a category
 func (a *App) DeleteCategory(id string) error {
-	return a.store.DeleteCategory(id)
+	if err := a.store.DeleteCategory(id); err != nil {
+		return err
+	}
+	removeCollages(id, "")
+	return nil
 }
 
 // DeleteTab deletes a tab and its managed file if applicable
 func (a *App) DeleteTab(id string) error {
+	if isSharedTab(id) {
+		return errSharedReadOnly
+	}
 	// Find tab first to check for managed file
 	targetTab, err := a.store.GetTab(id)
 	if err != nil {
@@ -399,17 +921,8 @@ func (a *App) DeleteTab(id string) error {
 		return fmt.Errorf("tab not found")
 	}
 
-	if targetTab.IsManaged {
-		// Try to delete the file, log error but proceed with DB deletion
-		if err := os.Remove(targetTab.FilePath); err != nil {
-			a.logger.Error("Warning: Failed to delete managed file %s: %v", targetTab.FilePath, err)
-		}
-		// Also delete cover?
-		if targetTab.CoverPath != "" {
-			os.Remove(targetTab.CoverPath)
-		}
-	}
-
+	// Log errors but proceed with DB deletion
+	a.removeManagedFiles(*targetTab)
 	return a.store.DeleteTab(id)
 }
 
@@ -422,17 +935,7 @@ func (a *App) BatchDeleteTabs(ids []string) (int, error) {
 			continue
 		}
 
-		if targetTab.IsManaged {
-			// Try to delete the file
-			if err := os.Remove(targetTab.FilePath); err != nil {
-				a.logger.Error("Warning: Failed to delete managed file %s: %v", targetTab.FilePath, err)
-			}
-			// Also delete cover
-			if targetTab.CoverPath != "" {
-				os.Remove(targetTab.CoverPath)
-			}
-		}
-
+		a.removeManagedFiles(*targetTab)
 		if err := a.store.DeleteTab(id); err == nil {
 			deleted++
 		}
@@ -451,6 +954,9 @@ func (a *App) BatchMoveTabs(ids []string, categoryID string) (int, error) {
 		if categoryID != "" {
 			cats = append(cats, categoryID)
 		}
+		if isSharedTab(id) {
+			continue
+		}
 		if err := a.store.SetTabCategories(id, cats, baseTime+int64(i)); err == nil {
 			moved++
 		}
@@ -491,6 +997,9 @@ func (a *App) BatchAddTabsToCategory(ids []string, categoryID string) (int, erro
 
 // MoveTab updates the category of a tab (replaces existing categories with this one)
 func (a *App) MoveTab(tabID, categoryID string) error {
+	if isSharedTab(tabID) {
+		return errSharedReadOnly
+	}
 	cats := []string{}
 	if categoryID != "" {
 		cats = append(cats, categoryID)
@@ -500,11 +1009,17 @@ func (a *App) MoveTab(tabID, categoryID string) error {
 
 // UpdateTabCategories updates the categories for a tab
 func (a *App) UpdateTabCategories(tabID string, categoryIDs []string) error {
+	if isSharedTab(tabID) {
+		return errSharedReadOnly
+	}
 	return a.store.SetTabCategories(tabID, categoryIDs, time.Now().Unix())
 }
 
 // AddTabToCategory adds a tab to a category without removing it from others
 func (a *App) AddTabToCategory(tabID, categoryID string) error {
+	if isSharedTab(tabID) {
+		return errSharedReadOnly
+	}
 	tab, err := a.store.GetTab(tabID)
 	if err != nil {
 		return err
@@ -526,6 +1041,9 @@ func (a *App) AddTabToCategory(tabID, categoryID string) error {
 
 // RemoveTabFromCategory removes a tab from a category
 func (a *App) RemoveTabFromCategory(tabID, categoryID string) error {
+	if isSharedTab(tabID) {
+		return errSharedReadOnly
+	}
 	tab, err := a.store.GetTab(tabID)
 	if err != nil {
 		return err
@@ -551,18 +1069,41 @@ func (a *App) RemoveTabFromCategory(tabID, categoryID string) error {
 	return a.store.SetTabCategories(tabID, newCats, time.Now().Unix())
 }
 
-// MoveCategory moves a category into another category
+// MoveCategory moves a category into another category, or to the root for
+// an empty newParentID. Moving it into one of its subcategories fails.
 func (a *App) MoveCategory(id, newParentID string) error {
 	if id == newParentID {
 		return fmt.Errorf("cannot move category into itself")
 	}
-	// Note: A robust implementation should also check for circular dependency
 	return a.store.MoveCategory(id, newParentID)
 }
 
-// ExportTab copies the tab file to a destination folder
+// ReorderCategories puts categories, usually the children of one parent,
+// in the order of ids. Pinned categories still come first.
+func (a *App) ReorderCategories(ids []string) error {
+	return a.store.ReorderCategories(ids)
+}
+
+// SetCategoryPinned pins a category before its siblings or unpins it
+func (a *App) SetCategoryPinned(id string, pinned bool) error {
+	return a.store.SetCategoryPinned(id, pinned)
+}
+
+// GetTabsInCategoryTree returns the tabs in a category and all its
+// subcategories
+func (a *App) GetTabsInCategoryTree(categoryID string) []store.Tab {
+	tabs, err := a.store.GetTabsInCategoryTree(categoryID)
+	if err != nil {
+		a.logger.Error("Error getting tabs of category tree: %v", err)
+		return []store.Tab{}
+	}
+	return tabs
+}
+
+// ExportTab copies the tab file to a destination folder, with its links (if
+// any) in a <file>.links.json sidecar
 func (a *App) ExportTab(id string, destFolder string) error {
-	targetTab, err := a.store.GetTab(id)
+	targetTab, err := a.findTab(id)
 	if err != nil {
 		return fmt.Errorf("failed to get tab: %w", err)
 	}
@@ -570,23 +1111,174 @@ func (a *App) ExportTab(id string, destFolder string) error {
 		return fmt.Errorf("tab not found")
 	}
 
-	srcFile, err := os.Open(targetTab.FilePath)
+	destPath := filepath.Join(destFolder, filepath.Base(targetTab.FilePath))
+	if err := a.exportTabFile(*targetTab, destPath); err != nil {
+		return fmt.Errorf("failed to export file: %w", err)
+	}
+	return a.exportTabLinks(id, destPath)
+}
+
+// ExportGPTracks writes a copy of a GP tab to destFolder containing only the
+// given tracks (e.g. just the bass part). format is "gp" (the format of the
+// source file) or "midi"; MusicXML output is not supported yet and rejected.
+// Returns the path of the new file.
+func (a *App) ExportGPTracks(id string, trackIndexes []int, destFolder string, format string) (string, error) {
+	switch format {
+	case "", "gp", "midi":
+	case "musicxml":
+		return "", fmt.Errorf("exporting tracks as %s is not supported yet", format)
+	default:
+		return "", fmt.Errorf("unknown export format: %s", format)
+	}
+
+	targetTab, err := a.store.GetTab(id)
+	if err != nil {
+		return "", fmt.Errorf("failed to get tab: %w", err)
+	}
+	if targetTab == nil {
+		return "", fmt.Errorf("tab not found")
+	}
+	if targetTab.Type != "gp" {
+		return "", fmt.Errorf("track export is only available for Guitar Pro tabs")
+	}
+
+	// Name the copy after the exported parts, e.g. "Song (Bass).gp5"
+	var names []string
+	for _, idx := range trackIndexes {
+		name := fmt.Sprintf("Track %d", idx+1)
+		for _, t := range targetTab.Tracks {
+			if t.Index == idx && strings.TrimSpace(t.Name) != "" {
+				name = strings.TrimSpace(t.Name)
+			}
+		}
+		names = append(names, name)
+	}
+	ext := filepath.Ext(targetTab.FilePath)
+	base := strings.TrimSuffix(filepath.Base(targetTab.FilePath), ext)
+	if format == "midi" {
+		ext = ".mid"
+	}
+	destPath := uniquePath(destFolder, sanitizeFileName(fmt.Sprintf("%s (%s)", base, strings.Join(names, ", "))), ext)
+
+	if format == "midi" {
+		if len(trackIndexes) == 0 {
+			return "", fmt.Errorf("no tracks to export")
+		}
+		if err := writeMidi(targetTab.FilePath, trackIndexes, destPath); err != nil {
+			return "", err
+		}
+	} else if err := metadata.ExportTracks(targetTab.FilePath, destPath, trackIndexes); err != nil {
+		return "", fmt.Errorf("failed to export tracks: %w", err)
+	}
+
+	a.logger.Info("Exported %d track(s) of %s to %s", len(trackIndexes), targetTab.Title, destPath)
+	return destPath, nil
+}
+
+// GetPrintSettings returns the saved print layout of a tab (or the defaults)
+func (a *App) GetPrintSettings(tabID string) store.PrintSettings {
+	ps, err := a.store.GetPrintSettings(tabID)
+	if err != nil {
+		a.logger.Error("Error getting print settings: %v", err)
+		return store.DefaultPrintSettings(tabID)
+	}
+	return ps
+}
+
+// SavePrintSettings stores the print layout used when printing/exporting a tab
+func (a *App) SavePrintSettings(ps store.PrintSettings) error {
+	if isSharedTab(ps.TabID) {
+		return errSharedReadOnly
+	}
+	tab, err := a.store.GetTab(ps.TabID)
 	if err != nil {
-		return fmt.Errorf("failed to open source file: %w", err)
+		return fmt.Errorf("failed to get tab: %w", err)
+	}
+	if tab == nil {
+		return fmt.Errorf("tab not found: %s", ps.TabID)
 	}
-	defer srcFile.Close()
 
-	fileName := filepath.Base(targetTab.FilePath)
-	destPath := filepath.Join(destFolder, fileName)
+	// Track selection only applies to GP renders
+	if tab.Type != "gp" {
+		ps.Tracks = []int{}
+	}
+	if err := checkPrintSettings(ps); err != nil {
+		return err
+	}
+
+	return a.store.SetPrintSettings(ps)
+}
+
+// checkPrintSettings validates a print layout
+func checkPrintSettings(ps store.PrintSettings) error {
+	if _, ok := paperSizes[ps.PaperSize]; !ok {
+		return fmt.Errorf("unsupported paper size: %s", ps.PaperSize)
+	}
+	switch ps.PagesPerSheet {
+	case 1, 2, 4:
+	default:
+		return fmt.Errorf("pages per sheet must be 1, 2 or 4")
+	}
+	for _, m := range []float64{ps.MarginTop, ps.MarginRight, ps.MarginBottom, ps.MarginLeft} {
+		if m < 0 || m > 50 {
+			return fmt.Errorf("margins must be between 0 and 50 mm")
+		}
+	}
+	for _, t := range ps.Tracks {
+		if t < 0 {
+			return fmt.Errorf("invalid track index: %d", t)
+		}
+	}
+	return nil
+}
+
+// GetTabCredits returns the credits read from the header of a GP tab
+func (a *App) GetTabCredits(tabID string) store.TabCredits {
+	tab, err := a.findTab(tabID)
+	if err != nil {
+		a.logger.Error("Error getting credits: %v", err)
+	}
+	if tab == nil {
+		return store.TabCredits{}
+	}
+	return tab.Credits
+}
 
-	destFile, err := os.Create(destPath)
+// GetTabNotes returns the notes of a tab, oldest first
+func (a *App) GetTabNotes(tabID string) []store.TabNote {
+	notes, err := a.store.GetTabNotes(tabID)
 	if err != nil {
-		return fmt.Errorf("failed to create destination file: %w", err)
+		a.logger.Error("Error getting notes: %v", err)
+		return []store.TabNote{}
 	}
-	defer destFile.Close()
+	return notes
+}
 
-	_, err = io.Copy(destFile, srcFile)
-	return err
+// SaveTabNote adds a note to a tab, or edits the note noteID if it is not 0.
+// Notes are searchable with the "notes" search field.
+func (a *App) SaveTabNote(tabID string, noteID int64, content string) (store.TabNote, error) {
+	if isSharedTab(tabID) {
+		return store.TabNote{}, errSharedReadOnly
+	}
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return store.TabNote{}, fmt.Errorf("note is empty")
+	}
+	if noteID == 0 {
+		tab, err := a.store.GetTab(tabID)
+		if err != nil {
+			return store.TabNote{}, fmt.Errorf("failed to get tab: %w", err)
+		}
+		if tab == nil {
+			return store.TabNote{}, fmt.Errorf("tab not found: %s", tabID)
+		}
+	}
+	return a.store.SaveTabNote(store.TabNote{ID: noteID, TabID: tabID, Content: content})
+}
+
+// DeleteTabNote removes a note
+func (a *App) DeleteTabNote(noteID int64) error {
+	return a.store.DeleteTabNote(noteID)
 }
 
 // SelectFolder opens a folder selection dialog
@@ -621,31 +1313,17 @@ func (a *App) SaveTab(tab store.Tab, shouldCopy bool) error {
 		return fmt.Errorf("a tab with title '%s' already exists", existingByTitle.Title)
 	}
 
-	appDir := getAppDir()
-
 	// 1. Handle File Copy
 	if shouldCopy {
-		ext := filepath.Ext(tab.FilePath)
-		newFilename := tab.ID + ext
-		destPath := filepath.Join(appDir, "storage", newFilename)
-
-		src, err := os.Open(tab.FilePath)
+		// Copied and verified before the tab is saved, so the database never
+		// refers to a partial copy
+		locator, hash, err := a.storage.Import(tab.FilePath, tab.ID+filepath.Ext(tab.FilePath))
 		if err != nil {
-			return err
-		}
-		defer src.Close()
-
-		dst, err := os.Create(destPath)
-		if err != nil {
-			return err
-		}
-		defer dst.Close()
-
-		if _, err = io.Copy(dst, src); err != nil {
-			return err
+			return fmt.Errorf("failed to copy file to storage: %w", err)
 		}
 
-		tab.FilePath = destPath
+		tab.FilePath = locator
+		tab.FileHash = hash
 		tab.IsManaged = true
 	} else {
 		tab.IsManaged = false
@@ -654,24 +1332,48 @@ func (a *App) SaveTab(tab store.Tab, shouldCopy bool) error {
 	if tab.AddedAt == 0 {
 		tab.AddedAt = time.Now().Unix()
 	}
+	tab.Script = metadata.DetectScript(tab.Title, tab.Artist)
 
 	// Save initial version first
 	if err := a.store.AddTab(tab); err != nil {
+		if tab.IsManaged {
+			a.storage.Remove(tab.FilePath)
+		}
 		return err
 	}
 
+	if len(tab.Tracks) > 0 {
+		if err := a.store.SetTabTracks(tab.ID, tab.Tracks); err != nil {
+			a.logger.Error("Failed to save tracks for %s: %v", tab.Title, err)
+		}
+	}
+
 	// 2. Handle Cover (Async)
 	a.fetchCoverAsync(tab)
 
+	// 3. Index the lyrics for search (Async)
+	go a.syncService.BackfillContent()
+
 	return nil
 }
 
 // UpdateTab updates an existing tab's metadata
 func (a *App) UpdateTab(tab store.Tab) error {
+	if isSharedTab(tab.ID) {
+		return errSharedReadOnly
+	}
+	tab.Script = metadata.DetectScript(tab.Title, tab.Artist)
+
 	// Let's just update the store.
 	if err := a.store.AddTab(tab); err != nil {
 		return err
 	}
+	// A cover chosen by the user has no color yet
+	if tab.CoverPath != "" {
+		if stored, err := a.store.GetTab(tab.ID); err == nil && stored != nil && stored.CoverColor == "" {
+			a.syncService.UpdateCoverColor(tab.ID, tab.CoverPath)
+		}
+	}
 
 	// Trigger Cover Update (Async)
 	a.fetchCoverAsync(tab)
@@ -685,6 +1387,9 @@ func (a *App) UpdateTab(tab store.Tab) error {
 // - If no cover exists: prefer AlphaTab's data (more authoritative than filename parsing)
 // - If cover exists: only update placeholder fields (existing data was good enough for cover search)
 func (a *App) UpdateTabMetadata(id string, title string, artist string, album string) error {
+	if isSharedTab(id) {
+		return errSharedReadOnly
+	}
 	// Get current tab
 	currentTab, err := a.store.GetTab(id)
 	if err != nil {
@@ -781,7 +1486,7 @@ func (a *App) UpdateTabMetadata(id string, title string, artist string, album st
 
 // OpenTab opens the file using system default
 func (a *App) OpenTab(id string) error {
-	targetTab, err := a.store.GetTab(id)
+	targetTab, err := a.findTab(id)
 	if err != nil {
 		return fmt.Errorf("failed to get tab: %w", err)
 	}
@@ -789,36 +1494,125 @@ func (a *App) OpenTab(id string) error {
 		return fmt.Errorf("tab not found")
 	}
 
-	// Update LastOpened
-	targetTab.LastOpened = time.Now().Unix()
-	a.store.UpdateTab(*targetTab)
+	// Update LastOpened and the history; the shared library is read-only
+	if !targetTab.Shared {
+		if err := a.store.MarkTabOpened(id, time.Now().Unix()); err != nil {
+			a.logger.Error("Failed to record the open of %s: %v", targetTab.Title, err)
+		}
+	}
 
-	var cmd *exec.Cmd
-	path := targetTab.FilePath
+	return openWithSystem(targetTab.FilePath)
+}
+
+// OpenTabSource opens the file a tab was converted from on import with the
+// default application, e.g. a MuseScore file in MuseScore
+func (a *App) OpenTabSource(id string) error {
+	tab, err := a.findTab(id)
+	if err != nil {
+		return fmt.Errorf("failed to get tab: %w", err)
+	}
+	if tab == nil {
+		return fmt.Errorf("tab not found")
+	}
+	if tab.SourcePath == "" {
+		return fmt.Errorf("tab was not converted")
+	}
+	if _, err := os.Stat(tab.SourcePath); err != nil {
+		return fmt.Errorf("original not found: %s", tab.SourcePath)
+	}
+	return openWithSystem(tab.SourcePath)
+}
+
+// ResolveOpenMethod returns how a tab is opened: "inner" for the internal
+// viewer or "system". The category it is opened from decides first, or
+// outside a category the first of its categories with an override; a
+// category without an override inherits the one of its parent. Falls back
+// to the settings. Only PDF and GP tabs can be opened inside the app.
+func (a *App) ResolveOpenMethod(tabID, categoryID string) (string, error) {
+	tab, err := a.findTab(tabID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get tab: %w", err)
+	}
+	if tab == nil {
+		return "", fmt.Errorf("tab not found")
+	}
+	if tab.Type != "pdf" && tab.Type != "gp" {
+		return "system", nil
+	}
+
+	categories, err := a.store.GetCategories()
+	if err != nil {
+		return "", fmt.Errorf("failed to get categories: %w", err)
+	}
+	byID := make(map[string]store.Category, len(categories))
+	for _, c := range categories {
+		byID[c.ID] = c
+	}
+	override := func(id string) string {
+		seen := make(map[string]bool)
+		for id != "" && !seen[id] {
+			seen[id] = true
+			c, ok := byID[id]
+			if !ok {
+				break
+			}
+			method := c.OpenMethod
+			if tab.Type == "gp" {
+				method = c.OpenGpMethod
+			}
+			if method != "" {
+				return method
+			}
+			id = c.ParentID
+		}
+		return ""
+	}
 
+	method := ""
+	if categoryID != "" {
+		method = override(categoryID)
+	} else {
+		for _, id := range tab.CategoryIDs {
+			if method = override(id); method != "" {
+				break
+			}
+		}
+	}
+	if method == "" {
+		settings := a.store.GetSettings()
+		method = settings.OpenMethod
+		if tab.Type == "gp" {
+			method = settings.OpenGpMethod
+		}
+	}
+	if method != "inner" {
+		return "system", nil
+	}
+	return "inner", nil
+}
+
+// openWithSystem opens a file or URL with the default application
+func openWithSystem(target string) error {
+	var cmd *exec.Cmd
 	switch runtime.GOOS {
 	case "windows":
-		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", path)
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", target)
 	case "darwin":
-		cmd = exec.Command("open", path)
+		cmd = exec.Command("open", target)
 	default: // linux
-		cmd = exec.Command("xdg-open", path)
+		cmd = exec.Command("xdg-open", target)
 	}
 	return cmd.Start()
 }
 
-// MarkAsOpened updates the LastOpened timestamp for a tab without opening it
+// MarkAsOpened updates the LastOpened timestamp for a tab without opening
+// it, and records the open in the tab history
 func (a *App) MarkAsOpened(id string) error {
-	targetTab, err := a.store.GetTab(id)
-	if err != nil {
-		return fmt.Errorf("failed to get tab: %w", err)
-	}
-	if targetTab == nil {
-		return fmt.Errorf("tab not found")
+	// The shared library is read-only
+	if isSharedTab(id) {
+		return nil
 	}
-
-	targetTab.LastOpened = time.Now().Unix()
-	return a.store.UpdateTab(*targetTab)
+	return a.store.MarkTabOpened(id, time.Now().Unix())
 }
 
 // GetCover returns the base64 encoded image
@@ -838,7 +1632,7 @@ func (a *App) SelectFiles() []string {
 	selection, err := wailsRuntime.OpenMultipleFilesDialog(a.ctx, wailsRuntime.OpenDialogOptions{
 		Title: "Select Tab Files",
 		Filters: []wailsRuntime.FileFilter{
-			{DisplayName: "Tabs (*.pdf;*.gp;*.gp5;*.gpx)", Pattern: "*.pdf;*.gp;*.gp5;*.gpx"},
+			{DisplayName: "Tabs (*.pdf;*.gp;*.gp5;*.gpx;*.txt;*.tab;*.crd;*.cho;*.pro)", Pattern: "*.pdf;*.gp;*.gp5;*.gpx;*.txt;*.tab;*.crd;*.cho;*.pro;*.chopro;*.chordpro"},
 		},
 	})
 
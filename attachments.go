@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"haya-tab/pkg/store"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// attachmentTypes maps the supported audio extensions to their content type
+var attachmentTypes = map[string]string{
+	".mp3":  "audio/mpeg",
+	".wav":  "audio/wav",
+	".flac": "audio/flac",
+}
+
+// AddAttachment attaches an audio file to a tab for playing along. The file
+// is referenced in place and streamed from /api/attachment/{id}.
+func (a *App) AddAttachment(tabID string, filePath string) (store.Attachment, error) {
+	if isSharedTab(tabID) {
+		return store.Attachment{}, errSharedReadOnly
+	}
+	ext := strings.ToLower(filepath.Ext(filePath))
+	if _, ok := attachmentTypes[ext]; !ok {
+		return store.Attachment{}, fmt.Errorf("unsupported audio format: %s", ext)
+	}
+	if info, err := os.Stat(filePath); err != nil || info.IsDir() {
+		return store.Attachment{}, fmt.Errorf("file not found: %s", filePath)
+	}
+
+	tab, err := a.store.GetTab(tabID)
+	if err != nil {
+		return store.Attachment{}, fmt.Errorf("failed to get tab: %w", err)
+	}
+	if tab == nil {
+		return store.Attachment{}, fmt.Errorf("tab not found: %s", tabID)
+	}
+
+	return a.store.AddAttachment(store.Attachment{
+		TabID:    tabID,
+		Name:     strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath)),
+		FilePath: filePath,
+		AddedAt:  time.Now().Unix(),
+	})
+}
+
+// GetAttachments returns the audio files attached to a tab
+func (a *App) GetAttachments(tabID string) []store.Attachment {
+	attachments, err := a.store.GetAttachments(tabID)
+	if err != nil {
+		a.logger.Error("Error getting attachments: %v", err)
+		return []store.Attachment{}
+	}
+	return attachments
+}
+
+// RemoveAttachment detaches an audio file from its tab. The file is kept.
+func (a *App) RemoveAttachment(id int64) error {
+	return a.store.DeleteAttachment(id)
+}
+
+// SelectAudioFiles opens a file dialog for selecting backing tracks
+func (a *App) SelectAudioFiles() []string {
+	selection, err := wailsRuntime.OpenMultipleFilesDialog(a.ctx, wailsRuntime.OpenDialogOptions{
+		Title: "Select Audio Files",
+		Filters: []wailsRuntime.FileFilter{
+			{DisplayName: "Audio (*.mp3;*.wav;*.flac)", Pattern: "*.mp3;*.wav;*.flac"},
+		},
+	})
+
+	if err != nil {
+		return nil
+	}
+	return selection
+}
+
+// serveAttachment streams an attachment. Range requests are supported so
+// the player can seek without downloading the whole file.
+func (h *FileHandler) serveAttachment(w http.ResponseWriter, r *http.Request, idStr string) {
+	if h.app == nil || h.app.store == nil {
+		http.Error(w, "Service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid attachment ID", http.StatusBadRequest)
+		return
+	}
+
+	attachment, err := h.app.store.GetAttachment(id)
+	if err != nil || attachment == nil {
+		http.Error(w, "Attachment not found", http.StatusNotFound)
+		return
+	}
+
+	file, err := os.Open(attachment.FilePath)
+	if err != nil {
+		fmt.Printf("[ServeAttachment] Failed to open file %s: %v\n", attachment.FilePath, err)
+		http.Error(w, "File not found", http.StatusNotFound)
+		return
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		http.Error(w, "Cannot read file", http.StatusInternalServerError)
+		return
+	}
+
+	if contentType, ok := attachmentTypes[strings.ToLower(filepath.Ext(attachment.FilePath))]; ok {
+		w.Header().Set("Content-Type", contentType)
+	}
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+	http.ServeContent(w, r, filepath.Base(attachment.FilePath), stat.ModTime(), newContextReader(r.Context(), file))
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"haya-tab/pkg/collage"
+	"haya-tab/pkg/diskcache"
+	"haya-tab/pkg/jobpool"
+	"haya-tab/pkg/store"
+	"os"
+	"path/filepath"
+
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// collageDir is where the generated category covers are cached
+func collageDir() string {
+	return filepath.Join(getAppDir(), "covers", "collages")
+}
+
+// applyCategoryCollages sets the cover of the categories without a custom
+// cover to a collage of the covers of their tabs, when they have enough.
+// A collage is cached under a name derived from the covers it shows, so
+// adding or removing tabs, or a new cover of one of them, makes a new one.
+// Missing collages are composed in the background job pool, emitting
+// "category-covers-updated" when done; until then a category keeps the
+// cover of its first tab.
+func (a *App) applyCategoryCollages(categories []store.Category) {
+	covers, err := a.store.GetCategoryTabCovers(collage.Covers)
+	if err != nil {
+		a.logger.Info("Failed to get the covers of category tabs: %v", err)
+		return
+	}
+
+	dir := collageDir()
+	for i := range categories {
+		c := &categories[i]
+		paths := covers[c.ID]
+		if c.CoverPath != "" || len(paths) < collage.Covers {
+			continue
+		}
+		path := filepath.Join(dir, fmt.Sprintf("%s-%s.jpg", c.ID, collageKey(paths)))
+		if _, err := os.Stat(path); err == nil {
+			diskcache.Touch(path)
+			c.EffectiveCoverPath = path
+			continue
+		}
+		a.composeCollage(c.ID, paths, path)
+	}
+}
+
+// collageKey identifies the covers of a collage: their paths, and their
+// sizes and modification times since a new cover replaces the old file
+func collageKey(paths []string) string {
+	h := sha256.New()
+	for _, p := range paths {
+		fmt.Fprintln(h, p)
+		if info, err := os.Stat(p); err == nil {
+			fmt.Fprintln(h, info.Size(), info.ModTime().UnixNano())
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
+
+// composeCollage composes the collage of a category to path in the job
+// pool, replacing its previous collages. A collage that fails, e.g. for a
+// cover in an unsupported format, is not tried again until restart.
+func (a *App) composeCollage(categoryID string, paths []string, path string) {
+	if _, seen := a.collages.LoadOrStore(path, true); seen {
+		return
+	}
+	submitted := a.jobPool.Submit(jobpool.Job{
+		Name: "collage:" + categoryID,
+		Run: func(ctx context.Context) error {
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return err
+			}
+			if err := collage.Compose(paths, path); err != nil {
+				return err
+			}
+			removeCollages(categoryID, path)
+			return nil
+		},
+		OnComplete: func(err error) {
+			if err != nil {
+				a.logger.Info("Failed to compose the cover of category %s: %v", categoryID, err)
+				return
+			}
+			a.collages.Delete(path)
+			a.applyCacheLimit()
+			wailsRuntime.EventsEmit(a.ctx, "category-covers-updated", categoryID)
+		},
+	})
+	if !submitted {
+		a.collages.Delete(path)
+	}
+}
+
+// removeCollages deletes the cached collages of a category but keep
+func removeCollages(categoryID, keep string) {
+	matches, _ := filepath.Glob(filepath.Join(collageDir(), categoryID+"-*.jpg"))
+	for _, m := range matches {
+		if m != keep {
+			os.Remove(m)
+		}
+	}
+}
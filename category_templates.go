@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"haya-tab/pkg/store"
+	"strings"
+	"time"
+)
+
+// GetCategoryTemplates returns the built-in and saved category templates
+func (a *App) GetCategoryTemplates() []store.CategoryTemplate {
+	templates, err := a.store.GetCategoryTemplates()
+	if err != nil {
+		a.logger.Error("Error getting category templates: %v", err)
+		return []store.CategoryTemplate{}
+	}
+	return templates
+}
+
+// CreateCategoryTemplate saves the subcategories of a category, at every
+// depth, as a template named name
+func (a *App) CreateCategoryTemplate(name, categoryID string) (store.CategoryTemplate, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return store.CategoryTemplate{}, fmt.Errorf("template name is required")
+	}
+	categories, err := a.store.GetCategories()
+	if err != nil {
+		return store.CategoryTemplate{}, fmt.Errorf("failed to get categories: %w", err)
+	}
+
+	children := map[string][]store.Category{}
+	found := false
+	for _, c := range categories {
+		children[c.ParentID] = append(children[c.ParentID], c)
+		found = found || c.ID == categoryID
+	}
+	if !found {
+		return store.CategoryTemplate{}, fmt.Errorf("category not found: %s", categoryID)
+	}
+
+	var folders func(parentID string) []store.TemplateFolder
+	folders = func(parentID string) []store.TemplateFolder {
+		var result []store.TemplateFolder
+		for _, c := range children[parentID] {
+			result = append(result, store.TemplateFolder{Name: c.Name, Children: folders(c.ID)})
+		}
+		return result
+	}
+
+	template := store.CategoryTemplate{
+		ID:      fmt.Sprintf("tpl_%d", time.Now().UnixNano()),
+		Name:    name,
+		Folders: folders(categoryID),
+	}
+	if len(template.Folders) == 0 {
+		return store.CategoryTemplate{}, fmt.Errorf("the category has no subcategories to save")
+	}
+	if err := a.store.SaveCategoryTemplate(template); err != nil {
+		return store.CategoryTemplate{}, fmt.Errorf("failed to save template: %w", err)
+	}
+	return template, nil
+}
+
+// ApplyCategoryTemplate creates a category named name in parentID (empty
+// for the root) with the folders of a template inside. Returns the new
+// category.
+func (a *App) ApplyCategoryTemplate(templateID, name, parentID string) (store.Category, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return store.Category{}, fmt.Errorf("category name is required")
+	}
+	template, err := a.store.GetCategoryTemplate(templateID)
+	if err != nil {
+		return store.Category{}, fmt.Errorf("failed to get template: %w", err)
+	}
+	if template == nil {
+		return store.Category{}, fmt.Errorf("template not found: %s", templateID)
+	}
+
+	base := time.Now().UnixNano()
+	var categories []store.Category
+	newCategory := func(name, parentID string) string {
+		id := fmt.Sprintf("cat_%d", base+int64(len(categories)))
+		categories = append(categories, store.Category{ID: id, Name: name, ParentID: parentID})
+		return id
+	}
+	var addFolders func(folders []store.TemplateFolder, parentID string)
+	addFolders = func(folders []store.TemplateFolder, parentID string) {
+		for _, f := range folders {
+			addFolders(f.Children, newCategory(f.Name, parentID))
+		}
+	}
+	addFolders(template.Folders, newCategory(name, parentID))
+
+	if err := a.store.AddCategories(categories); err != nil {
+		return store.Category{}, fmt.Errorf("failed to create categories: %w", err)
+	}
+	a.logger.Info("Created %s from template %s (%d categories)", name, template.Name, len(categories))
+	return categories[0], nil
+}
+
+// DeleteCategoryTemplate deletes a saved category template. Built-in
+// templates cannot be deleted.
+func (a *App) DeleteCategoryTemplate(id string) error {
+	template, err := a.store.GetCategoryTemplate(id)
+	if err != nil {
+		return fmt.Errorf("failed to get template: %w", err)
+	}
+	if template == nil {
+		return fmt.Errorf("template not found: %s", id)
+	}
+	if template.BuiltIn {
+		return fmt.Errorf("built-in templates cannot be deleted")
+	}
+	return a.store.DeleteCategoryTemplate(id)
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"haya-tab/pkg/store"
+	"os"
+	"strings"
+	"time"
+
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// categoryTreeExt is the extension of exported category trees
+const categoryTreeExt = ".hayacats.json"
+
+// categoryTreeFormat is the version of the category tree file format
+const categoryTreeFormat = 1
+
+// categoryTreeFile is an exported category tree: the categories and the
+// tabs filed in them, without the tabs themselves
+type categoryTreeFile struct {
+	Format     int                `json:"format"`
+	ExportedAt int64              `json:"exportedAt"`
+	Folders    []categoryTreeNode `json:"folders"`
+}
+
+// categoryTreeNode is a category of an exported tree
+type categoryTreeNode struct {
+	Name     string             `json:"name"`
+	Color    string             `json:"color,omitempty"`
+	Icon     string             `json:"icon,omitempty"`
+	Tabs     []categoryTreeTab  `json:"tabs,omitempty"`
+	Children []categoryTreeNode `json:"children,omitempty"`
+}
+
+// categoryTreeTab identifies a tab of an exported tree by the hash of its
+// file or, if not computed yet, by its path. Title and artist are for
+// reading the file.
+type categoryTreeTab struct {
+	Hash   string `json:"hash,omitempty"`
+	Path   string `json:"path,omitempty"`
+	Title  string `json:"title"`
+	Artist string `json:"artist,omitempty"`
+}
+
+// CategoryTreeImport is the outcome of ImportCategoryTree
+type CategoryTreeImport struct {
+	Categories int      `json:"categories"` // Number of categories created
+	Tabs       int      `json:"tabs"`       // Number of tabs filed in a category
+	Unmatched  []string `json:"unmatched"`  // Titles of the tabs not in this library
+}
+
+// ExportCategoryTree writes every category, nested, with the tabs filed in
+// each to destFolder as a .hayacats.json file and returns its path. The
+// file is imported on another machine with ImportCategoryTree to share the
+// organization of a library having the same files.
+func (a *App) ExportCategoryTree(destFolder string) (string, error) {
+	categories, err := a.store.GetCategories()
+	if err != nil {
+		return "", fmt.Errorf("failed to get categories: %w", err)
+	}
+	if len(categories) == 0 {
+		return "", fmt.Errorf("there are no categories to export")
+	}
+	tabs, err := a.store.GetTabs()
+	if err != nil {
+		return "", fmt.Errorf("failed to get tabs: %w", err)
+	}
+
+	children := map[string][]store.Category{}
+	for _, c := range categories {
+		children[c.ParentID] = append(children[c.ParentID], c)
+	}
+	tabsOf := map[string][]categoryTreeTab{}
+	for _, t := range tabs {
+		for _, id := range t.CategoryIDs {
+			tabsOf[id] = append(tabsOf[id], categoryTreeTab{Hash: t.FileHash, Path: t.FilePath, Title: t.Title, Artist: t.Artist})
+		}
+	}
+
+	var nodes func(parentID string, seen map[string]bool) []categoryTreeNode
+	nodes = func(parentID string, seen map[string]bool) []categoryTreeNode {
+		var result []categoryTreeNode
+		for _, c := range children[parentID] {
+			if seen[c.ID] {
+				continue
+			}
+			seen[c.ID] = true
+			result = append(result, categoryTreeNode{
+				Name: c.Name, Color: c.Color, Icon: c.Icon, Tabs: tabsOf[c.ID], Children: nodes(c.ID, seen),
+			})
+		}
+		return result
+	}
+	file := categoryTreeFile{
+		Format:     categoryTreeFormat,
+		ExportedAt: time.Now().Unix(),
+		Folders:    nodes("", map[string]bool{}),
+	}
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	path := uniquePath(destFolder, "Categories", categoryTreeExt)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write category tree: %w", err)
+	}
+	a.logger.Info("Exported %d categories to %s", len(categories), path)
+	return path, nil
+}
+
+// ImportCategoryTree merges the category tree exported to path into the
+// library: a category with the same name and parent as one of the file is
+// reused as it is, the others are created with the color and icon of the
+// file. Tabs are matched by file hash, then by path, and added to their
+// categories without leaving the ones they are in. The categories and tabs
+// are applied in one transaction.
+func (a *App) ImportCategoryTree(path string) (CategoryTreeImport, error) {
+	result := CategoryTreeImport{Unmatched: []string{}}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return result, fmt.Errorf("failed to read category tree: %w", err)
+	}
+	var file categoryTreeFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return result, fmt.Errorf("not a category tree: %w", err)
+	}
+	if file.Format > categoryTreeFormat {
+		return result, fmt.Errorf("the category tree was exported by a newer version (format %d)", file.Format)
+	}
+	if len(file.Folders) == 0 {
+		return result, fmt.Errorf("the category tree has no categories")
+	}
+
+	categories, err := a.store.GetCategories()
+	if err != nil {
+		return result, fmt.Errorf("failed to get categories: %w", err)
+	}
+	tabs, err := a.store.GetTabs()
+	if err != nil {
+		return result, fmt.Errorf("failed to get tabs: %w", err)
+	}
+
+	existing := map[[2]string]string{} // Parent ID and lowercase name to category ID
+	for _, c := range categories {
+		existing[[2]string{c.ParentID, strings.ToLower(c.Name)}] = c.ID
+	}
+	byHash := map[string][]string{}
+	byPath := map[string][]string{}
+	for _, t := range tabs {
+		if t.FileHash != "" {
+			byHash[t.FileHash] = append(byHash[t.FileHash], t.ID)
+		}
+		byPath[t.FilePath] = append(byPath[t.FilePath], t.ID)
+	}
+	matchTab := func(t categoryTreeTab) []string {
+		if ids := byHash[t.Hash]; t.Hash != "" && len(ids) > 0 {
+			return ids
+		}
+		if t.Path != "" {
+			return byPath[t.Path]
+		}
+		return nil
+	}
+
+	base := time.Now().UnixNano()
+	var ops []store.Operation
+	var styled []store.Category // Created categories with a color or an icon
+	filed := map[string]bool{}
+	unmatched := map[string]bool{}
+	var addNodes func(nodes []categoryTreeNode, parentID string)
+	addNodes = func(nodes []categoryTreeNode, parentID string) {
+		for _, n := range nodes {
+			name := strings.TrimSpace(n.Name)
+			if name == "" {
+				continue
+			}
+			key := [2]string{parentID, strings.ToLower(name)}
+			id := existing[key]
+			if id == "" {
+				id = fmt.Sprintf("cat_%d", base+int64(result.Categories))
+				existing[key] = id
+				ops = append(ops, store.Operation{Op: "createCategory", CategoryID: id, Name: name, ParentID: parentID})
+				result.Categories++
+				if n.Color != "" || n.Icon != "" {
+					styled = append(styled, store.Category{ID: id, Name: name, ParentID: parentID, Color: n.Color, Icon: n.Icon})
+				}
+			}
+
+			var tabIDs []string
+			for _, t := range n.Tabs {
+				ids := matchTab(t)
+				if len(ids) == 0 {
+					if !unmatched[t.Title] {
+						unmatched[t.Title] = true
+						result.Unmatched = append(result.Unmatched, t.Title)
+					}
+					continue
+				}
+				for _, tabID := range ids {
+					tabIDs = append(tabIDs, tabID)
+					filed[tabID] = true
+				}
+			}
+			if len(tabIDs) > 0 {
+				ops = append(ops, store.Operation{Op: "addTabsToCategory", CategoryID: id, TabIDs: tabIDs})
+			}
+			addNodes(n.Children, id)
+		}
+	}
+	addNodes(file.Folders, "")
+	result.Tabs = len(filed)
+
+	if len(ops) > 0 {
+		var applied OperationsResult
+		if err := a.applyOperations(ops, &applied); err != nil {
+			return CategoryTreeImport{Unmatched: []string{}}, fmt.Errorf("failed to import categories: %w", err)
+		}
+	}
+	for _, c := range styled {
+		if err := a.AddCategory(c); err != nil {
+			a.logger.Error("Failed to set the color and icon of %s: %v", c.Name, err)
+		}
+	}
+	a.logger.Info("Imported category tree %s: %d categories created, %d tabs filed, %d not found",
+		path, result.Categories, result.Tabs, len(result.Unmatched))
+	return result, nil
+}
+
+// SelectCategoryTreeFile opens a file dialog for selecting an exported
+// category tree
+func (a *App) SelectCategoryTreeFile() string {
+	selection, err := wailsRuntime.OpenFileDialog(a.ctx, wailsRuntime.OpenDialogOptions{
+		Title: "Import Categories",
+		Filters: []wailsRuntime.FileFilter{
+			{DisplayName: "Category Trees (*.json)", Pattern: "*.json"},
+		},
+	})
+
+	if err != nil {
+		return ""
+	}
+	return selection
+}
@@ -0,0 +1,453 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"haya-tab/pkg/coverpool"
+	"haya-tab/pkg/jobpool"
+	"haya-tab/pkg/logger"
+	"haya-tab/pkg/metadata"
+	"haya-tab/pkg/storage"
+	"haya-tab/pkg/store"
+	syncpkg "haya-tab/pkg/sync"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// cliFlags are the flags that start the command line mode instead of the
+// window, see runCLI
+var cliFlags = []string{"add", "sync", "export-csv"}
+
+// cliOptions are the command line flags of the command line mode
+type cliOptions struct {
+	add       []string // Files to add, copied to app storage unless link
+	link      bool
+	category  string // Name of the category added files go to, created if missing
+	sync      bool
+	exportCSV string // File the library is exported to, "-" for stdout
+}
+
+// serverInfo lets the command line mode reach the running app, see
+// writeServerInfo
+type serverInfo struct {
+	Port  int    `json:"port"`
+	Token string `json:"token"`
+	PID   int    `json:"pid"`
+}
+
+// isCLI reports whether args ask for the command line mode
+func isCLI(args []string) bool {
+	for _, arg := range args {
+		name, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if strings.HasPrefix(arg, "-") {
+			for _, f := range cliFlags {
+				if name == f {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// parseCLI parses the flags of the command line mode. Arguments after the
+// flags are more files to add.
+func parseCLI(args []string, stderr io.Writer) (cliOptions, error) {
+	var opts cliOptions
+	fs := flag.NewFlagSet("haya-tab", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Func("add", "add a tab `file` to the library (repeatable)", func(path string) error {
+		opts.add = append(opts.add, path)
+		return nil
+	})
+	fs.BoolVar(&opts.link, "link", false, "link added files where they are instead of copying them to app storage")
+	fs.StringVar(&opts.category, "category", "", "`name` of the category to put added tabs in, created if missing")
+	fs.BoolVar(&opts.sync, "sync", false, "sync the configured folders")
+	fs.StringVar(&opts.exportCSV, "export-csv", "", "export the library to a CSV `file`, - for stdout")
+	if err := fs.Parse(args); err != nil {
+		return opts, err
+	}
+	opts.add = append(opts.add, fs.Args()...)
+	for i, path := range opts.add {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return opts, err
+		}
+		opts.add[i] = abs
+	}
+	return opts, nil
+}
+
+// runCLI runs the command line mode: through the file server of the
+// running app if any, so its window and watchers stay in step, otherwise
+// directly on the database. Returns the exit code.
+func runCLI(args []string) int {
+	opts, err := parseCLI(args, os.Stderr)
+	if err != nil {
+		return 2
+	}
+
+	var c cliClient
+	if info, err := readServerInfo(); err == nil {
+		c = &remoteCLI{info: info, client: &http.Client{Timeout: 30 * time.Minute}}
+		err = c.run(opts, os.Stdout)
+		if !errors.Is(err, errAppNotRunning) {
+			return cliExitCode(err)
+		}
+	}
+	a, err := openLibrary()
+	if err != nil {
+		return cliExitCode(err)
+	}
+	defer a.closeLibrary()
+	return cliExitCode((&localCLI{app: a}).run(opts, os.Stdout))
+}
+
+func cliExitCode(err error) int {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "haya-tab:", err)
+		return 1
+	}
+	return 0
+}
+
+// errAppNotRunning is returned by remoteCLI when the app does not answer
+var errAppNotRunning = errors.New("the app is not running")
+
+type cliClient interface {
+	run(opts cliOptions, out io.Writer) error
+}
+
+// remoteCLI runs the command line mode through the file server of the
+// running app, see cliHandler
+type remoteCLI struct {
+	info   serverInfo
+	client *http.Client
+}
+
+func (c *remoteCLI) run(opts cliOptions, out io.Writer) error {
+	for i, path := range opts.add {
+		query := url.Values{"path": {path}, "category": {opts.category}, "link": {strconv.FormatBool(opts.link)}}
+		var tab store.Tab
+		if err := c.call("add", query, &tab); err != nil {
+			if i == 0 && errors.Is(err, errAppNotRunning) {
+				return err
+			}
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		fmt.Fprintf(out, "Added %s (%s)\n", tab.Title, tab.ID)
+	}
+	if opts.sync {
+		var message string
+		if err := c.call("sync", nil, &message); err != nil {
+			return err
+		}
+		fmt.Fprintln(out, message)
+	}
+	if opts.exportCSV != "" {
+		return writeCSVTo(opts.exportCSV, out, func(w io.Writer) error {
+			resp, err := c.post("export-csv", nil)
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+			_, err = io.Copy(w, resp.Body)
+			return err
+		})
+	}
+	return nil
+}
+
+// call posts to /api/cli/{command} and decodes the JSON answer into v
+func (c *remoteCLI) call(command string, query url.Values, v any) error {
+	resp, err := c.post(command, query)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func (c *remoteCLI) post(command string, query url.Values) (*http.Response, error) {
+	u := fmt.Sprintf("http://127.0.0.1:%d/api/cli/%s?%s", c.info.Port, command, query.Encode())
+	req, err := http.NewRequest(http.MethodPost, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.info.Token)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errAppNotRunning, err)
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		resp.Body.Close()
+		return nil, errAppNotRunning // Another process took the port of an app that crashed
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, errors.New(strings.TrimSpace(string(msg)))
+	}
+	return resp, nil
+}
+
+// localCLI runs the command line mode on the database while the app is
+// closed, see openLibrary
+type localCLI struct {
+	app *App
+}
+
+func (c *localCLI) run(opts cliOptions, out io.Writer) error {
+	for _, path := range opts.add {
+		tab, err := c.app.addFileFromCLI(path, opts.category, opts.link)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		fmt.Fprintf(out, "Added %s (%s)\n", tab.Title, tab.ID)
+	}
+	if opts.sync {
+		message, err := c.app.syncService.TriggerSync()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(out, message)
+	}
+	if opts.exportCSV != "" {
+		return writeCSVTo(opts.exportCSV, out, c.app.writeLibraryCSV)
+	}
+	return nil
+}
+
+// writeCSVTo writes a CSV export with write to path, or to out for "-"
+func writeCSVTo(path string, out io.Writer, write func(io.Writer) error) error {
+	if path == "-" {
+		return write(out)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// openLibrary opens the library of the app directory for the command line
+// mode while the app is closed. Covers of added tabs are queued for the app
+// to fetch on its next start, see closeLibrary.
+func openLibrary() (*App, error) {
+	appDir := getAppDir()
+	dbPath := filepath.Join(appDir, "data", "haya-tab.db")
+	if _, err := os.Stat(dbPath); err != nil {
+		return nil, fmt.Errorf("no library found in %s", appDir)
+	}
+
+	a := NewApp()
+	a.logger = logger.NewCLILogger(appDir)
+	a.storage = storage.NewLocal(filepath.Join(appDir, "storage"))
+	a.store = store.NewDBStore(dbPath)
+	if err := a.store.Initialize(); err != nil {
+		return nil, fmt.Errorf("failed to open the library: %w", err)
+	}
+	a.applyNetwork()
+	a.coverPool = coverpool.NewCoverPool(1, metadata.DownloadCover)
+	a.applyCoverEncoding()
+	a.coverPool.Pause()
+	a.coverPool.Start()
+	a.jobPool = jobpool.NewPool(1)
+	a.jobPool.Pause()
+	a.jobPool.Start()
+	a.syncService = syncpkg.NewSyncService(a.store, a.storage, a.logger, a.coverPool, a.jobPool, cliEmitter{}, appDir)
+	return a, nil
+}
+
+// closeLibrary queues the covers held by the paused cover pool and closes
+// the library opened by openLibrary. File hashes and tracks are computed
+// by the app on its next start.
+func (a *App) closeLibrary() {
+	var ids []string
+	for _, job := range a.coverPool.TakeHeld() {
+		ids = append(ids, job.TabID)
+	}
+	if len(ids) > 0 {
+		if err := a.store.QueueCoverFetches(ids); err != nil {
+			a.logger.Info("Failed to queue %d cover(s): %v", len(ids), err)
+		}
+	}
+	a.coverPool.Stop()
+	a.jobPool.Stop()
+	a.store.Close()
+	a.logger.Close()
+}
+
+// cliEmitter drops the events of the sync service in the command line mode
+type cliEmitter struct{}
+
+func (cliEmitter) Emit(string, interface{}) {}
+
+// addFileFromCLI adds the tab file at path to the library, in the category
+// named category if not empty
+func (a *App) addFileFromCLI(path, category string, link bool) (store.Tab, error) {
+	if info, err := os.Stat(path); err != nil || info.IsDir() {
+		return store.Tab{}, fmt.Errorf("not a file")
+	}
+	tab := a.syncService.ProcessFile(path)
+	if tab.Type == "unknown" {
+		return store.Tab{}, fmt.Errorf("unsupported file type")
+	}
+	if category != "" {
+		id, err := a.categoryByName(category)
+		if err != nil {
+			return store.Tab{}, err
+		}
+		tab.CategoryIDs = []string{id}
+	}
+	if err := a.SaveTab(tab, !link); err != nil {
+		return store.Tab{}, err
+	}
+	return tab, nil
+}
+
+// categoryByName returns the ID of the category named name, ignoring case,
+// creating it at the root if there is none
+func (a *App) categoryByName(name string) (string, error) {
+	categories, err := a.store.GetCategories()
+	if err != nil {
+		return "", err
+	}
+	for _, c := range categories {
+		if strings.EqualFold(c.Name, name) {
+			return c.ID, nil
+		}
+	}
+	c := store.Category{ID: fmt.Sprintf("cat_%d", time.Now().UnixNano()), Name: name}
+	if err := a.AddCategory(c); err != nil {
+		return "", err
+	}
+	return c.ID, nil
+}
+
+// writeLibraryCSV writes a line per tab with its metadata and the names of
+// its categories
+func (a *App) writeLibraryCSV(w io.Writer) error {
+	tabs, err := a.store.GetTabs()
+	if err != nil {
+		return err
+	}
+	categories, err := a.store.GetCategories()
+	if err != nil {
+		return err
+	}
+	names := make(map[string]string, len(categories))
+	for _, c := range categories {
+		names[c.ID] = c.Name
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"id", "title", "artist", "album", "type", "categories", "file_path", "managed", "missing",
+		"added_at", "rating", "difficulty", "practice_status", "key", "capo", "genre", "year"})
+	for _, t := range tabs {
+		var cats []string
+		for _, id := range t.CategoryIDs {
+			if name, ok := names[id]; ok {
+				cats = append(cats, name)
+			}
+		}
+		cw.Write([]string{
+			t.ID, t.Title, t.Artist, t.Album, t.Type, strings.Join(cats, "; "), t.FilePath,
+			strconv.FormatBool(t.IsManaged), strconv.FormatBool(t.IsMissing),
+			time.Unix(t.AddedAt, 0).Format(time.RFC3339), strconv.Itoa(t.Rating), t.Difficulty, t.PracticeStatus,
+			t.Key, strconv.Itoa(t.Capo), t.Genre, strconv.Itoa(t.Year),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// serverInfoPath is the file telling the command line mode how to reach
+// the running app
+func serverInfoPath() string {
+	return filepath.Join(getAppDir(), "data", "server.json")
+}
+
+// writeServerInfo writes the port and token of the file server for the
+// command line mode; removed on shutdown
+func (a *App) writeServerInfo() {
+	data, err := json.Marshal(serverInfo{Port: a.fileServerPort, Token: a.serverToken, PID: os.Getpid()})
+	if err == nil {
+		err = os.WriteFile(serverInfoPath(), data, 0600)
+	}
+	if err != nil {
+		a.logger.Info("Failed to write the server info: %v", err)
+	}
+}
+
+func readServerInfo() (serverInfo, error) {
+	var info serverInfo
+	data, err := os.ReadFile(serverInfoPath())
+	if err != nil {
+		return info, err
+	}
+	return info, json.Unmarshal(data, &info)
+}
+
+// cliHandler answers the command line mode on the file server:
+// POST /api/cli/add?path=&category=&link=, /api/cli/sync and
+// /api/cli/export-csv
+type cliHandler struct {
+	app *App
+}
+
+func (h *cliHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.app.store == nil || h.app.syncService == nil {
+		http.Error(w, "The app is starting", http.StatusServiceUnavailable)
+		return
+	}
+
+	query := r.URL.Query()
+	var result any
+	var err error
+	switch strings.TrimPrefix(r.URL.Path, "/api/cli/") {
+	case "add":
+		var tab store.Tab
+		tab, err = h.app.addFileFromCLI(query.Get("path"), query.Get("category"), query.Get("link") == "true")
+		if err == nil {
+			wailsRuntime.EventsEmit(h.app.ctx, "tab-updated", tab)
+		}
+		result = tab
+	case "sync":
+		result, err = h.app.TriggerSync()
+	case "export-csv":
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		if err := h.app.writeLibraryCSV(w); err != nil {
+			h.app.logger.Error("CSV export failed: %v", err)
+		}
+		return
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
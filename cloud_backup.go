@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"haya-tab/pkg/cloudbackup"
+	"haya-tab/pkg/metadata"
+	"os"
+	"path/filepath"
+	"time"
+
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// cloudRetryDelay is how long scheduled backups wait after a failed one
+const cloudRetryDelay = time.Hour
+
+// errCloudBusy is returned while a backup or restore is running
+var errCloudBusy = errors.New("a cloud backup or restore is already running")
+
+// BackupToCloud uploads the database, the files of managed tabs and the
+// covers to the bucket of the settings. Progress is emitted as
+// "cloud-backup-progress" and the result as "cloud-backup-completed".
+func (a *App) BackupToCloud() (cloudbackup.Result, error) {
+	if !a.cloudBusy.CompareAndSwap(false, true) {
+		return cloudbackup.Result{}, errCloudBusy
+	}
+	defer a.cloudBusy.Store(false)
+	a.cloudLastAttempt.Store(time.Now().Unix())
+
+	result, err := a.backupToCloud()
+	if err != nil {
+		a.logger.Error("Cloud backup failed: %v", err)
+		wailsRuntime.EventsEmit(a.ctx, "cloud-backup-completed", map[string]interface{}{"error": err.Error()})
+		return result, err
+	}
+	if err := a.store.SetSetting("lastCloudBackup", time.Now().Unix()); err != nil {
+		a.logger.Info("Failed to record the cloud backup time: %v", err)
+	}
+	a.logger.Info("Cloud backup done: %d uploaded, %d unchanged", result.Transferred, result.Skipped)
+	wailsRuntime.EventsEmit(a.ctx, "cloud-backup-completed", result)
+	return result, nil
+}
+
+func (a *App) backupToCloud() (cloudbackup.Result, error) {
+	client, err := a.cloudClient()
+	if err != nil {
+		return cloudbackup.Result{}, err
+	}
+
+	snapshot := filepath.Join(getAppDir(), "data", ".cloud-snapshot.db")
+	os.Remove(snapshot)
+	defer os.Remove(snapshot)
+	if err := a.store.Snapshot(snapshot); err != nil {
+		return cloudbackup.Result{}, fmt.Errorf("failed to snapshot the database: %w", err)
+	}
+	return cloudbackup.Backup(context.Background(), client, snapshot, cloudDirs(), func(p cloudbackup.Progress) {
+		wailsRuntime.EventsEmit(a.ctx, "cloud-backup-progress", p)
+	})
+}
+
+// RestoreFromCloud downloads the latest backup of the bucket of the
+// settings. Stored files and covers are restored right away; the database
+// replaces the current one on the next start. Progress is emitted as
+// "cloud-restore-progress" and the result as "cloud-restore-completed".
+func (a *App) RestoreFromCloud() (cloudbackup.Result, error) {
+	if !a.cloudBusy.CompareAndSwap(false, true) {
+		return cloudbackup.Result{}, errCloudBusy
+	}
+	defer a.cloudBusy.Store(false)
+
+	client, err := a.cloudClient()
+	if err != nil {
+		return cloudbackup.Result{}, err
+	}
+	result, err := cloudbackup.Restore(context.Background(), client, cloudDirs(), func(p cloudbackup.Progress) {
+		wailsRuntime.EventsEmit(a.ctx, "cloud-restore-progress", p)
+	})
+	if err != nil {
+		a.logger.Error("Cloud restore failed: %v", err)
+		wailsRuntime.EventsEmit(a.ctx, "cloud-restore-completed", map[string]interface{}{"error": err.Error()})
+		return result, err
+	}
+	a.logger.Info("Cloud restore downloaded %d files; the database is restored on the next start", result.Transferred)
+	wailsRuntime.EventsEmit(a.ctx, "cloud-restore-completed", result)
+	return result, nil
+}
+
+// cloudClient returns a client of the bucket of the settings, going through
+// the proxy and TLS settings of metadata requests
+func (a *App) cloudClient() (*cloudbackup.S3, error) {
+	if metadata.Offline() {
+		return nil, metadata.ErrOffline
+	}
+	s := a.store.GetSettings()
+	httpClient, err := metadata.NewHTTPClient(metadata.NetworkConfig{
+		ProxyURL:      s.HTTPProxy,
+		CAFile:        s.TLSCAFile,
+		SkipTLSVerify: s.TLSSkipVerify,
+	})
+	if err != nil {
+		return nil, err
+	}
+	httpClient.Timeout = 0 // Large files take long to transfer
+	return cloudbackup.NewS3(cloudbackup.Config{
+		Endpoint:  s.CloudEndpoint,
+		Region:    s.CloudRegion,
+		Bucket:    s.CloudBucket,
+		Prefix:    s.CloudPrefix,
+		AccessKey: s.CloudAccessKey,
+		SecretKey: s.CloudSecretKey,
+	}, httpClient)
+}
+
+// cloudDirs returns the directories of the library backed up
+func cloudDirs() cloudbackup.Dirs {
+	appDir := getAppDir()
+	return cloudbackup.Dirs{
+		Data:    filepath.Join(appDir, "data"),
+		Storage: filepath.Join(appDir, "storage"),
+		Covers:  filepath.Join(appDir, "covers"),
+	}
+}
+
+// runCloudBackup backs up in the background when the configured interval
+// has elapsed since the last backup, waiting cloudRetryDelay after a failure
+func (a *App) runCloudBackup() {
+	s := a.store.GetSettings()
+	if s.CloudBackupHours <= 0 || s.CloudEndpoint == "" || metadata.Offline() || a.cloudBusy.Load() {
+		return
+	}
+	if time.Since(time.Unix(s.LastCloudBackup, 0)) < time.Duration(s.CloudBackupHours)*time.Hour ||
+		time.Since(time.Unix(a.cloudLastAttempt.Load(), 0)) < cloudRetryDelay {
+		return
+	}
+	a.logger.Info("Scheduled cloud backup triggered (every %d hours)", s.CloudBackupHours)
+	go a.BackupToCloud()
+}
+
+// applyCloudRestore swaps in the database downloaded by RestoreFromCloud,
+// before the store opens it
+func (a *App) applyCloudRestore(dbPath string) *cloudbackup.Manifest {
+	manifest, err := cloudbackup.ApplyRestore(dbPath)
+	if err != nil {
+		a.logger.Error("Failed to apply the cloud restore: %v", err)
+		return nil
+	}
+	if manifest != nil {
+		a.logger.Info("Restored the database of the cloud backup of %s", time.Unix(manifest.CreatedAt, 0).Format(time.RFC3339))
+	}
+	return manifest
+}
+
+// relocateRestoredFiles points the restored tabs to the files of this
+// machine when the backup was made from another app directory
+func (a *App) relocateRestoredFiles(manifest *cloudbackup.Manifest) {
+	dirs := cloudDirs()
+	for _, d := range [][2]string{{manifest.StorageDir, dirs.Storage}, {manifest.CoversDir, dirs.Covers}} {
+		if d[0] == "" {
+			continue
+		}
+		n, err := a.store.RelocateFiles(d[0], d[1])
+		if err != nil {
+			a.logger.Error("Failed to relocate the restored files of %s: %v", d[0], err)
+		} else if n > 0 {
+			a.logger.Info("Relocated %d restored paths from %s to %s", n, d[0], d[1])
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// dbSnapshotKeep is how many snapshots of each reason are kept, the oldest
+// being removed first
+const dbSnapshotKeep = 5
+
+// dbSnapshotInterval is how often a daily snapshot is taken
+const dbSnapshotInterval = 24 * time.Hour
+
+// dbSnapshotLayout is the time in the names of snapshots, which sort by it
+const dbSnapshotLayout = "20060102-150405"
+
+// DbSnapshot is a copy of the library database in data/backups
+type DbSnapshot struct {
+	Name      string `json:"name"`      // File name, e.g. "haya-tab-20261016-180000-sync.db"
+	Reason    string `json:"reason"`    // "sync", "daily" or "restore" (taken before restoring another)
+	CreatedAt int64  `json:"createdAt"` // Unix timestamp
+	Size      int64  `json:"size"`
+	SizeText  string `json:"sizeText"` // Size formatted for the locale
+}
+
+// dbSnapshotDir is where the snapshots of the database are kept
+func dbSnapshotDir() string {
+	return filepath.Join(getAppDir(), "data", "backups")
+}
+
+// ListDbSnapshots returns the snapshots of the database, newest first
+func (a *App) ListDbSnapshots() []DbSnapshot {
+	entries, err := os.ReadDir(dbSnapshotDir())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			a.logger.Error("Error listing database snapshots: %v", err)
+		}
+		return []DbSnapshot{}
+	}
+
+	f := a.formatter()
+	snapshots := []DbSnapshot{}
+	for _, e := range entries {
+		snapshot, ok := parseDbSnapshotName(e.Name())
+		if !ok {
+			continue
+		}
+		if info, err := e.Info(); err == nil {
+			snapshot.Size = info.Size()
+			snapshot.SizeText = f.Bytes(info.Size())
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].Name > snapshots[j].Name })
+	return snapshots
+}
+
+// RestoreDbSnapshot replaces the library database by the snapshot name of
+// ListDbSnapshots. The current database is snapshotted first, so the
+// restore can be undone. Emits "db-snapshot-restored"; the frontend then
+// reloads everything.
+func (a *App) RestoreDbSnapshot(name string) error {
+	if _, ok := parseDbSnapshotName(name); !ok || filepath.Base(name) != name {
+		return fmt.Errorf("invalid snapshot: %s", name)
+	}
+	path := filepath.Join(dbSnapshotDir(), name)
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("snapshot not found: %s", name)
+	}
+
+	if _, err := a.takeDbSnapshot("restore"); err != nil {
+		return fmt.Errorf("failed to snapshot the current database: %w", err)
+	}
+	if err := a.store.Restore(path); err != nil {
+		a.logger.Error("Failed to restore snapshot %s: %v", name, err)
+		return fmt.Errorf("failed to restore the snapshot: %w", err)
+	}
+	a.logger.Info("Restored the database snapshot %s", name)
+	// Apply the restored settings
+	if err := a.SaveSettings(a.store.GetSettings()); err != nil {
+		a.logger.Info("Failed to apply the restored settings: %v", err)
+	}
+	wailsRuntime.EventsEmit(a.ctx, "db-snapshot-restored", name)
+	return nil
+}
+
+// takeDbSnapshot copies the database to data/backups with the backup API
+// and removes the oldest snapshots of the same reason past dbSnapshotKeep
+func (a *App) takeDbSnapshot(reason string) (DbSnapshot, error) {
+	dir := dbSnapshotDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return DbSnapshot{}, err
+	}
+	now := time.Now()
+	name := fmt.Sprintf("haya-tab-%s-%s.db", now.Format(dbSnapshotLayout), reason)
+	path := filepath.Join(dir, name)
+	// Written under a hidden name, so an interrupted copy is never listed
+	tmp := filepath.Join(dir, "."+name+".tmp")
+	os.Remove(tmp)
+	if err := a.store.Backup(tmp); err != nil {
+		os.Remove(tmp)
+		return DbSnapshot{}, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return DbSnapshot{}, err
+	}
+
+	kept := 0
+	for _, s := range a.ListDbSnapshots() {
+		if s.Reason != reason {
+			continue
+		}
+		if kept++; kept > dbSnapshotKeep {
+			os.Remove(filepath.Join(dir, s.Name))
+		}
+	}
+	a.logger.Info("Database snapshot %s taken", name)
+	return DbSnapshot{Name: name, Reason: reason, CreatedAt: now.Unix()}, nil
+}
+
+// runDbSnapshot takes the daily snapshot of the database when the last one
+// is older than dbSnapshotInterval
+func (a *App) runDbSnapshot() {
+	for _, s := range a.ListDbSnapshots() {
+		if s.Reason == "daily" {
+			if time.Since(time.Unix(s.CreatedAt, 0)) < dbSnapshotInterval {
+				return
+			}
+			break
+		}
+	}
+	if _, err := a.takeDbSnapshot("daily"); err != nil {
+		a.logger.Error("Failed to take the daily database snapshot: %v", err)
+	}
+}
+
+// parseDbSnapshotName returns the snapshot named name, without its size
+func parseDbSnapshotName(name string) (DbSnapshot, bool) {
+	rest, ok := strings.CutPrefix(name, "haya-tab-")
+	if !ok {
+		return DbSnapshot{}, false
+	}
+	rest, ok = strings.CutSuffix(rest, ".db")
+	if !ok || len(rest) < len(dbSnapshotLayout)+2 || rest[len(dbSnapshotLayout)] != '-' {
+		return DbSnapshot{}, false
+	}
+	created, err := time.ParseInLocation(dbSnapshotLayout, rest[:len(dbSnapshotLayout)], time.Local)
+	if err != nil {
+		return DbSnapshot{}, false
+	}
+	return DbSnapshot{Name: name, Reason: rest[len(dbSnapshotLayout)+1:], CreatedAt: created.Unix()}, true
+}
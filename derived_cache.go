@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"haya-tab/pkg/coverpool"
+	"haya-tab/pkg/diskcache"
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// derivedCacheDirs are the directories of derived images, which are
+// generated again when missing. Tab covers, downloaded or chosen, are
+// originals and never in them.
+func derivedCacheDirs() []string {
+	return []string{collageDir()}
+}
+
+// CacheUsage is the disk space taken by images
+type CacheUsage struct {
+	Derived     diskcache.Usage `json:"derived"`     // Images evicted past the limit, e.g. category collages
+	DerivedSize string          `json:"derivedSize"` // Derived.Bytes formatted for the locale
+	LimitMB     int             `json:"limitMB"`     // Settings.CacheLimitMB
+	Covers      diskcache.Usage `json:"covers"`      // Tab covers, never evicted
+	CoversSize  string          `json:"coversSize"`  // Covers.Bytes formatted for the locale
+}
+
+// GetCacheUsage returns the disk space taken by derived images and covers
+func (a *App) GetCacheUsage() CacheUsage {
+	f := a.formatter()
+	derived := diskcache.Measure(derivedCacheDirs()...)
+	all := diskcache.Measure(filepath.Join(getAppDir(), "covers"))
+	usage := CacheUsage{
+		Derived: derived,
+		LimitMB: a.store.GetSettings().CacheLimitMB,
+		// The collages are under the covers directory
+		Covers: diskcache.Usage{Files: all.Files - derived.Files, Bytes: all.Bytes - derived.Bytes},
+	}
+	usage.DerivedSize = f.Bytes(usage.Derived.Bytes)
+	usage.CoversSize = f.Bytes(usage.Covers.Bytes)
+	return usage
+}
+
+// ClearDerivedCache removes all derived images; they are generated again
+// when next shown. Returns the number of files removed.
+func (a *App) ClearDerivedCache() int {
+	removed := diskcache.Clear(derivedCacheDirs()...)
+	a.logger.Info("Cleared %d derived image(s), %s", removed.Files, a.formatter().Bytes(removed.Bytes))
+	return removed.Files
+}
+
+// applyCacheLimit removes the least recently used derived images past
+// Settings.CacheLimitMB
+func (a *App) applyCacheLimit() {
+	limitMB := a.store.GetSettings().CacheLimitMB
+	if limitMB <= 0 {
+		return
+	}
+	removed := diskcache.Evict(int64(limitMB)<<20, derivedCacheDirs()...)
+	if removed.Files > 0 {
+		a.logger.Info("Evicted %d derived image(s), %s, to stay under %d MB", removed.Files, a.formatter().Bytes(removed.Bytes), limitMB)
+	}
+}
+
+// CompactCovers re-encodes the JPEG covers already downloaded with
+// Settings.CoverMaxSize and Settings.CoverJPEGQuality, as new covers are.
+// Covers that would not get smaller are left alone. Returns the number of
+// covers re-encoded and the bytes saved.
+func (a *App) CompactCovers() (diskcache.Usage, error) {
+	s := a.store.GetSettings()
+	enc := coverpool.Encoding{MaxSize: s.CoverMaxSize, Quality: s.CoverJPEGQuality}
+	if !enc.Enabled() {
+		return diskcache.Usage{}, fmt.Errorf("set a maximum size or a quality for covers first")
+	}
+
+	var saved diskcache.Usage
+	collages := collageDir()
+	filepath.WalkDir(filepath.Join(getAppDir(), "covers"), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if path == collages {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if ext := strings.ToLower(filepath.Ext(path)); ext != ".jpg" && ext != ".jpeg" {
+			return nil
+		}
+		n, err := coverpool.Reencode(path, enc)
+		if err != nil {
+			a.logger.Error("Failed to re-encode cover %s: %v", filepath.Base(path), err)
+			return nil
+		}
+		if n > 0 {
+			saved.Files++
+			saved.Bytes += n
+		}
+		return nil
+	})
+	a.logger.Info("Re-encoded %d cover(s), saving %s", saved.Files, a.formatter().Bytes(saved.Bytes))
+	return saved, nil
+}
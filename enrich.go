@@ -0,0 +1,51 @@
+package main
+
+import (
+	"haya-tab/pkg/enrich"
+	"haya-tab/pkg/metadata"
+)
+
+// applyEnrichment sets up the metadata enrichment provider of the settings.
+// Switching to another provider looks all tabs up again.
+func (a *App) applyEnrichment() {
+	s := a.store.GetSettings()
+	cfg := enrich.Config{
+		Provider:            s.EnrichProvider,
+		LastFMAPIKey:        s.LastFMAPIKey,
+		SpotifyClientID:     s.SpotifyClientID,
+		SpotifyClientSecret: s.SpotifySecret,
+	}
+
+	a.enrichMu.Lock()
+	defer a.enrichMu.Unlock()
+	if cfg == a.enrichConfig {
+		return
+	}
+	previous := a.enrichConfig.Provider
+	a.enrichConfig = cfg
+
+	p, err := enrich.New(cfg)
+	if err != nil {
+		a.enricher = nil
+		a.logger.Error("Metadata enrichment disabled: %v", err)
+		return
+	}
+	a.enricher = p
+	if p != nil && previous != "" && previous != cfg.Provider {
+		if err := a.store.ResetEnrichment(); err != nil {
+			a.logger.Info("Failed to reset the metadata enrichment: %v", err)
+		}
+	}
+}
+
+// runEnrichment looks up some tabs lacking an album, genre or year when a
+// provider is set, unless background jobs are paused or offline mode is on
+func (a *App) runEnrichment() {
+	a.enrichMu.Lock()
+	p := a.enricher
+	a.enrichMu.Unlock()
+	if p == nil || a.jobPool.IsPaused() || metadata.Offline() {
+		return
+	}
+	a.syncService.EnrichTabs(p)
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"haya-tab/pkg/store"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// tabSidecar is the metadata of an exported tab, written next to its file
+// as <file>.meta.json
+type tabSidecar struct {
+	Title          string   `json:"title"`
+	Artist         string   `json:"artist"`
+	Album          string   `json:"album"`
+	Type           string   `json:"type"`
+	Tag            string   `json:"tag"`
+	Key            string   `json:"key,omitempty"`
+	Capo           int      `json:"capo,omitempty"`
+	Rating         int      `json:"rating,omitempty"`
+	Difficulty     string   `json:"difficulty,omitempty"`
+	PracticeStatus string   `json:"practiceStatus,omitempty"`
+	Country        string   `json:"country,omitempty"`
+	Language       string   `json:"language,omitempty"`
+	Categories     []string `json:"categories"`      // Category paths, e.g. "Rock/Beatles"
+	Cover          string   `json:"cover,omitempty"` // File name of the exported cover
+	Added          string   `json:"added"`           // Formatted for the locale
+}
+
+// ExportTabBundle copies a tab file to destFolder, named after the tab,
+// with its cover as <file>.cover.<ext>, its metadata, tag and categories
+// in a <file>.meta.json sidecar and its links as ExportTab does. Returns
+// the path of the exported file.
+func (a *App) ExportTabBundle(id, destFolder string) (string, error) {
+	tab, err := a.store.GetTab(id)
+	if err != nil {
+		return "", fmt.Errorf("failed to get tab: %w", err)
+	}
+	if tab == nil {
+		return "", fmt.Errorf("tab not found")
+	}
+	categories, err := a.store.GetCategories()
+	if err != nil {
+		return "", fmt.Errorf("failed to get categories: %w", err)
+	}
+	return a.exportTabBundle(*tab, destFolder, categoryPaths(categories))
+}
+
+// BatchExport exports tabs to destFolder as by ExportTabBundle. Tabs that
+// fail are skipped; returns the number exported, and an error listing the
+// failures if any.
+func (a *App) BatchExport(ids []string, destFolder string) (int, error) {
+	categories, err := a.store.GetCategories()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get categories: %w", err)
+	}
+	paths := categoryPaths(categories)
+
+	exported := 0
+	var failed []string
+	for _, id := range ids {
+		tab, err := a.store.GetTab(id)
+		if err != nil || tab == nil {
+			failed = append(failed, id)
+			continue
+		}
+		if _, err := a.exportTabBundle(*tab, destFolder, paths); err != nil {
+			a.logger.Info("Failed to export %s: %v", tab.Title, err)
+			failed = append(failed, tab.Title)
+			continue
+		}
+		exported++
+	}
+	if len(failed) > 0 {
+		return exported, fmt.Errorf("failed to export %d tab(s): %s", len(failed), strings.Join(failed, ", "))
+	}
+	return exported, nil
+}
+
+// ExportCategory exports the tabs of a category to a new folder in
+// destFolder named after it, each as by ExportTabBundle, with a subfolder
+// per subcategory. Returns the path of the folder.
+func (a *App) ExportCategory(categoryID, destFolder string) (string, error) {
+	categories, err := a.store.GetCategories()
+	if err != nil {
+		return "", fmt.Errorf("failed to get categories: %w", err)
+	}
+	tabs, err := a.store.GetTabs()
+	if err != nil {
+		return "", fmt.Errorf("failed to get tabs: %w", err)
+	}
+
+	var root *store.Category
+	children := map[string][]store.Category{}
+	for i, c := range categories {
+		children[c.ParentID] = append(children[c.ParentID], c)
+		if c.ID == categoryID {
+			root = &categories[i]
+		}
+	}
+	if root == nil {
+		return "", fmt.Errorf("category not found: %s", categoryID)
+	}
+	tabsOf := map[string][]store.Tab{}
+	for _, t := range tabs {
+		for _, id := range t.CategoryIDs {
+			tabsOf[id] = append(tabsOf[id], t)
+		}
+	}
+	paths := categoryPaths(categories)
+
+	exported := 0
+	var export func(c store.Category, dir string) (string, error)
+	export = func(c store.Category, dir string) (string, error) {
+		folder := uniquePath(dir, sanitizeFileName(c.Name), "")
+		if err := os.MkdirAll(folder, 0755); err != nil {
+			return "", fmt.Errorf("failed to create folder: %w", err)
+		}
+		for _, t := range tabsOf[c.ID] {
+			if _, err := a.exportTabBundle(t, folder, paths); err != nil {
+				return "", fmt.Errorf("failed to export %s: %w", t.Title, err)
+			}
+			exported++
+		}
+		for _, child := range children[c.ID] {
+			if _, err := export(child, folder); err != nil {
+				return "", err
+			}
+		}
+		return folder, nil
+	}
+
+	folder, err := export(*root, destFolder)
+	if err != nil {
+		return "", err
+	}
+	a.logger.Info("Exported %d tab(s) of %s to %s", exported, root.Name, folder)
+	return folder, nil
+}
+
+// exportTabBundle writes the file, cover and sidecars of a tab to
+// destFolder. paths maps category IDs to their paths (see categoryPaths).
+func (a *App) exportTabBundle(tab store.Tab, destFolder string, paths map[string]string) (string, error) {
+	name := sanitizeFileName(tab.Title)
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(tab.FilePath), filepath.Ext(tab.FilePath))
+	}
+	destPath := uniquePath(destFolder, name, filepath.Ext(tab.FilePath))
+	if err := a.exportTabFile(tab, destPath); err != nil {
+		return "", fmt.Errorf("failed to copy file: %w", err)
+	}
+
+	f := a.formatter()
+	sidecar := tabSidecar{
+		Title:          tab.Title,
+		Artist:         tab.Artist,
+		Album:          tab.Album,
+		Type:           tab.Type,
+		Tag:            tab.Tag,
+		Key:            tab.Key,
+		Capo:           tab.Capo,
+		Rating:         tab.Rating,
+		Difficulty:     tab.Difficulty,
+		PracticeStatus: tab.PracticeStatus,
+		Country:        tab.Country,
+		Language:       tab.Language,
+		Categories:     []string{},
+		Added:          f.Date(tab.AddedAt),
+	}
+	for _, id := range tab.CategoryIDs {
+		if p, ok := paths[id]; ok {
+			sidecar.Categories = append(sidecar.Categories, p)
+		}
+	}
+
+	if tab.CoverPath != "" {
+		coverPath := destPath + ".cover" + filepath.Ext(tab.CoverPath)
+		if err := copyFile(tab.CoverPath, coverPath); err != nil {
+			// The bundle is still usable without its cover
+			a.logger.Info("Failed to export the cover of %s: %v", tab.Title, err)
+		} else {
+			sidecar.Cover = filepath.Base(coverPath)
+		}
+	}
+
+	data, err := json.MarshalIndent(sidecar, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(destPath+".meta.json", data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write metadata: %w", err)
+	}
+	if err := a.exportTabLinks(tab.ID, destPath); err != nil {
+		a.logger.Info("Failed to export links of %s: %v", tab.Title, err)
+	}
+	return destPath, nil
+}
+
+// categoryPaths maps the ID of each category to its path from the root,
+// e.g. "Rock/Beatles"
+func categoryPaths(categories []store.Category) map[string]string {
+	byID := make(map[string]store.Category, len(categories))
+	for _, c := range categories {
+		byID[c.ID] = c
+	}
+	paths := make(map[string]string, len(categories))
+	for _, c := range categories {
+		names := []string{c.Name}
+		seen := map[string]bool{c.ID: true}
+		for parent, ok := byID[c.ParentID]; ok && !seen[parent.ID]; parent, ok = byID[parent.ParentID] {
+			seen[parent.ID] = true
+			names = append([]string{parent.Name}, names...)
+		}
+		paths[c.ID] = strings.Join(names, "/")
+	}
+	return paths
+}
@@ -1,3 +1,7 @@
 export { useToast } from './useToast'
 export { useContextMenu } from './useContextMenu'
 export { useDragDrop } from './useDragDrop'
+export { usePrintLayout } from './usePrintLayout'
+export { usePdfThumbnails } from './usePdfThumbnails'
+export { usePracticeTimer } from './usePracticeTimer'
+export { useFileServer } from './useFileServer'
@@ -0,0 +1,17 @@
+// Port and session token of the local file server, fetched once
+let server: Promise<{ port: number; token: string }> | null = null
+
+export function useFileServer() {
+  // Returns the URL of a file server path such as '/api/file/{id}'. Tab
+  // files, covers and attachments need the token of the session.
+  async function fileServerUrl(path: string): Promise<string> {
+    server ??= Promise.all([
+      window.go.main.App.GetFileServerPort(),
+      window.go.main.App.GetFileServerToken()
+    ]).then(([port, token]) => ({ port, token }))
+    const { port, token } = await server
+    return `http://127.0.0.1:${port}${path}?token=${encodeURIComponent(token)}`
+  }
+
+  return { fileServerUrl }
+}
@@ -0,0 +1,64 @@
+import { useFileServer } from './useFileServer'
+
+// PDF.js is shipped in public/pdfjs for the viewer; the same build is loaded
+// here on demand to render page thumbnails
+let pdfjsLoading: Promise<any> | null = null
+
+function loadPdfJs(): Promise<any> {
+  if ((window as any).pdfjsLib) {
+    return Promise.resolve((window as any).pdfjsLib)
+  }
+  if (!pdfjsLoading) {
+    pdfjsLoading = new Promise((resolve, reject) => {
+      const script = document.createElement('script')
+      script.src = 'pdfjs/build/pdf.js'
+      script.onload = () => {
+        const lib = (window as any).pdfjsLib
+        lib.GlobalWorkerOptions.workerSrc = 'pdfjs/build/pdf.worker.js'
+        resolve(lib)
+      }
+      script.onerror = () => {
+        pdfjsLoading = null
+        reject(new Error('Failed to load PDF.js'))
+      }
+      document.head.appendChild(script)
+    })
+  }
+  return pdfjsLoading
+}
+
+export function usePdfThumbnails() {
+  const { fileServerUrl } = useFileServer()
+  let doc: any = null
+
+  // Opens the PDF of a tab and returns its page count
+  async function open(tabId: string): Promise<number> {
+    const lib = await loadPdfJs()
+    doc = await lib.getDocument(await fileServerUrl(`/api/file/${tabId}`)).promise
+    return doc.numPages
+  }
+
+  // Renders a page (1-based) into canvas, scaled to width pixels
+  async function render(pageNumber: number, canvas: HTMLCanvasElement, width = 120) {
+    if (!doc) return
+    const page = await doc.getPage(pageNumber)
+    const unscaled = page.getViewport({ scale: 1 })
+    const viewport = page.getViewport({ scale: width / unscaled.width })
+    canvas.width = Math.floor(viewport.width)
+    canvas.height = Math.floor(viewport.height)
+    await page.render({ canvasContext: canvas.getContext('2d'), viewport }).promise
+  }
+
+  function close() {
+    if (doc) {
+      doc.destroy()
+      doc = null
+    }
+  }
+
+  return {
+    open,
+    render,
+    close
+  }
+}
@@ -0,0 +1,45 @@
+import { watch, onUnmounted } from 'vue'
+import { useUIStore } from '@/stores'
+
+// Shorter periods are browsing rather than practice and are not recorded
+const MIN_PRACTICE_SECONDS = 60
+
+// usePracticeTimer records the time tabs are open in a viewer while the
+// window is visible, for the year review
+export function usePracticeTimer() {
+  const uiStore = useUIStore()
+  let tabId = ''
+  let startedAt = 0
+
+  function viewedTab(): string {
+    const view = uiStore.currentView
+    if (document.hidden || !(view.startsWith('pdf-') || view.startsWith('gp-'))) return ''
+    return view.slice(view.indexOf('-') + 1)
+  }
+
+  function flush() {
+    if (tabId) {
+      const seconds = Math.round((Date.now() - startedAt) / 1000)
+      if (seconds >= MIN_PRACTICE_SECONDS) {
+        window.go.main.App.RecordPracticeTime(tabId, Math.floor(startedAt / 1000), seconds)
+          .catch(err => console.warn('Failed to record practice time:', err))
+      }
+    }
+    tabId = viewedTab()
+    startedAt = Date.now()
+  }
+
+  function handleVisibilityChange() {
+    if (viewedTab() !== tabId) flush()
+  }
+
+  watch(() => uiStore.currentView, flush, { immediate: true })
+  document.addEventListener('visibilitychange', handleVisibilityChange)
+  window.addEventListener('beforeunload', flush)
+
+  onUnmounted(() => {
+    document.removeEventListener('visibilitychange', handleVisibilityChange)
+    window.removeEventListener('beforeunload', flush)
+    flush()
+  })
+}
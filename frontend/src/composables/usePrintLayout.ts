@@ -0,0 +1,61 @@
+import type { PrintSettings } from '@/types'
+
+// Paper sizes in millimetres (portrait)
+const PAPER_SIZES: Record<string, [number, number]> = {
+  A3: [297, 420],
+  A4: [210, 297],
+  A5: [148, 210],
+  Letter: [216, 279],
+  Legal: [216, 356]
+}
+
+export function usePrintLayout() {
+  async function loadPrintSettings(tabId: string): Promise<PrintSettings | null> {
+    try {
+      return await window.go.main.App.GetPrintSettings(tabId)
+    } catch (e) {
+      console.warn('Failed to load print settings:', e)
+      return null
+    }
+  }
+
+  // Sheet size in mm; 2 pages per sheet print side by side on a landscape sheet
+  function sheetSize(ps: PrintSettings): [number, number] {
+    const [w, h] = PAPER_SIZES[ps.paperSize] || PAPER_SIZES.A4
+    return ps.pagesPerSheet === 2 ? [h, w] : [w, h]
+  }
+
+  // Printable width in mm, used to lay out rendered scores
+  function contentWidth(ps: PrintSettings): number {
+    return sheetSize(ps)[0] - ps.marginLeft - ps.marginRight
+  }
+
+  // CSS applying paper size and margins. When pageSelector is given, the
+  // matching elements (one per page) are laid out pagesPerSheet per sheet.
+  function pageCss(ps: PrintSettings, pageSelector = ''): string {
+    const [w, h] = sheetSize(ps)
+    let css = `@page { size: ${w}mm ${h}mm; margin: ${ps.marginTop}mm ${ps.marginRight}mm ${ps.marginBottom}mm ${ps.marginLeft}mm; }`
+    if (!pageSelector || ps.pagesPerSheet <= 1) return css
+
+    const perSheet = ps.pagesPerSheet
+    const height = perSheet === 4 ? '50%' : '100%'
+    css += `
+      ${pageSelector} {
+        display: inline-flex !important;
+        width: 50% !important;
+        height: ${height} !important;
+        vertical-align: top;
+        page-break-after: auto !important;
+      }
+      ${pageSelector}:nth-child(${perSheet}n) {
+        page-break-after: always !important;
+      }`
+    return css
+  }
+
+  return {
+    loadPrintSettings,
+    contentWidth,
+    pageCss
+  }
+}
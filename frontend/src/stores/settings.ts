@@ -1,6 +1,6 @@
 import { defineStore } from 'pinia'
 import { ref } from 'vue'
-import type { Settings } from '@/types'
+import type { Settings, SyncPath } from '@/types'
 
 export const useSettingsStore = defineStore('settings', () => {
   // State
@@ -11,11 +11,48 @@ export const useSettingsStore = defineStore('settings', () => {
     openMethod: 'inner',
     openGpMethod: 'inner',
     audioDevice: 'default',
-    syncPaths: [],
     syncStrategy: 'skip',
+    syncZipArchives: false,
+    converterCommand: '',
+    converterExts: '',
+    converterFormat: 'pdf',
     autoSyncEnabled: false,
     autoSyncFrequency: 'startup',
     lastSyncTime: 0,
+    locale: '',
+    coverDailyBudget: 500,
+    coverRefreshMonths: 0,
+    coverMaxSize: 0,
+    coverJpegQuality: 0,
+    cacheLimitMB: 256,
+    offlineMode: false,
+    httpProxy: '',
+    httpTimeout: 0,
+    tlsCaFile: '',
+    tlsSkipVerify: false,
+    enrichProvider: '',
+    lastfmApiKey: '',
+    spotifyClientId: '',
+    spotifySecret: '',
+    cloudBackupHours: 0,
+    cloudEndpoint: '',
+    cloudRegion: '',
+    cloudBucket: '',
+    cloudPrefix: '',
+    cloudAccessKey: '',
+    cloudSecretKey: '',
+    lastCloudBackup: 0,
+    maintenanceDays: 0,
+    lastMaintenance: 0,
+    lanServerEnabled: false,
+    lanServerPort: 0,
+    lanServerToken: '',
+    fuzzySearch: false,
+    sharedLibraryPath: '',
+    keyProfile: 'Default',
+    pedalEnabled: false,
+    pedalBindings: {},
+    pedalProfiles: [],
     keyBindings: {
       scrollDown: 'j',
       scrollUp: 'k',
@@ -34,6 +71,7 @@ export const useSettingsStore = defineStore('settings', () => {
     }
   })
 
+  const syncPaths = ref<SyncPath[]>([])
   const loading = ref(false)
 
   // Actions
@@ -46,13 +84,15 @@ export const useSettingsStore = defineStore('settings', () => {
           ...settings.value,
           ...loaded,
           audioDevice: loaded.audioDevice || 'default',
-          syncPaths: loaded.syncPaths || [],
+          pedalBindings: loaded.pedalBindings || {},
+          pedalProfiles: loaded.pedalProfiles || [],
           keyBindings: {
             ...settings.value.keyBindings,
             ...(loaded.keyBindings || {})
           }
         }
       }
+      syncPaths.value = await window.go.main.App.GetSyncPaths()
       applyTheme()
       await applyBackground()
     } catch (err) {
@@ -76,6 +116,12 @@ export const useSettingsStore = defineStore('settings', () => {
     }
   }
 
+  // Offline mode applies at once, without saving the other settings
+  async function setOfflineMode(offline: boolean) {
+    await window.go.main.App.SetOfflineMode(offline)
+    settings.value.offlineMode = offline
+  }
+
   function applyTheme() {
     const theme = settings.value.theme
     if (theme === 'light') {
@@ -116,14 +162,23 @@ export const useSettingsStore = defineStore('settings', () => {
     }
   }
 
-  function addSyncPath(path: string) {
-    if (!settings.value.syncPaths.includes(path)) {
-      settings.value.syncPaths.push(path)
+  // Sync paths are saved at once, without saving the other settings
+  async function addSyncPath(path: string) {
+    const added = await window.go.main.App.AddSyncPath(path)
+    syncPaths.value.push(added)
+  }
+
+  async function updateSyncPath(path: SyncPath) {
+    await window.go.main.App.UpdateSyncPath(path)
+    const index = syncPaths.value.findIndex(p => p.path === path.path)
+    if (index >= 0) {
+      syncPaths.value[index] = path
     }
   }
 
-  function removeSyncPath(index: number) {
-    settings.value.syncPaths.splice(index, 1)
+  async function removeSyncPath(path: string) {
+    await window.go.main.App.RemoveSyncPath(path)
+    syncPaths.value = syncPaths.value.filter(p => p.path !== path)
   }
 
   async function triggerSync() {
@@ -142,12 +197,15 @@ export const useSettingsStore = defineStore('settings', () => {
 
   return {
     settings,
+    syncPaths,
     loading,
     loadSettings,
     saveSettings,
+    setOfflineMode,
     applyTheme,
     applyBackground,
     addSyncPath,
+    updateSyncPath,
     removeSyncPath,
     triggerSync
   }
@@ -1,11 +1,12 @@
 import { defineStore } from 'pinia'
 import { ref, computed } from 'vue'
-import type { Tab, Category, TabsResponse } from '@/types'
+import type { Tab, Category, CategoryStats, TabsResponse, TabFilters, Operation } from '@/types'
 
 export const useTabsStore = defineStore('tabs', () => {
   // State
   const tabs = ref<Tab[]>([])
   const categories = ref<Category[]>([])
+  const categoryStats = ref<Record<string, CategoryStats>>({})
   const recentCategories = ref<Category[]>([])
   const recentTabs = ref<Tab[]>([])
   const currentCategoryId = ref('')
@@ -23,6 +24,9 @@ export const useTabsStore = defineStore('tabs', () => {
   const searchScope = ref<'global' | 'local'>('local')
   const sortBy = ref('title')
   const sortDesc = ref(false)
+  const tabFilters = ref<TabFilters>({ minRating: 0, difficulties: [] })
+  const didYouMean = ref('') // Corrected query of a search finding nothing
+  const searchCorrected = ref(false) // The tabs are those of didYouMean
 
   // Batch selection state
   const isBatchSelectMode = ref(false)
@@ -74,14 +78,19 @@ export const useTabsStore = defineStore('tabs', () => {
         searchFilters.value,
         searchScope.value === 'global',
         sortBy.value,
-        sortDesc.value
+        sortDesc.value,
+        tabFilters.value
       )
       tabs.value = response.tabs
       pagination.value.total = response.total
       pagination.value.hasMore = response.hasMore
+      didYouMean.value = response.didYouMean || ''
+      searchCorrected.value = !!response.corrected
     } catch (err) {
       console.error('Error fetching paginated tabs:', err)
       tabs.value = []
+      didYouMean.value = ''
+      searchCorrected.value = false
     } finally {
       loading.value = false
     }
@@ -101,7 +110,8 @@ export const useTabsStore = defineStore('tabs', () => {
         searchFilters.value,
         searchScope.value === 'global',
         sortBy.value,
-        sortDesc.value
+        sortDesc.value,
+        tabFilters.value
       )
       tabs.value = [...tabs.value, ...response.tabs]
       pagination.value.hasMore = response.hasMore
@@ -142,6 +152,12 @@ export const useTabsStore = defineStore('tabs', () => {
     fetchTabsPaginated()
   }
 
+  function setTabFilters(filters: TabFilters) {
+    tabFilters.value = filters
+    pagination.value.page = 1
+    fetchTabsPaginated()
+  }
+
   async function fetchCategories() {
     try {
       categories.value = await window.go.main.App.GetCategories() || []
@@ -149,6 +165,16 @@ export const useTabsStore = defineStore('tabs', () => {
       console.error('Error fetching categories:', err)
       categories.value = []
     }
+    await fetchCategoryStats()
+  }
+
+  async function fetchCategoryStats() {
+    try {
+      const stats = await window.go.main.App.GetCategoryStats() || []
+      categoryStats.value = Object.fromEntries(stats.map(s => [s.categoryId, s]))
+    } catch (err) {
+      console.error('Error fetching category stats:', err)
+    }
   }
 
   async function fetchRecentCategories(limit: number) {
@@ -228,6 +254,26 @@ export const useTabsStore = defineStore('tabs', () => {
     return moved
   }
 
+  // Creates a category under parentId and adds the selected tabs to it, in one transaction
+  async function batchAddTabsToNewCategory(name: string, parentId: string) {
+    if (selectedTabIds.value.size === 0) return 0
+    const ids = Array.from(selectedTabIds.value)
+    await applyOperations([
+      { op: 'createCategory', name, parentId },
+      { op: 'addTabsToCategory', categoryId: '$1', tabIds: ids },
+    ])
+    exitBatchSelectMode()
+    return ids.length
+  }
+
+  async function applyOperations(ops: Operation[]) {
+    try {
+      return await window.go.main.App.ApplyOperations(ops)
+    } finally {
+      await refreshData()
+    }
+  }
+
   async function batchAddTabsToCategory(categoryId: string) {
     if (selectedTabIds.value.size === 0) return 0
     const ids = Array.from(selectedTabIds.value)
@@ -253,6 +299,26 @@ export const useTabsStore = defineStore('tabs', () => {
     await fetchCategories()
   }
 
+  // Moves a category offset places among its siblings, within the pinned
+  // or the unpinned ones
+  async function reorderCategory(id: string, offset: number) {
+    const category = categories.value.find(c => c.id === id)
+    if (!category) return
+    const siblings = categories.value.filter(c => c.parentId === category.parentId)
+    const from = siblings.indexOf(category)
+    const to = from + offset
+    if (to < 0 || to >= siblings.length || !!siblings[to].pinned !== !!category.pinned) return
+    siblings.splice(from, 1)
+    siblings.splice(to, 0, category)
+    await window.go.main.App.ReorderCategories(siblings.map(c => c.id))
+    await fetchCategories()
+  }
+
+  async function setCategoryPinned(id: string, pinned: boolean) {
+    await window.go.main.App.SetCategoryPinned(id, pinned)
+    await fetchCategories()
+  }
+
   function navigateToCategory(categoryId: string) {
     currentCategoryId.value = categoryId
     pagination.value.page = 1
@@ -323,6 +389,7 @@ export const useTabsStore = defineStore('tabs', () => {
     // State
     tabs,
     categories,
+    categoryStats,
     recentCategories,
     recentTabs,
     currentCategoryId,
@@ -335,6 +402,9 @@ export const useTabsStore = defineStore('tabs', () => {
     searchScope,
     sortBy,
     sortDesc,
+    tabFilters,
+    didYouMean,
+    searchCorrected,
 
     // Getters
     currentTabs,
@@ -350,7 +420,9 @@ export const useTabsStore = defineStore('tabs', () => {
     setSearchFilters,
     setSearchScope,
     setSort,
+    setTabFilters,
     fetchCategories,
+    fetchCategoryStats,
     fetchRecentCategories,
     fetchRecentTabs,
     refreshData,
@@ -362,10 +434,14 @@ export const useTabsStore = defineStore('tabs', () => {
     removeTabFromCategory,
     batchDeleteTabs,
     batchMoveTabs,
+    batchAddTabsToNewCategory,
+    applyOperations,
     batchAddTabsToCategory,
     addCategory,
     deleteCategory,
     moveCategory,
+    reorderCategory,
+    setCategoryPinned,
     navigateToCategory,
     goHome,
     goBack,
@@ -14,11 +14,18 @@ export const useUIStore = defineStore('ui', () => {
   const batchMoveModalVisible = ref(false)
   const confirmModalVisible = ref(false)
   const keyBindingsModalVisible = ref(false)
+  const splitModalVisible = ref(false)
+  const mergeModalVisible = ref(false)
+  const assignModalVisible = ref(false)
+  const reminderModalVisible = ref(false)
 
   // Modal data
   const editModalData = ref<any>(null)
   const categoryModalData = ref<any>(null)
   const moveModalTabId = ref('')
+  const splitModalTabId = ref('')
+  const assignModalTabId = ref('')
+  const reminderModalTarget = ref<{ tabId: string, categoryId: string, name: string }>({ tabId: '', categoryId: '', name: '' })
   const confirmModalData = ref<{
     title: string
     message: string
@@ -72,6 +79,44 @@ export const useUIStore = defineStore('ui', () => {
     moveModalTabId.value = ''
   }
 
+  function showSplitModal(tabId: string) {
+    splitModalTabId.value = tabId
+    splitModalVisible.value = true
+  }
+
+  function hideSplitModal() {
+    splitModalVisible.value = false
+    splitModalTabId.value = ''
+  }
+
+  function showMergeModal() {
+    mergeModalVisible.value = true
+  }
+
+  function hideMergeModal() {
+    mergeModalVisible.value = false
+  }
+
+  function showAssignModal(tabId: string) {
+    assignModalTabId.value = tabId
+    assignModalVisible.value = true
+  }
+
+  function hideAssignModal() {
+    assignModalVisible.value = false
+    assignModalTabId.value = ''
+  }
+
+  // Schedules practice reminders for a tab, or a category if tabId is ''
+  function showReminderModal(tabId: string, categoryId: string, name: string) {
+    reminderModalTarget.value = { tabId, categoryId, name }
+    reminderModalVisible.value = true
+  }
+
+  function hideReminderModal() {
+    reminderModalVisible.value = false
+  }
+
   function showBatchMoveModal() {
     batchMoveModalVisible.value = true
   }
@@ -126,9 +171,16 @@ export const useUIStore = defineStore('ui', () => {
     batchMoveModalVisible,
     confirmModalVisible,
     keyBindingsModalVisible,
+    splitModalVisible,
+    mergeModalVisible,
+    assignModalVisible,
+    reminderModalVisible,
     editModalData,
     categoryModalData,
     moveModalTabId,
+    splitModalTabId,
+    assignModalTabId,
+    reminderModalTarget,
     confirmModalData,
     contextMenuVisible,
     contextMenuX,
@@ -144,6 +196,14 @@ export const useUIStore = defineStore('ui', () => {
     hideCategoryModal,
     showMoveModal,
     hideMoveModal,
+    showSplitModal,
+    hideSplitModal,
+    showMergeModal,
+    hideMergeModal,
+    showAssignModal,
+    hideAssignModal,
+    showReminderModal,
+    hideReminderModal,
     showBatchMoveModal,
     hideBatchMoveModal,
     showConfirmModal,
@@ -5,7 +5,7 @@ export interface Tab {
   artist: string
   album: string
   filePath: string
-  type: 'pdf' | 'gp' | 'unknown'
+  type: 'pdf' | 'gp' | 'text' | 'chordpro' | 'unknown'
   isManaged: boolean
   coverPath: string
   categoryIds: string[]
@@ -14,6 +14,99 @@ export interface Tab {
   tag: string
   addedAt: number
   lastOpened: number
+  rating: number
+  difficulty: '' | 'beginner' | 'intermediate' | 'advanced'
+  needsReview?: boolean // Imported from the inbox, metadata not confirmed yet
+  key?: string // Key of a chord sheet or GP file, e.g. "G" or "Em"
+  capo?: number // Capo fret of a chord sheet, 0 for none
+  bpm?: number // Initial tempo of a GP file, 0 if unknown
+  duration?: number // Estimated playing time of a GP file in seconds, 0 if unknown
+  script?: '' | 'latin' | 'cyrillic' | 'cjk' // Writing system of title and artist, detected by the backend
+  genre?: string // e.g. 'Classic Rock'; empty if unknown
+  year?: number // Release year, 0 if unknown
+  fileSize?: number // Size of the file in bytes, 0 if unknown
+  coverColor?: string // Dominant color of the cover as '#rrggbb', empty if none
+  credits?: TabCredits // Of GP files; empty in lists, see GetTabCredits
+  sourcePath?: string // File the managed file was converted from on import; empty if not converted
+  shared?: boolean // Of the shared library, which is read-only
+}
+
+// TabCredits are the credits written in the header of a Guitar Pro file
+export interface TabCredits {
+  words: string // Lyricist; GP3 and GP4 files credit words and music together here
+  music: string // Composer
+  copyright: string
+  tabAuthor: string // Who transcribed the tab
+  notice: string // Lines of the notice of the file
+}
+
+// TabFilters narrows the results of GetTabsPaginated
+export interface TabFilters {
+  minRating: number
+  difficulties: string[]
+  needsReview?: boolean
+  scripts?: string[] // Writing systems of title and artist, e.g. ['cjk']
+  genres?: string[] // Case-insensitive, e.g. ['Classic Rock']
+  keys?: string[] // Case-insensitive, e.g. ['G', 'Em']
+  tunings?: string[] // Tabs with a track in one of these tunings, e.g. ['Drop D']
+  minBpm?: number // 0 for no bound
+  maxBpm?: number // 0 for no bound; tabs of unknown tempo are left out
+  minDuration?: number // Seconds, 0 for no bound
+  maxDuration?: number // Seconds, 0 for no bound; tabs of unknown length are left out
+}
+
+// PrintSettings are the print layout preferences of a tab
+export interface PrintSettings {
+  tabId: string
+  paperSize: 'A4' | 'A3' | 'A5' | 'Letter' | 'Legal'
+  marginTop: number
+  marginRight: number
+  marginBottom: number
+  marginLeft: number
+  pagesPerSheet: 1 | 2 | 4
+  tracks: number[]
+}
+
+// PDFExportOptions are the options of ExportTabAsPDF
+export interface PDFExportOptions {
+  destFolder: string
+  layout?: PrintSettings // The saved print settings of the tab if omitted
+}
+
+// PDFRenderRequest asks for the pages of a GP tab to be rendered for a PDF
+// export, answered with CompletePDFRender
+export interface PDFRenderRequest {
+  id: string
+  tabId: string
+  tracks: number[] // Empty for all
+  contentWidth: number // Size of a page inside the margins, in millimetres
+  contentHeight: number
+  scale: number // Pixels per CSS pixel of the rendered pages
+}
+
+// Attachment is an audio file attached to a tab, e.g. a backing track
+export interface Attachment {
+  id: number
+  tabId: string
+  name: string
+  filePath: string
+  addedAt: number
+}
+
+// TabLink is a reference URL of a tab, e.g. a video lesson or the original song
+export interface TabLink {
+  id: number
+  tabId: string
+  title: string
+  url: string
+  addedAt: number
+}
+
+// PageRange is a part of a PDF to split into its own tab (1-based, inclusive)
+export interface PageRange {
+  from: number
+  to: number
+  title: string
 }
 
 // Category represents a virtual folder for organizing tabs
@@ -23,24 +116,181 @@ export interface Category {
   parentId: string
   coverPath: string
   effectiveCoverPath?: string
+  openMethod?: string   // Overrides the setting for PDF tabs: 'system', 'inner'; empty to inherit
+  openGpMethod?: string // Overrides the setting for GP tabs, as openMethod
+  color?: string        // '#rrggbb' the folder is drawn with; empty for the theme color
+  icon?: string         // Icon name, e.g. 'music'; empty for a folder
+  sortOrder?: number    // Position among its siblings, see ReorderCategories
+  pinned?: boolean      // Shown before its unpinned siblings
+}
+
+// Operation is one step of a batch applied by ApplyOperations, all or nothing.
+// categoryId and parentId may be '$n' for the category created by operation n (from 1)
+export interface Operation {
+  op: 'createCategory' | 'renameCategory' | 'moveCategory' | 'deleteCategory' | 'moveTabs' |
+    'addTabsToCategory' | 'removeTabsFromCategory' | 'setRating' | 'setPracticeStatus' | 'setDifficulty'
+  categoryId?: string
+  name?: string
+  parentId?: string
+  tabIds?: string[]
+  rating?: number
+  practiceStatus?: string
+  difficulty?: string
+}
+
+// OperationsResult is the outcome of ApplyOperations
+export interface OperationsResult {
+  applied: number       // Number of operations applied: all or none
+  categoryIds: string[] // IDs of the created categories, in order
+  error?: string
+}
+
+// CategoryTreeImport is the outcome of ImportCategoryTree
+export interface CategoryTreeImport {
+  categories: number  // Number of categories created
+  tabs: number        // Number of tabs filed in a category
+  unmatched: string[] // Titles of the tabs not in this library
+}
+
+// CategoryStats summarizes the tabs directly in a category
+export interface CategoryStats {
+  categoryId: string
+  tabs: number
+  types: Record<string, number> // Tabs per type, e.g. 'pdf', 'gp'
+  totalBytes: number
+  totalSize: string // totalBytes formatted for the locale
+  lastActivity: number // Latest tab added, opened or filed into it (Unix timestamp)
+}
+
+// GenreCount is a genre and its number of tabs
+export interface GenreCount {
+  genre: string
+  tabs: number
+}
+
+// SearchSuggestion is a title or artist completing the search box
+export interface SearchSuggestion {
+  value: string
+  field: 'title' | 'artist'
+  tabs: number // Tabs with this title or artist
+}
+
+// LibraryStats summarizes the whole library
+export interface LibraryStats {
+  tabs: number
+  types: Record<string, number> // Tabs per type, e.g. 'pdf', 'gp'
+  topArtists: { artist: string; tabs: number }[] // Most tabs first
+  managedBytes: number // Files copied to app storage
+  managedSize: string
+  linkedBytes: number // Files left in place, e.g. in sync folders
+  linkedSize: string
+  covers: number // Tabs with a cover
+  openedThisMonth: number
+}
+
+// TemplateFolder is a category created by a category template
+export interface TemplateFolder {
+  name: string
+  children: TemplateFolder[] | null
+}
+
+// CategoryTemplate is a folder structure created in one step
+export interface CategoryTemplate {
+  id: string
+  name: string
+  folders: TemplateFolder[]
+  builtIn: boolean
+}
+
+// Student is a pupil of a teacher, with tabs assigned to practice
+export interface Student {
+  id: number
+  name: string
+  notes: string
+  createdAt: number
+}
+
+// AssignmentStatus is the progress of a student on an assigned tab
+export type AssignmentStatus = 'assigned' | 'practicing' | 'done'
+
+// Assignment is a tab assigned to a student
+export interface Assignment {
+  id: number
+  studentId: number
+  tabId: string
+  tabTitle: string
+  tabArtist: string
+  status: AssignmentStatus
+  dueAt: number // Unix timestamp, 0 for no due date
+  note: string
+  assignedAt: number
 }
 
 // Settings represents application settings
-export interface KeyBindings {
-  scrollDown: string
-  scrollUp: string
-  metronome: string
-  playPause: string
-  stop: string
-  bpmPlus: string
-  bpmMinus: string
-  toggleLoop: string
-  clearSelection: string
-  jumpToBar: string
-  jumpToStart: string
-  autoScroll: string
-  scrollSpeedUp: string
-  scrollSpeedDown: string
+// KeyBindings maps action names (see KeyAction) to lower case key values
+export type KeyBindings = Record<string, string>
+
+// KeyAction is an action that can be bound to a key
+export interface KeyAction {
+  name: string
+  label: string
+  defaultKey: string
+}
+
+// KeyBindingProfile is a named set of key bindings; the active one is
+// Settings.keyProfile
+export interface KeyBindingProfile {
+  name: string
+  keyBindings: KeyBindings
+  updatedAt: number
+  active: boolean
+}
+
+// PedalBindings maps foot controller pedals (PedalPress.control) to action
+// names
+export type PedalBindings = Record<string, string>
+
+// PedalProfile binds the pedals of the controllers whose name contains
+// device (any controller when empty)
+export interface PedalProfile {
+  name: string
+  device: string
+  bindings: PedalBindings
+}
+
+// PedalDevice is a connected foot controller
+export interface PedalDevice {
+  name: string
+  path: string
+  kind: 'midi' | 'hid'
+}
+
+// PedalPress is a pedal pressed on a foot controller
+export interface PedalPress {
+  device: string
+  control: string // e.g. "cc:64", "note:60", "pc:2" or "key:109"
+}
+
+// KeyBindingConflict is a key rejected when saving key bindings
+export interface KeyBindingConflict {
+  key: string
+  actions: string[]
+  reason: 'duplicate' | 'reserved'
+}
+
+// How the folders under a sync path become categories of new tabs: one
+// per folder level, the first folder only, or the folder holding the file
+export type FolderCategoryStrategy = 'nested' | 'top' | 'parent'
+
+// SyncPath is a folder scanned by syncs and watched for changes
+export interface SyncPath {
+  path: string
+  recursive: boolean // Scan subfolders too
+  mode: 'link' | 'copy' // Keep tabs on their file, or copy files once to app storage
+  categoryStrategy: FolderCategoryStrategy | '' // Empty for no folder categories
+  categoryRoot: string // Category holding the folder categories; empty for the root
+  include: string[] // Name patterns of the files to add, e.g. "*.gp5"; all supported files if empty
+  exclude: string[] // Patterns of the files and folders to skip, e.g. "*.tmp", "**/Backups/**"
 }
 
 export interface Settings {
@@ -50,12 +300,201 @@ export interface Settings {
   openMethod: 'system' | 'inner'
   openGpMethod: 'system' | 'inner'
   audioDevice: string
-  syncPaths: string[]
-  syncStrategy: 'skip' | 'overwrite'
+  syncStrategy: 'skip' | 'overwrite' | 'ask'
+  syncZipArchives: boolean // Import the tab files inside .zip archives found by a sync
+  converterCommand: string // Converts files of converterExts on import, with {input} and {output} placeholders; empty for none
+  converterExts: string // Converted extensions, e.g. ".mscz .mxl .musicxml"
+  converterFormat: 'pdf' | 'gp' | 'gp5' | '' // Of the converted files; 'pdf' if empty
   autoSyncEnabled: boolean
   autoSyncFrequency: 'startup' | 'weekly' | 'monthly' | 'yearly'
   lastSyncTime: number
   keyBindings: KeyBindings
+  keyProfile: string // Name of the active key binding profile
+  locale: string // BCP 47 tag for dates and sizes in reports; empty for the system locale
+  coverDailyBudget: number // Covers fetched per day for large imports; 0 for no limit
+  coverRefreshMonths: number // Re-fetch covers this old when their tab's metadata changed; 0 to never
+  coverMaxSize: number // Width and height downloaded covers are scaled down to; 0 to keep their size
+  coverJpegQuality: number // JPEG quality 1-100 downloaded covers are re-encoded with; 0 with coverMaxSize 0 to keep them as downloaded
+  cacheLimitMB: number // Size cap of derived images such as category collages; 0 for no limit
+  offlineMode: boolean // Hold cover downloads and make no network requests
+  httpProxy: string // Proxy of metadata requests, e.g. 'http://proxy:8080'; empty for the system proxy
+  httpTimeout: number // Seconds per metadata request; 0 for the default
+  tlsCaFile: string // PEM certificates trusted besides the system ones
+  tlsSkipVerify: boolean // Accept any certificate; only for debugging
+  enrichProvider: '' | 'lastfm' | 'spotify' // Fills missing album, genre and year
+  lastfmApiKey: string
+  spotifyClientId: string
+  spotifySecret: string // Client secret of the Spotify app
+  cloudBackupHours: number // Hours between backups to the bucket; 0 for manual backups only
+  cloudEndpoint: string // S3-compatible endpoint, e.g. 'https://s3.us-west-004.backblazeb2.com'
+  cloudRegion: string // 'us-east-1' if empty
+  cloudBucket: string
+  cloudPrefix: string // Folder of the backup in the bucket
+  cloudAccessKey: string
+  cloudSecretKey: string
+  lastCloudBackup: number // Unix timestamp of the last complete backup
+  maintenanceDays: number // Days between automatic database maintenance; 0 for manual only
+  lastMaintenance: number // Unix timestamp of the last maintenance
+  lanServerEnabled: boolean // Serve the library read-only to devices on the LAN
+  lanServerPort: number // 0 for the default
+  lanServerToken: string // Required by LAN requests; changed with RegenerateLANToken
+  fuzzySearch: boolean // Show the tabs of the corrected query when a search finds nothing
+  sharedLibraryPath: string // Folder or database of a library attached read-only; empty for none
+  pedalEnabled: boolean // Read MIDI and HID foot controllers
+  pedalBindings: PedalBindings // Pedals no matching profile binds
+  pedalProfiles: PedalProfile[] // Checked in order
+}
+
+// CacheUsage is the disk space taken by images
+export interface CacheUsage {
+  derived: { files: number; bytes: number } // Images evicted past the limit, e.g. category collages
+  derivedSize: string
+  limitMB: number
+  covers: { files: number; bytes: number } // Tab covers, never evicted
+  coversSize: string
+}
+
+// OrphanReport lists the files of storage and covers no tab refers to, and the references to missing files
+export interface OrphanReport {
+  files: { path: string; size: number }[]
+  bytes: number
+  size: string // bytes formatted for the locale
+  missing: { kind: 'file' | 'cover' | 'categoryCover'; id: string; name: string; path: string }[]
+  deleted: boolean // Whether the orphans were removed
+}
+
+// SyncConflict is a new file a sync skipped or retitled because a tab already has its title
+export interface SyncConflict {
+  kind: 'skipped' | 'retitled'
+  title: string
+  newTitle?: string
+  path: string
+  existingPath: string // File of the tab with the title
+}
+
+// SyncPreviewFile is a file a sync would add, skip or restore
+export interface SyncPreviewFile {
+  path: string // Inside a zip archive: the archive path joined with the entry name
+  title: string
+  artist: string
+  action: 'add' | 'retitle' | 'skip' | 'restore' | 'ask' | 'move'
+  copy: boolean // Copied to app storage instead of linked
+  convert?: boolean // Converted by the converter command first, then copied
+  newTitle?: string // For 'retitle'
+  existingPath?: string // File of the tab with the title, for conflicts; former file, for 'move'
+}
+
+// SyncPreview lists what a sync would do without running it
+export interface SyncPreview {
+  files: SyncPreviewFile[]
+  added: number
+  conflicts: number
+  skipped: number
+  pending: number // Files queued with the 'ask' strategy
+  restored: number
+  moved: number // Files of tabs moved on disk
+  unchanged: number // Files already in the library, not listed
+}
+
+// SyncRun is a full sync recorded in the sync history
+export interface SyncRun {
+  id: number
+  startedAt: number
+  durationMs: number
+  total: number
+  added: number
+  updated: number
+  skipped: number
+  errors: number
+  pending: number
+  conflicts: number
+  report: string // Conflict report name, empty if none
+  fileErrors: { path: string; error: string }[]
+}
+
+// PendingConflict is a new file a sync with the 'ask' strategy left for the user to resolve
+export interface PendingConflict {
+  path: string
+  title: string
+  existingPath: string // File of the tab with the title
+  foundAt: number
+}
+
+// CloudBackupProgress is emitted for each file of a cloud backup or restore
+export interface CloudBackupProgress {
+  phase: 'storage' | 'covers' | 'database'
+  file: string
+  done: number
+  total: number
+}
+
+// CloudBackupResult counts the files of a cloud backup or restore
+export interface CloudBackupResult {
+  transferred: number // Uploaded or downloaded
+  skipped: number // Already up to date
+  bytes: number
+}
+
+// LANStatus describes the read-only file server on the LAN
+export interface LANStatus {
+  running: boolean
+  port: number
+  urls: string[] // Addresses of this machine to open on the tablet, with the token
+  error: string // Why the server is not running although enabled
+}
+
+// SharedLibraryStatus describes the library attached read-only
+export interface SharedLibraryStatus {
+  attached: boolean
+  path: string
+  tabs: number // Number of tabs of the shared library
+  error?: string // Why the library of the settings could not be attached
+}
+
+// ConflictReport is a CSV report of sync conflicts in the logs directory
+export interface ConflictReport {
+  name: string
+  path: string
+  createdAt: number
+}
+
+// DatabaseMetrics counts the writes that found the database locked
+export interface DatabaseMetrics {
+  busyRetries: number
+  busyFailures: number
+}
+
+// MaintenanceResult is the outcome of MaintainDatabase
+export interface MaintenanceResult {
+  problems: string[] // Reported by the integrity check; empty if the database is sound
+  sizeBefore: number // Bytes
+  sizeAfter: number
+}
+
+// DbSnapshot is a copy of the library database taken before a sync or daily
+export interface DbSnapshot {
+  name: string
+  reason: string // 'sync', 'daily' or 'restore' (taken before restoring another)
+  createdAt: number
+  size: number
+  sizeText: string
+}
+
+// CoverBootstrapStatus is the progress of fetching the covers of a large import
+export interface CoverBootstrapStatus {
+  active: boolean
+  pending: number
+  fetchedToday: number
+  dailyBudget: number
+  failed: number // Tabs whose download failed on a network error, retried with backoff
+}
+
+// CoverSweepStatus is the progress of FetchMissingCovers
+export interface CoverSweepStatus {
+  active: boolean
+  total: number // Tabs without a cover when the sweep started
+  done: number  // Tabs whose cover was searched
+  found: number // Covers downloaded
 }
 
 // TabsResponse represents a paginated response for tabs
@@ -65,6 +504,8 @@ export interface TabsResponse {
   page: number
   pageSize: number
   hasMore: boolean
+  didYouMean?: string // Corrected query of a search finding nothing
+  corrected?: boolean // The tabs are those of didYouMean (fuzzySearch setting)
 }
 
 // OpenedTab represents a tab that is currently open in a viewer
@@ -97,5 +538,50 @@ export interface DragItem {
   id: string
 }
 
+// YearReview summarizes the use of the library during a year
+export interface YearReview {
+  year: number
+  tabsAdded: number
+  tabsOpened: number
+  tabsPracticed: number
+  practiceSeconds: number
+  practiceDays: number
+  notesWritten: number
+  monthlyAdded: number[] // Tabs added per month, January first
+  topArtists: { artist: string, tabsAdded: number, practiceSeconds: number }[]
+  topTabs: { id: string, title: string, artist: string, practiceSeconds: number }[]
+}
+
+// Reminder schedules a tab or a category for practice
+export interface Reminder {
+  id: number
+  tabId: string // '' for a category
+  categoryId: string // '' for a tab
+  name: string // Title of the tab or name of the category
+  weekdays: number[] // Days it repeats on, 0 for Sunday; empty for once on date
+  date: string // Day of a one-time reminder, e.g. '2024-06-01'
+  time: string // Local time of day, e.g. '18:30'
+  note: string
+  nextAt: number // Unix timestamp of the next notification, 0 if none
+  createdAt: number
+}
+
+// TabOpen is an open of a tab recorded in its history
+export interface TabOpen {
+  id: number
+  tabId: string
+  title: string
+  artist: string
+  openedAt: number // Unix timestamp
+  seconds: number // Time open in a viewer, 0 if unknown
+}
+
+// OpenedTab is a tab of GetMostOpenedTabs
+export interface OpenedTab {
+  tab: Tab
+  opens: number
+  seconds: number
+}
+
 // ViewType represents the current view
-export type ViewType = 'home' | 'library' | 'settings' | `pdf-${string}` | `gp-${string}`
+export type ViewType = 'home' | 'library' | 'students' | 'settings' | `pdf-${string}` | `gp-${string}`
@@ -11,15 +11,39 @@ interface Window {
     main: {
       App: {
         GetTabs(): Promise<import('./types').Tab[]>
-        GetTabsPaginated(categoryId: string, page: number, pageSize: number, searchQuery: string, filterBy: string[], isGlobal: boolean, sortBy: string, sortDesc: boolean): Promise<import('./types').TabsResponse>
+        GetTabsPaginated(categoryId: string, page: number, pageSize: number, searchQuery: string, filterBy: string[], isGlobal: boolean, sortBy: string, sortDesc: boolean, filters: import('./types').TabFilters): Promise<import('./types').TabsResponse>
         GetCategories(): Promise<import('./types').Category[]>
+        GetCategoryStats(): Promise<import('./types').CategoryStats[]>
+        GetLibraryStats(): Promise<import('./types').LibraryStats>
+        GetGenres(): Promise<import('./types').GenreCount[]>
+        GetSearchSuggestions(prefix: string, field: string): Promise<import('./types').SearchSuggestion[]>
         GetRecentCategories(limit: number): Promise<import('./types').Category[]>
         GetRecentTabs(limit: number): Promise<import('./types').Tab[]>
+        GetRandomTabs(count: number, filters: import('./types').TabFilters, preferStale: boolean): Promise<import('./types').Tab[]>
         GetSettings(): Promise<import('./types').Settings>
         SaveSettings(settings: import('./types').Settings): Promise<void>
+        GetKeyActions(): Promise<import('./types').KeyAction[]>
+        ResetKeyBindings(): Promise<import('./types').KeyBindings>
+        GetKeyBindingProfiles(): Promise<import('./types').KeyBindingProfile[]>
+        SaveKeyBindingProfile(name: string): Promise<void>
+        SwitchKeyBindingProfile(name: string): Promise<import('./types').KeyBindings>
+        DeleteKeyBindingProfile(name: string): Promise<void>
+        ExportKeyBindingProfile(name: string, destFolder: string): Promise<string>
+        ImportKeyBindingProfile(path: string): Promise<import('./types').KeyBindingProfile>
+        SelectKeyBindingProfileFile(): Promise<string>
+        GetPedalDevices(): Promise<import('./types').PedalDevice[]>
+        ReconnectPedals(): Promise<import('./types').PedalDevice[]>
+        GetPedalPresets(): Promise<import('./types').PedalProfile[]>
+        LearnPedal(profile: string, action: string): Promise<void>
+        CancelPedalLearn(): Promise<void>
+        ReadyForOpenFiles(): Promise<void>
         AddCategory(category: import('./types').Category): Promise<void>
         DeleteCategory(id: string): Promise<void>
         MoveCategory(id: string, newParentId: string): Promise<void>
+        ReorderCategories(ids: string[]): Promise<void>
+        SetCategoryPinned(id: string, pinned: boolean): Promise<void>
+        GetTabsInCategoryTree(categoryId: string): Promise<import('./types').Tab[]>
+        ApplyOperations(ops: import('./types').Operation[]): Promise<import('./types').OperationsResult>
         SaveTab(tab: import('./types').Tab, shouldCopy: boolean): Promise<void>
         UpdateTab(tab: import('./types').Tab): Promise<void>
         UpdateTabMetadata(id: string, title: string, artist: string, album: string): Promise<void>
@@ -32,15 +56,96 @@ interface Window {
         BatchMoveTabs(ids: string[], categoryId: string): Promise<number>
         BatchAddTabsToCategory(ids: string[], categoryId: string): Promise<number>
         OpenTab(id: string): Promise<void>
+        ResolveOpenMethod(tabId: string, categoryId: string): Promise<string>
         MarkAsOpened(id: string): Promise<void>
+        GetReminders(): Promise<import('./types').Reminder[]>
+        SaveReminder(reminder: import('./types').Reminder): Promise<import('./types').Reminder>
+        DeleteReminder(id: number): Promise<void>
+        SnoozeReminder(id: number, minutes: number): Promise<void>
+        DismissReminder(id: number): Promise<void>
+        GetTabHistory(tabId: string, limit: number): Promise<import('./types').TabOpen[]>
+        GetMostOpenedTabs(limit: number, days: number): Promise<import('./types').OpenedTab[]>
         ExportTab(id: string, destFolder: string): Promise<void>
+        ExportTabBundle(id: string, destFolder: string): Promise<string>
+        BatchExport(ids: string[], destFolder: string): Promise<number>
+        ExportCategory(categoryId: string, destFolder: string): Promise<string>
+        ExportCategoryTree(destFolder: string): Promise<string>
+        ImportCategoryTree(path: string): Promise<import('./types').CategoryTreeImport>
+        SelectCategoryTreeFile(): Promise<string>
+        ExportGPTracks(id: string, trackIndexes: number[], destFolder: string, format: string): Promise<string>
+        GetPDFPageCount(tabId: string): Promise<number>
+        SplitPDF(tabId: string, ranges: import('./types').PageRange[]): Promise<import('./types').Tab[]>
+        MergePDFs(ids: string[], title: string, keepSources: boolean): Promise<import('./types').Tab>
+        GetPrintSettings(tabId: string): Promise<import('./types').PrintSettings>
+        SavePrintSettings(settings: import('./types').PrintSettings): Promise<void>
+        ExportTabAsPDF(id: string, options: import('./types').PDFExportOptions): Promise<string>
+        CompletePDFRender(requestId: string, pages: string[], renderError: string): Promise<void>
+        ExportTabAsMidi(id: string, destFolder: string): Promise<string>
         ProcessFile(path: string): Promise<import('./types').Tab>
         SelectFiles(): Promise<string[]>
         SelectFolder(): Promise<string>
         SelectImage(): Promise<string>
         TriggerSync(): Promise<string>
+        TriggerSyncPreview(): Promise<import('./types').SyncPreview>
+        GetSyncPaths(): Promise<import('./types').SyncPath[]>
+        AddSyncPath(path: string): Promise<import('./types').SyncPath>
+        UpdateSyncPath(path: import('./types').SyncPath): Promise<void>
+        RemoveSyncPath(path: string): Promise<void>
+        GetSyncHistory(limit: number): Promise<import('./types').SyncRun[]>
+        GetPendingConflicts(): Promise<import('./types').PendingConflict[]>
+        ResolveConflict(path: string, action: string, title: string): Promise<void>
+        GetConflictReports(): Promise<import('./types').ConflictReport[]>
+        GetConflictReport(name: string): Promise<import('./types').SyncConflict[]>
+        OpenConflictReport(name: string): Promise<void>
         GetCover(path: string): Promise<string>
         GetFileServerPort(): Promise<number>
+        GetFileServerToken(): Promise<string>
+        GetAttachments(tabId: string): Promise<import('./types').Attachment[]>
+        AddAttachment(tabId: string, filePath: string): Promise<import('./types').Attachment>
+        RemoveAttachment(id: number): Promise<void>
+        SelectAudioFiles(): Promise<string[]>
+        GetTabCredits(tabId: string): Promise<import('./types').TabCredits>
+        GetTabLinks(tabId: string): Promise<import('./types').TabLink[]>
+        AddTabLink(tabId: string, url: string, title: string): Promise<import('./types').TabLink>
+        RemoveTabLink(id: number): Promise<void>
+        OpenTabLink(id: number): Promise<void>
+        OpenTabSource(id: string): Promise<void>
+        GetInboxPath(): Promise<string>
+        OpenInbox(): Promise<void>
+        MarkTabReviewed(id: string): Promise<void>
+        GetCoverBootstrapStatus(): Promise<import('./types').CoverBootstrapStatus>
+        RetryFailedCovers(): Promise<number>
+        FetchMissingCovers(): Promise<import('./types').CoverSweepStatus>
+        GetCoverSweepStatus(): Promise<import('./types').CoverSweepStatus>
+        SetOfflineMode(offline: boolean): Promise<void>
+        BackupToCloud(): Promise<import('./types').CloudBackupResult>
+        RestoreFromCloud(): Promise<import('./types').CloudBackupResult>
+        GetLANStatus(): Promise<import('./types').LANStatus>
+        RegenerateLANToken(): Promise<string>
+        GetSharedLibraryStatus(): Promise<import('./types').SharedLibraryStatus>
+        GetCategoryTemplates(): Promise<import('./types').CategoryTemplate[]>
+        CreateCategoryTemplate(name: string, categoryId: string): Promise<import('./types').CategoryTemplate>
+        ApplyCategoryTemplate(templateId: string, name: string, parentId: string): Promise<import('./types').Category>
+        DeleteCategoryTemplate(id: string): Promise<void>
+        GetStudents(): Promise<import('./types').Student[]>
+        SaveStudent(student: import('./types').Student): Promise<import('./types').Student>
+        DeleteStudent(id: number): Promise<void>
+        GetStudentAssignments(studentId: number): Promise<import('./types').Assignment[]>
+        AssignTab(studentId: number, tabId: string, dueAt: number, note: string): Promise<import('./types').Assignment>
+        SetAssignmentStatus(id: number, status: string): Promise<void>
+        RemoveAssignment(id: number): Promise<void>
+        ExportStudentPacket(studentId: number, destFolder: string): Promise<string>
+        RecordPracticeTime(tabId: string, startedAt: number, seconds: number): Promise<void>
+        GetYearReview(year: number): Promise<import('./types').YearReview>
+        ExportYearReview(year: number, destFolder: string): Promise<string>
+        GetCacheUsage(): Promise<import('./types').CacheUsage>
+        ClearDerivedCache(): Promise<number>
+        CompactCovers(): Promise<{ files: number; bytes: number }>
+        CleanupOrphans(remove: boolean): Promise<import('./types').OrphanReport>
+        GetDatabaseMetrics(): Promise<import('./types').DatabaseMetrics>
+        MaintainDatabase(): Promise<import('./types').MaintenanceResult>
+        ListDbSnapshots(): Promise<import('./types').DbSnapshot[]>
+        RestoreDbSnapshot(name: string): Promise<void>
       }
     }
   }
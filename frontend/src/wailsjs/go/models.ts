@@ -117,6 +117,22 @@ export namespace store {
 	        this.tag = source["tag"];
 	    }
 	}
+	export class TabFilters {
+	    minRating: number;
+	    difficulties: string[];
+	    needsReview: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new TabFilters(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.minRating = source["minRating"];
+	        this.difficulties = source["difficulties"];
+	        this.needsReview = source["needsReview"];
+	    }
+	}
 
 }
 
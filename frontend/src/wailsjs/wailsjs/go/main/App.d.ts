@@ -33,7 +33,7 @@ export function GetSettings():Promise<store.Settings>;
 
 export function GetTabs():Promise<Array<store.Tab>>;
 
-export function GetTabsPaginated(arg1:string,arg2:number,arg3:number,arg4:string,arg5:Array<string>,arg6:boolean,arg7:string,arg8:boolean):Promise<main.TabsResponse>;
+export function GetTabsPaginated(arg1:string,arg2:number,arg3:number,arg4:string,arg5:Array<string>,arg6:boolean,arg7:string,arg8:boolean,arg9:store.TabFilters):Promise<main.TabsResponse>;
 
 export function MarkAsOpened(arg1:string):Promise<void>;
 
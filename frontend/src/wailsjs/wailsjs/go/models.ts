@@ -63,44 +63,6 @@ export namespace store {
 	        this.effectiveCoverPath = source["effectiveCoverPath"];
 	    }
 	}
-	export class KeyBindings {
-	    scrollDown: string;
-	    scrollUp: string;
-	    metronome: string;
-	    playPause: string;
-	    stop: string;
-	    bpmPlus: string;
-	    bpmMinus: string;
-	    toggleLoop: string;
-	    clearSelection: string;
-	    jumpToBar: string;
-	    jumpToStart: string;
-	    autoScroll: string;
-	    scrollSpeedUp: string;
-	    scrollSpeedDown: string;
-	
-	    static createFrom(source: any = {}) {
-	        return new KeyBindings(source);
-	    }
-	
-	    constructor(source: any = {}) {
-	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.scrollDown = source["scrollDown"];
-	        this.scrollUp = source["scrollUp"];
-	        this.metronome = source["metronome"];
-	        this.playPause = source["playPause"];
-	        this.stop = source["stop"];
-	        this.bpmPlus = source["bpmPlus"];
-	        this.bpmMinus = source["bpmMinus"];
-	        this.toggleLoop = source["toggleLoop"];
-	        this.clearSelection = source["clearSelection"];
-	        this.jumpToBar = source["jumpToBar"];
-	        this.jumpToStart = source["jumpToStart"];
-	        this.autoScroll = source["autoScroll"];
-	        this.scrollSpeedUp = source["scrollSpeedUp"];
-	        this.scrollSpeedDown = source["scrollSpeedDown"];
-	    }
-	}
 	export class Settings {
 	    theme: string;
 	    background: string;
@@ -113,7 +75,7 @@ export namespace store {
 	    autoSyncEnabled: boolean;
 	    autoSyncFrequency: string;
 	    lastSyncTime: number;
-	    keyBindings: KeyBindings;
+	    keyBindings: {[key: string]: string};
 	
 	    static createFrom(source: any = {}) {
 	        return new Settings(source);
@@ -132,26 +94,8 @@ export namespace store {
 	        this.autoSyncEnabled = source["autoSyncEnabled"];
 	        this.autoSyncFrequency = source["autoSyncFrequency"];
 	        this.lastSyncTime = source["lastSyncTime"];
-	        this.keyBindings = this.convertValues(source["keyBindings"], KeyBindings);
+	        this.keyBindings = source["keyBindings"];
 	    }
-	
-		convertValues(a: any, classs: any, asMap: boolean = false): any {
-		    if (!a) {
-		        return a;
-		    }
-		    if (a.slice && a.map) {
-		        return (a as any[]).map(elem => this.convertValues(elem, classs));
-		    } else if ("object" === typeof a) {
-		        if (asMap) {
-		            for (const key of Object.keys(a)) {
-		                a[key] = new classs(a[key]);
-		            }
-		            return a;
-		        }
-		        return new classs(a);
-		    }
-		    return a;
-		}
 	}
 	export class Tab {
 	    id: string;
@@ -191,6 +135,22 @@ export namespace store {
 	        this.lastOpened = source["lastOpened"];
 	    }
 	}
+	export class TabFilters {
+	    minRating: number;
+	    difficulties: string[];
+	    needsReview: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new TabFilters(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.minRating = source["minRating"];
+	        this.difficulties = source["difficulties"];
+	        this.needsReview = source["needsReview"];
+	    }
+	}
 
 }
 
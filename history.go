@@ -0,0 +1,42 @@
+package main
+
+import (
+	"haya-tab/pkg/store"
+	"time"
+)
+
+// historyLimit caps the opens GetTabHistory and the tabs GetMostOpenedTabs
+// return
+const historyLimit = 500
+
+// GetTabHistory returns up to limit opens of a tab, or of the whole library
+// if tabID is empty, newest first
+func (a *App) GetTabHistory(tabID string, limit int) []store.TabOpen {
+	if limit < 1 || limit > historyLimit {
+		limit = historyLimit
+	}
+	opens, err := a.store.GetTabHistory(tabID, limit)
+	if err != nil {
+		a.logger.Error("Error getting tab history: %v", err)
+		return []store.TabOpen{}
+	}
+	return opens
+}
+
+// GetMostOpenedTabs returns the limit tabs opened the most during the last
+// days, or ever if days is 0, for "most played" lists
+func (a *App) GetMostOpenedTabs(limit, days int) []store.OpenedTab {
+	if limit < 1 || limit > historyLimit {
+		limit = 20
+	}
+	var since int64
+	if days > 0 {
+		since = time.Now().AddDate(0, 0, -days).Unix()
+	}
+	tabs, err := a.store.GetMostOpenedTabs(limit, since)
+	if err != nil {
+		a.logger.Error("Error getting most opened tabs: %v", err)
+		return []store.OpenedTab{}
+	}
+	return tabs
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"haya-tab/pkg/watcher"
+	"path/filepath"
+)
+
+// inboxDir returns the inbox folder. Files dropped there are imported as
+// managed tabs and flagged for review, separately from the sync paths.
+func inboxDir() string {
+	return filepath.Join(getAppDir(), "inbox")
+}
+
+// startInbox imports what was dropped in the inbox while the app was
+// closed and watches it for new files
+func (a *App) startInbox() {
+	dir := inboxDir()
+	a.inboxWatcher = watcher.NewFileWatcher(a.handleInboxChanges)
+	a.inboxWatcher.SetLogger(a.logger)
+	if err := a.inboxWatcher.Start(); err != nil {
+		a.logger.Error("Failed to start inbox watcher: %v", err)
+	} else if err := a.inboxWatcher.AddPath(dir); err != nil {
+		a.logger.Error("Failed to watch inbox %s: %v", dir, err)
+	}
+
+	go a.syncService.ImportInbox(dir)
+}
+
+// handleInboxChanges imports new inbox files. Removals are the files
+// moved to storage by the import itself.
+func (a *App) handleInboxChanges(changes []watcher.Change) {
+	for _, c := range changes {
+		if !c.Removed {
+			a.syncService.ImportInbox(inboxDir())
+			return
+		}
+	}
+}
+
+// GetInboxPath returns the inbox folder
+func (a *App) GetInboxPath() string {
+	return inboxDir()
+}
+
+// OpenInbox opens the inbox folder in the file manager
+func (a *App) OpenInbox() error {
+	return openWithSystem(inboxDir())
+}
+
+// MarkTabReviewed clears the review flag of a tab imported from the inbox.
+// Saving the tab from the edit dialog clears it as well.
+func (a *App) MarkTabReviewed(id string) error {
+	if isSharedTab(id) {
+		return errSharedReadOnly
+	}
+	return a.store.SetTabNeedsReview(id, false)
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"github.com/wailsapp/wails/v2/pkg/options"
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// singleInstanceID identifies HAYA-TAB to the Wails single-instance lock.
+// A second launch exits before startup opens the database and hands its
+// arguments to the running instance instead.
+const singleInstanceID = "com.hayasaka7.haya-tab"
+
+// onSecondInstanceLaunch brings the window of the running instance to the
+// front and opens the files the second launch was given
+func (a *App) onSecondInstanceLaunch(data options.SecondInstanceData) {
+	wailsRuntime.WindowUnminimise(a.ctx)
+	wailsRuntime.WindowShow(a.ctx)
+
+	for _, path := range fileArgs(data.Args, data.WorkingDirectory) {
+		a.openFile(path)
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"haya-tab/pkg/store"
+	"os"
+	"path/filepath"
+	"strings"
+
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// keyProfileExt is the extension of exported key binding profiles
+const keyProfileExt = ".hayakeys.json"
+
+// keyProfileFile is an exported key binding profile
+type keyProfileFile struct {
+	Name        string            `json:"name"`
+	KeyBindings store.KeyBindings `json:"keyBindings"`
+}
+
+// GetKeyBindingProfiles returns the key binding profiles, with the active
+// one flagged
+func (a *App) GetKeyBindingProfiles() []store.KeyBindingProfile {
+	profiles, err := a.store.GetKeyBindingProfiles()
+	if err != nil {
+		a.logger.Error("Error getting key binding profiles: %v", err)
+		return []store.KeyBindingProfile{}
+	}
+	return profiles
+}
+
+// SaveKeyBindingProfile saves the current key bindings as a new profile
+// named name. The active profile stays the same.
+func (a *App) SaveKeyBindingProfile(name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fmt.Errorf("profile name is required")
+	}
+	existing, err := a.store.GetKeyBindingProfile(name)
+	if err != nil {
+		return fmt.Errorf("failed to check profiles: %w", err)
+	}
+	if existing != nil {
+		return fmt.Errorf("a profile named '%s' already exists", name)
+	}
+	return a.store.SaveKeyBindingProfile(name, a.store.GetSettings().KeyBindings)
+}
+
+// SwitchKeyBindingProfile makes a profile the active one and returns its
+// key bindings
+func (a *App) SwitchKeyBindingProfile(name string) (store.KeyBindings, error) {
+	return a.store.SwitchKeyBindingProfile(name)
+}
+
+// DeleteKeyBindingProfile removes a profile other than the active one
+func (a *App) DeleteKeyBindingProfile(name string) error {
+	return a.store.DeleteKeyBindingProfile(name)
+}
+
+// ExportKeyBindingProfile writes a profile to destFolder as a
+// .hayakeys.json file and returns its path
+func (a *App) ExportKeyBindingProfile(name, destFolder string) (string, error) {
+	profile, err := a.store.GetKeyBindingProfile(name)
+	if err != nil {
+		return "", fmt.Errorf("failed to get profile: %w", err)
+	}
+	if profile == nil {
+		return "", fmt.Errorf("key binding profile not found: %s", name)
+	}
+
+	data, err := json.MarshalIndent(keyProfileFile{Name: profile.Name, KeyBindings: profile.KeyBindings}, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	path := uniquePath(destFolder, sanitizeFileName(profile.Name), keyProfileExt)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write profile: %w", err)
+	}
+	return path, nil
+}
+
+// ImportKeyBindingProfile adds the profile exported to path. A profile with
+// the same name is kept and the import gets a numbered name instead.
+// Returns the imported profile; it is not made active.
+func (a *App) ImportKeyBindingProfile(path string) (store.KeyBindingProfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return store.KeyBindingProfile{}, fmt.Errorf("failed to read profile: %w", err)
+	}
+	var file keyProfileFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return store.KeyBindingProfile{}, fmt.Errorf("not a key binding profile: %w", err)
+	}
+	if len(file.KeyBindings) == 0 {
+		return store.KeyBindingProfile{}, fmt.Errorf("the profile has no key bindings")
+	}
+
+	base := strings.TrimSpace(file.Name)
+	if base == "" {
+		base = strings.TrimSuffix(filepath.Base(path), keyProfileExt)
+	}
+	name := base
+	for i := 2; ; i++ {
+		existing, err := a.store.GetKeyBindingProfile(name)
+		if err != nil {
+			return store.KeyBindingProfile{}, fmt.Errorf("failed to check profiles: %w", err)
+		}
+		if existing == nil {
+			break
+		}
+		name = fmt.Sprintf("%s (%d)", base, i)
+	}
+
+	if err := a.store.SaveKeyBindingProfile(name, file.KeyBindings); err != nil {
+		return store.KeyBindingProfile{}, err
+	}
+	profile, err := a.store.GetKeyBindingProfile(name)
+	if err != nil || profile == nil {
+		return store.KeyBindingProfile{}, fmt.Errorf("failed to read back profile %s: %v", name, err)
+	}
+	return *profile, nil
+}
+
+// SelectKeyBindingProfileFile opens a file dialog for selecting an
+// exported key binding profile
+func (a *App) SelectKeyBindingProfileFile() string {
+	selection, err := wailsRuntime.OpenFileDialog(a.ctx, wailsRuntime.OpenDialogOptions{
+		Title: "Import Key Binding Profile",
+		Filters: []wailsRuntime.FileFilter{
+			{DisplayName: "Key Binding Profiles (*.json)", Pattern: "*.json"},
+		},
+	})
+
+	if err != nil {
+		return ""
+	}
+	return selection
+}
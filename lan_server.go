@@ -0,0 +1,336 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"haya-tab/pkg/store"
+	"html/template"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// lanDefaultPort is the port of the LAN server when Settings.LANServerPort is 0
+const lanDefaultPort = 8765
+
+// LANTab is a tab as listed by the LAN API. Paths on the desktop are left
+// out; the file and cover are streamed from /api/file/{id} and /api/cover/{id}.
+type LANTab struct {
+	ID          string   `json:"id"`
+	Title       string   `json:"title"`
+	Artist      string   `json:"artist"`
+	Album       string   `json:"album"`
+	Type        string   `json:"type"`
+	CategoryIDs []string `json:"categoryIds"`
+	Key         string   `json:"key"`
+	Capo        int      `json:"capo"`
+	Genre       string   `json:"genre"`
+	Year        int      `json:"year"`
+	HasCover    bool     `json:"hasCover"`
+}
+
+// LANCategory is a category as listed by the LAN API
+type LANCategory struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	ParentID string `json:"parentId"` // Empty if root
+}
+
+// LANStatus describes the LAN server
+type LANStatus struct {
+	Running bool     `json:"running"`
+	Port    int      `json:"port"`
+	URLs    []string `json:"urls"`  // Addresses of this machine to open on the tablet, with the token
+	Error   string   `json:"error"` // Why the server is not running although enabled
+}
+
+// lanServer is the file server bound on the LAN, see applyLANServer
+type lanServer struct {
+	server *http.Server
+	port   int
+	err    error // Of the last start
+}
+
+// applyLANServer starts, restarts or stops the read-only LAN server
+// according to the settings. A token is generated the first time it is
+// enabled.
+func (a *App) applyLANServer() {
+	settings := a.store.GetSettings()
+	port := settings.LANServerPort
+	if port <= 0 {
+		port = lanDefaultPort
+	}
+
+	a.lanMu.Lock()
+	defer a.lanMu.Unlock()
+	running := a.lan.server != nil
+	if running && settings.LANServerEnabled && a.lan.port == port {
+		return
+	}
+	if running {
+		a.lan.server.Close()
+		a.logger.Info("Stopped the LAN server on port %d", a.lan.port)
+	}
+	a.lan = lanServer{port: port}
+	if !settings.LANServerEnabled {
+		return
+	}
+
+	if settings.LANServerToken == "" {
+		if _, err := a.regenerateLANToken(); err != nil {
+			a.lan.err = err
+			a.logger.Error("Failed to create the LAN server token: %v", err)
+			return
+		}
+	}
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		a.lan.err = err
+		a.logger.Error("Failed to start the LAN server on port %d: %v", port, err)
+		return
+	}
+	a.lan.server = &http.Server{Handler: &lanHandler{app: a, files: NewFileHandler(a)}}
+	go func(server *http.Server) {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			a.logger.Error("LAN server error: %v", err)
+		}
+	}(a.lan.server)
+	a.logger.Info("LAN server listening on port %d", port)
+}
+
+// stopLANServer stops the LAN server if running
+func (a *App) stopLANServer() {
+	a.lanMu.Lock()
+	defer a.lanMu.Unlock()
+	if a.lan.server != nil {
+		a.lan.server.Close()
+		a.lan.server = nil
+	}
+}
+
+// GetLANStatus returns whether the LAN server runs and the addresses to
+// reach it at
+func (a *App) GetLANStatus() LANStatus {
+	a.lanMu.Lock()
+	status := LANStatus{Running: a.lan.server != nil, Port: a.lan.port}
+	if a.lan.err != nil {
+		status.Error = a.lan.err.Error()
+	}
+	a.lanMu.Unlock()
+	if !status.Running {
+		return status
+	}
+
+	token := a.store.GetSettings().LANServerToken
+	for _, ip := range lanAddresses() {
+		status.URLs = append(status.URLs, fmt.Sprintf("http://%s/?token=%s", net.JoinHostPort(ip, fmt.Sprint(status.Port)), token))
+	}
+	return status
+}
+
+// RegenerateLANToken replaces the token of the LAN server, so devices given
+// the previous one lose access
+func (a *App) RegenerateLANToken() (string, error) {
+	return a.regenerateLANToken()
+}
+
+func (a *App) regenerateLANToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+	if err := a.store.SetSetting("lanServerToken", token); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// lanAddresses returns the IPv4 addresses of this machine on the LAN
+func lanAddresses() []string {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil
+	}
+	var ips []string
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.IsLoopback() || ipNet.IP.To4() == nil {
+			continue
+		}
+		ips = append(ips, ipNet.IP.String())
+	}
+	return ips
+}
+
+// lanHandler serves the library read-only to other devices: a page listing
+// the tabs at "/", the JSON API under /api/ and the tab files and covers.
+// Every request must carry the token of the settings, as the "token" query
+// parameter or an "Authorization: Bearer" header.
+type lanHandler struct {
+	app   *App
+	files *FileHandler
+}
+
+func (h *lanHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "Read-only", http.StatusMethodNotAllowed)
+		return
+	}
+	h.app.serveWithMetrics(w, r, func(w http.ResponseWriter) {
+		if !validToken(r, h.app.store.GetSettings().LANServerToken) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		h.route(w, r)
+	})
+}
+
+func (h *lanHandler) route(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	switch {
+	case path == "/":
+		h.serveIndex(w, r)
+	case path == "/api/tabs":
+		h.serveJSON(w, h.lanTabs)
+	case path == "/api/categories":
+		h.serveJSON(w, h.lanCategories)
+	case path == "/api/search":
+		h.serveSearch(w, r)
+	case strings.HasPrefix(path, "/api/file/"):
+		h.files.serveTabFile(w, r, strings.TrimPrefix(path, "/api/file/"))
+	case strings.HasPrefix(path, "/api/cover/"):
+		h.files.serveCoverFile(w, r, strings.TrimPrefix(path, "/api/cover/"))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (h *lanHandler) serveJSON(w http.ResponseWriter, list func() (any, error)) {
+	v, err := list()
+	if err != nil {
+		http.Error(w, "Cannot read the library", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(v)
+}
+
+// lanTabs returns the tabs whose file is present, by artist then title
+func (h *lanHandler) lanTabs() (any, error) {
+	tabs, err := h.app.store.GetTabs()
+	if err != nil {
+		return nil, err
+	}
+	list := []LANTab{}
+	for _, t := range tabs {
+		if t.IsMissing {
+			continue
+		}
+		list = append(list, newLANTab(t))
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		if a, b := strings.ToLower(list[i].Artist), strings.ToLower(list[j].Artist); a != b {
+			return a < b
+		}
+		return strings.ToLower(list[i].Title) < strings.ToLower(list[j].Title)
+	})
+	return list, nil
+}
+
+// serveSearch answers /api/search as FileHandler does
+func (h *lanHandler) serveSearch(w http.ResponseWriter, r *http.Request) {
+	result, err := h.files.search(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	h.serveJSON(w, func() (any, error) {
+		return newLANSearchPage(result), nil
+	})
+}
+
+// newLANSearchPage lists a page of search results as LANTabs, without the
+// file paths and other private fields of the tabs
+func newLANSearchPage(result TabsResponse) map[string]any {
+	tabs := make([]LANTab, 0, len(result.Tabs))
+	for _, t := range result.Tabs {
+		tabs = append(tabs, newLANTab(t))
+	}
+	return map[string]any{
+		"tabs":     tabs,
+		"total":    result.Total,
+		"page":     result.Page,
+		"pageSize": result.PageSize,
+		"hasMore":  result.HasMore,
+	}
+}
+
+func newLANTab(t store.Tab) LANTab {
+	return LANTab{
+		ID:          t.ID,
+		Title:       t.Title,
+		Artist:      t.Artist,
+		Album:       t.Album,
+		Type:        t.Type,
+		CategoryIDs: t.CategoryIDs,
+		Key:         t.Key,
+		Capo:        t.Capo,
+		Genre:       t.Genre,
+		Year:        t.Year,
+		HasCover:    t.CoverPath != "",
+	}
+}
+
+func (h *lanHandler) lanCategories() (any, error) {
+	categories, err := h.app.store.GetCategories()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]LANCategory, 0, len(categories))
+	for _, c := range categories {
+		list = append(list, LANCategory{ID: c.ID, Name: c.Name, ParentID: c.ParentID})
+	}
+	return list, nil
+}
+
+// lanIndex lists the tabs for browsers, each opening its file
+var lanIndex = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>HAYA-TAB</title>
+<style>
+body { font-family: sans-serif; margin: 0; padding: 12px; background: #1b2636; color: #eee; }
+input { width: 100%; box-sizing: border-box; padding: 10px; font-size: 1.1rem; margin-bottom: 12px; }
+ul { list-style: none; padding: 0; margin: 0; }
+li a { display: block; padding: 12px 8px; border-bottom: 1px solid #334; color: inherit; text-decoration: none; }
+li small { color: #999; }
+</style>
+</head>
+<body>
+<input type="search" placeholder="Filter" oninput="for (const li of document.querySelectorAll('li')) li.hidden = !li.textContent.toLowerCase().includes(this.value.toLowerCase())">
+<ul>
+{{range .Tabs}}<li><a href="/api/file/{{.ID}}?token={{$.Token}}">{{.Title}}{{if .Artist}} <small>{{.Artist}}</small>{{end}}</a></li>
+{{end}}</ul>
+</body>
+</html>
+`))
+
+func (h *lanHandler) serveIndex(w http.ResponseWriter, r *http.Request) {
+	tabs, err := h.lanTabs()
+	if err != nil {
+		http.Error(w, "Cannot read the library", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	lanIndex.Execute(w, map[string]any{"Tabs": tabs, "Token": r.URL.Query().Get("token")})
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"haya-tab/pkg/store"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// AddTabLink associates a reference URL with a tab, e.g. a video lesson or
+// the original recording. Only http and https URLs are accepted since the
+// link is handed to the system browser. title defaults to the host name.
+func (a *App) AddTabLink(tabID string, rawURL string, title string) (store.TabLink, error) {
+	if isSharedTab(tabID) {
+		return store.TabLink{}, errSharedReadOnly
+	}
+	rawURL = strings.TrimSpace(rawURL)
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return store.TabLink{}, fmt.Errorf("not a web link: %s", rawURL)
+	}
+
+	tab, err := a.store.GetTab(tabID)
+	if err != nil {
+		return store.TabLink{}, fmt.Errorf("failed to get tab: %w", err)
+	}
+	if tab == nil {
+		return store.TabLink{}, fmt.Errorf("tab not found: %s", tabID)
+	}
+
+	title = strings.TrimSpace(title)
+	if title == "" {
+		title = strings.TrimPrefix(u.Hostname(), "www.")
+	}
+	return a.store.AddTabLink(store.TabLink{
+		TabID:   tabID,
+		Title:   title,
+		URL:     u.String(),
+		AddedAt: time.Now().Unix(),
+	})
+}
+
+// GetTabLinks returns the reference links of a tab
+func (a *App) GetTabLinks(tabID string) []store.TabLink {
+	links, err := a.store.GetTabLinks(tabID)
+	if err != nil {
+		a.logger.Error("Error getting links: %v", err)
+		return []store.TabLink{}
+	}
+	return links
+}
+
+// RemoveTabLink removes a reference link from its tab
+func (a *App) RemoveTabLink(id int64) error {
+	return a.store.DeleteTabLink(id)
+}
+
+// OpenTabLink opens a reference link in the system browser
+func (a *App) OpenTabLink(id int64) error {
+	link, err := a.store.GetTabLink(id)
+	if err != nil {
+		return fmt.Errorf("failed to get link: %w", err)
+	}
+	if link == nil {
+		return fmt.Errorf("link not found")
+	}
+	return openWithSystem(link.URL)
+}
+
+// exportTabLinks writes the links of a tab next to an exported file as
+// <file>.links.json, so they are not lost when the file leaves the
+// library. Nothing is written for tabs without links.
+func (a *App) exportTabLinks(tabID string, destPath string) error {
+	links, err := a.store.GetTabLinks(tabID)
+	if err != nil {
+		return fmt.Errorf("failed to get links: %w", err)
+	}
+	if len(links) == 0 {
+		return nil
+	}
+
+	type exportedLink struct {
+		Title string `json:"title"`
+		URL   string `json:"url"`
+		Added string `json:"added"` // Formatted for the locale
+	}
+	f := a.formatter()
+	exported := make([]exportedLink, len(links))
+	for i, l := range links {
+		exported[i] = exportedLink{Title: l.Title, URL: l.URL, Added: f.Date(l.AddedAt)}
+	}
+	data, err := json.MarshalIndent(exported, "", "  ")
+	if err != nil {
+		return err
+	}
+	linksPath := filepath.Join(filepath.Dir(destPath), filepath.Base(destPath)+".links.json")
+	return os.WriteFile(linksPath, data, 0644)
+}
@@ -1,24 +1,48 @@
 package main
 
 import (
+	"context"
+	"crypto/subtle"
 	"embed"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"haya-tab/pkg/metadata"
+	"haya-tab/pkg/store"
 	"io"
 	"net"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/options"
 	"github.com/wailsapp/wails/v2/pkg/options/assetserver"
+	"github.com/wailsapp/wails/v2/pkg/options/mac"
 )
 
 //go:embed all:frontend/dist
 var assets embed.FS
 
-// StartFileServer starts a local HTTP server to serve files
+// formatError converts errors returned by bound methods for the frontend.
+// Key binding conflicts are passed as {message, conflicts} so the settings
+// view can point at them; other errors stay plain messages.
+func formatError(err error) any {
+	var kbErr *store.KeyBindingsError
+	if errors.As(err, &kbErr) {
+		return map[string]interface{}{
+			"message":   err.Error(),
+			"conflicts": kbErr.Conflicts,
+		}
+	}
+	return err.Error()
+}
+
+// StartFileServer starts a local HTTP server to serve files. Streams of tab
+// files, covers and attachments require the session token of the app, see
+// GetFileServerToken.
 func StartFileServer(app *App) (int, error) {
 	listener, err := net.Listen("tcp", "127.0.0.1:0")
 	if err != nil {
@@ -28,7 +52,8 @@ func StartFileServer(app *App) (int, error) {
 
 	mux := http.NewServeMux()
 	handler := &FileHandler{app: app}
-	mux.Handle("/", handler)
+	mux.Handle("/", &tokenHandler{token: app.serverToken, next: handler})
+	mux.Handle("/api/cli/", &tokenHandler{token: app.serverToken, next: &cliHandler{app: app}})
 
 	fmt.Printf("[FileServer] Listening on http://127.0.0.1:%d\n", port)
 
@@ -46,6 +71,38 @@ type FileHandler struct {
 	app *App
 }
 
+// tokenHandler rejects the requests for tab files, covers, attachments, song
+// summaries, searches and the command line mode not carrying token, as the
+// "token" query parameter or an "Authorization: Bearer" header, so other
+// local processes cannot read the library through the file server
+type tokenHandler struct {
+	token string
+	next  http.Handler
+}
+
+func (h *tokenHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	protected := false
+	for _, prefix := range []string{"/api/file/", "/api/cover/", "/api/attachment/", "/api/summary/", "/api/search", "/api/cli/"} {
+		protected = protected || strings.HasPrefix(r.URL.Path, prefix)
+	}
+	if protected && r.Method != http.MethodOptions && !validToken(r, h.token) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	h.next.ServeHTTP(w, r)
+}
+
+// validToken reports whether r carries token, as the "token" query
+// parameter or an "Authorization: Bearer" header
+func validToken(r *http.Request, token string) bool {
+	got := r.URL.Query().Get("token")
+	if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		got = bearer
+	}
+	return token != "" && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
+
 // NewFileHandler creates a new file handler
 func NewFileHandler(app *App) *FileHandler {
 	return &FileHandler{app: app}
@@ -56,7 +113,7 @@ func (h *FileHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// Enable CORS for local development
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Range, Authorization")
 
 	if r.Method == "OPTIONS" {
 		w.WriteHeader(http.StatusOK)
@@ -67,8 +124,16 @@ func (h *FileHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// Only log api calls to avoid noise
 	if strings.HasPrefix(path, "/api/") {
 		fmt.Printf("[FileHandler] Request: %s\n", path)
+		if h.app != nil {
+			h.app.serveWithMetrics(w, r, func(w http.ResponseWriter) { h.serveAPI(w, r, path) })
+			return
+		}
 	}
+	h.serveAPI(w, r, path)
+}
 
+// serveAPI routes a request to the handler for its path
+func (h *FileHandler) serveAPI(w http.ResponseWriter, r *http.Request, path string) {
 	// Handle /api/file/{id} - stream tab file content
 	if strings.HasPrefix(path, "/api/file/") {
 		h.serveTabFile(w, r, strings.TrimPrefix(path, "/api/file/"))
@@ -81,6 +146,24 @@ func (h *FileHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Handle /api/attachment/{id} - stream an audio attachment
+	if strings.HasPrefix(path, "/api/attachment/") {
+		h.serveAttachment(w, r, strings.TrimPrefix(path, "/api/attachment/"))
+		return
+	}
+
+	// Handle /api/summary/{id} - song structure of a GP tab as JSON
+	if strings.HasPrefix(path, "/api/summary/") {
+		h.serveSummary(w, r, strings.TrimPrefix(path, "/api/summary/"))
+		return
+	}
+
+	// Handle /api/search?q=&fields=&page= - search the library as JSON
+	if path == "/api/search" {
+		h.serveSearch(w, r)
+		return
+	}
+
 	// Not found
 	http.NotFound(w, r)
 }
@@ -93,7 +176,7 @@ func (h *FileHandler) serveTabFile(w http.ResponseWriter, r *http.Request, id st
 		return
 	}
 
-	tab, err := h.app.store.GetTab(id)
+	tab, err := h.app.findTab(id)
 	if err != nil {
 		fmt.Printf("[ServeTabFile] Error getting tab %s: %v\n", id, err)
 		http.Error(w, "Tab not found", http.StatusBadRequest)
@@ -108,7 +191,7 @@ func (h *FileHandler) serveTabFile(w http.ResponseWriter, r *http.Request, id st
 	fmt.Printf("[ServeTabFile] Found tab: %s, Path: %s\n", tab.Title, tab.FilePath)
 
 	// Open the file
-	file, err := os.Open(tab.FilePath)
+	file, err := h.app.openTabFile(*tab)
 	if err != nil {
 		fmt.Printf("[ServeTabFile] Failed to open file %s: %v\n", tab.FilePath, err)
 		http.Error(w, "File not found", http.StatusInternalServerError)
@@ -132,6 +215,8 @@ func (h *FileHandler) serveTabFile(w http.ResponseWriter, r *http.Request, id st
 		contentType = "application/pdf"
 	case ".gp", ".gp5", ".gpx":
 		contentType = "application/x-guitar-pro"
+	case ".txt", ".tab", ".crd", ".cho", ".pro", ".chopro", ".chordpro":
+		contentType = "text/plain; charset=utf-8"
 	}
 
 	// Set headers
@@ -140,8 +225,10 @@ func (h *FileHandler) serveTabFile(w http.ResponseWriter, r *http.Request, id st
 	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=\"%s\"", filepath.Base(tab.FilePath)))
 	w.Header().Set("Cache-Control", "private, max-age=3600")
 
-	// Stream the file
-	io.Copy(w, file)
+	// Stream the file, stopping when the client goes away
+	if _, err := io.Copy(w, newContextReader(r.Context(), file)); err != nil && r.Context().Err() != nil {
+		fmt.Printf("[ServeTabFile] Stream of %s cancelled: %v\n", tab.FilePath, err)
+	}
 }
 
 func (h *FileHandler) serveCoverFile(w http.ResponseWriter, r *http.Request, id string) {
@@ -150,7 +237,7 @@ func (h *FileHandler) serveCoverFile(w http.ResponseWriter, r *http.Request, id
 		return
 	}
 
-	tab, err := h.app.store.GetTab(id)
+	tab, err := h.app.findTab(id)
 	if err != nil || tab == nil {
 		http.Error(w, "Tab not found", http.StatusNotFound)
 		return
@@ -193,11 +280,113 @@ func (h *FileHandler) serveCoverFile(w http.ResponseWriter, r *http.Request, id
 	w.Header().Set("Content-Length", fmt.Sprintf("%d", stat.Size()))
 	w.Header().Set("Cache-Control", "public, max-age=86400") // Cache covers for 24 hours
 
-	// Stream the file
-	io.Copy(w, file)
+	// Stream the file, stopping when the client goes away
+	io.Copy(w, newContextReader(r.Context(), file))
+}
+
+func (h *FileHandler) serveSummary(w http.ResponseWriter, r *http.Request, id string) {
+	if h.app == nil || h.app.store == nil {
+		http.Error(w, "Service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	tab, err := h.app.findTab(id)
+	if err != nil || tab == nil {
+		http.Error(w, "Tab not found", http.StatusNotFound)
+		return
+	}
+
+	if tab.Type != "gp" {
+		http.Error(w, "Summary is only available for Guitar Pro tabs", http.StatusBadRequest)
+		return
+	}
+
+	summary, err := metadata.ParseSummary(tab.FilePath)
+	if err != nil {
+		fmt.Printf("[ServeSummary] Failed to parse %s: %v\n", tab.FilePath, err)
+		http.Error(w, "Cannot parse file", http.StatusUnprocessableEntity)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+	json.NewEncoder(w).Encode(summary)
+}
+
+// searchFields are the fields /api/search can match, see GetTabsPaginated
+var searchFields = map[string]bool{"title": true, "artist": true, "album": true, "tag": true, "notes": true, "genre": true, "content": true}
+
+// search runs the /api/search query of r: q, the comma-separated fields to
+// match ("title" if empty), page and pageSize, and category to search a
+// single category. It returns an error for invalid parameters.
+func (h *FileHandler) search(r *http.Request) (TabsResponse, error) {
+	query := r.URL.Query()
+	var fields []string
+	for _, f := range strings.Split(query.Get("fields"), ",") {
+		if f = strings.TrimSpace(f); f == "" {
+			continue
+		}
+		if !searchFields[f] {
+			return TabsResponse{}, fmt.Errorf("unknown field: %s", f)
+		}
+		fields = append(fields, f)
+	}
+	page, pageSize := 1, 0
+	var err error
+	if v := query.Get("page"); v != "" {
+		if page, err = strconv.Atoi(v); err != nil {
+			return TabsResponse{}, fmt.Errorf("invalid page: %s", v)
+		}
+	}
+	if v := query.Get("pageSize"); v != "" {
+		if pageSize, err = strconv.Atoi(v); err != nil {
+			return TabsResponse{}, fmt.Errorf("invalid pageSize: %s", v)
+		}
+	}
+	category := query.Get("category")
+	return h.app.GetTabsPaginated(category, page, pageSize, query.Get("q"), fields, category == "", "", false, store.TabFilters{}), nil
+}
+
+func (h *FileHandler) serveSearch(w http.ResponseWriter, r *http.Request) {
+	if h.app == nil || h.app.store == nil {
+		http.Error(w, "Service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	result, err := h.search(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(newLANSearchPage(result))
+}
+
+// contextReader stops reading once its context is done, so a stream ends
+// as soon as the client disconnects instead of reading the rest of the file
+type contextReader struct {
+	ctx context.Context
+	io.ReadSeeker
+}
+
+func newContextReader(ctx context.Context, r io.ReadSeeker) *contextReader {
+	return &contextReader{ctx: ctx, ReadSeeker: r}
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.ReadSeeker.Read(p)
 }
 
 func main() {
+	// Script the library from the command line instead of opening the window
+	if isCLI(os.Args[1:]) {
+		os.Exit(runCLI(os.Args[1:]))
+	}
+
 	// Create an instance of the app structure
 	app := NewApp()
 
@@ -214,6 +403,14 @@ func main() {
 	// Create file handler for streaming
 	fileHandler := NewFileHandler(app)
 
+	// Open the files the app was launched with (Open With, double click on
+	// an associated file)
+	if wd, err := os.Getwd(); err == nil {
+		for _, path := range fileArgs(os.Args[1:], wd) {
+			app.openFile(path)
+		}
+	}
+
 	// Create application with options
 	err = wails.Run(&options.App{
 		Title:  "HAYA-TAB",
@@ -226,6 +423,15 @@ func main() {
 		BackgroundColour: &options.RGBA{R: 27, G: 38, B: 54, A: 1},
 		OnStartup:        app.startup,
 		OnShutdown:       app.shutdown,
+		ErrorFormatter:   formatError,
+		SingleInstanceLock: &options.SingleInstanceLock{
+			UniqueId:               singleInstanceID,
+			OnSecondInstanceLaunch: app.onSecondInstanceLaunch,
+		},
+		Mac: &mac.Options{
+			// Files opened through a file association while the app runs
+			OnFileOpen: app.openFile,
+		},
 		Bind: []interface{}{
 			app,
 		},
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"haya-tab/pkg/store"
+	"time"
+
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// errMaintenanceBusy is returned while maintenance is running
+var errMaintenanceBusy = errors.New("database maintenance is already running")
+
+// MaintainDatabase checks the integrity of the database, rebuilds the
+// full-text index, runs ANALYZE and VACUUM (see store.Maintain). Each step
+// is emitted as "db-maintenance-progress" and the result as
+// "db-maintenance-completed".
+func (a *App) MaintainDatabase() (store.MaintenanceResult, error) {
+	if !a.maintenanceBusy.CompareAndSwap(false, true) {
+		return store.MaintenanceResult{}, errMaintenanceBusy
+	}
+	defer a.maintenanceBusy.Store(false)
+
+	start := time.Now()
+	result, err := a.store.Maintain(func(step string) {
+		done := 0
+		for i, s := range store.MaintenanceSteps {
+			if s == step {
+				done = i
+			}
+		}
+		wailsRuntime.EventsEmit(a.ctx, "db-maintenance-progress", map[string]interface{}{
+			"step":  step,
+			"done":  done,
+			"total": len(store.MaintenanceSteps),
+		})
+	})
+	if err != nil {
+		a.logger.Error("Database maintenance failed: %v", err)
+		wailsRuntime.EventsEmit(a.ctx, "db-maintenance-completed", map[string]interface{}{"error": err.Error()})
+		return result, err
+	}
+	if err := a.store.SetSetting("lastMaintenance", time.Now().Unix()); err != nil {
+		a.logger.Info("Failed to record the maintenance time: %v", err)
+	}
+	if len(result.Problems) > 0 {
+		a.logger.Error("Database integrity check found %d problem(s), first: %s", len(result.Problems), result.Problems[0])
+	} else {
+		f := a.formatter()
+		a.logger.Info("Database maintenance done in %s: %s to %s", time.Since(start).Round(time.Millisecond),
+			f.Bytes(result.SizeBefore), f.Bytes(result.SizeAfter))
+	}
+	wailsRuntime.EventsEmit(a.ctx, "db-maintenance-completed", result)
+	return result, nil
+}
+
+// runMaintenance maintains the database in the background when the
+// configured interval has elapsed since the last maintenance
+func (a *App) runMaintenance() {
+	s := a.store.GetSettings()
+	if s.MaintenanceDays <= 0 || a.maintenanceBusy.Load() {
+		return
+	}
+	if time.Since(time.Unix(s.LastMaintenance, 0)) < time.Duration(s.MaintenanceDays)*24*time.Hour {
+		return
+	}
+	a.logger.Info("Scheduled database maintenance triggered (every %d days)", s.MaintenanceDays)
+	go a.MaintainDatabase()
+}
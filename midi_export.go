@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"haya-tab/pkg/metadata"
+	"os"
+)
+
+// ExportTabAsMidi exports a GP tab as a standard MIDI file, e.g. for a DAW
+// or a backing track, with repeats played out. Returns the path of the
+// file.
+func (a *App) ExportTabAsMidi(id string, destFolder string) (string, error) {
+	tab, err := a.findTab(id)
+	if err != nil {
+		return "", fmt.Errorf("failed to get tab: %w", err)
+	}
+	if tab == nil {
+		return "", fmt.Errorf("tab not found")
+	}
+	if tab.Type != "gp" {
+		return "", fmt.Errorf("MIDI export is only available for Guitar Pro tabs")
+	}
+	if destFolder == "" {
+		return "", fmt.Errorf("no destination folder")
+	}
+
+	destPath := uniquePath(destFolder, sanitizeFileName(tab.Title), ".mid")
+	if err := writeMidi(tab.FilePath, nil, destPath); err != nil {
+		return "", err
+	}
+	a.logger.Info("Exported %s as MIDI to %s", tab.Title, destPath)
+	return destPath, nil
+}
+
+// writeMidi writes the given tracks of the GP file at path, all of them if
+// trackIndexes is empty, as a MIDI file to destPath
+func writeMidi(path string, trackIndexes []int, destPath string) error {
+	s, err := metadata.ParseScore(path)
+	if err != nil {
+		return fmt.Errorf("failed to read the score: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := s.WriteMIDI(&buf, trackIndexes); err != nil {
+		return fmt.Errorf("failed to write MIDI: %w", err)
+	}
+	if err := os.WriteFile(destPath, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write the MIDI file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"haya-tab/pkg/metadata"
+
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// SetOfflineMode turns offline mode on or off and saves it in the settings.
+// While on, cover downloads are held and no request leaves the machine;
+// turning it off downloads the held covers.
+func (a *App) SetOfflineMode(offline bool) error {
+	settings := a.store.GetSettings()
+	settings.OfflineMode = offline
+	if err := a.store.UpdateSettings(settings); err != nil {
+		return err
+	}
+	a.applyOfflineMode()
+	return nil
+}
+
+// applyOfflineMode pauses or resumes the cover downloads according to
+// Settings.OfflineMode and emits "offline-mode-changed" when it changes
+func (a *App) applyOfflineMode() {
+	offline := a.store.GetSettings().OfflineMode
+	metadata.SetOffline(offline)
+	if offline == a.coverPool.IsPaused() {
+		return
+	}
+
+	if offline {
+		a.coverPool.Pause()
+		a.logger.Info("Offline mode on: cover downloads are held")
+	} else {
+		held := a.coverPool.QueueSize()
+		a.coverPool.Resume()
+		a.logger.Info("Offline mode off: downloading %d held cover(s)", held)
+		a.syncService.ResumeCoverSweep()
+	}
+	wailsRuntime.EventsEmit(a.ctx, "offline-mode-changed", offline)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// fileArgs returns the command line arguments that are existing files,
+// resolving relative paths against dir
+func fileArgs(args []string, dir string) []string {
+	var paths []string
+	for _, arg := range args {
+		if !filepath.IsAbs(arg) {
+			arg = filepath.Join(dir, arg)
+		}
+		if info, err := os.Stat(arg); err == nil && !info.IsDir() {
+			paths = append(paths, arg)
+		}
+	}
+	return paths
+}
+
+// openFile opens a file given on the command line, forwarded by a second
+// instance or opened through a file association. Until the frontend
+// listens for "open-file-request" (see ReadyForOpenFiles), files are
+// queued.
+func (a *App) openFile(path string) {
+	a.openFilesMu.Lock()
+	if !a.openFilesReady {
+		a.pendingOpenFiles = append(a.pendingOpenFiles, path)
+		a.openFilesMu.Unlock()
+		return
+	}
+	a.openFilesMu.Unlock()
+
+	if err := a.requestOpenFile(path); err != nil {
+		a.logger.Error("Cannot open %s: %v", filepath.Base(path), err)
+	}
+}
+
+// requestOpenFile adds path to the library as a linked tab unless it is
+// already there, and emits "open-file-request" with its tab for the
+// frontend to open
+func (a *App) requestOpenFile(path string) error {
+	tab, err := a.store.GetTabByPath(path)
+	if err != nil {
+		return fmt.Errorf("failed to look up tab: %w", err)
+	}
+	if tab == nil {
+		newTab := a.syncService.ProcessFile(path)
+		if newTab.Type == "unknown" {
+			return fmt.Errorf("unsupported file type")
+		}
+		if err := a.SaveTab(newTab, false); err != nil {
+			return err
+		}
+		tab = &newTab
+	}
+	a.logger.Info("Opening %s from outside the app", path)
+	wailsRuntime.EventsEmit(a.ctx, "open-file-request", *tab)
+	return nil
+}
+
+// ReadyForOpenFiles is called by the frontend once it listens for
+// "open-file-request". Opens the files the app was launched with.
+func (a *App) ReadyForOpenFiles() {
+	a.openFilesMu.Lock()
+	pending := a.pendingOpenFiles
+	a.pendingOpenFiles = nil
+	a.openFilesReady = true
+	a.openFilesMu.Unlock()
+
+	for _, path := range pending {
+		a.openFile(path)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"haya-tab/pkg/store"
+	"strconv"
+	"strings"
+	"time"
+
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// OperationsResult is the outcome of ApplyOperations
+type OperationsResult struct {
+	Applied     int      `json:"applied"`     // Number of operations applied: all or none
+	CategoryIDs []string `json:"categoryIds"` // IDs of the created categories, in order
+	Error       string   `json:"error,omitempty"`
+}
+
+// ApplyOperations applies a batch of operations (see store.Operation) in
+// one transaction, all or nothing, e.g. creating a category and moving tabs
+// into it. A created category without an ID gets one; later operations of
+// the batch refer to the category created by operation n (from 1) with the
+// category or parent ID "$n". Emits "operations-applied" with the result.
+func (a *App) ApplyOperations(ops []store.Operation) (OperationsResult, error) {
+	result := OperationsResult{CategoryIDs: []string{}}
+	err := a.applyOperations(ops, &result)
+	if err != nil {
+		result.Error = err.Error()
+	} else {
+		result.Applied = len(ops)
+	}
+	wailsRuntime.EventsEmit(a.ctx, "operations-applied", result)
+	return result, err
+}
+
+func (a *App) applyOperations(ops []store.Operation, result *OperationsResult) error {
+	created := map[string]string{} // "$n" to the ID of the category created by operation n
+	resolve := func(id string) (string, error) {
+		if !strings.HasPrefix(id, "$") {
+			return id, nil
+		}
+		if created[id] == "" {
+			return "", fmt.Errorf("%s does not refer to a category created before", id)
+		}
+		return created[id], nil
+	}
+
+	base := time.Now().UnixNano()
+	for i := range ops {
+		op := &ops[i]
+		var err error
+		if op.CategoryID, err = resolve(op.CategoryID); err == nil {
+			op.ParentID, err = resolve(op.ParentID)
+		}
+		if err == nil {
+			err = validateOperation(*op)
+		}
+		if err != nil {
+			return fmt.Errorf("operation %d (%s): %w", i+1, op.Op, err)
+		}
+
+		if op.Op == "createCategory" {
+			if op.CategoryID == "" {
+				op.CategoryID = fmt.Sprintf("cat_%d", base+int64(i))
+			}
+			created["$"+strconv.Itoa(i+1)] = op.CategoryID
+			result.CategoryIDs = append(result.CategoryIDs, op.CategoryID)
+		}
+	}
+
+	if err := a.store.ApplyOperations(ops, time.Now().Unix()); err != nil {
+		result.CategoryIDs = []string{}
+		return err
+	}
+	for _, op := range ops {
+		if op.Op == "deleteCategory" {
+			removeCollages(op.CategoryID, "")
+		}
+	}
+	a.logger.Info("Applied %d operation(s)", len(ops))
+	return nil
+}
+
+// validateOperation checks the values of an operation, as the bindings
+// doing the same one at a time do
+func validateOperation(op store.Operation) error {
+	switch op.Op {
+	case "setRating":
+		if op.Rating < 0 || op.Rating > 5 {
+			return fmt.Errorf("rating must be between 0 and 5 (0 clears it)")
+		}
+	case "setPracticeStatus":
+		if !practiceStatuses[op.PracticeStatus] {
+			return fmt.Errorf("invalid practice status: %s", op.PracticeStatus)
+		}
+	case "setDifficulty":
+		if op.Difficulty != "" && store.DifficultyLevel(op.Difficulty) == 0 {
+			return fmt.Errorf("invalid difficulty: %s", op.Difficulty)
+		}
+	case "moveCategory":
+		if op.CategoryID == op.ParentID {
+			return fmt.Errorf("cannot move category into itself")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// orphanGrace is how recent a file may be and still not be an orphan: it
+// may belong to an import not yet saved
+const orphanGrace = 10 * time.Minute
+
+// OrphanFile is a file of app storage or of the covers directory that no
+// tab or category refers to
+type OrphanFile struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// MissingFile is a tab or a category referring to a file that does not
+// exist
+type MissingFile struct {
+	Kind string `json:"kind"` // "file" for the file of a tab, "cover" for a cover, "categoryCover" for the cover of a category
+	ID   string `json:"id"`   // Tab or category ID
+	Name string `json:"name"` // Tab title or category name
+	Path string `json:"path"`
+}
+
+// OrphanReport is the outcome of CleanupOrphans
+type OrphanReport struct {
+	Files   []OrphanFile  `json:"files"`
+	Bytes   int64         `json:"bytes"` // Size of Files
+	Size    string        `json:"size"`  // Bytes formatted for the locale
+	Missing []MissingFile `json:"missing"`
+	Deleted bool          `json:"deleted"` // Whether the orphans were removed
+}
+
+// CleanupOrphans looks for the files of storage/ and covers/ that no tab or
+// category refers to, left behind by interrupted imports or older
+// versions, and for the tabs and categories referring to files that no
+// longer exist. With remove true, the orphan files are deleted, missing
+// covers are cleared so they are fetched again, and managed tabs whose
+// stored file is gone are deleted. Linked tabs whose file is missing are
+// only reported: their drive may be disconnected. Generated collages are
+// left to the derived image cache, and files written in the last
+// orphanGrace are never orphans.
+func (a *App) CleanupOrphans(remove bool) (OrphanReport, error) {
+	report := OrphanReport{Files: []OrphanFile{}, Missing: []MissingFile{}}
+	tabs, err := a.store.GetTabs()
+	if err != nil {
+		return report, fmt.Errorf("failed to get tabs: %w", err)
+	}
+	categories, err := a.store.GetCategories()
+	if err != nil {
+		return report, fmt.Errorf("failed to get categories: %w", err)
+	}
+
+	referenced := map[string]bool{}
+	exists := func(path string) bool {
+		referenced[filepath.Clean(path)] = true
+		_, err := os.Stat(path)
+		return err == nil
+	}
+	var managedMissing []string
+	for _, t := range tabs {
+		if !exists(t.FilePath) {
+			report.Missing = append(report.Missing, MissingFile{Kind: "file", ID: t.ID, Name: t.Title, Path: t.FilePath})
+			if t.IsManaged {
+				managedMissing = append(managedMissing, t.ID)
+			}
+		}
+		if t.CoverPath != "" && !exists(t.CoverPath) {
+			report.Missing = append(report.Missing, MissingFile{Kind: "cover", ID: t.ID, Name: t.Title, Path: t.CoverPath})
+		}
+	}
+	for _, c := range categories {
+		if c.CoverPath != "" && !exists(c.CoverPath) {
+			report.Missing = append(report.Missing, MissingFile{Kind: "categoryCover", ID: c.ID, Name: c.Name, Path: c.CoverPath})
+		}
+	}
+
+	appDir := getAppDir()
+	collages := collageDir()
+	for _, dir := range []string{filepath.Join(appDir, "storage"), filepath.Join(appDir, "covers")} {
+		filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if d.IsDir() {
+				if path == collages {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			// Files being written are hidden until complete
+			if strings.HasPrefix(d.Name(), ".") || referenced[filepath.Clean(path)] {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil || time.Since(info.ModTime()) < orphanGrace {
+				return nil
+			}
+			report.Files = append(report.Files, OrphanFile{Path: path, Size: info.Size()})
+			report.Bytes += info.Size()
+			return nil
+		})
+	}
+	sort.Slice(report.Files, func(i, j int) bool { return report.Files[i].Path < report.Files[j].Path })
+	report.Size = a.formatter().Bytes(report.Bytes)
+
+	if !remove {
+		return report, nil
+	}
+	for _, f := range report.Files {
+		if err := os.Remove(f.Path); err != nil && !os.IsNotExist(err) {
+			a.logger.Error("Failed to remove orphan file %s: %v", f.Path, err)
+		}
+	}
+	cleared := 0
+	for _, m := range report.Missing {
+		if m.Kind == "file" {
+			continue
+		}
+		if err := a.store.ClearCover(m.ID, m.Path, m.Kind == "categoryCover"); err != nil {
+			a.logger.Error("Failed to clear the cover of %s: %v", m.Name, err)
+			continue
+		}
+		cleared++
+	}
+	for _, id := range managedMissing {
+		if err := a.DeleteTab(id); err != nil {
+			a.logger.Error("Failed to delete tab %s: %v", id, err)
+		}
+	}
+	report.Deleted = true
+	a.logger.Info("Removed %d orphan file(s), %s; cleared %d missing cover(s), deleted %d tab(s) without file",
+		len(report.Files), report.Size, cleared, len(managedMissing))
+	return report, nil
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"haya-tab/pkg/pdf"
+	"haya-tab/pkg/store"
+	"image"
+	_ "image/png"
+	"strings"
+	"time"
+
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// paperSizes are the paper sizes of print layouts in millimetres, portrait
+var paperSizes = map[string][2]float64{
+	"A3":     {297, 420},
+	"A4":     {210, 297},
+	"A5":     {148, 210},
+	"Letter": {216, 279},
+	"Legal":  {216, 356},
+}
+
+// pdfRenderTimeout bounds the wait of ExportTabAsPDF for the rendered pages
+const pdfRenderTimeout = 3 * time.Minute
+
+// pdfRenderScale is the number of pixels per CSS pixel pages are rendered
+// with: 2 gives 192 dpi, sharp enough for printing
+const pdfRenderScale = 2
+
+// PDFExportOptions are the options of ExportTabAsPDF
+type PDFExportOptions struct {
+	DestFolder string               `json:"destFolder"`
+	Layout     *store.PrintSettings `json:"layout,omitempty"` // Paper size, margins, pages per sheet and tracks; the saved print settings of the tab if nil
+}
+
+// PDFRenderRequest asks the frontend to render the pages of a GP tab for
+// ExportTabAsPDF. It answers with CompletePDFRender.
+type PDFRenderRequest struct {
+	ID            string  `json:"id"`
+	TabID         string  `json:"tabId"`
+	Tracks        []int   `json:"tracks"`        // Track indexes to render, empty for all
+	ContentWidth  float64 `json:"contentWidth"`  // Size of a page inside the margins, in millimetres
+	ContentHeight float64 `json:"contentHeight"` // Millimetres
+	Scale         float64 `json:"scale"`         // Pixels per CSS pixel of the rendered pages
+}
+
+// pdfRenderResult is the answer of the frontend to a PDFRenderRequest
+type pdfRenderResult struct {
+	pages []string // PNG data URLs, one per page
+	err   error
+}
+
+// ExportTabAsPDF exports a GP tab as a paginated PDF for printing, laid out
+// as options.Layout says. The score is rendered by alphaTab in the window,
+// out of sight: the request is emitted as "render-tab-pdf", the pages come
+// back through CompletePDFRender and are assembled here. Returns the path
+// of the PDF.
+func (a *App) ExportTabAsPDF(id string, options PDFExportOptions) (string, error) {
+	tab, err := a.findTab(id)
+	if err != nil {
+		return "", fmt.Errorf("failed to get tab: %w", err)
+	}
+	if tab == nil {
+		return "", fmt.Errorf("tab not found")
+	}
+	if tab.Type != "gp" {
+		return "", fmt.Errorf("PDF export is only available for Guitar Pro tabs")
+	}
+	if options.DestFolder == "" {
+		return "", fmt.Errorf("no destination folder")
+	}
+
+	layout := store.DefaultPrintSettings(id)
+	if options.Layout != nil {
+		layout = *options.Layout
+	} else if !tab.Shared {
+		layout = a.GetPrintSettings(id)
+	}
+	if err := checkPrintSettings(layout); err != nil {
+		return "", err
+	}
+	paper := paperSizes[layout.PaperSize]
+	request := PDFRenderRequest{
+		ID:            rand.Text(),
+		TabID:         id,
+		Tracks:        layout.Tracks,
+		ContentWidth:  paper[0] - layout.MarginLeft - layout.MarginRight,
+		ContentHeight: paper[1] - layout.MarginTop - layout.MarginBottom,
+		Scale:         pdfRenderScale,
+	}
+	if request.ContentWidth <= 0 || request.ContentHeight <= 0 {
+		return "", fmt.Errorf("the margins leave no room on %s paper", layout.PaperSize)
+	}
+
+	pages, err := a.renderPDFPages(request)
+	if err != nil {
+		return "", err
+	}
+	w := pdf.NewWriter()
+	w.SetTitle(tab.Title)
+	if err := layoutPDFPages(w, pages, layout); err != nil {
+		return "", fmt.Errorf("failed to lay out the pages: %w", err)
+	}
+	destPath := uniquePath(options.DestFolder, sanitizeFileName(tab.Title), ".pdf")
+	if err := w.Save(destPath); err != nil {
+		return "", fmt.Errorf("failed to write the PDF: %w", err)
+	}
+
+	a.logger.Info("Exported %s as a PDF of %d page(s) to %s", tab.Title, len(pages), destPath)
+	return destPath, nil
+}
+
+// CompletePDFRender hands the pages rendered for a PDFRenderRequest back to
+// ExportTabAsPDF, as PNG data URLs, or the error that stopped the render
+func (a *App) CompletePDFRender(requestID string, pages []string, renderErr string) error {
+	a.pdfRendersMu.Lock()
+	done, ok := a.pdfRenders[requestID]
+	a.pdfRendersMu.Unlock()
+	if !ok {
+		return fmt.Errorf("no PDF export is waiting for this render")
+	}
+
+	result := pdfRenderResult{pages: pages}
+	if renderErr != "" {
+		result.err = fmt.Errorf("failed to render the score: %s", renderErr)
+	}
+	select {
+	case done <- result:
+	default: // Answered already
+	}
+	return nil
+}
+
+// renderPDFPages emits request for the frontend to render and waits for
+// the pages
+func (a *App) renderPDFPages(request PDFRenderRequest) ([]image.Image, error) {
+	if a.ctx == nil {
+		return nil, fmt.Errorf("PDF export needs the app window")
+	}
+	done := make(chan pdfRenderResult, 1)
+	a.pdfRendersMu.Lock()
+	if a.pdfRenders == nil {
+		a.pdfRenders = map[string]chan pdfRenderResult{}
+	}
+	a.pdfRenders[request.ID] = done
+	a.pdfRendersMu.Unlock()
+	defer func() {
+		a.pdfRendersMu.Lock()
+		delete(a.pdfRenders, request.ID)
+		a.pdfRendersMu.Unlock()
+	}()
+
+	wailsRuntime.EventsEmit(a.ctx, "render-tab-pdf", request)
+	var result pdfRenderResult
+	select {
+	case result = <-done:
+	case <-time.After(pdfRenderTimeout):
+		return nil, fmt.Errorf("rendering the score timed out")
+	case <-a.ctx.Done():
+		return nil, a.ctx.Err()
+	}
+	if result.err != nil {
+		return nil, result.err
+	}
+	if len(result.pages) == 0 {
+		return nil, fmt.Errorf("the score rendered no pages")
+	}
+
+	pages := make([]image.Image, len(result.pages))
+	for i, page := range result.pages {
+		img, err := decodeDataURL(page)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read page %d: %w", i+1, err)
+		}
+		pages[i] = img
+	}
+	return pages, nil
+}
+
+// layoutPDFPages adds the rendered pages to w, layout.PagesPerSheet on each
+// sheet of its paper. Two pages share a landscape sheet side by side, four
+// a portrait sheet in a grid; pages are scaled down to fit, keeping their
+// aspect ratio.
+func layoutPDFPages(w *pdf.Writer, pages []image.Image, layout store.PrintSettings) error {
+	paper := paperSizes[layout.PaperSize]
+	sheetW, sheetH := paper[0], paper[1]
+	cols, rows := 1, 1
+	switch layout.PagesPerSheet {
+	case 2:
+		sheetW, sheetH = sheetH, sheetW
+		cols = 2
+	case 4:
+		cols, rows = 2, 2
+	}
+	// The sheet inside the margins is split into a cell per page
+	cellW := (sheetW - layout.MarginLeft - layout.MarginRight) / float64(cols)
+	cellH := (sheetH - layout.MarginTop - layout.MarginBottom) / float64(rows)
+
+	for start := 0; start < len(pages); start += cols * rows {
+		var placements []pdf.PagePlacement
+		for i, img := range pages[start:min(start+cols*rows, len(pages))] {
+			b := img.Bounds()
+			scale := min(cellW/float64(b.Dx()), cellH/float64(b.Dy()))
+			width, height := float64(b.Dx())*scale, float64(b.Dy())*scale
+			// Centered horizontally, at the top of the cell; PDF measures
+			// from the bottom
+			x := layout.MarginLeft + float64(i%cols)*cellW + (cellW-width)/2
+			y := sheetH - layout.MarginTop - float64(i/cols)*cellH - height
+			placements = append(placements, pdf.PagePlacement{
+				Image: img,
+				Box:   pdf.Rect{X: mmToPt(x), Y: mmToPt(y), W: mmToPt(width), H: mmToPt(height)},
+			})
+		}
+		if err := w.AddImagePage(mmToPt(sheetW), mmToPt(sheetH), placements); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mmToPt converts millimetres to PDF points
+func mmToPt(mm float64) float64 {
+	return mm * 72 / 25.4
+}
+
+// decodeDataURL decodes an image sent by the frontend as a base64 data URL,
+// e.g. "data:image/png;base64,..."
+func decodeDataURL(url string) (image.Image, error) {
+	_, data, ok := strings.Cut(url, ";base64,")
+	if !ok || !strings.HasPrefix(url, "data:image/") {
+		return nil, fmt.Errorf("not a base64 image data URL")
+	}
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(bytes.NewReader(raw))
+	return img, err
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"fmt"
+	"haya-tab/pkg/metadata"
+	"haya-tab/pkg/pdf"
+	"haya-tab/pkg/store"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PageRange is a part of a PDF to split off. Pages are 1-based and inclusive.
+type PageRange struct {
+	From  int    `json:"from"`
+	To    int    `json:"to"`
+	Title string `json:"title"` // Defaults to "<tab title> (p. From-To)"
+}
+
+// GetPDFPageCount returns the number of pages of a PDF tab
+func (a *App) GetPDFPageCount(tabID string) (int, error) {
+	tab, err := a.getPDFTab(tabID)
+	if err != nil {
+		return 0, err
+	}
+	doc, err := pdf.Open(tab.FilePath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read PDF: %w", err)
+	}
+	return doc.NumPages(), nil
+}
+
+// SplitPDF creates a managed tab for each page range of a PDF tab, e.g. for
+// the songs of a compilation book. The new tabs get the artist, album,
+// tag, categories and cover of the original, which is kept. Returns the
+// new tabs.
+func (a *App) SplitPDF(tabID string, ranges []PageRange) ([]store.Tab, error) {
+	tab, err := a.getPDFTab(tabID)
+	if err != nil {
+		return nil, err
+	}
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("no page ranges given")
+	}
+
+	doc, err := pdf.Open(tab.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+
+	// Validate everything before writing any file
+	titles := map[string]bool{}
+	for i := range ranges {
+		r := &ranges[i]
+		if r.From < 1 || r.To < r.From || r.To > doc.NumPages() {
+			return nil, fmt.Errorf("invalid page range %d-%d (the PDF has %d pages)", r.From, r.To, doc.NumPages())
+		}
+		r.Title = strings.TrimSpace(r.Title)
+		if r.Title == "" {
+			if r.From == r.To {
+				r.Title = fmt.Sprintf("%s (p. %d)", tab.Title, r.From)
+			} else {
+				r.Title = fmt.Sprintf("%s (p. %d-%d)", tab.Title, r.From, r.To)
+			}
+		}
+		if titles[strings.ToLower(r.Title)] {
+			return nil, fmt.Errorf("duplicate title: %s", r.Title)
+		}
+		titles[strings.ToLower(r.Title)] = true
+		if existing, err := a.store.GetTabByTitle(r.Title); err != nil {
+			return nil, fmt.Errorf("failed to check for duplicate title: %w", err)
+		} else if existing != nil {
+			return nil, fmt.Errorf("a tab with title '%s' already exists", r.Title)
+		}
+	}
+
+	var created []store.Tab
+	rollback := func() {
+		for _, t := range created {
+			a.store.DeleteTab(t.ID)
+			a.removeManagedFiles(t)
+		}
+	}
+
+	base := time.Now().UnixNano()
+	for i, r := range ranges {
+		w := pdf.NewWriter()
+		w.SetTitle(r.Title)
+		for page := r.From; page <= r.To; page++ {
+			if err := w.AddPage(doc, page-1); err != nil {
+				rollback()
+				return nil, err
+			}
+		}
+
+		piece := a.newManagedPDFTab(strconv.FormatInt(base+int64(i), 10), r.Title, tab)
+		if err := a.saveManagedPDF(w, &piece); err != nil {
+			if piece.CoverPath != "" {
+				os.Remove(piece.CoverPath)
+			}
+			rollback()
+			return nil, fmt.Errorf("failed to write %s: %w", r.Title, err)
+		}
+		if err := a.store.AddTab(piece); err != nil {
+			a.removeManagedFiles(piece)
+			rollback()
+			return nil, fmt.Errorf("failed to save %s: %w", r.Title, err)
+		}
+		created = append(created, piece)
+	}
+
+	a.logger.Info("Split %s into %d tab(s)", tab.Title, len(created))
+	return created, nil
+}
+
+// MergePDFs combines PDF tabs, in the given order, into a new managed tab,
+// e.g. for a medley or a lesson packet. The new tab gets the metadata and
+// cover of the first tab and the categories of all of them. The source
+// tabs are removed unless keepSources is set.
+func (a *App) MergePDFs(ids []string, title string, keepSources bool) (store.Tab, error) {
+	if len(ids) < 2 {
+		return store.Tab{}, fmt.Errorf("select at least two PDFs to merge")
+	}
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return store.Tab{}, fmt.Errorf("title is required")
+	}
+	if existing, err := a.store.GetTabByTitle(title); err != nil {
+		return store.Tab{}, fmt.Errorf("failed to check for duplicate title: %w", err)
+	} else if existing != nil {
+		return store.Tab{}, fmt.Errorf("a tab with title '%s' already exists", title)
+	}
+
+	w := pdf.NewWriter()
+	w.SetTitle(title)
+	var sources []*store.Tab
+	seen := map[string]bool{}
+	for _, id := range ids {
+		if seen[id] {
+			return store.Tab{}, fmt.Errorf("tab %s is listed twice", id)
+		}
+		seen[id] = true
+
+		tab, err := a.getPDFTab(id)
+		if err != nil {
+			return store.Tab{}, err
+		}
+		doc, err := pdf.Open(tab.FilePath)
+		if err != nil {
+			return store.Tab{}, fmt.Errorf("failed to read %s: %w", tab.Title, err)
+		}
+		for page := 0; page < doc.NumPages(); page++ {
+			if err := w.AddPage(doc, page); err != nil {
+				return store.Tab{}, fmt.Errorf("failed to copy %s: %w", tab.Title, err)
+			}
+		}
+		sources = append(sources, tab)
+	}
+
+	merged := a.newManagedPDFTab(strconv.FormatInt(time.Now().UnixNano(), 10), title, sources[0])
+	categories := map[string]bool{}
+	merged.CategoryIDs = nil
+	for _, tab := range sources {
+		for _, id := range tab.CategoryIDs {
+			if !categories[id] {
+				categories[id] = true
+				merged.CategoryIDs = append(merged.CategoryIDs, id)
+			}
+		}
+	}
+
+	if err := a.saveManagedPDF(w, &merged); err != nil {
+		if merged.CoverPath != "" {
+			os.Remove(merged.CoverPath)
+		}
+		return store.Tab{}, fmt.Errorf("failed to write %s: %w", title, err)
+	}
+	if err := a.store.AddTab(merged); err != nil {
+		a.removeManagedFiles(merged)
+		return store.Tab{}, fmt.Errorf("failed to save %s: %w", title, err)
+	}
+	a.logger.Info("Merged %d PDF(s) into %s (%d pages)", len(sources), title, w.NumPages())
+
+	if !keepSources {
+		a.BatchDeleteTabs(ids)
+	}
+	return merged, nil
+}
+
+// getPDFTab returns a tab, checking that it is a PDF
+func (a *App) getPDFTab(tabID string) (*store.Tab, error) {
+	tab, err := a.store.GetTab(tabID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tab: %w", err)
+	}
+	if tab == nil {
+		return nil, fmt.Errorf("tab not found: %s", tabID)
+	}
+	if tab.Type != "pdf" {
+		return nil, fmt.Errorf("%s is not a PDF", tab.Title)
+	}
+	return tab, nil
+}
+
+// newManagedPDFTab returns a tab for a PDF to write to app storage (see
+// saveManagedPDF), with the metadata of source. The cover of source is
+// copied, since deleting a managed tab deletes its cover.
+func (a *App) newManagedPDFTab(id, title string, source *store.Tab) store.Tab {
+	appDir := getAppDir()
+	tab := store.Tab{
+		ID:          id,
+		Title:       title,
+		Artist:      source.Artist,
+		Album:       source.Album,
+		Type:        "pdf",
+		IsManaged:   true,
+		CategoryIDs: source.CategoryIDs,
+		Country:     source.Country,
+		Language:    source.Language,
+		Tag:         source.Tag,
+		AddedAt:     time.Now().Unix(),
+		Difficulty:  source.Difficulty,
+		Script:      metadata.DetectScript(title, source.Artist),
+	}
+	if source.CoverPath != "" {
+		coverPath := filepath.Join(appDir, "covers", id+filepath.Ext(source.CoverPath))
+		if err := copyFile(source.CoverPath, coverPath); err == nil {
+			tab.CoverPath = coverPath
+			tab.CoverColor = source.CoverColor
+		}
+	}
+	return tab
+}
+
+// saveManagedPDF writes a PDF to app storage as the file of tab, through a
+// temporary file
+func (a *App) saveManagedPDF(w *pdf.Writer, tab *store.Tab) error {
+	tmp, err := os.CreateTemp("", "haya-pdf-*.pdf")
+	if err != nil {
+		return err
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+	if err := w.Save(tmp.Name()); err != nil {
+		return err
+	}
+
+	locator, hash, err := a.storage.Import(tmp.Name(), tab.ID+".pdf")
+	if err != nil {
+		return err
+	}
+	tab.FilePath = locator
+	tab.FileHash = hash
+	if info, err := os.Stat(tmp.Name()); err == nil {
+		tab.FileSize = info.Size()
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"haya-tab/pkg/pedal"
+	"haya-tab/pkg/store"
+
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// pedalLearning is a pending LearnPedal
+type pedalLearning struct {
+	profile string // Name of the profile to bind the pedal in; "" for Settings.PedalBindings
+	action  string
+}
+
+// applyPedals starts or stops reading foot controllers according to the
+// settings, reopening them when the profiles name other controllers
+func (a *App) applyPedals() {
+	settings := a.store.GetSettings()
+	namesChanged := a.pedals.SetDeviceNames(pedalDeviceNames(settings))
+	running := a.pedals.IsRunning()
+	if !settings.PedalEnabled {
+		if running {
+			a.pedals.Stop()
+			a.logger.Info("Stopped reading foot controllers")
+		}
+		return
+	}
+	if running && !namesChanged {
+		return
+	}
+	a.pedals.Stop()
+	devices, err := a.pedals.Start()
+	if err != nil {
+		a.logger.Info("Cannot read foot controllers: %v", err)
+		return
+	}
+	a.logger.Info("Reading %d foot controller(s)", len(devices))
+}
+
+// pedalDeviceNames returns the controller names of the pedal profiles
+func pedalDeviceNames(settings store.Settings) []string {
+	var names []string
+	for _, p := range settings.PedalProfiles {
+		if p.Device != "" {
+			names = append(names, p.Device)
+		}
+	}
+	return names
+}
+
+// handlePedalPress emits "pedal-press" for every press and "key-action"
+// with the action the pedal is bound to. The frontend runs key actions as
+// if their key was pressed, so pedals work wherever the keyboard shortcuts
+// do. While learning (see LearnPedal), the press is bound instead.
+func (a *App) handlePedalPress(p pedal.Press) {
+	wailsRuntime.EventsEmit(a.ctx, "pedal-press", p)
+	if learn := a.pedalLearn.Swap(nil); learn != nil {
+		a.bindLearnedPedal(*learn, p)
+		return
+	}
+	if action := a.store.GetSettings().PedalAction(p.Device, p.Control); action != "" {
+		wailsRuntime.EventsEmit(a.ctx, "key-action", action)
+	}
+}
+
+// LearnPedal binds the next pedal pressed to action, in the pedal profile
+// named profile ("" for the bindings used when no profile binds a pedal). Emits
+// "pedal-learned" with the updated settings once a pedal is pressed.
+func (a *App) LearnPedal(profile, action string) error {
+	if err := pedal.CheckSupported(); err != nil {
+		return err
+	}
+	settings := a.store.GetSettings()
+	if !settings.PedalEnabled {
+		return fmt.Errorf("foot controllers are turned off")
+	}
+	if profile != "" && pedalProfileIndex(settings, profile) < 0 {
+		return fmt.Errorf("pedal profile not found: %s", profile)
+	}
+	a.pedalLearn.Store(&pedalLearning{profile: profile, action: action})
+	return nil
+}
+
+// CancelPedalLearn stops waiting for the pedal of LearnPedal
+func (a *App) CancelPedalLearn() {
+	a.pedalLearn.Store(nil)
+}
+
+// bindLearnedPedal saves the binding of a learned pedal. A pedal learned
+// in a profile for any controller makes the profile specific to the
+// controller it came from.
+func (a *App) bindLearnedPedal(learn pedalLearning, p pedal.Press) {
+	settings := a.store.GetSettings()
+	if learn.profile == "" {
+		settings.PedalBindings[p.Control] = learn.action
+	} else {
+		i := pedalProfileIndex(settings, learn.profile)
+		if i < 0 {
+			a.logger.Info("Pedal profile %s was removed while learning", learn.profile)
+			return
+		}
+		profile := &settings.PedalProfiles[i]
+		if profile.Device == "" {
+			profile.Device = p.Device
+		}
+		profile.Bindings[p.Control] = learn.action
+	}
+	if err := a.store.UpdateSettings(settings); err != nil {
+		a.logger.Error("Failed to save learned pedal: %v", err)
+		return
+	}
+	a.applyPedals()
+	wailsRuntime.EventsEmit(a.ctx, "pedal-learned", map[string]interface{}{
+		"press":    p,
+		"profile":  learn.profile,
+		"action":   learn.action,
+		"settings": settings,
+	})
+}
+
+// pedalProfileIndex returns the index of the profile named name, or -1
+func pedalProfileIndex(settings store.Settings, name string) int {
+	for i, p := range settings.PedalProfiles {
+		if p.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// GetPedalPresets returns the built-in pedal profiles for common page
+// turners
+func (a *App) GetPedalPresets() []store.PedalProfile {
+	return store.PedalPresets()
+}
+
+// GetPedalDevices returns the connected foot controllers, or
+// pedal.ErrUnsupported on systems where they cannot be read
+func (a *App) GetPedalDevices() ([]pedal.Device, error) {
+	devices, err := pedal.Discover(pedalDeviceNames(a.store.GetSettings()))
+	if err != nil {
+		return nil, err
+	}
+	if devices == nil {
+		return []pedal.Device{}, nil
+	}
+	return devices, nil
+}
+
+// ReconnectPedals reopens the foot controllers, picking up the ones plugged
+// in since they were opened. Returns the controllers being read.
+func (a *App) ReconnectPedals() ([]pedal.Device, error) {
+	a.pedals.Stop()
+	if err := pedal.CheckSupported(); err != nil {
+		return nil, err
+	}
+	if !a.store.GetSettings().PedalEnabled {
+		return []pedal.Device{}, nil
+	}
+	devices, err := a.pedals.Start()
+	if err != nil {
+		return nil, err
+	}
+	if devices == nil {
+		return []pedal.Device{}, nil
+	}
+	return devices, nil
+}
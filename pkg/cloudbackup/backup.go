@@ -0,0 +1,320 @@
+// Package cloudbackup backs the library up to an S3-compatible bucket and
+// restores it. A backup holds a snapshot of the database, the files of
+// managed tabs and the covers, under the prefix of the Config:
+//
+//	database/haya-tab.db
+//	storage/<stored file>
+//	covers/<cover>
+//	manifest.json
+//
+// Files already in the bucket with the same content are not uploaded again,
+// and files removed from the library are left in the bucket. The manifest is
+// written last, so a backup without one is incomplete.
+package cloudbackup
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Keys of a backup, under the prefix of the Config
+const (
+	databaseKey = "database/haya-tab.db"
+	manifestKey = "manifest.json"
+)
+
+// Files written by Restore next to the database, swapped in by ApplyRestore
+const (
+	restoreDB       = "restore.db"
+	restoreManifest = "restore.json"
+)
+
+// Config locates the bucket backups go to
+type Config struct {
+	Endpoint  string // e.g. "https://s3.us-west-004.backblazeb2.com"
+	Region    string // "us-east-1" if empty
+	Bucket    string
+	Prefix    string // Folder of the backup in the bucket, e.g. "haya-tab"; empty for the root
+	AccessKey string
+	SecretKey string
+}
+
+// Validate checks that cfg has what requests need
+func (cfg Config) Validate() error {
+	switch {
+	case cfg.Endpoint == "":
+		return errors.New("no backup endpoint")
+	case cfg.Bucket == "":
+		return errors.New("no backup bucket")
+	case cfg.AccessKey == "" || cfg.SecretKey == "":
+		return errors.New("no backup credentials")
+	}
+	return nil
+}
+
+// key returns the key of name under the prefix of cfg
+func (cfg Config) key(name string) string {
+	prefix := strings.Trim(cfg.Prefix, "/")
+	if prefix == "" {
+		return name
+	}
+	return prefix + "/" + name
+}
+
+// Dirs are the directories of the library
+type Dirs struct {
+	Data    string // Holds the database; restored databases are put there
+	Storage string // Files of managed tabs
+	Covers  string
+}
+
+// Manifest describes a complete backup
+type Manifest struct {
+	Version    int    `json:"version"`
+	CreatedAt  int64  `json:"createdAt"`  // Unix timestamp
+	StorageDir string `json:"storageDir"` // Dirs of the backed up library, to move the paths of its files
+	CoversDir  string `json:"coversDir"`
+}
+
+// Progress is reported for each file of a backup or restore
+type Progress struct {
+	Phase string `json:"phase"` // "storage", "covers" or "database"
+	File  string `json:"file"`
+	Done  int    `json:"done"` // Files done so far, out of Total
+	Total int    `json:"total"`
+}
+
+// Result counts the files of a backup or restore
+type Result struct {
+	Transferred int   `json:"transferred"` // Uploaded or downloaded
+	Skipped     int   `json:"skipped"`     // Already up to date
+	Bytes       int64 `json:"bytes"`       // Transferred
+}
+
+// localFile is a file to back up
+type localFile struct {
+	phase string
+	path  string
+	key   string
+}
+
+// Backup uploads snapshot, a copy of the database, and the files under the
+// storage and covers dirs to the bucket. Generated images under
+// covers/collages are left out. progress is called before each file.
+func Backup(ctx context.Context, c *S3, snapshot string, dirs Dirs, progress func(Progress)) (Result, error) {
+	var result Result
+	existing := map[string]Object{}
+	objects, err := c.List(ctx, c.cfg.key(""))
+	if err != nil {
+		return result, fmt.Errorf("failed to list the bucket: %w", err)
+	}
+	for _, o := range objects {
+		existing[o.Key] = o
+	}
+
+	files := listFiles("storage", dirs.Storage, c.cfg)
+	files = append(files, listFiles("covers", dirs.Covers, c.cfg)...)
+	files = append(files, localFile{phase: "database", path: snapshot, key: c.cfg.key(databaseKey)})
+	for i, f := range files {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		progress(Progress{Phase: f.phase, File: filepath.Base(f.path), Done: i, Total: len(files)})
+		size, uploaded, err := upload(ctx, c, f, existing[f.key])
+		if err != nil {
+			return result, fmt.Errorf("failed to upload %s: %w", f.path, err)
+		}
+		if uploaded {
+			result.Transferred++
+			result.Bytes += size
+		} else {
+			result.Skipped++
+		}
+	}
+
+	manifest, err := json.Marshal(Manifest{
+		Version:    1,
+		CreatedAt:  time.Now().Unix(),
+		StorageDir: dirs.Storage,
+		CoversDir:  dirs.Covers,
+	})
+	if err != nil {
+		return result, err
+	}
+	if err := c.Put(ctx, c.cfg.key(manifestKey), strings.NewReader(string(manifest)), int64(len(manifest)), hexSHA256(manifest)); err != nil {
+		return result, fmt.Errorf("failed to upload the manifest: %w", err)
+	}
+	return result, nil
+}
+
+// listFiles returns the files under dir to back up as phase. Hidden files
+// are being written and collages can be generated again.
+func listFiles(phase, dir string, cfg Config) []localFile {
+	var files []localFile
+	filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if phase == "covers" && d.Name() == "collages" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return nil
+		}
+		files = append(files, localFile{phase: phase, path: p, key: cfg.key(phase + "/" + filepath.ToSlash(rel))})
+		return nil
+	})
+	return files
+}
+
+// upload puts f in the bucket unless existing has the same content. It
+// returns the size of the file and whether it was uploaded.
+func upload(ctx context.Context, c *S3, f localFile, existing Object) (int64, bool, error) {
+	file, err := os.Open(f.path)
+	if err != nil {
+		return 0, false, err
+	}
+	defer file.Close()
+
+	md5Hash, sha := md5.New(), sha256.New()
+	size, err := io.Copy(io.MultiWriter(md5Hash, sha), file)
+	if err != nil {
+		return 0, false, err
+	}
+	if existing.Key != "" && existing.Size == size && existing.ETag == hex.EncodeToString(md5Hash.Sum(nil)) {
+		return size, false, nil
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return 0, false, err
+	}
+	return size, true, c.Put(ctx, f.key, file, size, hex.EncodeToString(sha.Sum(nil)))
+}
+
+// Restore downloads the latest backup: the stored files and covers missing
+// or of another size go straight to their dirs, while the database is put
+// next to the current one until ApplyRestore swaps it in on the next start.
+func Restore(ctx context.Context, c *S3, dirs Dirs, progress func(Progress)) (Result, error) {
+	var result Result
+	var manifestData strings.Builder
+	if err := c.Get(ctx, c.cfg.key(manifestKey), &manifestData); err != nil {
+		return result, fmt.Errorf("no complete backup found: %w", err)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal([]byte(manifestData.String()), &manifest); err != nil {
+		return result, fmt.Errorf("invalid backup manifest: %w", err)
+	}
+	prefix := c.cfg.key("")
+	objects, err := c.List(ctx, prefix)
+	if err != nil {
+		return result, fmt.Errorf("failed to list the bucket: %w", err)
+	}
+	for i, o := range objects {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		phase, rel, ok := strings.Cut(strings.TrimPrefix(o.Key, prefix), "/")
+		dir := dirs.Storage
+		switch {
+		case !ok || rel == "":
+			continue
+		case phase == "covers":
+			dir = dirs.Covers
+		case phase != "storage":
+			continue
+		}
+		dst := filepath.Join(dir, filepath.FromSlash(path.Clean("/"+rel)))
+		if info, err := os.Stat(dst); err == nil && info.Size() == o.Size {
+			result.Skipped++
+			continue
+		}
+		progress(Progress{Phase: phase, File: path.Base(rel), Done: i, Total: len(objects)})
+		if err := download(ctx, c, o.Key, dst); err != nil {
+			return result, fmt.Errorf("failed to download %s: %w", o.Key, err)
+		}
+		result.Transferred++
+		result.Bytes += o.Size
+	}
+
+	progress(Progress{Phase: "database", File: path.Base(databaseKey), Done: len(objects), Total: len(objects)})
+	if err := download(ctx, c, c.cfg.key(databaseKey), filepath.Join(dirs.Data, restoreDB)); err != nil {
+		return result, fmt.Errorf("failed to download the database: %w", err)
+	}
+	result.Transferred++
+	if err := os.WriteFile(filepath.Join(dirs.Data, restoreManifest), []byte(manifestData.String()), 0644); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// download writes the content of key to dst, through a temporary file so
+// an interrupted download leaves no partial file
+func download(ctx context.Context, c *S3, key, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".restore-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := c.Get(ctx, key, tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
+}
+
+// ApplyRestore replaces the database at dbPath, which must not be open, by
+// the one downloaded by Restore, if any. The current database is kept as
+// dbPath + ".before-restore". It returns the manifest of the restored
+// backup, nil if there was none to apply.
+func ApplyRestore(dbPath string) (*Manifest, error) {
+	dir := filepath.Dir(dbPath)
+	data, err := os.ReadFile(filepath.Join(dir, restoreManifest))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid backup manifest: %w", err)
+	}
+
+	if err := os.Rename(dbPath, dbPath+".before-restore"); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	// The journal of the old database must not be applied to the new one
+	os.Remove(dbPath + "-wal")
+	os.Remove(dbPath + "-shm")
+	if err := os.Rename(filepath.Join(dir, restoreDB), dbPath); err != nil {
+		return nil, err
+	}
+	if err := os.Remove(filepath.Join(dir, restoreManifest)); err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
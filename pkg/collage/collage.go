@@ -0,0 +1,106 @@
+// Package collage composes the cover of a category from the covers of its
+// tabs, like the playlist art of music apps
+package collage
+
+import (
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"os"
+	"path/filepath"
+)
+
+// Size is the width and height of a collage in pixels
+const Size = 600
+
+// Covers is the number of covers in a collage, laid out 2x2
+const Covers = 4
+
+// Compose writes to dst a JPEG collage of the first Covers images of
+// paths, each cropped to a centered square. JPEG, PNG and GIF images are
+// supported. dst is written through a temporary file, so it is either
+// complete or absent.
+func Compose(paths []string, dst string) error {
+	if len(paths) < Covers {
+		return fmt.Errorf("a collage needs %d covers, got %d", Covers, len(paths))
+	}
+
+	const tile = Size / 2
+	out := image.NewRGBA(image.Rect(0, 0, Size, Size))
+	for i, path := range paths[:Covers] {
+		img, err := load(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+		}
+		x, y := (i%2)*tile, (i/2)*tile
+		drawScaled(out, image.Rect(x, y, x+tile, y+tile), img, squareCrop(img.Bounds()))
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".collage-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := jpeg.Encode(tmp, out, &jpeg.Options{Quality: 85}); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
+}
+
+func load(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	return img, err
+}
+
+// squareCrop returns the largest square centered in r
+func squareCrop(r image.Rectangle) image.Rectangle {
+	side := min(r.Dx(), r.Dy())
+	x := r.Min.X + (r.Dx()-side)/2
+	y := r.Min.Y + (r.Dy()-side)/2
+	return image.Rect(x, y, x+side, y+side)
+}
+
+// drawScaled draws the sr part of src into the r part of dst. Each pixel
+// of dst is the average of the pixels of src it covers (a box filter),
+// which keeps downscaled covers smooth; upscaling repeats pixels.
+func drawScaled(dst *image.RGBA, r image.Rectangle, src image.Image, sr image.Rectangle) {
+	if sr.Empty() {
+		return
+	}
+	for dy := 0; dy < r.Dy(); dy++ {
+		sy0 := sr.Min.Y + dy*sr.Dy()/r.Dy()
+		sy1 := max(sr.Min.Y+(dy+1)*sr.Dy()/r.Dy(), sy0+1)
+		for dx := 0; dx < r.Dx(); dx++ {
+			sx0 := sr.Min.X + dx*sr.Dx()/r.Dx()
+			sx1 := max(sr.Min.X+(dx+1)*sr.Dx()/r.Dx(), sx0+1)
+
+			var rs, gs, bs, n uint32
+			for sy := sy0; sy < sy1; sy++ {
+				for sx := sx0; sx < sx1; sx++ {
+					cr, cg, cb, _ := src.At(sx, sy).RGBA()
+					rs += cr >> 8
+					gs += cg >> 8
+					bs += cb >> 8
+					n++
+				}
+			}
+			i := dst.PixOffset(r.Min.X+dx, r.Min.Y+dy)
+			dst.Pix[i] = uint8(rs / n)
+			dst.Pix[i+1] = uint8(gs / n)
+			dst.Pix[i+2] = uint8(bs / n)
+			dst.Pix[i+3] = 0xff
+		}
+	}
+}
@@ -17,7 +17,9 @@ type CoverJob struct {
 	OnComplete func(tabID, coverPath string, err error)
 }
 
-// CoverPool manages concurrent cover download workers
+// CoverPool manages concurrent cover download workers.
+// A paused pool finishes its running downloads and holds the jobs submitted
+// meanwhile until Resume is called, e.g. while offline.
 type CoverPool struct {
 	jobs       chan CoverJob
 	workers    int
@@ -25,6 +27,11 @@ type CoverPool struct {
 	ctx        context.Context
 	cancel     context.CancelFunc
 	downloadFn func(artist, album, title, country, lang, dstPath string) error
+
+	mu       sync.Mutex
+	resume   chan struct{} // Non-nil while paused, closed on Resume
+	held     []CoverJob    // Submitted while paused
+	encoding Encoding      // Applied to each downloaded cover
 }
 
 // NewCoverPool creates a new worker pool with the specified number of workers
@@ -55,6 +62,9 @@ func (p *CoverPool) Start() {
 func (p *CoverPool) worker(id int) {
 	defer p.wg.Done()
 	for {
+		if !p.waitIfPaused() {
+			return
+		}
 		select {
 		case <-p.ctx.Done():
 			return
@@ -63,6 +73,9 @@ func (p *CoverPool) worker(id int) {
 				return
 			}
 			err := p.downloadFn(job.Artist, job.Album, job.Title, job.Country, job.Language, job.CoverPath)
+			if err == nil {
+				p.reencode(job.CoverPath)
+			}
 			if job.OnComplete != nil {
 				job.OnComplete(job.TabID, job.CoverPath, err)
 			}
@@ -70,8 +83,29 @@ func (p *CoverPool) worker(id int) {
 	}
 }
 
-// Submit adds a new job to the queue
+// waitIfPaused blocks while the pool is paused. Returns false if the pool
+// was stopped meanwhile.
+func (p *CoverPool) waitIfPaused() bool {
+	p.mu.Lock()
+	resume := p.resume
+	p.mu.Unlock()
+	if resume == nil {
+		return true
+	}
+	select {
+	case <-resume:
+		return true
+	case <-p.ctx.Done():
+		return false
+	}
+}
+
+// Submit adds a new job to the queue. While paused, the job is held
+// without blocking.
 func (p *CoverPool) Submit(job CoverJob) {
+	if p.hold(job) {
+		return
+	}
 	select {
 	case p.jobs <- job:
 		// Job submitted
@@ -82,6 +116,9 @@ func (p *CoverPool) Submit(job CoverJob) {
 
 // SubmitAsync adds a job without blocking (drops if queue is full)
 func (p *CoverPool) SubmitAsync(job CoverJob) bool {
+	if p.hold(job) {
+		return true
+	}
 	select {
 	case p.jobs <- job:
 		return true
@@ -90,6 +127,66 @@ func (p *CoverPool) SubmitAsync(job CoverJob) bool {
 	}
 }
 
+// hold keeps job for Resume if the pool is paused
+func (p *CoverPool) hold(job CoverJob) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.resume == nil {
+		return false
+	}
+	p.held = append(p.held, job)
+	return true
+}
+
+// Pause stops workers from picking up new jobs
+func (p *CoverPool) Pause() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.resume == nil {
+		p.resume = make(chan struct{})
+	}
+}
+
+// Resume lets workers pick up jobs again, the held ones first
+func (p *CoverPool) Resume() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.resume == nil {
+		return
+	}
+	close(p.resume)
+	p.resume = nil
+
+	held := p.held
+	p.held = nil
+	if len(held) > 0 {
+		go func() {
+			for _, job := range held {
+				if p.ctx.Err() != nil {
+					return
+				}
+				p.Submit(job)
+			}
+		}()
+	}
+}
+
+// TakeHeld removes and returns the jobs held while paused
+func (p *CoverPool) TakeHeld() []CoverJob {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	held := p.held
+	p.held = nil
+	return held
+}
+
+// IsPaused reports whether the pool is paused
+func (p *CoverPool) IsPaused() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.resume != nil
+}
+
 // Stop gracefully shuts down the worker pool
 func (p *CoverPool) Stop() {
 	p.cancel()
@@ -97,7 +194,9 @@ func (p *CoverPool) Stop() {
 	p.wg.Wait()
 }
 
-// QueueSize returns the current number of pending jobs
+// QueueSize returns the current number of pending jobs, held ones included
 func (p *CoverPool) QueueSize() int {
-	return len(p.jobs)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.jobs) + len(p.held)
 }
@@ -0,0 +1,136 @@
+package coverpool
+
+import (
+	"bytes"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"os"
+	"path/filepath"
+)
+
+// DefaultQuality is the JPEG quality of re-encoded covers when
+// Encoding.Quality is 0
+const DefaultQuality = 80
+
+// Encoding is how downloaded covers are re-encoded to save disk space.
+// Covers are kept as JPEG: the standard library has no WebP encoder.
+type Encoding struct {
+	MaxSize int // Width and height covers are scaled down to fit; 0 to keep their size
+	Quality int // JPEG quality from 1 to 100; 0 for DefaultQuality
+}
+
+// Enabled reports whether covers are re-encoded at all
+func (e Encoding) Enabled() bool {
+	return e.MaxSize > 0 || e.Quality > 0
+}
+
+// SetEncoding sets how covers are re-encoded once downloaded
+func (p *CoverPool) SetEncoding(e Encoding) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.encoding = e
+}
+
+// reencode applies the encoding to a downloaded cover. A cover that cannot
+// be re-encoded is kept as downloaded.
+func (p *CoverPool) reencode(path string) {
+	p.mu.Lock()
+	e := p.encoding
+	p.mu.Unlock()
+	if e.Enabled() {
+		Reencode(path, e)
+	}
+}
+
+// Reencode scales the JPEG, PNG or GIF image at path down to e.MaxSize and
+// writes it back as a JPEG of quality e.Quality. A cover already small
+// enough is only rewritten if that saves a tenth of its size, so covers do
+// not lose quality each time they are re-encoded. Returns the number of
+// bytes saved. The file is replaced through a temporary file, so it is
+// never left half written.
+func Reencode(path string, e Encoding) (int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return 0, err
+	}
+	scaled := fit(img, e.MaxSize)
+
+	quality := e.Quality
+	if quality <= 0 || quality > 100 {
+		quality = DefaultQuality
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, scaled, &jpeg.Options{Quality: quality}); err != nil {
+		return 0, err
+	}
+	if buf.Len() >= len(data) || (scaled == img && buf.Len() > len(data)*9/10) {
+		return 0, nil
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".cover-*.tmp")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return 0, err
+	}
+	if err := tmp.Close(); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return 0, err
+	}
+	return int64(len(data) - buf.Len()), nil
+}
+
+// fit scales img down, keeping its aspect ratio, so that neither side is
+// larger than size. Smaller images and a size of 0 keep img as it is. Each
+// pixel is the average of the pixels it covers (a box filter), which keeps
+// scaled covers smooth.
+func fit(img image.Image, size int) image.Image {
+	sr := img.Bounds()
+	if size <= 0 || (sr.Dx() <= size && sr.Dy() <= size) {
+		return img
+	}
+	w, h := size, size
+	if sr.Dx() > sr.Dy() {
+		h = max(1, sr.Dy()*size/sr.Dx())
+	} else {
+		w = max(1, sr.Dx()*size/sr.Dy())
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for dy := 0; dy < h; dy++ {
+		sy0 := sr.Min.Y + dy*sr.Dy()/h
+		sy1 := max(sr.Min.Y+(dy+1)*sr.Dy()/h, sy0+1)
+		for dx := 0; dx < w; dx++ {
+			sx0 := sr.Min.X + dx*sr.Dx()/w
+			sx1 := max(sr.Min.X+(dx+1)*sr.Dx()/w, sx0+1)
+
+			var rs, gs, bs, n uint32
+			for sy := sy0; sy < sy1; sy++ {
+				for sx := sx0; sx < sx1; sx++ {
+					cr, cg, cb, _ := img.At(sx, sy).RGBA()
+					rs += cr >> 8
+					gs += cg >> 8
+					bs += cb >> 8
+					n++
+				}
+			}
+			i := dst.PixOffset(dx, dy)
+			dst.Pix[i] = uint8(rs / n)
+			dst.Pix[i+1] = uint8(gs / n)
+			dst.Pix[i+2] = uint8(bs / n)
+			dst.Pix[i+3] = 0xff
+		}
+	}
+	return dst
+}
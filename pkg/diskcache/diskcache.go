@@ -0,0 +1,111 @@
+// Package diskcache bounds the size of directories of derived files, files
+// the app can generate again such as category collages, by removing the
+// least recently used ones. The modification time of a file is its last
+// use (see Touch), since access times are often not recorded.
+package diskcache
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// touchInterval is how old the last use of a file must be for Touch to
+// update it, so files used all the time are not written all the time
+const touchInterval = time.Hour
+
+// Usage is the size of cached files
+type Usage struct {
+	Files int   `json:"files"`
+	Bytes int64 `json:"bytes"`
+}
+
+type entry struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// list returns the files under dirs. Hidden files are skipped: they are
+// temporary files being written.
+func list(dirs []string) []entry {
+	var entries []entry
+	for _, dir := range dirs {
+		filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || strings.HasPrefix(d.Name(), ".") {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			entries = append(entries, entry{path: path, size: info.Size(), modTime: info.ModTime()})
+			return nil
+		})
+	}
+	return entries
+}
+
+// Measure returns the number and size of the files under dirs
+func Measure(dirs ...string) Usage {
+	var u Usage
+	for _, e := range list(dirs) {
+		u.Files++
+		u.Bytes += e.size
+	}
+	return u
+}
+
+// Touch marks a cached file as used now
+func Touch(path string) {
+	info, err := os.Stat(path)
+	if err != nil || time.Since(info.ModTime()) < touchInterval {
+		return
+	}
+	now := time.Now()
+	os.Chtimes(path, now, now)
+}
+
+// Evict removes the least recently used files under dirs until they take
+// at most limit bytes. Files used within touchInterval are kept even past
+// the limit, so the images on screen are not generated again and again.
+// Returns what was removed.
+func Evict(limit int64, dirs ...string) Usage {
+	return evict(limit, time.Now().Add(-touchInterval), dirs)
+}
+
+// Clear removes the files under dirs. Returns what was removed.
+func Clear(dirs ...string) Usage {
+	return evict(0, time.Now(), dirs)
+}
+
+// evict removes the files under dirs last used before usedBefore, oldest
+// first, until they take at most limit bytes
+func evict(limit int64, usedBefore time.Time, dirs []string) Usage {
+	entries := list(dirs)
+	var total int64
+	for _, e := range entries {
+		total += e.size
+	}
+	if total <= limit {
+		return Usage{}
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].modTime.Before(entries[j].modTime) })
+	var removed Usage
+	for _, e := range entries {
+		if total <= limit || !e.modTime.Before(usedBefore) {
+			break
+		}
+		if os.Remove(e.path) != nil {
+			continue
+		}
+		total -= e.size
+		removed.Files++
+		removed.Bytes += e.size
+	}
+	return removed
+}
@@ -0,0 +1,114 @@
+// Package enrich looks up the album, genre and release year of a song on
+// Last.fm or Spotify, to fill what the tab's file name and tags lack.
+package enrich
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"haya-tab/pkg/metadata"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Info is what a provider knows of a song; fields it does not know are empty
+type Info struct {
+	Album string
+	Genre string
+	Year  int
+}
+
+// Provider looks up songs on a metadata service
+type Provider interface {
+	Name() string
+	// Lookup returns ErrNotFound if the service does not know the song
+	Lookup(artist, title string) (Info, error)
+}
+
+// ErrNotFound is returned by Lookup when the service does not know the song
+var ErrNotFound = errors.New("song not found")
+
+// RateLimitError is returned when the service asks to slow down
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limited, retry after %s", e.RetryAfter)
+}
+
+// Config selects and authenticates a provider
+type Config struct {
+	Provider            string // "lastfm", "spotify" or "" for none
+	LastFMAPIKey        string
+	SpotifyClientID     string
+	SpotifyClientSecret string
+}
+
+// New returns the provider cfg selects, nil if none
+func New(cfg Config) (Provider, error) {
+	switch cfg.Provider {
+	case "":
+		return nil, nil
+	case "lastfm":
+		if cfg.LastFMAPIKey == "" {
+			return nil, errors.New("Last.fm needs an API key")
+		}
+		return &LastFM{apiKey: cfg.LastFMAPIKey}, nil
+	case "spotify":
+		if cfg.SpotifyClientID == "" || cfg.SpotifyClientSecret == "" {
+			return nil, errors.New("Spotify needs a client ID and secret")
+		}
+		return &Spotify{clientID: cfg.SpotifyClientID, clientSecret: cfg.SpotifyClientSecret}, nil
+	}
+	return nil, fmt.Errorf("unknown metadata provider: %s", cfg.Provider)
+}
+
+// getJSON sends req with the shared metadata client and decodes the JSON
+// answer into v
+func getJSON(req *http.Request, v interface{}) error {
+	if metadata.Offline() {
+		return metadata.ErrOffline
+	}
+	resp, err := metadata.HTTPClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		seconds, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+		return &RateLimitError{RetryAfter: time.Duration(max(seconds, 60)) * time.Second}
+	case resp.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case resp.StatusCode != http.StatusOK:
+		return fmt.Errorf("%s: status code %d", req.URL.Host, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// parseYear returns the year a date starts or ends with, e.g. "1999-05-01"
+// or "01 May 1999, 00:00"; 0 if there is none
+func parseYear(date string) int {
+	for _, field := range strings.FieldsFunc(date, func(r rune) bool { return r < '0' || r > '9' }) {
+		if year, err := strconv.Atoi(field); err == nil && len(field) == 4 && year >= 1000 {
+			return year
+		}
+	}
+	return 0
+}
+
+// genreName capitalizes a tag like "classic rock" as "Classic Rock"
+func genreName(tag string) string {
+	words := strings.Fields(tag)
+	for i, w := range words {
+		r := []rune(w)
+		r[0] = unicode.ToUpper(r[0])
+		words[i] = string(r)
+	}
+	return strings.Join(words, " ")
+}
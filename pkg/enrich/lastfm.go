@@ -0,0 +1,80 @@
+package enrich
+
+import (
+	"net/http"
+	"net/url"
+)
+
+const lastFMURL = "https://ws.audioscrobbler.com/2.0/"
+
+// LastFM looks songs up with the Last.fm API. Its tags are user-made, so
+// the genre is the most used tag.
+type LastFM struct {
+	apiKey string
+}
+
+func (l *LastFM) Name() string { return "Last.fm" }
+
+type lastFMTags struct {
+	Tag []struct {
+		Name string `json:"name"`
+	} `json:"tag"`
+}
+
+func (l *LastFM) Lookup(artist, title string) (Info, error) {
+	var track struct {
+		Error int `json:"error"`
+		Track struct {
+			Album struct {
+				Title string `json:"title"`
+			} `json:"album"`
+			TopTags lastFMTags `json:"toptags"`
+		} `json:"track"`
+	}
+	if err := l.call("track.getInfo", url.Values{"artist": {artist}, "track": {title}}, &track); err != nil {
+		return Info{}, err
+	}
+	if track.Error != 0 {
+		// 6: the track is unknown
+		return Info{}, ErrNotFound
+	}
+
+	info := Info{Album: track.Track.Album.Title}
+	if tags := track.Track.TopTags.Tag; len(tags) > 0 {
+		info.Genre = genreName(tags[0].Name)
+	}
+
+	// Tracks have no date; their album may
+	if info.Album != "" {
+		var album struct {
+			Album struct {
+				Wiki struct {
+					Published string `json:"published"`
+				} `json:"wiki"`
+				Tags lastFMTags `json:"tags"`
+			} `json:"album"`
+		}
+		if err := l.call("album.getInfo", url.Values{"artist": {artist}, "album": {info.Album}}, &album); err == nil {
+			info.Year = parseYear(album.Album.Wiki.Published)
+			if tags := album.Album.Tags.Tag; info.Genre == "" && len(tags) > 0 {
+				info.Genre = genreName(tags[0].Name)
+			}
+		}
+	}
+	if info == (Info{}) {
+		return info, ErrNotFound
+	}
+	return info, nil
+}
+
+func (l *LastFM) call(method string, params url.Values, v interface{}) error {
+	params.Set("method", method)
+	params.Set("api_key", l.apiKey)
+	params.Set("autocorrect", "1")
+	params.Set("format", "json")
+	req, err := http.NewRequest("GET", lastFMURL+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	return getJSON(req, v)
+}
@@ -0,0 +1,135 @@
+package enrich
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	spotifyTokenURL = "https://accounts.spotify.com/api/token"
+	spotifyAPIURL   = "https://api.spotify.com/v1"
+)
+
+// Spotify looks songs up with the Spotify Web API, authenticated as an app
+// (client credentials). Genres belong to artists, so the genre is the
+// artist's first.
+type Spotify struct {
+	clientID, clientSecret string
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+	genres  map[string]string // By artist ID
+}
+
+func (s *Spotify) Name() string { return "Spotify" }
+
+func (s *Spotify) Lookup(artist, title string) (Info, error) {
+	var search struct {
+		Tracks struct {
+			Items []struct {
+				Album struct {
+					Name        string `json:"name"`
+					ReleaseDate string `json:"release_date"`
+				} `json:"album"`
+				Artists []struct {
+					ID string `json:"id"`
+				} `json:"artists"`
+			} `json:"items"`
+		} `json:"tracks"`
+	}
+	query := url.Values{
+		"q":     {fmt.Sprintf("track:%s artist:%s", title, artist)},
+		"type":  {"track"},
+		"limit": {"1"},
+	}
+	if err := s.get("/search?"+query.Encode(), &search); err != nil {
+		return Info{}, err
+	}
+	if len(search.Tracks.Items) == 0 {
+		return Info{}, ErrNotFound
+	}
+
+	track := search.Tracks.Items[0]
+	info := Info{Album: track.Album.Name, Year: parseYear(track.Album.ReleaseDate)}
+	if len(track.Artists) > 0 {
+		info.Genre = s.artistGenre(track.Artists[0].ID)
+	}
+	return info, nil
+}
+
+// artistGenre returns the first genre of an artist, remembered for the
+// artist's other songs
+func (s *Spotify) artistGenre(id string) string {
+	s.mu.Lock()
+	genre, ok := s.genres[id]
+	s.mu.Unlock()
+	if ok {
+		return genre
+	}
+
+	var artist struct {
+		Genres []string `json:"genres"`
+	}
+	if err := s.get("/artists/"+url.PathEscape(id), &artist); err != nil {
+		return ""
+	}
+	if len(artist.Genres) > 0 {
+		genre = genreName(artist.Genres[0])
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.genres == nil {
+		s.genres = map[string]string{}
+	}
+	s.genres[id] = genre
+	return genre
+}
+
+func (s *Spotify) get(path string, v interface{}) error {
+	token, err := s.accessToken()
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("GET", spotifyAPIURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return getJSON(req, v)
+}
+
+// accessToken returns the app's access token, requesting a new one when it
+// expires
+func (s *Spotify) accessToken() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != "" && time.Now().Before(s.expires) {
+		return s.token, nil
+	}
+
+	body := url.Values{"grant_type": {"client_credentials"}}.Encode()
+	req, err := http.NewRequest("POST", spotifyTokenURL, strings.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.SetBasicAuth(s.clientID, s.clientSecret)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := getJSON(req, &token); err != nil {
+		return "", fmt.Errorf("Spotify authentication failed: %w", err)
+	}
+	s.token = token.AccessToken
+	// Renew a minute early
+	s.expires = time.Now().Add(time.Duration(token.ExpiresIn-60) * time.Second)
+	return s.token, nil
+}
@@ -0,0 +1,134 @@
+package jobpool
+
+import (
+	"context"
+	"sync"
+)
+
+// Job represents a background task
+type Job struct {
+	Name       string
+	Run        func(ctx context.Context) error
+	OnComplete func(err error)
+}
+
+// Pool manages concurrent workers for generic background jobs.
+// Workers can be paused; a paused pool finishes its running jobs and keeps
+// the pending ones queued until Resume is called.
+type Pool struct {
+	jobs    chan Job
+	workers int
+	wg      sync.WaitGroup
+	ctx     context.Context
+	cancel  context.CancelFunc
+
+	mu     sync.Mutex
+	resume chan struct{} // Non-nil while paused, closed on Resume
+}
+
+// NewPool creates a new worker pool with the specified number of workers
+func NewPool(workers int) *Pool {
+	if workers < 1 {
+		workers = 2
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Pool{
+		jobs:    make(chan Job, 100), // Buffer for pending jobs
+		workers: workers,
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// Start launches the worker goroutines
+func (p *Pool) Start() {
+	for i := 0; i < p.workers; i++ {
+		p.wg.Add(1)
+		go p.worker()
+	}
+}
+
+// worker processes jobs from the queue
+func (p *Pool) worker() {
+	defer p.wg.Done()
+	for {
+		if !p.waitIfPaused() {
+			return
+		}
+		select {
+		case <-p.ctx.Done():
+			return
+		case job := <-p.jobs:
+			err := job.Run(p.ctx)
+			if job.OnComplete != nil {
+				job.OnComplete(err)
+			}
+		}
+	}
+}
+
+// waitIfPaused blocks while the pool is paused. Returns false if the pool
+// was stopped meanwhile.
+func (p *Pool) waitIfPaused() bool {
+	p.mu.Lock()
+	resume := p.resume
+	p.mu.Unlock()
+	if resume == nil {
+		return true
+	}
+	select {
+	case <-resume:
+		return true
+	case <-p.ctx.Done():
+		return false
+	}
+}
+
+// Submit adds a new job to the queue, blocking while the queue is full.
+// Returns false if the pool is shutting down.
+func (p *Pool) Submit(job Job) bool {
+	select {
+	case p.jobs <- job:
+		return true
+	case <-p.ctx.Done():
+		return false
+	}
+}
+
+// Pause stops workers from picking up new jobs
+func (p *Pool) Pause() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.resume == nil {
+		p.resume = make(chan struct{})
+	}
+}
+
+// Resume lets workers pick up jobs again
+func (p *Pool) Resume() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.resume != nil {
+		close(p.resume)
+		p.resume = nil
+	}
+}
+
+// IsPaused reports whether the pool is paused
+func (p *Pool) IsPaused() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.resume != nil
+}
+
+// Stop shuts down the worker pool. Running jobs see their context
+// cancelled; pending jobs are dropped.
+func (p *Pool) Stop() {
+	p.cancel()
+	p.wg.Wait()
+}
+
+// QueueSize returns the current number of pending jobs
+func (p *Pool) QueueSize() int {
+	return len(p.jobs)
+}
@@ -0,0 +1,127 @@
+// Package locale formats dates and numbers for reports and exports
+// according to the locale setting.
+package locale
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// dateLayouts are the date and time layouts of a language, or of a
+// language-region pair where the region changes the order
+type dateLayouts struct {
+	date string
+	time string
+}
+
+var layouts = map[string]dateLayouts{
+	"en-US": {"Jan 2, 2006", "3:04 PM"},
+	"en":    {"2 Jan 2006", "15:04"}, // Other English speaking regions
+	"de":    {"02.01.2006", "15:04"},
+	"fr":    {"02/01/2006", "15:04"},
+	"ja":    {"2006/01/02", "15:04"},
+	"ko":    {"2006. 1. 2.", "15:04"},
+	"zh":    {"2006/1/2", "15:04"},
+}
+
+// isoLayouts are used for languages without an entry in layouts
+var isoLayouts = dateLayouts{"2006-01-02", "15:04"}
+
+// Formatter formats values for one locale. The zero value is not usable;
+// use New.
+type Formatter struct {
+	tag     language.Tag
+	printer *message.Printer
+	layouts dateLayouts
+}
+
+// New returns a Formatter for a BCP 47 tag such as "ja-JP". An empty or
+// invalid tag selects the system locale, from LC_ALL or LANG, and then
+// US English.
+func New(tag string) *Formatter {
+	t, err := language.Parse(tag)
+	if tag == "" || err != nil {
+		t = systemTag()
+	}
+
+	// The region is inferred when missing, e.g. "en" is "en-US"
+	base, _ := t.Base()
+	region, _ := t.Region()
+	l, ok := layouts[base.String()+"-"+region.String()]
+	if !ok {
+		l, ok = layouts[base.String()]
+	}
+	if !ok {
+		l = isoLayouts
+	}
+
+	return &Formatter{
+		tag:     t,
+		printer: message.NewPrinter(t),
+		layouts: l,
+	}
+}
+
+// systemTag returns the locale of the environment, e.g. "ja_JP.UTF-8" in
+// LANG. Windows does not set these variables; US English is used there.
+func systemTag() language.Tag {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		value := os.Getenv(name)
+		value, _, _ = strings.Cut(value, ".")
+		value, _, _ = strings.Cut(value, "@")
+		if value == "" || value == "C" || value == "POSIX" {
+			continue
+		}
+		if t, err := language.Parse(strings.ReplaceAll(value, "_", "-")); err == nil {
+			return t
+		}
+	}
+	return language.AmericanEnglish
+}
+
+// Tag returns the BCP 47 tag of the locale
+func (f *Formatter) Tag() string {
+	return f.tag.String()
+}
+
+// Date formats a Unix timestamp as a local date. Zero means "never" and
+// gives an empty string.
+func (f *Formatter) Date(unix int64) string {
+	if unix == 0 {
+		return ""
+	}
+	return time.Unix(unix, 0).Format(f.layouts.date)
+}
+
+// DateTime formats a Unix timestamp as a local date and time
+func (f *Formatter) DateTime(unix int64) string {
+	if unix == 0 {
+		return ""
+	}
+	return time.Unix(unix, 0).Format(f.layouts.date + " " + f.layouts.time)
+}
+
+// Number formats an integer with the digit grouping of the locale
+func (f *Formatter) Number(n int64) string {
+	return f.printer.Sprintf("%d", n)
+}
+
+// Bytes formats a size with a binary unit, e.g. "1.5 MB" or "1,5 MB"
+func (f *Formatter) Bytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return f.printer.Sprintf("%d B", n)
+	}
+	value := float64(n)
+	units := []string{"KB", "MB", "GB", "TB"}
+	i := -1
+	for value >= unit && i < len(units)-1 {
+		value /= unit
+		i++
+	}
+	return f.printer.Sprintf("%.1f %s", value, units[i])
+}
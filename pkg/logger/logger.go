@@ -28,12 +28,22 @@ type Logger struct {
 }
 
 func NewLogger(appDir string) *Logger {
+	return newLogger(appDir, os.Stdout)
+}
+
+// NewCLILogger returns a logger writing to stderr besides the log file,
+// leaving stdout to the output of the command line mode
+func NewCLILogger(appDir string) *Logger {
+	return newLogger(appDir, os.Stderr)
+}
+
+func newLogger(appDir string, console io.Writer) *Logger {
 	// Create logs directory
 	logDir := filepath.Join(appDir, "logs")
 	if err := os.MkdirAll(logDir, 0755); err != nil {
 		fmt.Printf("Failed to create log directory: %v\n", err)
 		return &Logger{
-			logger:   log.New(os.Stdout, "", log.LstdFlags),
+			logger:   log.New(console, "", log.LstdFlags),
 			logLevel: LevelInfo,
 		}
 	}
@@ -45,13 +55,13 @@ func NewLogger(appDir string) *Logger {
 	if err != nil {
 		fmt.Printf("Failed to open log file: %v\n", err)
 		return &Logger{
-			logger:   log.New(os.Stdout, "", log.LstdFlags),
+			logger:   log.New(console, "", log.LstdFlags),
 			logLevel: LevelInfo,
 		}
 	}
 
-	// Multiwriter: stdout + file
-	mw := io.MultiWriter(os.Stdout, file)
+	// Multiwriter: console + file
+	mw := io.MultiWriter(console, file)
 
 	return &Logger{
 		logFile:  file,
@@ -0,0 +1,71 @@
+package metadata
+
+import (
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+)
+
+// decodeGPString converts a string field of a GP3/4/5 file to UTF-8.
+// The format has no charset marker: Guitar Pro writes strings in the
+// Windows code page of the author's system, while some third party tools
+// write UTF-8. We keep valid UTF-8 as is, use Shift-JIS for Japanese text
+// and fall back to CP1252 (Western European).
+func decodeGPString(b []byte) string {
+	if isASCII(b) || utf8.Valid(b) {
+		return string(b)
+	}
+
+	if looksLikeShiftJIS(b) {
+		if s, err := japanese.ShiftJIS.NewDecoder().Bytes(b); err == nil {
+			return string(s)
+		}
+	}
+
+	s, err := charmap.Windows1252.NewDecoder().Bytes(b)
+	if err != nil {
+		return string(b)
+	}
+	return string(s)
+}
+
+func isASCII(b []byte) bool {
+	for _, c := range b {
+		if c >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// looksLikeShiftJIS reports whether b is well formed Shift-JIS containing
+// at least one double byte character with a lead byte in 0x81-0x9F.
+// Kana, Japanese punctuation and most kanji use those lead bytes, while
+// accented Latin letters in CP1252 are all in 0xC0-0xFF, so requiring one
+// keeps European titles ("Fédération") from being read as Shift-JIS.
+func looksLikeShiftJIS(b []byte) bool {
+	lowLead := false
+	for i := 0; i < len(b); i++ {
+		c := b[i]
+		switch {
+		case c < 0x80, c >= 0xA1 && c <= 0xDF:
+			// ASCII or half-width katakana
+		case c >= 0x81 && c <= 0x9F, c >= 0xE0 && c <= 0xEF:
+			if i+1 >= len(b) {
+				return false
+			}
+			t := b[i+1]
+			if t < 0x40 || t == 0x7F || t > 0xFC {
+				return false
+			}
+			if c <= 0x9F {
+				lowLead = true
+			}
+			i++
+		default:
+			return false
+		}
+	}
+	return lowLead
+}
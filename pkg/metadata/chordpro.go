@@ -0,0 +1,90 @@
+package metadata
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// chordProDirective matches a ChordPro directive line, e.g. "{title: Let It
+// Be}" or "{key G}"
+var chordProDirective = regexp.MustCompile(`^\{\s*([A-Za-z_]+)\s*(?:[:\s]\s*(.*?))?\s*\}$`)
+
+// IsChordProFile reports whether path has a ChordPro extension
+func IsChordProFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".cho", ".pro", ".chopro", ".chordpro":
+		return true
+	}
+	return false
+}
+
+// parseChordPro reads the {title}, {artist}, {album}, {key} and {capo}
+// directives of a ChordPro file. The first occurrence of each wins;
+// directives later in the song (e.g. a key change) are ignored.
+func parseChordPro(path string) (Metadata, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Metadata{}, err
+	}
+	defer f.Close()
+
+	var m Metadata
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(decodeGPString(bytes.TrimPrefix(scanner.Bytes(), []byte("\xef\xbb\xbf"))))
+		match := chordProDirective.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		value := strings.TrimSpace(match[2])
+		switch strings.ToLower(match[1]) {
+		case "title", "t":
+			if m.Title == "" {
+				m.Title = value
+			}
+		case "artist":
+			if m.Artist == "" {
+				m.Artist = value
+			}
+		case "album":
+			if m.Album == "" {
+				m.Album = value
+			}
+		case "key":
+			if m.Key == "" {
+				m.Key = value
+			}
+		case "capo":
+			if n, err := strconv.Atoi(value); err == nil && m.Capo == 0 && n > 0 {
+				m.Capo = n
+			}
+		}
+	}
+	return m, scanner.Err()
+}
+
+// withChordProDirectives overrides the filename metadata m with the
+// directives of the ChordPro file at path
+func withChordProDirectives(m Metadata, path string) (Metadata, error) {
+	song, err := parseChordPro(path)
+	if err != nil {
+		return m, err
+	}
+	if song.Title != "" {
+		m.Title = song.Title
+	}
+	if song.Artist != "" {
+		m.Artist = song.Artist
+	}
+	if song.Album != "" {
+		m.Album = song.Album
+	}
+	m.Key = song.Key
+	m.Capo = song.Capo
+	return m, nil
+}
@@ -0,0 +1,135 @@
+package metadata
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// MaxContentLength caps the text ExtractContent returns, so a huge text
+// file does not bloat the search index
+const MaxContentLength = 64 * 1024
+
+var (
+	// "[Am]", "[G/B]": inline chords of ChordPro lyrics lines
+	chordProChord = regexp.MustCompile(`\[[^\]]*\]`)
+
+	// "e|---0---3--|", "B|-1-", "|--5h7--|": tab staff lines
+	tabStaffLine = regexp.MustCompile(`^[A-Ga-g#b]{0,2}\s*[|:]?[-0-9|:hpbrx/\\~<>()*.\s]*-[-0-9|:hpbrx/\\~<>()*.\s]*\|?\s*$`)
+
+	// A chord symbol above lyrics, e.g. "Am", "G/B", "Cmaj7", "F#m7b5", "N.C."
+	chordSymbol = regexp.MustCompile(`^(?:[A-G][#b]?(?:maj|min|m|dim|aug|sus|add|M|\d|[#b]\d)*(?:/[A-G][#b]?)?|N\.?C\.?|x\d+|\|+|-+)$`)
+
+	// "[Verse]", "[Chorus 2]": section headers of text tabs
+	sectionHeader = regexp.MustCompile(`^\[[^\]]*\]$`)
+)
+
+// ExtractContent returns the lyrics and other words of a tab file for the
+// full-text index: the lyrics lines of ChordPro files without their chords
+// and directives, the lines of text tabs that are neither tab staves nor
+// chords, and the lyrics of Guitar Pro files. Other formats have no
+// content. The result is at most MaxContentLength bytes.
+func ExtractContent(path string) (string, error) {
+	var content string
+	var err error
+	switch {
+	case IsChordProFile(path):
+		content, err = extractLines(path, chordProContentLine)
+	case IsTextTabFile(path):
+		content, err = extractLines(path, textTabContentLine)
+	case IsGuitarProFile(path):
+		var m Metadata
+		m, err = parseGuitarProSafe(path)
+		content = gpLyricsContent(m.Lyrics)
+	}
+	if err != nil {
+		return "", err
+	}
+	return truncateContent(content), nil
+}
+
+// extractLines returns the lines of the text file at path kept by line,
+// which returns the text to index of a trimmed line, or "" to skip it
+func extractLines(path string, line func(string) string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	var b strings.Builder
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() && b.Len() < MaxContentLength {
+		text := line(strings.TrimSpace(decodeGPString(bytes.TrimPrefix(scanner.Bytes(), []byte("\xef\xbb\xbf")))))
+		if text == "" {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString(text)
+	}
+	return b.String(), scanner.Err()
+}
+
+// chordProContentLine returns the lyrics of a ChordPro line without its
+// chords. Directives and comments are skipped.
+func chordProContentLine(line string) string {
+	if line == "" || strings.HasPrefix(line, "#") || chordProDirective.MatchString(line) {
+		return ""
+	}
+	return strings.Join(strings.Fields(chordProChord.ReplaceAllString(line, "")), " ")
+}
+
+// textTabContentLine returns a text tab line unless it is a tab staff, a
+// line of chord symbols or a section header
+func textTabContentLine(line string) string {
+	if line == "" || sectionHeader.MatchString(line) || tabStaffLine.MatchString(line) {
+		return ""
+	}
+	fields := strings.Fields(line)
+	for _, f := range fields {
+		if !chordSymbol.MatchString(f) {
+			return strings.Join(fields, " ")
+		}
+	}
+	return ""
+}
+
+// gpLyricsContent joins the syllables of Guitar Pro lyrics back into words:
+// "-" splits a word into syllables and "+" puts several words on a beat
+func gpLyricsContent(lyrics string) string {
+	lyrics = strings.ReplaceAll(lyrics, "+", " ")
+	runes := []rune(lyrics)
+	var b strings.Builder
+	for i, r := range runes {
+		if r == '-' && i > 0 && i < len(runes)-1 && unicode.IsLetter(runes[i-1]) && unicode.IsLetter(runes[i+1]) {
+			continue
+		}
+		b.WriteRune(r)
+	}
+	lyrics = b.String()
+	var lines []string
+	for _, line := range strings.Split(lyrics, "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// truncateContent cuts content to MaxContentLength bytes at a line break
+func truncateContent(content string) string {
+	if len(content) <= MaxContentLength {
+		return content
+	}
+	content = content[:MaxContentLength]
+	if i := strings.LastIndexByte(content, '\n'); i > 0 {
+		return content[:i]
+	}
+	return strings.ToValidUTF8(content, "")
+}
@@ -0,0 +1,106 @@
+package metadata
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CoverRegion is an iTunes storefront searched for covers
+type CoverRegion struct {
+	Country string `json:"country"` // e.g. "US", "JP"
+	Lang    string `json:"lang"`    // e.g. "en_us", "ja_jp"
+}
+
+// RegionStatus is the health of a cover region as seen from this network
+type RegionStatus struct {
+	Country   string `json:"country"`
+	Timeouts  int    `json:"timeouts"`  // Consecutive timeouts
+	SkipUntil int64  `json:"skipUntil"` // Unix timestamp, 0 if the region is used
+}
+
+const (
+	// regionTimeoutLimit is the number of consecutive timeouts after which a
+	// region is skipped for regionCooldown
+	regionTimeoutLimit = 3
+	regionCooldown     = 30 * time.Minute
+)
+
+var (
+	regionsMu    sync.Mutex
+	coverRegions = []CoverRegion{{Country: "US", Lang: "en_us"}}
+	regionHealth = map[string]*RegionStatus{}
+)
+
+// SetCoverRegions sets the regions tried in order after the tab's own
+// region when searching covers
+func SetCoverRegions(regions []CoverRegion) {
+	regionsMu.Lock()
+	defer regionsMu.Unlock()
+	coverRegions = append([]CoverRegion{}, regions...)
+}
+
+// CoverRegionHealth returns the health of the regions tried so far
+func CoverRegionHealth() []RegionStatus {
+	regionsMu.Lock()
+	defer regionsMu.Unlock()
+
+	statuses := make([]RegionStatus, 0, len(regionHealth))
+	for _, h := range regionHealth {
+		statuses = append(statuses, *h)
+	}
+	return statuses
+}
+
+// candidateRegions returns first, then the configured regions, without
+// duplicates and skipping regions that keep timing out
+func candidateRegions(first CoverRegion) []CoverRegion {
+	regionsMu.Lock()
+	defer regionsMu.Unlock()
+
+	var regions []CoverRegion
+	seen := map[string]bool{}
+	now := time.Now().Unix()
+	for _, r := range append([]CoverRegion{first}, coverRegions...) {
+		country := strings.ToUpper(r.Country)
+		if country == "" || seen[country] {
+			continue
+		}
+		seen[country] = true
+		if h, ok := regionHealth[country]; ok && h.SkipUntil > now {
+			continue
+		}
+		if r.Lang == "" {
+			r.Lang = "en_us"
+		}
+		regions = append(regions, CoverRegion{Country: country, Lang: r.Lang})
+	}
+	return regions
+}
+
+// recordRegionResult updates the health of a region after a search
+func recordRegionResult(country string, err error) {
+	regionsMu.Lock()
+	defer regionsMu.Unlock()
+
+	h, ok := regionHealth[country]
+	if !ok {
+		h = &RegionStatus{Country: country}
+		regionHealth[country] = h
+	}
+
+	var netErr net.Error
+	if err == nil || !errors.As(err, &netErr) || !netErr.Timeout() {
+		// The region answered, even if it found nothing
+		h.Timeouts = 0
+		h.SkipUntil = 0
+		return
+	}
+
+	h.Timeouts++
+	if h.Timeouts >= regionTimeoutLimit {
+		h.SkipUntil = time.Now().Add(regionCooldown).Unix()
+	}
+}
@@ -0,0 +1,287 @@
+package metadata
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ExportTracks writes a copy of the Guitar Pro file src to dst keeping only
+// the given tracks (0-based indexes). The copy has the same format as the
+// source; everything else in the file is kept byte for byte where possible.
+func ExportTracks(src, dst string, tracks []int) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("failed to export %s: %v", src, r)
+		}
+	}()
+
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+
+	// Tracks are always written in file order
+	keep := append([]int{}, tracks...)
+	sort.Ints(keep)
+	for i := 1; i < len(keep); i++ {
+		if keep[i] == keep[i-1] {
+			return fmt.Errorf("duplicate track index: %d", keep[i])
+		}
+	}
+	if len(keep) == 0 {
+		return fmt.Errorf("no tracks selected")
+	}
+	if keep[0] < 0 {
+		return fmt.Errorf("invalid track index: %d", keep[0])
+	}
+
+	var out []byte
+	isGPIF, err := isGPIFContainer(src)
+	if err != nil {
+		return err
+	}
+	if isGPIF {
+		out, err = exportGPIFTracks(data, keep)
+	} else {
+		var song *gpSong
+		song, err = readGPSong(data)
+		if err != nil {
+			return err
+		}
+		out, err = writeGPTracks(data, song, keep)
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(dst, out, 0644)
+}
+
+// writeGPTracks rebuilds a GP3/4/5 file with only the kept tracks, by
+// copying the byte ranges recorded while reading it
+func writeGPTracks(data []byte, song *gpSong, keep []int) ([]byte, error) {
+	for _, t := range keep {
+		if t < 0 || t >= len(song.trackBlocks) {
+			return nil, fmt.Errorf("invalid track index: %d", t)
+		}
+	}
+
+	body, err := readGPBody(data, song)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read measures: %w", err)
+	}
+
+	var out bytes.Buffer
+	putInt := func(v int) {
+		binary.Write(&out, binary.LittleEndian, int32(v))
+	}
+
+	// Song header, with the lyrics track renumbered (1-based, 0 for none)
+	header := append([]byte{}, data[:song.trackCountOffset]...)
+	if off := song.lyricsTrackOffset; off >= 0 {
+		lyricsTrack := int(int32(binary.LittleEndian.Uint32(header[off:])))
+		newTrack := 0
+		for i, t := range keep {
+			if t+1 == lyricsTrack {
+				newTrack = i + 1
+			}
+		}
+		binary.LittleEndian.PutUint32(header[off:], uint32(newTrack))
+	}
+	out.Write(header)
+	putInt(len(keep))
+
+	// Measure headers are shared by all tracks
+	out.Write(data[song.trackCountOffset+4 : song.tracksOffset])
+
+	// GP5.00 pads every track with a byte, GP5.10 only the first one
+	for i, t := range keep {
+		if song.major >= 5 && (i == 0 || song.minor == 0) {
+			out.WriteByte(data[song.tracksOffset])
+		}
+		block := song.trackBlocks[t]
+		out.Write(data[block.Start:block.End])
+	}
+	out.Write(data[song.trackBlocks[len(song.trackBlocks)-1].End:song.bodyOffset])
+
+	for _, measure := range body.Blocks {
+		for _, t := range keep {
+			out.Write(data[measure[t].Start:measure[t].End])
+		}
+	}
+	out.Write(data[body.End:])
+
+	return out.Bytes(), nil
+}
+
+// gpifLayoutFiles describe per track layout; Guitar Pro rebuilds them when
+// missing, so they are dropped rather than rewritten
+var gpifLayoutFiles = map[string]bool{
+	"partconfiguration":   true,
+	"layoutconfiguration": true,
+}
+
+// exportGPIFTracks rewrites a GP6 (BCFZ/BCFS) or GP7 (zip) container with
+// only the kept tracks in its score.gpif
+func exportGPIFTracks(data []byte, keep []int) ([]byte, error) {
+	if bytes.HasPrefix(data, []byte("BCF")) {
+		files, err := readBCFSFiles(data)
+		if err != nil {
+			return nil, err
+		}
+		var kept []bcfsFile
+		for _, f := range files {
+			if gpifLayoutFiles[strings.ToLower(f.Name)] {
+				continue
+			}
+			if strings.EqualFold(f.Name, "score.gpif") {
+				if f.Data, err = filterGPIFTracks(f.Data, keep); err != nil {
+					return nil, err
+				}
+			}
+			kept = append(kept, f)
+		}
+		return writeBCFS(kept)
+	}
+
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, f := range r.File {
+		base := strings.ToLower(path.Base(f.Name))
+		if gpifLayoutFiles[base] {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		content, err := io.ReadAll(io.LimitReader(rc, 64*1024*1024))
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		if base == "score.gpif" {
+			if content, err = filterGPIFTracks(content, keep); err != nil {
+				return nil, err
+			}
+		}
+
+		fw, err := w.CreateHeader(&zip.FileHeader{Name: f.Name, Method: f.Method, Modified: f.Modified})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := fw.Write(content); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// filterGPIFTracks removes the tracks not in keep from a score.gpif
+// document. Kept tracks are renumbered from 0; the bars of removed tracks
+// are unlinked from the master bars.
+func filterGPIFTracks(content []byte, keep []int) ([]byte, error) {
+	newIndex := make(map[int]int, len(keep))
+	ids := make([]string, len(keep))
+	for i, t := range keep {
+		newIndex[t] = i
+		ids[i] = strconv.Itoa(i)
+	}
+
+	dec := xml.NewDecoder(bytes.NewReader(content))
+	var out bytes.Buffer
+	enc := xml.NewEncoder(&out)
+
+	var stack []string
+	trackIndex := -1
+	skipDepth := 0 // > 0 while inside a removed track
+	for {
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid score.gpif: %w", err)
+		}
+
+		if skipDepth > 0 {
+			switch tok.(type) {
+			case xml.StartElement:
+				skipDepth++
+			case xml.EndElement:
+				skipDepth--
+			}
+			continue
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "Track" && strings.Join(stack, ">") == "GPIF>Tracks" {
+				trackIndex++
+				i, ok := newIndex[trackIndex]
+				if !ok {
+					skipDepth = 1
+					continue
+				}
+				t = t.Copy()
+				for a := range t.Attr {
+					if t.Attr[a].Name.Local == "id" {
+						t.Attr[a].Value = strconv.Itoa(i)
+					}
+				}
+				tok = t
+			}
+			stack = append(stack, t.Name.Local)
+		case xml.EndElement:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			switch strings.Join(stack, ">") {
+			case "GPIF>MasterTrack>Tracks":
+				tok = xml.CharData(strings.Join(ids, " "))
+			case "GPIF>MasterBars>MasterBar>Bars":
+				// One bar id per track, in track order
+				fields := strings.Fields(string(t))
+				bars := make([]string, 0, len(keep))
+				for _, k := range keep {
+					if k < len(fields) {
+						bars = append(bars, fields[k])
+					}
+				}
+				tok = xml.CharData(strings.Join(bars, " "))
+			}
+		}
+
+		if err := enc.EncodeToken(xml.CopyToken(tok)); err != nil {
+			return nil, err
+		}
+	}
+
+	if trackIndex < keep[len(keep)-1] {
+		return nil, fmt.Errorf("invalid track index: %d", keep[len(keep)-1])
+	}
+	if err := enc.Flush(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,243 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// GP6 (.gpx) files are not zips: they are a small sector based filesystem
+// ("BCFS"), usually compressed with a custom LZ77 variant ("BCFZ").
+
+const bcfsSectorSize = 0x1000
+
+// bitReader reads a byte slice bit by bit, most significant bit first
+type bitReader struct {
+	data []byte
+	pos  int // Byte position
+	bit  int // Bit position in current byte (0-7)
+}
+
+var errBitsExhausted = fmt.Errorf("unexpected end of BCFZ data")
+
+func (b *bitReader) readBit() (int, error) {
+	if b.pos >= len(b.data) {
+		return 0, errBitsExhausted
+	}
+	v := int(b.data[b.pos]>>(7-b.bit)) & 0x01
+	b.bit++
+	if b.bit == 8 {
+		b.bit = 0
+		b.pos++
+	}
+	return v, nil
+}
+
+// readBits reads count bits, most significant first
+func (b *bitReader) readBits(count int) (int, error) {
+	v := 0
+	for i := count - 1; i >= 0; i-- {
+		bit, err := b.readBit()
+		if err != nil {
+			return 0, err
+		}
+		v |= bit << i
+	}
+	return v, nil
+}
+
+// readBitsReversed reads count bits, least significant first
+func (b *bitReader) readBitsReversed(count int) (int, error) {
+	v := 0
+	for i := 0; i < count; i++ {
+		bit, err := b.readBit()
+		if err != nil {
+			return 0, err
+		}
+		v |= bit << i
+	}
+	return v, nil
+}
+
+// decompressBCFZ inflates a BCFZ stream (without the 4 byte "BCFZ" header)
+func decompressBCFZ(data []byte) ([]byte, error) {
+	if len(data) < 4 {
+		return nil, errBitsExhausted
+	}
+	expected := int(binary.LittleEndian.Uint32(data))
+	if expected < 0 || expected > 64*1024*1024 {
+		return nil, fmt.Errorf("invalid BCFZ size: %d", expected)
+	}
+
+	src := &bitReader{data: data[4:]}
+	out := make([]byte, 0, expected)
+
+	for len(out) < expected {
+		flag, err := src.readBit()
+		if err != nil {
+			break // Truncated streams still carry usable data
+		}
+
+		if flag == 1 {
+			// Back reference into what we already decompressed
+			wordSize, err := src.readBits(4)
+			if err != nil {
+				break
+			}
+			offset, err := src.readBitsReversed(wordSize)
+			if err != nil {
+				break
+			}
+			size, err := src.readBitsReversed(wordSize)
+			if err != nil {
+				break
+			}
+			start := len(out) - offset
+			if offset == 0 || start < 0 {
+				return nil, fmt.Errorf("invalid BCFZ back reference")
+			}
+			toRead := size
+			if offset < toRead {
+				toRead = offset
+			}
+			out = append(out, out[start:start+toRead]...)
+		} else {
+			// Literal bytes
+			size, err := src.readBitsReversed(2)
+			if err != nil {
+				break
+			}
+			for i := 0; i < size; i++ {
+				v, err := src.readBits(8)
+				if err != nil {
+					break
+				}
+				out = append(out, byte(v))
+			}
+		}
+	}
+
+	return out, nil
+}
+
+// bcfsFile is one file stored in a GP6 container
+type bcfsFile struct {
+	Name string
+	Data []byte
+}
+
+// readBCFSFile extracts a named file from a GP6 container (BCFZ or BCFS)
+func readBCFSFile(data []byte, name string) ([]byte, error) {
+	files, err := readBCFSFiles(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		if strings.EqualFold(f.Name, name) {
+			return f.Data, nil
+		}
+	}
+	return nil, fmt.Errorf("%s not found in gpx file", name)
+}
+
+// readBCFSFiles returns all files of a GP6 container (BCFZ or BCFS), in order
+func readBCFSFiles(data []byte) ([]bcfsFile, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte("BCFZ")):
+		inflated, err := decompressBCFZ(data[4:])
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.HasPrefix(inflated, []byte("BCFS")) {
+			return nil, fmt.Errorf("invalid BCFZ content")
+		}
+		data = inflated[4:]
+	case bytes.HasPrefix(data, []byte("BCFS")):
+		data = data[4:]
+	default:
+		return nil, fmt.Errorf("not a GP6 container")
+	}
+
+	getInt := func(offset int) int {
+		if offset < 0 || offset+4 > len(data) {
+			return 0
+		}
+		return int(int32(binary.LittleEndian.Uint32(data[offset:])))
+	}
+
+	// The first sector is unused; each following sector may hold a file entry
+	// (type 2): name at 0x04, size at 0x8C, then a zero terminated list of
+	// data sector indexes at 0x94.
+	var files []bcfsFile
+	offset := 0
+	for offset+bcfsSectorSize+3 < len(data) {
+		offset += bcfsSectorSize
+		if getInt(offset) != 2 {
+			continue
+		}
+
+		nameBytes := data[offset+0x04 : min(offset+0x04+127, len(data))]
+		if idx := bytes.IndexByte(nameBytes, 0); idx != -1 {
+			nameBytes = nameBytes[:idx]
+		}
+		fileSize := getInt(offset + 0x8C)
+
+		var content []byte
+		pointer := offset + 0x94
+		for i := 0; ; i++ {
+			sector := getInt(pointer + 4*i)
+			if sector <= 0 {
+				break
+			}
+			start := sector * bcfsSectorSize
+			if start+bcfsSectorSize > len(data) {
+				break
+			}
+			content = append(content, data[start:start+bcfsSectorSize]...)
+			// Continue scanning after the file's data, never backwards
+			offset = max(offset, start)
+		}
+
+		if fileSize >= 0 && fileSize < len(content) {
+			content = content[:fileSize]
+		}
+		files = append(files, bcfsFile{Name: string(nameBytes), Data: content})
+	}
+
+	return files, nil
+}
+
+// writeBCFS builds an uncompressed GP6 container holding the given files.
+// Each file gets an entry sector directly followed by its data sectors.
+func writeBCFS(files []bcfsFile) ([]byte, error) {
+	// Sector indexes of a file must fit in its entry sector
+	maxSectors := (bcfsSectorSize-0x94)/4 - 1
+
+	out := make([]byte, bcfsSectorSize) // Unused first sector
+	for _, f := range files {
+		if len(f.Name) > 127 {
+			return nil, fmt.Errorf("file name too long: %s", f.Name)
+		}
+		sectorCount := (len(f.Data) + bcfsSectorSize - 1) / bcfsSectorSize
+		if sectorCount > maxSectors {
+			return nil, fmt.Errorf("file too large for gpx container: %s", f.Name)
+		}
+
+		entry := make([]byte, bcfsSectorSize)
+		binary.LittleEndian.PutUint32(entry, 2)
+		copy(entry[0x04:], f.Name)
+		binary.LittleEndian.PutUint32(entry[0x8C:], uint32(len(f.Data)))
+		first := len(out)/bcfsSectorSize + 1
+		for i := 0; i < sectorCount; i++ {
+			binary.LittleEndian.PutUint32(entry[0x94+4*i:], uint32(first+i))
+		}
+		out = append(out, entry...)
+
+		content := make([]byte, sectorCount*bcfsSectorSize)
+		copy(content, f.Data)
+		out = append(out, content...)
+	}
+
+	return append([]byte("BCFS"), out...), nil
+}
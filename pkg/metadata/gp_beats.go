@@ -0,0 +1,333 @@
+package metadata
+
+import (
+	"fmt"
+	"haya-tab/pkg/score"
+)
+
+// The measure data of GP3/4/5 files has no size prefixes, so finding the
+// tempo changes (stored in beat mix tables) or the bytes belonging to a
+// track means walking every beat and note. The rhythm and the notes are
+// decoded for the score; effects are only read to stay in sync.
+
+// gpBody is what readGPBody learns from the measure data
+type gpBody struct {
+	TempoChanges []TempoChange
+	Blocks       [][]gpRange       // Bytes of each measure (first index) of each track
+	Measures     [][]score.Measure // Content of each measure (first index) of each track
+	End          int               // End of the measure data
+}
+
+// readGPBody walks the measure data of a song read by readGPSong. On error
+// the returned body holds everything read before the failure.
+func readGPBody(data []byte, song *gpSong) (*gpBody, error) {
+	r := &gpReader{data: data, pos: song.bodyOffset, major: song.major, minor: song.minor}
+	body := &gpBody{TempoChanges: []TempoChange{}}
+
+	voices := 1
+	if r.major >= 5 {
+		voices = 2
+	}
+
+	current := song.Tempo
+	for m := range song.Measures {
+		blocks := make([]gpRange, 0, len(song.stringCounts))
+		measures := make([]score.Measure, 0, len(song.stringCounts))
+		for _, stringCount := range song.stringCounts {
+			start := r.pos
+			measure := score.Measure{Voices: make([]score.Voice, voices)}
+			for v := 0; v < voices; v++ {
+				beatCount := int(r.readInt())
+				if beatCount < 0 || beatCount > 1024 {
+					return body, fmt.Errorf("invalid beat count in measure %d: %d", m+1, beatCount)
+				}
+				for b := 0; b < beatCount && r.err == nil; b++ {
+					beat, tempo := r.readBeat(stringCount)
+					measure.Voices[v].Beats = append(measure.Voices[v].Beats, beat)
+					if tempo > 0 && tempo != current {
+						body.TempoChanges = append(body.TempoChanges, TempoChange{Measure: m + 1, Tempo: tempo})
+						current = tempo
+					}
+				}
+			}
+			if r.major >= 5 {
+				r.readByte() // Line break
+			}
+			if r.err != nil {
+				return body, fmt.Errorf("failed to read measure %d: %w", m+1, r.err)
+			}
+			blocks = append(blocks, gpRange{Start: start, End: r.pos})
+			measures = append(measures, measure)
+		}
+		body.Blocks = append(body.Blocks, blocks)
+		body.Measures = append(body.Measures, measures)
+	}
+	body.End = r.pos
+	return body, nil
+}
+
+// readBeat reads one beat and returns it with the tempo it sets, or -1
+func (r *gpReader) readBeat(stringCount int) (score.Beat, int) {
+	var beat score.Beat
+	flags := r.readByte()
+	if flags&0x40 != 0 {
+		status := r.readByte()
+		beat.Rest = status == 0x00 || status == 0x02 // Empty or rest
+	}
+	// -2 for a whole note, -1 for a half, 0 for a quarter and so on
+	value := 4
+	if duration := int(r.readSignedByte()); duration >= -2 && duration <= 6 {
+		value = 1 << (duration + 2)
+	}
+	dots := 0
+	if flags&0x01 != 0 {
+		dots = 1
+	}
+	tuplet := 0
+	if flags&0x20 != 0 {
+		tuplet = int(r.readInt())
+	}
+	beat.Ticks = score.NoteTicks(value, dots, tuplet, tupletSpan(tuplet))
+	if flags&0x02 != 0 {
+		r.readChord()
+	}
+	if flags&0x04 != 0 {
+		r.readIntByteSizeString() // Text
+	}
+	if flags&0x08 != 0 {
+		r.readBeatEffects()
+	}
+	tempo := -1
+	if flags&0x10 != 0 {
+		tempo = r.readMixTableChange()
+	}
+	notes := r.readNotes(stringCount)
+	if !beat.Rest {
+		beat.Notes = notes
+	}
+
+	if r.major >= 5 {
+		flags2 := r.readShort()
+		if flags2&0x0800 != 0 {
+			r.readByte() // Secondary beam break
+		}
+	}
+	return beat, tempo
+}
+
+// tupletSpan returns the number of regular notes in the time of which a
+// tuplet of n notes is played, e.g. 2 for a triplet; 0 if n is no tuplet
+func tupletSpan(n int) int {
+	switch {
+	case n < 2 || n > 13:
+		return 0
+	case n == 2:
+		return 3 // Duplet in compound time
+	}
+	span := 1
+	for span*2 < n {
+		span *= 2
+	}
+	return span
+}
+
+// readChord skips a chord diagram
+func (r *gpReader) readChord() {
+	if r.major >= 5 {
+		r.skip(107)
+		return
+	}
+
+	if !r.readBool() {
+		// Old format: name, first fret and 6 frets if the fret is set
+		r.readIntByteSizeString()
+		if r.readInt() != 0 {
+			r.skip(6 * 4)
+		}
+		return
+	}
+
+	if r.major == 3 {
+		r.skip(124)
+	} else {
+		r.skip(106)
+	}
+}
+
+// readBeatEffects skips the effects applied to a whole beat
+func (r *gpReader) readBeatEffects() {
+	if r.major < 4 {
+		flags := r.readByte()
+		if flags&0x20 != 0 {
+			r.readByte() // Tap, slap, pop or tremolo bar
+			r.readInt()  // Tremolo bar value
+		}
+		if flags&0x40 != 0 {
+			r.skip(2) // Stroke up/down
+		}
+		return
+	}
+
+	flags1 := r.readByte()
+	flags2 := r.readByte()
+	if flags1&0x20 != 0 {
+		r.readByte() // Tap, slap, pop
+	}
+	if flags2&0x04 != 0 {
+		r.readBend() // Tremolo bar
+	}
+	if flags1&0x40 != 0 {
+		r.skip(2) // Stroke up/down
+	}
+	if flags2&0x02 != 0 {
+		r.readByte() // Pick stroke
+	}
+}
+
+// readMixTableChange reads a mix table change and returns its tempo, or -1
+func (r *gpReader) readMixTableChange() int {
+	r.readSignedByte() // Instrument
+	if r.major >= 5 {
+		r.skip(16) // RSE instrument
+	}
+	values := r.next(6) // Volume, balance, chorus, reverb, phaser, tremolo
+	if r.major >= 5 {
+		r.readIntByteSizeString() // Tempo name
+	}
+	tempo := int(r.readInt())
+
+	// Transition durations, only present for the values that change
+	for _, v := range values {
+		if int8(v) >= 0 {
+			r.readByte()
+		}
+	}
+	if tempo >= 0 {
+		r.readByte()
+		if r.major >= 5 && r.minor > 0 {
+			r.readBool() // Hide tempo
+		}
+	}
+
+	if r.major >= 4 {
+		r.readByte() // Apply to all tracks flags
+	}
+	if r.major >= 5 {
+		r.readSignedByte() // Wah
+		if r.minor > 0 {
+			r.readIntByteSizeString() // RSE effect
+			r.readIntByteSizeString() // RSE effect category
+		}
+	}
+	return tempo
+}
+
+// readNotes reads the notes of a beat
+func (r *gpReader) readNotes(stringCount int) []score.Note {
+	var notes []score.Note
+	stringFlags := r.readByte()
+	for s := 1; s <= stringCount; s++ {
+		if stringFlags&(1<<(7-s)) != 0 {
+			notes = append(notes, r.readNote(s))
+		}
+	}
+	return notes
+}
+
+// readNote reads a single note on string s
+func (r *gpReader) readNote(s int) score.Note {
+	note := score.Note{String: s, Velocity: score.DefaultVelocity}
+	flags := r.readByte()
+	if flags&0x20 != 0 {
+		switch r.readByte() {
+		case 2:
+			note.Tied = true
+		case 3:
+			note.Dead = true
+		}
+	}
+	if r.major < 5 && flags&0x01 != 0 {
+		r.skip(2) // Time independent duration and tuplet
+	}
+	if flags&0x10 != 0 {
+		note.Velocity = score.DynamicVelocity(int(r.readSignedByte()))
+	}
+	if flags&0x20 != 0 {
+		note.Fret = int(r.readSignedByte())
+	}
+	if flags&0x80 != 0 {
+		r.skip(2) // Left and right hand fingering
+	}
+	if r.major >= 5 {
+		if flags&0x01 != 0 {
+			r.skip(8) // Duration percent (float64)
+		}
+		r.readByte() // Second flags byte
+	}
+	if flags&0x08 != 0 {
+		r.readNoteEffects()
+	}
+	return note
+}
+
+// readNoteEffects skips the effects of a note
+func (r *gpReader) readNoteEffects() {
+	if r.major < 4 {
+		flags := r.readByte()
+		if flags&0x01 != 0 {
+			r.readBend()
+		}
+		if flags&0x10 != 0 {
+			r.skip(4) // Grace note
+		}
+		return
+	}
+
+	flags1 := r.readByte()
+	flags2 := r.readByte()
+	if flags1&0x01 != 0 {
+		r.readBend()
+	}
+	if flags1&0x10 != 0 {
+		// Grace note, GP5 adds a flags byte
+		if r.major >= 5 {
+			r.skip(5)
+		} else {
+			r.skip(4)
+		}
+	}
+	if flags2&0x04 != 0 {
+		r.readByte() // Tremolo picking
+	}
+	if flags2&0x08 != 0 {
+		r.readByte() // Slide
+	}
+	if flags2&0x10 != 0 {
+		harmonic := r.readByte()
+		if r.major >= 5 {
+			switch harmonic {
+			case 2:
+				r.skip(3) // Artificial: semitone, accidental, octave
+			case 3:
+				r.readByte() // Tapped: fret
+			}
+		}
+	}
+	if flags2&0x20 != 0 {
+		r.skip(2) // Trill fret and period
+	}
+}
+
+// readBend skips a bend (or GP4+ tremolo bar) curve
+func (r *gpReader) readBend() {
+	r.readSignedByte() // Type
+	r.readInt()        // Value
+	points := int(r.readInt())
+	if points < 0 || points > 1024 {
+		if r.err == nil {
+			r.err = fmt.Errorf("invalid bend point count: %d", points)
+		}
+		return
+	}
+	r.skip(points * 9) // Position, value, vibrato
+}
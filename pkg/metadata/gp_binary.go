@@ -2,132 +2,572 @@ package metadata
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
-	"io"
+	"haya-tab/pkg/score"
 	"os"
 	"strings"
 )
 
+var errGPTruncated = errors.New("unexpected end of GP file")
+
+// gpReader reads the little-endian primitives used by GP3/4/5 files.
+// The first error is sticky: once set, every read returns a zero value,
+// so callers only need to check r.err at section boundaries.
+type gpReader struct {
+	data  []byte
+	pos   int
+	err   error
+	major int
+	minor int
+}
+
+func (r *gpReader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || r.pos+n > len(r.data) {
+		r.err = errGPTruncated
+		return nil
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+func (r *gpReader) skip(n int) {
+	r.next(n)
+}
+
+func (r *gpReader) readByte() byte {
+	b := r.next(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (r *gpReader) readSignedByte() int8 {
+	return int8(r.readByte())
+}
+
+func (r *gpReader) readBool() bool {
+	return r.readByte() != 0
+}
+
+func (r *gpReader) readShort() int16 {
+	b := r.next(2)
+	if b == nil {
+		return 0
+	}
+	return int16(binary.LittleEndian.Uint16(b))
+}
+
+func (r *gpReader) readInt() int32 {
+	b := r.next(4)
+	if b == nil {
+		return 0
+	}
+	return int32(binary.LittleEndian.Uint32(b))
+}
+
+// readString reads size bytes and keeps the first length of them.
+// A size of 0 means the field is exactly length bytes long.
+func (r *gpReader) readString(size, length int) string {
+	count := size
+	if count <= 0 {
+		count = length
+	}
+	if length < 0 || count < 0 {
+		if r.err == nil {
+			r.err = fmt.Errorf("invalid string length: %d", length)
+		}
+		return ""
+	}
+	buf := r.next(count)
+	if buf == nil {
+		return ""
+	}
+	if length > len(buf) {
+		length = len(buf)
+	}
+	return decodeGPString(buf[:length])
+}
+
+// readByteSizeString reads a 1 byte length followed by a fixed size field
+func (r *gpReader) readByteSizeString(size int) string {
+	length := int(r.readByte())
+	return r.readString(size, length)
+}
+
+// readIntSizeString reads a 4 byte length followed by the string
+func (r *gpReader) readIntSizeString() string {
+	length := int(r.readInt())
+	return r.readString(length, length)
+}
+
+// readIntByteSizeString reads a 4 byte field size, then a byte size string
+func (r *gpReader) readIntByteSizeString() string {
+	size := int(r.readInt()) - 1
+	return r.readByteSizeString(size)
+}
+
+// gpSong holds the parts of a GP3/4/5 file that we extract
+type gpSong struct {
+	Version   string
+	Title     string
+	Subtitle  string
+	Artist    string
+	Album     string
+	Words     string // Words author, or words and music author before GP5
+	Music     string // Music author (GP5)
+	Copyright string
+	TabAuthor string
+	Notice    []string // Lines of the notice, empty ones left out
+	Lyrics    []string // Lines of the lyrics block (GP4+), empty ones left out
+	Tempo     int
+	Key       string // Initial key, see keySignatureName; "" for C major
+	Measures  []gpMeasureHeader
+	Tracks    []TrackInfo
+
+	major        int
+	minor        int
+	stringCounts []int // Strings per track as stored, including drum tracks
+
+	// Byte offsets used to rewrite the file (see writeGPTracks)
+	lyricsTrackOffset int       // Lyrics track number (GP4+), -1 if absent
+	trackCountOffset  int       // Track count
+	tracksOffset      int       // First track, right after the measure headers
+	trackBlocks       []gpRange // Each track, without GP5 padding bytes
+	bodyOffset        int       // Start of the measure data, right after the tracks
+}
+
+// gpRange is a byte range [Start, End) of a GP file
+type gpRange struct {
+	Start int
+	End   int
+}
+
+// gpMeasureHeader is the per measure information shared by all tracks
+type gpMeasureHeader struct {
+	Numerator   int // Time signature, carried over from the previous measure
+	Denominator int
+	RepeatOpen  bool
+	RepeatClose int   // Number of plays of the repeated block, 0 if none
+	Endings     []int // Alternate ending numbers, e.g. [1, 2]
+	Marker      string
+	Key         string // Key signature set at this measure, e.g. "Em"; "" if none
+}
+
+// gpMidiChannel is one of the 64 MIDI channels declared in the file header
+type gpMidiChannel struct {
+	Program int
+}
+
 // parseGPBinary attempts to parse GP3, GP4, GP5 files
 func parseGPBinary(path string) (Metadata, error) {
-	f, err := os.Open(path)
+	data, err := os.ReadFile(path)
 	if err != nil {
 		return Metadata{}, err
 	}
-	defer f.Close()
 
-	// 1. Read Version (30 bytes)
-	versionBuf := make([]byte, 30)
-	if _, err := io.ReadFull(f, versionBuf); err != nil {
+	song, err := readGPSong(data)
+	if err != nil {
 		return Metadata{}, err
 	}
-	
-	// Truncate null bytes and clean
-	versionRaw := string(versionBuf)
-	if idx := indexOf(versionBuf, 0); idx != -1 {
-		versionRaw = versionRaw[:idx]
+
+	artist := creditedArtist(song.Artist, song.Music, song.Words)
+	return Metadata{
+		Title:  song.Title,
+		Artist: artist,
+		Album:  song.Album,
+		Tracks: song.Tracks,
+		Score: ScoreInfo{
+			Title:     song.Title,
+			Artist:    artist,
+			Album:     song.Album,
+			Words:     song.Words,
+			Music:     song.Music,
+			Copyright: song.Copyright,
+			TabAuthor: song.TabAuthor,
+			Notice:    song.Notice,
+			Tempo:     validTempo(song.Tempo),
+			Key:       song.Key,
+			Duration:  gpSongSummary(data, song).Duration,
+		},
+		Lyrics: strings.Join(song.Lyrics, "\n"),
+	}, nil
+}
+
+// creditedArtist returns the artist of a score, or when it has none the
+// author of its music, else of its words
+func creditedArtist(artist, music, words string) string {
+	for _, name := range []string{artist, music, words} {
+		if name = strings.TrimSpace(name); name != "" {
+			return name
+		}
 	}
-	version := strings.TrimSpace(versionRaw)
+	return ""
+}
 
+// readGPSong walks a GP3/4/5 file from the version header up to the end of
+// the track list. Measure contents are not read.
+func readGPSong(data []byte) (*gpSong, error) {
+	r := &gpReader{data: data}
+
+	// Version: 1 byte length + 30 bytes, e.g. "FICHIER GUITAR PRO v5.00"
+	version := strings.TrimSpace(r.readByteSizeString(30))
+	if r.err != nil {
+		return nil, r.err
+	}
 	if !validVersion(version) {
-		return Metadata{}, fmt.Errorf("unknown GP version: %s", version)
+		return nil, fmt.Errorf("unknown GP version: %s", version)
+	}
+	r.major, r.minor = parseGPVersion(version)
+	if r.major < 3 || r.major > 5 {
+		return nil, fmt.Errorf("unsupported GP version: %s", version)
 	}
 
-	// Determine version for string reading strategy
-	// Format: "FICHIER GUITAR PRO vX.YZ"
-	var majorVersion int
-	// Find "v"
-	vIdx := strings.LastIndex(version, "v")
-	if vIdx != -1 && vIdx+1 < len(version) {
-		fmt.Sscanf(version[vIdx+1:], "%d", &majorVersion)
+	song := &gpSong{Version: version, lyricsTrackOffset: -1}
+	r.readInfo(song)
+	if r.err != nil {
+		return nil, fmt.Errorf("failed to read score information: %w", r.err)
+	}
+
+	if r.major < 5 {
+		r.readBool() // Triplet feel
+	}
+	if r.major >= 4 {
+		song.lyricsTrackOffset = r.pos
+		song.Lyrics = r.readLyrics()
+	}
+	if r.major >= 5 {
+		r.readRSEMasterEffect()
+		r.readPageSetup()
+		r.readIntByteSizeString() // Tempo name
 	}
 
-	// Strategy:
-	// GP3: 1 byte length
-	// GP4/5: 4 byte length (int32)
+	song.Tempo = int(r.readInt())
+	if r.major >= 5 && r.minor > 0 {
+		r.readBool() // Hide tempo
+	}
+
+	// Key signature and octave
+	var key int
+	switch r.major {
+	case 3:
+		key = int(r.readInt())
+	case 4:
+		key = int(int8(r.readInt())) // The key is the first byte
+		r.readSignedByte()
+	default:
+		key = int(r.readSignedByte())
+		r.readInt()
+	}
+
+	channels := r.readMidiChannels()
+	if r.major >= 5 {
+		r.skip(19 * 2) // Musical directions (coda, segno, ...)
+		r.readInt()    // Master reverb
+	}
+
+	measureCount := int(r.readInt())
+	song.trackCountOffset = r.pos
+	trackCount := int(r.readInt())
+	if r.err != nil {
+		return nil, fmt.Errorf("failed to read song header: %w", r.err)
+	}
+	if measureCount < 0 || measureCount > 10000 || trackCount < 0 || trackCount > 128 {
+		return nil, fmt.Errorf("invalid measure/track count: %d/%d", measureCount, trackCount)
+	}
 
-	readString := func() (string, error) {
-		var length int
-		
-		if majorVersion < 4 {
-			// GP3: 1 byte length
-			var l uint8
-			if err := binary.Read(f, binary.LittleEndian, &l); err != nil {
-				return "", err
+	song.Measures = r.readMeasureHeaders(measureCount)
+	if r.err != nil {
+		return nil, fmt.Errorf("failed to read measure headers: %w", r.err)
+	}
+	// The first measure may set the key again, with its mode
+	song.Key = keySignatureName(key, false)
+	if len(song.Measures) > 0 && song.Measures[0].Key != "" {
+		song.Key = song.Measures[0].Key
+	}
+	if song.Key == "C" {
+		song.Key = ""
+	}
+
+	song.tracksOffset = r.pos
+	r.readTracks(song, trackCount, channels)
+	if r.err != nil {
+		return nil, fmt.Errorf("failed to read tracks: %w", r.err)
+	}
+
+	song.major, song.minor = r.major, r.minor
+	song.bodyOffset = r.pos
+	return song, nil
+}
+
+// readInfo reads the score information block
+func (r *gpReader) readInfo(song *gpSong) {
+	song.Title = r.readIntByteSizeString()
+	song.Subtitle = r.readIntByteSizeString()
+	song.Artist = r.readIntByteSizeString()
+	song.Album = r.readIntByteSizeString()
+	song.Words = strings.TrimSpace(r.readIntByteSizeString())
+	if r.major >= 5 {
+		song.Music = strings.TrimSpace(r.readIntByteSizeString())
+	}
+	song.Copyright = strings.TrimSpace(r.readIntByteSizeString())
+	song.TabAuthor = strings.TrimSpace(r.readIntByteSizeString())
+	r.readIntByteSizeString() // Instructions
+
+	noticeLines := int(r.readInt())
+	if noticeLines < 0 || noticeLines > 256 {
+		if r.err == nil {
+			r.err = fmt.Errorf("invalid notice line count: %d", noticeLines)
+		}
+		return
+	}
+	for i := 0; i < noticeLines; i++ {
+		if line := strings.TrimSpace(r.readIntByteSizeString()); line != "" {
+			song.Notice = append(song.Notice, line)
+		}
+	}
+}
+
+// readLyrics reads the five lines of the lyrics block (GP4+) and returns
+// the non-empty ones
+func (r *gpReader) readLyrics() []string {
+	var lines []string
+	r.readInt() // Lyrics track
+	for i := 0; i < 5; i++ {
+		r.readInt() // Starting measure
+		if line := strings.TrimSpace(r.readIntSizeString()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// readRSEMasterEffect skips the RSE master effect (GP5.10+)
+func (r *gpReader) readRSEMasterEffect() {
+	if r.minor > 0 {
+		r.readInt() // Master volume
+		r.readInt()
+		r.skip(11) // Equalizer: 10 bands + gain
+	}
+}
+
+// readPageSetup skips the GP5 page setup block
+func (r *gpReader) readPageSetup() {
+	r.skip(7 * 4) // Page size, margins and score size proportion
+	r.readShort() // Header and footer flags
+	for i := 0; i < 10; i++ {
+		r.readIntByteSizeString()
+	}
+}
+
+// readMidiChannels reads the 64 channel table (4 ports x 16 channels)
+func (r *gpReader) readMidiChannels() []gpMidiChannel {
+	channels := make([]gpMidiChannel, 64)
+	for i := range channels {
+		channels[i].Program = int(r.readInt())
+		r.skip(6) // Volume, balance, chorus, reverb, phaser, tremolo
+		r.skip(2)
+	}
+	return channels
+}
+
+// readMeasureHeaders reads the measure headers
+func (r *gpReader) readMeasureHeaders(count int) []gpMeasureHeader {
+	headers := make([]gpMeasureHeader, 0, count)
+	numerator, denominator := 4, 4
+	for i := 0; i < count && r.err == nil; i++ {
+		var header gpMeasureHeader
+		if r.major >= 5 && i > 0 {
+			r.skip(1)
+		}
+		flags := r.readByte()
+		if flags&0x01 != 0 {
+			numerator = int(r.readSignedByte())
+		}
+		if flags&0x02 != 0 {
+			denominator = int(r.readSignedByte())
+		}
+		header.Numerator, header.Denominator = numerator, denominator
+		header.RepeatOpen = flags&0x04 != 0
+		if flags&0x08 != 0 {
+			// GP5 stores the number of plays, older versions the number of repeats
+			header.RepeatClose = int(r.readSignedByte())
+			if r.major < 5 {
+				header.RepeatClose++
 			}
-			length = int(l)
-		} else {
-			// GP4/5: 4 byte length
-			var l int32
-			if err := binary.Read(f, binary.LittleEndian, &l); err != nil {
-				return "", err
+		}
+		if r.major < 5 && flags&0x10 != 0 {
+			// Single ending number
+			if n := int(r.readByte()); n > 0 {
+				header.Endings = []int{n}
 			}
-			length = int(l)
 		}
+		if flags&0x20 != 0 {
+			header.Marker = strings.TrimSpace(r.readIntByteSizeString())
+			r.skip(4) // Marker color
+		}
+		if flags&0x40 != 0 {
+			accidentals := int(r.readSignedByte())
+			header.Key = keySignatureName(accidentals, r.readSignedByte() == 1)
+		}
+		if r.major >= 5 {
+			if flags&0x10 != 0 {
+				// Bit mask of ending numbers
+				mask := r.readByte()
+				for n := 0; n < 8; n++ {
+					if mask&(1<<n) != 0 {
+						header.Endings = append(header.Endings, n+1)
+					}
+				}
+			}
+			if flags&0x03 != 0 {
+				r.skip(4) // Beam groups
+			}
+			if flags&0x10 == 0 {
+				r.skip(1)
+			}
+			r.readByte() // Triplet feel
+		}
+		headers = append(headers, header)
+	}
+	return headers
+}
 
-		if length == 0 {
-			return "", nil
+// readTracks reads the track definitions into song. It also records the
+// string count of every track, which is needed to walk the measure data.
+func (r *gpReader) readTracks(song *gpSong, count int, channels []gpMidiChannel) {
+	tracks := make([]TrackInfo, 0, count)
+	stringCounts := make([]int, 0, count)
+	blocks := make([]gpRange, 0, count)
+	for i := 0; i < count && r.err == nil; i++ {
+		if r.major >= 5 && (i == 0 || r.minor == 0) {
+			r.skip(1)
 		}
-		
-		// Sanity check
-		if length < 0 || length > 2048 {
-			return "", fmt.Errorf("invalid string length: %d", length)
+		start := r.pos
+
+		flags := r.readByte()
+		track := TrackInfo{
+			Index:        i,
+			Name:         strings.TrimSpace(r.readByteSizeString(40)),
+			IsPercussion: flags&0x01 != 0,
+			Program:      -1,
+		}
+
+		stringCount := int(r.readInt())
+		if stringCount < 0 || stringCount > 7 {
+			if r.err == nil {
+				r.err = fmt.Errorf("invalid string count: %d", stringCount)
+			}
+			break
+		}
+		stringCounts = append(stringCounts, stringCount)
+		for s := 0; s < 7; s++ {
+			tuning := int(r.readInt())
+			if s < stringCount {
+				track.Tuning = append(track.Tuning, tuning)
+			}
+		}
+		track.StringCount = len(track.Tuning)
+
+		r.readInt() // Port
+		channelIndex := int(r.readInt()) - 1
+		r.readInt() // Effect channel
+		if channelIndex >= 0 && channelIndex < len(channels) {
+			track.Program = channels[channelIndex].Program
+			if channelIndex%16 == 9 {
+				track.IsPercussion = true
+			}
 		}
+		r.readInt() // Fret count
+		track.Capo = int(r.readInt())
+		r.skip(4) // Color
 
-		buf := make([]byte, length)
-		if _, err := io.ReadFull(f, buf); err != nil {
-			return "", err
+		if r.major >= 5 {
+			r.readShort() // Track settings flags
+			r.readByte()  // Auto accentuation
+			r.readByte()  // MIDI bank
+			r.readTrackRSE()
 		}
-		// NOTE: Real implementation should handle Charset (CP1252), but for now raw string
-		return string(buf), nil
+
+		if track.IsPercussion {
+			track.Tuning = nil
+			track.StringCount = 0
+		}
+		finishTrackInfo(&track, "")
+		tracks = append(tracks, track)
+		blocks = append(blocks, gpRange{Start: start, End: r.pos})
 	}
-	
-	// GP5 often has score info immediately after version?
-	// The structure for GP3/4/5 generally starts with:
-	// - Version (30 bytes)
-	// - Score Information Block
-	//   - Title
-	//   - Subtitle
-	//   - Artist
-	//   - Album
-	//   - Author
-	//   ...
-	
-	// However, some versions might have extra bytes.
-	// For robustness, if the first read fails or looks like garbage, we might need a better parser.
-	// But assuming standard file integrity:
 
-	var m Metadata
+	if r.major >= 5 {
+		if r.minor == 0 {
+			r.skip(2)
+		} else {
+			r.skip(1)
+		}
+	}
+	song.Tracks, song.stringCounts, song.trackBlocks = tracks, stringCounts, blocks
+}
 
-	// Title
-	title, err := readString()
-	if err != nil { return Metadata{}, fmt.Errorf("failed to read title: %w", err) }
-	m.Title = title
+// readTrackRSE skips the GP5 track RSE block
+func (r *gpReader) readTrackRSE() {
+	r.readByte() // Humanize
+	r.skip(3 * 4)
+	r.skip(12)
+	r.readInt() // Instrument
+	r.readInt()
+	r.readInt() // Sound bank
+	if r.minor == 0 {
+		r.readShort() // Effect number
+		r.skip(1)
+	} else {
+		r.readInt()               // Effect number
+		r.skip(4)                 // Equalizer: 3 bands + gain
+		r.readIntByteSizeString() // Effect
+		r.readIntByteSizeString() // Effect category
+	}
+}
 
-	// Subtitle (skip)
-	_, err = readString()
-	if err != nil { return Metadata{}, fmt.Errorf("failed to read subtitle: %w", err) }
+// majorKeys and minorKeys are the keys of the signatures from 7 flats to 7
+// sharps
+var (
+	majorKeys = [15]string{"Cb", "Gb", "Db", "Ab", "Eb", "Bb", "F", "C", "G", "D", "A", "E", "B", "F#", "C#"}
+	minorKeys = [15]string{"Abm", "Ebm", "Bbm", "Fm", "Cm", "Gm", "Dm", "Am", "Em", "Bm", "F#m", "C#m", "G#m", "D#m", "A#m"}
+)
 
-	// Artist
-	artist, err := readString()
-	if err != nil { return Metadata{}, fmt.Errorf("failed to read artist: %w", err) }
-	m.Artist = artist
+// keySignatureName returns the key of a signature of accidentals sharps, or
+// flats when negative, e.g. "D" for 2 or "Bm" if minor. Returns "" for a
+// signature out of range.
+func keySignatureName(accidentals int, minor bool) string {
+	if accidentals < -7 || accidentals > 7 {
+		return ""
+	}
+	if minor {
+		return minorKeys[accidentals+7]
+	}
+	return majorKeys[accidentals+7]
+}
 
-	// Album
-	album, err := readString()
-	if err != nil { return Metadata{}, fmt.Errorf("failed to read album: %w", err) }
-	m.Album = album
-	
-	return m, nil
+// validTempo returns tempo, or 0 if it is not a plausible tempo in BPM
+func validTempo(tempo int) int {
+	if tempo < score.MinTempo || tempo > score.MaxTempo {
+		return 0
+	}
+	return tempo
 }
 
-func indexOf(data []byte, b byte) int {
-	for i, v := range data {
-		if v == b {
-			return i
-		}
+// parseGPVersion extracts the major/minor numbers from "... vX.YZ"
+func parseGPVersion(version string) (int, int) {
+	var major, minor int
+	vIdx := strings.LastIndex(version, "v")
+	if vIdx != -1 && vIdx+1 < len(version) {
+		fmt.Sscanf(version[vIdx+1:], "%d.%d", &major, &minor)
 	}
-	return -1
+	return major, minor
 }
 
 func validVersion(v string) bool {
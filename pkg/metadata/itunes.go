@@ -0,0 +1,226 @@
+package metadata
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// iTunes throttles searches per address, answering 429 or 403, and a bulk
+// sync makes hundreds of them: searches share one rate limit, back off
+// together when throttled, and identical searches are made once.
+const (
+	itunesPerMinute = 20
+	itunesBurst     = 5
+	// itunesMaxWait bounds how long a search waits for its turn; longer
+	// waits fail with ErrRateLimited so cover workers stay responsive
+	itunesMaxWait = 30 * time.Second
+	// itunesRetries is the number of retries of a throttled search, after
+	// Retry-After or itunesRetryDelay, doubled for each retry
+	itunesRetries    = 2
+	itunesRetryDelay = 10 * time.Second
+	// itunesResultTTL is how long a search result, found or not, is reused
+	itunesResultTTL  = 10 * time.Minute
+	itunesMaxResults = 1000
+)
+
+// itunesSearchURL is the search endpoint of the iTunes API
+var itunesSearchURL = "https://itunes.apple.com/search"
+
+// ErrRateLimited is returned when iTunes throttles the searches
+var ErrRateLimited = errors.New("iTunes rate limit exceeded")
+
+// ErrOffline is returned instead of making a request in offline mode
+var ErrOffline = errors.New("offline mode is on")
+
+var offline atomic.Bool
+
+// SetOffline turns offline mode on or off: while on, no cover request
+// leaves the machine
+func SetOffline(on bool) {
+	offline.Store(on)
+}
+
+// Offline reports whether offline mode is on
+func Offline() bool {
+	return offline.Load()
+}
+
+// rateLimiter spaces requests interval apart, letting burst of them go at once
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	burst    int
+	next     time.Time // Time of the next request once the burst is used up
+}
+
+var itunesLimiter = &rateLimiter{interval: time.Minute / itunesPerMinute, burst: itunesBurst}
+
+// wait blocks until a request may be made. It returns ErrRateLimited,
+// without taking a turn, if that is more than maxWait away.
+func (l *rateLimiter) wait(maxWait time.Duration) error {
+	l.mu.Lock()
+	now := time.Now()
+	if earliest := now.Add(-time.Duration(l.burst-1) * l.interval); l.next.Before(earliest) {
+		l.next = earliest
+	}
+	at := l.next
+	if at.Sub(now) > maxWait {
+		l.mu.Unlock()
+		return ErrRateLimited
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+
+	time.Sleep(time.Until(at))
+	return nil
+}
+
+// pause holds back all requests until until
+func (l *rateLimiter) pause(until time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.next.Before(until) {
+		l.next = until
+	}
+}
+
+// searchCall is a search in progress or done, shared by identical searches
+type searchCall struct {
+	done       chan struct{}
+	artworkURL string
+	err        error
+	expires    time.Time
+}
+
+var (
+	searchesMu sync.Mutex
+	searches   = map[string]*searchCall{}
+)
+
+// searchArtwork returns the artwork URL of the first iTunes result for
+// term. Concurrent identical searches wait for the first one, and its
+// result is reused for itunesResultTTL unless it failed.
+func searchArtwork(term, entity, country, lang string) (string, error) {
+	key := strings.ToLower(strings.Join([]string{entity, country, lang, term}, "\x00"))
+
+	searchesMu.Lock()
+	if c, ok := searches[key]; ok {
+		select {
+		case <-c.done:
+			if time.Now().Before(c.expires) {
+				searchesMu.Unlock()
+				return c.artworkURL, c.err
+			}
+		default:
+			searchesMu.Unlock()
+			<-c.done
+			return c.artworkURL, c.err
+		}
+	}
+	c := &searchCall{done: make(chan struct{})}
+	searches[key] = c
+	if len(searches) > itunesMaxResults {
+		pruneSearches()
+	}
+	searchesMu.Unlock()
+
+	c.artworkURL, c.err = searchITunes(term, entity, country, lang)
+
+	searchesMu.Lock()
+	c.expires = time.Now().Add(itunesResultTTL)
+	if c.err != nil && !errors.Is(c.err, ErrNoCover) && searches[key] == c {
+		delete(searches, key)
+	}
+	searchesMu.Unlock()
+	close(c.done)
+	return c.artworkURL, c.err
+}
+
+// pruneSearches drops expired search results. searchesMu must be held.
+func pruneSearches() {
+	now := time.Now()
+	for key, c := range searches {
+		select {
+		case <-c.done:
+			if now.After(c.expires) {
+				delete(searches, key)
+			}
+		default:
+		}
+	}
+}
+
+// searchITunes makes the search, retrying when throttled
+func searchITunes(term, entity, country, lang string) (string, error) {
+	apiURL := fmt.Sprintf("%s?term=%s&entity=%s&limit=1&country=%s&lang=%s",
+		itunesSearchURL, url.QueryEscape(term), entity, country, lang)
+
+	for attempt := 0; ; attempt++ {
+		if offline.Load() {
+			return "", ErrOffline
+		}
+		if err := itunesLimiter.wait(itunesMaxWait); err != nil {
+			return "", err
+		}
+
+		req, err := http.NewRequest("GET", apiURL, nil)
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("User-Agent", userAgent)
+
+		resp, err := HTTPClient().Do(req)
+		if err != nil {
+			return "", err
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusForbidden {
+			resp.Body.Close()
+			delay := retryAfter(resp.Header.Get("Retry-After"), itunesRetryDelay<<attempt)
+			itunesLimiter.pause(time.Now().Add(delay))
+			if attempt == itunesRetries {
+				return "", fmt.Errorf("%w (status code %d)", ErrRateLimited, resp.StatusCode)
+			}
+			continue
+		}
+
+		artworkURL, err := readSearchResult(resp)
+		resp.Body.Close()
+		return artworkURL, err
+	}
+}
+
+func readSearchResult(resp *http.Response) (string, error) {
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("iTunes API error: status code %d", resp.StatusCode)
+	}
+
+	var result ItunesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	if result.ResultCount == 0 || len(result.Results) == 0 {
+		return "", ErrNoCover
+	}
+	return result.Results[0].ArtworkUrl100, nil
+}
+
+// retryAfter returns the delay of a Retry-After header, in seconds or an
+// HTTP date, or fallback if there is none
+func retryAfter(header string, fallback time.Duration) time.Duration {
+	if seconds, err := strconv.Atoi(strings.TrimSpace(header)); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(header); err == nil {
+		return max(time.Until(t), 0)
+	}
+	return fallback
+}
@@ -1,11 +1,10 @@
 package metadata
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -13,9 +12,30 @@ import (
 )
 
 type Metadata struct {
-	Title  string `json:"title"`
-	Artist string `json:"artist"`
-	Album  string `json:"album"`
+	Title  string      `json:"title"`
+	Artist string      `json:"artist"`
+	Album  string      `json:"album"`
+	Tracks []TrackInfo `json:"tracks,omitempty"` // Only filled for Guitar Pro files
+	Score  ScoreInfo   `json:"score"`            // Only filled for Guitar Pro files
+	Key    string      `json:"key,omitempty"`    // Only filled for chord sheets and Guitar Pro files
+	Capo   int         `json:"capo,omitempty"`   // Only filled for chord sheets
+	Lyrics string      `json:"-"`                // Only filled for Guitar Pro files, see ExtractContent
+}
+
+// ScoreInfo is the song information written inside a Guitar Pro file, which
+// may differ from what the filename says
+type ScoreInfo struct {
+	Title     string   `json:"title"`
+	Artist    string   `json:"artist"` // The words or music author if the file has no artist
+	Album     string   `json:"album"`
+	Words     string   `json:"words"` // Lyricist; GP3 and GP4 credit words and music together here
+	Music     string   `json:"music"` // Composer
+	Copyright string   `json:"copyright"`
+	TabAuthor string   `json:"tabAuthor"` // Who transcribed the tab
+	Notice    []string `json:"notice"`    // Lines of the notice, empty ones left out
+	Tempo     int      `json:"tempo"`     // Initial tempo in BPM, 0 if unknown
+	Key       string   `json:"key"`       // Initial key, e.g. "G" or "Em"; empty if unknown or C major, the default of GP files
+	Duration  int      `json:"duration"`  // Estimated playing time in seconds, 0 if unknown
 }
 
 type ItunesResponse struct {
@@ -158,14 +178,44 @@ func removeKeyFromTitle(title string) string {
 	return title
 }
 
-// ParseFile extracts metadata from the filename only.
-// Binary parsing has been removed for stability and performance.
+// ParseFile extracts metadata from the filename. Guitar Pro files are also
+// read once for their track list and score information; if that fails the
+// filename metadata is still returned along with the error. Text tabs and
+// ChordPro files take the song information from their header or directives
+// when they have them.
 // The frontend (AlphaTab) handles accurate metadata extraction and writes it back.
 func ParseFile(path string) (Metadata, error) {
-	// Filename-first strategy: Always parse from filename for speed and stability.
-	// Complex binary formats (GP3/4/5/GPX) are prone to crashes and encoding issues.
-	// The frontend will provide accurate metadata via reverse write-back mechanism.
-	return ParseFilename(path), nil
+	// Filename-first strategy: the title always comes from the filename for
+	// stability. Complex binary formats (GP3/4/5/GPX) are prone to encoding
+	// issues, so the score information is only reported in Score.
+	m := ParseFilename(path)
+	if IsTextTabFile(path) {
+		return withTextTabHeader(m, path)
+	}
+	if IsChordProFile(path) {
+		return withChordProDirectives(m, path)
+	}
+	if !IsGuitarProFile(path) {
+		return m, nil
+	}
+
+	gp, err := parseGuitarProSafe(path)
+	if err != nil {
+		return m, err
+	}
+	m.Tracks = gp.Tracks
+	m.Score = gp.Score
+	m.Key = gp.Score.Key
+	return m, nil
+}
+
+// IsGuitarProFile reports whether path has a Guitar Pro extension
+func IsGuitarProFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".gp", ".gp3", ".gp4", ".gp5", ".gpx":
+		return true
+	}
+	return false
 }
 
 // cleanFilename removes common artifacts from filenames
@@ -193,10 +243,18 @@ func cleanFilename(name string) string {
 	return result
 }
 
+// userAgent is sent with cover requests
+const userAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36"
+
+// ErrNoCover is returned by DownloadCover when the search found no cover,
+// as opposed to network errors, after which a download may be retried
+var ErrNoCover = errors.New("no results found")
+
 // DownloadCover searches iTunes and saves the cover to dstPath.
-// Falls back to US/en_us if specific country/lang returns no results.
+// The given country/lang is tried first, then the regions set with
+// SetCoverRegions; regions that keep timing out are skipped for a while.
+// Returns ErrRateLimited while iTunes throttles the searches.
 func DownloadCover(artist, album, title, country, lang, dstPath string) error {
-	// 1. Try with user params
 	if country == "" {
 		country = "US"
 	}
@@ -204,15 +262,17 @@ func DownloadCover(artist, album, title, country, lang, dstPath string) error {
 		lang = "en_us"
 	}
 
-	err := attemptDownload(artist, album, title, country, lang, dstPath)
-	if err == nil {
-		return nil
-	}
-
-	// 2. Fallback to US if different
-	if country != "US" {
-		fmt.Printf("Search failed for %s/%s, falling back to US...\n", country, lang)
-		return attemptDownload(artist, album, title, "US", "en_us", dstPath)
+	err := fmt.Errorf("no cover region available")
+	for i, r := range candidateRegions(CoverRegion{Country: country, Lang: lang}) {
+		if i > 0 {
+			fmt.Printf("Search failed (%v), falling back to %s...\n", err, r.Country)
+		}
+		err = attemptDownload(artist, album, title, r.Country, r.Lang, dstPath)
+		recordRegionResult(r.Country, err)
+		if err == nil || errors.Is(err, ErrRateLimited) || errors.Is(err, ErrOffline) {
+			// Other regions are throttled or offline as well
+			return err
+		}
 	}
 
 	return err
@@ -228,48 +288,25 @@ func attemptDownload(artist, album, title, country, lang, dstPath string) error
 		entity = "song"
 	}
 
-	query := url.QueryEscape(term)
-	apiURL := fmt.Sprintf("https://itunes.apple.com/search?term=%s&entity=%s&limit=1&country=%s&lang=%s", query, entity, country, lang)
-
-	req, err := http.NewRequest("GET", apiURL, nil)
-	if err != nil {
-		return err
-	}
-
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	artworkURL, err := searchArtwork(term, entity, country, lang)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("iTunes API error: status code %d", resp.StatusCode)
-	}
-
-	var result ItunesResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return err
-	}
-
-	if result.ResultCount == 0 || len(result.Results) == 0 {
-		return fmt.Errorf("no results found")
-	}
-
-	artworkURL := result.Results[0].ArtworkUrl100
 	// Try to get higher res
 	artworkURL = strings.Replace(artworkURL, "100x100bb", "600x600bb", 1)
 
 	// Download
+	if offline.Load() {
+		return ErrOffline
+	}
 	imgReq, err := http.NewRequest("GET", artworkURL, nil)
 	if err != nil {
 		return err
 	}
-	imgReq.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36")
+	imgReq.Header.Set("User-Agent", userAgent)
 
-	imgResp, err := client.Do(imgReq)
+	imgResp, err := HTTPClient().Do(imgReq)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,84 @@
+package metadata
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// defaultTimeout bounds cover requests so a dead region fails over quickly
+const defaultTimeout = 15 * time.Second
+
+// NetworkConfig configures the HTTP client of all metadata requests
+type NetworkConfig struct {
+	ProxyURL       string // e.g. "http://proxy:8080" or "socks5://host:1080"; empty for the system proxy
+	TimeoutSeconds int    // Per request, 0 for the default
+	CAFile         string // PEM certificates trusted besides the system ones, e.g. of an intercepting proxy
+	SkipTLSVerify  bool   // Accept any certificate; only for debugging
+}
+
+var httpClient atomic.Pointer[http.Client]
+
+func init() {
+	client, _ := NewHTTPClient(NetworkConfig{})
+	httpClient.Store(client)
+}
+
+// NewHTTPClient returns a client for cfg
+func NewHTTPClient(cfg NetworkConfig) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if cfg.ProxyURL != "" {
+		proxy, err := url.Parse(cfg.ProxyURL)
+		if err != nil || proxy.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL: %s", cfg.ProxyURL)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	if cfg.CAFile != "" || cfg.SkipTLSVerify {
+		tlsConfig := &tls.Config{InsecureSkipVerify: cfg.SkipTLSVerify}
+		if cfg.CAFile != "" {
+			pem, err := os.ReadFile(cfg.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read CA file: %w", err)
+			}
+			pool, err := x509.SystemCertPool()
+			if err != nil {
+				pool = x509.NewCertPool()
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificate found in %s", cfg.CAFile)
+			}
+			tlsConfig.RootCAs = pool
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	timeout := defaultTimeout
+	if cfg.TimeoutSeconds > 0 {
+		timeout = time.Duration(cfg.TimeoutSeconds) * time.Second
+	}
+	return &http.Client{Transport: transport, Timeout: timeout}, nil
+}
+
+// SetNetworkConfig makes all metadata requests use a client for cfg. On
+// error the current client is kept.
+func SetNetworkConfig(cfg NetworkConfig) error {
+	client, err := NewHTTPClient(cfg)
+	if err != nil {
+		return err
+	}
+	httpClient.Store(client)
+	return nil
+}
+
+// HTTPClient returns the client of metadata requests, see SetNetworkConfig
+func HTTPClient() *http.Client {
+	return httpClient.Load()
+}
@@ -2,29 +2,182 @@ package metadata
 
 import (
 	"archive/zip"
+	"bytes"
 	"encoding/xml"
 	"fmt"
 	"io"
+	"os"
 	"strings"
 )
 
 type GpifScore struct {
-	Title  string `xml:"Score>Title"`
-	Artist string `xml:"Score>Artist"`
-	Album  string `xml:"Score>Album"`
+	Title     string `xml:"Title"`
+	Artist    string `xml:"Artist"`
+	Album     string `xml:"Album"`
+	Words     string `xml:"Words"`
+	Music     string `xml:"Music"`
+	Copyright string `xml:"Copyright"`
+	Tabber    string `xml:"Tabber"`
+	Notices   string `xml:"Notices"`
+}
+
+type GpifProperty struct {
+	Name    string `xml:"name,attr"`
+	Pitches string `xml:"Pitches"`
+	Fret    string `xml:"Fret"`
+}
+
+// GpifStaff is a staff of a GP7 track; GP6 tracks have a single one,
+// implicit
+type GpifStaff struct {
+	Properties []GpifProperty `xml:"Properties>Property"`
+}
+
+type GpifTrack struct {
+	Name string `xml:"Name"`
+	// GP6 references a built-in instrument, e.g. "e-gtr6", "drumKit"
+	Instrument struct {
+		Ref string `xml:"ref,attr"`
+	} `xml:"Instrument"`
+	// GP7 describes the instrument inline
+	InstrumentSet struct {
+		Name string `xml:"Name"`
+		Type string `xml:"Type"`
+		// MIDI notes of the sounds of a drum kit, which notes refer to by
+		// index
+		Articulations []int `xml:"Elements>Element>Articulations>Articulation>OutputMidiNumber"`
+	} `xml:"InstrumentSet"`
+	GeneralMidi struct {
+		Table   string `xml:"table,attr"`
+		Program *int   `xml:"Program"`
+	} `xml:"GeneralMidi"`
+	SoundPrograms []int          `xml:"Sounds>Sound>MIDI>Program"`
+	Properties    []GpifProperty `xml:"Properties>Property"`
+	Staves        []GpifStaff    `xml:"Staves>Staff"`
+	Lyrics        []string       `xml:"Lyrics>Line>Text"`
+}
+
+type GpifAutomation struct {
+	Type  string `xml:"Type"`
+	Bar   int    `xml:"Bar"`
+	Value string `xml:"Value"`
+}
+
+type GpifMasterBar struct {
+	Repeat *struct {
+		Start bool `xml:"start,attr"`
+		End   bool `xml:"end,attr"`
+		Count int  `xml:"count,attr"`
+	} `xml:"Repeat"`
+	AlternateEndings string `xml:"AlternateEndings"`
+	Time             string `xml:"Time"` // Time signature, e.g. "3/4"
+	Bars             string `xml:"Bars"` // Bar ids, one per staff of each track
+	Section          *struct {
+		Letter string `xml:"Letter"`
+		Text   string `xml:"Text"`
+	} `xml:"Section"`
+	Key *struct {
+		AccidentalCount int    `xml:"AccidentalCount"`
+		Mode            string `xml:"Mode"` // "Major" or "Minor"
+	} `xml:"Key"`
 }
 
 type GpifRoot struct {
-	Score GpifScore `xml:"Score"`
+	XMLName     xml.Name  `xml:"GPIF"`
+	Score       GpifScore `xml:"Score"`
+	MasterTrack struct {
+		Automations []GpifAutomation `xml:"Automations>Automation"`
+	} `xml:"MasterTrack"`
+	Tracks     []GpifTrack     `xml:"Tracks>Track"`
+	MasterBars []GpifMasterBar `xml:"MasterBars>MasterBar"`
 }
 
-// parseGPX parses .gpx files (zipped XML)
+// parseGPX parses .gpx (GP6) and .gp (GP7) files, both of which wrap a
+// score.gpif XML document
 func parseGPX(path string) (Metadata, error) {
-	r, err := zip.OpenReader(path)
+	root, err := readGPIFRoot(path)
 	if err != nil {
 		return Metadata{}, err
 	}
-	defer r.Close()
+
+	tracks := make([]TrackInfo, 0, len(root.Tracks))
+	var lyrics []string
+	for i, t := range root.Tracks {
+		tracks = append(tracks, gpifTrackInfo(i, t))
+		for _, line := range t.Lyrics {
+			if line = strings.TrimSpace(line); line != "" {
+				lyrics = append(lyrics, line)
+			}
+		}
+	}
+
+	score := root.Score
+	var notice []string
+	for _, line := range strings.Split(score.Notices, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			notice = append(notice, line)
+		}
+	}
+	artist := creditedArtist(score.Artist, score.Music, score.Words)
+	summary := gpifSummary(root)
+	var key string
+	if len(root.MasterBars) > 0 && root.MasterBars[0].Key != nil {
+		k := root.MasterBars[0].Key
+		if key = keySignatureName(k.AccidentalCount, strings.EqualFold(k.Mode, "Minor")); key == "C" {
+			key = ""
+		}
+	}
+	return Metadata{
+		Title:  score.Title,
+		Artist: artist,
+		Album:  score.Album,
+		Tracks: tracks,
+		Score: ScoreInfo{
+			Title:     score.Title,
+			Artist:    artist,
+			Album:     score.Album,
+			Words:     strings.TrimSpace(score.Words),
+			Music:     strings.TrimSpace(score.Music),
+			Copyright: strings.TrimSpace(score.Copyright),
+			TabAuthor: strings.TrimSpace(score.Tabber),
+			Notice:    notice,
+			Tempo:     validTempo(summary.Tempo),
+			Key:       key,
+			Duration:  summary.Duration,
+		},
+		Lyrics: strings.Join(lyrics, "\n"),
+	}, nil
+}
+
+// readGPIFRoot reads and decodes the score.gpif document of a GP6/GP7 file
+func readGPIFRoot(path string) (*GpifRoot, error) {
+	content, err := readGPIF(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var root GpifRoot
+	if err := xml.Unmarshal(content, &root); err != nil {
+		return nil, err
+	}
+	return &root, nil
+}
+
+// readGPIF returns the score.gpif document of a GP6/GP7 file
+func readGPIF(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if bytes.HasPrefix(data, []byte("BCF")) {
+		return readBCFSFile(data, "score.gpif")
+	}
+
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
 
 	var scoreFile *zip.File
 	for _, f := range r.File {
@@ -35,30 +188,70 @@ func parseGPX(path string) (Metadata, error) {
 	}
 
 	if scoreFile == nil {
-		return Metadata{}, fmt.Errorf("score.gpif not found in gpx file")
+		return nil, fmt.Errorf("score.gpif not found in gpx file")
 	}
 
 	rc, err := scoreFile.Open()
 	if err != nil {
-		return Metadata{}, err
+		return nil, err
 	}
 	defer rc.Close()
 
 	// Read content
 	// Limit to reasonable size to prevent bombs
-	content, err := io.ReadAll(io.LimitReader(rc, 10*1024*1024)) // 10MB limit
-	if err != nil {
-		return Metadata{}, err
+	return io.ReadAll(io.LimitReader(rc, 10*1024*1024)) // 10MB limit
+}
+
+// gpifTrackInfo converts a GPIF track into a TrackInfo
+func gpifTrackInfo(index int, t GpifTrack) TrackInfo {
+	info := TrackInfo{
+		Index:   index,
+		Name:    strings.TrimSpace(t.Name),
+		Program: -1,
 	}
 
-	var root GpifRoot
-	if err := xml.Unmarshal(content, &root); err != nil {
-		return Metadata{}, err
+	switch {
+	case t.GeneralMidi.Program != nil:
+		info.Program = *t.GeneralMidi.Program
+	case len(t.SoundPrograms) > 0:
+		info.Program = t.SoundPrograms[0]
 	}
 
-	return Metadata{
-		Title:  root.Score.Title,
-		Artist: root.Score.Artist,
-		Album:  root.Score.Album,
-	}, nil
+	hint := t.InstrumentSet.Type
+	if hint == "" {
+		hint = t.Instrument.Ref
+	}
+	info.Instrument = t.InstrumentSet.Name
+	info.IsPercussion = strings.EqualFold(t.GeneralMidi.Table, "Percussion") ||
+		strings.Contains(strings.ToLower(hint), "drum")
+
+	// GP6 keeps properties on the track, GP7 on each staff
+	props := append([]GpifProperty{}, t.Properties...)
+	for _, staff := range t.Staves {
+		props = append(props, staff.Properties...)
+	}
+	for _, p := range props {
+		switch p.Name {
+		case "Tuning":
+			if info.Tuning != nil {
+				continue
+			}
+			// Pitches are listed lowest string first
+			fields := strings.Fields(p.Pitches)
+			for i := len(fields) - 1; i >= 0; i-- {
+				var note int
+				if _, err := fmt.Sscanf(fields[i], "%d", &note); err == nil {
+					info.Tuning = append(info.Tuning, note)
+				}
+			}
+		case "CapoFret":
+			fmt.Sscanf(strings.TrimSpace(p.Fret), "%d", &info.Capo)
+		}
+	}
+
+	if info.IsPercussion {
+		info.Tuning = nil
+	}
+	finishTrackInfo(&info, hint)
+	return info
 }
@@ -0,0 +1,331 @@
+package metadata
+
+import (
+	"encoding/xml"
+	"fmt"
+	"haya-tab/pkg/score"
+	"os"
+	"strings"
+)
+
+// ParseScore reads the music of a Guitar Pro file (GP3 to GP7) into a
+// score.Score, e.g. for a MIDI export
+func ParseScore(path string) (s *score.Score, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			s = nil
+			err = fmt.Errorf("failed to parse %s: %v", path, r)
+		}
+	}()
+
+	isGPIF, err := isGPIFContainer(path)
+	if err != nil {
+		return nil, err
+	}
+	if isGPIF {
+		content, err := readGPIF(path)
+		if err != nil {
+			return nil, err
+		}
+		var root GpifRoot
+		if err := xml.Unmarshal(content, &root); err != nil {
+			return nil, err
+		}
+		// The music is only decoded here, the other parsers have no use
+		// for it
+		var music gpifMusic
+		if err := xml.Unmarshal(content, &music); err != nil {
+			return nil, err
+		}
+		return gpifScore(&root, &music), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	song, err := readGPSong(data)
+	if err != nil {
+		return nil, err
+	}
+	return gpSongScore(data, song)
+}
+
+// gpSongScore builds the score of a GP3/4/5 song. Unlike the summary, it
+// fails on a construct of the measure data it cannot read: the music
+// after it would be missing.
+func gpSongScore(data []byte, song *gpSong) (*score.Score, error) {
+	body, err := readGPBody(data, song)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &score.Score{
+		Title:      song.Title,
+		Artist:     creditedArtist(song.Artist, song.Music, song.Words),
+		Tempo:      validTempo(song.Tempo),
+		MasterBars: gpSongMasterBars(song, body.TempoChanges),
+	}
+
+	for t, info := range song.Tracks {
+		track := newScoreTrack(info)
+		track.Measures = make([]score.Measure, len(song.Measures))
+		for m, measures := range body.Measures {
+			track.Measures[m] = measures[t]
+		}
+		s.Tracks = append(s.Tracks, track)
+	}
+	return s, nil
+}
+
+// gpSongMasterBars returns the master bars of a GP3/4/5 song, with the
+// tempo changes found in its measure data
+func gpSongMasterBars(song *gpSong, changes []TempoChange) []score.MasterBar {
+	bars := make([]score.MasterBar, len(song.Measures))
+	for i, h := range song.Measures {
+		bars[i] = score.MasterBar{
+			Numerator:   h.Numerator,
+			Denominator: h.Denominator,
+			RepeatOpen:  h.RepeatOpen,
+			RepeatClose: h.RepeatClose,
+			Endings:     h.Endings,
+			Marker:      h.Marker,
+		}
+	}
+	for _, c := range changes {
+		if c.Measure >= 1 && c.Measure <= len(bars) {
+			bars[c.Measure-1].Tempo = c.Tempo
+		}
+	}
+	return bars
+}
+
+// newScoreTrack returns the track of a score described by info, without
+// its measures
+func newScoreTrack(info TrackInfo) score.Track {
+	return score.Track{
+		Name:       info.Name,
+		Program:    info.Program,
+		Percussion: info.IsPercussion,
+		Tuning:     info.Tuning,
+		Capo:       info.Capo,
+	}
+}
+
+// gpifMusic is the music of a GPIF document. Bars, voices, beats, notes
+// and rhythms are each listed once and referenced by id: a master bar
+// lists a bar per staff, a bar its voices, a voice its beats and so on.
+type gpifMusic struct {
+	Bars []struct {
+		ID     int    `xml:"id,attr"`
+		Voices string `xml:"Voices"` // Voice ids, -1 for unused voices
+	} `xml:"Bars>Bar"`
+	Voices []struct {
+		ID    int    `xml:"id,attr"`
+		Beats string `xml:"Beats"`
+	} `xml:"Voices>Voice"`
+	Beats   []gpifBeat `xml:"Beats>Beat"`
+	Notes   []gpifNote `xml:"Notes>Note"`
+	Rhythms []struct {
+		ID        int    `xml:"id,attr"`
+		NoteValue string `xml:"NoteValue"` // e.g. "Quarter", "16th"
+		Dot       *struct {
+			Count int `xml:"count,attr"`
+		} `xml:"AugmentationDot"`
+		Tuplet *struct {
+			Num int `xml:"num,attr"`
+			Den int `xml:"den,attr"`
+		} `xml:"PrimaryTuplet"`
+	} `xml:"Rhythms>Rhythm"`
+}
+
+type gpifBeat struct {
+	ID     int `xml:"id,attr"`
+	Rhythm struct {
+		Ref int `xml:"ref,attr"`
+	} `xml:"Rhythm"`
+	Notes   string `xml:"Notes"`
+	Dynamic string `xml:"Dynamic"` // "PPP" to "FFF"
+}
+
+type gpifNote struct {
+	ID  int `xml:"id,attr"`
+	Tie *struct {
+		Destination bool `xml:"destination,attr"`
+	} `xml:"Tie"`
+	Properties []struct {
+		Name   string    `xml:"name,attr"`
+		String *int      `xml:"String"`
+		Fret   *int      `xml:"Fret"`
+		Number *int      `xml:"Number"` // MIDI note, of the "Midi" property
+		Enable *struct{} `xml:"Enable"`
+	} `xml:"Properties>Property"`
+	Articulation *int `xml:"InstrumentArticulation"` // Drum sound of a GP7 drum kit
+}
+
+// gpifNoteValues are the note values of GPIF rhythms, as 1/n of a whole
+var gpifNoteValues = map[string]int{
+	"Whole": 1, "Half": 2, "Quarter": 4, "Eighth": 8, "16th": 16,
+	"32nd": 32, "64th": 64, "128th": 128, "256th": 256,
+}
+
+// gpifDynamics are the dynamics of GPIF beats, from ppp
+var gpifDynamics = []string{"PPP", "PP", "P", "MP", "MF", "F", "FF", "FFF"}
+
+// gpifScore builds the score of a GP6/GP7 document
+func gpifScore(root *GpifRoot, music *gpifMusic) *score.Score {
+	tempo, masterBars := gpifMasterBars(root)
+	s := &score.Score{
+		Title:      root.Score.Title,
+		Artist:     creditedArtist(root.Score.Artist, root.Score.Music, root.Score.Words),
+		Tempo:      tempo,
+		MasterBars: masterBars,
+	}
+
+	bars := map[int]string{}
+	for _, b := range music.Bars {
+		bars[b.ID] = b.Voices
+	}
+	voices := map[int]string{}
+	for _, v := range music.Voices {
+		voices[v.ID] = v.Beats
+	}
+	beats := map[int]*gpifBeat{}
+	for i := range music.Beats {
+		beats[music.Beats[i].ID] = &music.Beats[i]
+	}
+	notes := map[int]*gpifNote{}
+	for i := range music.Notes {
+		notes[music.Notes[i].ID] = &music.Notes[i]
+	}
+	rhythms := map[int]int{} // Ticks
+	for _, r := range music.Rhythms {
+		dots, num, den := 0, 0, 0
+		if r.Dot != nil {
+			dots = r.Dot.Count
+		}
+		if r.Tuplet != nil {
+			num, den = r.Tuplet.Num, r.Tuplet.Den
+		}
+		rhythms[r.ID] = score.NoteTicks(gpifNoteValues[r.NoteValue], dots, num, den)
+	}
+
+	// A master bar lists the bars of every staff of every track in order
+	first := 0
+	for t, gt := range root.Tracks {
+		track := newScoreTrack(gpifTrackInfo(t, gt))
+		track.Measures = make([]score.Measure, len(root.MasterBars))
+		staves := max(len(gt.Staves), 1)
+		for m, mb := range root.MasterBars {
+			ids := parseInts(mb.Bars)
+			for _, barID := range ids[min(first, len(ids)):min(first+staves, len(ids))] {
+				for _, voiceID := range parseInts(bars[barID]) {
+					if voiceID < 0 {
+						continue
+					}
+					var voice score.Voice
+					for _, beatID := range parseInts(voices[voiceID]) {
+						if b, ok := beats[beatID]; ok {
+							voice.Beats = append(voice.Beats, gpifScoreBeat(b, rhythms, notes, &track, gt.InstrumentSet.Articulations))
+						}
+					}
+					track.Measures[m].Voices = append(track.Measures[m].Voices, voice)
+				}
+			}
+		}
+		first += staves
+		s.Tracks = append(s.Tracks, track)
+	}
+	return s
+}
+
+// gpifMasterBars returns the initial tempo of a GP6/GP7 score, 0 if it
+// has none or an implausible one, and its master bars
+func gpifMasterBars(root *GpifRoot) (int, []score.MasterBar) {
+	tempo, changes := gpifTempos(root)
+	bars := make([]score.MasterBar, len(root.MasterBars))
+	numerator, denominator := 4, 4
+	for i, bar := range root.MasterBars {
+		if n, d, ok := parseTimeSignature(bar.Time); ok {
+			numerator, denominator = n, d
+		}
+		mb := score.MasterBar{
+			Numerator:   numerator,
+			Denominator: denominator,
+			Endings:     parseInts(bar.AlternateEndings),
+		}
+		if bar.Repeat != nil {
+			mb.RepeatOpen = bar.Repeat.Start
+			if bar.Repeat.End {
+				mb.RepeatClose = bar.Repeat.Count
+			}
+		}
+		if bar.Section != nil {
+			if mb.Marker = strings.TrimSpace(bar.Section.Text); mb.Marker == "" {
+				mb.Marker = strings.TrimSpace(bar.Section.Letter)
+			}
+		}
+		bars[i] = mb
+	}
+	for _, c := range changes {
+		if c.Measure >= 1 && c.Measure <= len(bars) {
+			bars[c.Measure-1].Tempo = c.Tempo
+		}
+	}
+	return validTempo(tempo), bars
+}
+
+// gpifScoreBeat converts a GPIF beat of track. articulations are the MIDI
+// notes of the drum sounds of a GP7 drum kit.
+func gpifScoreBeat(b *gpifBeat, rhythms map[int]int, notes map[int]*gpifNote, track *score.Track, articulations []int) score.Beat {
+	ticks, ok := rhythms[b.Rhythm.Ref]
+	if !ok {
+		ticks = score.TicksPerQuarter
+	}
+	beat := score.Beat{Ticks: ticks}
+	velocity := score.DefaultVelocity
+	for i, d := range gpifDynamics {
+		if strings.EqualFold(strings.TrimSpace(b.Dynamic), d) {
+			velocity = score.DynamicVelocity(i + 1)
+		}
+	}
+
+	for _, id := range parseInts(b.Notes) {
+		n, ok := notes[id]
+		if !ok {
+			continue
+		}
+		note := score.Note{Velocity: velocity, Tied: n.Tie != nil && n.Tie.Destination}
+		str, fret, midi := -1, 0, -1
+		for _, p := range n.Properties {
+			switch {
+			case p.Name == "String" && p.String != nil:
+				str = *p.String
+			case p.Name == "Fret" && p.Fret != nil:
+				fret = *p.Fret
+			case p.Name == "Midi" && p.Number != nil:
+				midi = *p.Number
+			case p.Name == "Muted" && p.Enable != nil:
+				note.Dead = true
+			}
+		}
+
+		switch {
+		case len(track.Tuning) > 0 && !track.Percussion:
+			// Strings are numbered from the lowest, from 0
+			if str < 0 || str >= len(track.Tuning) {
+				continue
+			}
+			note.String, note.Fret = len(track.Tuning)-str, fret
+		case midi >= 0:
+			note.Fret = midi
+		case n.Articulation != nil && *n.Articulation >= 0 && *n.Articulation < len(articulations):
+			note.Fret = articulations[*n.Articulation]
+		default:
+			continue
+		}
+		beat.Notes = append(beat.Notes, note)
+	}
+	beat.Rest = len(beat.Notes) == 0
+	return beat
+}
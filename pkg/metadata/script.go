@@ -0,0 +1,74 @@
+package metadata
+
+import "unicode"
+
+// Writing systems of tab metadata, see DetectScript
+const (
+	ScriptLatin    = "latin"
+	ScriptCyrillic = "cyrillic"
+	ScriptCJK      = "cjk"
+)
+
+// scriptCounts counts the letters of each writing system in a text
+type scriptCounts struct {
+	latin, cyrillic, han, kana, hangul int
+}
+
+func countScripts(texts []string) scriptCounts {
+	var c scriptCounts
+	for _, text := range texts {
+		for _, r := range text {
+			switch {
+			case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+				c.kana++
+			case unicode.Is(unicode.Hangul, r):
+				c.hangul++
+			case unicode.Is(unicode.Han, r):
+				c.han++
+			case unicode.Is(unicode.Cyrillic, r):
+				c.cyrillic++
+			case unicode.Is(unicode.Latin, r):
+				c.latin++
+			}
+		}
+	}
+	return c
+}
+
+// DetectScript returns the writing system of texts such as the title and
+// artist of a tab: ScriptCJK, ScriptCyrillic or ScriptLatin, or "" if they
+// have no letters of those. Titles often mix in Latin words ("feat.",
+// romanized artist names), so any CJK letter makes the text CJK, and any
+// Cyrillic letter makes the rest Cyrillic.
+func DetectScript(texts ...string) string {
+	c := countScripts(texts)
+	switch {
+	case c.kana+c.hangul+c.han > 0:
+		return ScriptCJK
+	case c.cyrillic > 0:
+		return ScriptCyrillic
+	case c.latin > 0:
+		return ScriptLatin
+	}
+	return ""
+}
+
+// ScriptCoverRegion returns the store to search the cover of a tab in,
+// guessed from the script of texts such as its title and artist, and false
+// for Latin text or no letters, which keep the configured regions. Kana is
+// read as Japanese and Hangul as Korean; Han characters alone could be
+// Chinese or Japanese and are searched in the Japanese store.
+func ScriptCoverRegion(texts ...string) (CoverRegion, bool) {
+	c := countScripts(texts)
+	switch {
+	case c.kana > 0:
+		return CoverRegion{Country: "JP", Lang: "ja_jp"}, true
+	case c.hangul > 0:
+		return CoverRegion{Country: "KR", Lang: "ko_kr"}, true
+	case c.han > 0:
+		return CoverRegion{Country: "JP", Lang: "ja_jp"}, true
+	case c.cyrillic > 0:
+		return CoverRegion{Country: "RU", Lang: "ru_ru"}, true
+	}
+	return CoverRegion{}, false
+}
@@ -0,0 +1,188 @@
+package metadata
+
+import (
+	"fmt"
+	"haya-tab/pkg/score"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Summary is the structure of a song, used to draw a song map/timeline.
+// Measure numbers are 1-based.
+type Summary struct {
+	MeasureCount int               `json:"measureCount"`
+	Tempo        int               `json:"tempo"`    // Initial tempo (BPM)
+	Duration     int               `json:"duration"` // Estimated playing time in seconds, with repeats; 0 if the tempo is unknown
+	Sections     []Section         `json:"sections"`
+	TempoChanges []TempoChange     `json:"tempoChanges"`
+	Repeats      []Repeat          `json:"repeats"`
+	Endings      []AlternateEnding `json:"alternateEndings"`
+}
+
+// Section is a marker/rehearsal mark, e.g. "Intro", "Chorus"
+type Section struct {
+	Measure int    `json:"measure"`
+	Name    string `json:"name"`
+}
+
+// TempoChange is a tempo set at a measure after the initial tempo
+type TempoChange struct {
+	Measure int `json:"measure"`
+	Tempo   int `json:"tempo"`
+}
+
+// Repeat is a repeated block of measures
+type Repeat struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+	Plays int `json:"plays"` // Total number of times the block is played
+}
+
+// AlternateEnding marks a measure played only on the given passes
+type AlternateEnding struct {
+	Measure int   `json:"measure"`
+	Numbers []int `json:"numbers"`
+}
+
+// ParseSummary reads the song structure of a Guitar Pro file (GP3 to GP7)
+func ParseSummary(path string) (summary *Summary, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			summary = nil
+			err = fmt.Errorf("failed to parse %s: %v", path, r)
+		}
+	}()
+
+	isGPIF, err := isGPIFContainer(path)
+	if err != nil {
+		return nil, err
+	}
+	if isGPIF {
+		root, err := readGPIFRoot(path)
+		if err != nil {
+			return nil, err
+		}
+		return gpifSummary(root), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	song, err := readGPSong(data)
+	if err != nil {
+		return nil, err
+	}
+	return gpSongSummary(data, song), nil
+}
+
+// newSummary returns a Summary with empty (not null) lists
+func newSummary(measureCount, tempo int) *Summary {
+	return &Summary{
+		MeasureCount: measureCount,
+		Tempo:        tempo,
+		Sections:     []Section{},
+		TempoChanges: []TempoChange{},
+		Repeats:      []Repeat{},
+		Endings:      []AlternateEnding{},
+	}
+}
+
+// gpSongSummary builds the summary of a GP3/4/5 song
+func gpSongSummary(data []byte, song *gpSong) *Summary {
+	// Tempo changes need the whole measure data; keep whatever was read
+	// before an unsupported construct rather than failing the summary
+	body, _ := readGPBody(data, song)
+	return scoreSummary(&score.Score{
+		Tempo:      validTempo(song.Tempo),
+		MasterBars: gpSongMasterBars(song, body.TempoChanges),
+	})
+}
+
+// gpifSummary builds the summary of a GP6/GP7 score
+func gpifSummary(root *GpifRoot) *Summary {
+	tempo, bars := gpifMasterBars(root)
+	return scoreSummary(&score.Score{Tempo: tempo, MasterBars: bars})
+}
+
+// scoreSummary builds the summary of the master bars of s
+func scoreSummary(s *score.Score) *Summary {
+	summary := newSummary(len(s.MasterBars), s.Tempo)
+	repeatStart := 1
+	for i, bar := range s.MasterBars {
+		measure := i + 1
+		if bar.Marker != "" {
+			summary.Sections = append(summary.Sections, Section{Measure: measure, Name: bar.Marker})
+		}
+		if bar.Tempo > 0 {
+			summary.TempoChanges = append(summary.TempoChanges, TempoChange{Measure: measure, Tempo: bar.Tempo})
+		}
+		if bar.RepeatOpen {
+			repeatStart = measure
+		}
+		if len(bar.Endings) > 0 {
+			summary.Endings = append(summary.Endings, AlternateEnding{Measure: measure, Numbers: bar.Endings})
+		}
+		if bar.RepeatClose > 0 {
+			summary.Repeats = append(summary.Repeats, Repeat{Start: repeatStart, End: measure, Plays: bar.RepeatClose})
+			// A close without an open repeats from the end of the previous block
+			repeatStart = measure + 1
+		}
+	}
+	summary.Duration = s.Duration()
+	return summary
+}
+
+// gpifTempos returns the initial tempo of a GP6/GP7 score, 0 if it has
+// none, and its later tempo changes
+func gpifTempos(root *GpifRoot) (int, []TempoChange) {
+	initial := 0
+	changes := []TempoChange{}
+	for _, a := range root.MasterTrack.Automations {
+		if !strings.EqualFold(a.Type, "Tempo") {
+			continue
+		}
+		// Value is "<bpm> <reference unit>", e.g. "120 2"
+		fields := strings.Fields(a.Value)
+		if len(fields) == 0 {
+			continue
+		}
+		bpm, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			continue
+		}
+		tempo := int(bpm + 0.5)
+		if a.Bar == 0 && initial == 0 {
+			initial = tempo
+			continue
+		}
+		changes = append(changes, TempoChange{Measure: a.Bar + 1, Tempo: tempo})
+	}
+	return initial, changes
+}
+
+// parseInts parses a space separated list of integers, skipping bad fields
+func parseInts(s string) []int {
+	var values []int
+	for _, field := range strings.Fields(s) {
+		if v, err := strconv.Atoi(field); err == nil {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// parseTimeSignature parses a time signature such as "6/8"
+func parseTimeSignature(s string) (int, int, bool) {
+	num, den, ok := strings.Cut(strings.TrimSpace(s), "/")
+	if !ok {
+		return 0, 0, false
+	}
+	n, err1 := strconv.Atoi(strings.TrimSpace(num))
+	d, err2 := strconv.Atoi(strings.TrimSpace(den))
+	if err1 != nil || err2 != nil || n <= 0 || d <= 0 {
+		return 0, 0, false
+	}
+	return n, d, true
+}
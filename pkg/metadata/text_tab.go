@@ -0,0 +1,114 @@
+package metadata
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// textTabHeaderLines is how many non-empty lines at the top of a text tab
+// are searched for the song information
+const textTabHeaderLines = 15
+
+var (
+	// "Wonderwall Chords by Oasis", "Hysteria Bass Tab by Muse", as on the
+	// Ultimate Guitar page and at the top of its downloads
+	ugByPattern = regexp.MustCompile(`(?i)^(.+?)\s+(?:(?:bass|ukulele|guitar|drum)\s+)?(?:chords|tabs?)\s+by\s+(.+)$`)
+
+	// " (ver 2)" after the title of alternative versions
+	versionPattern = regexp.MustCompile(`(?i)\s*\(ver\.?\s*\d+\)$`)
+
+	// "Oasis - Wonderwall (Chords)" or "Oasis - Wonderwall Tab"
+	dashTypePattern = regexp.MustCompile(`(?i)^(.+?)\s+-\s+(.+?)\s*[\(\[]?(?:chords|tabs?)[\)\]]?$`)
+
+	// "Artist: Oasis", "Song: Wonderwall", "Capo: 2nd fret"
+	headerFieldPattern = regexp.MustCompile(`^(?i)(title|song|song title|artist|band|album|key|capo)\s*:\s*(.+)$`)
+
+	// The fret of a "Capo:" header, e.g. "2nd fret" or "2"
+	capoFretPattern = regexp.MustCompile(`^(\d+)`)
+)
+
+// IsTextTabFile reports whether path has a plain text tab extension
+// (Ultimate Guitar downloads, ChordPro-like chord sheets)
+func IsTextTabFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".txt", ".tab", ".crd":
+		return true
+	}
+	return false
+}
+
+// parseTextTab reads the song information, key and capo from the header of
+// a text tab.
+// Fields not found in the header are left empty.
+func parseTextTab(path string) (Metadata, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Metadata{}, err
+	}
+	defer f.Close()
+
+	var m Metadata
+	scanner := bufio.NewScanner(f)
+	for lines := 0; lines < textTabHeaderLines && scanner.Scan(); {
+		line := strings.TrimSpace(decodeGPString(bytes.TrimPrefix(scanner.Bytes(), []byte("\xef\xbb\xbf"))))
+		if line == "" {
+			continue
+		}
+		lines++
+
+		if match := headerFieldPattern.FindStringSubmatch(line); match != nil {
+			value := strings.TrimSpace(match[2])
+			switch strings.ToLower(match[1]) {
+			case "title", "song", "song title":
+				m.Title = value
+			case "artist", "band":
+				m.Artist = value
+			case "album":
+				m.Album = value
+			case "key":
+				m.Key = value
+			case "capo":
+				if fret := capoFretPattern.FindString(value); fret != "" {
+					m.Capo, _ = strconv.Atoi(fret)
+				}
+			}
+			continue
+		}
+		if m.Title != "" {
+			continue
+		}
+		if match := ugByPattern.FindStringSubmatch(line); match != nil {
+			m.Title, m.Artist = versionPattern.ReplaceAllString(match[1], ""), strings.TrimSpace(match[2])
+		} else if match := dashTypePattern.FindStringSubmatch(line); match != nil {
+			m.Artist, m.Title = strings.TrimSpace(match[1]), strings.TrimSpace(match[2])
+		}
+	}
+	return m, scanner.Err()
+}
+
+// withTextTabHeader overrides the filename metadata m with the song
+// information from the header of the text tab at path. Downloads are named
+// after the site's URL slug, so the header is the better source.
+func withTextTabHeader(m Metadata, path string) (Metadata, error) {
+	header, err := parseTextTab(path)
+	if err != nil {
+		return m, err
+	}
+	if header.Title != "" {
+		m.Title = header.Title
+	}
+	if header.Artist != "" {
+		m.Artist = header.Artist
+	}
+	if header.Album != "" {
+		m.Album = header.Album
+	}
+	m.Key = header.Key
+	m.Capo = header.Capo
+	return m, nil
+}
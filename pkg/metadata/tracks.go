@@ -0,0 +1,205 @@
+package metadata
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// TrackInfo describes one track (part) of a Guitar Pro file
+type TrackInfo struct {
+	Index        int    `json:"index"`
+	Name         string `json:"name"`
+	Instrument   string `json:"instrument"`   // e.g. "Electric Guitar", "Bass"
+	Program      int    `json:"program"`      // General MIDI program, -1 if unknown
+	Kind         string `json:"kind"`         // "guitar", "bass", "drums", "keys", "vocals", "other"
+	StringCount  int    `json:"stringCount"`  // 0 for non-stringed/percussion tracks
+	Tuning       []int  `json:"tuning"`       // MIDI note per string, highest string first
+	TuningName   string `json:"tuningName"`   // e.g. "E Standard", "Drop D"
+	Capo         int    `json:"capo"`         // Capo fret, 0 if none
+	IsPercussion bool   `json:"isPercussion"` // Drum/percussion track
+}
+
+// Track kinds used for filtering
+const (
+	TrackKindGuitar = "guitar"
+	TrackKindBass   = "bass"
+	TrackKindDrums  = "drums"
+	TrackKindKeys   = "keys"
+	TrackKindVocals = "vocals"
+	TrackKindOther  = "other"
+)
+
+// gmFamilies are the General MIDI instrument families (8 programs each)
+var gmFamilies = []string{
+	"Piano", "Chromatic Percussion", "Organ", "Guitar",
+	"Bass", "Strings", "Ensemble", "Brass",
+	"Reed", "Pipe", "Synth Lead", "Synth Pad",
+	"Synth Effects", "Ethnic", "Percussive", "Sound Effects",
+}
+
+// noteNames uses the spellings guitarists use for tunings ("Eb Standard")
+var noteNames = []string{"C", "C#", "D", "Eb", "E", "F", "F#", "G", "Ab", "A", "Bb", "B"}
+
+// ParseTracks reads the track list from a Guitar Pro file (GP3 to GP7).
+// The format is detected from the file content rather than the extension.
+func ParseTracks(path string) ([]TrackInfo, error) {
+	m, err := parseGuitarProSafe(path)
+	if err != nil {
+		return nil, err
+	}
+	return m.Tracks, nil
+}
+
+// parseGuitarProSafe is parseGuitarPro turning parser panics into errors.
+// Binary GP formats vary a lot in the wild; never let a malformed file take
+// down a sync.
+func parseGuitarProSafe(path string) (m Metadata, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			m = Metadata{}
+			err = fmt.Errorf("failed to parse %s: %v", path, r)
+		}
+	}()
+	return parseGuitarPro(path)
+}
+
+// parseGuitarPro dispatches to the right parser based on the file signature
+func parseGuitarPro(path string) (Metadata, error) {
+	isGPIF, err := isGPIFContainer(path)
+	if err != nil {
+		return Metadata{}, err
+	}
+	if isGPIF {
+		return parseGPX(path)
+	}
+	return parseGPBinary(path)
+}
+
+// isGPIFContainer reports whether a file is a GP7 (.gp, zip) or GP6
+// (.gpx, BCFZ/BCFS container) file rather than a GP3/4/5 binary file
+func isGPIFContainer(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	magic := make([]byte, 4)
+	n, _ := f.Read(magic)
+	f.Close()
+	magic = magic[:n]
+
+	return bytes.HasPrefix(magic, []byte("PK")) || bytes.HasPrefix(magic, []byte("BCF")), nil
+}
+
+// finishTrackInfo fills the derived fields (instrument name, kind, tuning name).
+// hint is an instrument name/type from the file, if it has one.
+func finishTrackInfo(t *TrackInfo, hint string) {
+	if t.Instrument == "" {
+		t.Instrument = hint
+	}
+	if t.Instrument == "" && t.Program >= 0 && t.Program < 128 {
+		t.Instrument = gmFamilies[t.Program/8]
+	}
+	if t.IsPercussion && t.Instrument == "" {
+		t.Instrument = "Drums"
+	}
+	t.Kind = classifyTrack(t, hint)
+	t.StringCount = len(t.Tuning)
+	t.TuningName = describeTuning(t.Tuning, t.Kind)
+}
+
+// classifyTrack guesses the kind of part a track is
+func classifyTrack(t *TrackInfo, hint string) string {
+	if t.IsPercussion {
+		return TrackKindDrums
+	}
+
+	// Names and instrument hints are more specific than the MIDI program,
+	// e.g. a bass part played with a synth patch.
+	for _, s := range []string{hint, t.Name} {
+		lower := strings.ToLower(s)
+		switch {
+		case lower == "":
+			continue
+		case strings.Contains(lower, "bass"):
+			return TrackKindBass
+		case strings.Contains(lower, "drum"), strings.Contains(lower, "kit"), strings.Contains(lower, "perc"):
+			return TrackKindDrums
+		case strings.Contains(lower, "guitar"), strings.Contains(lower, "gtr"):
+			return TrackKindGuitar
+		case strings.Contains(lower, "voc"), strings.Contains(lower, "voice"):
+			return TrackKindVocals
+		case strings.Contains(lower, "piano"), strings.Contains(lower, "key"), strings.Contains(lower, "organ"):
+			return TrackKindKeys
+		}
+	}
+
+	switch {
+	case t.Program >= 24 && t.Program <= 31:
+		return TrackKindGuitar
+	case t.Program >= 32 && t.Program <= 39:
+		return TrackKindBass
+	case t.Program >= 0 && t.Program <= 23:
+		return TrackKindKeys
+	case t.Program >= 52 && t.Program <= 54:
+		return TrackKindVocals
+	case t.Program == 105 || t.Program == 106 || t.Program == 107:
+		// Banjo, shamisen, koto
+		return TrackKindGuitar
+	}
+	return TrackKindOther
+}
+
+// describeTuning names common tunings, e.g. "E Standard", "Drop D", "B Standard".
+// tuning is ordered from the highest string to the lowest.
+func describeTuning(tuning []int, kind string) string {
+	n := len(tuning)
+	if n == 0 {
+		return ""
+	}
+
+	// Intervals (in semitones) between adjacent strings, lowest string first,
+	// for standard tuning
+	var standard []int
+	switch {
+	case kind == TrackKindBass && n >= 4 && n <= 6:
+		standard = []int{5, 5, 5, 5, 5}[:n-1]
+	case kind != TrackKindBass && n >= 6 && n <= 8:
+		standard = []int{5, 5, 5, 5, 5, 4, 5}[8-n:]
+	default:
+		return ""
+	}
+
+	// Work lowest string first
+	low := make([]int, n)
+	for i, note := range tuning {
+		low[n-1-i] = note
+	}
+
+	matches := func(from int) bool {
+		for i := from; i < n-1; i++ {
+			if low[i+1]-low[i] != standard[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	if matches(0) {
+		return noteName(low[0]) + " Standard"
+	}
+	// Drop tunings: lowest string a whole step below standard
+	if low[1]-low[0] == standard[0]+2 && matches(1) {
+		return "Drop " + noteName(low[0])
+	}
+	return ""
+}
+
+// noteName returns the pitch class name of a MIDI note
+func noteName(note int) string {
+	if note < 0 {
+		return ""
+	}
+	return noteNames[note%12]
+}
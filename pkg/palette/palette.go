@@ -0,0 +1,105 @@
+// Package palette finds the dominant color of a cover, which the interface
+// tints the views of a tab with, like the now playing screen of music apps
+package palette
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+)
+
+// samples is about the number of pixels read along each side of an image;
+// a cover does not need more to find its main color
+const samples = 64
+
+// bucketBits is the precision per channel of the colors counted together
+const bucketBits = 4
+
+// DominantFile returns the dominant color of the JPEG, PNG or GIF image at
+// path as "#rrggbb"
+func DominantFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return "", err
+	}
+	return Hex(Dominant(img)), nil
+}
+
+// Dominant returns the most frequent color of img, favoring colorful pixels
+// over the white, black and grey of borders and text. It is the average of
+// the pixels close to that color, so it exists in the image. An image with
+// no pixels gives black.
+func Dominant(img image.Image) color.RGBA {
+	b := img.Bounds()
+	stepX := max(1, b.Dx()/samples)
+	stepY := max(1, b.Dy()/samples)
+
+	type bucket struct {
+		weight  float64
+		r, g, b float64 // Weighted sums
+	}
+	buckets := map[uint16]*bucket{}
+	var best *bucket
+	for y := b.Min.Y; y < b.Max.Y; y += stepY {
+		for x := b.Min.X; x < b.Max.X; x += stepX {
+			c := color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)
+			if c.A < 128 {
+				continue
+			}
+			const shift = 8 - bucketBits
+			key := uint16(c.R>>shift)<<(2*bucketBits) | uint16(c.G>>shift)<<bucketBits | uint16(c.B>>shift)
+			bk := buckets[key]
+			if bk == nil {
+				bk = &bucket{}
+				buckets[key] = bk
+			}
+			w := weight(c)
+			bk.weight += w
+			bk.r += w * float64(c.R)
+			bk.g += w * float64(c.G)
+			bk.b += w * float64(c.B)
+			if best == nil || bk.weight > best.weight {
+				best = bk
+			}
+		}
+	}
+	if best == nil {
+		return color.RGBA{A: 255}
+	}
+	return color.RGBA{
+		R: uint8(best.r/best.weight + 0.5),
+		G: uint8(best.g/best.weight + 0.5),
+		B: uint8(best.b/best.weight + 0.5),
+		A: 255,
+	}
+}
+
+// weight is how much a pixel counts: saturated colors count up to four
+// times as much as greys, and near white or black pixels little
+func weight(c color.RGBA) float64 {
+	hi := max(c.R, c.G, c.B)
+	lo := min(c.R, c.G, c.B)
+	w := 1.0
+	if hi > 0 {
+		w += 3 * float64(hi-lo) / float64(hi)
+	}
+	if hi < 24 || lo > 232 {
+		w *= 0.1
+	}
+	return w
+}
+
+// Hex formats c as "#rrggbb"
+func Hex(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
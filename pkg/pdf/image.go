@@ -0,0 +1,133 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// Rect is a rectangle of a page in points, from the bottom left corner
+type Rect struct {
+	X, Y, W, H float64
+}
+
+// PagePlacement is an image drawn on a page by AddImagePage
+type PagePlacement struct {
+	Image image.Image
+	Box   Rect // Where the image is drawn, stretched to the box
+}
+
+// AddImagePage appends a page of width by height points showing the images
+// of placements, e.g. rendered pages of a score. Images are stored
+// losslessly, in gray when they have no color.
+func (w *Writer) AddImagePage(width, height float64, placements []PagePlacement) error {
+	if width <= 0 || height <= 0 {
+		return fmt.Errorf("pdf: invalid page size %gx%g", width, height)
+	}
+
+	resources := Dict{}
+	var content bytes.Buffer
+	for i, p := range placements {
+		stream, err := imageStream(p.Image)
+		if err != nil {
+			return err
+		}
+		name := Name(fmt.Sprintf("Im%d", i+1))
+		resources[name] = w.add(stream)
+		fmt.Fprintf(&content, "q %s 0 0 %s %s %s cm /%s Do Q\n",
+			formatNumber(p.Box.W), formatNumber(p.Box.H), formatNumber(p.Box.X), formatNumber(p.Box.Y), name)
+	}
+
+	page := Dict{
+		"Type":     Name("Page"),
+		"Parent":   pagesRef,
+		"MediaBox": Array{int64(0), int64(0), width, height},
+		"Contents": w.add(&Stream{Dict: Dict{}, Data: content.Bytes()}),
+	}
+	if len(resources) > 0 {
+		page["Resources"] = Dict{"XObject": resources}
+	}
+	w.pages = append(w.pages, w.add(page))
+	return nil
+}
+
+// imageStream encodes img as a Flate compressed image XObject
+func imageStream(img image.Image) (*Stream, error) {
+	b := img.Bounds()
+	if b.Empty() {
+		return nil, fmt.Errorf("pdf: empty image")
+	}
+
+	gray := isGray(img)
+	var raw bytes.Buffer
+	zw := zlib.NewWriter(&raw)
+	row := make([]byte, 0, b.Dx()*3)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		row = row[:0]
+		for x := b.Min.X; x < b.Max.X; x++ {
+			// Transparent pixels are drawn on white, as on paper
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			r, g, bl := onWhite(c.R, c.A), onWhite(c.G, c.A), onWhite(c.B, c.A)
+			if gray {
+				row = append(row, r)
+			} else {
+				row = append(row, r, g, bl)
+			}
+		}
+		if _, err := zw.Write(row); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	colorSpace := Name("DeviceRGB")
+	if gray {
+		colorSpace = "DeviceGray"
+	}
+	return &Stream{
+		Dict: Dict{
+			"Type":             Name("XObject"),
+			"Subtype":          Name("Image"),
+			"Width":            int64(b.Dx()),
+			"Height":           int64(b.Dy()),
+			"ColorSpace":       colorSpace,
+			"BitsPerComponent": int64(8),
+			"Filter":           Name("FlateDecode"),
+		},
+		Data: raw.Bytes(),
+	}, nil
+}
+
+// isGray reports whether every pixel of img is a shade of gray
+func isGray(img image.Image) bool {
+	switch img.(type) {
+	case *image.Gray, *image.Gray16:
+		return true
+	}
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			if c.R != c.G || c.G != c.B {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// onWhite blends a color component of alpha a over white
+func onWhite(v, a uint8) uint8 {
+	return uint8((uint16(v)*uint16(a) + 255*uint16(255-a)) / 255)
+}
+
+// formatNumber formats a coordinate for a content stream
+func formatNumber(f float64) string {
+	var buf bytes.Buffer
+	writeObject(&buf, f)
+	return buf.String()
+}
@@ -0,0 +1,134 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// Only the object structure of a PDF is handled: page content is copied as
+// is, so splitting and merging never re-renders or re-compresses anything.
+
+// Object is a PDF object: nil (null), bool, int64, float64, String, Name,
+// Array, Dict, *Stream or Ref
+type Object interface{}
+
+// Name is a PDF name, without the leading slash
+type Name string
+
+// String is a PDF string, decoded
+type String string
+
+// Array is a PDF array
+type Array []Object
+
+// Dict is a PDF dictionary
+type Dict map[Name]Object
+
+// Ref is a reference to an indirect object
+type Ref struct {
+	Num int
+	Gen int
+}
+
+// Stream is a PDF stream. Data is kept encoded, as stored in the file.
+type Stream struct {
+	Dict Dict
+	Data []byte
+}
+
+// writeObject serializes obj in PDF syntax
+func writeObject(w *bytes.Buffer, obj Object) error {
+	switch v := obj.(type) {
+	case nil:
+		w.WriteString("null")
+	case bool:
+		w.WriteString(strconv.FormatBool(v))
+	case int64:
+		w.WriteString(strconv.FormatInt(v, 10))
+	case float64:
+		w.WriteString(strconv.FormatFloat(v, 'f', -1, 64))
+	case Name:
+		writeName(w, v)
+	case String:
+		// Hex strings need no escaping
+		fmt.Fprintf(w, "<%x>", string(v))
+	case Array:
+		w.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				w.WriteByte(' ')
+			}
+			if err := writeObject(w, item); err != nil {
+				return err
+			}
+		}
+		w.WriteByte(']')
+	case Dict:
+		return writeDict(w, v)
+	case *Stream:
+		dict := make(Dict, len(v.Dict)+1)
+		for k, item := range v.Dict {
+			dict[k] = item
+		}
+		dict["Length"] = int64(len(v.Data))
+		if err := writeDict(w, dict); err != nil {
+			return err
+		}
+		w.WriteString("\nstream\n")
+		w.Write(v.Data)
+		w.WriteString("\nendstream")
+	case Ref:
+		fmt.Fprintf(w, "%d %d R", v.Num, v.Gen)
+	default:
+		return fmt.Errorf("pdf: cannot write %T", obj)
+	}
+	return nil
+}
+
+// writeDict writes a dictionary with sorted keys, for reproducible output
+func writeDict(w *bytes.Buffer, d Dict) error {
+	keys := make([]string, 0, len(d))
+	for k := range d {
+		keys = append(keys, string(k))
+	}
+	sort.Strings(keys)
+
+	w.WriteString("<<")
+	for _, k := range keys {
+		writeName(w, Name(k))
+		w.WriteByte(' ')
+		if err := writeObject(w, d[Name(k)]); err != nil {
+			return err
+		}
+	}
+	w.WriteString(">>")
+	return nil
+}
+
+// writeName writes a name, escaping delimiters and non-printable bytes
+func writeName(w *bytes.Buffer, n Name) {
+	w.WriteByte('/')
+	for i := 0; i < len(n); i++ {
+		c := n[i]
+		if c < '!' || c > '~' || c == '#' || isDelimiter(c) {
+			fmt.Fprintf(w, "#%02x", c)
+		} else {
+			w.WriteByte(c)
+		}
+	}
+}
+
+// countingWriter tracks the offset of the output for the xref table
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,291 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+)
+
+// parser reads PDF objects from a byte slice
+type parser struct {
+	data []byte
+	pos  int
+}
+
+func isWhitespace(c byte) bool {
+	return c == 0 || c == '\t' || c == '\n' || c == '\f' || c == '\r' || c == ' '
+}
+
+func isDelimiter(c byte) bool {
+	switch c {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return false
+}
+
+func isRegular(c byte) bool {
+	return !isWhitespace(c) && !isDelimiter(c)
+}
+
+// skipSpace skips whitespace and comments
+func (p *parser) skipSpace() {
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		if isWhitespace(c) {
+			p.pos++
+		} else if c == '%' {
+			for p.pos < len(p.data) && p.data[p.pos] != '\n' && p.data[p.pos] != '\r' {
+				p.pos++
+			}
+		} else {
+			return
+		}
+	}
+}
+
+// keyword reads the next run of regular characters
+func (p *parser) keyword() string {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.data) && isRegular(p.data[p.pos]) {
+		p.pos++
+	}
+	return string(p.data[start:p.pos])
+}
+
+// integer reads a non-negative integer, or returns false and leaves the
+// position unchanged
+func (p *parser) integer() (int, bool) {
+	start := p.pos
+	p.skipSpace()
+	digits := p.pos
+	for p.pos < len(p.data) && p.data[p.pos] >= '0' && p.data[p.pos] <= '9' {
+		p.pos++
+	}
+	if p.pos == digits || (p.pos < len(p.data) && isRegular(p.data[p.pos])) {
+		p.pos = start
+		return 0, false
+	}
+	n, err := strconv.Atoi(string(p.data[digits:p.pos]))
+	if err != nil {
+		p.pos = start
+		return 0, false
+	}
+	return n, true
+}
+
+// object reads the next direct object. Streams are handled by the reader,
+// since their length may be an indirect object.
+func (p *parser) object() (Object, error) {
+	p.skipSpace()
+	if p.pos >= len(p.data) {
+		return nil, fmt.Errorf("pdf: unexpected end of data")
+	}
+
+	switch c := p.data[p.pos]; {
+	case c == '/':
+		return p.name(), nil
+	case c == '(':
+		return p.literalString()
+	case c == '<' && p.pos+1 < len(p.data) && p.data[p.pos+1] == '<':
+		return p.dict()
+	case c == '<':
+		return p.hexString()
+	case c == '[':
+		return p.array()
+	case c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9'):
+		return p.number()
+	}
+
+	start := p.pos
+	switch kw := p.keyword(); kw {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("pdf: unexpected %q at offset %d", kw, start)
+	}
+}
+
+func (p *parser) name() Name {
+	p.pos++ // "/"
+	var b []byte
+	for p.pos < len(p.data) && isRegular(p.data[p.pos]) {
+		c := p.data[p.pos]
+		if c == '#' && p.pos+2 < len(p.data) {
+			if v, err := strconv.ParseUint(string(p.data[p.pos+1:p.pos+3]), 16, 8); err == nil {
+				b = append(b, byte(v))
+				p.pos += 3
+				continue
+			}
+		}
+		b = append(b, c)
+		p.pos++
+	}
+	return Name(b)
+}
+
+// number reads an integer, a real or a reference ("12 0 R")
+func (p *parser) number() (Object, error) {
+	start := p.pos
+	for p.pos < len(p.data) && isRegular(p.data[p.pos]) {
+		p.pos++
+	}
+	text := string(p.data[start:p.pos])
+
+	if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+		// Look ahead for "gen R"
+		save := p.pos
+		if gen, ok := p.integer(); ok && n >= 0 {
+			p.skipSpace()
+			if p.pos < len(p.data) && p.data[p.pos] == 'R' &&
+				(p.pos+1 == len(p.data) || !isRegular(p.data[p.pos+1])) {
+				p.pos++
+				return Ref{Num: int(n), Gen: gen}, nil
+			}
+		}
+		p.pos = save
+		return n, nil
+	}
+
+	f, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		// Malformed numbers such as "--5" are read as 0, like most viewers do
+		return int64(0), nil
+	}
+	return f, nil
+}
+
+func (p *parser) literalString() (Object, error) {
+	p.pos++ // "("
+	var b []byte
+	depth := 1
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		p.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return String(b), nil
+			}
+		case '\\':
+			if p.pos >= len(p.data) {
+				continue
+			}
+			e := p.data[p.pos]
+			p.pos++
+			switch e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				// Line continuation
+				if p.pos < len(p.data) && p.data[p.pos] == '\n' {
+					p.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for i := 0; i < 2 && p.pos < len(p.data) && p.data[p.pos] >= '0' && p.data[p.pos] <= '7'; i++ {
+						v = v*8 + int(p.data[p.pos]-'0')
+						p.pos++
+					}
+					c = byte(v)
+				} else {
+					c = e
+				}
+			}
+		}
+		b = append(b, c)
+	}
+	return nil, fmt.Errorf("pdf: unterminated string")
+}
+
+func (p *parser) hexString() (Object, error) {
+	p.pos++ // "<"
+	var b []byte
+	var digits []byte
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		p.pos++
+		if c == '>' {
+			if len(digits)%2 == 1 {
+				digits = append(digits, '0')
+			}
+			for i := 0; i < len(digits); i += 2 {
+				v, _ := strconv.ParseUint(string(digits[i:i+2]), 16, 8)
+				b = append(b, byte(v))
+			}
+			return String(b), nil
+		}
+		if isWhitespace(c) {
+			continue
+		}
+		digits = append(digits, c)
+	}
+	return nil, fmt.Errorf("pdf: unterminated hex string")
+}
+
+func (p *parser) array() (Object, error) {
+	p.pos++ // "["
+	arr := Array{}
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.data) {
+			return nil, fmt.Errorf("pdf: unterminated array")
+		}
+		if p.data[p.pos] == ']' {
+			p.pos++
+			return arr, nil
+		}
+		item, err := p.object()
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, item)
+	}
+}
+
+func (p *parser) dict() (Object, error) {
+	p.pos += 2 // "<<"
+	dict := Dict{}
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.data) {
+			return nil, fmt.Errorf("pdf: unterminated dictionary")
+		}
+		if bytes.HasPrefix(p.data[p.pos:], []byte(">>")) {
+			p.pos += 2
+			return dict, nil
+		}
+		if p.data[p.pos] != '/' {
+			return nil, fmt.Errorf("pdf: dictionary key expected at offset %d", p.pos)
+		}
+		key := p.name()
+		value, err := p.object()
+		if err != nil {
+			return nil, err
+		}
+		// A null value is the same as a missing entry
+		if value != nil {
+			dict[key] = value
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package pdf
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"io"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// samplePath is the sample document bundled with pdf.js, 14 pages with
+// object streams and a cross-reference stream
+const samplePath = "../../frontend/public/pdfjs/web/compressed.tracemonkey-pldi-09.pdf"
+
+const samplePages = 14
+
+// minimalPDF is a one page document with a classic cross-reference table
+const minimalPDF = "%PDF-1.4\n" +
+	"1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n" +
+	"2 0 obj\n<< /Type /Pages /Kids [3 0 R] /Count 1 /MediaBox [0 0 200 100] >>\nendobj\n" +
+	"3 0 obj\n<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>\nendobj\n" +
+	"4 0 obj\n<< /Length 8 >>\nstream\n0 0 m S\n\nendstream\nendobj\n" +
+	"trailer\n<< /Root 1 0 R /Size 5 >>\n%%EOF\n"
+
+func readSample(t *testing.T) []byte {
+	t.Helper()
+	data, err := os.ReadFile(samplePath)
+	if err != nil {
+		t.Fatalf("reading the sample: %v", err)
+	}
+	return data
+}
+
+// write writes the given pages of r to a new document and reads it back
+func write(t *testing.T, r *Reader, pages ...int) (*Reader, []byte) {
+	t.Helper()
+	w := NewWriter()
+	w.SetTitle("Split")
+	for _, page := range pages {
+		if err := w.AddPage(r, page); err != nil {
+			t.Fatalf("AddPage(%d): %v", page, err)
+		}
+	}
+	var buf bytes.Buffer
+	if _, err := w.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+	out, err := NewReader(buf.Bytes())
+	if err != nil {
+		t.Fatalf("reading the written document: %v", err)
+	}
+	if out.rebuilt {
+		t.Errorf("the cross-reference table of the written document had to be rebuilt")
+	}
+	return out, buf.Bytes()
+}
+
+// pageContent returns the decoded content streams of page index of r
+func pageContent(t *testing.T, r *Reader, index int) []byte {
+	t.Helper()
+	var streams []Object
+	switch v := r.Resolve(r.pages[index]["Contents"]).(type) {
+	case Array:
+		streams = v
+	case *Stream:
+		streams = []Object{v}
+	}
+	var content []byte
+	for _, s := range streams {
+		stream, ok := r.Resolve(s).(*Stream)
+		if !ok {
+			t.Fatalf("page %d: content is not a stream", index+1)
+		}
+		data, err := r.decodeStream(stream)
+		if err != nil {
+			t.Fatalf("page %d: decoding the content: %v", index+1, err)
+		}
+		content = append(content, data...)
+	}
+	return content
+}
+
+func TestPageCount(t *testing.T) {
+	r, err := NewReader(readSample(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := r.NumPages(); got != samplePages {
+		t.Errorf("NumPages() = %d, want %d", got, samplePages)
+	}
+
+	r, err = NewReader([]byte(minimalPDF))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := r.NumPages(); got != 1 {
+		t.Errorf("NumPages() of the minimal document = %d, want 1", got)
+	}
+	// The media box is inherited from the page tree
+	if box := r.Resolve(r.pages[0]["MediaBox"]); !reflect.DeepEqual(box, Array{int64(0), int64(0), int64(200), int64(100)}) {
+		t.Errorf("MediaBox = %v, want the one of the page tree", box)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	r, err := NewReader(readSample(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, _ := write(t, r, 1, 2, 3)
+	if got := out.NumPages(); got != 3 {
+		t.Fatalf("NumPages() = %d, want 3", got)
+	}
+	for i, page := range []int{1, 2, 3} {
+		if !bytes.Equal(pageContent(t, out, i), pageContent(t, r, page)) {
+			t.Errorf("page %d: content differs from page %d of the source", i+1, page+1)
+		}
+		if got, want := out.Resolve(out.pages[i]["MediaBox"]), r.Resolve(r.pages[page]["MediaBox"]); !reflect.DeepEqual(got, want) {
+			t.Errorf("page %d: MediaBox = %v, want %v", i+1, got, want)
+		}
+	}
+
+	if err := NewWriter().AddPage(r, samplePages); err == nil {
+		t.Errorf("AddPage past the last page succeeded")
+	}
+	if _, err := NewWriter().WriteTo(io.Discard); err == nil {
+		t.Errorf("writing a document without pages succeeded")
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	r, err := NewReader(readSample(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	all := make([]int, samplePages)
+	for i := range all {
+		all[i] = i
+	}
+	first, _ := write(t, r, all...)
+	if got := first.NumPages(); got != samplePages {
+		t.Fatalf("NumPages() = %d, want %d", got, samplePages)
+	}
+	for i := range all {
+		if !bytes.Equal(pageContent(t, first, i), pageContent(t, r, i)) {
+			t.Errorf("page %d: content differs from the source", i+1)
+		}
+	}
+
+	// A written document can be read and written again; object numbers
+	// may change, the pages may not
+	second, _ := write(t, first, all...)
+	if got := second.NumPages(); got != samplePages {
+		t.Fatalf("NumPages() after a second write = %d, want %d", got, samplePages)
+	}
+	for i := range all {
+		if !bytes.Equal(pageContent(t, second, i), pageContent(t, r, i)) {
+			t.Errorf("page %d: content differs from the source after a second write", i+1)
+		}
+	}
+}
+
+func TestImagePage(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 2))
+	img.Set(0, 0, color.NRGBA{R: 255, A: 255})
+	w := NewWriter()
+	if err := w.AddImagePage(100, 50, []PagePlacement{{Image: img, Box: Rect{X: 10, Y: 10, W: 40, H: 20}}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.AddImagePage(0, 50, nil); err == nil {
+		t.Errorf("AddImagePage with no width succeeded")
+	}
+	var buf bytes.Buffer
+	if _, err := w.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewReader(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.NumPages() != 1 {
+		t.Fatalf("NumPages() = %d, want 1", r.NumPages())
+	}
+	xobjects := r.resolveDict(r.resolveDict(r.pages[0]["Resources"])["XObject"])
+	stream, ok := r.Resolve(xobjects["Im1"]).(*Stream)
+	if !ok {
+		t.Fatal("the image is missing")
+	}
+	data, err := r.decodeStream(stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// RGB, transparent pixels drawn on white
+	want := []byte{255, 0, 0, 255, 255, 255}
+	if len(data) != 4*2*3 || !bytes.Equal(data[:6], want) {
+		t.Errorf("image data = %v, want %d bytes starting with %v", data, 4*2*3, want)
+	}
+}
+
+// FuzzParse checks that no input makes the reader panic or loop, and that
+// whatever it accepts can be written out again. The writer copies objects
+// in map order, so coverage varies from run to run and minimizing inputs
+// stalls: run with -fuzzminimizetime 0.
+func FuzzParse(f *testing.F) {
+	f.Add([]byte(minimalPDF))
+	w := NewWriter()
+	w.SetTitle("Seed")
+	w.AddImagePage(10, 10, []PagePlacement{{Image: image.NewGray(image.Rect(0, 0, 2, 2)), Box: Rect{W: 10, H: 10}}})
+	var seed bytes.Buffer
+	w.WriteTo(&seed)
+	f.Add(seed.Bytes())
+	f.Add([]byte("%PDF-1.7\nxref\n0 1\n0000000000 65535 f\r\ntrailer\n<< /Root 1 0 R >>\nstartxref\n9\n%%EOF"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		r, err := NewReader(data)
+		if err != nil {
+			return
+		}
+		w := NewWriter()
+		for i := 0; i < r.NumPages(); i++ {
+			if err := w.AddPage(r, i); err != nil {
+				t.Fatalf("AddPage(%d) of %d: %v", i, r.NumPages(), err)
+			}
+		}
+		if r.NumPages() > 0 {
+			if _, err := w.WriteTo(io.Discard); err != nil {
+				t.Fatalf("WriteTo: %v", err)
+			}
+		}
+	})
+}
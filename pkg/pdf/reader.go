@@ -0,0 +1,671 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+)
+
+// ErrEncrypted is returned for password protected or encrypted files
+var ErrEncrypted = errors.New("pdf: encrypted files are not supported")
+
+// xrefEntry locates an object in the file
+type xrefEntry struct {
+	offset int // Byte offset, or index in the object stream
+	stream int // Object stream holding the object, 0 if stored directly
+}
+
+// Reader gives access to the objects and pages of a PDF held in memory
+type Reader struct {
+	data    []byte
+	xref    map[int]xrefEntry
+	trailer Dict
+	cache   map[int]Object
+	loading map[int]bool
+	rebuilt bool
+
+	pages []Dict // Page dictionaries with inherited attributes filled in
+}
+
+// Open reads a PDF file
+func Open(path string) (*Reader, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewReader(data)
+}
+
+// NewReader parses a PDF from memory
+func NewReader(data []byte) (*Reader, error) {
+	// The header may follow some junk, but must be near the start
+	if !bytes.Contains(data[:min(len(data), 1024)], []byte("%PDF-")) {
+		return nil, fmt.Errorf("pdf: not a PDF file")
+	}
+
+	r := &Reader{
+		data:    data,
+		xref:    map[int]xrefEntry{},
+		cache:   map[int]Object{},
+		loading: map[int]bool{},
+	}
+	if err := r.readXref(); err != nil {
+		// Damaged cross-reference data: locate the objects by scanning
+		if err := r.rebuildXref(); err != nil {
+			return nil, err
+		}
+	}
+	if _, ok := r.trailer["Encrypt"]; ok {
+		return nil, ErrEncrypted
+	}
+	if err := r.loadPages(); err != nil && !r.rebuilt {
+		// The xref may point to the wrong offsets; try again from a scan
+		if err := r.rebuildXref(); err != nil {
+			return nil, err
+		}
+		if err := r.loadPages(); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// NumPages returns the number of pages
+func (r *Reader) NumPages() int {
+	return len(r.pages)
+}
+
+// Resolve follows a reference to the object it points to. Other objects
+// are returned unchanged.
+func (r *Reader) Resolve(obj Object) Object {
+	for i := 0; i < 32; i++ {
+		ref, ok := obj.(Ref)
+		if !ok {
+			return obj
+		}
+		obj = r.object(ref.Num)
+	}
+	return nil
+}
+
+// resolveDict resolves obj and returns it as a dictionary, or nil. The
+// dictionary of a stream is returned for streams.
+func (r *Reader) resolveDict(obj Object) Dict {
+	switch v := r.Resolve(obj).(type) {
+	case Dict:
+		return v
+	case *Stream:
+		return v.Dict
+	}
+	return nil
+}
+
+func (r *Reader) resolveInt(obj Object) (int, bool) {
+	switch v := r.Resolve(obj).(type) {
+	case int64:
+		return int(v), true
+	case float64:
+		return int(v), true
+	}
+	return 0, false
+}
+
+// === Cross-reference ===
+
+// readXref reads the cross-reference sections, newest first
+func (r *Reader) readXref() error {
+	i := bytes.LastIndex(r.data, []byte("startxref"))
+	if i < 0 {
+		return fmt.Errorf("pdf: startxref not found")
+	}
+	p := &parser{data: r.data, pos: i + len("startxref")}
+	offset, ok := p.integer()
+	if !ok {
+		return fmt.Errorf("pdf: invalid startxref")
+	}
+
+	seen := map[int]bool{}
+	for offset > 0 && !seen[offset] {
+		seen[offset] = true
+		trailer, err := r.readXrefSection(offset)
+		if err != nil {
+			return err
+		}
+		if r.trailer == nil {
+			r.trailer = trailer
+		}
+
+		// Hybrid files keep part of the table in a stream
+		if stm, ok := r.resolveInt(trailer["XRefStm"]); ok && !seen[stm] {
+			seen[stm] = true
+			if _, err := r.readXrefSection(stm); err != nil {
+				return err
+			}
+		}
+
+		prev, ok := r.resolveInt(trailer["Prev"])
+		if !ok {
+			break
+		}
+		offset = prev
+	}
+
+	if r.trailer == nil || r.trailer["Root"] == nil {
+		return fmt.Errorf("pdf: trailer has no document catalog")
+	}
+	return nil
+}
+
+// readXrefSection reads a table or stream at offset and returns its trailer.
+// Entries already known from a newer section are kept.
+func (r *Reader) readXrefSection(offset int) (Dict, error) {
+	if offset >= len(r.data) {
+		return nil, fmt.Errorf("pdf: xref offset out of range")
+	}
+	p := &parser{data: r.data, pos: offset}
+	p.skipSpace()
+	if bytes.HasPrefix(r.data[p.pos:], []byte("xref")) {
+		p.pos += 4
+		return r.readXrefTable(p)
+	}
+	return r.readXrefStream(p)
+}
+
+func (r *Reader) readXrefTable(p *parser) (Dict, error) {
+	for {
+		p.skipSpace()
+		if bytes.HasPrefix(p.data[p.pos:], []byte("trailer")) {
+			p.pos += len("trailer")
+			obj, err := p.object()
+			if err != nil {
+				return nil, err
+			}
+			trailer, ok := obj.(Dict)
+			if !ok {
+				return nil, fmt.Errorf("pdf: invalid trailer")
+			}
+			return trailer, nil
+		}
+
+		start, ok1 := p.integer()
+		count, ok2 := p.integer()
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("pdf: invalid xref subsection at offset %d", p.pos)
+		}
+		for i := 0; i < count; i++ {
+			offset, ok1 := p.integer()
+			_, ok2 := p.integer()
+			kind := p.keyword()
+			if !ok1 || !ok2 || (kind != "n" && kind != "f") {
+				return nil, fmt.Errorf("pdf: invalid xref entry at offset %d", p.pos)
+			}
+			num := start + i
+			if _, known := r.xref[num]; known {
+				continue
+			}
+			if kind == "n" {
+				r.xref[num] = xrefEntry{offset: offset}
+			} else {
+				r.xref[num] = xrefEntry{offset: -1}
+			}
+		}
+	}
+}
+
+func (r *Reader) readXrefStream(p *parser) (Dict, error) {
+	_, stream, err := r.indirectObject(p)
+	if err != nil {
+		return nil, err
+	}
+	s, ok := stream.(*Stream)
+	if !ok || s.Dict["Type"] != Name("XRef") {
+		return nil, fmt.Errorf("pdf: invalid xref stream")
+	}
+	data, err := r.decodeStream(s)
+	if err != nil {
+		return nil, err
+	}
+
+	w, ok := s.Dict["W"].(Array)
+	if !ok || len(w) != 3 {
+		return nil, fmt.Errorf("pdf: invalid xref stream widths")
+	}
+	var widths [3]int
+	for i := range widths {
+		n, _ := w[i].(int64)
+		widths[i] = int(n)
+	}
+	size, _ := s.Dict["Size"].(int64)
+	index := Array{int64(0), size}
+	if idx, ok := s.Dict["Index"].(Array); ok {
+		index = idx
+	}
+
+	rowSize := widths[0] + widths[1] + widths[2]
+	if rowSize == 0 {
+		return nil, fmt.Errorf("pdf: invalid xref stream widths")
+	}
+	field := func(row []byte, i int) int {
+		start := 0
+		for j := 0; j < i; j++ {
+			start += widths[j]
+		}
+		v := 0
+		for _, b := range row[start : start+widths[i]] {
+			v = v<<8 | int(b)
+		}
+		return v
+	}
+
+	pos := 0
+	for i := 0; i+1 < len(index); i += 2 {
+		start, _ := index[i].(int64)
+		count, _ := index[i+1].(int64)
+		for j := 0; j < int(count) && pos+rowSize <= len(data); j++ {
+			row := data[pos : pos+rowSize]
+			pos += rowSize
+
+			kind := 1 // The type field defaults to 1 when its width is 0
+			if widths[0] > 0 {
+				kind = field(row, 0)
+			}
+			num := int(start) + j
+			if _, known := r.xref[num]; known {
+				continue
+			}
+			switch kind {
+			case 0:
+				r.xref[num] = xrefEntry{offset: -1}
+			case 1:
+				r.xref[num] = xrefEntry{offset: field(row, 1)}
+			case 2:
+				r.xref[num] = xrefEntry{stream: field(row, 1), offset: field(row, 2)}
+			}
+		}
+	}
+	return s.Dict, nil
+}
+
+var objHeaderPattern = regexp.MustCompile(`(?m)(\d+)[\x00\t\n\f\r ]+(\d+)[\x00\t\n\f\r ]+obj\b`)
+
+// rebuildXref finds all objects by scanning the file, for files whose
+// cross-reference data is missing or wrong. Later definitions win.
+func (r *Reader) rebuildXref() error {
+	r.rebuilt = true
+	r.xref = map[int]xrefEntry{}
+	r.cache = map[int]Object{}
+
+	for _, m := range objHeaderPattern.FindAllSubmatchIndex(r.data, -1) {
+		// Skip matches inside another token, e.g. "112 0 obj" matched as "12 0 obj"
+		if m[0] > 0 && r.data[m[0]-1] >= '0' && r.data[m[0]-1] <= '9' {
+			continue
+		}
+		num, _ := strconv.Atoi(string(r.data[m[2]:m[3]]))
+		r.xref[num] = xrefEntry{offset: m[0]}
+	}
+
+	// Objects inside object streams
+	for num, entry := range r.xref {
+		if entry.stream != 0 {
+			continue
+		}
+		s, ok := r.object(num).(*Stream)
+		if !ok || s.Dict["Type"] != Name("ObjStm") {
+			continue
+		}
+		nums, _, err := r.objectStreamIndex(s)
+		if err != nil {
+			continue
+		}
+		for i, n := range nums {
+			if _, known := r.xref[n]; !known {
+				r.xref[n] = xrefEntry{stream: num, offset: i}
+			}
+		}
+	}
+
+	// Use the last trailer, or find the catalog
+	trailer := Dict{}
+	if i := bytes.LastIndex(r.data, []byte("trailer")); i >= 0 {
+		p := &parser{data: r.data, pos: i + len("trailer")}
+		if obj, err := p.object(); err == nil {
+			if d, ok := obj.(Dict); ok {
+				trailer = d
+			}
+		}
+	}
+	if trailer["Root"] == nil {
+		for num := range r.xref {
+			if d := r.resolveDict(Ref{Num: num}); d != nil && d["Type"] == Name("Catalog") {
+				trailer["Root"] = Ref{Num: num}
+				break
+			}
+		}
+	}
+	if trailer["Root"] == nil {
+		return fmt.Errorf("pdf: document catalog not found")
+	}
+	r.trailer = trailer
+	return nil
+}
+
+// === Objects ===
+
+// object loads an indirect object by number. Missing or broken objects
+// are read as null.
+func (r *Reader) object(num int) Object {
+	if obj, ok := r.cache[num]; ok {
+		return obj
+	}
+	entry, ok := r.xref[num]
+	if !ok || entry.offset < 0 || r.loading[num] {
+		return nil
+	}
+	r.loading[num] = true
+	defer delete(r.loading, num)
+
+	var obj Object
+	if entry.stream != 0 {
+		obj = r.compressedObject(entry.stream, entry.offset)
+	} else if entry.offset < len(r.data) {
+		p := &parser{data: r.data, pos: entry.offset}
+		if n, o, err := r.indirectObject(p); err == nil && n == num {
+			obj = o
+		}
+	}
+	r.cache[num] = obj
+	return obj
+}
+
+// indirectObject reads "num gen obj ... endobj" at the parser position
+func (r *Reader) indirectObject(p *parser) (int, Object, error) {
+	num, ok1 := p.integer()
+	_, ok2 := p.integer()
+	if !ok1 || !ok2 || p.keyword() != "obj" {
+		return 0, nil, fmt.Errorf("pdf: object header expected at offset %d", p.pos)
+	}
+	obj, err := p.object()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	dict, ok := obj.(Dict)
+	if !ok {
+		return num, obj, nil
+	}
+	save := p.pos
+	if p.keyword() != "stream" {
+		p.pos = save
+		return num, obj, nil
+	}
+
+	// Stream data starts after the end of line following the keyword
+	if p.pos < len(p.data) && p.data[p.pos] == '\r' {
+		p.pos++
+	}
+	if p.pos < len(p.data) && p.data[p.pos] == '\n' {
+		p.pos++
+	}
+	start := p.pos
+	end := -1
+	if length, ok := r.resolveInt(dict["Length"]); ok && length >= 0 && start+length <= len(p.data) {
+		q := &parser{data: p.data, pos: start + length}
+		if q.keyword() == "endstream" {
+			end = start + length
+		}
+	}
+	if end < 0 {
+		// Wrong length: the data ends at the "endstream" keyword
+		i := bytes.Index(p.data[start:], []byte("endstream"))
+		if i < 0 {
+			return 0, nil, fmt.Errorf("pdf: unterminated stream at offset %d", start)
+		}
+		end = start + i
+		if end > start && p.data[end-1] == '\n' {
+			end--
+		}
+		if end > start && p.data[end-1] == '\r' {
+			end--
+		}
+	}
+	return num, &Stream{Dict: dict, Data: p.data[start:end]}, nil
+}
+
+// compressedObject reads the object at index in an object stream
+func (r *Reader) compressedObject(streamNum, index int) Object {
+	s, ok := r.object(streamNum).(*Stream)
+	if !ok {
+		return nil
+	}
+	_, offsets, err := r.objectStreamIndex(s)
+	if err != nil || index >= len(offsets) {
+		return nil
+	}
+	data, err := r.decodeStream(s)
+	if err != nil || offsets[index] >= len(data) {
+		return nil
+	}
+	p := &parser{data: data, pos: offsets[index]}
+	obj, err := p.object()
+	if err != nil {
+		return nil
+	}
+	return obj
+}
+
+// objectStreamIndex returns the numbers and data offsets of the objects
+// in an object stream
+func (r *Reader) objectStreamIndex(s *Stream) ([]int, []int, error) {
+	data, err := r.decodeStream(s)
+	if err != nil {
+		return nil, nil, err
+	}
+	n, _ := r.resolveInt(s.Dict["N"])
+	first, _ := r.resolveInt(s.Dict["First"])
+
+	p := &parser{data: data}
+	var nums, offsets []int
+	for i := 0; i < n; i++ {
+		num, ok1 := p.integer()
+		offset, ok2 := p.integer()
+		if !ok1 || !ok2 {
+			return nil, nil, fmt.Errorf("pdf: invalid object stream header")
+		}
+		nums = append(nums, num)
+		offsets = append(offsets, first+offset)
+	}
+	return nums, offsets, nil
+}
+
+// decodeStream decodes the data of a stream. Only FlateDecode is supported,
+// which is all cross-reference and object streams use in practice.
+func (r *Reader) decodeStream(s *Stream) ([]byte, error) {
+	var filters Array
+	switch f := r.Resolve(s.Dict["Filter"]).(type) {
+	case Name:
+		filters = Array{f}
+	case Array:
+		filters = f
+	}
+	var params Array
+	switch dp := r.Resolve(s.Dict["DecodeParms"]).(type) {
+	case Dict:
+		params = Array{dp}
+	case Array:
+		params = dp
+	}
+
+	data := s.Data
+	for i, f := range filters {
+		if r.Resolve(f) != Name("FlateDecode") {
+			return nil, fmt.Errorf("pdf: unsupported filter %v", f)
+		}
+		zr, err := zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		decoded, err := io.ReadAll(zr)
+		if err != nil && len(decoded) == 0 {
+			return nil, err
+		}
+		data = decoded
+
+		if i < len(params) {
+			if dp := r.resolveDict(params[i]); dp != nil {
+				if data, err = r.unpredict(data, dp); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return data, nil
+}
+
+// unpredict reverses the PNG predictors used by cross-reference streams
+func (r *Reader) unpredict(data []byte, params Dict) ([]byte, error) {
+	predictor, _ := r.resolveInt(params["Predictor"])
+	if predictor < 10 {
+		if predictor > 1 {
+			return nil, fmt.Errorf("pdf: unsupported predictor %d", predictor)
+		}
+		return data, nil
+	}
+	columns, ok := r.resolveInt(params["Columns"])
+	if !ok || columns <= 0 {
+		columns = 1
+	}
+	colors, ok := r.resolveInt(params["Colors"])
+	if !ok || colors <= 0 {
+		colors = 1
+	}
+	bpc, ok := r.resolveInt(params["BitsPerComponent"])
+	if !ok || bpc <= 0 {
+		bpc = 8
+	}
+	bpp := max(1, colors*bpc/8)
+	rowSize := (columns*colors*bpc + 7) / 8
+
+	var out []byte
+	prev := make([]byte, rowSize)
+	for pos := 0; pos+rowSize+1 <= len(data); pos += rowSize + 1 {
+		filter := data[pos]
+		row := append([]byte(nil), data[pos+1:pos+1+rowSize]...)
+		for i := range row {
+			var left, upLeft byte
+			if i >= bpp {
+				left = row[i-bpp]
+				upLeft = prev[i-bpp]
+			}
+			up := prev[i]
+			switch filter {
+			case 1:
+				row[i] += left
+			case 2:
+				row[i] += up
+			case 3:
+				row[i] += byte((int(left) + int(up)) / 2)
+			case 4:
+				row[i] += paeth(left, up, upLeft)
+			}
+		}
+		out = append(out, row...)
+		prev = row
+	}
+	return out, nil
+}
+
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := abs(p-int(a)), abs(p-int(b)), abs(p-int(c))
+	if pa <= pb && pa <= pc {
+		return a
+	}
+	if pb <= pc {
+		return b
+	}
+	return c
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// === Pages ===
+
+// inheritedKeys are the page attributes that may be set on a parent node
+var inheritedKeys = []Name{"Resources", "MediaBox", "CropBox", "Rotate"}
+
+// loadPages flattens the page tree
+func (r *Reader) loadPages() error {
+	r.pages = nil
+	catalog := r.resolveDict(r.trailer["Root"])
+	if catalog == nil {
+		return fmt.Errorf("pdf: document catalog not found")
+	}
+	root, ok := catalog["Pages"].(Ref)
+	if !ok {
+		return fmt.Errorf("pdf: page tree not found")
+	}
+	if err := r.walkPages(root, Dict{}, map[int]bool{}); err != nil {
+		return err
+	}
+	if len(r.pages) == 0 {
+		return fmt.Errorf("pdf: document has no pages")
+	}
+	return nil
+}
+
+func (r *Reader) walkPages(ref Ref, inherited Dict, visited map[int]bool) error {
+	if visited[ref.Num] {
+		return fmt.Errorf("pdf: page tree contains a cycle")
+	}
+	visited[ref.Num] = true
+
+	node := r.resolveDict(ref)
+	if node == nil {
+		return fmt.Errorf("pdf: page tree node %d not found", ref.Num)
+	}
+
+	attrs := Dict{}
+	for _, key := range inheritedKeys {
+		if v, ok := node[key]; ok {
+			attrs[key] = v
+		} else if v, ok := inherited[key]; ok {
+			attrs[key] = v
+		}
+	}
+
+	kids, isTree := r.Resolve(node["Kids"]).(Array)
+	if !isTree || node["Type"] == Name("Page") {
+		page := make(Dict, len(node)+len(attrs))
+		for k, v := range node {
+			page[k] = v
+		}
+		for k, v := range attrs {
+			page[k] = v
+		}
+		r.pages = append(r.pages, page)
+		return nil
+	}
+
+	for _, kid := range kids {
+		kidRef, ok := kid.(Ref)
+		if !ok {
+			continue
+		}
+		if err := r.walkPages(kidRef, attrs, visited); err != nil {
+			return err
+		}
+	}
+	return nil
+}
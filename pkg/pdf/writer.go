@@ -0,0 +1,256 @@
+package pdf
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// droppedPageKeys are page entries not copied to a new document: they
+// point to other pages (annotations, article beads) or to structures of
+// the source document
+var droppedPageKeys = map[Name]bool{
+	"Parent":         true,
+	"Annots":         true,
+	"B":              true,
+	"StructParents":  true,
+	"PieceInfo":      true,
+	"Metadata":       true,
+	"ID":             true,
+	"PZ":             true,
+	"SeparationInfo": true,
+}
+
+// sourceRef identifies an object of a source document
+type sourceRef struct {
+	reader *Reader
+	num    int
+}
+
+// Writer builds a new document from pages of one or more source documents
+type Writer struct {
+	objects []Object // Object n is objects[n-1]
+	copied  map[sourceRef]Ref
+	pages   Array
+	title   string
+}
+
+// Object numbers reserved by NewWriter
+var (
+	catalogRef = Ref{Num: 1}
+	pagesRef   = Ref{Num: 2}
+)
+
+// NewWriter returns an empty document
+func NewWriter() *Writer {
+	return &Writer{
+		objects: []Object{nil, nil}, // Catalog and page tree, filled on write
+		copied:  map[sourceRef]Ref{},
+	}
+}
+
+// SetTitle sets the title stored in the document information
+func (w *Writer) SetTitle(title string) {
+	w.title = title
+}
+
+// NumPages returns the number of pages added so far
+func (w *Writer) NumPages() int {
+	return len(w.pages)
+}
+
+// AddPage appends page index (0-based) of r. Resources shared between
+// pages of the same source are copied once.
+func (w *Writer) AddPage(r *Reader, index int) error {
+	if index < 0 || index >= len(r.pages) {
+		return fmt.Errorf("pdf: page %d out of range (1-%d)", index+1, len(r.pages))
+	}
+
+	page := Dict{"Parent": pagesRef}
+	for key, value := range r.pages[index] {
+		if droppedPageKeys[key] {
+			continue
+		}
+		page[key] = w.copy(r, value)
+	}
+	page["Type"] = Name("Page")
+	if page["MediaBox"] == nil {
+		// Required; US Letter is the documented default of most writers
+		page["MediaBox"] = Array{int64(0), int64(0), int64(612), int64(792)}
+	}
+
+	w.pages = append(w.pages, w.add(page))
+	return nil
+}
+
+// add stores a new object and returns its reference
+func (w *Writer) add(obj Object) Ref {
+	w.objects = append(w.objects, obj)
+	return Ref{Num: len(w.objects)}
+}
+
+// copy deep copies obj from r, giving indirect objects new numbers
+func (w *Writer) copy(r *Reader, obj Object) Object {
+	switch v := obj.(type) {
+	case Ref:
+		key := sourceRef{reader: r, num: v.Num}
+		if ref, ok := w.copied[key]; ok {
+			return ref
+		}
+		target := r.Resolve(v)
+		if d := r.resolveDict(v); d != nil && (d["Type"] == Name("Page") || d["Type"] == Name("Pages")) {
+			// Would drag other pages of the source along
+			return nil
+		}
+		ref := w.add(nil)
+		w.copied[key] = ref
+		w.objects[ref.Num-1] = w.copy(r, target)
+		return ref
+	case Array:
+		arr := make(Array, len(v))
+		for i, item := range v {
+			arr[i] = w.copy(r, item)
+		}
+		return arr
+	case Dict:
+		dict := make(Dict, len(v))
+		for k, item := range v {
+			if c := w.copy(r, item); c != nil {
+				dict[k] = c
+			}
+		}
+		return dict
+	case *Stream:
+		dict := make(Dict, len(v.Dict))
+		for k, item := range v.Dict {
+			// Length is written from the data
+			if k == "Length" {
+				continue
+			}
+			if c := w.copy(r, item); c != nil {
+				dict[k] = c
+			}
+		}
+		return &Stream{Dict: dict, Data: v.Data}
+	default:
+		return obj
+	}
+}
+
+// WriteTo writes the document
+func (w *Writer) WriteTo(out io.Writer) (int64, error) {
+	if len(w.pages) == 0 {
+		return 0, fmt.Errorf("pdf: document has no pages")
+	}
+
+	w.objects[pagesRef.Num-1] = Dict{
+		"Type":  Name("Pages"),
+		"Kids":  w.pages,
+		"Count": int64(len(w.pages)),
+	}
+	w.objects[catalogRef.Num-1] = Dict{
+		"Type":  Name("Catalog"),
+		"Pages": pagesRef,
+	}
+	objects := w.objects
+	trailer := Dict{"Root": catalogRef}
+	if w.title != "" {
+		objects = append(objects, Dict{
+			"Title":    String(encodeText(w.title)),
+			"Producer": String("HAYA-TAB"),
+		})
+		trailer["Info"] = Ref{Num: len(objects)}
+	}
+	trailer["Size"] = int64(len(objects) + 1)
+
+	cw := &countingWriter{w: out}
+	bw := bufio.NewWriter(cw)
+	var buf bytes.Buffer
+	offsets := make([]int64, len(objects))
+
+	// Binary comment so transfer tools treat the file as binary
+	bw.WriteString("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+	for i, obj := range objects {
+		buf.Reset()
+		fmt.Fprintf(&buf, "%d 0 obj\n", i+1)
+		if err := writeObject(&buf, obj); err != nil {
+			return cw.n, err
+		}
+		buf.WriteString("\nendobj\n")
+
+		bw.Flush()
+		offsets[i] = cw.n
+		if _, err := bw.Write(buf.Bytes()); err != nil {
+			return cw.n, err
+		}
+	}
+
+	bw.Flush()
+	xrefOffset := cw.n
+	fmt.Fprintf(bw, "xref\n0 %d\n0000000000 65535 f\r\n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(bw, "%010d 00000 n\r\n", offset)
+	}
+	buf.Reset()
+	if err := writeDict(&buf, trailer); err != nil {
+		return cw.n, err
+	}
+	fmt.Fprintf(bw, "trailer\n%s\nstartxref\n%d\n%%%%EOF\n", buf.Bytes(), xrefOffset)
+	if err := bw.Flush(); err != nil {
+		return cw.n, err
+	}
+	return cw.n, nil
+}
+
+// Save writes the document to path, through a temporary file so a failed
+// write never leaves a truncated PDF behind
+func (w *Writer) Save(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".pdf-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := w.WriteTo(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// encodeText encodes a text string: as is when it is ASCII, otherwise as
+// UTF-16BE with a byte order mark
+func encodeText(s string) string {
+	ascii := true
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return s
+	}
+
+	b := []byte{0xfe, 0xff}
+	for _, r := range s {
+		if r > 0xffff {
+			r -= 0x10000
+			hi, lo := 0xd800+(r>>10), 0xdc00+(r&0x3ff)
+			b = append(b, byte(hi>>8), byte(hi), byte(lo>>8), byte(lo))
+			continue
+		}
+		b = append(b, byte(r>>8), byte(r))
+	}
+	return string(b)
+}
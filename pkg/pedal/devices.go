@@ -0,0 +1,85 @@
+package pedal
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// ErrUnsupported is returned on systems where foot controllers cannot be
+// read yet: they are found and read through the Linux device files
+var ErrUnsupported = errors.New("foot controllers can only be read on Linux for now")
+
+// footSwitchName matches the names of input devices that are foot
+// controllers or Bluetooth page turners rather than keyboards
+var footSwitchName = regexp.MustCompile(`(?i)foot|pedal|page ?turn|airturn|pageflip|blueturn`)
+
+// CheckSupported returns ErrUnsupported if foot controllers cannot be
+// read on this system
+func CheckSupported() error {
+	if runtime.GOOS != "linux" {
+		return ErrUnsupported
+	}
+	return nil
+}
+
+// Discover returns the connected foot controllers: every raw MIDI device,
+// and the input devices named like a foot switch or containing one of
+// names (case-insensitive). Devices are found through the Linux device
+// files; on other systems Discover returns ErrUnsupported.
+func Discover(names []string) ([]Device, error) {
+	if err := CheckSupported(); err != nil {
+		return nil, err
+	}
+	var devices []Device
+
+	midi, _ := filepath.Glob("/dev/snd/midiC*D*")
+	sort.Strings(midi)
+	for _, path := range midi {
+		devices = append(devices, Device{Name: midiName(path), Path: path, Kind: "midi"})
+	}
+
+	nameFiles, _ := filepath.Glob("/sys/class/input/event*/device/name")
+	sort.Strings(nameFiles)
+	for _, nameFile := range nameFiles {
+		data, err := os.ReadFile(nameFile)
+		name := strings.TrimSpace(string(data))
+		if err != nil || !(footSwitchName.MatchString(name) || containsAny(name, names)) {
+			continue
+		}
+		event := filepath.Base(filepath.Dir(filepath.Dir(nameFile)))
+		devices = append(devices, Device{Name: name, Path: filepath.Join("/dev/input", event), Kind: "hid"})
+	}
+	return devices, nil
+}
+
+// midiName returns the name of the sound card of a raw MIDI device, e.g.
+// "FS1" for /dev/snd/midiC1D0, or the device file name
+func midiName(path string) string {
+	base := filepath.Base(path)
+	card, _, ok := strings.Cut(strings.TrimPrefix(base, "midiC"), "D")
+	if !ok {
+		return base
+	}
+	data, err := os.ReadFile(filepath.Join("/proc/asound", "card"+card, "id"))
+	if err != nil || strings.TrimSpace(string(data)) == "" {
+		return base
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// containsAny reports whether name contains one of the non-empty parts,
+// ignoring case
+func containsAny(name string, parts []string) bool {
+	name = strings.ToLower(name)
+	for _, part := range parts {
+		if part != "" && strings.Contains(name, strings.ToLower(part)) {
+			return true
+		}
+	}
+	return false
+}
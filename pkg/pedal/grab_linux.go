@@ -0,0 +1,31 @@
+package pedal
+
+import (
+	"os"
+	"syscall"
+)
+
+// eviocgrab is the EVIOCGRAB ioctl, _IOW('E', 0x90, int)
+const eviocgrab = 0x40044590
+
+// grab takes exclusive access to an input event device, so the keys of a
+// foot switch reach the app as pedal presses only, not also as key presses
+// in the focused window
+func grab(f *os.File) error {
+	conn, err := f.SyscallConn()
+	if err != nil {
+		return err
+	}
+	// Not f.Fd(): it puts the file in blocking mode, and Stop could then no
+	// longer interrupt a read by closing the file
+	var errno syscall.Errno
+	if err := conn.Control(func(fd uintptr) {
+		_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, fd, eviocgrab, 1)
+	}); err != nil {
+		return err
+	}
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
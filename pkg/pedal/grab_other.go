@@ -0,0 +1,10 @@
+//go:build !linux
+
+package pedal
+
+import "os"
+
+// grab does nothing: input event devices only exist on Linux
+func grab(f *os.File) error {
+	return nil
+}
@@ -0,0 +1,36 @@
+package pedal
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Linux input event types and key values (linux/input-event-codes.h)
+const (
+	evKey        = 0x01
+	keyPressed   = 1 // 0 is released, 2 is auto-repeat
+	timevalBytes = 2 * strconv.IntSize / 8
+)
+
+// readHID reads a Linux input event device until it fails, calling press
+// with "key:<code>" for each key pressed. USB foot switches are HID
+// keyboards; reading their event device directly gets their presses even
+// when the window does not have the focus.
+func readHID(r io.Reader, press func(control string)) error {
+	// struct input_event: struct timeval, __u16 type, __u16 code, __s32 value
+	event := make([]byte, timevalBytes+8)
+	for {
+		if _, err := io.ReadFull(r, event); err != nil {
+			return err
+		}
+		fields := event[timevalBytes:]
+		typ := binary.NativeEndian.Uint16(fields[0:])
+		code := binary.NativeEndian.Uint16(fields[2:])
+		value := int32(binary.NativeEndian.Uint32(fields[4:]))
+		if typ == evKey && value == keyPressed {
+			press(fmt.Sprintf("key:%d", code))
+		}
+	}
+}
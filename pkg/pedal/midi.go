@@ -0,0 +1,86 @@
+package pedal
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// midiParser turns a raw MIDI byte stream into pedal presses. Channels are
+// ignored: a foot controller sends on one channel.
+type midiParser struct {
+	status  byte
+	data    []byte
+	sysex   bool
+	ccValue [128]byte // Last value of each controller
+}
+
+// feed parses one byte and returns the control pressed by the message it
+// completes, if any
+func (p *midiParser) feed(b byte) (string, bool) {
+	switch {
+	case b >= 0xf8:
+		// Real-time messages (clock, active sensing) may appear anywhere
+		return "", false
+	case b == 0xf0:
+		p.sysex, p.status = true, 0
+		return "", false
+	case b >= 0xf0:
+		// System common messages and the end of a SysEx cancel running status
+		p.sysex, p.status = false, 0
+		return "", false
+	case b&0x80 != 0:
+		p.sysex, p.status, p.data = false, b, p.data[:0]
+		return "", false
+	case p.sysex || p.status == 0:
+		return "", false
+	}
+
+	p.data = append(p.data, b)
+	kind := p.status & 0xf0
+	size := 2
+	if kind == 0xc0 || kind == 0xd0 {
+		size = 1
+	}
+	if len(p.data) < size {
+		return "", false
+	}
+	data := p.data
+	p.data = p.data[:0] // Running status: the next message may omit the status byte
+
+	switch kind {
+	case 0x90:
+		// Note on; velocity 0 is a note off
+		if data[1] > 0 {
+			return fmt.Sprintf("note:%d", data[0]), true
+		}
+	case 0xb0:
+		// Control change: a sustain-style pedal is pressed when its value
+		// goes from below to at least 64
+		previous := p.ccValue[data[0]]
+		p.ccValue[data[0]] = data[1]
+		if data[1] >= 64 && previous < 64 {
+			return fmt.Sprintf("cc:%d", data[0]), true
+		}
+	case 0xc0:
+		// Program change, sent by preset-switching pedal boards
+		return fmt.Sprintf("pc:%d", data[0]), true
+	}
+	return "", false
+}
+
+// readMIDI reads a raw MIDI device until it fails, calling press for each
+// control pressed
+func readMIDI(r io.Reader, press func(control string)) error {
+	var p midiParser
+	br := bufio.NewReader(r)
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return err
+		}
+		if control, ok := p.feed(b); ok {
+			press(control)
+		}
+	}
+}
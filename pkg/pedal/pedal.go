@@ -0,0 +1,169 @@
+package pedal
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Logger interface for dependency injection
+type Logger interface {
+	Info(format string, args ...interface{})
+	Error(format string, args ...interface{})
+}
+
+// debounce is how long repeated presses of the same control are ignored.
+// Foot switches bounce, and some pedals send a note and a CC per press.
+const debounce = 150 * time.Millisecond
+
+// Press is a pedal pressed on a foot controller
+type Press struct {
+	Device  string `json:"device"`  // Name of the controller
+	Control string `json:"control"` // e.g. "cc:64", "note:60", "pc:2" (MIDI) or "key:109" (HID key code)
+}
+
+// Device is a foot controller the listener can read
+type Device struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	Kind string `json:"kind"` // "midi" or "hid"
+}
+
+// Listener reads foot controllers and reports their pedal presses
+type Listener struct {
+	onPress func(Press)
+	logger  Logger
+	mu      sync.Mutex
+	running bool
+	names   []string // Extra input device names to read, see Discover
+	files   []*os.File
+	last    map[Press]time.Time
+}
+
+// NewListener creates a listener that calls onPress for every pedal press
+func NewListener(onPress func(Press)) *Listener {
+	return &Listener{
+		onPress: onPress,
+		last:    make(map[Press]time.Time),
+	}
+}
+
+// SetLogger sets the logger
+func (l *Listener) SetLogger(lg Logger) {
+	l.logger = lg
+}
+
+// SetDeviceNames sets the names of input devices to read besides those
+// named like a foot switch, e.g. the names of Bluetooth page turners.
+// Takes effect on the next Start; reports whether the names changed.
+func (l *Listener) SetDeviceNames(names []string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if slices.Equal(l.names, names) {
+		return false
+	}
+	l.names = slices.Clone(names)
+	return true
+}
+
+// Start opens the connected foot controllers (see Discover) and reads them
+// until Stop. Controllers that can't be opened, typically for lack of
+// permission, are logged and skipped. Returns the controllers opened, or
+// ErrUnsupported on systems where they cannot be read.
+func (l *Listener) Start() ([]Device, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.running {
+		return nil, nil
+	}
+	devices, err := Discover(l.names)
+	if err != nil {
+		return nil, err
+	}
+	l.running = true
+
+	var opened []Device
+	for _, d := range devices {
+		f, err := os.Open(d.Path)
+		if err != nil {
+			l.logInfo("Cannot open foot controller %s (%s): %v", d.Name, d.Path, err)
+			continue
+		}
+		if d.Kind == "hid" {
+			if err := grab(f); err != nil {
+				l.logInfo("Cannot take exclusive access to %s, its keys also reach the window: %v", d.Name, err)
+			}
+		}
+		l.files = append(l.files, f)
+		opened = append(opened, d)
+		go l.read(d, f)
+	}
+	return opened, nil
+}
+
+// Stop closes the controllers, ending their reads
+func (l *Listener) Stop() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.running {
+		return
+	}
+	for _, f := range l.files {
+		f.Close()
+	}
+	l.files = nil
+	l.running = false
+}
+
+// IsRunning returns whether the listener is started
+func (l *Listener) IsRunning() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.running
+}
+
+// read reports the presses of one controller until its file is closed
+// or the controller is unplugged
+func (l *Listener) read(d Device, r io.Reader) {
+	emit := func(control string) {
+		l.press(Press{Device: d.Name, Control: control})
+	}
+	var err error
+	switch d.Kind {
+	case "midi":
+		err = readMIDI(r, emit)
+	case "hid":
+		err = readHID(r, emit)
+	default:
+		err = fmt.Errorf("unknown controller kind %q", d.Kind)
+	}
+	if err != nil && l.IsRunning() {
+		l.logInfo("Stopped reading foot controller %s: %v", d.Name, err)
+	}
+}
+
+// press reports p unless the same control was pressed within debounce
+func (l *Listener) press(p Press) {
+	now := time.Now()
+	l.mu.Lock()
+	if now.Sub(l.last[p]) < debounce {
+		l.mu.Unlock()
+		return
+	}
+	l.last[p] = now
+	l.mu.Unlock()
+
+	l.onPress(p)
+}
+
+func (l *Listener) logInfo(format string, args ...interface{}) {
+	if l.logger != nil {
+		l.logger.Info(format, args...)
+	}
+}
@@ -0,0 +1,65 @@
+package remotefs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"haya-tab/pkg/diskcache"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// Cache keeps local copies of the files of shares in a directory, so a file
+// is fetched again only once it changed on the share. Each version of a file
+// is kept in its own subdirectory under its own name, which syncs read the
+// title of. Old versions are removed by Evict.
+type Cache struct {
+	dir string
+}
+
+// NewCache returns a cache in dir
+func NewCache(dir string) *Cache {
+	return &Cache{dir: dir}
+}
+
+// Get returns the local copy of f, a file of fsys, the share at shareURL,
+// fetching it unless the same version was fetched before
+func (c *Cache) Get(ctx context.Context, fsys FS, shareURL string, f File) (string, error) {
+	version := fmt.Sprintf("%s\x00%s\x00%d\x00%d\x00%s", Redact(shareURL), f.Path, f.Size, f.ModTime.Unix(), f.ETag)
+	sum := sha256.Sum256([]byte(version))
+	dir := filepath.Join(c.dir, hex.EncodeToString(sum[:16]))
+	dst := filepath.Join(dir, path.Base(f.Path))
+	if _, err := os.Stat(dst); err == nil {
+		diskcache.Touch(dst)
+		return dst, nil
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	// Hidden while written, so an interrupted fetch is not taken as cached
+	tmp, err := os.CreateTemp(dir, ".fetch-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if err := fsys.Fetch(ctx, f.Path, tmp); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		return "", err
+	}
+	return dst, nil
+}
+
+// Evict removes the least recently used copies past limit bytes, see
+// diskcache.Evict. Returns what was removed.
+func (c *Cache) Evict(limit int64) diskcache.Usage {
+	return diskcache.Evict(limit, c.dir)
+}
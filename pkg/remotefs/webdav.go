@@ -0,0 +1,194 @@
+package remotefs
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"haya-tab/pkg/metadata"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// webDAVTimeout bounds each request, listing a folder or fetching a file,
+// unless the network settings allow longer
+const webDAVTimeout = 5 * time.Minute
+
+// maxWebDAVDepth bounds the folders walked under the shared folder, in case
+// the server lists a folder inside itself
+const maxWebDAVDepth = 32
+
+// propfindBody asks for the properties Walk needs
+const propfindBody = `<?xml version="1.0" encoding="utf-8"?>
+<propfind xmlns="DAV:"><prop><resourcetype/><getcontentlength/><getlastmodified/><getetag/></prop></propfind>`
+
+// webDAV is a folder shared over WebDAV. Folders are listed one level at a
+// time (Depth: 1), since servers often refuse infinite depth.
+type webDAV struct {
+	base     *url.URL // Shared folder, its path ending with "/", without credentials
+	user     string
+	password string
+	client   *http.Client
+}
+
+// multistatus is the response to a PROPFIND request
+type multistatus struct {
+	Responses []struct {
+		Href     string `xml:"href"`
+		Propstat []struct {
+			Prop struct {
+				ResourceType struct {
+					Collection *struct{} `xml:"collection"`
+				} `xml:"resourcetype"`
+				ContentLength string `xml:"getcontentlength"`
+				LastModified  string `xml:"getlastmodified"`
+				ETag          string `xml:"getetag"`
+			} `xml:"prop"`
+			Status string `xml:"status"`
+		} `xml:"propstat"`
+	} `xml:"response"`
+}
+
+func newWebDAV(u *url.URL) *webDAV {
+	base := *u
+	switch strings.ToLower(base.Scheme) {
+	case "webdav":
+		base.Scheme = "http"
+	case "webdavs":
+		base.Scheme = "https"
+	}
+	// The proxy and TLS settings of metadata requests apply to shares too;
+	// files can take longer to fetch than a cover
+	client := *metadata.HTTPClient()
+	client.Timeout = max(client.Timeout, webDAVTimeout)
+	d := &webDAV{client: &client}
+	if base.User != nil {
+		d.user = base.User.Username()
+		d.password, _ = base.User.Password()
+		base.User = nil
+	}
+	if !strings.HasSuffix(base.Path, "/") {
+		base.Path += "/"
+	}
+	base.RawPath = ""
+	d.base = &base
+	return d
+}
+
+// Walk lists the folders breadth first
+func (d *webDAV) Walk(ctx context.Context, fn func(File) error) error {
+	dirs := []string{""}
+	for depth := 0; len(dirs) > 0 && depth <= maxWebDAVDepth; depth++ {
+		var next []string
+		for _, dir := range dirs {
+			files, subdirs, err := d.list(ctx, dir)
+			if err != nil {
+				return err
+			}
+			for _, f := range files {
+				if err := fn(f); err != nil {
+					return err
+				}
+			}
+			next = append(next, subdirs...)
+		}
+		dirs = next
+	}
+	return nil
+}
+
+// list returns the files and subfolders of dir, a slash separated path
+// relative to the shared folder, "" for the folder itself
+func (d *webDAV) list(ctx context.Context, dir string) ([]File, []string, error) {
+	req, err := d.request(ctx, "PROPFIND", dir, strings.NewReader(propfindBody))
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Depth", "1")
+	req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusMultiStatus {
+		return nil, nil, fmt.Errorf("failed to list %s: %s", d.base.JoinPath(dir).Redacted(), resp.Status)
+	}
+
+	var ms multistatus
+	if err := xml.NewDecoder(resp.Body).Decode(&ms); err != nil {
+		return nil, nil, fmt.Errorf("failed to read the listing of %s: %w", d.base.JoinPath(dir).Redacted(), err)
+	}
+	var files []File
+	var dirs []string
+	for _, r := range ms.Responses {
+		rel, ok := d.relPath(r.Href)
+		if !ok || rel == strings.TrimSuffix(dir, "/") {
+			continue // The listed folder itself
+		}
+		for _, ps := range r.Propstat {
+			if !strings.Contains(ps.Status, " 200 ") {
+				continue
+			}
+			if ps.Prop.ResourceType.Collection != nil {
+				dirs = append(dirs, rel)
+				break
+			}
+			f := File{Path: rel, ETag: strings.Trim(ps.Prop.ETag, `"`)}
+			f.Size, _ = strconv.ParseInt(ps.Prop.ContentLength, 10, 64)
+			f.ModTime, _ = http.ParseTime(ps.Prop.LastModified)
+			files = append(files, f)
+			break
+		}
+	}
+	return files, dirs, nil
+}
+
+// relPath returns the path relative to the shared folder of an href of a
+// PROPFIND response, which may be a full URL or an absolute path
+func (d *webDAV) relPath(href string) (string, bool) {
+	u, err := url.Parse(href)
+	if err != nil {
+		return "", false
+	}
+	p := path.Clean("/" + u.Path)
+	basePath := strings.TrimSuffix(d.base.Path, "/")
+	if p != basePath && !strings.HasPrefix(p, basePath+"/") {
+		return "", false
+	}
+	return strings.TrimPrefix(strings.TrimPrefix(p, basePath), "/"), true
+}
+
+// Fetch downloads a file
+func (d *webDAV) Fetch(ctx context.Context, filePath string, w io.Writer) error {
+	req, err := d.request(ctx, http.MethodGet, filePath, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch %s: %s", d.base.JoinPath(filePath).Redacted(), resp.Status)
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// request returns a request for the file or folder at rel
+func (d *webDAV) request(ctx context.Context, method, rel string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, d.base.JoinPath(rel).String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if d.user != "" {
+		req.SetBasicAuth(d.user, d.password)
+	}
+	return req, nil
+}
@@ -0,0 +1,210 @@
+package score
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/bits"
+	"slices"
+)
+
+// DefaultTempo is the tempo of scores that do not set one
+const DefaultTempo = 120
+
+// percussionChannel is the MIDI channel of General MIDI drums, the 10th
+const percussionChannel = 9
+
+// deadNoteTicks is how long a dead note sounds, a 64th
+const deadNoteTicks = TicksPerQuarter / 16
+
+// midiEvent is an event of a MIDI track at an absolute tick
+type midiEvent struct {
+	tick int
+	data []byte
+}
+
+// WriteMIDI writes the score to w as a standard MIDI file of format 1: a
+// first track with the tempo and time signatures, then one for each track
+// of tracks, indexes in s.Tracks, or of every track if tracks is empty.
+// Repeats and alternate endings are played out. Drum tracks play on the
+// General MIDI drum channel, the others each on a channel of their own
+// while there are enough.
+func (s *Score) WriteMIDI(w io.Writer, tracks []int) error {
+	if len(tracks) == 0 {
+		for i := range s.Tracks {
+			tracks = append(tracks, i)
+		}
+	}
+	if len(tracks) == 0 {
+		return fmt.Errorf("the score has no tracks")
+	}
+	for _, t := range tracks {
+		if t < 0 || t >= len(s.Tracks) {
+			return fmt.Errorf("invalid track index %d", t)
+		}
+	}
+
+	// Start of each played measure
+	order := s.PlayOrder()
+	starts := make([]int, len(order)+1)
+	for p, m := range order {
+		starts[p+1] = starts[p] + s.MasterBars[m].Ticks()
+	}
+
+	var out bytes.Buffer
+	out.WriteString("MThd")
+	binary.Write(&out, binary.BigEndian, uint32(6))
+	binary.Write(&out, binary.BigEndian, []uint16{1, uint16(len(tracks) + 1), TicksPerQuarter})
+	writeMIDITrack(&out, s.conductorEvents(order, starts))
+	channel := 0
+	for _, t := range tracks {
+		track := &s.Tracks[t]
+		ch := percussionChannel
+		if !track.Percussion {
+			ch = channel
+			if channel++; channel == percussionChannel {
+				channel++
+			}
+			channel %= 16
+		}
+		writeMIDITrack(&out, track.midiEvents(ch, order, starts))
+	}
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+// conductorEvents returns the events of the first track of a MIDI file:
+// the title, tempos and time signatures of the measures played in order,
+// starting at starts
+func (s *Score) conductorEvents(order, starts []int) []midiEvent {
+	var events []midiEvent
+	if s.Title != "" {
+		events = append(events, midiEvent{0, metaEvent(0x03, []byte(s.Title))})
+	}
+	tempos := s.Tempos()
+	tempo, numerator, denominator := 0, 0, 0
+	for p, m := range order {
+		bar := s.MasterBars[m]
+		if bar.Numerator != numerator || bar.Denominator != denominator {
+			numerator, denominator = bar.Numerator, bar.Denominator
+			// The denominator is stored as a power of 2
+			if numerator > 0 && numerator < 256 && denominator > 0 && denominator&(denominator-1) == 0 {
+				power := byte(bits.TrailingZeros(uint(denominator)))
+				events = append(events, midiEvent{starts[p], metaEvent(0x58, []byte{byte(numerator), power, 24, 8})})
+			}
+		}
+		bpm := tempos[m]
+		if bpm == 0 {
+			bpm = DefaultTempo
+		}
+		if bpm != tempo {
+			tempo = bpm
+			us := 60000000 / bpm // Microseconds per quarter note
+			events = append(events, midiEvent{starts[p], metaEvent(0x51, []byte{byte(us >> 16), byte(us >> 8), byte(us)})})
+		}
+	}
+	return events
+}
+
+// midiEvents returns the events of the track on channel ch, for the
+// measures played in order, starting at starts
+func (t *Track) midiEvents(ch int, order, starts []int) []midiEvent {
+	events := []midiEvent{{0, metaEvent(0x03, []byte(t.Name))}}
+	if !t.Percussion && t.Program >= 0 && t.Program < 128 {
+		events = append(events, midiEvent{0, []byte{0xC0 | byte(ch), byte(t.Program)}})
+	}
+
+	type sounding struct{ start, end, pitch, velocity int }
+	var notes []sounding
+	// Last note of each voice and string (or pitch), which a tied note
+	// extends
+	last := map[[2]int]int{}
+	for p, m := range order {
+		if m >= len(t.Measures) {
+			continue
+		}
+		for v, voice := range t.Measures[m].Voices {
+			tick := starts[p]
+			for _, beat := range voice.Beats {
+				for _, n := range beat.Notes {
+					pitch := t.Pitch(n)
+					if pitch < 0 || pitch > 127 {
+						continue
+					}
+					key := [2]int{v, n.String}
+					if len(t.Tuning) == 0 || t.Percussion {
+						key[1] = pitch
+					}
+					if i, ok := last[key]; ok && n.Tied {
+						notes[i].end = tick + beat.Ticks
+						continue
+					}
+					end := tick + beat.Ticks
+					if n.Dead {
+						end = tick + min(beat.Ticks, deadNoteTicks)
+					}
+					last[key] = len(notes)
+					notes = append(notes, sounding{tick, end, pitch, min(max(n.Velocity, 1), 127)})
+				}
+				tick += beat.Ticks
+			}
+		}
+	}
+
+	// A note ends before the next starts on the same tick
+	slices.SortStableFunc(notes, func(a, b sounding) int { return a.start - b.start })
+	var offs []midiEvent
+	for _, n := range notes {
+		events = append(events, midiEvent{n.start, []byte{0x90 | byte(ch), byte(n.pitch), byte(n.velocity)}})
+		offs = append(offs, midiEvent{max(n.end, n.start+1), []byte{0x80 | byte(ch), byte(n.pitch), 0}})
+	}
+	events = append(offs, events...)
+	slices.SortStableFunc(events, func(a, b midiEvent) int { return a.tick - b.tick })
+	return events
+}
+
+// metaEvent returns a meta event of the given type
+func metaEvent(kind byte, data []byte) []byte {
+	event := []byte{0xFF, kind}
+	event = appendVarInt(event, len(data))
+	return append(event, data...)
+}
+
+// writeMIDITrack writes a track chunk of events, sorted by tick
+func writeMIDITrack(out *bytes.Buffer, events []midiEvent) {
+	var data []byte
+	tick := 0
+	for _, e := range events {
+		data = appendVarInt(data, e.tick-tick)
+		data = append(data, e.data...)
+		tick = e.tick
+	}
+	data = append(data, 0x00, 0xFF, 0x2F, 0x00) // End of track
+
+	out.WriteString("MTrk")
+	binary.Write(out, binary.BigEndian, uint32(len(data)))
+	out.Write(data)
+}
+
+// appendVarInt appends v as a MIDI variable-length quantity: 7 bits per
+// byte, most significant first, the high bit set on all but the last
+func appendVarInt(b []byte, v int) []byte {
+	var groups [5]byte
+	n := 0
+	for {
+		groups[n] = byte(v & 0x7F)
+		n++
+		if v >>= 7; v == 0 {
+			break
+		}
+	}
+	for i := n - 1; i >= 0; i-- {
+		if i > 0 {
+			b = append(b, groups[i]|0x80)
+		} else {
+			b = append(b, groups[i])
+		}
+	}
+	return b
+}
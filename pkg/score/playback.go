@@ -0,0 +1,90 @@
+package score
+
+import "slices"
+
+// maxPlayedMeasures bounds the play order of a score, whatever its repeats
+const maxPlayedMeasures = 100000
+
+// PlayOrder returns the indexes of the master bars in the order they are
+// played: a repeated block is played RepeatClose times, and the measures
+// of an alternate ending only on the passes of its numbers. A close
+// without an open repeats from the end of the previous block.
+func (s *Score) PlayOrder() []int {
+	starts := map[int]int{} // Start of the block ending at each close
+	start := 0
+	for i, bar := range s.MasterBars {
+		if bar.RepeatOpen {
+			start = i
+		}
+		if bar.RepeatClose > 0 {
+			starts[i] = start
+			start = i + 1
+		}
+	}
+
+	order := make([]int, 0, len(s.MasterBars))
+	jumps := map[int]int{} // Times the block ending at a measure was repeated
+	pass := 1
+	jumped := false
+	for i := 0; i < len(s.MasterBars) && len(order) < maxPlayedMeasures; i++ {
+		bar := s.MasterBars[i]
+		if bar.RepeatOpen && !jumped {
+			pass = 1
+		}
+		jumped = false
+		if len(bar.Endings) > 0 && !slices.Contains(bar.Endings, pass) {
+			continue
+		}
+		order = append(order, i)
+
+		if bar.RepeatClose == 0 {
+			continue
+		}
+		if jumps[i] < bar.RepeatClose-1 {
+			jumps[i]++
+			pass = jumps[i] + 1
+			jumped = true
+			i = starts[i] - 1
+		} else {
+			pass = 1
+		}
+	}
+	return order
+}
+
+// Duration returns the playing time of the score in seconds, measures
+// played in PlayOrder, each at the tempo in effect at its start. Returns 0
+// if the initial tempo is unknown.
+func (s *Score) Duration() int {
+	if s.Tempo < MinTempo || s.Tempo > MaxTempo {
+		return 0
+	}
+	tempos := s.Tempos()
+	var seconds float64
+	for _, m := range s.PlayOrder() {
+		quarters := float64(s.MasterBars[m].Ticks()) / TicksPerQuarter
+		seconds += quarters * 60 / float64(tempos[m])
+	}
+	return int(seconds + 0.5)
+}
+
+// Tempos returns the tempo in effect at the start of each master bar, 0
+// until one is set if the initial tempo is unknown. Tempos out of range
+// are ignored.
+func (s *Score) Tempos() []int {
+	tempos := make([]int, len(s.MasterBars))
+	tempo := s.Tempo
+	for i, bar := range s.MasterBars {
+		if bar.Tempo >= MinTempo && bar.Tempo <= MaxTempo {
+			tempo = bar.Tempo
+		}
+		tempos[i] = tempo
+	}
+	return tempos
+}
+
+// Tempos outside this range are taken for bad data
+const (
+	MinTempo = 20
+	MaxTempo = 400
+)
@@ -0,0 +1,121 @@
+// Package score is a tab in a normalized form, whatever the format of its
+// file: tracks, measures, voices, beats and notes. It is filled by the
+// Guitar Pro parsers of pkg/metadata, and what is learned from the music
+// of a tab, e.g. its duration or a MIDI rendition, is computed from it.
+package score
+
+// TicksPerQuarter is the resolution of durations, the usual one of MIDI
+// files
+const TicksPerQuarter = 960
+
+// Score is the music of a tab
+type Score struct {
+	Title      string
+	Artist     string
+	Tempo      int // Initial tempo in quarter notes per minute, 0 if unknown
+	MasterBars []MasterBar
+	Tracks     []Track
+}
+
+// MasterBar is what a measure of the score shares across tracks
+type MasterBar struct {
+	Numerator   int    // Time signature, that of the previous measure if unchanged
+	Denominator int    // e.g. 8 in 6/8
+	Tempo       int    // Tempo set in the measure, applied from its start; 0 if unchanged
+	RepeatOpen  bool   // A repeated block starts here
+	RepeatClose int    // Number of plays of the repeated block ending here, 0 if none
+	Endings     []int  // Alternate ending numbers: the measure is only played on these passes
+	Marker      string // Section name, e.g. "Chorus"
+}
+
+// Ticks returns the length of the measure from its time signature, that of
+// 4/4 if it is invalid
+func (m MasterBar) Ticks() int {
+	if m.Numerator <= 0 || m.Denominator <= 0 {
+		return 4 * TicksPerQuarter
+	}
+	return m.Numerator * 4 * TicksPerQuarter / m.Denominator
+}
+
+// Track is a part of the score, e.g. the bass
+type Track struct {
+	Name       string
+	Program    int       // General MIDI program, -1 if unknown
+	Percussion bool      // Notes are drum sounds, see Note.Fret
+	Tuning     []int     // MIDI note of each open string, highest string first; none if not stringed
+	Capo       int       // Capo fret, 0 if none
+	Measures   []Measure // One per master bar
+}
+
+// Pitch returns the MIDI note of n on the track, or -1 if the string of n
+// does not exist
+func (t *Track) Pitch(n Note) int {
+	if t.Percussion || len(t.Tuning) == 0 {
+		return n.Fret
+	}
+	if n.String < 1 || n.String > len(t.Tuning) {
+		return -1
+	}
+	return t.Tuning[n.String-1] + t.Capo + n.Fret
+}
+
+// Measure is the content of a master bar on one track
+type Measure struct {
+	Voices []Voice
+}
+
+// Voice is a sequence of beats played from the start of the measure, next
+// to the other voices
+type Voice struct {
+	Beats []Beat
+}
+
+// Beat is a rest, or notes starting together
+type Beat struct {
+	Ticks int // Length, with dots and tuplets
+	Rest  bool
+	Notes []Note
+}
+
+// Note is a note of a beat
+type Note struct {
+	String   int  // 1 for the highest string
+	Fret     int  // The MIDI note instead on tracks without strings, e.g. drums
+	Velocity int  // MIDI velocity, 1 to 127
+	Tied     bool // Continues the note on the same string of the previous beat
+	Dead     bool // Muted, with no pitch
+}
+
+// Dynamic velocities, from ppp to fff
+var dynamics = [8]int{15, 31, 47, 63, 79, 95, 111, 127}
+
+// DefaultVelocity is the velocity of notes without dynamics, that of forte
+const DefaultVelocity = 95
+
+// DynamicVelocity returns the velocity of a dynamic from 1 (ppp) to 8
+// (fff), DefaultVelocity if out of range
+func DynamicVelocity(dynamic int) int {
+	if dynamic < 1 || dynamic > len(dynamics) {
+		return DefaultVelocity
+	}
+	return dynamics[dynamic-1]
+}
+
+// NoteTicks returns the length of a note value of 1/value of a whole note,
+// e.g. 4 for a quarter note, with dots and within a tuplet of tupletNum
+// notes in the time of tupletDen (0 for none)
+func NoteTicks(value, dots, tupletNum, tupletDen int) int {
+	if value <= 0 {
+		value = 4
+	}
+	ticks := 4 * TicksPerQuarter / value
+	dotted := ticks
+	for d := 0; d < dots; d++ {
+		ticks /= 2
+		dotted += ticks
+	}
+	if tupletNum > 0 && tupletDen > 0 {
+		dotted = dotted * tupletDen / tupletNum
+	}
+	return dotted
+}
@@ -0,0 +1,76 @@
+// Package storage keeps the files of managed tabs, the files copied into
+// the library. A stored file is identified by its locator, which the tab
+// keeps as its file path: a Backend returns it when storing a file and
+// takes it back to read or remove the file. Local, on disk, is the only
+// backend for now; remote ones (WebDAV, S3) would return their own
+// locators.
+package storage
+
+import (
+	"fmt"
+	syncpkg "haya-tab/pkg/sync"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// File is a stored file opened for reading
+type File interface {
+	io.ReadSeekCloser
+	Stat() (fs.FileInfo, error)
+}
+
+// Backend stores the files of managed tabs
+type Backend interface {
+	// Import stores a copy of the local file src as name (e.g. "<tab
+	// ID>.pdf"), replacing a file of that name. Returns the locator and
+	// the hex encoded SHA-256 of the stored file.
+	Import(src, name string) (locator, hash string, err error)
+	// Export copies a stored file to the local path dst
+	Export(locator, dst string) error
+	// Open opens a stored file for reading
+	Open(locator string) (File, error)
+	// Remove deletes a stored file
+	Remove(locator string) error
+}
+
+// Local stores files in a directory on disk. Its locators are absolute
+// paths, as the tabs stored before backends existed have.
+type Local struct {
+	dir string
+}
+
+// NewLocal returns a backend storing files in dir
+func NewLocal(dir string) *Local {
+	return &Local{dir: dir}
+}
+
+// Import copies src into the directory, see syncpkg.CopyFile
+func (l *Local) Import(src, name string) (string, string, error) {
+	if filepath.Base(name) != name {
+		return "", "", fmt.Errorf("invalid stored file name: %s", name)
+	}
+	dst := filepath.Join(l.dir, name)
+	hash, err := syncpkg.CopyFile(src, dst)
+	if err != nil {
+		return "", "", err
+	}
+	return dst, hash, nil
+}
+
+// Export copies a stored file to dst, see syncpkg.CopyFile
+func (l *Local) Export(locator, dst string) error {
+	_, err := syncpkg.CopyFile(locator, dst)
+	return err
+}
+
+// Open opens a stored file
+func (l *Local) Open(locator string) (File, error) {
+	return os.Open(locator)
+}
+
+// Remove deletes a stored file
+func (l *Local) Remove(locator string) error {
+	return os.Remove(locator)
+}
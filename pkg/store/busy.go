@@ -0,0 +1,80 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// busyRetries is how many times a write is retried when the database is
+// still locked after busy_timeout
+const busyRetries = 4
+
+// busyBackoff is the delay before the first retry of a locked write. It
+// doubles with every retry, plus up to as much random jitter so writers
+// that collided do not retry in step.
+const busyBackoff = 50 * time.Millisecond
+
+// Metrics counts the writes that found the database locked
+type Metrics struct {
+	BusyRetries  int64 `json:"busyRetries"`  // Writes retried after SQLITE_BUSY
+	BusyFailures int64 `json:"busyFailures"` // Writes still locked after every retry
+}
+
+// isBusy reports whether err is SQLITE_BUSY ("database is locked"),
+// including its extended codes
+func isBusy(err error) bool {
+	var sqliteErr *sqlite.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code()&0xff == sqlite3.SQLITE_BUSY
+}
+
+// retryBusy runs op, running it again with backoff while it fails with
+// SQLITE_BUSY
+func (s *DBStore) retryBusy(op func() error) error {
+	err := op()
+	for attempt := 0; attempt < busyRetries && isBusy(err); attempt++ {
+		s.busyRetries.Add(1)
+		delay := busyBackoff << attempt
+		time.Sleep(delay + rand.N(delay))
+		err = op()
+	}
+	if isBusy(err) {
+		s.busyFailures.Add(1)
+	}
+	return err
+}
+
+// exec is db.Exec, retried while the database is locked
+func (s *DBStore) exec(query string, args ...interface{}) (sql.Result, error) {
+	var result sql.Result
+	err := s.retryBusy(func() (err error) {
+		result, err = s.db.Exec(query, args...)
+		return err
+	})
+	return result, err
+}
+
+// begin starts a write transaction, retried while the database is locked.
+// Write transactions take the write lock up front (BEGIN IMMEDIATE, see
+// Initialize), so a locked database fails here rather than halfway through
+// the transaction, where it could not be retried.
+func (s *DBStore) begin() (*sql.Tx, error) {
+	var tx *sql.Tx
+	err := s.retryBusy(func() (err error) {
+		tx, err = s.db.Begin()
+		return err
+	})
+	return tx, err
+}
+
+// Metrics returns the lock contention counters since the store was opened
+func (s *DBStore) Metrics() Metrics {
+	return Metrics{
+		BusyRetries:  s.busyRetries.Load(),
+		BusyFailures: s.busyFailures.Load(),
+	}
+}
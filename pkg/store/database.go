@@ -1,64 +1,97 @@
 package store
 
 import (
+	"cmp"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	_ "modernc.org/sqlite"
 )
 
+// DBStore is the SQLite store. Writes hold mu and go through db, retried
+// while another process holds the database lock; reads go through the
+// read-only rdb pool without the lock, which WAL mode lets read while a
+// write is in progress.
 type DBStore struct {
-	mu       sync.Mutex
+	mu       sync.RWMutex // Serializes writes and guards Settings
 	db       *sql.DB
+	rdb      *sql.DB // Read-only pool; with WAL, reads run alongside a write
 	dbPath   string
+	memory   bool // See NewMemoryDBStore
 	Settings Settings
+
+	settingsExtra map[string]interface{} // Settings keys unknown to Settings
+
+	stmtMu sync.Mutex
+	stmts  map[string]*sql.Stmt // Prepared statements of hot queries, by query
+
+	busyRetries  atomic.Int64 // See Metrics
+	busyFailures atomic.Int64
+}
+
+// connPragmas configure every pooled connection; journal_mode is stored in
+// the database file and set once in Initialize
+var connPragmas = []string{
+	"busy_timeout(5000)",
+	"foreign_keys(ON)",
+	"synchronous(NORMAL)",
+	"cache_size(-64000)", // 64MB cache
+	"temp_store(MEMORY)",
 }
 
 func NewDBStore(dbPath string) *DBStore {
 	return &DBStore{
 		dbPath: dbPath,
 		Settings: Settings{
-			Theme:        "system",
-			OpenMethod:   "inner",
-			OpenGpMethod: "inner",
-			SyncStrategy: "skip",
-			SyncPaths:    []string{},
-			KeyBindings: KeyBindings{
-				ScrollDown:      "j",
-				ScrollUp:        "k",
-				Metronome:       "m",
-				PlayPause:       "p",
-				Stop:            "o",
-				BpmPlus:         "l",
-				BpmMinus:        "h",
-				ToggleLoop:      "r",
-				ClearSelection:  "escape",
-				JumpToBar:       "t",
-				JumpToStart:     "i",
-				AutoScroll:      "n",
-				ScrollSpeedUp:   ",",
-				ScrollSpeedDown: ".",
-			},
+			Theme:            "system",
+			OpenMethod:       "inner",
+			OpenGpMethod:     "inner",
+			SyncStrategy:     "skip",
+			AutoSyncInterval: 6,
+			KeyBindings:      DefaultKeyBindings(),
+			KeyProfile:       DefaultKeyProfile,
+			PedalBindings:    PedalBindings{},
+			PedalProfiles:    []PedalProfile{},
+			CoverRegions:     []CoverRegion{{Country: "US", Lang: "en_us"}},
+			CoverDailyBudget: 500,
+			CacheLimitMB:     256,
 		},
 	}
 }
 
+// NewMemoryDBStore creates a store whose database lives in memory until it
+// is closed, for tests and benchmarks. Stores of the same name share it.
+func NewMemoryDBStore(name string) *DBStore {
+	s := NewDBStore("file:/" + name + "?vfs=memdb")
+	s.memory = true
+	return s
+}
+
 // Initialize creates the database and tables
 func (s *DBStore) Initialize() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	// Ensure directory exists
-	dir := filepath.Dir(s.dbPath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
+	sep := "?"
+	if s.memory {
+		sep = "&"
+	} else if err := os.MkdirAll(filepath.Dir(s.dbPath), 0755); err != nil {
 		return fmt.Errorf("failed to create data directory: %w", err)
 	}
 
-	db, err := sql.Open("sqlite", s.dbPath)
+	dsn := s.dbPath + sep + "_pragma=" + strings.Join(connPragmas, "&_pragma=")
+	db, err := sql.Open("sqlite", dsn+"&_txlock=immediate")
 	if err != nil {
 		return fmt.Errorf("failed to open database: %w", err)
 	}
@@ -66,24 +99,10 @@ func (s *DBStore) Initialize() error {
 
 	// Enable WAL mode for better read/write concurrency
 	// This allows reading while writing, preventing UI freezes during sync
-	if _, err := s.db.Exec("PRAGMA journal_mode=WAL"); err != nil {
+	if _, err := s.exec("PRAGMA journal_mode=WAL"); err != nil {
 		return fmt.Errorf("failed to enable WAL mode: %w", err)
 	}
 
-	// Optimize SQLite settings for better performance
-	if _, err := s.db.Exec("PRAGMA synchronous=NORMAL"); err != nil {
-		return fmt.Errorf("failed to set synchronous mode: %w", err)
-	}
-	if _, err := s.db.Exec("PRAGMA cache_size=-64000"); err != nil { // 64MB cache
-		return fmt.Errorf("failed to set cache size: %w", err)
-	}
-	if _, err := s.db.Exec("PRAGMA temp_store=MEMORY"); err != nil {
-		return fmt.Errorf("failed to set temp store: %w", err)
-	}
-	if _, err := s.db.Exec("PRAGMA foreign_keys = ON"); err != nil {
-		return fmt.Errorf("failed to enable foreign keys: %w", err)
-	}
-
 	// Create tables
 	if err := s.createTables(); err != nil {
 		return fmt.Errorf("failed to create tables: %w", err)
@@ -94,6 +113,13 @@ func (s *DBStore) Initialize() error {
 		return fmt.Errorf("failed to run migrations: %w", err)
 	}
 
+	// Open the read pool once the schema is in place
+	rdb, err := sql.Open("sqlite", dsn+"&_pragma=query_only(ON)")
+	if err != nil {
+		return fmt.Errorf("failed to open read connections: %w", err)
+	}
+	s.rdb = rdb
+
 	// Load settings into memory
 	if err := s.loadSettings(); err != nil {
 		return fmt.Errorf("failed to load settings: %w", err)
@@ -118,14 +144,45 @@ func (s *DBStore) createTables() error {
 		language TEXT DEFAULT '',
 		tag TEXT DEFAULT '',
 		added_at INTEGER DEFAULT 0,
-		last_opened INTEGER DEFAULT 0
+		last_opened INTEGER DEFAULT 0,
+		file_hash TEXT DEFAULT '',
+		is_missing INTEGER DEFAULT 0,
+		practice_status TEXT DEFAULT '',
+		rating INTEGER DEFAULT 0,
+		difficulty TEXT DEFAULT '',
+		notes TEXT DEFAULT '',
+		tracks_scanned INTEGER DEFAULT 0,
+		needs_review INTEGER DEFAULT 0,
+		song_key TEXT DEFAULT '',
+		capo INTEGER DEFAULT 0,
+		script TEXT, -- NULL until detected, see GetTabsMissingScript
+		genre TEXT DEFAULT '',
+		year INTEGER DEFAULT 0,
+		enriched INTEGER DEFAULT 0, -- Looked up by the metadata enrichment, see GetTabsToEnrich
+		content TEXT, -- Lyrics and words of the file, NULL until extracted, see GetTabsMissingContent
+		file_size INTEGER, -- Bytes, NULL until measured, see GetTabsMissingFileSize
+		cover_color TEXT, -- "#rrggbb", NULL until computed, see GetTabsMissingCoverColor
+		words TEXT DEFAULT '', -- Credits of GP files, see SetTabCredits
+		music TEXT DEFAULT '',
+		copyright TEXT DEFAULT '',
+		tab_author TEXT DEFAULT '',
+		notice TEXT DEFAULT '',
+		bpm INTEGER DEFAULT 0,
+		duration INTEGER DEFAULT 0, -- Estimated playing time of GP files in seconds, see SetTabDuration
+		source_path TEXT DEFAULT '' -- Original of a converted file
 	);
 
 	CREATE TABLE IF NOT EXISTS categories (
 		id TEXT PRIMARY KEY,
 		name TEXT NOT NULL,
 		parent_id TEXT DEFAULT '',
-		cover_path TEXT DEFAULT ''
+		cover_path TEXT DEFAULT '',
+		open_method TEXT DEFAULT '',
+		open_gp_method TEXT DEFAULT '',
+		color TEXT DEFAULT '',
+		icon TEXT DEFAULT '',
+		sort_order INTEGER DEFAULT 0,
+		pinned INTEGER DEFAULT 0
 	);
 
 	CREATE TABLE IF NOT EXISTS tab_categories (
@@ -137,6 +194,209 @@ func (s *DBStore) createTables() error {
 		FOREIGN KEY(category_id) REFERENCES categories(id) ON DELETE CASCADE
 	);
 
+	CREATE TABLE IF NOT EXISTS tab_tracks (
+		tab_id TEXT,
+		track_index INTEGER,
+		name TEXT DEFAULT '',
+		instrument TEXT DEFAULT '',
+		program INTEGER DEFAULT -1,
+		kind TEXT DEFAULT '',
+		string_count INTEGER DEFAULT 0,
+		tuning TEXT DEFAULT '',
+		tuning_name TEXT DEFAULT '',
+		capo INTEGER DEFAULT 0,
+		is_percussion INTEGER DEFAULT 0,
+		PRIMARY KEY (tab_id, track_index),
+		FOREIGN KEY(tab_id) REFERENCES tabs(id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS tab_print_settings (
+		tab_id TEXT PRIMARY KEY,
+		paper_size TEXT DEFAULT 'A4',
+		margin_top REAL DEFAULT 10,
+		margin_right REAL DEFAULT 10,
+		margin_bottom REAL DEFAULT 10,
+		margin_left REAL DEFAULT 10,
+		pages_per_sheet INTEGER DEFAULT 1,
+		tracks TEXT DEFAULT '',
+		FOREIGN KEY(tab_id) REFERENCES tabs(id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS tab_notes (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		tab_id TEXT NOT NULL,
+		content TEXT NOT NULL,
+		created_at INTEGER DEFAULT 0,
+		updated_at INTEGER DEFAULT 0,
+		FOREIGN KEY(tab_id) REFERENCES tabs(id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS attachments (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		tab_id TEXT NOT NULL,
+		name TEXT DEFAULT '',
+		file_path TEXT NOT NULL,
+		added_at INTEGER DEFAULT 0,
+		FOREIGN KEY(tab_id) REFERENCES tabs(id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS tab_links (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		tab_id TEXT NOT NULL,
+		title TEXT DEFAULT '',
+		url TEXT NOT NULL,
+		added_at INTEGER DEFAULT 0,
+		FOREIGN KEY(tab_id) REFERENCES tabs(id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS practice_queue (
+		position INTEGER PRIMARY KEY,
+		tab_id TEXT NOT NULL,
+		done INTEGER DEFAULT 0,
+		FOREIGN KEY(tab_id) REFERENCES tabs(id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS cover_queue (
+		tab_id TEXT PRIMARY KEY,
+		queued_at INTEGER DEFAULT 0,
+		fetched_at INTEGER DEFAULT 0,
+		FOREIGN KEY(tab_id) REFERENCES tabs(id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS cover_sweep (
+		tab_id TEXT PRIMARY KEY,
+		done_at INTEGER DEFAULT 0,
+		found INTEGER DEFAULT 0,
+		FOREIGN KEY(tab_id) REFERENCES tabs(id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS cover_sources (
+		tab_id TEXT PRIMARY KEY,
+		artist TEXT DEFAULT '',
+		album TEXT DEFAULT '',
+		title TEXT DEFAULT '',
+		checked_at INTEGER DEFAULT 0,
+		FOREIGN KEY(tab_id) REFERENCES tabs(id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS cover_fetch_attempts (
+		tab_id TEXT PRIMARY KEY,
+		attempts INTEGER DEFAULT 0,
+		last_error TEXT DEFAULT '',
+		next_attempt_at INTEGER DEFAULT 0,
+		FOREIGN KEY(tab_id) REFERENCES tabs(id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS category_templates (
+		id TEXT PRIMARY KEY,
+		name TEXT NOT NULL,
+		folders TEXT DEFAULT '[]'
+	);
+
+	CREATE TABLE IF NOT EXISTS keybinding_profiles (
+		name TEXT PRIMARY KEY,
+		bindings TEXT DEFAULT '{}',
+		updated_at INTEGER DEFAULT 0
+	);
+
+	CREATE TABLE IF NOT EXISTS students (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		notes TEXT DEFAULT '',
+		created_at INTEGER DEFAULT 0
+	);
+
+	CREATE TABLE IF NOT EXISTS assignments (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		student_id INTEGER NOT NULL,
+		tab_id TEXT NOT NULL,
+		status TEXT DEFAULT 'assigned',
+		due_at INTEGER DEFAULT 0,
+		note TEXT DEFAULT '',
+		assigned_at INTEGER DEFAULT 0,
+		UNIQUE(student_id, tab_id),
+		FOREIGN KEY(student_id) REFERENCES students(id) ON DELETE CASCADE,
+		FOREIGN KEY(tab_id) REFERENCES tabs(id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS practice_time (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		tab_id TEXT NOT NULL,
+		started_at INTEGER DEFAULT 0,
+		seconds INTEGER DEFAULT 0,
+		FOREIGN KEY(tab_id) REFERENCES tabs(id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS tab_history (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		tab_id TEXT NOT NULL,
+		opened_at INTEGER NOT NULL,
+		seconds INTEGER DEFAULT 0, -- Time open in a viewer, see AddPracticeTime
+		FOREIGN KEY(tab_id) REFERENCES tabs(id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS reminders (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		tab_id TEXT, -- NULL for a category
+		category_id TEXT, -- NULL for a tab
+		weekdays TEXT DEFAULT '[]',
+		date TEXT DEFAULT '',
+		time TEXT DEFAULT '',
+		note TEXT DEFAULT '',
+		next_at INTEGER DEFAULT 0,
+		created_at INTEGER DEFAULT 0,
+		FOREIGN KEY(tab_id) REFERENCES tabs(id) ON DELETE CASCADE,
+		FOREIGN KEY(category_id) REFERENCES categories(id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS sync_paths (
+		path TEXT PRIMARY KEY,
+		recursive INTEGER DEFAULT 1,
+		mode TEXT DEFAULT 'link',
+		category_strategy TEXT DEFAULT '',
+		category_root TEXT DEFAULT '',
+		include TEXT DEFAULT '[]',
+		exclude TEXT DEFAULT '[]',
+		position INTEGER DEFAULT 0
+	);
+
+	CREATE TABLE IF NOT EXISTS copied_files (
+		path TEXT PRIMARY KEY,
+		tab_id TEXT DEFAULT '',
+		copied_at INTEGER DEFAULT 0
+	);
+
+	CREATE TABLE IF NOT EXISTS archive_entries (
+		archive_path TEXT NOT NULL,
+		entry TEXT NOT NULL,
+		tab_id TEXT DEFAULT '',
+		imported_at INTEGER DEFAULT 0,
+		PRIMARY KEY(archive_path, entry)
+	);
+
+	CREATE TABLE IF NOT EXISTS sync_history (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		started_at INTEGER NOT NULL,
+		duration_ms INTEGER DEFAULT 0,
+		total INTEGER DEFAULT 0,
+		added INTEGER DEFAULT 0,
+		updated INTEGER DEFAULT 0,
+		skipped INTEGER DEFAULT 0,
+		errors INTEGER DEFAULT 0,
+		pending INTEGER DEFAULT 0,
+		conflicts INTEGER DEFAULT 0,
+		report TEXT DEFAULT '',
+		file_errors TEXT DEFAULT '[]'
+	);
+
+	CREATE TABLE IF NOT EXISTS pending_conflicts (
+		path TEXT PRIMARY KEY,
+		title TEXT NOT NULL,
+		existing_path TEXT DEFAULT '',
+		found_at INTEGER DEFAULT 0,
+		resolution TEXT DEFAULT ''
+	);
+
 	CREATE TABLE IF NOT EXISTS settings (
 		key TEXT PRIMARY KEY,
 		value TEXT
@@ -146,218 +406,121 @@ func (s *DBStore) createTables() error {
 	CREATE INDEX IF NOT EXISTS idx_categories_parent ON categories(parent_id);
 	CREATE INDEX IF NOT EXISTS idx_tab_categories_tab ON tab_categories(tab_id);
 	CREATE INDEX IF NOT EXISTS idx_tab_categories_cat ON tab_categories(category_id);
+	CREATE INDEX IF NOT EXISTS idx_tab_tracks_kind ON tab_tracks(kind, string_count);
+	CREATE INDEX IF NOT EXISTS idx_tab_notes_tab ON tab_notes(tab_id);
+	CREATE INDEX IF NOT EXISTS idx_attachments_tab ON attachments(tab_id);
+	CREATE INDEX IF NOT EXISTS idx_tab_links_tab ON tab_links(tab_id);
+	CREATE INDEX IF NOT EXISTS idx_assignments_tab ON assignments(tab_id);
+	CREATE INDEX IF NOT EXISTS idx_practice_time_started ON practice_time(started_at);
+	CREATE INDEX IF NOT EXISTS idx_tab_history_tab ON tab_history(tab_id, opened_at);
+	CREATE INDEX IF NOT EXISTS idx_tab_history_opened ON tab_history(opened_at);
+	CREATE INDEX IF NOT EXISTS idx_reminders_next ON reminders(next_at);
 	`
 
-	if _, err := s.db.Exec(schema); err != nil {
+	if _, err := s.exec(schema); err != nil {
 		return err
 	}
 
 	// Create FTS5 virtual table for full-text search
-	// Using content= option for external content table (keeps data in sync with tabs table)
-	ftsSchema := `
+	if _, err := s.exec(ftsSchema); err != nil {
+		return err
+	}
+	_, err := s.exec(ftsVocabSchema)
+	return err
+}
+
+// ftsSchema creates the FTS5 virtual table for full-text search.
+// Using content= option for external content table (keeps data in sync with tabs table).
+// tabs.notes holds the text of the tab's notes (see refreshNotesIndex) and
+// tabs.content the lyrics of its file (see SetTabContent).
+const ftsSchema = `
 	CREATE VIRTUAL TABLE IF NOT EXISTS tabs_fts USING fts5(
-		title, artist, album, tag,
+		title, artist, album, tag, notes, genre, content,
 		content='tabs',
 		content_rowid='rowid'
 	);
 
 	-- Triggers to keep FTS index in sync with main table
 	CREATE TRIGGER IF NOT EXISTS tabs_ai AFTER INSERT ON tabs BEGIN
-		INSERT INTO tabs_fts(rowid, title, artist, album, tag) 
-		VALUES (NEW.rowid, NEW.title, NEW.artist, NEW.album, NEW.tag);
+		INSERT INTO tabs_fts(rowid, title, artist, album, tag, notes, genre, content)
+		VALUES (NEW.rowid, NEW.title, NEW.artist, NEW.album, NEW.tag, NEW.notes, NEW.genre, NEW.content);
 	END;
 
 	CREATE TRIGGER IF NOT EXISTS tabs_ad AFTER DELETE ON tabs BEGIN
-		INSERT INTO tabs_fts(tabs_fts, rowid, title, artist, album, tag) 
-		VALUES ('delete', OLD.rowid, OLD.title, OLD.artist, OLD.album, OLD.tag);
+		INSERT INTO tabs_fts(tabs_fts, rowid, title, artist, album, tag, notes, genre, content)
+		VALUES ('delete', OLD.rowid, OLD.title, OLD.artist, OLD.album, OLD.tag, OLD.notes, OLD.genre, OLD.content);
 	END;
 
 	CREATE TRIGGER IF NOT EXISTS tabs_au AFTER UPDATE ON tabs BEGIN
-		INSERT INTO tabs_fts(tabs_fts, rowid, title, artist, album, tag) 
-		VALUES ('delete', OLD.rowid, OLD.title, OLD.artist, OLD.album, OLD.tag);
-		INSERT INTO tabs_fts(rowid, title, artist, album, tag) 
-		VALUES (NEW.rowid, NEW.title, NEW.artist, NEW.album, NEW.tag);
+		INSERT INTO tabs_fts(tabs_fts, rowid, title, artist, album, tag, notes, genre, content)
+		VALUES ('delete', OLD.rowid, OLD.title, OLD.artist, OLD.album, OLD.tag, OLD.notes, OLD.genre, OLD.content);
+		INSERT INTO tabs_fts(rowid, title, artist, album, tag, notes, genre, content)
+		VALUES (NEW.rowid, NEW.title, NEW.artist, NEW.album, NEW.tag, NEW.notes, NEW.genre, NEW.content);
 	END;
-	`
-
-	_, err := s.db.Exec(ftsSchema)
-	return err
-}
-
-// runMigrations handles schema updates for existing databases
-func (s *DBStore) runMigrations() error {
-	// Add tag column if it doesn't exist (for databases created before this version)
-	_, err := s.db.Exec("ALTER TABLE tabs ADD COLUMN tag TEXT DEFAULT ''")
-	if err != nil {
-		// Ignore error if column already exists
-		if !strings.Contains(err.Error(), "duplicate column name") {
-			// It's okay, column might already exist
-		}
-	}
-
-	// Add added_at column
-	_, err = s.db.Exec("ALTER TABLE tabs ADD COLUMN added_at INTEGER DEFAULT 0")
-	if err != nil {
-		if !strings.Contains(err.Error(), "duplicate column name") {
-			// It's okay
-		}
-	}
-
-	// Add last_opened column
-	_, err = s.db.Exec("ALTER TABLE tabs ADD COLUMN last_opened INTEGER DEFAULT 0")
-	if err != nil {
-		if !strings.Contains(err.Error(), "duplicate column name") {
-			// It's okay
-		}
-	}
+`
 
-	// Add cover_path column to categories
-	_, err = s.db.Exec("ALTER TABLE categories ADD COLUMN cover_path TEXT DEFAULT ''")
-	if err != nil {
-		if !strings.Contains(err.Error(), "duplicate column name") {
-			// It's okay
-		}
+// Close closes the database connection
+func (s *DBStore) Close() error {
+	s.stmtMu.Lock()
+	for _, stmt := range s.stmts {
+		stmt.Close()
 	}
+	s.stmts = nil
+	s.stmtMu.Unlock()
 
-	// Rebuild FTS index if needed (for existing databases upgrading to FTS5)
-	// This populates the FTS table with any existing tab data
-	if _, err := s.db.Exec("INSERT INTO tabs_fts(tabs_fts) VALUES('rebuild')"); err != nil {
-		// Ignore errors - table might not exist or already be populated
+	if s.rdb != nil {
+		s.rdb.Close()
 	}
-
-	// Create tab_categories if not exists (handled in createTables, but good for safety if adding later)
-	// Migrate existing category_id to tab_categories
-	_, err = s.db.Exec(`
-		INSERT INTO tab_categories (tab_id, category_id, added_at)
-		SELECT id, category_id, added_at FROM tabs
-		WHERE category_id != '' AND category_id IS NOT NULL
-		AND NOT EXISTS (
-			SELECT 1 FROM tab_categories tc WHERE tc.tab_id = tabs.id AND tc.category_id = tabs.category_id
-		)
-	`)
-	if err != nil {
-		// Log error or handle gracefully
-		fmt.Printf("Migration warning: failed to migrate categories: %v\n", err)
+	if s.db != nil {
+		return s.db.Close()
 	}
-
 	return nil
 }
 
-func (s *DBStore) loadSettings() error {
-	rows, err := s.db.Query("SELECT key, value FROM settings")
-	if err != nil {
-		return err
-	}
-	defer rows.Close()
-
-	settings := make(map[string]string)
-	for rows.Next() {
-		var key, value string
-		if err := rows.Scan(&key, &value); err != nil {
-			return err
-		}
-		settings[key] = value
-	}
+// prepared returns the cached prepared statement of query on the read
+// pool, preparing it on first use
+func (s *DBStore) prepared(query string) (*sql.Stmt, error) {
+	s.stmtMu.Lock()
+	defer s.stmtMu.Unlock()
 
-	if v, ok := settings["theme"]; ok {
-		s.Settings.Theme = v
+	if stmt, ok := s.stmts[query]; ok {
+		return stmt, nil
 	}
-	if v, ok := settings["background"]; ok {
-		s.Settings.Background = v
-	}
-	if v, ok := settings["bgType"]; ok {
-		s.Settings.BgType = v
-	}
-	if v, ok := settings["openMethod"]; ok {
-		s.Settings.OpenMethod = v
-	}
-	if v, ok := settings["openGpMethod"]; ok {
-		s.Settings.OpenGpMethod = v
-	}
-	if v, ok := settings["audioDevice"]; ok {
-		s.Settings.AudioDevice = v
-	}
-	if v, ok := settings["autoSyncEnabled"]; ok {
-		s.Settings.AutoSyncEnabled = (v == "true")
-	}
-	if v, ok := settings["autoSyncFrequency"]; ok {
-		s.Settings.AutoSyncFrequency = v
-	}
-	if v, ok := settings["lastSyncTime"]; ok {
-		var t int64
-		fmt.Sscanf(v, "%d", &t)
-		s.Settings.LastSyncTime = t
-	}
-	if v, ok := settings["syncStrategy"]; ok {
-		s.Settings.SyncStrategy = v
+	stmt, err := s.rdb.Prepare(query)
+	if err != nil {
+		return nil, err
 	}
-	if v, ok := settings["syncPaths"]; ok && v != "" {
-		s.Settings.SyncPaths = strings.Split(v, "|")
+	if s.stmts == nil {
+		s.stmts = map[string]*sql.Stmt{}
 	}
+	s.stmts[query] = stmt
+	return stmt, nil
+}
 
-	// Load key bindings
-	if v, ok := settings["keyBindings.scrollDown"]; ok && v != "" {
-		s.Settings.KeyBindings.ScrollDown = v
-	}
-	if v, ok := settings["keyBindings.scrollUp"]; ok && v != "" {
-		s.Settings.KeyBindings.ScrollUp = v
-	}
-	if v, ok := settings["keyBindings.metronome"]; ok && v != "" {
-		s.Settings.KeyBindings.Metronome = v
-	}
-	if v, ok := settings["keyBindings.playPause"]; ok && v != "" {
-		s.Settings.KeyBindings.PlayPause = v
-	}
-	if v, ok := settings["keyBindings.stop"]; ok && v != "" {
-		s.Settings.KeyBindings.Stop = v
-	}
-	if v, ok := settings["keyBindings.bpmPlus"]; ok && v != "" {
-		s.Settings.KeyBindings.BpmPlus = v
-	}
-	if v, ok := settings["keyBindings.bpmMinus"]; ok && v != "" {
-		s.Settings.KeyBindings.BpmMinus = v
-	}
-	if v, ok := settings["keyBindings.toggleLoop"]; ok && v != "" {
-		s.Settings.KeyBindings.ToggleLoop = v
-	}
-	if v, ok := settings["keyBindings.clearSelection"]; ok && v != "" {
-		s.Settings.KeyBindings.ClearSelection = v
-	}
-	if v, ok := settings["keyBindings.jumpToBar"]; ok && v != "" {
-		s.Settings.KeyBindings.JumpToBar = v
-	}
-	if v, ok := settings["keyBindings.jumpToStart"]; ok && v != "" {
-		s.Settings.KeyBindings.JumpToStart = v
-	}
-	if v, ok := settings["keyBindings.autoScroll"]; ok && v != "" {
-		s.Settings.KeyBindings.AutoScroll = v
-	}
-	if v, ok := settings["keyBindings.scrollSpeedUp"]; ok && v != "" {
-		s.Settings.KeyBindings.ScrollSpeedUp = v
-	}
-	if v, ok := settings["keyBindings.scrollSpeedDown"]; ok && v != "" {
-		s.Settings.KeyBindings.ScrollSpeedDown = v
+// queryRow is rdb.QueryRow through a cached prepared statement, for queries
+// run many times per sync
+func (s *DBStore) queryRow(query string, args ...interface{}) *sql.Row {
+	stmt, err := s.prepared(query)
+	if err != nil {
+		return s.rdb.QueryRow(query, args...) // Reports the error on Scan
 	}
-
-	return nil
+	return stmt.QueryRow(args...)
 }
 
-// Close closes the database connection
-func (s *DBStore) Close() error {
-	if s.db != nil {
-		return s.db.Close()
+// query is rdb.Query through a cached prepared statement
+func (s *DBStore) query(query string, args ...interface{}) (*sql.Rows, error) {
+	stmt, err := s.prepared(query)
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	return stmt.Query(args...)
 }
 
 // === Tab Operations ===
 
 func (s *DBStore) GetTabs() ([]Tab, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	rows, err := s.db.Query(`
-		SELECT id, title, artist, album, file_path, type, is_managed, cover_path, category_id, country, language, COALESCE(tag, ''), added_at, last_opened 
+	rows, err := s.rdb.Query(`
+		SELECT id, title, artist, album, file_path, type, is_managed, cover_path, category_id, country, language, COALESCE(tag, ''), added_at, last_opened, COALESCE(file_hash, ''), is_missing, practice_status, rating, difficulty, needs_review, song_key, capo, COALESCE(script, ''), genre, year, COALESCE(file_size, 0), COALESCE(cover_color, ''), bpm, duration, source_path 
 		FROM tabs
 	`)
 	if err != nil {
@@ -366,23 +529,23 @@ func (s *DBStore) GetTabs() ([]Tab, error) {
 	defer rows.Close()
 
 	tabs := []Tab{}
-	tabMap := make(map[string]*Tab) // Pointer map for easy update
+	tabIndex := make(map[string]int) // Index in tabs: pointers would go stale as tabs grows
 
 	for rows.Next() {
 		var t Tab
 		var isManaged int
 		var legacyCatID sql.NullString // Handle legacy or null category_id
-		if err := rows.Scan(&t.ID, &t.Title, &t.Artist, &t.Album, &t.FilePath, &t.Type, &isManaged, &t.CoverPath, &legacyCatID, &t.Country, &t.Language, &t.Tag, &t.AddedAt, &t.LastOpened); err != nil {
+		if err := rows.Scan(&t.ID, &t.Title, &t.Artist, &t.Album, &t.FilePath, &t.Type, &isManaged, &t.CoverPath, &legacyCatID, &t.Country, &t.Language, &t.Tag, &t.AddedAt, &t.LastOpened, &t.FileHash, &t.IsMissing, &t.PracticeStatus, &t.Rating, &t.Difficulty, &t.NeedsReview, &t.Key, &t.Capo, &t.Script, &t.Genre, &t.Year, &t.FileSize, &t.CoverColor, &t.Bpm, &t.Duration, &t.SourcePath); err != nil {
 			return nil, err
 		}
 		t.IsManaged = isManaged == 1
 		t.CategoryIDs = []string{} // Initialize
+		tabIndex[t.ID] = len(tabs)
 		tabs = append(tabs, t)
-		tabMap[t.ID] = &tabs[len(tabs)-1]
 	}
 
 	// Fetch all categories
-	catRows, err := s.db.Query("SELECT tab_id, category_id FROM tab_categories")
+	catRows, err := s.rdb.Query("SELECT tab_id, category_id FROM tab_categories")
 	if err != nil {
 		// Just return tabs without categories if this fails, or log?
 		// For now return error
@@ -393,8 +556,8 @@ func (s *DBStore) GetTabs() ([]Tab, error) {
 	for catRows.Next() {
 		var tID, cID string
 		if err := catRows.Scan(&tID, &cID); err == nil {
-			if tab, ok := tabMap[tID]; ok {
-				tab.CategoryIDs = append(tab.CategoryIDs, cID)
+			if i, ok := tabIndex[tID]; ok {
+				tabs[i].CategoryIDs = append(tabs[i].CategoryIDs, cID)
 			}
 		}
 	}
@@ -402,13 +565,184 @@ func (s *DBStore) GetTabs() ([]Tab, error) {
 	return tabs, nil
 }
 
-func (s *DBStore) GetTabsPaginated(categoryId string, page, pageSize int, searchQuery string, filterBy []string, isGlobal bool, sortBy string, sortDesc bool) ([]Tab, int, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// difficultyRank orders difficulties from unset (0) to advanced (3)
+const difficultyRank = "(CASE tabs.difficulty WHEN 'beginner' THEN 1 WHEN 'intermediate' THEN 2 WHEN 'advanced' THEN 3 ELSE 0 END)"
+
+// tabSortKeys are the orderings of GetTabsPaginated by sort key
+var tabSortKeys = map[string]string{
+	"title":       "tabs.title",
+	"artist":      "tabs.artist COLLATE NOCASE",
+	"album":       "tabs.album COLLATE NOCASE",
+	"type":        "tabs.type",
+	"added_at":    "tabs.added_at",
+	"last_opened": "tabs.last_opened",
+	"rating":      "tabs.rating",
+	"difficulty":  difficultyRank,
+	"file_size":   "COALESCE(tabs.file_size, 0)",
+	"duration":    "tabs.duration",
+}
+
+// tabOrderBy returns the ORDER BY clause of a sort key, by title if the key
+// is unknown. Ties are ordered by title then ID, so pages do not overlap.
+func tabOrderBy(sortBy string, sortDesc bool) string {
+	column, ok := tabSortKeys[sortBy]
+	if !ok {
+		column = tabSortKeys["title"]
+	}
+	direction := "ASC"
+	if sortDesc {
+		direction = "DESC"
+	}
+	return column + " " + direction + ", tabs.title ASC, tabs.id ASC"
+}
+
+// CompareTabs orders a and b as tabOrderBy does in SQL, or as
+// getTabsPaginatedFTS does for searches without a sort key, for merging the
+// pages of two libraries: it returns a negative number if a comes first
+func CompareTabs(a, b Tab, sortBy string, sortDesc bool) int {
+	if _, ok := tabSortKeys[sortBy]; !ok && (a.rank != 0 || b.rank != 0) {
+		// Best matches first
+		return cmp.Or(cmp.Compare(a.rank, b.rank), strings.Compare(a.Title, b.Title), strings.Compare(a.ID, b.ID))
+	}
+	var c int
+	switch sortBy {
+	case "artist":
+		c = compareNoCase(a.Artist, b.Artist)
+	case "album":
+		c = compareNoCase(a.Album, b.Album)
+	case "type":
+		c = strings.Compare(a.Type, b.Type)
+	case "added_at":
+		c = cmp.Compare(a.AddedAt, b.AddedAt)
+	case "last_opened":
+		c = cmp.Compare(a.LastOpened, b.LastOpened)
+	case "rating":
+		c = cmp.Compare(a.Rating, b.Rating)
+	case "difficulty":
+		c = cmp.Compare(DifficultyLevel(a.Difficulty), DifficultyLevel(b.Difficulty))
+	case "file_size":
+		c = cmp.Compare(a.FileSize, b.FileSize)
+	case "duration":
+		c = cmp.Compare(a.Duration, b.Duration)
+	default:
+		c = strings.Compare(a.Title, b.Title)
+	}
+	if sortDesc {
+		c = -c
+	}
+	if c == 0 {
+		c = strings.Compare(a.Title, b.Title)
+	}
+	if c == 0 {
+		c = strings.Compare(a.ID, b.ID)
+	}
+	return c
+}
+
+// compareNoCase compares as the NOCASE collation of SQLite, which folds
+// ASCII letters only
+func compareNoCase(a, b string) int {
+	fold := func(s string) string {
+		return strings.Map(func(r rune) rune {
+			if r >= 'A' && r <= 'Z' {
+				return r + 'a' - 'A'
+			}
+			return r
+		}, s)
+	}
+	return strings.Compare(fold(a), fold(b))
+}
+
+// DifficultyLevel returns the rank of a difficulty as used by difficultyRank
+func DifficultyLevel(difficulty string) int {
+	switch difficulty {
+	case "beginner":
+		return 1
+	case "intermediate":
+		return 2
+	case "advanced":
+		return 3
+	}
+	return 0
+}
+
+// tabFilterClauses returns the WHERE conditions of filters
+func tabFilterClauses(filters TabFilters) ([]string, []interface{}) {
+	var clauses []string
+	var args []interface{}
+	if filters.MinRating > 0 {
+		clauses = append(clauses, "tabs.rating >= ?")
+		args = append(args, filters.MinRating)
+	}
+	if len(filters.Difficulties) > 0 {
+		placeholders := strings.Repeat("?,", len(filters.Difficulties))
+		clauses = append(clauses, fmt.Sprintf("tabs.difficulty IN (%s)", placeholders[:len(placeholders)-1]))
+		for _, d := range filters.Difficulties {
+			args = append(args, d)
+		}
+	}
+	if filters.NeedsReview {
+		clauses = append(clauses, "tabs.needs_review = 1")
+	}
+	if len(filters.Genres) > 0 {
+		placeholders := strings.Repeat("?,", len(filters.Genres))
+		clauses = append(clauses, fmt.Sprintf("tabs.genre COLLATE NOCASE IN (%s)", placeholders[:len(placeholders)-1]))
+		for _, g := range filters.Genres {
+			args = append(args, g)
+		}
+	}
+	if len(filters.Scripts) > 0 {
+		placeholders := strings.Repeat("?,", len(filters.Scripts))
+		clauses = append(clauses, fmt.Sprintf("COALESCE(tabs.script, '') IN (%s)", placeholders[:len(placeholders)-1]))
+		for _, sc := range filters.Scripts {
+			args = append(args, sc)
+		}
+	}
+	if len(filters.Keys) > 0 {
+		placeholders := strings.Repeat("?,", len(filters.Keys))
+		clauses = append(clauses, fmt.Sprintf("tabs.song_key COLLATE NOCASE IN (%s)", placeholders[:len(placeholders)-1]))
+		for _, k := range filters.Keys {
+			args = append(args, k)
+		}
+	}
+	if len(filters.Tunings) > 0 {
+		placeholders := strings.Repeat("?,", len(filters.Tunings))
+		clauses = append(clauses, fmt.Sprintf("EXISTS (SELECT 1 FROM tab_tracks WHERE tab_tracks.tab_id = tabs.id AND tab_tracks.tuning_name COLLATE NOCASE IN (%s))", placeholders[:len(placeholders)-1]))
+		for _, t := range filters.Tunings {
+			args = append(args, t)
+		}
+	}
+	if filters.MinBpm > 0 {
+		clauses = append(clauses, "tabs.bpm >= ?")
+		args = append(args, filters.MinBpm)
+	}
+	if filters.MaxBpm > 0 {
+		clauses = append(clauses, "tabs.bpm > 0 AND tabs.bpm <= ?")
+		args = append(args, filters.MaxBpm)
+	}
+	if filters.MinDuration > 0 {
+		clauses = append(clauses, "tabs.duration >= ?")
+		args = append(args, filters.MinDuration)
+	}
+	if filters.MaxDuration > 0 {
+		clauses = append(clauses, "tabs.duration > 0 AND tabs.duration <= ?")
+		args = append(args, filters.MaxDuration)
+	}
+	return clauses, args
+}
+
+// GetTabsPaginated returns a page of tabs and the number of tabs matching.
+// searchQuery may hold field terms besides free text, see parseSearchQuery;
+// the free text is searched in the filterBy fields.
+func (s *DBStore) GetTabsPaginated(categoryId string, page, pageSize int, searchQuery string, filterBy []string, isGlobal bool, sortBy string, sortDesc bool, filters TabFilters) ([]Tab, int, error) {
+	q, err := parseSearchQuery(searchQuery)
+	if err != nil {
+		return nil, 0, err
+	}
 
 	// Use FTS5 for search if query is provided
-	if searchQuery != "" && len(filterBy) > 0 {
-		return s.getTabsPaginatedFTS(categoryId, page, pageSize, searchQuery, filterBy, isGlobal, sortBy, sortDesc)
+	if q.ftsQuery(filterBy) != "" {
+		return s.getTabsPaginatedFTS(categoryId, page, pageSize, q, filterBy, isGlobal, sortBy, sortDesc, filters)
 	}
 
 	// Standard query without search
@@ -426,6 +760,13 @@ func (s *DBStore) GetTabsPaginated(categoryId string, page, pageSize int, search
 		}
 	}
 
+	// Rating/difficulty filters and field terms
+	filterClauses, filterArgs := tabFilterClauses(filters)
+	whereClauses = append(whereClauses, filterClauses...)
+	args = append(args, filterArgs...)
+	whereClauses = append(whereClauses, q.Clauses...)
+	args = append(args, q.Args...)
+
 	whereSQL := ""
 	if len(whereClauses) > 0 {
 		whereSQL = "WHERE " + strings.Join(whereClauses, " AND ")
@@ -435,7 +776,7 @@ func (s *DBStore) GetTabsPaginated(categoryId string, page, pageSize int, search
 	// Count Total
 	countQuery := fmt.Sprintf("SELECT COUNT(DISTINCT tabs.id) FROM tabs %s %s", joinSQL, whereSQL)
 	var total int
-	if err := s.db.QueryRow(countQuery, args...).Scan(&total); err != nil {
+	if err := s.rdb.QueryRow(countQuery, args...).Scan(&total); err != nil {
 		return nil, 0, err
 	}
 
@@ -443,25 +784,10 @@ func (s *DBStore) GetTabsPaginated(categoryId string, page, pageSize int, search
 	offset := (page - 1) * pageSize
 	limit := pageSize
 
-	orderBy := "tabs.title ASC"
-	direction := "ASC"
-	if sortDesc {
-		direction = "DESC"
-	}
-
-	switch sortBy {
-	case "added_at":
-		orderBy = "tabs.added_at " + direction
-	case "last_opened":
-		orderBy = "tabs.last_opened " + direction
-	case "title":
-		orderBy = "tabs.title " + direction
-	default:
-		orderBy = "tabs.title " + direction
-	}
+	orderBy := tabOrderBy(sortBy, sortDesc)
 
 	query := fmt.Sprintf(`
-		SELECT tabs.id, tabs.title, tabs.artist, tabs.album, tabs.file_path, tabs.type, tabs.is_managed, tabs.cover_path, tabs.category_id, tabs.country, tabs.language, COALESCE(tabs.tag, ''), tabs.added_at, tabs.last_opened 
+		SELECT tabs.id, tabs.title, tabs.artist, tabs.album, tabs.file_path, tabs.type, tabs.is_managed, tabs.cover_path, tabs.category_id, tabs.country, tabs.language, COALESCE(tabs.tag, ''), tabs.added_at, tabs.last_opened, COALESCE(tabs.file_hash, ''), tabs.is_missing, tabs.practice_status, tabs.rating, tabs.difficulty, tabs.needs_review, tabs.song_key, tabs.capo, COALESCE(tabs.script, ''), tabs.genre, tabs.year, COALESCE(tabs.file_size, 0), COALESCE(tabs.cover_color, ''), tabs.bpm, tabs.duration, tabs.source_path 
 		FROM tabs 
 		%s
 		%s 
@@ -471,7 +797,7 @@ func (s *DBStore) GetTabsPaginated(categoryId string, page, pageSize int, search
 
 	queryArgs := append(args, limit, offset)
 
-	rows, err := s.db.Query(query, queryArgs...)
+	rows, err := s.rdb.Query(query, queryArgs...)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -485,7 +811,7 @@ func (s *DBStore) GetTabsPaginated(categoryId string, page, pageSize int, search
 		var t Tab
 		var isManaged int
 		var legacyCatID sql.NullString
-		if err := rows.Scan(&t.ID, &t.Title, &t.Artist, &t.Album, &t.FilePath, &t.Type, &isManaged, &t.CoverPath, &legacyCatID, &t.Country, &t.Language, &t.Tag, &t.AddedAt, &t.LastOpened); err != nil {
+		if err := rows.Scan(&t.ID, &t.Title, &t.Artist, &t.Album, &t.FilePath, &t.Type, &isManaged, &t.CoverPath, &legacyCatID, &t.Country, &t.Language, &t.Tag, &t.AddedAt, &t.LastOpened, &t.FileHash, &t.IsMissing, &t.PracticeStatus, &t.Rating, &t.Difficulty, &t.NeedsReview, &t.Key, &t.Capo, &t.Script, &t.Genre, &t.Year, &t.FileSize, &t.CoverColor, &t.Bpm, &t.Duration, &t.SourcePath); err != nil {
 			return nil, 0, err
 		}
 		t.IsManaged = isManaged == 1
@@ -501,7 +827,7 @@ func (s *DBStore) GetTabsPaginated(categoryId string, page, pageSize int, search
 		placeholders = placeholders[:len(placeholders)-1]
 		catQuery := fmt.Sprintf("SELECT tab_id, category_id FROM tab_categories WHERE tab_id IN (%s)", placeholders)
 		
-		catRows, err := s.db.Query(catQuery, tabIDs...)
+		catRows, err := s.rdb.Query(catQuery, tabIDs...)
 		if err != nil {
 			return nil, 0, err
 		}
@@ -521,25 +847,14 @@ func (s *DBStore) GetTabsPaginated(categoryId string, page, pageSize int, search
 }
 
 // getTabsPaginatedFTS uses FTS5 for fast full-text search
-func (s *DBStore) getTabsPaginatedFTS(categoryId string, page, pageSize int, searchQuery string, filterBy []string, isGlobal bool, sortBy string, sortDesc bool) ([]Tab, int, error) {
+func (s *DBStore) getTabsPaginatedFTS(categoryId string, page, pageSize int, q searchQuery, filterBy []string, isGlobal bool, sortBy string, sortDesc bool, filters TabFilters) ([]Tab, int, error) {
 	// Build FTS5 match query with column filters
 	// FTS5 supports column filters like: title:query OR artist:query
-	var ftsTerms []string
-	for _, field := range filterBy {
-		switch field {
-		case "title", "artist", "album", "tag":
-			// Escape special FTS5 characters and add wildcards for prefix matching
-			escapedQuery := strings.ReplaceAll(searchQuery, "\"", "\"\"")
-			ftsTerms = append(ftsTerms, fmt.Sprintf("%s:\"%s\"*", field, escapedQuery))
-		}
-	}
-
-	if len(ftsTerms) == 0 {
+	ftsQuery := q.ftsQuery(filterBy)
+	if ftsQuery == "" {
 		return nil, 0, fmt.Errorf("no valid filter fields")
 	}
 
-	ftsQuery := strings.Join(ftsTerms, " OR ")
-
 	// Build category filter
 	var catWhere string
 	var catJoin string
@@ -553,6 +868,14 @@ func (s *DBStore) getTabsPaginatedFTS(categoryId string, page, pageSize int, sea
 		}
 	}
 
+	// Rating/difficulty filters and field terms
+	filterClauses, filterArgs := tabFilterClauses(filters)
+	for _, clause := range append(filterClauses, q.Clauses...) {
+		catWhere += " AND " + clause
+	}
+	catArgs = append(catArgs, filterArgs...)
+	catArgs = append(catArgs, q.Args...)
+
 	// Count total with FTS5 join
 	countQuery := fmt.Sprintf(`
 		SELECT COUNT(DISTINCT tabs.id) 
@@ -564,34 +887,25 @@ func (s *DBStore) getTabsPaginatedFTS(categoryId string, page, pageSize int, sea
 
 	countArgs := append([]interface{}{ftsQuery}, catArgs...)
 	var total int
-	if err := s.db.QueryRow(countQuery, countArgs...).Scan(&total); err != nil {
+	if err := s.rdb.QueryRow(countQuery, countArgs...).Scan(&total); err != nil {
 		// Fallback to LIKE query if FTS fails (e.g., special characters)
-		return s.getTabsPaginatedLike(categoryId, page, pageSize, searchQuery, filterBy, isGlobal, sortBy, sortDesc)
+		return s.getTabsPaginatedLike(categoryId, page, pageSize, q, filterBy, isGlobal, sortBy, sortDesc, filters)
 	}
 
 	// Get paginated results
 	offset := (page - 1) * pageSize
 	limit := pageSize
 
-	orderBy := "bm25(tabs_fts), tabs.title ASC"
-	direction := "ASC"
-	if sortDesc {
-		direction = "DESC"
-	}
-
-	switch sortBy {
-	case "added_at":
-		orderBy = "tabs.added_at " + direction
-	case "last_opened":
-		orderBy = "tabs.last_opened " + direction
-	case "title":
-		orderBy = "tabs.title " + direction
+	// Best matches first unless a sort key is given
+	orderBy := "bm25(tabs_fts), tabs.title ASC, tabs.id ASC"
+	if _, ok := tabSortKeys[sortBy]; ok {
+		orderBy = tabOrderBy(sortBy, sortDesc)
 	}
 
 	query := fmt.Sprintf(`
 		SELECT tabs.id, tabs.title, tabs.artist, tabs.album, tabs.file_path, tabs.type, 
 			   tabs.is_managed, tabs.cover_path, tabs.category_id, tabs.country, tabs.language, 
-			   COALESCE(tabs.tag, ''), tabs.added_at, tabs.last_opened 
+			   COALESCE(tabs.tag, ''), tabs.added_at, tabs.last_opened, COALESCE(tabs.file_hash, ''), tabs.is_missing, tabs.practice_status, tabs.rating, tabs.difficulty, tabs.needs_review, tabs.song_key, tabs.capo, COALESCE(tabs.script, ''), tabs.genre, tabs.year, COALESCE(tabs.file_size, 0), COALESCE(tabs.cover_color, ''), tabs.bpm, tabs.duration, tabs.source_path, bm25(tabs_fts) 
 		FROM tabs 
 		INNER JOIN tabs_fts ON tabs.rowid = tabs_fts.rowid
 		%s
@@ -603,10 +917,10 @@ func (s *DBStore) getTabsPaginatedFTS(categoryId string, page, pageSize int, sea
 	queryArgs := append([]interface{}{ftsQuery}, catArgs...)
 	queryArgs = append(queryArgs, limit, offset)
 
-	rows, err := s.db.Query(query, queryArgs...)
+	rows, err := s.rdb.Query(query, queryArgs...)
 	if err != nil {
 		// Fallback to LIKE query if FTS fails
-		return s.getTabsPaginatedLike(categoryId, page, pageSize, searchQuery, filterBy, isGlobal, sortBy, sortDesc)
+		return s.getTabsPaginatedLike(categoryId, page, pageSize, q, filterBy, isGlobal, sortBy, sortDesc, filters)
 	}
 	defer rows.Close()
 
@@ -618,7 +932,7 @@ func (s *DBStore) getTabsPaginatedFTS(categoryId string, page, pageSize int, sea
 		var t Tab
 		var isManaged int
 		var legacyCatID sql.NullString
-		if err := rows.Scan(&t.ID, &t.Title, &t.Artist, &t.Album, &t.FilePath, &t.Type, &isManaged, &t.CoverPath, &legacyCatID, &t.Country, &t.Language, &t.Tag, &t.AddedAt, &t.LastOpened); err != nil {
+		if err := rows.Scan(&t.ID, &t.Title, &t.Artist, &t.Album, &t.FilePath, &t.Type, &isManaged, &t.CoverPath, &legacyCatID, &t.Country, &t.Language, &t.Tag, &t.AddedAt, &t.LastOpened, &t.FileHash, &t.IsMissing, &t.PracticeStatus, &t.Rating, &t.Difficulty, &t.NeedsReview, &t.Key, &t.Capo, &t.Script, &t.Genre, &t.Year, &t.FileSize, &t.CoverColor, &t.Bpm, &t.Duration, &t.SourcePath, &t.rank); err != nil {
 			return nil, 0, err
 		}
 		t.IsManaged = isManaged == 1
@@ -634,7 +948,7 @@ func (s *DBStore) getTabsPaginatedFTS(categoryId string, page, pageSize int, sea
 		placeholders = placeholders[:len(placeholders)-1]
 		catQuery := fmt.Sprintf("SELECT tab_id, category_id FROM tab_categories WHERE tab_id IN (%s)", placeholders)
 		
-		catRows, err := s.db.Query(catQuery, tabIDs...)
+		catRows, err := s.rdb.Query(catQuery, tabIDs...)
 		if err != nil {
 			return nil, 0, err
 		}
@@ -654,7 +968,7 @@ func (s *DBStore) getTabsPaginatedFTS(categoryId string, page, pageSize int, sea
 }
 
 // getTabsPaginatedLike is the fallback using LIKE (for special cases or when FTS fails)
-func (s *DBStore) getTabsPaginatedLike(categoryId string, page, pageSize int, searchQuery string, filterBy []string, isGlobal bool, sortBy string, sortDesc bool) ([]Tab, int, error) {
+func (s *DBStore) getTabsPaginatedLike(categoryId string, page, pageSize int, q searchQuery, filterBy []string, isGlobal bool, sortBy string, sortDesc bool, filters TabFilters) ([]Tab, int, error) {
 	var whereClauses []string
 	var args []interface{}
 	var joins []string
@@ -668,18 +982,33 @@ func (s *DBStore) getTabsPaginatedLike(categoryId string, page, pageSize int, se
 		}
 	}
 
+	// Rating/difficulty filters
+	filterClauses, filterArgs := tabFilterClauses(filters)
+	whereClauses = append(whereClauses, filterClauses...)
+	args = append(args, filterArgs...)
+
+	// Field terms
+	whereClauses = append(whereClauses, q.Clauses...)
+	args = append(args, q.Args...)
+	for _, m := range q.Match {
+		whereClauses = append(whereClauses, fmt.Sprintf("tabs.%s LIKE ?", m.Column))
+		args = append(args, "%"+m.Value+"%")
+	}
+
 	// Search Filter with LIKE
-	var searchConditions []string
-	term := "%" + searchQuery + "%"
-	for _, field := range filterBy {
-		switch field {
-		case "title", "artist", "album", "tag":
-			searchConditions = append(searchConditions, fmt.Sprintf("%s LIKE ?", field))
-			args = append(args, term)
+	if q.Text != "" {
+		var searchConditions []string
+		term := "%" + q.Text + "%"
+		for _, field := range filterBy {
+			switch field {
+			case "title", "artist", "album", "tag", "notes", "genre", "content":
+				searchConditions = append(searchConditions, fmt.Sprintf("%s LIKE ?", field))
+				args = append(args, term)
+			}
+		}
+		if len(searchConditions) > 0 {
+			whereClauses = append(whereClauses, "("+strings.Join(searchConditions, " OR ")+")")
 		}
-	}
-	if len(searchConditions) > 0 {
-		whereClauses = append(whereClauses, "("+strings.Join(searchConditions, " OR ")+")")
 	}
 
 	whereSQL := ""
@@ -699,23 +1028,10 @@ func (s *DBStore) getTabsPaginatedLike(categoryId string, page, pageSize int, se
 	offset := (page - 1) * pageSize
 	limit := pageSize
 
-	orderBy := "title ASC"
-	direction := "ASC"
-	if sortDesc {
-		direction = "DESC"
-	}
-
-	switch sortBy {
-	case "added_at":
-		orderBy = "added_at " + direction
-	case "last_opened":
-		orderBy = "last_opened " + direction
-	case "title":
-		orderBy = "title " + direction
-	}
+	orderBy := tabOrderBy(sortBy, sortDesc)
 
 	query := fmt.Sprintf(`
-		SELECT tabs.id, tabs.title, tabs.artist, tabs.album, tabs.file_path, tabs.type, tabs.is_managed, tabs.cover_path, tabs.category_id, tabs.country, tabs.language, COALESCE(tabs.tag, ''), tabs.added_at, tabs.last_opened 
+		SELECT tabs.id, tabs.title, tabs.artist, tabs.album, tabs.file_path, tabs.type, tabs.is_managed, tabs.cover_path, tabs.category_id, tabs.country, tabs.language, COALESCE(tabs.tag, ''), tabs.added_at, tabs.last_opened, COALESCE(tabs.file_hash, ''), tabs.is_missing, tabs.practice_status, tabs.rating, tabs.difficulty, tabs.needs_review, tabs.song_key, tabs.capo, COALESCE(tabs.script, ''), tabs.genre, tabs.year, COALESCE(tabs.file_size, 0), COALESCE(tabs.cover_color, ''), tabs.bpm, tabs.duration, tabs.source_path 
 		FROM tabs 
 		%s
 		%s 
@@ -739,7 +1055,7 @@ func (s *DBStore) getTabsPaginatedLike(categoryId string, page, pageSize int, se
 		var t Tab
 		var isManaged int
 		var legacyCatID sql.NullString
-		if err := rows.Scan(&t.ID, &t.Title, &t.Artist, &t.Album, &t.FilePath, &t.Type, &isManaged, &t.CoverPath, &legacyCatID, &t.Country, &t.Language, &t.Tag, &t.AddedAt, &t.LastOpened); err != nil {
+		if err := rows.Scan(&t.ID, &t.Title, &t.Artist, &t.Album, &t.FilePath, &t.Type, &isManaged, &t.CoverPath, &legacyCatID, &t.Country, &t.Language, &t.Tag, &t.AddedAt, &t.LastOpened, &t.FileHash, &t.IsMissing, &t.PracticeStatus, &t.Rating, &t.Difficulty, &t.NeedsReview, &t.Key, &t.Capo, &t.Script, &t.Genre, &t.Year, &t.FileSize, &t.CoverColor, &t.Bpm, &t.Duration, &t.SourcePath); err != nil {
 			return nil, 0, err
 		}
 		t.IsManaged = isManaged == 1
@@ -775,16 +1091,15 @@ func (s *DBStore) getTabsPaginatedLike(categoryId string, page, pageSize int, se
 }
 
 func (s *DBStore) GetTab(id string) (*Tab, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
 	var t Tab
 	var isManaged int
 	var legacyCatID sql.NullString
-	err := s.db.QueryRow(`
-		SELECT id, title, artist, album, file_path, type, is_managed, cover_path, category_id, country, language, COALESCE(tag, ''), added_at, last_opened 
+	err := s.queryRow(`
+		SELECT id, title, artist, album, file_path, type, is_managed, cover_path, category_id, country, language, COALESCE(tag, ''), added_at, last_opened, COALESCE(file_hash, ''), is_missing, practice_status, rating, difficulty, needs_review, song_key, capo, COALESCE(script, ''), genre, year, COALESCE(file_size, 0), COALESCE(cover_color, ''), bpm, duration, source_path,
+			words, music, copyright, tab_author, notice
 		FROM tabs WHERE id = ?
-	`, id).Scan(&t.ID, &t.Title, &t.Artist, &t.Album, &t.FilePath, &t.Type, &isManaged, &t.CoverPath, &legacyCatID, &t.Country, &t.Language, &t.Tag, &t.AddedAt, &t.LastOpened)
+	`, id).Scan(&t.ID, &t.Title, &t.Artist, &t.Album, &t.FilePath, &t.Type, &isManaged, &t.CoverPath, &legacyCatID, &t.Country, &t.Language, &t.Tag, &t.AddedAt, &t.LastOpened, &t.FileHash, &t.IsMissing, &t.PracticeStatus, &t.Rating, &t.Difficulty, &t.NeedsReview, &t.Key, &t.Capo, &t.Script, &t.Genre, &t.Year, &t.FileSize, &t.CoverColor, &t.Bpm, &t.Duration, &t.SourcePath,
+		&t.Credits.Words, &t.Credits.Music, &t.Credits.Copyright, &t.Credits.TabAuthor, &t.Credits.Notice)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -795,7 +1110,7 @@ func (s *DBStore) GetTab(id string) (*Tab, error) {
 	t.CategoryIDs = []string{}
 
 	// Fetch categories
-	rows, err := s.db.Query("SELECT category_id FROM tab_categories WHERE tab_id = ?", id)
+	rows, err := s.query("SELECT category_id FROM tab_categories WHERE tab_id = ?", id)
 	if err == nil {
 		defer rows.Close()
 		for rows.Next() {
@@ -806,6 +1121,13 @@ func (s *DBStore) GetTab(id string) (*Tab, error) {
 		}
 	}
 
+	// Fetch tracks
+	tracks, err := s.getTabTracks(id)
+	if err != nil {
+		return nil, err
+	}
+	t.Tracks = tracks
+
 	return &t, nil
 }
 
@@ -814,17 +1136,58 @@ func (s *DBStore) AddTab(tab Tab) error {
 	defer s.mu.Unlock()
 
 	// Start transaction
-	tx, err := s.db.Begin()
+	tx, err := s.begin()
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	isManaged := 0
-	if tab.IsManaged {
+	if err := upsertTab(tx, tab); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// AddTabsBatch adds many tabs in one transaction, which is much faster than
+// calling AddTab for each. The track lists and credits of Guitar Pro tabs
+// are stored too, as by SetTabTracks and SetTabCredits. Nothing is stored
+// if one of the tabs fails.
+func (s *DBStore) AddTabsBatch(tabs []Tab) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, tab := range tabs {
+		if err := upsertTab(tx, tab); err != nil {
+			return fmt.Errorf("failed to add %s: %w", tab.Title, err)
+		}
+		if tab.Type == "gp" {
+			if err := writeTabTracks(tx, tab.ID, tab.Tracks); err != nil {
+				return fmt.Errorf("failed to add tracks of %s: %w", tab.Title, err)
+			}
+			if err := writeTabCredits(tx, tab.ID, tab.Credits); err != nil {
+				return fmt.Errorf("failed to add credits of %s: %w", tab.Title, err)
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// upsertTab inserts or updates a tab and replaces its categories
+func upsertTab(tx *sql.Tx, tab Tab) error {
+	isManaged := 0
+	if tab.IsManaged {
 		isManaged = 1
 	}
 
+	// Upsert instead of INSERT OR REPLACE: REPLACE deletes the old row, which
+	// would cascade to child tables such as tab_tracks.
+
 	// For backward compatibility or if we decide to keep a "primary" category, we could use the first one.
 	// For now, let's just use empty string for category_id in tabs table
 	primaryCatID := ""
@@ -832,10 +1195,31 @@ func (s *DBStore) AddTab(tab Tab) error {
 		primaryCatID = tab.CategoryIDs[0]
 	}
 
-	_, err = tx.Exec(`
-		INSERT OR REPLACE INTO tabs (id, title, artist, album, file_path, type, is_managed, cover_path, category_id, country, language, tag, added_at, last_opened)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`, tab.ID, tab.Title, tab.Artist, tab.Album, tab.FilePath, tab.Type, isManaged, tab.CoverPath, primaryCatID, tab.Country, tab.Language, tab.Tag, tab.AddedAt, tab.LastOpened)
+	_, err := tx.Exec(`
+		INSERT INTO tabs (id, title, artist, album, file_path, type, is_managed, cover_path, category_id, country, language, tag, added_at, last_opened, file_hash, practice_status, rating, difficulty, needs_review, song_key, capo, script, genre, year, file_size, cover_color, bpm, duration, source_path)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			title = excluded.title, artist = excluded.artist, album = excluded.album,
+			file_path = excluded.file_path, type = excluded.type, is_managed = excluded.is_managed,
+			cover_path = excluded.cover_path, category_id = excluded.category_id,
+			country = excluded.country, language = excluded.language, tag = excluded.tag,
+			added_at = excluded.added_at, last_opened = excluded.last_opened,
+			file_hash = CASE
+				WHEN excluded.file_hash != '' THEN excluded.file_hash
+				WHEN tabs.file_path = excluded.file_path THEN tabs.file_hash
+				ELSE ''
+			END,
+			is_missing = CASE WHEN tabs.file_path = excluded.file_path THEN tabs.is_missing ELSE 0 END,
+			practice_status = excluded.practice_status, rating = excluded.rating, difficulty = excluded.difficulty,
+			needs_review = excluded.needs_review, song_key = excluded.song_key, capo = excluded.capo, bpm = excluded.bpm,
+			script = excluded.script, genre = excluded.genre, year = excluded.year,
+			file_size = CASE
+				WHEN excluded.file_size IS NOT NULL THEN excluded.file_size
+				WHEN tabs.file_path = excluded.file_path THEN tabs.file_size
+			END,
+			-- A new cover gets its color from SetTabCoverColor
+			cover_color = CASE WHEN tabs.cover_path = excluded.cover_path THEN tabs.cover_color END
+	`, tab.ID, tab.Title, tab.Artist, tab.Album, tab.FilePath, tab.Type, isManaged, tab.CoverPath, primaryCatID, tab.Country, tab.Language, tab.Tag, tab.AddedAt, tab.LastOpened, tab.FileHash, tab.PracticeStatus, tab.Rating, tab.Difficulty, tab.NeedsReview, tab.Key, tab.Capo, tab.Script, tab.Genre, tab.Year, sql.NullInt64{Int64: tab.FileSize, Valid: tab.FileSize > 0}, tab.CoverColor, tab.Bpm, tab.Duration, tab.SourcePath)
 	if err != nil {
 		return err
 	}
@@ -860,18 +1244,93 @@ func (s *DBStore) AddTab(tab Tab) error {
 		}
 	}
 
-	return tx.Commit()
+	return nil
 }
 
 func (s *DBStore) UpdateTab(tab Tab) error {
-	return s.AddTab(tab) // INSERT OR REPLACE handles update
+	return s.AddTab(tab) // Upsert handles update
+}
+
+// SetTabMissing flags a tab whose file no longer exists on disk
+func (s *DBStore) SetTabMissing(id string, missing bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	value := 0
+	if missing {
+		value = 1
+	}
+	_, err := s.exec("UPDATE tabs SET is_missing = ? WHERE id = ?", value, id)
+	return err
+}
+
+// SetTabNeedsReview flags a tab whose metadata should be confirmed, e.g.
+// one imported from the inbox
+func (s *DBStore) SetTabNeedsReview(id string, needsReview bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.exec("UPDATE tabs SET needs_review = ? WHERE id = ?", needsReview, id)
+	return err
+}
+
+// UpdateTabPath points a tab to a moved or renamed file. The file hash is
+// kept since the content is the same.
+func (s *DBStore) UpdateTabPath(id, filePath string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.exec("UPDATE tabs SET file_path = ?, is_missing = 0 WHERE id = ?", filePath, id)
+	return err
+}
+
+// SetTabInfo updates the title, artist and album of a tab
+func (s *DBStore) SetTabInfo(id, title, artist, album string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.exec("UPDATE tabs SET title = ?, artist = ?, album = ? WHERE id = ?", title, artist, album, id)
+	return err
+}
+
+// SetTabStorage moves a tab between managed (copied into app storage) and
+// linked (file left in place) mode
+func (s *DBStore) SetTabStorage(id, filePath string, isManaged bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	managed := 0
+	if isManaged {
+		managed = 1
+	}
+	_, err := s.exec("UPDATE tabs SET file_path = ?, is_managed = ?, is_missing = 0 WHERE id = ?", filePath, managed, id)
+	return err
+}
+
+// SetTabRating sets the rating of a tab (1-5, 0 for none)
+func (s *DBStore) SetTabRating(id string, rating int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.exec("UPDATE tabs SET rating = ? WHERE id = ?", rating, id)
+	return err
+}
+
+// SetTabDifficulty sets the difficulty of a tab ("beginner", "intermediate",
+// "advanced" or "" for none)
+func (s *DBStore) SetTabDifficulty(id, difficulty string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.exec("UPDATE tabs SET difficulty = ? WHERE id = ?", difficulty, id)
+	return err
 }
 
 func (s *DBStore) DeleteTab(id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	_, err := s.db.Exec("DELETE FROM tabs WHERE id = ?", id)
+	_, err := s.exec("DELETE FROM tabs WHERE id = ?", id)
 	return err
 }
 
@@ -879,12 +1338,20 @@ func (s *DBStore) SetTabCategories(id string, categoryIDs []string, addedAt int6
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	tx, err := s.db.Begin()
+	tx, err := s.begin()
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
+	if err := writeTabCategories(tx, id, categoryIDs, addedAt); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// writeTabCategories replaces the categories of a tab
+func writeTabCategories(tx *sql.Tx, id string, categoryIDs []string, addedAt int64) error {
 	// Update legacy category_id (primary category)
 	primaryCatID := ""
 	if len(categoryIDs) > 0 {
@@ -911,21 +1378,17 @@ func (s *DBStore) SetTabCategories(id string, categoryIDs []string, addedAt int6
 			return err
 		}
 	}
-
-	return tx.Commit()
+	return nil
 }
 
 func (s *DBStore) GetTabByPath(filePath string) (*Tab, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
 	var t Tab
 	var isManaged int
 	var legacyCatID sql.NullString
-	err := s.db.QueryRow(`
-		SELECT id, title, artist, album, file_path, type, is_managed, cover_path, category_id, country, language, COALESCE(tag, ''), added_at, last_opened 
+	err := s.queryRow(`
+		SELECT id, title, artist, album, file_path, type, is_managed, cover_path, category_id, country, language, COALESCE(tag, ''), added_at, last_opened, COALESCE(file_hash, ''), is_missing, practice_status, rating, difficulty, needs_review, song_key, capo, COALESCE(script, ''), genre, year, COALESCE(file_size, 0), COALESCE(cover_color, ''), bpm, duration, source_path 
 		FROM tabs WHERE file_path = ?
-	`, filePath).Scan(&t.ID, &t.Title, &t.Artist, &t.Album, &t.FilePath, &t.Type, &isManaged, &t.CoverPath, &legacyCatID, &t.Country, &t.Language, &t.Tag, &t.AddedAt, &t.LastOpened)
+	`, filePath).Scan(&t.ID, &t.Title, &t.Artist, &t.Album, &t.FilePath, &t.Type, &isManaged, &t.CoverPath, &legacyCatID, &t.Country, &t.Language, &t.Tag, &t.AddedAt, &t.LastOpened, &t.FileHash, &t.IsMissing, &t.PracticeStatus, &t.Rating, &t.Difficulty, &t.NeedsReview, &t.Key, &t.Capo, &t.Script, &t.Genre, &t.Year, &t.FileSize, &t.CoverColor, &t.Bpm, &t.Duration, &t.SourcePath)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -936,7 +1399,7 @@ func (s *DBStore) GetTabByPath(filePath string) (*Tab, error) {
 	t.CategoryIDs = []string{}
 
 	// Fetch categories
-	rows, err := s.db.Query("SELECT category_id FROM tab_categories WHERE tab_id = ?", t.ID)
+	rows, err := s.query("SELECT category_id FROM tab_categories WHERE tab_id = ?", t.ID)
 	if err == nil {
 		defer rows.Close()
 		for rows.Next() {
@@ -951,16 +1414,13 @@ func (s *DBStore) GetTabByPath(filePath string) (*Tab, error) {
 }
 
 func (s *DBStore) GetTabByTitle(title string) (*Tab, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
 	var t Tab
 	var isManaged int
 	var legacyCatID sql.NullString
-	err := s.db.QueryRow(`
-		SELECT id, title, artist, album, file_path, type, is_managed, cover_path, category_id, country, language, COALESCE(tag, ''), added_at, last_opened 
+	err := s.queryRow(`
+		SELECT id, title, artist, album, file_path, type, is_managed, cover_path, category_id, country, language, COALESCE(tag, ''), added_at, last_opened, COALESCE(file_hash, ''), is_missing, practice_status, rating, difficulty, needs_review, song_key, capo, COALESCE(script, ''), genre, year, COALESCE(file_size, 0), COALESCE(cover_color, ''), bpm, duration, source_path 
 		FROM tabs WHERE title = ?
-	`, title).Scan(&t.ID, &t.Title, &t.Artist, &t.Album, &t.FilePath, &t.Type, &isManaged, &t.CoverPath, &legacyCatID, &t.Country, &t.Language, &t.Tag, &t.AddedAt, &t.LastOpened)
+	`, title).Scan(&t.ID, &t.Title, &t.Artist, &t.Album, &t.FilePath, &t.Type, &isManaged, &t.CoverPath, &legacyCatID, &t.Country, &t.Language, &t.Tag, &t.AddedAt, &t.LastOpened, &t.FileHash, &t.IsMissing, &t.PracticeStatus, &t.Rating, &t.Difficulty, &t.NeedsReview, &t.Key, &t.Capo, &t.Script, &t.Genre, &t.Year, &t.FileSize, &t.CoverColor, &t.Bpm, &t.Duration, &t.SourcePath)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -971,7 +1431,7 @@ func (s *DBStore) GetTabByTitle(title string) (*Tab, error) {
 	t.CategoryIDs = []string{}
 
 	// Fetch categories
-	rows, err := s.db.Query("SELECT category_id FROM tab_categories WHERE tab_id = ?", t.ID)
+	rows, err := s.query("SELECT category_id FROM tab_categories WHERE tab_id = ?", t.ID)
 	if err == nil {
 		defer rows.Close()
 		for rows.Next() {
@@ -985,240 +1445,3168 @@ func (s *DBStore) GetTabByTitle(title string) (*Tab, error) {
 	return &t, nil
 }
 
-// === Category Operations ===
+// === Track Operations ===
 
-func (s *DBStore) GetCategories() ([]Category, error) {
+// SetTabTracks replaces the stored track list of a tab and marks the tab as
+// scanned, so GetTabsMissingTracks skips it even if the list is empty
+func (s *DBStore) SetTabTracks(tabID string, tracks []TabTrack) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	rows, err := s.db.Query(`
-		SELECT c.id, c.name, c.parent_id, c.cover_path,
-		COALESCE(NULLIF(c.cover_path, ''), (SELECT cover_path FROM tabs WHERE category_id = c.id ORDER BY added_at ASC LIMIT 1), '') as effective_cover_path
-		FROM categories c
+	tx, err := s.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := writeTabTracks(tx, tabID, tracks); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// writeTabTracks replaces the track list of a tab and marks it as scanned
+func writeTabTracks(tx *sql.Tx, tabID string, tracks []TabTrack) error {
+	if _, err := tx.Exec("DELETE FROM tab_tracks WHERE tab_id = ?", tabID); err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO tab_tracks (tab_id, track_index, name, instrument, program, kind, string_count, tuning, tuning_name, capo, is_percussion)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`)
 	if err != nil {
-		return []Category{}, err
+		return err
 	}
-	defer rows.Close()
+	defer stmt.Close()
 
-	categories := []Category{}
-	for rows.Next() {
-		var c Category
-		if err := rows.Scan(&c.ID, &c.Name, &c.ParentID, &c.CoverPath, &c.EffectiveCoverPath); err != nil {
-			return nil, err
+	for _, t := range tracks {
+		isPercussion := 0
+		if t.IsPercussion {
+			isPercussion = 1
+		}
+		if _, err := stmt.Exec(tabID, t.Index, t.Name, t.Instrument, t.Program, t.Kind, t.StringCount, formatInts(t.Tuning), t.TuningName, t.Capo, isPercussion); err != nil {
+			return err
 		}
-		categories = append(categories, c)
 	}
-	return categories, nil
+
+	_, err = tx.Exec("UPDATE tabs SET tracks_scanned = 1 WHERE id = ?", tabID)
+	return err
 }
 
-func (s *DBStore) GetRecentCategories(limit int) ([]Category, error) {
+// SetTabCredits stores the credits read from the header of a GP file. They
+// are not changed by AddTab.
+func (s *DBStore) SetTabCredits(tabID string, credits TabCredits) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if limit <= 0 {
-		limit = 10
+	tx, err := s.begin()
+	if err != nil {
+		return err
 	}
+	defer tx.Rollback()
 
-	rows, err := s.db.Query(`
-		SELECT c.id, c.name, c.parent_id, c.cover_path,
-		COALESCE(NULLIF(c.cover_path, ''), (SELECT cover_path FROM tabs WHERE category_id = c.id ORDER BY added_at ASC LIMIT 1), '') as effective_cover_path,
-		MAX(t.last_opened) as max_opened
-		FROM categories c
-		JOIN tabs t ON c.id = t.category_id
-		GROUP BY c.id
-		ORDER BY max_opened DESC
-		LIMIT ?
-	`, limit)
+	if err := writeTabCredits(tx, tabID, credits); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// FillTabTempoKey sets the tempo and key of a tab read from its GP file,
+// each only if the tab has none yet: they may have been set by hand
+func (s *DBStore) FillTabTempoKey(tabID string, bpm int, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.exec(`
+		UPDATE tabs SET
+			bpm = CASE WHEN bpm = 0 THEN ? ELSE bpm END,
+			song_key = CASE WHEN song_key = '' THEN ? ELSE song_key END
+		WHERE id = ?
+	`, bpm, key, tabID)
+	return err
+}
+
+// SetTabDuration sets the estimated playing time of a tab in seconds, read
+// from its GP file
+func (s *DBStore) SetTabDuration(tabID string, seconds int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.exec("UPDATE tabs SET duration = ? WHERE id = ?", seconds, tabID)
+	return err
+}
+
+// writeTabCredits replaces the credits of a tab
+func writeTabCredits(tx *sql.Tx, tabID string, c TabCredits) error {
+	_, err := tx.Exec("UPDATE tabs SET words = ?, music = ?, copyright = ?, tab_author = ?, notice = ? WHERE id = ?",
+		c.Words, c.Music, c.Copyright, c.TabAuthor, c.Notice, tabID)
+	return err
+}
+
+// GetTabsMissingTracks returns the Guitar Pro tabs whose track list has not
+// been read yet. Only ID, Title and FilePath are filled.
+func (s *DBStore) GetTabsMissingTracks() ([]Tab, error) {
+	rows, err := s.rdb.Query("SELECT id, title, file_path FROM tabs WHERE type = 'gp' AND tracks_scanned = 0 AND is_missing = 0 ORDER BY added_at DESC")
 	if err != nil {
-		return []Category{}, err
+		return []Tab{}, err
 	}
 	defer rows.Close()
 
-	categories := []Category{}
+	tabs := []Tab{}
 	for rows.Next() {
-		var c Category
-		var maxOpened int64
-		if err := rows.Scan(&c.ID, &c.Name, &c.ParentID, &c.CoverPath, &c.EffectiveCoverPath, &maxOpened); err != nil {
+		var t Tab
+		if err := rows.Scan(&t.ID, &t.Title, &t.FilePath); err != nil {
 			return nil, err
 		}
-		categories = append(categories, c)
+		tabs = append(tabs, t)
 	}
-	return categories, nil
+	return tabs, rows.Err()
 }
 
-func (s *DBStore) GetRecentTabs(limit int) ([]Tab, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// getTabTracks loads the tracks of a tab
+func (s *DBStore) getTabTracks(tabID string) ([]TabTrack, error) {
+	rows, err := s.query(`
+		SELECT track_index, name, instrument, program, kind, string_count, tuning, tuning_name, capo, is_percussion
+		FROM tab_tracks WHERE tab_id = ? ORDER BY track_index
+	`, tabID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-	if limit <= 0 {
-		limit = 20
+	tracks := []TabTrack{}
+	for rows.Next() {
+		var t TabTrack
+		var tuning string
+		var isPercussion int
+		if err := rows.Scan(&t.Index, &t.Name, &t.Instrument, &t.Program, &t.Kind, &t.StringCount, &tuning, &t.TuningName, &t.Capo, &isPercussion); err != nil {
+			return nil, err
+		}
+		t.Tuning = parseInts(tuning)
+		t.IsPercussion = isPercussion == 1
+		tracks = append(tracks, t)
 	}
+	return tracks, nil
+}
 
-	rows, err := s.db.Query(`
-		SELECT id, title, artist, album, file_path, type, is_managed, cover_path, category_id, country, language, COALESCE(tag, ''), added_at, last_opened 
-		FROM tabs 
-		WHERE last_opened > 0
-		ORDER BY last_opened DESC 
-		LIMIT ?
-	`, limit)
+// GetTabsByTrack returns tabs that contain a track of the given kind
+// (e.g. "bass") and/or string count (e.g. 7). Zero values are ignored.
+func (s *DBStore) GetTabsByTrack(kind string, stringCount int) ([]Tab, error) {
+	var conditions []string
+	var args []interface{}
+	if kind != "" {
+		conditions = append(conditions, "tt.kind = ?")
+		args = append(args, kind)
+	}
+	if stringCount > 0 {
+		conditions = append(conditions, "tt.string_count = ?")
+		args = append(args, stringCount)
+	}
+	if len(conditions) == 0 {
+		return []Tab{}, nil
+	}
+
+	rows, err := s.rdb.Query(fmt.Sprintf(`
+		SELECT id, title, artist, album, file_path, type, is_managed, cover_path, category_id, country, language, COALESCE(tag, ''), added_at, last_opened, COALESCE(file_hash, ''), is_missing, practice_status, rating, difficulty, needs_review, song_key, capo, COALESCE(script, ''), genre, year, COALESCE(file_size, 0), COALESCE(cover_color, ''), bpm, duration, source_path
+		FROM tabs
+		WHERE EXISTS (SELECT 1 FROM tab_tracks tt WHERE tt.tab_id = tabs.id AND %s)
+		ORDER BY title ASC
+	`, strings.Join(conditions, " AND ")), args...)
 	if err != nil {
 		return []Tab{}, err
 	}
 	defer rows.Close()
 
 	tabs := []Tab{}
-	tabMap := make(map[string]*Tab)
-	
 	for rows.Next() {
 		var t Tab
 		var isManaged int
 		var legacyCatID sql.NullString
-		if err := rows.Scan(&t.ID, &t.Title, &t.Artist, &t.Album, &t.FilePath, &t.Type, &isManaged, &t.CoverPath, &legacyCatID, &t.Country, &t.Language, &t.Tag, &t.AddedAt, &t.LastOpened); err != nil {
+		if err := rows.Scan(&t.ID, &t.Title, &t.Artist, &t.Album, &t.FilePath, &t.Type, &isManaged, &t.CoverPath, &legacyCatID, &t.Country, &t.Language, &t.Tag, &t.AddedAt, &t.LastOpened, &t.FileHash, &t.IsMissing, &t.PracticeStatus, &t.Rating, &t.Difficulty, &t.NeedsReview, &t.Key, &t.Capo, &t.Script, &t.Genre, &t.Year, &t.FileSize, &t.CoverColor, &t.Bpm, &t.Duration, &t.SourcePath); err != nil {
 			return nil, err
 		}
 		t.IsManaged = isManaged == 1
 		t.CategoryIDs = []string{}
 		tabs = append(tabs, t)
-		tabMap[t.ID] = &tabs[len(tabs)-1]
 	}
+	return tabs, nil
+}
 
-	if len(tabs) > 0 {
-		// Fetch categories for these tabs
-		// Using a simpler approach: fetch all for these IDs
-		// Since it's recent tabs (small limit), we can iterate or use IN clause
-		// IN clause is better
-		placeholders := strings.Repeat("?,", len(tabs))
-		placeholders = placeholders[:len(placeholders)-1]
-		ids := make([]interface{}, len(tabs))
-		for i, t := range tabs {
-			ids[i] = t.ID
-		}
+// formatInts stores a list of integers (e.g. a tuning) space separated
+func formatInts(values []int) string {
+	parts := make([]string, len(values))
+	for i, n := range values {
+		parts[i] = fmt.Sprintf("%d", n)
+	}
+	return strings.Join(parts, " ")
+}
 
-		catRows, err := s.db.Query(fmt.Sprintf("SELECT tab_id, category_id FROM tab_categories WHERE tab_id IN (%s)", placeholders), ids...)
-		if err == nil {
-			defer catRows.Close()
-			for catRows.Next() {
-				var tID, cID string
-				if err := catRows.Scan(&tID, &cID); err == nil {
-					if tab, ok := tabMap[tID]; ok {
-						tab.CategoryIDs = append(tab.CategoryIDs, cID)
-					}
-				}
-			}
+// parseInts reverses formatInts
+func parseInts(s string) []int {
+	values := []int{}
+	for _, f := range strings.Fields(s) {
+		var n int
+		if _, err := fmt.Sscanf(f, "%d", &n); err == nil {
+			values = append(values, n)
 		}
 	}
+	return values
+}
 
-	return tabs, nil
+// === Print Settings Operations ===
+
+// GetPrintSettings returns the print settings of a tab, or the defaults if
+// none were saved yet
+func (s *DBStore) GetPrintSettings(tabID string) (PrintSettings, error) {
+	ps := DefaultPrintSettings(tabID)
+	var tracks string
+	err := s.rdb.QueryRow(`
+		SELECT paper_size, margin_top, margin_right, margin_bottom, margin_left, pages_per_sheet, tracks
+		FROM tab_print_settings WHERE tab_id = ?
+	`, tabID).Scan(&ps.PaperSize, &ps.MarginTop, &ps.MarginRight, &ps.MarginBottom, &ps.MarginLeft, &ps.PagesPerSheet, &tracks)
+	if err == sql.ErrNoRows {
+		return ps, nil
+	}
+	if err != nil {
+		return ps, err
+	}
+	ps.Tracks = parseInts(tracks)
+	return ps, nil
 }
 
-func (s *DBStore) AddCategory(cat Category) error {
+// SetPrintSettings saves the print settings of a tab
+func (s *DBStore) SetPrintSettings(ps PrintSettings) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	_, err := s.db.Exec(`
-		INSERT OR REPLACE INTO categories (id, name, parent_id, cover_path)
-		VALUES (?, ?, ?, ?)
-	`, cat.ID, cat.Name, cat.ParentID, cat.CoverPath)
+	_, err := s.exec(`
+		INSERT OR REPLACE INTO tab_print_settings (tab_id, paper_size, margin_top, margin_right, margin_bottom, margin_left, pages_per_sheet, tracks)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, ps.TabID, ps.PaperSize, ps.MarginTop, ps.MarginRight, ps.MarginBottom, ps.MarginLeft, ps.PagesPerSheet, formatInts(ps.Tracks))
 	return err
 }
 
-func (s *DBStore) DeleteCategory(id string) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// === Tab Note Operations ===
 
-	// Start a transaction
-	tx, err := s.db.Begin()
+// GetTabNotes returns the notes of a tab, oldest first
+func (s *DBStore) GetTabNotes(tabID string) ([]TabNote, error) {
+	rows, err := s.rdb.Query(`
+		SELECT id, tab_id, content, created_at, updated_at
+		FROM tab_notes WHERE tab_id = ? ORDER BY created_at, id
+	`, tabID)
 	if err != nil {
-		return err
+		return []TabNote{}, err
 	}
-	defer tx.Rollback()
+	defer rows.Close()
 
-	// Move sub-categories to root (or delete them recursively? Current behavior is move to root)
-	if _, err := tx.Exec("UPDATE categories SET parent_id = '' WHERE parent_id = ?", id); err != nil {
-		return err
+	notes := []TabNote{}
+	for rows.Next() {
+		var n TabNote
+		if err := rows.Scan(&n.ID, &n.TabID, &n.Content, &n.CreatedAt, &n.UpdatedAt); err != nil {
+			return notes, err
+		}
+		notes = append(notes, n)
 	}
+	return notes, rows.Err()
+}
 
-	// Delete the category.
-	// Since we enabled foreign keys and set ON DELETE CASCADE on tab_categories(category_id),
-	// this will automatically remove associations in tab_categories.
-	if _, err := tx.Exec("DELETE FROM categories WHERE id = ?", id); err != nil {
-		return err
+// SaveTabNote adds a note to a tab, or updates its content if note.ID is
+// set, and returns the saved note
+func (s *DBStore) SaveTabNote(note TabNote) (TabNote, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.begin()
+	if err != nil {
+		return note, err
 	}
+	defer tx.Rollback()
 
-	// Optional: Clear legacy category_id in tabs if it matches (for consistency)
-	if _, err := tx.Exec("UPDATE tabs SET category_id = '' WHERE category_id = ?", id); err != nil {
-		// Ignore error, non-critical
+	now := time.Now().Unix()
+	if note.ID == 0 {
+		res, err := tx.Exec("INSERT INTO tab_notes (tab_id, content, created_at, updated_at) VALUES (?, ?, ?, ?)",
+			note.TabID, note.Content, now, now)
+		if err != nil {
+			return note, err
+		}
+		if note.ID, err = res.LastInsertId(); err != nil {
+			return note, err
+		}
+		note.CreatedAt = now
+	} else {
+		err := tx.QueryRow("SELECT tab_id, created_at FROM tab_notes WHERE id = ?", note.ID).Scan(&note.TabID, &note.CreatedAt)
+		if err == sql.ErrNoRows {
+			return note, fmt.Errorf("note not found: %d", note.ID)
+		}
+		if err != nil {
+			return note, err
+		}
+		if _, err := tx.Exec("UPDATE tab_notes SET content = ?, updated_at = ? WHERE id = ?", note.Content, now, note.ID); err != nil {
+			return note, err
+		}
 	}
+	note.UpdatedAt = now
 
-	return tx.Commit()
+	if err := refreshNotesIndex(tx, note.TabID); err != nil {
+		return note, err
+	}
+	return note, tx.Commit()
 }
 
-func (s *DBStore) MoveCategory(id, newParentID string) error {
+// DeleteTabNote removes a note
+func (s *DBStore) DeleteTabNote(id int64) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	_, err := s.db.Exec("UPDATE categories SET parent_id = ? WHERE id = ?", newParentID, id)
+	tx, err := s.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var tabID string
+	err = tx.QueryRow("SELECT tab_id FROM tab_notes WHERE id = ?", id).Scan(&tabID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM tab_notes WHERE id = ?", id); err != nil {
+		return err
+	}
+	if err := refreshNotesIndex(tx, tabID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// refreshNotesIndex copies the text of a tab's notes to tabs.notes, which
+// the FTS triggers index for search
+func refreshNotesIndex(tx *sql.Tx, tabID string) error {
+	_, err := tx.Exec(`
+		UPDATE tabs SET notes = (
+			SELECT COALESCE(GROUP_CONCAT(content, char(10)), '')
+			FROM (SELECT content FROM tab_notes WHERE tab_id = ? ORDER BY created_at, id)
+		) WHERE id = ?
+	`, tabID, tabID)
+	return err
+}
+
+// === Attachment Operations ===
+
+// GetAttachments returns the audio files attached to a tab, oldest first
+func (s *DBStore) GetAttachments(tabID string) ([]Attachment, error) {
+	rows, err := s.rdb.Query(`
+		SELECT id, tab_id, name, file_path, added_at
+		FROM attachments WHERE tab_id = ? ORDER BY added_at, id
+	`, tabID)
+	if err != nil {
+		return []Attachment{}, err
+	}
+	defer rows.Close()
+
+	attachments := []Attachment{}
+	for rows.Next() {
+		var a Attachment
+		if err := rows.Scan(&a.ID, &a.TabID, &a.Name, &a.FilePath, &a.AddedAt); err != nil {
+			return attachments, err
+		}
+		attachments = append(attachments, a)
+	}
+	return attachments, rows.Err()
+}
+
+// GetAttachment returns an attachment, or nil if it does not exist
+func (s *DBStore) GetAttachment(id int64) (*Attachment, error) {
+	var a Attachment
+	err := s.rdb.QueryRow("SELECT id, tab_id, name, file_path, added_at FROM attachments WHERE id = ?", id).
+		Scan(&a.ID, &a.TabID, &a.Name, &a.FilePath, &a.AddedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// AddAttachment attaches a file to a tab and returns the attachment with
+// its ID set
+func (s *DBStore) AddAttachment(a Attachment) (Attachment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res, err := s.exec("INSERT INTO attachments (tab_id, name, file_path, added_at) VALUES (?, ?, ?, ?)",
+		a.TabID, a.Name, a.FilePath, a.AddedAt)
+	if err != nil {
+		return a, err
+	}
+	a.ID, err = res.LastInsertId()
+	return a, err
+}
+
+// DeleteAttachment removes an attachment; the file itself is not touched
+func (s *DBStore) DeleteAttachment(id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.exec("DELETE FROM attachments WHERE id = ?", id)
+	return err
+}
+
+// === Tab Link Operations ===
+
+// GetTabLinks returns the reference links of a tab, oldest first
+func (s *DBStore) GetTabLinks(tabID string) ([]TabLink, error) {
+	rows, err := s.rdb.Query(`
+		SELECT id, tab_id, title, url, added_at
+		FROM tab_links WHERE tab_id = ? ORDER BY added_at, id
+	`, tabID)
+	if err != nil {
+		return []TabLink{}, err
+	}
+	defer rows.Close()
+
+	links := []TabLink{}
+	for rows.Next() {
+		var l TabLink
+		if err := rows.Scan(&l.ID, &l.TabID, &l.Title, &l.URL, &l.AddedAt); err != nil {
+			return links, err
+		}
+		links = append(links, l)
+	}
+	return links, rows.Err()
+}
+
+// GetTabLink returns a link, or nil if it does not exist
+func (s *DBStore) GetTabLink(id int64) (*TabLink, error) {
+	var l TabLink
+	err := s.rdb.QueryRow("SELECT id, tab_id, title, url, added_at FROM tab_links WHERE id = ?", id).
+		Scan(&l.ID, &l.TabID, &l.Title, &l.URL, &l.AddedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// AddTabLink adds a link to a tab and returns the link with its ID set
+func (s *DBStore) AddTabLink(l TabLink) (TabLink, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res, err := s.exec("INSERT INTO tab_links (tab_id, title, url, added_at) VALUES (?, ?, ?, ?)",
+		l.TabID, l.Title, l.URL, l.AddedAt)
+	if err != nil {
+		return l, err
+	}
+	l.ID, err = res.LastInsertId()
+	return l, err
+}
+
+// DeleteTabLink removes a link
+func (s *DBStore) DeleteTabLink(id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.exec("DELETE FROM tab_links WHERE id = ?", id)
+	return err
+}
+
+// === Student Operations ===
+
+// GetStudents returns the students by name
+func (s *DBStore) GetStudents() ([]Student, error) {
+	rows, err := s.rdb.Query("SELECT id, name, notes, created_at FROM students ORDER BY name COLLATE NOCASE, id")
+	if err != nil {
+		return []Student{}, err
+	}
+	defer rows.Close()
+
+	students := []Student{}
+	for rows.Next() {
+		var st Student
+		if err := rows.Scan(&st.ID, &st.Name, &st.Notes, &st.CreatedAt); err != nil {
+			return students, err
+		}
+		students = append(students, st)
+	}
+	return students, rows.Err()
+}
+
+// GetStudent returns a student, or nil if it does not exist
+func (s *DBStore) GetStudent(id int64) (*Student, error) {
+	var st Student
+	err := s.rdb.QueryRow("SELECT id, name, notes, created_at FROM students WHERE id = ?", id).
+		Scan(&st.ID, &st.Name, &st.Notes, &st.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+// SaveStudent adds a student, or updates the name and notes of st.ID if it
+// is not 0. Returns the student with its ID set.
+func (s *DBStore) SaveStudent(st Student) (Student, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if st.ID != 0 {
+		res, err := s.exec("UPDATE students SET name = ?, notes = ? WHERE id = ?", st.Name, st.Notes, st.ID)
+		if err != nil {
+			return st, err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return st, err
+		} else if n == 0 {
+			return st, fmt.Errorf("student not found: %d", st.ID)
+		}
+		return st, nil
+	}
+
+	st.CreatedAt = time.Now().Unix()
+	res, err := s.exec("INSERT INTO students (name, notes, created_at) VALUES (?, ?, ?)", st.Name, st.Notes, st.CreatedAt)
+	if err != nil {
+		return st, err
+	}
+	st.ID, err = res.LastInsertId()
+	return st, err
+}
+
+// DeleteStudent removes a student and its assignments
+func (s *DBStore) DeleteStudent(id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.exec("DELETE FROM students WHERE id = ?", id)
+	return err
+}
+
+// GetAssignments returns the assignments of a student, the next due first
+// and those without a due date last
+func (s *DBStore) GetAssignments(studentID int64) ([]Assignment, error) {
+	rows, err := s.rdb.Query(`
+		SELECT a.id, a.student_id, a.tab_id, tabs.title, tabs.artist, a.status, a.due_at, a.note, a.assigned_at
+		FROM assignments a
+		JOIN tabs ON tabs.id = a.tab_id
+		WHERE a.student_id = ?
+		ORDER BY a.due_at = 0, a.due_at, a.assigned_at, a.id
+	`, studentID)
+	if err != nil {
+		return []Assignment{}, err
+	}
+	defer rows.Close()
+
+	assignments := []Assignment{}
+	for rows.Next() {
+		var as Assignment
+		if err := rows.Scan(&as.ID, &as.StudentID, &as.TabID, &as.TabTitle, &as.TabArtist, &as.Status, &as.DueAt, &as.Note, &as.AssignedAt); err != nil {
+			return assignments, err
+		}
+		assignments = append(assignments, as)
+	}
+	return assignments, rows.Err()
+}
+
+// SaveAssignment assigns a tab to a student. If the tab is already assigned
+// to the student, its due date and note are updated and its status is kept.
+// Returns the assignment with its ID set.
+func (s *DBStore) SaveAssignment(as Assignment) (Assignment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if as.Status == "" {
+		as.Status = "assigned"
+	}
+	err := s.db.QueryRow(`
+		INSERT INTO assignments (student_id, tab_id, status, due_at, note, assigned_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(student_id, tab_id) DO UPDATE SET due_at = excluded.due_at, note = excluded.note
+		RETURNING id, status, assigned_at
+	`, as.StudentID, as.TabID, as.Status, as.DueAt, as.Note, as.AssignedAt).Scan(&as.ID, &as.Status, &as.AssignedAt)
+	return as, err
+}
+
+// SetAssignmentStatus sets the status of an assignment
+func (s *DBStore) SetAssignmentStatus(id int64, status string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res, err := s.exec("UPDATE assignments SET status = ? WHERE id = ?", status, id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return fmt.Errorf("assignment not found: %d", id)
+	}
+	return nil
+}
+
+// DeleteAssignment removes an assignment
+func (s *DBStore) DeleteAssignment(id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.exec("DELETE FROM assignments WHERE id = ?", id)
+	return err
+}
+
+// === File Hash Operations ===
+
+// GetTabsMissingHash returns the tabs whose file hash has not been computed
+// yet. Only ID and FilePath are filled.
+func (s *DBStore) GetTabsMissingHash() ([]Tab, error) {
+	rows, err := s.rdb.Query("SELECT id, file_path FROM tabs WHERE COALESCE(file_hash, '') = '' ORDER BY added_at DESC")
+	if err != nil {
+		return []Tab{}, err
+	}
+	defer rows.Close()
+
+	tabs := []Tab{}
+	for rows.Next() {
+		var t Tab
+		if err := rows.Scan(&t.ID, &t.FilePath); err != nil {
+			return nil, err
+		}
+		tabs = append(tabs, t)
+	}
+	return tabs, rows.Err()
+}
+
+// GetLinkedTabsByHash returns the linked tabs whose file has hash, the
+// candidates for a file moved on disk. Only ID, Title and FilePath are
+// filled.
+func (s *DBStore) GetLinkedTabsByHash(hash string) ([]Tab, error) {
+	rows, err := s.rdb.Query("SELECT id, title, file_path FROM tabs WHERE file_hash = ? AND is_managed = 0", hash)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tabs := []Tab{}
+	for rows.Next() {
+		var t Tab
+		if err := rows.Scan(&t.ID, &t.Title, &t.FilePath); err != nil {
+			return nil, err
+		}
+		tabs = append(tabs, t)
+	}
+	return tabs, rows.Err()
+}
+
+// SetTabHash stores the file hash of a tab. The hash is ignored if the tab
+// now points to another file than the one that was hashed.
+func (s *DBStore) SetTabHash(id, filePath, hash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.exec("UPDATE tabs SET file_hash = ? WHERE id = ? AND file_path = ?", hash, id, filePath)
+	return err
+}
+
+// GetTabsMissingScript returns the ID, title and artist of the tabs whose
+// script has not been detected yet
+func (s *DBStore) GetTabsMissingScript() ([]Tab, error) {
+	rows, err := s.rdb.Query("SELECT id, title, artist FROM tabs WHERE script IS NULL")
+	if err != nil {
+		return []Tab{}, err
+	}
+	defer rows.Close()
+
+	tabs := []Tab{}
+	for rows.Next() {
+		var t Tab
+		if err := rows.Scan(&t.ID, &t.Title, &t.Artist); err != nil {
+			return nil, err
+		}
+		tabs = append(tabs, t)
+	}
+	return tabs, rows.Err()
+}
+
+// SetTabScript stores the detected script of a tab
+func (s *DBStore) SetTabScript(id, script string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.exec("UPDATE tabs SET script = ? WHERE id = ?", script, id)
+	return err
+}
+
+// GetTabsMissingFileSize returns the ID and file path of the tabs whose
+// file size has not been measured yet
+func (s *DBStore) GetTabsMissingFileSize() ([]Tab, error) {
+	rows, err := s.rdb.Query("SELECT id, file_path FROM tabs WHERE file_size IS NULL AND is_missing = 0")
+	if err != nil {
+		return []Tab{}, err
+	}
+	defer rows.Close()
+
+	tabs := []Tab{}
+	for rows.Next() {
+		var t Tab
+		if err := rows.Scan(&t.ID, &t.FilePath); err != nil {
+			return nil, err
+		}
+		tabs = append(tabs, t)
+	}
+	return tabs, rows.Err()
+}
+
+// SetTabFileSize stores the size of the file of a tab, unless the tab was
+// pointed to another file meanwhile
+func (s *DBStore) SetTabFileSize(id, filePath string, size int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.exec("UPDATE tabs SET file_size = ? WHERE id = ? AND file_path = ?", size, id, filePath)
+	return err
+}
+
+// GetTabsMissingCoverColor returns the ID and cover path of the tabs with a
+// cover whose color has not been computed yet
+func (s *DBStore) GetTabsMissingCoverColor() ([]Tab, error) {
+	rows, err := s.rdb.Query("SELECT id, cover_path FROM tabs WHERE cover_color IS NULL AND cover_path != ''")
+	if err != nil {
+		return []Tab{}, err
+	}
+	defer rows.Close()
+
+	tabs := []Tab{}
+	for rows.Next() {
+		var t Tab
+		if err := rows.Scan(&t.ID, &t.CoverPath); err != nil {
+			return nil, err
+		}
+		tabs = append(tabs, t)
+	}
+	return tabs, rows.Err()
+}
+
+// SetTabCoverColor stores the dominant color of the cover of a tab, ""
+// if it could not be computed, unless the tab was given another cover
+// meanwhile
+func (s *DBStore) SetTabCoverColor(id, coverPath, color string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.exec("UPDATE tabs SET cover_color = ? WHERE id = ? AND cover_path = ?", color, id, coverPath)
+	return err
+}
+
+// ClearCover removes the cover of a tab or, with category true, of a
+// category, unless it was given another cover meanwhile. A tab without
+// cover is searched again by the cover downloads.
+func (s *DBStore) ClearCover(id, coverPath string, category bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	query := "UPDATE tabs SET cover_path = '', cover_color = NULL WHERE id = ? AND cover_path = ?"
+	if category {
+		query = "UPDATE categories SET cover_path = '' WHERE id = ? AND cover_path = ?"
+	}
+	_, err := s.exec(query, id, coverPath)
+	return err
+}
+
+// GetTabsMissingContent returns the tabs with a text or Guitar Pro file
+// whose content has not been extracted yet. Only ID, Title and FilePath are
+// filled.
+func (s *DBStore) GetTabsMissingContent() ([]Tab, error) {
+	rows, err := s.rdb.Query("SELECT id, title, file_path FROM tabs WHERE content IS NULL AND type IN ('gp', 'text', 'chordpro') AND is_missing = 0 ORDER BY added_at DESC")
+	if err != nil {
+		return []Tab{}, err
+	}
+	defer rows.Close()
+
+	tabs := []Tab{}
+	for rows.Next() {
+		var t Tab
+		if err := rows.Scan(&t.ID, &t.Title, &t.FilePath); err != nil {
+			return nil, err
+		}
+		tabs = append(tabs, t)
+	}
+	return tabs, rows.Err()
+}
+
+// SetTabContent stores the lyrics and words extracted from the file of a
+// tab, which the FTS triggers index for search
+func (s *DBStore) SetTabContent(id, content string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.exec("UPDATE tabs SET content = ? WHERE id = ?", content, id)
+	return err
+}
+
+// GetSearchSuggestions returns up to limit titles or artists (field
+// "title" or "artist", both if empty) with a word starting with prefix.
+// Values starting with prefix come first, then those of the most tabs, then
+// by FTS rank. Values differing only in case count as one.
+func (s *DBStore) GetSearchSuggestions(prefix, field string, limit int) ([]SearchSuggestion, error) {
+	prefix = strings.TrimSpace(prefix)
+	suggestions := []SearchSuggestion{}
+	if prefix == "" || limit <= 0 {
+		return suggestions, nil
+	}
+
+	fields := []string{"title", "artist"}
+	switch field {
+	case "":
+	case "title", "artist":
+		fields = []string{field}
+	default:
+		return nil, fmt.Errorf("unknown suggestion field: %s", field)
+	}
+
+	escaped := strings.ReplaceAll(prefix, "\"", "\"\"")
+	for _, f := range fields {
+		rows, err := s.rdb.Query(fmt.Sprintf(`
+			SELECT MIN(value), COUNT(*), MIN(rank) FROM (
+				SELECT tabs.%[1]s AS value, tabs_fts.rank AS rank
+				FROM tabs_fts
+				INNER JOIN tabs ON tabs.rowid = tabs_fts.rowid
+				WHERE tabs_fts MATCH ? AND tabs.%[1]s != ''
+			)
+			GROUP BY value COLLATE NOCASE
+			ORDER BY MIN(value) LIKE ? ESCAPE '\' DESC, COUNT(*) DESC, MIN(rank)
+			LIMIT ?
+		`, f), fmt.Sprintf("%s:\"%s\"*", f, escaped), escapeLike(prefix)+"%", limit)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			sg := SearchSuggestion{Field: f}
+			if err := rows.Scan(&sg.Value, &sg.Tabs, &sg.rank); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			suggestions = append(suggestions, sg)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	lower := strings.ToLower(prefix)
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		a, b := suggestions[i], suggestions[j]
+		aStarts, bStarts := strings.HasPrefix(strings.ToLower(a.Value), lower), strings.HasPrefix(strings.ToLower(b.Value), lower)
+		if aStarts != bStarts {
+			return aStarts
+		}
+		if a.Tabs != b.Tabs {
+			return a.Tabs > b.Tabs
+		}
+		return a.rank < b.rank
+	})
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions, nil
+}
+
+// escapeLike escapes the LIKE wildcards of s for ESCAPE '\'
+func escapeLike(s string) string {
+	return strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(s)
+}
+
+// GetGenres returns the genres of the tabs with their number of tabs, most
+// tabs first. Genres differing only in case count as one.
+func (s *DBStore) GetGenres() ([]GenreCount, error) {
+	rows, err := s.rdb.Query(`
+		SELECT MIN(genre), COUNT(*) FROM tabs
+		WHERE genre != ''
+		GROUP BY genre COLLATE NOCASE
+		ORDER BY COUNT(*) DESC, MIN(genre) COLLATE NOCASE
+	`)
+	if err != nil {
+		return []GenreCount{}, err
+	}
+	defer rows.Close()
+
+	genres := []GenreCount{}
+	for rows.Next() {
+		var g GenreCount
+		if err := rows.Scan(&g.Genre, &g.Tabs); err != nil {
+			return nil, err
+		}
+		genres = append(genres, g)
+	}
+	return genres, rows.Err()
+}
+
+// GetTabsToEnrich returns up to limit tabs, oldest first, that the metadata
+// enrichment has not looked up yet and that lack an album, genre or year.
+// Only ID, title, artist, album, genre and year are filled.
+func (s *DBStore) GetTabsToEnrich(limit int) ([]Tab, error) {
+	rows, err := s.rdb.Query(`
+		SELECT id, title, artist, album, genre, year FROM tabs
+		WHERE enriched = 0 AND artist != '' AND title != '' AND (album = '' OR genre = '' OR year = 0)
+		ORDER BY added_at, id
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return []Tab{}, err
+	}
+	defer rows.Close()
+
+	tabs := []Tab{}
+	for rows.Next() {
+		var t Tab
+		if err := rows.Scan(&t.ID, &t.Title, &t.Artist, &t.Album, &t.Genre, &t.Year); err != nil {
+			return nil, err
+		}
+		tabs = append(tabs, t)
+	}
+	return tabs, rows.Err()
+}
+
+// SetTabEnrichment fills the empty album, genre and year of a tab with the
+// given ones and marks it as looked up. Empty values leave the field as is.
+// Reports whether a field was filled.
+func (s *DBStore) SetTabEnrichment(id, album, genre string, year int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var t Tab
+	if err := tx.QueryRow("SELECT album, genre, year FROM tabs WHERE id = ?", id).Scan(&t.Album, &t.Genre, &t.Year); err != nil {
+		return false, err
+	}
+	filled := false
+	if t.Album == "" && album != "" {
+		t.Album, filled = album, true
+	}
+	if t.Genre == "" && genre != "" {
+		t.Genre, filled = genre, true
+	}
+	if t.Year == 0 && year != 0 {
+		t.Year, filled = year, true
+	}
+	if _, err := tx.Exec("UPDATE tabs SET album = ?, genre = ?, year = ?, enriched = 1 WHERE id = ?",
+		t.Album, t.Genre, t.Year, id); err != nil {
+		return false, err
+	}
+	return filled, tx.Commit()
+}
+
+// ResetEnrichment makes the metadata enrichment look up all tabs again,
+// e.g. after switching providers
+func (s *DBStore) ResetEnrichment() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.exec("UPDATE tabs SET enriched = 0 WHERE enriched = 1")
+	return err
+}
+
+// === Practice Queue Operations ===
+
+// SetPracticeStatus sets the practice status of a tab
+func (s *DBStore) SetPracticeStatus(id, status string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.exec("UPDATE tabs SET practice_status = ? WHERE id = ?", status, id)
+	return err
+}
+
+// RandomTabIDs returns up to count random tab IDs matching filter. Tabs
+// whose file is missing are never picked.
+func (s *DBStore) RandomTabIDs(filter PracticeFilter, count int) ([]string, error) {
+	return s.randomTabIDs(s.rdb, filter, count)
+}
+
+// queryer is implemented by both *sql.DB and *sql.Tx
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// randomTabIDs is RandomTabIDs without locking, usable in a transaction
+func (s *DBStore) randomTabIDs(q queryer, filter PracticeFilter, count int) ([]string, error) {
+	conditions := []string{"tabs.is_missing = 0"}
+	var args []interface{}
+	if filter.CategoryID != "" {
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM tab_categories tc WHERE tc.tab_id = tabs.id AND tc.category_id = ?)")
+		args = append(args, filter.CategoryID)
+	}
+	if filter.Status != "" {
+		conditions = append(conditions, "tabs.practice_status = ?")
+		args = append(args, filter.Status)
+	}
+	if filter.Type != "" {
+		conditions = append(conditions, "tabs.type = ?")
+		args = append(args, filter.Type)
+	}
+	if filter.MinDifficulty != "" {
+		conditions = append(conditions, difficultyRank+" >= ?")
+		args = append(args, DifficultyLevel(filter.MinDifficulty))
+	}
+	if filter.MaxDifficulty != "" {
+		conditions = append(conditions, difficultyRank+" <= ?")
+		args = append(args, DifficultyLevel(filter.MaxDifficulty))
+	}
+	args = append(args, count)
+
+	rows, err := q.Query(fmt.Sprintf(`
+		SELECT tabs.id FROM tabs
+		WHERE %s
+		ORDER BY RANDOM()
+		LIMIT ?
+	`, strings.Join(conditions, " AND ")), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// BuildPracticeQueue replaces the practice queue with up to count random
+// tabs matching filter and returns their IDs in queue order
+func (s *DBStore) BuildPracticeQueue(filter PracticeFilter, count int) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	ids, err := s.randomTabIDs(tx, filter, count)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec("DELETE FROM practice_queue"); err != nil {
+		return nil, err
+	}
+	for i, id := range ids {
+		if _, err := tx.Exec("INSERT INTO practice_queue (position, tab_id) VALUES (?, ?)", i, id); err != nil {
+			return nil, err
+		}
+	}
+
+	return ids, tx.Commit()
+}
+
+// GetPracticeQueue returns the IDs of the tabs left in the practice queue
+func (s *DBStore) GetPracticeQueue() ([]string, error) {
+	rows, err := s.rdb.Query("SELECT tab_id FROM practice_queue WHERE done = 0 ORDER BY position")
+	if err != nil {
+		return []string{}, err
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// MarkPracticeDone removes a tab from the pending part of the practice queue.
+// Returns an error if the tab is not pending in the queue.
+func (s *DBStore) MarkPracticeDone(tabID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res, err := s.exec("UPDATE practice_queue SET done = 1 WHERE tab_id = ? AND done = 0", tabID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return fmt.Errorf("tab not in practice queue: %s", tabID)
+	}
+	return nil
+}
+
+// ClearPracticeQueue empties the practice queue
+func (s *DBStore) ClearPracticeQueue() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.exec("DELETE FROM practice_queue")
+	return err
+}
+
+// === Reminder Operations ===
+
+// reminderColumns are the columns of a reminder row, in the order
+// scanReminders reads them
+const reminderColumns = `r.id, COALESCE(r.tab_id, ''), COALESCE(r.category_id, ''), COALESCE(t.title, c.name, ''),
+	r.weekdays, r.date, r.time, r.note, r.next_at, r.created_at`
+
+// scanReminders reads the reminders of a query selecting reminderColumns
+func scanReminders(rows *sql.Rows) ([]Reminder, error) {
+	defer rows.Close()
+
+	reminders := []Reminder{}
+	for rows.Next() {
+		var r Reminder
+		var weekdays string
+		if err := rows.Scan(&r.ID, &r.TabID, &r.CategoryID, &r.Name, &weekdays, &r.Date, &r.Time, &r.Note, &r.NextAt, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(weekdays), &r.Weekdays); err != nil || r.Weekdays == nil {
+			r.Weekdays = []int{}
+		}
+		reminders = append(reminders, r)
+	}
+	return reminders, rows.Err()
+}
+
+// GetReminders returns the reminders, the next due first and those done
+// last
+func (s *DBStore) GetReminders() ([]Reminder, error) {
+	rows, err := s.rdb.Query(`
+		SELECT ` + reminderColumns + `
+		FROM reminders r
+		LEFT JOIN tabs t ON t.id = r.tab_id
+		LEFT JOIN categories c ON c.id = r.category_id
+		ORDER BY r.next_at = 0, r.next_at, r.id
+	`)
+	if err != nil {
+		return []Reminder{}, err
+	}
+	return scanReminders(rows)
+}
+
+// GetReminder returns a reminder, or nil if there is none with that ID
+func (s *DBStore) GetReminder(id int64) (*Reminder, error) {
+	rows, err := s.rdb.Query(`
+		SELECT `+reminderColumns+`
+		FROM reminders r
+		LEFT JOIN tabs t ON t.id = r.tab_id
+		LEFT JOIN categories c ON c.id = r.category_id
+		WHERE r.id = ?
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	reminders, err := scanReminders(rows)
+	if err != nil || len(reminders) == 0 {
+		return nil, err
+	}
+	return &reminders[0], nil
+}
+
+// GetDueReminders returns the reminders whose next notification is at or
+// before now (Unix timestamp)
+func (s *DBStore) GetDueReminders(now int64) ([]Reminder, error) {
+	rows, err := s.rdb.Query(`
+		SELECT `+reminderColumns+`
+		FROM reminders r
+		LEFT JOIN tabs t ON t.id = r.tab_id
+		LEFT JOIN categories c ON c.id = r.category_id
+		WHERE r.next_at > 0 AND r.next_at <= ?
+		ORDER BY r.next_at, r.id
+	`, now)
+	if err != nil {
+		return []Reminder{}, err
+	}
+	return scanReminders(rows)
+}
+
+// SaveReminder adds a reminder, or updates r.ID if it is not 0
+func (s *DBStore) SaveReminder(r Reminder) (Reminder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.Weekdays == nil {
+		r.Weekdays = []int{}
+	}
+	weekdays, err := json.Marshal(r.Weekdays)
+	if err != nil {
+		return r, err
+	}
+
+	if r.ID != 0 {
+		res, err := s.exec(`
+			UPDATE reminders SET tab_id = NULLIF(?, ''), category_id = NULLIF(?, ''), weekdays = ?, date = ?, time = ?, note = ?, next_at = ?
+			WHERE id = ?
+		`, r.TabID, r.CategoryID, string(weekdays), r.Date, r.Time, r.Note, r.NextAt, r.ID)
+		if err != nil {
+			return r, err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return r, err
+		} else if n == 0 {
+			return r, fmt.Errorf("reminder not found: %d", r.ID)
+		}
+		return r, nil
+	}
+
+	r.CreatedAt = time.Now().Unix()
+	res, err := s.exec(`
+		INSERT INTO reminders (tab_id, category_id, weekdays, date, time, note, next_at, created_at)
+		VALUES (NULLIF(?, ''), NULLIF(?, ''), ?, ?, ?, ?, ?, ?)
+	`, r.TabID, r.CategoryID, string(weekdays), r.Date, r.Time, r.Note, r.NextAt, r.CreatedAt)
+	if err != nil {
+		return r, err
+	}
+	r.ID, err = res.LastInsertId()
+	return r, err
+}
+
+// SetReminderNextAt sets when a reminder notifies next, 0 for never
+func (s *DBStore) SetReminderNextAt(id, nextAt int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.exec("UPDATE reminders SET next_at = ? WHERE id = ?", nextAt, id)
+	return err
+}
+
+// DeleteReminder removes a reminder
+func (s *DBStore) DeleteReminder(id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.exec("DELETE FROM reminders WHERE id = ?", id)
+	return err
+}
+
+// === Practice Time Operations ===
+
+// historyOpenSlack is how long after startedAt an open recorded by
+// MarkTabOpened may be and still get the practice time of AddPracticeTime:
+// the viewer can show a tab shortly before it is marked as opened
+const historyOpenSlack = 60
+
+// AddPracticeTime records that a tab was open in a viewer for seconds from
+// startedAt (Unix timestamp). The time is added to the last open of the tab
+// in its history, if any.
+func (s *DBStore) AddPracticeTime(tabID string, startedAt int64, seconds int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("INSERT INTO practice_time (tab_id, started_at, seconds) VALUES (?, ?, ?)", tabID, startedAt, seconds); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+		UPDATE tab_history SET seconds = seconds + ?
+		WHERE id = (SELECT id FROM tab_history WHERE tab_id = ? AND opened_at <= ? ORDER BY opened_at DESC, id DESC LIMIT 1)
+	`, seconds, tabID, startedAt+historyOpenSlack); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// === Tab History Operations ===
+
+// MarkTabOpened sets the last opened time of a tab and records the open in
+// its history
+func (s *DBStore) MarkTabOpened(id string, openedAt int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("UPDATE tabs SET last_opened = ? WHERE id = ?", openedAt, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("tab not found")
+	}
+	if _, err := tx.Exec("INSERT INTO tab_history (tab_id, opened_at) VALUES (?, ?)", id, openedAt); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetTabHistory returns up to limit opens of a tab, or of every tab if tabID
+// is empty, newest first
+func (s *DBStore) GetTabHistory(tabID string, limit int) ([]TabOpen, error) {
+	where := ""
+	args := []interface{}{}
+	if tabID != "" {
+		where = "WHERE h.tab_id = ?"
+		args = append(args, tabID)
+	}
+	args = append(args, limit)
+
+	rows, err := s.rdb.Query(fmt.Sprintf(`
+		SELECT h.id, h.tab_id, t.title, t.artist, h.opened_at, h.seconds
+		FROM tab_history h
+		JOIN tabs t ON t.id = h.tab_id
+		%s
+		ORDER BY h.opened_at DESC, h.id DESC
+		LIMIT ?
+	`, where), args...)
+	if err != nil {
+		return []TabOpen{}, err
+	}
+	defer rows.Close()
+
+	opens := []TabOpen{}
+	for rows.Next() {
+		var o TabOpen
+		if err := rows.Scan(&o.ID, &o.TabID, &o.Title, &o.Artist, &o.OpenedAt, &o.Seconds); err != nil {
+			return nil, err
+		}
+		opens = append(opens, o)
+	}
+	return opens, rows.Err()
+}
+
+// GetMostOpenedTabs returns up to limit tabs opened the most times since
+// the given Unix timestamp (0 for ever), then the longest in a viewer
+func (s *DBStore) GetMostOpenedTabs(limit int, since int64) ([]OpenedTab, error) {
+	rows, err := s.rdb.Query(`
+		SELECT tab_id, COUNT(*) AS opens, SUM(seconds) AS secs
+		FROM tab_history
+		WHERE opened_at >= ?
+		GROUP BY tab_id
+		ORDER BY opens DESC, secs DESC, MAX(opened_at) DESC
+		LIMIT ?
+	`, since, limit)
+	if err != nil {
+		return []OpenedTab{}, err
+	}
+	defer rows.Close()
+
+	result := []OpenedTab{}
+	ids := []interface{}{}
+	for rows.Next() {
+		var o OpenedTab
+		if err := rows.Scan(&o.Tab.ID, &o.Opens, &o.Seconds); err != nil {
+			return nil, err
+		}
+		result = append(result, o)
+		ids = append(ids, o.Tab.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(result) == 0 {
+		return result, nil
+	}
+
+	placeholders := strings.Repeat("?,", len(ids))
+	tabs, err := s.queryTabs(fmt.Sprintf("SELECT %s FROM tabs WHERE tabs.id IN (%s)", tabColumns, placeholders[:len(placeholders)-1]), ids...)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]Tab, len(tabs))
+	for _, t := range tabs {
+		byID[t.ID] = t
+	}
+	for i := range result {
+		result[i].Tab = byID[result[i].Tab.ID]
+	}
+	return result, nil
+}
+
+// reviewTopCount is the length of the top lists of a YearReview
+const reviewTopCount = 5
+
+// GetYearReview summarizes the use of the library during a year, the days
+// and months being those of loc. Practice time is counted in the year it
+// started; the time of deleted tabs is not counted.
+func (s *DBStore) GetYearReview(year int, loc *time.Location) (YearReview, error) {
+	from := time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+	to := from.AddDate(1, 0, 0)
+	review := YearReview{
+		Year:         year,
+		MonthlyAdded: make([]int, 12),
+		TopArtists:   []ArtistReview{},
+		TopTabs:      []TabReview{},
+	}
+
+	artists := map[string]*ArtistReview{}
+	artist := func(name string) *ArtistReview {
+		a, ok := artists[name]
+		if !ok {
+			a = &ArtistReview{Artist: name}
+			artists[name] = a
+		}
+		return a
+	}
+
+	rows, err := s.rdb.Query("SELECT added_at, artist FROM tabs WHERE added_at >= ? AND added_at < ?", from.Unix(), to.Unix())
+	if err != nil {
+		return review, err
+	}
+	for rows.Next() {
+		var addedAt int64
+		var name string
+		if err := rows.Scan(&addedAt, &name); err != nil {
+			rows.Close()
+			return review, err
+		}
+		review.TabsAdded++
+		review.MonthlyAdded[time.Unix(addedAt, 0).In(loc).Month()-1]++
+		if name != "" {
+			artist(name).TabsAdded++
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return review, err
+	}
+
+	if err := s.rdb.QueryRow("SELECT COUNT(*) FROM tabs WHERE last_opened >= ? AND last_opened < ?", from.Unix(), to.Unix()).Scan(&review.TabsOpened); err != nil {
+		return review, err
+	}
+	if err := s.rdb.QueryRow("SELECT COUNT(*) FROM tab_notes WHERE created_at >= ? AND created_at < ?", from.Unix(), to.Unix()).Scan(&review.NotesWritten); err != nil {
+		return review, err
+	}
+
+	rows, err = s.rdb.Query(`
+		SELECT p.tab_id, p.started_at, p.seconds, t.title, t.artist
+		FROM practice_time p
+		JOIN tabs t ON t.id = p.tab_id
+		WHERE p.started_at >= ? AND p.started_at < ?
+	`, from.Unix(), to.Unix())
+	if err != nil {
+		return review, err
+	}
+	defer rows.Close()
+
+	tabs := map[string]*TabReview{}
+	days := map[int]bool{}
+	for rows.Next() {
+		var t TabReview
+		var startedAt, seconds int64
+		if err := rows.Scan(&t.ID, &startedAt, &seconds, &t.Title, &t.Artist); err != nil {
+			return review, err
+		}
+		review.PracticeSeconds += seconds
+		days[time.Unix(startedAt, 0).In(loc).YearDay()] = true
+		if tabs[t.ID] == nil {
+			tabs[t.ID] = &t
+		}
+		tabs[t.ID].PracticeSeconds += seconds
+		if t.Artist != "" {
+			artist(t.Artist).PracticeSeconds += seconds
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return review, err
+	}
+	review.PracticeDays = len(days)
+	review.TabsPracticed = len(tabs)
+
+	for _, t := range tabs {
+		review.TopTabs = append(review.TopTabs, *t)
+	}
+	sort.Slice(review.TopTabs, func(i, j int) bool {
+		a, b := review.TopTabs[i], review.TopTabs[j]
+		if a.PracticeSeconds != b.PracticeSeconds {
+			return a.PracticeSeconds > b.PracticeSeconds
+		}
+		return a.Title < b.Title
+	})
+	if len(review.TopTabs) > reviewTopCount {
+		review.TopTabs = review.TopTabs[:reviewTopCount]
+	}
+
+	for _, a := range artists {
+		review.TopArtists = append(review.TopArtists, *a)
+	}
+	sort.Slice(review.TopArtists, func(i, j int) bool {
+		a, b := review.TopArtists[i], review.TopArtists[j]
+		if a.PracticeSeconds != b.PracticeSeconds {
+			return a.PracticeSeconds > b.PracticeSeconds
+		}
+		if a.TabsAdded != b.TabsAdded {
+			return a.TabsAdded > b.TabsAdded
+		}
+		return a.Artist < b.Artist
+	})
+	if len(review.TopArtists) > reviewTopCount {
+		review.TopArtists = review.TopArtists[:reviewTopCount]
+	}
+	return review, nil
+}
+
+// === Library Statistics ===
+
+// topArtistsCount is the number of artists in LibraryStats.TopArtists
+const topArtistsCount = 10
+
+// GetLibraryStats returns the statistics of the whole library. Tabs opened
+// since monthStart count as opened this month. File sizes are read from
+// disk; missing files are not counted.
+func (s *DBStore) GetLibraryStats(monthStart int64) (LibraryStats, error) {
+	stats := LibraryStats{Types: map[string]int{}, TopArtists: []ArtistCount{}}
+
+	err := s.rdb.QueryRow(`
+		SELECT COUNT(*),
+			COUNT(CASE WHEN COALESCE(cover_path, '') != '' THEN 1 END),
+			COUNT(CASE WHEN last_opened >= ? THEN 1 END)
+		FROM tabs
+	`, monthStart).Scan(&stats.Tabs, &stats.Covers, &stats.OpenedThisMonth)
+	if err != nil {
+		return stats, err
+	}
+
+	rows, err := s.rdb.Query("SELECT type, COUNT(*) FROM tabs GROUP BY type")
+	if err != nil {
+		return stats, err
+	}
+	for rows.Next() {
+		var tabType string
+		var n int
+		if err := rows.Scan(&tabType, &n); err != nil {
+			rows.Close()
+			return stats, err
+		}
+		stats.Types[tabType] = n
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return stats, err
+	}
+
+	rows, err = s.rdb.Query(`
+		SELECT artist, COUNT(*) AS n FROM tabs
+		WHERE artist != ''
+		GROUP BY artist
+		ORDER BY n DESC, artist ASC
+		LIMIT ?
+	`, topArtistsCount)
+	if err != nil {
+		return stats, err
+	}
+	for rows.Next() {
+		var a ArtistCount
+		if err := rows.Scan(&a.Artist, &a.Tabs); err != nil {
+			rows.Close()
+			return stats, err
+		}
+		stats.TopArtists = append(stats.TopArtists, a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return stats, err
+	}
+
+	rows, err = s.rdb.Query("SELECT file_path, is_managed FROM tabs WHERE is_missing = 0")
+	if err != nil {
+		return stats, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var filePath string
+		var isManaged bool
+		if err := rows.Scan(&filePath, &isManaged); err != nil {
+			return stats, err
+		}
+		info, err := os.Stat(filePath)
+		if err != nil {
+			continue
+		}
+		if isManaged {
+			stats.ManagedBytes += info.Size()
+		} else {
+			stats.LinkedBytes += info.Size()
+		}
+	}
+	return stats, rows.Err()
+}
+
+// === Cover Queue Operations ===
+
+// QueueCoverFetches adds tabs to the cover queue, which the cover bootstrap
+// works through a few tabs at a time. Queued tabs are skipped.
+func (s *DBStore) QueueCoverFetches(tabIDs []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().Unix()
+	for _, id := range tabIDs {
+		if _, err := tx.Exec("INSERT OR IGNORE INTO cover_queue (tab_id, queued_at) VALUES (?, ?)", id, now); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// NextCoverFetches returns the IDs of up to limit queued tabs that still
+// have no cover, oldest first
+func (s *DBStore) NextCoverFetches(limit int) ([]string, error) {
+	rows, err := s.rdb.Query(`
+		SELECT q.tab_id FROM cover_queue q
+		JOIN tabs ON tabs.id = q.tab_id
+		WHERE q.fetched_at = 0 AND tabs.cover_path = ''
+		ORDER BY q.queued_at, q.rowid
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// MarkCoverFetched records that the cover of a queued tab was requested at
+// the given Unix time
+func (s *DBStore) MarkCoverFetched(tabID string, at int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.exec("UPDATE cover_queue SET fetched_at = ? WHERE tab_id = ?", at, tabID)
+	return err
+}
+
+// CoverQueueCounts returns the number of queued tabs still waiting for a
+// cover, and the number of covers requested since the given Unix time
+func (s *DBStore) CoverQueueCounts(since int64) (pending, fetched int, err error) {
+	err = s.rdb.QueryRow(`
+		SELECT
+			COALESCE(SUM(q.fetched_at = 0 AND tabs.cover_path = ''), 0),
+			COALESCE(SUM(q.fetched_at >= ?), 0)
+		FROM cover_queue q
+		JOIN tabs ON tabs.id = q.tab_id
+	`, since).Scan(&pending, &fetched)
+	return pending, fetched, err
+}
+
+// PruneCoverQueue removes the tabs requested before the given Unix time, and
+// the waiting tabs that got a cover meanwhile
+func (s *DBStore) PruneCoverQueue(before int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.exec(`
+		DELETE FROM cover_queue
+		WHERE (fetched_at > 0 AND fetched_at < ?)
+			OR (fetched_at = 0 AND tab_id IN (SELECT id FROM tabs WHERE cover_path != ''))
+	`, before)
+	return err
+}
+
+// === Cover Sweep Operations ===
+
+// StartCoverSweep lists the tabs having an artist but no cover for a sweep
+// of the whole library, unless the previous sweep has tabs left, which
+// resumes it
+func (s *DBStore) StartCoverSweep() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var pending bool
+	if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM cover_sweep WHERE done_at = 0)").Scan(&pending); err != nil {
+		return err
+	}
+	if pending {
+		return nil
+	}
+	if _, err := tx.Exec("DELETE FROM cover_sweep"); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO cover_sweep (tab_id)
+		SELECT id FROM tabs
+		WHERE cover_path = '' AND artist != '' AND is_missing = 0
+		ORDER BY artist, album, title
+	`); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// NextCoverSweep returns the IDs of up to limit tabs of the cover sweep not
+// searched yet, in sweep order
+func (s *DBStore) NextCoverSweep(limit int) ([]string, error) {
+	rows, err := s.rdb.Query("SELECT tab_id FROM cover_sweep WHERE done_at = 0 ORDER BY rowid LIMIT ?", limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// MarkCoverSweepDone records that the cover of a tab of the sweep was
+// searched at the given Unix time, and whether it was found
+func (s *DBStore) MarkCoverSweepDone(tabID string, at int64, found bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.exec("UPDATE cover_sweep SET done_at = ?, found = ? WHERE tab_id = ?", at, found, tabID)
+	return err
+}
+
+// CoverSweepCounts returns the number of tabs of the last cover sweep, of
+// those searched and of the covers found
+func (s *DBStore) CoverSweepCounts() (total, done, found int, err error) {
+	err = s.rdb.QueryRow(`
+		SELECT COUNT(*), COALESCE(SUM(done_at > 0), 0), COALESCE(SUM(found), 0) FROM cover_sweep
+	`).Scan(&total, &done, &found)
+	return total, done, found, err
+}
+
+// === Cover Fetch Attempt Operations ===
+
+// RecordCoverFailure records a failed cover download of a tab at the given
+// Unix time. The next attempt is due backoff(attempts) later, attempts
+// counting this one. Returns the number of attempts.
+func (s *DBStore) RecordCoverFailure(tabID, lastError string, at int64, backoff func(attempts int) time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var attempts int
+	err = tx.QueryRow("SELECT attempts FROM cover_fetch_attempts WHERE tab_id = ?", tabID).Scan(&attempts)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, err
+	}
+	attempts++
+	next := at + int64(backoff(attempts)/time.Second)
+	if _, err := tx.Exec(`
+		INSERT INTO cover_fetch_attempts (tab_id, attempts, last_error, next_attempt_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(tab_id) DO UPDATE SET attempts = excluded.attempts, last_error = excluded.last_error, next_attempt_at = excluded.next_attempt_at
+	`, tabID, attempts, lastError, next); err != nil {
+		return 0, err
+	}
+	return attempts, tx.Commit()
+}
+
+// ClearCoverFailure forgets the failed cover downloads of a tab
+func (s *DBStore) ClearCoverFailure(tabID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.exec("DELETE FROM cover_fetch_attempts WHERE tab_id = ?", tabID)
+	return err
+}
+
+// DueCoverRetries returns the IDs of up to limit tabs still without a cover
+// whose next download attempt is due at the given Unix time, after fewer
+// than maxAttempts attempts. Earliest due first.
+func (s *DBStore) DueCoverRetries(now int64, maxAttempts, limit int) ([]string, error) {
+	rows, err := s.rdb.Query(`
+		SELECT a.tab_id FROM cover_fetch_attempts a
+		JOIN tabs ON tabs.id = a.tab_id
+		WHERE a.next_attempt_at <= ? AND a.attempts < ? AND tabs.cover_path = ''
+		ORDER BY a.next_attempt_at
+		LIMIT ?
+	`, now, maxAttempts, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// DeferCoverRetry sets the next download attempt of a failed cover to the
+// given Unix time
+func (s *DBStore) DeferCoverRetry(tabID string, at int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.exec("UPDATE cover_fetch_attempts SET next_attempt_at = ? WHERE tab_id = ?", at, tabID)
+	return err
+}
+
+// CountCoverFailures returns the number of tabs still without a cover
+// whose download failed
+func (s *DBStore) CountCoverFailures() (int, error) {
+	var n int
+	err := s.rdb.QueryRow(`
+		SELECT COUNT(*) FROM cover_fetch_attempts a
+		JOIN tabs ON tabs.id = a.tab_id
+		WHERE tabs.cover_path = ''
+	`).Scan(&n)
+	return n, err
+}
+
+// ResetCoverFailures makes the failed downloads of the tabs still without a
+// cover due now, with their attempts counted from zero again. Returns the
+// number of tabs.
+func (s *DBStore) ResetCoverFailures() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res, err := s.exec(`
+		UPDATE cover_fetch_attempts SET attempts = 0, next_attempt_at = 0
+		WHERE tab_id IN (SELECT id FROM tabs WHERE cover_path = '')
+	`)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// === Cover Source Operations ===
+
+// SetCoverSource records the metadata a tab's cover was downloaded with, at
+// the given Unix time
+func (s *DBStore) SetCoverSource(tabID, artist, album, title string, at int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.exec(`
+		INSERT INTO cover_sources (tab_id, artist, album, title, checked_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(tab_id) DO UPDATE SET
+			artist = excluded.artist, album = excluded.album, title = excluded.title,
+			checked_at = excluded.checked_at
+	`, tabID, artist, album, title, at)
+	return err
+}
+
+// MarkCoverChecked records that a tab's cover was re-checked at the given
+// Unix time, keeping the metadata it was downloaded with
+func (s *DBStore) MarkCoverChecked(tabID string, at int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.exec("UPDATE cover_sources SET checked_at = ? WHERE tab_id = ?", at, tabID)
+	return err
+}
+
+// StaleCovers returns up to limit tabs whose cover was downloaded or last
+// checked before the given Unix time, and whose artist, album or title has
+// changed since the download, least recently checked first. Covers
+// downloaded before sources were recorded are never stale.
+func (s *DBStore) StaleCovers(before int64, limit int) ([]string, error) {
+	rows, err := s.rdb.Query(`
+		SELECT tabs.id FROM cover_sources c
+		JOIN tabs ON tabs.id = c.tab_id
+		WHERE c.checked_at < ? AND tabs.cover_path != '' AND tabs.is_missing = 0
+			AND (lower(trim(c.artist)) != lower(trim(tabs.artist))
+				OR lower(trim(c.album)) != lower(trim(tabs.album))
+				OR lower(trim(c.title)) != lower(trim(tabs.title)))
+		ORDER BY c.checked_at
+		LIMIT ?
+	`, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// === Sync Path Operations ===
+
+// GetSyncPaths returns the sync paths in the order they were added
+func (s *DBStore) GetSyncPaths() ([]SyncPath, error) {
+	rows, err := s.rdb.Query(`
+		SELECT path, recursive, mode, category_strategy, category_root, include, exclude
+		FROM sync_paths ORDER BY position, path
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	paths := []SyncPath{}
+	for rows.Next() {
+		var p SyncPath
+		var include, exclude string
+		if err := rows.Scan(&p.Path, &p.Recursive, &p.Mode, &p.CategoryStrategy, &p.CategoryRoot, &include, &exclude); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(include), &p.Include); err != nil {
+			return nil, fmt.Errorf("invalid include patterns of %s: %w", p.Path, err)
+		}
+		if err := json.Unmarshal([]byte(exclude), &p.Exclude); err != nil {
+			return nil, fmt.Errorf("invalid exclude patterns of %s: %w", p.Path, err)
+		}
+		paths = append(paths, p)
+	}
+	return paths, rows.Err()
+}
+
+// AddSyncPath adds a sync path after the others
+func (s *DBStore) AddSyncPath(p SyncPath) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	include, err := patternsJSON(p.Include)
+	if err != nil {
+		return err
+	}
+	exclude, err := patternsJSON(p.Exclude)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// An upsert selecting its values needs a WHERE clause to parse
+	res, err := s.exec(`
+		INSERT INTO sync_paths (path, recursive, mode, category_strategy, category_root, include, exclude, position)
+		SELECT ?, ?, ?, ?, ?, ?, ?, COALESCE(MAX(position), 0) + 1 FROM sync_paths WHERE true
+		ON CONFLICT(path) DO NOTHING
+	`, p.Path, p.Recursive, p.Mode, p.CategoryStrategy, p.CategoryRoot, include, exclude)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("sync path already added: %s", p.Path)
+	}
+	return nil
+}
+
+// UpdateSyncPath replaces the options of a sync path
+func (s *DBStore) UpdateSyncPath(p SyncPath) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	include, err := patternsJSON(p.Include)
+	if err != nil {
+		return err
+	}
+	exclude, err := patternsJSON(p.Exclude)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res, err := s.exec(`
+		UPDATE sync_paths SET recursive = ?, mode = ?, category_strategy = ?, category_root = ?, include = ?, exclude = ?
+		WHERE path = ?
+	`, p.Recursive, p.Mode, p.CategoryStrategy, p.CategoryRoot, include, exclude, p.Path)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("sync path not found: %s", p.Path)
+	}
+	return nil
+}
+
+// RemoveSyncPath removes a sync path. Its tabs are kept.
+func (s *DBStore) RemoveSyncPath(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.exec("DELETE FROM sync_paths WHERE path = ?", path)
+	return err
+}
+
+// patternsJSON encodes include or exclude patterns for the sync_paths table
+func patternsJSON(patterns []string) (string, error) {
+	if patterns == nil {
+		patterns = []string{}
+	}
+	data, err := json.Marshal(patterns)
+	return string(data), err
+}
+
+// IsFileCopied reports whether a file of a sync path in copy mode was
+// already copied to storage
+func (s *DBStore) IsFileCopied(path string) (bool, error) {
+	var n int
+	err := s.queryRow("SELECT COUNT(*) FROM copied_files WHERE path = ?", path).Scan(&n)
+	return n > 0, err
+}
+
+// AddCopiedFile records that a file of a sync path in copy mode was copied
+// to storage as a tab at the given Unix time. Like archive entries, the
+// record outlives the tab.
+func (s *DBStore) AddCopiedFile(path, tabID string, at int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.exec(`
+		INSERT INTO copied_files (path, tab_id, copied_at) VALUES (?, ?, ?)
+		ON CONFLICT(path) DO UPDATE SET tab_id = excluded.tab_id, copied_at = excluded.copied_at
+	`, path, tabID, at)
+	return err
+}
+
+// === Archive Entry Operations ===
+
+// GetArchiveEntries returns the entries of a zip archive which were already
+// imported, so they are not imported again when the archive is scanned
+func (s *DBStore) GetArchiveEntries(archivePath string) (map[string]bool, error) {
+	rows, err := s.rdb.Query("SELECT entry FROM archive_entries WHERE archive_path = ?", archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := map[string]bool{}
+	for rows.Next() {
+		var entry string
+		if err := rows.Scan(&entry); err != nil {
+			return nil, err
+		}
+		entries[entry] = true
+	}
+	return entries, rows.Err()
+}
+
+// AddArchiveEntry records that an entry of a zip archive was imported as a
+// tab at the given Unix time. The record outlives the tab, so deleted tabs
+// are not brought back by the next sync.
+func (s *DBStore) AddArchiveEntry(archivePath, entry, tabID string, at int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.exec(`
+		INSERT INTO archive_entries (archive_path, entry, tab_id, imported_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(archive_path, entry) DO UPDATE SET
+			tab_id = excluded.tab_id, imported_at = excluded.imported_at
+	`, archivePath, entry, tabID, at)
+	return err
+}
+
+// === Sync History Operations ===
+
+// MaxSyncFileErrors is the number of file errors kept per sync run
+const MaxSyncFileErrors = 500
+
+// maxSyncRuns is the number of sync runs kept in the history
+const maxSyncRuns = 200
+
+// AddSyncRun records a full sync in the history, dropping the oldest runs
+// past maxSyncRuns
+func (s *DBStore) AddSyncRun(run SyncRun) error {
+	fileErrors := run.FileErrors
+	if fileErrors == nil {
+		fileErrors = []SyncFileError{}
+	}
+	if len(fileErrors) > MaxSyncFileErrors {
+		fileErrors = fileErrors[:MaxSyncFileErrors]
+	}
+	data, err := json.Marshal(fileErrors)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`
+		INSERT INTO sync_history (started_at, duration_ms, total, added, updated, skipped, errors, pending, conflicts, report, file_errors)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, run.StartedAt, run.DurationMs, run.Total, run.Added, run.Updated, run.Skipped, run.Errors,
+		run.Pending, run.Conflicts, run.Report, string(data)); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+		DELETE FROM sync_history WHERE id NOT IN (SELECT id FROM sync_history ORDER BY id DESC LIMIT ?)
+	`, maxSyncRuns); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetSyncHistory returns the latest limit sync runs, newest first
+func (s *DBStore) GetSyncHistory(limit int) ([]SyncRun, error) {
+	rows, err := s.rdb.Query(`
+		SELECT id, started_at, duration_ms, total, added, updated, skipped, errors, pending, conflicts, report, file_errors
+		FROM sync_history ORDER BY id DESC LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	runs := []SyncRun{}
+	for rows.Next() {
+		var r SyncRun
+		var fileErrors string
+		if err := rows.Scan(&r.ID, &r.StartedAt, &r.DurationMs, &r.Total, &r.Added, &r.Updated, &r.Skipped,
+			&r.Errors, &r.Pending, &r.Conflicts, &r.Report, &fileErrors); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(fileErrors), &r.FileErrors); err != nil || r.FileErrors == nil {
+			r.FileErrors = []SyncFileError{}
+		}
+		runs = append(runs, r)
+	}
+	return runs, rows.Err()
+}
+
+// === Pending Conflict Operations ===
+
+// AddPendingConflict queues a title conflict for the user to resolve. A file
+// already queued, or whose conflict was resolved, is left as it is.
+func (s *DBStore) AddPendingConflict(c PendingConflict) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.exec(`
+		INSERT INTO pending_conflicts (path, title, existing_path, found_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(path) DO NOTHING
+	`, c.Path, c.Title, c.ExistingPath, c.FoundAt)
+	return err
+}
+
+// IsConflictKnown reports whether the conflict of a file is pending, or was
+// resolved by skipping or merging it, so syncs leave the file alone
+func (s *DBStore) IsConflictKnown(path string) (bool, error) {
+	var n int
+	err := s.queryRow("SELECT COUNT(*) FROM pending_conflicts WHERE path = ?", path).Scan(&n)
+	return n > 0, err
+}
+
+// GetPendingConflicts returns the conflicts waiting for the user, oldest first
+func (s *DBStore) GetPendingConflicts() ([]PendingConflict, error) {
+	rows, err := s.rdb.Query(`
+		SELECT path, title, existing_path, found_at FROM pending_conflicts
+		WHERE resolution = '' ORDER BY found_at, path
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	conflicts := []PendingConflict{}
+	for rows.Next() {
+		var c PendingConflict
+		if err := rows.Scan(&c.Path, &c.Title, &c.ExistingPath, &c.FoundAt); err != nil {
+			return nil, err
+		}
+		conflicts = append(conflicts, c)
+	}
+	return conflicts, rows.Err()
+}
+
+// GetPendingConflict returns the pending conflict of a file, nil if there is
+// none
+func (s *DBStore) GetPendingConflict(path string) (*PendingConflict, error) {
+	var c PendingConflict
+	err := s.queryRow(`
+		SELECT path, title, existing_path, found_at FROM pending_conflicts
+		WHERE path = ? AND resolution = ''
+	`, path).Scan(&c.Path, &c.Title, &c.ExistingPath, &c.FoundAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// ResolvePendingConflict records how the conflict of a file was resolved,
+// e.g. "skip", so later syncs do not queue it again
+func (s *DBStore) ResolvePendingConflict(path, resolution string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.exec("UPDATE pending_conflicts SET resolution = ? WHERE path = ?", resolution, path)
+	return err
+}
+
+// RemovePendingConflict forgets the conflict of a file, e.g. once it was
+// added under another title
+func (s *DBStore) RemovePendingConflict(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.exec("DELETE FROM pending_conflicts WHERE path = ?", path)
+	return err
+}
+
+// === Category Operations ===
+
+// GetCategories returns every category, those of a parent in the order the
+// user gave them: pinned first, then by sort order, then as created
+func (s *DBStore) GetCategories() ([]Category, error) {
+	rows, err := s.rdb.Query(`
+		SELECT c.id, c.name, c.parent_id, c.cover_path, c.open_method, c.open_gp_method, c.color, c.icon, c.sort_order, c.pinned,
+		COALESCE(NULLIF(c.cover_path, ''), (SELECT cover_path FROM tabs WHERE category_id = c.id ORDER BY added_at ASC LIMIT 1), '') as effective_cover_path
+		FROM categories c
+		ORDER BY c.pinned DESC, c.sort_order, c.rowid
+	`)
+	if err != nil {
+		return []Category{}, err
+	}
+	defer rows.Close()
+
+	categories := []Category{}
+	for rows.Next() {
+		var c Category
+		if err := rows.Scan(&c.ID, &c.Name, &c.ParentID, &c.CoverPath, &c.OpenMethod, &c.OpenGpMethod, &c.Color, &c.Icon, &c.SortOrder, &c.Pinned, &c.EffectiveCoverPath); err != nil {
+			return nil, err
+		}
+		categories = append(categories, c)
+	}
+	return categories, nil
+}
+
+// GetCategoryTabCovers returns for each category the distinct covers of
+// its tabs, in the order the tabs were added to it, up to limit per
+// category
+func (s *DBStore) GetCategoryTabCovers(limit int) (map[string][]string, error) {
+	rows, err := s.rdb.Query(`
+		SELECT tc.category_id, t.cover_path
+		FROM tab_categories tc
+		JOIN tabs t ON t.id = tc.tab_id
+		WHERE COALESCE(t.cover_path, '') != ''
+		ORDER BY tc.category_id, tc.added_at ASC, t.added_at ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	covers := map[string][]string{}
+	for rows.Next() {
+		var categoryID, coverPath string
+		if err := rows.Scan(&categoryID, &coverPath); err != nil {
+			return nil, err
+		}
+		list := covers[categoryID]
+		if len(list) >= limit || slices.Contains(list, coverPath) {
+			continue
+		}
+		covers[categoryID] = append(list, coverPath)
+	}
+	return covers, rows.Err()
+}
+
+// GetCategoryStats returns the statistics of the categories with tabs,
+// counting the tabs directly in each. File sizes are read from disk; a
+// missing file counts as empty.
+func (s *DBStore) GetCategoryStats() ([]CategoryStats, error) {
+	rows, err := s.rdb.Query(`
+		SELECT tc.category_id, t.type, t.file_path, MAX(t.added_at, t.last_opened, tc.added_at)
+		FROM tab_categories tc
+		JOIN tabs t ON t.id = tc.tab_id
+		ORDER BY tc.category_id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := []CategoryStats{}
+	sizes := map[string]int64{} // Tabs can be in several categories
+	for rows.Next() {
+		var categoryID, tabType, filePath string
+		var activity int64
+		if err := rows.Scan(&categoryID, &tabType, &filePath, &activity); err != nil {
+			return nil, err
+		}
+		if len(stats) == 0 || stats[len(stats)-1].CategoryID != categoryID {
+			stats = append(stats, CategoryStats{CategoryID: categoryID, Types: map[string]int{}})
+		}
+		st := &stats[len(stats)-1]
+		st.Tabs++
+		st.Types[tabType]++
+		st.LastActivity = max(st.LastActivity, activity)
+
+		size, ok := sizes[filePath]
+		if !ok {
+			if info, err := os.Stat(filePath); err == nil {
+				size = info.Size()
+			}
+			sizes[filePath] = size
+		}
+		st.TotalBytes += size
+	}
+	return stats, rows.Err()
+}
+
+func (s *DBStore) GetRecentCategories(limit int) ([]Category, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+
+	rows, err := s.rdb.Query(`
+		SELECT c.id, c.name, c.parent_id, c.cover_path, c.open_method, c.open_gp_method, c.color, c.icon, c.sort_order, c.pinned,
+		COALESCE(NULLIF(c.cover_path, ''), (SELECT cover_path FROM tabs WHERE category_id = c.id ORDER BY added_at ASC LIMIT 1), '') as effective_cover_path,
+		MAX(t.last_opened) as max_opened
+		FROM categories c
+		JOIN tabs t ON c.id = t.category_id
+		GROUP BY c.id
+		ORDER BY max_opened DESC
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return []Category{}, err
+	}
+	defer rows.Close()
+
+	categories := []Category{}
+	for rows.Next() {
+		var c Category
+		var maxOpened int64
+		if err := rows.Scan(&c.ID, &c.Name, &c.ParentID, &c.CoverPath, &c.OpenMethod, &c.OpenGpMethod, &c.Color, &c.Icon, &c.SortOrder, &c.Pinned, &c.EffectiveCoverPath, &maxOpened); err != nil {
+			return nil, err
+		}
+		categories = append(categories, c)
+	}
+	return categories, nil
+}
+
+func (s *DBStore) GetRecentTabs(limit int) ([]Tab, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+
+	rows, err := s.rdb.Query(`
+		SELECT id, title, artist, album, file_path, type, is_managed, cover_path, category_id, country, language, COALESCE(tag, ''), added_at, last_opened, COALESCE(file_hash, ''), is_missing, practice_status, rating, difficulty, needs_review, song_key, capo, COALESCE(script, ''), genre, year, COALESCE(file_size, 0), COALESCE(cover_color, ''), bpm, duration, source_path 
+		FROM tabs 
+		WHERE last_opened > 0
+		ORDER BY last_opened DESC 
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return []Tab{}, err
+	}
+	defer rows.Close()
+
+	tabs := []Tab{}
+	tabMap := make(map[string]*Tab)
+	
+	for rows.Next() {
+		var t Tab
+		var isManaged int
+		var legacyCatID sql.NullString
+		if err := rows.Scan(&t.ID, &t.Title, &t.Artist, &t.Album, &t.FilePath, &t.Type, &isManaged, &t.CoverPath, &legacyCatID, &t.Country, &t.Language, &t.Tag, &t.AddedAt, &t.LastOpened, &t.FileHash, &t.IsMissing, &t.PracticeStatus, &t.Rating, &t.Difficulty, &t.NeedsReview, &t.Key, &t.Capo, &t.Script, &t.Genre, &t.Year, &t.FileSize, &t.CoverColor, &t.Bpm, &t.Duration, &t.SourcePath); err != nil {
+			return nil, err
+		}
+		t.IsManaged = isManaged == 1
+		t.CategoryIDs = []string{}
+		tabs = append(tabs, t)
+		tabMap[t.ID] = &tabs[len(tabs)-1]
+	}
+
+	if len(tabs) > 0 {
+		// Fetch categories for these tabs
+		// Using a simpler approach: fetch all for these IDs
+		// Since it's recent tabs (small limit), we can iterate or use IN clause
+		// IN clause is better
+		placeholders := strings.Repeat("?,", len(tabs))
+		placeholders = placeholders[:len(placeholders)-1]
+		ids := make([]interface{}, len(tabs))
+		for i, t := range tabs {
+			ids[i] = t.ID
+		}
+
+		catRows, err := s.rdb.Query(fmt.Sprintf("SELECT tab_id, category_id FROM tab_categories WHERE tab_id IN (%s)", placeholders), ids...)
+		if err == nil {
+			defer catRows.Close()
+			for catRows.Next() {
+				var tID, cID string
+				if err := catRows.Scan(&tID, &cID); err == nil {
+					if tab, ok := tabMap[tID]; ok {
+						tab.CategoryIDs = append(tab.CategoryIDs, cID)
+					}
+				}
+			}
+		}
+	}
+
+	return tabs, nil
+}
+
+// tabColumns are the columns of a tab row, in the order queryTabs scans them
+const tabColumns = "tabs.id, tabs.title, tabs.artist, tabs.album, tabs.file_path, tabs.type, tabs.is_managed, tabs.cover_path, tabs.category_id, tabs.country, tabs.language, COALESCE(tabs.tag, ''), tabs.added_at, tabs.last_opened, COALESCE(tabs.file_hash, ''), tabs.is_missing, tabs.practice_status, tabs.rating, tabs.difficulty, tabs.needs_review, tabs.song_key, tabs.capo, COALESCE(tabs.script, ''), tabs.genre, tabs.year, COALESCE(tabs.file_size, 0), COALESCE(tabs.cover_color, ''), tabs.bpm, tabs.duration, tabs.source_path"
+
+// queryTabs runs a query selecting tabColumns and returns its tabs with
+// their categories
+func (s *DBStore) queryTabs(query string, args ...interface{}) ([]Tab, error) {
+	rows, err := s.rdb.Query(query, args...)
+	if err != nil {
+		return []Tab{}, err
+	}
+	defer rows.Close()
+
+	tabs := []Tab{}
+	for rows.Next() {
+		var t Tab
+		var isManaged int
+		var legacyCatID sql.NullString
+		if err := rows.Scan(&t.ID, &t.Title, &t.Artist, &t.Album, &t.FilePath, &t.Type, &isManaged, &t.CoverPath, &legacyCatID, &t.Country, &t.Language, &t.Tag, &t.AddedAt, &t.LastOpened, &t.FileHash, &t.IsMissing, &t.PracticeStatus, &t.Rating, &t.Difficulty, &t.NeedsReview, &t.Key, &t.Capo, &t.Script, &t.Genre, &t.Year, &t.FileSize, &t.CoverColor, &t.Bpm, &t.Duration, &t.SourcePath); err != nil {
+			return nil, err
+		}
+		t.IsManaged = isManaged == 1
+		t.CategoryIDs = []string{}
+		tabs = append(tabs, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(tabs) == 0 {
+		return tabs, nil
+	}
+
+	tabMap := make(map[string]*Tab, len(tabs))
+	ids := make([]interface{}, len(tabs))
+	for i := range tabs {
+		tabMap[tabs[i].ID] = &tabs[i]
+		ids[i] = tabs[i].ID
+	}
+	placeholders := strings.Repeat("?,", len(ids))
+	catRows, err := s.rdb.Query(fmt.Sprintf("SELECT tab_id, category_id FROM tab_categories WHERE tab_id IN (%s)", placeholders[:len(placeholders)-1]), ids...)
+	if err != nil {
+		return nil, err
+	}
+	defer catRows.Close()
+	for catRows.Next() {
+		var tID, cID string
+		if err := catRows.Scan(&tID, &cID); err != nil {
+			return nil, err
+		}
+		if tab, ok := tabMap[tID]; ok {
+			tab.CategoryIDs = append(tab.CategoryIDs, cID)
+		}
+	}
+	return tabs, catRows.Err()
+}
+
+// staleWeight weighs a tab by the days since it was last opened, capped at
+// 30 so tabs never opened are favored without crowding out the rest
+const staleWeight = "(1 + MIN(? - tabs.last_opened, 30 * 86400) / 86400.0)"
+
+// GetRandomTabs returns up to count random tabs matching filters, skipping
+// tabs whose file is missing. With preferStale, the chance of a tab grows
+// with the days since it was last opened: a tab not opened for a
+// month or never is 31 times as likely to be picked as one opened today.
+func (s *DBStore) GetRandomTabs(count int, filters TabFilters, preferStale bool) ([]Tab, error) {
+	whereClauses, args := tabFilterClauses(filters)
+	whereClauses = append([]string{"tabs.is_missing = 0"}, whereClauses...)
+
+	orderBy := "RANDOM()"
+	if preferStale {
+		// Weighted sampling: the largest u^(1/weight), u uniform in (0, 1)
+		orderBy = "pow((ABS(RANDOM()) % 1000000 + 1) / 1000001.0, 1.0 / " + staleWeight + ") DESC"
+		args = append(args, time.Now().Unix())
+	}
+	args = append(args, count)
+
+	return s.queryTabs(fmt.Sprintf("SELECT %s FROM tabs WHERE %s ORDER BY %s LIMIT ?",
+		tabColumns, strings.Join(whereClauses, " AND "), orderBy), args...)
+}
+
+// nextCategoryOrder is the sort order after the last child of the parent
+// given as its argument
+const nextCategoryOrder = "(SELECT COALESCE(MAX(sort_order), -1) + 1 FROM categories WHERE parent_id = ?)"
+
+// AddCategory adds a category after its siblings, or updates the one with
+// the same ID, keeping its order and pin
+func (s *DBStore) AddCategory(cat Category) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Upsert instead of INSERT OR REPLACE: REPLACE deletes the old row, which
+	// would cascade to tab_categories and reminders
+	_, err := s.exec(`
+		INSERT INTO categories (id, name, parent_id, cover_path, open_method, open_gp_method, color, icon, sort_order)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, `+nextCategoryOrder+`)
+		ON CONFLICT(id) DO UPDATE SET
+			name = excluded.name, parent_id = excluded.parent_id, cover_path = excluded.cover_path,
+			open_method = excluded.open_method, open_gp_method = excluded.open_gp_method,
+			color = excluded.color, icon = excluded.icon
+	`, cat.ID, cat.Name, cat.ParentID, cat.CoverPath, cat.OpenMethod, cat.OpenGpMethod, cat.Color, cat.Icon, cat.ParentID)
+	return err
+}
+
+func (s *DBStore) DeleteCategory(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Start a transaction
+	tx, err := s.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Move sub-categories to root (or delete them recursively? Current behavior is move to root)
+	if _, err := tx.Exec("UPDATE categories SET parent_id = '' WHERE parent_id = ?", id); err != nil {
+		return err
+	}
+
+	// Delete the category.
+	// Since we enabled foreign keys and set ON DELETE CASCADE on tab_categories(category_id),
+	// this will automatically remove associations in tab_categories.
+	if _, err := tx.Exec("DELETE FROM categories WHERE id = ?", id); err != nil {
+		return err
+	}
+
+	// Optional: Clear legacy category_id in tabs if it matches (for consistency)
+	if _, err := tx.Exec("UPDATE tabs SET category_id = '' WHERE category_id = ?", id); err != nil {
+		// Ignore error, non-critical
+	}
+
+	return tx.Commit()
+}
+
+// categoryTree selects the IDs of a category (the first argument) and of
+// all its subcategories as "tree". UNION drops the rows already seen, so a
+// cycle left by older versions ends the recursion instead of looping.
+const categoryTree = `
+	WITH RECURSIVE tree(id) AS (
+		SELECT ?
+		UNION
+		SELECT categories.id FROM categories JOIN tree ON categories.parent_id = tree.id
+	)`
+
+// MoveCategory makes newParentID ("" for the root) the parent of a
+// category. Moving a category into itself or one of its subcategories
+// would make a cycle, and fails.
+func (s *DBStore) MoveCategory(id, newParentID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if newParentID != "" {
+		var cycle bool
+		err := s.rdb.QueryRow(categoryTree+" SELECT EXISTS (SELECT 1 FROM tree WHERE id = ?)", id, newParentID).Scan(&cycle)
+		if err != nil {
+			return err
+		}
+		if cycle {
+			return fmt.Errorf("cannot move a category into itself or one of its subcategories")
+		}
+	}
+
+	res, err := s.exec("UPDATE categories SET parent_id = ?, sort_order = "+nextCategoryOrder+" WHERE id = ?", newParentID, newParentID, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("category not found: %s", id)
+	}
+	return nil
+}
+
+// ReorderCategories sets the order of categories, usually the children of
+// one parent, to the order of ids
+func (s *DBStore) ReorderCategories(ids []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for i, id := range ids {
+		if err := execOne(tx, "category", id, "UPDATE categories SET sort_order = ? WHERE id = ?", i, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// SetCategoryPinned pins a category before its siblings or unpins it
+func (s *DBStore) SetCategoryPinned(id string, pinned bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res, err := s.exec("UPDATE categories SET pinned = ? WHERE id = ?", pinned, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("category not found: %s", id)
+	}
+	return nil
+}
+
+// GetTabsInCategoryTree returns the tabs in a category or any of its
+// subcategories, each once, by title
+func (s *DBStore) GetTabsInCategoryTree(categoryID string) ([]Tab, error) {
+	inTree := "SELECT tab_id FROM tab_categories WHERE category_id IN (SELECT id FROM tree)"
+	rows, err := s.rdb.Query(categoryTree+`
+		SELECT id, title, artist, album, file_path, type, is_managed, cover_path, category_id, country, language, COALESCE(tag, ''), added_at, last_opened, COALESCE(file_hash, ''), is_missing, practice_status, rating, difficulty, needs_review, song_key, capo, COALESCE(script, ''), genre, year, COALESCE(file_size, 0), COALESCE(cover_color, ''), bpm, duration, source_path
+		FROM tabs
+		WHERE id IN (`+inTree+`)
+		ORDER BY title ASC
+	`, categoryID)
+	if err != nil {
+		return []Tab{}, err
+	}
+	defer rows.Close()
+
+	tabs := []Tab{}
+	for rows.Next() {
+		var t Tab
+		var isManaged int
+		var legacyCatID sql.NullString
+		if err := rows.Scan(&t.ID, &t.Title, &t.Artist, &t.Album, &t.FilePath, &t.Type, &isManaged, &t.CoverPath, &legacyCatID, &t.Country, &t.Language, &t.Tag, &t.AddedAt, &t.LastOpened, &t.FileHash, &t.IsMissing, &t.PracticeStatus, &t.Rating, &t.Difficulty, &t.NeedsReview, &t.Key, &t.Capo, &t.Script, &t.Genre, &t.Year, &t.FileSize, &t.CoverColor, &t.Bpm, &t.Duration, &t.SourcePath); err != nil {
+			return nil, err
+		}
+		t.IsManaged = isManaged == 1
+		t.CategoryIDs = []string{}
+		tabs = append(tabs, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]*Tab, len(tabs))
+	for i := range tabs {
+		byID[tabs[i].ID] = &tabs[i]
+	}
+	catRows, err := s.rdb.Query(categoryTree+" SELECT tab_id, category_id FROM tab_categories WHERE tab_id IN ("+inTree+")", categoryID)
+	if err != nil {
+		return nil, err
+	}
+	defer catRows.Close()
+	for catRows.Next() {
+		var tabID, catID string
+		if err := catRows.Scan(&tabID, &catID); err != nil {
+			return nil, err
+		}
+		if t, ok := byID[tabID]; ok {
+			t.CategoryIDs = append(t.CategoryIDs, catID)
+		}
+	}
+	return tabs, catRows.Err()
+}
+
+// AddCategories adds categories in one transaction; parents must come
+// before their children
+func (s *DBStore) AddCategories(cats []Category) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, cat := range cats {
+		if _, err := tx.Exec(`
+			INSERT INTO categories (id, name, parent_id, cover_path, open_method, open_gp_method, color, icon, sort_order)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, `+nextCategoryOrder+`)
+		`, cat.ID, cat.Name, cat.ParentID, cat.CoverPath, cat.OpenMethod, cat.OpenGpMethod, cat.Color, cat.Icon, cat.ParentID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// === Batch Operations ===
+
+// ApplyOperations applies ops in order in one transaction: either all of
+// them are applied or, if one fails, none. at is the Unix time tabs are
+// filed into categories.
+func (s *DBStore) ApplyOperations(ops []Operation, at int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for i, op := range ops {
+		if err := applyOperation(tx, op, at); err != nil {
+			return fmt.Errorf("operation %d (%s): %w", i+1, op.Op, err)
+		}
+	}
+	return tx.Commit()
+}
+
+func applyOperation(tx *sql.Tx, op Operation, at int64) error {
+	switch op.Op {
+	case "createCategory":
+		if op.CategoryID == "" || strings.TrimSpace(op.Name) == "" {
+			return fmt.Errorf("category ID and name are required")
+		}
+		_, err := tx.Exec("INSERT INTO categories (id, name, parent_id, sort_order) VALUES (?, ?, ?, "+nextCategoryOrder+")",
+			op.CategoryID, op.Name, op.ParentID, op.ParentID)
+		return err
+	case "renameCategory":
+		if strings.TrimSpace(op.Name) == "" {
+			return fmt.Errorf("name is required")
+		}
+		return execOne(tx, "category", op.CategoryID, "UPDATE categories SET name = ? WHERE id = ?", op.Name, op.CategoryID)
+	case "moveCategory":
+		if op.ParentID != "" {
+			var cycle bool
+			err := tx.QueryRow(categoryTree+" SELECT EXISTS (SELECT 1 FROM tree WHERE id = ?)", op.CategoryID, op.ParentID).Scan(&cycle)
+			if err != nil {
+				return err
+			}
+			if cycle {
+				return fmt.Errorf("cannot move a category into itself or one of its subcategories")
+			}
+		}
+		return execOne(tx, "category", op.CategoryID, "UPDATE categories SET parent_id = ?, sort_order = "+nextCategoryOrder+" WHERE id = ?",
+			op.ParentID, op.ParentID, op.CategoryID)
+	case "deleteCategory":
+		// As DeleteCategory: subcategories move to the root
+		if _, err := tx.Exec("UPDATE categories SET parent_id = '' WHERE parent_id = ?", op.CategoryID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("UPDATE tabs SET category_id = '' WHERE category_id = ?", op.CategoryID); err != nil {
+			return err
+		}
+		return execOne(tx, "category", op.CategoryID, "DELETE FROM categories WHERE id = ?", op.CategoryID)
+	case "moveTabs":
+		var categoryIDs []string
+		if op.CategoryID != "" {
+			categoryIDs = []string{op.CategoryID}
+		}
+		return forEachTab(tx, op.TabIDs, func(id string) error {
+			return writeTabCategories(tx, id, categoryIDs, at)
+		})
+	case "addTabsToCategory":
+		return forEachTab(tx, op.TabIDs, func(id string) error {
+			if _, err := tx.Exec("INSERT OR IGNORE INTO tab_categories (tab_id, category_id, added_at) VALUES (?, ?, ?)", id, op.CategoryID, at); err != nil {
+				return err
+			}
+			_, err := tx.Exec("UPDATE tabs SET category_id = ? WHERE id = ? AND COALESCE(category_id, '') = ''", op.CategoryID, id)
+			return err
+		})
+	case "removeTabsFromCategory":
+		return forEachTab(tx, op.TabIDs, func(id string) error {
+			if _, err := tx.Exec("DELETE FROM tab_categories WHERE tab_id = ? AND category_id = ?", id, op.CategoryID); err != nil {
+				return err
+			}
+			_, err := tx.Exec(`
+				UPDATE tabs SET category_id = COALESCE((SELECT category_id FROM tab_categories WHERE tab_id = ? ORDER BY added_at LIMIT 1), '')
+				WHERE id = ? AND category_id = ?
+			`, id, id, op.CategoryID)
+			return err
+		})
+	case "setRating":
+		return forEachTab(tx, op.TabIDs, func(id string) error {
+			_, err := tx.Exec("UPDATE tabs SET rating = ? WHERE id = ?", op.Rating, id)
+			return err
+		})
+	case "setPracticeStatus":
+		return forEachTab(tx, op.TabIDs, func(id string) error {
+			_, err := tx.Exec("UPDATE tabs SET practice_status = ? WHERE id = ?", op.PracticeStatus, id)
+			return err
+		})
+	case "setDifficulty":
+		return forEachTab(tx, op.TabIDs, func(id string) error {
+			_, err := tx.Exec("UPDATE tabs SET difficulty = ? WHERE id = ?", op.Difficulty, id)
+			return err
+		})
+	}
+	return fmt.Errorf("unknown operation")
+}
+
+// execOne runs a statement that must change the row of kind with id
+func execOne(tx *sql.Tx, kind, id, query string, args ...interface{}) error {
+	res, err := tx.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("%s not found: %s", kind, id)
+	}
+	return nil
+}
+
+// forEachTab runs fn on each tab of ids, failing on unknown tabs
+func forEachTab(tx *sql.Tx, ids []string, fn func(id string) error) error {
+	for _, id := range ids {
+		var exists bool
+		if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM tabs WHERE id = ?)", id).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("tab not found: %s", id)
+		}
+		if err := fn(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// === Category Template Operations ===
+
+// GetCategoryTemplates returns the built-in templates, then the saved ones
+// by name
+func (s *DBStore) GetCategoryTemplates() ([]CategoryTemplate, error) {
+	templates := append([]CategoryTemplate{}, builtinCategoryTemplates...)
+	rows, err := s.rdb.Query("SELECT id, name, folders FROM category_templates ORDER BY name COLLATE NOCASE")
+	if err != nil {
+		return templates, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var t CategoryTemplate
+		var folders string
+		if err := rows.Scan(&t.ID, &t.Name, &folders); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(folders), &t.Folders); err != nil {
+			return nil, fmt.Errorf("invalid folders of template %s: %w", t.Name, err)
+		}
+		templates = append(templates, t)
+	}
+	return templates, rows.Err()
+}
+
+// GetCategoryTemplate returns a built-in or saved template, or nil if there
+// is none with that ID
+func (s *DBStore) GetCategoryTemplate(id string) (*CategoryTemplate, error) {
+	templates, err := s.GetCategoryTemplates()
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range templates {
+		if t.ID == id {
+			return &t, nil
+		}
+	}
+	return nil, nil
+}
+
+// SaveCategoryTemplate adds or replaces a saved template
+func (s *DBStore) SaveCategoryTemplate(t CategoryTemplate) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	folders, err := json.Marshal(t.Folders)
+	if err != nil {
+		return err
+	}
+	_, err = s.exec(`
+		INSERT INTO category_templates (id, name, folders) VALUES (?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET name = excluded.name, folders = excluded.folders
+	`, t.ID, t.Name, string(folders))
+	return err
+}
+
+// DeleteCategoryTemplate removes a saved template
+func (s *DBStore) DeleteCategoryTemplate(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.exec("DELETE FROM category_templates WHERE id = ?", id)
+	return err
+}
+
+// === Key Binding Profile Operations ===
+
+// GetKeyBindingProfiles returns the saved key binding profiles by name.
+// The active profile has the current key bindings and is listed even
+// before it was first saved.
+func (s *DBStore) GetKeyBindingProfiles() ([]KeyBindingProfile, error) {
+	rows, err := s.rdb.Query("SELECT name, bindings, updated_at FROM keybinding_profiles ORDER BY name COLLATE NOCASE")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	s.mu.RLock()
+	active, current := s.Settings.KeyProfile, maps.Clone(s.Settings.KeyBindings)
+	s.mu.RUnlock()
+
+	var profiles []KeyBindingProfile
+	found := false
+	for rows.Next() {
+		var p KeyBindingProfile
+		var bindings string
+		if err := rows.Scan(&p.Name, &bindings, &p.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if p.Name == active {
+			p.KeyBindings, p.Active, found = current, true, true
+		} else if err := json.Unmarshal([]byte(bindings), &p.KeyBindings); err != nil {
+			return nil, fmt.Errorf("invalid key bindings of profile %s: %w", p.Name, err)
+		}
+		profiles = append(profiles, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if !found {
+		profiles = append([]KeyBindingProfile{{Name: active, KeyBindings: current, Active: true}}, profiles...)
+	}
+	return profiles, nil
+}
+
+// GetKeyBindingProfile returns a key binding profile, or nil if there is
+// none with that name
+func (s *DBStore) GetKeyBindingProfile(name string) (*KeyBindingProfile, error) {
+	profiles, err := s.GetKeyBindingProfiles()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range profiles {
+		if p.Name == name {
+			return &p, nil
+		}
+	}
+	return nil, nil
+}
+
+// SaveKeyBindingProfile adds or replaces a key binding profile. The
+// bindings are validated with ValidateKeyBindings; saving the active
+// profile changes the current key bindings.
+func (s *DBStore) SaveKeyBindingProfile(name string, kb KeyBindings) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if name == "" {
+		return fmt.Errorf("profile name is required")
+	}
+	kb = withDefaultKeys(kb)
+	if err := ValidateKeyBindings(kb); err != nil {
+		return err
+	}
+	if name == s.Settings.KeyProfile {
+		s.Settings.KeyBindings = kb
+		if err := s.saveSettings(); err != nil {
+			return err
+		}
+	}
+	return s.saveKeyBindingProfile(name, kb)
+}
+
+// SwitchKeyBindingProfile makes a saved profile the active one and returns
+// its key bindings. The bindings of the profile switched from are saved
+// first, so they are kept.
+func (s *DBStore) SwitchKeyBindingProfile(name string) (KeyBindings, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if name == s.Settings.KeyProfile {
+		return maps.Clone(s.Settings.KeyBindings), nil
+	}
+	var bindings string
+	err := s.rdb.QueryRow("SELECT bindings FROM keybinding_profiles WHERE name = ?", name).Scan(&bindings)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("key binding profile not found: %s", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var kb KeyBindings
+	if err := json.Unmarshal([]byte(bindings), &kb); err != nil {
+		return nil, fmt.Errorf("invalid key bindings of profile %s: %w", name, err)
+	}
+
+	if err := s.saveKeyBindingProfile(s.Settings.KeyProfile, s.Settings.KeyBindings); err != nil {
+		return nil, err
+	}
+	s.Settings.KeyProfile = name
+	s.Settings.KeyBindings = withDefaultKeys(kb)
+	if err := s.saveSettings(); err != nil {
+		return nil, err
+	}
+	return maps.Clone(s.Settings.KeyBindings), nil
+}
+
+// DeleteKeyBindingProfile removes a key binding profile. The active profile
+// can't be deleted; switch to another one first.
+func (s *DBStore) DeleteKeyBindingProfile(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if name == s.Settings.KeyProfile {
+		return fmt.Errorf("can't delete the active key binding profile")
+	}
+	_, err := s.exec("DELETE FROM keybinding_profiles WHERE name = ?", name)
+	return err
+}
+
+// saveKeyBindingProfile writes the bindings of a profile. Callers hold mu.
+func (s *DBStore) saveKeyBindingProfile(name string, kb KeyBindings) error {
+	data, err := json.Marshal(kb)
+	if err != nil {
+		return err
+	}
+	_, err = s.exec(`
+		INSERT INTO keybinding_profiles (name, bindings, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET bindings = excluded.bindings, updated_at = excluded.updated_at
+	`, name, string(data), time.Now().Unix())
 	return err
 }
 
 // === Settings Operations ===
 
 func (s *DBStore) GetSettings() Settings {
-	return s.Settings
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	settings := s.Settings
+	settings.KeyBindings = maps.Clone(settings.KeyBindings)
+	settings.PedalBindings = maps.Clone(settings.PedalBindings)
+	settings.PedalProfiles = clonePedalProfiles(settings.PedalProfiles)
+	return settings
 }
 
+// UpdateSettings replaces the settings. Changed key bindings are validated
+// with ValidateKeyBindings; unchanged ones are saved as they are.
 func (s *DBStore) UpdateSettings(settings Settings) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	settings.KeyBindings = withDefaultKeys(settings.KeyBindings)
+	// The active profile only changes with SwitchKeyBindingProfile
+	settings.KeyProfile = s.Settings.KeyProfile
+	// Saving the settings loaded before a new token was made must keep it
+	settings.LANServerToken = s.Settings.LANServerToken
+	changed := !maps.Equal(settings.KeyBindings, s.Settings.KeyBindings)
+	if changed {
+		if err := ValidateKeyBindings(settings.KeyBindings); err != nil {
+			return err
+		}
+	}
 	s.Settings = settings
+	if err := s.saveSettings(); err != nil {
+		return err
+	}
+	if changed {
+		return s.saveKeyBindingProfile(s.Settings.KeyProfile, s.Settings.KeyBindings)
+	}
+	return nil
+}
+
+// GetSetting returns a single setting by its JSON key; nested settings use
+// a dotted path, e.g. "keyBindings.playPause"
+func (s *DBStore) GetSetting(key string) (interface{}, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	value, ok := lookupSetting(s.allSettings(), key)
+	if !ok {
+		return nil, fmt.Errorf("setting not found: %s", key)
+	}
+	return value, nil
+}
+
+// SetSetting changes a single setting by its JSON key (see GetSetting).
+// Keys that are not fields of Settings are stored as is.
+func (s *DBStore) SetSetting(key string, value interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	// Save each setting
-	settingsMap := map[string]string{
-		"theme":                       settings.Theme,
-		"background":                  settings.Background,
-		"bgType":                      settings.BgType,
-		"openMethod":                  settings.OpenMethod,
-		"openGpMethod":                settings.OpenGpMethod,
-		"audioDevice":                 settings.AudioDevice,
-		"autoSyncEnabled":             fmt.Sprintf("%v", settings.AutoSyncEnabled),
-		"autoSyncFrequency":           settings.AutoSyncFrequency,
-		"lastSyncTime":                fmt.Sprintf("%d", settings.LastSyncTime),
-		"syncStrategy":                settings.SyncStrategy,
-		"syncPaths":                   strings.Join(settings.SyncPaths, "|"),
-		"keyBindings.scrollDown":      settings.KeyBindings.ScrollDown,
-		"keyBindings.scrollUp":        settings.KeyBindings.ScrollUp,
-		"keyBindings.metronome":       settings.KeyBindings.Metronome,
-		"keyBindings.playPause":       settings.KeyBindings.PlayPause,
-		"keyBindings.stop":            settings.KeyBindings.Stop,
-		"keyBindings.bpmPlus":         settings.KeyBindings.BpmPlus,
-		"keyBindings.bpmMinus":        settings.KeyBindings.BpmMinus,
-		"keyBindings.toggleLoop":      settings.KeyBindings.ToggleLoop,
-		"keyBindings.clearSelection":  settings.KeyBindings.ClearSelection,
-		"keyBindings.jumpToBar":       settings.KeyBindings.JumpToBar,
-		"keyBindings.jumpToStart":     settings.KeyBindings.JumpToStart,
-		"keyBindings.autoScroll":      settings.KeyBindings.AutoScroll,
-		"keyBindings.scrollSpeedUp":   settings.KeyBindings.ScrollSpeedUp,
-		"keyBindings.scrollSpeedDown": settings.KeyBindings.ScrollSpeedDown,
-	}
-
-	for key, value := range settingsMap {
-		if _, err := s.db.Exec("INSERT OR REPLACE INTO settings (key, value) VALUES (?, ?)", key, value); err != nil {
+	if key == "" {
+		return fmt.Errorf("empty setting key")
+	}
+	values := s.allSettings()
+	if err := assignSetting(values, key, value); err != nil {
+		return err
+	}
+	previous, previousExtra := s.Settings, s.settingsExtra
+	if err := s.applySettings(values); err != nil {
+		return fmt.Errorf("invalid value for setting %s: %w", key, err)
+	}
+	s.Settings.KeyProfile = previous.KeyProfile
+	changed := !maps.Equal(s.Settings.KeyBindings, previous.KeyBindings)
+	if changed {
+		if err := ValidateKeyBindings(s.Settings.KeyBindings); err != nil {
+			s.Settings, s.settingsExtra = previous, previousExtra
 			return err
 		}
 	}
-
+	if err := s.saveSettings(); err != nil {
+		return err
+	}
+	if changed {
+		return s.saveKeyBindingProfile(s.Settings.KeyProfile, s.Settings.KeyBindings)
+	}
 	return nil
 }
 
 // HasData checks if the database has any data
 func (s *DBStore) HasData() bool {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
 	var count int
-	err := s.db.QueryRow("SELECT COUNT(*) FROM tabs").Scan(&count)
+	err := s.rdb.QueryRow("SELECT COUNT(*) FROM tabs").Scan(&count)
 	if err != nil {
 		return false
 	}
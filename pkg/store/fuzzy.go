@@ -0,0 +1,159 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ftsVocabSchema exposes the terms of the full-text index per column, which
+// SuggestCorrection matches misspelled words against
+const ftsVocabSchema = `
+	CREATE VIRTUAL TABLE IF NOT EXISTS tabs_fts_vocab USING fts5vocab(tabs_fts, 'col');
+`
+
+// minFuzzyWordLength is the length under which words are never corrected:
+// short words are one typo away from too many others
+const minFuzzyWordLength = 4
+
+// maxTypos is how many edits a word of length n may be from its correction
+func maxTypos(n int) int {
+	switch {
+	case n < minFuzzyWordLength:
+		return 0
+	case n < 8:
+		return 1
+	}
+	return 2
+}
+
+// SuggestCorrection returns query with its misspelled words replaced by the
+// closest terms of the given fields in the full-text index ("Metalica" →
+// "metallica"), or "" if every word is known or no close term exists. The
+// last word may be the beginning of a term, as searches match prefixes.
+// Among terms as close, the one in the most tabs wins. Field terms such as
+// artist:"jason mraz" are kept as typed.
+func (s *DBStore) SuggestCorrection(query string, fields []string) (string, error) {
+	q, err := parseSearchQuery(query)
+	if err != nil {
+		return "", nil
+	}
+	words := strings.FieldsFunc(strings.ToLower(q.Text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 || len(fields) == 0 {
+		return "", nil
+	}
+
+	placeholders := strings.Repeat("?,", len(fields))
+	args := make([]interface{}, len(fields))
+	for i, f := range fields {
+		args[i] = f
+	}
+	rows, err := s.rdb.Query(fmt.Sprintf(
+		"SELECT term, SUM(doc) FROM tabs_fts_vocab WHERE col IN (%s) GROUP BY term",
+		placeholders[:len(placeholders)-1]), args...)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	var terms []vocabTerm
+	known := map[string]bool{}
+	for rows.Next() {
+		var t string
+		var docs int
+		if err := rows.Scan(&t, &docs); err != nil {
+			return "", err
+		}
+		terms = append(terms, vocabTerm{[]rune(t), docs})
+		known[t] = true
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	corrected := false
+	for i, word := range words {
+		last := i == len(words)-1
+		if known[word] {
+			continue
+		}
+		w := []rune(word)
+		if last && hasTermWithPrefix(terms, w) {
+			continue
+		}
+
+		limit := maxTypos(len(w))
+		best, bestDistance, bestDocs := "", limit+1, 0
+		for _, t := range terms {
+			d := editDistance(w, t.text, limit)
+			if last && len(t.text) > len(w) {
+				// The word may be the misspelled beginning of the term
+				d = min(d, editDistance(w, t.text[:len(w)], limit))
+			}
+			if d < bestDistance || (d == bestDistance && d <= limit && t.docs > bestDocs) {
+				best, bestDistance, bestDocs = string(t.text), d, t.docs
+			}
+		}
+		if best != "" {
+			words[i] = best
+			corrected = true
+		}
+	}
+	if !corrected {
+		return "", nil
+	}
+	return strings.Join(append(words, q.Terms...), " "), nil
+}
+
+// vocabTerm is a term of the full-text index and the number of tabs having it
+type vocabTerm struct {
+	text []rune
+	docs int
+}
+
+// hasTermWithPrefix reports whether one of terms starts with prefix
+func hasTermWithPrefix(terms []vocabTerm, prefix []rune) bool {
+	for _, t := range terms {
+		if len(t.text) >= len(prefix) && string(t.text[:len(prefix)]) == string(prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// editDistance returns the optimal string alignment distance between a and
+// b (insertions, deletions, substitutions and swaps of adjacent letters),
+// or limit+1 once it exceeds limit
+func editDistance(a, b []rune, limit int) int {
+	if d := len(a) - len(b); d > limit || -d > limit {
+		return limit + 1
+	}
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+			rowMin = min(rowMin, cur[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return min(prev[len(b)], limit+1)
+}
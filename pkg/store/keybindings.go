@@ -0,0 +1,150 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// KeyAction is an action that can be bound to a key
+type KeyAction struct {
+	Name       string `json:"name"` // Key in KeyBindings
+	Label      string `json:"label"`
+	DefaultKey string `json:"defaultKey"`
+}
+
+// keyActions is the registry of bindable actions, in the order they are
+// listed in the settings. To add a shortcut, add its action here; settings
+// loading fills in its default key.
+var keyActions = []KeyAction{
+	{Name: "scrollDown", Label: "Scroll Down", DefaultKey: "j"},
+	{Name: "scrollUp", Label: "Scroll Up", DefaultKey: "k"},
+	{Name: "metronome", Label: "Toggle Metronome", DefaultKey: "m"},
+	{Name: "playPause", Label: "Play / Pause", DefaultKey: "p"},
+	{Name: "stop", Label: "Stop / Rewind", DefaultKey: "o"},
+	{Name: "bpmPlus", Label: "Increase BPM", DefaultKey: "l"},
+	{Name: "bpmMinus", Label: "Decrease BPM", DefaultKey: "h"},
+	{Name: "toggleLoop", Label: "Toggle Loop", DefaultKey: "r"},
+	{Name: "clearSelection", Label: "Clear Selection", DefaultKey: "escape"},
+	{Name: "jumpToBar", Label: "Jump to Bar", DefaultKey: "t"},
+	{Name: "jumpToStart", Label: "Jump to Start", DefaultKey: "i"},
+	{Name: "autoScroll", Label: "Toggle Auto-Scroll", DefaultKey: "n"},
+	{Name: "scrollSpeedUp", Label: "Increase Scroll Speed", DefaultKey: ","},
+	{Name: "scrollSpeedDown", Label: "Decrease Scroll Speed", DefaultKey: "."},
+}
+
+// KeyActions returns the bindable actions
+func KeyActions() []KeyAction {
+	return append([]KeyAction{}, keyActions...)
+}
+
+// DefaultKeyBindings returns the key bindings of a new installation
+func DefaultKeyBindings() KeyBindings {
+	kb := make(KeyBindings, len(keyActions))
+	for _, a := range keyActions {
+		kb[a.Name] = a.DefaultKey
+	}
+	return kb
+}
+
+// withDefaultKeys returns a copy of kb with the default key of every
+// registered action missing from it. Bindings of actions that are not
+// registered are kept, so the frontend can add actions of its own.
+func withDefaultKeys(kb KeyBindings) KeyBindings {
+	merged := DefaultKeyBindings()
+	for action, key := range kb {
+		merged[action] = key
+	}
+	return merged
+}
+
+// reservedKeys can't be bound: the webview handles them (reload, full
+// screen, developer tools) or they are needed to use forms and dialogs.
+// Keys are KeyboardEvent.key values in lower case.
+var reservedKeys = map[string]bool{
+	"tab":      true,
+	"enter":    true,
+	"shift":    true,
+	"control":  true,
+	"alt":      true,
+	"meta":     true,
+	"capslock": true,
+	"f5":       true,
+	"f11":      true,
+	"f12":      true,
+}
+
+// KeyBindingConflict is a key that can't be bound as requested
+type KeyBindingConflict struct {
+	Key     string   `json:"key"`
+	Actions []string `json:"actions"` // e.g. "playPause"
+	Reason  string   `json:"reason"`  // "duplicate" or "reserved"
+}
+
+// KeyBindingsError is returned when saving key bindings with conflicts
+type KeyBindingsError struct {
+	Conflicts []KeyBindingConflict `json:"conflicts"`
+}
+
+func (e *KeyBindingsError) Error() string {
+	parts := make([]string, len(e.Conflicts))
+	for i, c := range e.Conflicts {
+		if c.Reason == "reserved" {
+			parts[i] = fmt.Sprintf("%s is reserved (%s)", c.Key, strings.Join(c.Actions, ", "))
+		} else {
+			parts[i] = fmt.Sprintf("%s is bound to %s", c.Key, strings.Join(c.Actions, " and "))
+		}
+	}
+	return "key binding conflicts: " + strings.Join(parts, "; ")
+}
+
+// ValidateKeyBindings checks that no key is bound to more than one action
+// and that no reserved key is bound. Empty bindings (unbound actions) are
+// allowed. Returns a *KeyBindingsError listing all conflicts.
+func ValidateKeyBindings(kb KeyBindings) error {
+	// Registered actions first, in registry order, so conflicts are
+	// reported in the order of the settings
+	var names []string
+	registered := map[string]bool{}
+	for _, a := range keyActions {
+		registered[a.Name] = true
+		if _, ok := kb[a.Name]; ok {
+			names = append(names, a.Name)
+		}
+	}
+	var others []string
+	for name := range kb {
+		if !registered[name] {
+			others = append(others, name)
+		}
+	}
+	sort.Strings(others)
+	names = append(names, others...)
+
+	var keys []string
+	actions := map[string][]string{}
+	for _, name := range names {
+		key := strings.ToLower(kb[name])
+		if key == "" {
+			continue
+		}
+		if _, ok := actions[key]; !ok {
+			keys = append(keys, key)
+		}
+		actions[key] = append(actions[key], name)
+	}
+
+	var conflicts []KeyBindingConflict
+	for _, key := range keys {
+		switch {
+		case reservedKeys[key]:
+			conflicts = append(conflicts, KeyBindingConflict{Key: key, Actions: actions[key], Reason: "reserved"})
+		case len(actions[key]) > 1:
+			conflicts = append(conflicts, KeyBindingConflict{Key: key, Actions: actions[key], Reason: "duplicate"})
+		}
+	}
+	if len(conflicts) > 0 {
+		return &KeyBindingsError{Conflicts: conflicts}
+	}
+	return nil
+}
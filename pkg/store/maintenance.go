@@ -0,0 +1,84 @@
+package store
+
+import "fmt"
+
+// MaintenanceSteps are the steps of Maintain, in order
+var MaintenanceSteps = []string{"integrity", "fts", "analyze", "vacuum"}
+
+// MaintenanceResult is the outcome of Maintain
+type MaintenanceResult struct {
+	Problems   []string `json:"problems"`   // Reported by the integrity check; empty if the database is sound
+	SizeBefore int64    `json:"sizeBefore"` // Size of the database in bytes
+	SizeAfter  int64    `json:"sizeAfter"`
+}
+
+// Maintain checks the integrity of the database, rebuilds the full-text
+// index, refreshes the statistics of the query planner and compacts the
+// file. A database failing the integrity check is left as it is, with the
+// problems in the result. progress is called before each step of
+// MaintenanceSteps. Writes wait until maintenance is done.
+func (s *DBStore) Maintain(progress func(step string)) (MaintenanceResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := MaintenanceResult{Problems: []string{}}
+	size, err := s.size()
+	if err != nil {
+		return result, err
+	}
+	result.SizeBefore = size
+
+	progress("integrity")
+	rows, err := s.db.Query("PRAGMA integrity_check")
+	if err != nil {
+		return result, fmt.Errorf("integrity check failed: %w", err)
+	}
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			rows.Close()
+			return result, err
+		}
+		if line != "ok" {
+			result.Problems = append(result.Problems, line)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return result, fmt.Errorf("integrity check failed: %w", err)
+	}
+	if len(result.Problems) > 0 {
+		result.SizeAfter = size
+		return result, nil
+	}
+
+	progress("fts")
+	if _, err := s.exec("INSERT INTO tabs_fts(tabs_fts) VALUES('rebuild')"); err != nil {
+		return result, fmt.Errorf("failed to rebuild the full-text index: %w", err)
+	}
+	progress("analyze")
+	if _, err := s.exec("ANALYZE"); err != nil {
+		return result, fmt.Errorf("failed to analyze: %w", err)
+	}
+	progress("vacuum")
+	if _, err := s.exec("VACUUM"); err != nil {
+		return result, fmt.Errorf("failed to vacuum: %w", err)
+	}
+
+	if result.SizeAfter, err = s.size(); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// size returns the size of the database in bytes, without its WAL
+func (s *DBStore) size() (int64, error) {
+	var pages, pageSize int64
+	if err := s.db.QueryRow("PRAGMA page_count").Scan(&pages); err != nil {
+		return 0, err
+	}
+	if err := s.db.QueryRow("PRAGMA page_size").Scan(&pageSize); err != nil {
+		return 0, err
+	}
+	return pages * pageSize, nil
+}
@@ -0,0 +1,71 @@
+package store
+
+import (
+	"maps"
+	"strings"
+)
+
+// Linux key codes sent by page turners (linux/input-event-codes.h)
+const (
+	keyCodeUp       = "key:103"
+	keyCodePageUp   = "key:104"
+	keyCodeLeft     = "key:105"
+	keyCodeRight    = "key:106"
+	keyCodeDown     = "key:108"
+	keyCodePageDown = "key:109"
+)
+
+// pedalPresets are profiles for the key layouts page turners commonly use.
+// They bind any controller; set Device to the page turner's name.
+var pedalPresets = []PedalProfile{
+	{
+		Name: "Arrow Keys",
+		Bindings: PedalBindings{
+			keyCodeLeft:  "scrollUp",
+			keyCodeRight: "scrollDown",
+			keyCodeUp:    "scrollUp",
+			keyCodeDown:  "scrollDown",
+		},
+	},
+	{
+		Name: "Page Up / Page Down",
+		Bindings: PedalBindings{
+			keyCodePageUp:   "scrollUp",
+			keyCodePageDown: "scrollDown",
+		},
+	},
+}
+
+// PedalPresets returns the built-in pedal profiles
+func PedalPresets() []PedalProfile {
+	return clonePedalProfiles(pedalPresets)
+}
+
+// Matches reports whether the profile binds the controller named device
+func (p PedalProfile) Matches(device string) bool {
+	return strings.Contains(strings.ToLower(device), strings.ToLower(p.Device))
+}
+
+// PedalAction returns the action a pedal of the controller named device is
+// bound to, or "" if it is unbound
+func (s Settings) PedalAction(device, control string) string {
+	for _, p := range s.PedalProfiles {
+		if action, ok := p.Bindings[control]; ok && p.Matches(device) {
+			return action
+		}
+	}
+	return s.PedalBindings[control]
+}
+
+// clonePedalProfiles returns a copy of profiles that shares no bindings
+func clonePedalProfiles(profiles []PedalProfile) []PedalProfile {
+	clone := make([]PedalProfile, len(profiles))
+	for i, p := range profiles {
+		p.Bindings = maps.Clone(p.Bindings)
+		if p.Bindings == nil {
+			p.Bindings = PedalBindings{}
+		}
+		clone[i] = p
+	}
+	return clone
+}
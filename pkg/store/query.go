@@ -0,0 +1,265 @@
+package store
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// searchQuery is a search box query parsed by parseSearchQuery, e.g.
+// `wonder artist:"jason mraz" type:gp added:>2024-01-01 -status:learned`
+type searchQuery struct {
+	Text    string       // Words outside field terms, matched against the searched fields
+	Match   []fieldMatch // Full-text columns every tab must match
+	Clauses []string     // SQL conditions on tabs every tab must meet
+	Args    []interface{}
+	Terms   []string // Field terms as typed, see SuggestCorrection
+}
+
+// fieldMatch is a full-text column term, e.g. artist:"jason mraz"
+type fieldMatch struct {
+	Column string
+	Value  string
+}
+
+// fts returns the FTS5 column filter of m, matching the words of its value
+// as a phrase prefix
+func (m fieldMatch) fts() string {
+	return fmt.Sprintf(`%s:"%s"*`, m.Column, strings.ReplaceAll(m.Value, `"`, `""`))
+}
+
+// queryTextFields are the full-text columns a field term can search,
+// keyed by the name used in queries
+var queryTextFields = map[string]string{
+	"title": "title", "artist": "artist", "album": "album", "tag": "tag",
+	"notes": "notes", "genre": "genre", "lyrics": "content", "content": "content",
+}
+
+// queryIntFields are the integer columns a field term can compare
+var queryIntFields = map[string]string{"rating": "tabs.rating", "year": "tabs.year", "capo": "tabs.capo", "bpm": "tabs.bpm"}
+
+// queryDateFields are the Unix timestamp columns a field term can compare
+var queryDateFields = map[string]string{"added": "tabs.added_at", "opened": "tabs.last_opened"}
+
+// queryExactFields are the text columns a field term must equal, ignoring
+// case
+var queryExactFields = map[string]string{
+	"type": "tabs.type", "difficulty": "tabs.difficulty", "status": "tabs.practice_status", "key": "tabs.song_key",
+}
+
+// isQueryField reports whether name is a field of field terms
+func isQueryField(name string) bool {
+	_, text := queryTextFields[name]
+	_, num := queryIntFields[name]
+	_, date := queryDateFields[name]
+	_, exact := queryExactFields[name]
+	return text || num || date || exact || name == "missing" || name == "tuning" || name == "length"
+}
+
+// parseSearchQuery splits a search into free text and field terms:
+//
+//	artist:"jason mraz"  full-text fields: title, artist, album, tag, notes, genre, lyrics
+//	type:gp              type, difficulty, status (practice status), key
+//	rating:>=4           rating, year, capo, bpm with =, >, >=, < or <=
+//	added:>2024-01-01    added, opened with a day, month (2024-01) or year
+//	tuning:"drop d"      tabs with a track in this tuning
+//	length:<4:30         estimated duration in minutes (4) or minutes and seconds (4:30)
+//	missing:true         tabs whose file is missing
+//
+// A term starting with "-" excludes the tabs it matches. Words that are not
+// field terms are free text.
+func parseSearchQuery(query string) (searchQuery, error) {
+	var q searchQuery
+	var text []string
+	rest := strings.TrimSpace(query)
+	for rest != "" {
+		var token string
+		token, rest = nextQueryToken(rest)
+
+		negate := strings.HasPrefix(token, "-")
+		name, value, isField := strings.Cut(strings.TrimPrefix(token, "-"), ":")
+		name = strings.ToLower(name)
+		if !isField || !isQueryField(name) || value == "" {
+			text = append(text, strings.Trim(token, `"`))
+			continue
+		}
+		if err := q.addTerm(name, value, negate); err != nil {
+			return searchQuery{}, err
+		}
+		q.Terms = append(q.Terms, token)
+	}
+	q.Text = strings.TrimSpace(strings.Join(text, " "))
+	return q, nil
+}
+
+// nextQueryToken returns the first token of s, a run of non-space
+// characters where double quotes may enclose spaces, and the rest of s
+func nextQueryToken(s string) (string, string) {
+	inQuotes := false
+	for i, r := range s {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+		case unicode.IsSpace(r) && !inQuotes:
+			return s[:i], strings.TrimSpace(s[i:])
+		}
+	}
+	return s, ""
+}
+
+// addTerm adds the condition of the field term name:value
+func (q *searchQuery) addTerm(name, value string, negate bool) error {
+	op, value := splitQueryOperator(value)
+	value = strings.Trim(value, `"`)
+
+	if column, ok := queryTextFields[name]; ok {
+		match := fieldMatch{Column: column, Value: value}
+		if negate {
+			q.addClause(true, "tabs.rowid IN (SELECT rowid FROM tabs_fts WHERE tabs_fts MATCH ?)", match.fts())
+		} else {
+			q.Match = append(q.Match, match)
+		}
+		return nil
+	}
+	if column, ok := queryExactFields[name]; ok {
+		q.addClause(negate, column+" = ? COLLATE NOCASE", value)
+		return nil
+	}
+	if column, ok := queryIntFields[name]; ok {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %s", name, value)
+		}
+		q.addClause(negate, fmt.Sprintf("%s %s ?", column, op), n)
+		return nil
+	}
+	if column, ok := queryDateFields[name]; ok {
+		start, end, err := parseQueryDate(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s date: %s", name, value)
+		}
+		switch op {
+		case ">":
+			q.addClause(negate, column+" >= ?", end.Unix())
+		case ">=":
+			q.addClause(negate, column+" >= ?", start.Unix())
+		case "<":
+			q.addClause(negate, column+" > 0 AND "+column+" < ?", start.Unix())
+		case "<=":
+			q.addClause(negate, column+" > 0 AND "+column+" < ?", end.Unix())
+		default:
+			q.addClause(negate, column+" >= ? AND "+column+" < ?", start.Unix(), end.Unix())
+		}
+		return nil
+	}
+	if name == "length" {
+		start, end, err := parseQueryLength(value)
+		if err != nil {
+			return fmt.Errorf("invalid length: %s", value)
+		}
+		switch op {
+		case ">":
+			q.addClause(negate, "tabs.duration >= ?", end)
+		case ">=":
+			q.addClause(negate, "tabs.duration >= ?", start)
+		case "<":
+			q.addClause(negate, "tabs.duration > 0 AND tabs.duration < ?", start)
+		case "<=":
+			q.addClause(negate, "tabs.duration > 0 AND tabs.duration < ?", end)
+		default:
+			q.addClause(negate, "tabs.duration >= ? AND tabs.duration < ?", start, end)
+		}
+		return nil
+	}
+	if name == "tuning" {
+		q.addClause(negate, "EXISTS (SELECT 1 FROM tab_tracks WHERE tab_tracks.tab_id = tabs.id AND tab_tracks.tuning_name = ? COLLATE NOCASE)", value)
+		return nil
+	}
+	// missing
+	missing, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("invalid missing: %s", value)
+	}
+	q.addClause(negate, "tabs.is_missing = ?", missing)
+	return nil
+}
+
+// addClause adds an SQL condition, negated if negate
+func (q *searchQuery) addClause(negate bool, clause string, args ...interface{}) {
+	if negate {
+		clause = "NOT (" + clause + ")"
+	} else {
+		clause = "(" + clause + ")"
+	}
+	q.Clauses = append(q.Clauses, clause)
+	q.Args = append(q.Args, args...)
+}
+
+// splitQueryOperator splits the comparison operator off a term value,
+// "=" if it has none
+func splitQueryOperator(value string) (string, string) {
+	for _, op := range []string{">=", "<=", ">", "<", "="} {
+		if v, ok := strings.CutPrefix(value, op); ok {
+			return op, v
+		}
+	}
+	return "=", value
+}
+
+// parseQueryDate returns the local time range [start, end) of a day
+// ("2024-01-31"), month ("2024-01") or year ("2024")
+func parseQueryDate(value string) (time.Time, time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, t.AddDate(0, 0, 1), nil
+	}
+	if t, err := time.ParseInLocation("2006-01", value, time.Local); err == nil {
+		return t, t.AddDate(0, 1, 0), nil
+	}
+	t, err := time.ParseInLocation("2006", value, time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return t, t.AddDate(1, 0, 0), nil
+}
+
+// parseQueryLength returns the range of seconds [start, end) of a length
+// in minutes ("4") or minutes and seconds ("4:30")
+func parseQueryLength(value string) (int, int, error) {
+	minutes, seconds, hasSeconds := strings.Cut(value, ":")
+	m, err := strconv.Atoi(minutes)
+	if err != nil || m < 0 {
+		return 0, 0, fmt.Errorf("invalid minutes: %s", minutes)
+	}
+	if !hasSeconds {
+		return m * 60, m*60 + 60, nil
+	}
+	s, err := strconv.Atoi(seconds)
+	if err != nil || s < 0 || s >= 60 {
+		return 0, 0, fmt.Errorf("invalid seconds: %s", seconds)
+	}
+	return m*60 + s, m*60 + s + 1, nil
+}
+
+// ftsQuery returns the FTS5 query matching the free text in any of fields
+// and every column filter, "" if there is nothing to match
+func (q searchQuery) ftsQuery(fields []string) string {
+	var terms []string
+	if q.Text != "" {
+		var textTerms []string
+		for _, field := range fields {
+			switch field {
+			case "title", "artist", "album", "tag", "notes", "genre", "content":
+				textTerms = append(textTerms, fieldMatch{Column: field, Value: q.Text}.fts())
+			}
+		}
+		if len(textTerms) > 0 {
+			terms = append(terms, "("+strings.Join(textTerms, " OR ")+")")
+		}
+	}
+	for _, m := range q.Match {
+		terms = append(terms, m.fts())
+	}
+	return strings.Join(terms, " AND ")
+}
@@ -0,0 +1,413 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// schemaMigration is one step of the database schema history. Steps are
+// applied in order, each in its own transaction, and recorded in the
+// schema_version table.
+//
+// createTables always creates the latest schema, so steps must be no-ops on
+// a fresh database (e.g. only add a column if it's missing). Never edit or
+// reorder a released step; append a new one instead.
+type schemaMigration struct {
+	Version     int
+	Description string
+	Apply       func(tx *sql.Tx) error
+}
+
+var schemaMigrations = []schemaMigration{
+	{1, "add tabs.tag", addColumn("tabs", "tag", "TEXT DEFAULT ''")},
+	{2, "add tabs.added_at", addColumn("tabs", "added_at", "INTEGER DEFAULT 0")},
+	{3, "add tabs.last_opened", addColumn("tabs", "last_opened", "INTEGER DEFAULT 0")},
+	{4, "add categories.cover_path", addColumn("categories", "cover_path", "TEXT DEFAULT ''")},
+	{5, "rebuild full-text index", func(tx *sql.Tx) error {
+		_, err := tx.Exec("INSERT INTO tabs_fts(tabs_fts) VALUES('rebuild')")
+		return err
+	}},
+	{6, "move tabs.category_id to tab_categories", func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			INSERT INTO tab_categories (tab_id, category_id, added_at)
+			SELECT id, category_id, added_at FROM tabs
+			WHERE category_id != '' AND category_id IS NOT NULL
+			AND category_id IN (SELECT id FROM categories)
+			AND NOT EXISTS (
+				SELECT 1 FROM tab_categories tc WHERE tc.tab_id = tabs.id AND tc.category_id = tabs.category_id
+			)
+		`)
+		return err
+	}},
+	{7, "add tabs.file_hash", func(tx *sql.Tx) error {
+		if err := addColumn("tabs", "file_hash", "TEXT DEFAULT ''")(tx); err != nil {
+			return err
+		}
+		_, err := tx.Exec("CREATE INDEX IF NOT EXISTS idx_tabs_file_hash ON tabs(file_hash)")
+		return err
+	}},
+	{8, "add tabs.is_missing", addColumn("tabs", "is_missing", "INTEGER DEFAULT 0")},
+	{9, "add tabs.practice_status", addColumn("tabs", "practice_status", "TEXT DEFAULT ''")},
+	{10, "add tabs.rating", addColumn("tabs", "rating", "INTEGER DEFAULT 0")},
+	{11, "add tabs.difficulty", addColumn("tabs", "difficulty", "TEXT DEFAULT ''")},
+	{12, "index tab notes", func(tx *sql.Tx) error {
+		if err := addColumn("tabs", "notes", "TEXT DEFAULT ''")(tx); err != nil {
+			return err
+		}
+		return recreateFTS(tx, ftsSchemaV12)
+	}},
+	{13, "add tabs.tracks_scanned", func(tx *sql.Tx) error {
+		if err := addColumn("tabs", "tracks_scanned", "INTEGER DEFAULT 0")(tx); err != nil {
+			return err
+		}
+		_, err := tx.Exec("UPDATE tabs SET tracks_scanned = 1 WHERE id IN (SELECT tab_id FROM tab_tracks)")
+		return err
+	}},
+	{14, "add tabs.needs_review", addColumn("tabs", "needs_review", "INTEGER DEFAULT 0")},
+	{15, "add tabs.song_key and tabs.capo", func(tx *sql.Tx) error {
+		if err := addColumn("tabs", "song_key", "TEXT DEFAULT ''")(tx); err != nil {
+			return err
+		}
+		return addColumn("tabs", "capo", "INTEGER DEFAULT 0")(tx)
+	}},
+	{16, "add categories.open_method and categories.open_gp_method", func(tx *sql.Tx) error {
+		if err := addColumn("categories", "open_method", "TEXT DEFAULT ''")(tx); err != nil {
+			return err
+		}
+		return addColumn("categories", "open_gp_method", "TEXT DEFAULT ''")(tx)
+	}},
+	// No default: NULL marks the tabs the script backfill has not seen
+	{17, "add tabs.script", addColumn("tabs", "script", "TEXT")},
+	{18, "add tabs.genre, tabs.year and tabs.enriched", func(tx *sql.Tx) error {
+		if err := addColumn("tabs", "genre", "TEXT DEFAULT ''")(tx); err != nil {
+			return err
+		}
+		if err := addColumn("tabs", "year", "INTEGER DEFAULT 0")(tx); err != nil {
+			return err
+		}
+		return addColumn("tabs", "enriched", "INTEGER DEFAULT 0")(tx)
+	}},
+	{19, "index tab genres", func(tx *sql.Tx) error {
+		return recreateFTS(tx, ftsSchemaV19)
+	}},
+	{20, "move sync paths to the sync_paths table", moveSyncPaths},
+	{21, "add sync_paths.exclude", addColumn("sync_paths", "exclude", "TEXT DEFAULT '[]'")},
+	// No default: NULL marks the tabs the content backfill has not seen
+	{22, "index tab contents", func(tx *sql.Tx) error {
+		if err := addColumn("tabs", "content", "TEXT")(tx); err != nil {
+			return err
+		}
+		return recreateFTS(tx, ftsSchema)
+	}},
+	// No default: NULL marks the tabs the file size backfill has not seen
+	{23, "add tabs.file_size", addColumn("tabs", "file_size", "INTEGER")},
+	// The history starts with the last open known of each tab
+	{24, "seed tab_history", func(tx *sql.Tx) error {
+		_, err := tx.Exec("INSERT INTO tab_history (tab_id, opened_at) SELECT id, last_opened FROM tabs WHERE last_opened > 0")
+		return err
+	}},
+	{25, "add categories.color and categories.icon", func(tx *sql.Tx) error {
+		if err := addColumn("categories", "color", "TEXT DEFAULT ''")(tx); err != nil {
+			return err
+		}
+		return addColumn("categories", "icon", "TEXT DEFAULT ''")(tx)
+	}},
+	{26, "add categories.sort_order and categories.pinned", func(tx *sql.Tx) error {
+		if err := addColumn("categories", "sort_order", "INTEGER DEFAULT 0")(tx); err != nil {
+			return err
+		}
+		return addColumn("categories", "pinned", "INTEGER DEFAULT 0")(tx)
+	}},
+	// No default: NULL marks the covers the color backfill has not seen
+	{27, "add tabs.cover_color", addColumn("tabs", "cover_color", "TEXT")},
+	{28, "add tabs.words, tabs.music, tabs.copyright, tabs.tab_author and tabs.notice", func(tx *sql.Tx) error {
+		for _, column := range []string{"words", "music", "copyright", "tab_author", "notice"} {
+			if err := addColumn("tabs", column, "TEXT DEFAULT ''")(tx); err != nil {
+				return err
+			}
+		}
+		// The track backfill reads the credits of the GP files again
+		_, err := tx.Exec("UPDATE tabs SET tracks_scanned = 0 WHERE type = 'gp'")
+		return err
+	}},
+	{29, "add tabs.bpm", func(tx *sql.Tx) error {
+		if err := addColumn("tabs", "bpm", "INTEGER DEFAULT 0")(tx); err != nil {
+			return err
+		}
+		// The track backfill reads the tempo and key of the GP files
+		_, err := tx.Exec("UPDATE tabs SET tracks_scanned = 0 WHERE type = 'gp'")
+		return err
+	}},
+	{30, "add tabs.duration", func(tx *sql.Tx) error {
+		if err := addColumn("tabs", "duration", "INTEGER DEFAULT 0")(tx); err != nil {
+			return err
+		}
+		// The track backfill estimates the duration of the GP files
+		_, err := tx.Exec("UPDATE tabs SET tracks_scanned = 0 WHERE type = 'gp'")
+		return err
+	}},
+	{31, "add tabs.source_path", addColumn("tabs", "source_path", "TEXT DEFAULT ''")},
+	{32, "remove share credentials from copied files and pending conflicts", stripShareCredentials},
+}
+
+// recreateFTS replaces the full-text index and its triggers with those of
+// schema and fills it again: FTS columns can't be altered
+func recreateFTS(tx *sql.Tx, schema string) error {
+	if _, err := tx.Exec(`
+		DROP TRIGGER IF EXISTS tabs_ai;
+		DROP TRIGGER IF EXISTS tabs_ad;
+		DROP TRIGGER IF EXISTS tabs_au;
+		DROP TABLE IF EXISTS tabs_fts;
+	`); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(schema); err != nil {
+		return err
+	}
+	_, err := tx.Exec("INSERT INTO tabs_fts(tabs_fts) VALUES('rebuild')")
+	return err
+}
+
+// ftsSchemaV12 is ftsSchema as of step 12, before tabs.genre existed
+const ftsSchemaV12 = `
+	CREATE VIRTUAL TABLE IF NOT EXISTS tabs_fts USING fts5(
+		title, artist, album, tag, notes,
+		content='tabs',
+		content_rowid='rowid'
+	);
+
+	CREATE TRIGGER IF NOT EXISTS tabs_ai AFTER INSERT ON tabs BEGIN
+		INSERT INTO tabs_fts(rowid, title, artist, album, tag, notes)
+		VALUES (NEW.rowid, NEW.title, NEW.artist, NEW.album, NEW.tag, NEW.notes);
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS tabs_ad AFTER DELETE ON tabs BEGIN
+		INSERT INTO tabs_fts(tabs_fts, rowid, title, artist, album, tag, notes)
+		VALUES ('delete', OLD.rowid, OLD.title, OLD.artist, OLD.album, OLD.tag, OLD.notes);
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS tabs_au AFTER UPDATE ON tabs BEGIN
+		INSERT INTO tabs_fts(tabs_fts, rowid, title, artist, album, tag, notes)
+		VALUES ('delete', OLD.rowid, OLD.title, OLD.artist, OLD.album, OLD.tag, OLD.notes);
+		INSERT INTO tabs_fts(rowid, title, artist, album, tag, notes)
+		VALUES (NEW.rowid, NEW.title, NEW.artist, NEW.album, NEW.tag, NEW.notes);
+	END;
+`
+
+// ftsSchemaV19 is ftsSchema as of step 19, before tabs.content existed
+const ftsSchemaV19 = `
+	CREATE VIRTUAL TABLE IF NOT EXISTS tabs_fts USING fts5(
+		title, artist, album, tag, notes, genre,
+		content='tabs',
+		content_rowid='rowid'
+	);
+
+	CREATE TRIGGER IF NOT EXISTS tabs_ai AFTER INSERT ON tabs BEGIN
+		INSERT INTO tabs_fts(rowid, title, artist, album, tag, notes, genre)
+		VALUES (NEW.rowid, NEW.title, NEW.artist, NEW.album, NEW.tag, NEW.notes, NEW.genre);
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS tabs_ad AFTER DELETE ON tabs BEGIN
+		INSERT INTO tabs_fts(tabs_fts, rowid, title, artist, album, tag, notes, genre)
+		VALUES ('delete', OLD.rowid, OLD.title, OLD.artist, OLD.album, OLD.tag, OLD.notes, OLD.genre);
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS tabs_au AFTER UPDATE ON tabs BEGIN
+		INSERT INTO tabs_fts(tabs_fts, rowid, title, artist, album, tag, notes, genre)
+		VALUES ('delete', OLD.rowid, OLD.title, OLD.artist, OLD.album, OLD.tag, OLD.notes, OLD.genre);
+		INSERT INTO tabs_fts(rowid, title, artist, album, tag, notes, genre)
+		VALUES (NEW.rowid, NEW.title, NEW.artist, NEW.album, NEW.tag, NEW.notes, NEW.genre);
+	END;
+`
+
+// runMigrations applies the schema migrations newer than the database
+func (s *DBStore) runMigrations() error {
+	if _, err := s.exec(`
+		CREATE TABLE IF NOT EXISTS schema_version (
+			version INTEGER PRIMARY KEY,
+			description TEXT NOT NULL,
+			applied_at INTEGER NOT NULL
+		)
+	`); err != nil {
+		return err
+	}
+
+	var current int
+	if err := s.db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_version").Scan(&current); err != nil {
+		return err
+	}
+
+	for _, m := range schemaMigrations {
+		if m.Version <= current {
+			continue
+		}
+		if err := s.applyMigration(m); err != nil {
+			return fmt.Errorf("migration %d (%s): %w", m.Version, m.Description, err)
+		}
+		fmt.Printf("[Migration] Applied %d: %s\n", m.Version, m.Description)
+	}
+	return nil
+}
+
+// applyMigration runs a migration step and records it in one transaction
+func (s *DBStore) applyMigration(m schemaMigration) error {
+	tx, err := s.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := m.Apply(tx); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("INSERT INTO schema_version (version, description, applied_at) VALUES (?, ?, ?)",
+		m.Version, m.Description, time.Now().Unix()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// moveSyncPaths moves the "syncPaths" setting, and the "folderCategories"
+// strategies of its paths, from the settings to the sync_paths table. Paths
+// are scanned recursively and linked, as before.
+func moveSyncPaths(tx *sql.Tx) error {
+	var paths []string
+	strategies := map[string]string{}
+
+	var raw string
+	err := tx.QueryRow("SELECT value FROM settings WHERE key = ?", settingsKey).Scan(&raw)
+	switch {
+	case err == sql.ErrNoRows:
+		// Per-key rows from before the settings document
+		var joined string
+		err := tx.QueryRow("SELECT value FROM settings WHERE key = 'syncPaths'").Scan(&joined)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if joined != "" {
+			paths = strings.Split(joined, "|")
+		}
+		if _, err := tx.Exec("DELETE FROM settings WHERE key = 'syncPaths'"); err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	default:
+		var doc settingsDocument
+		if err := json.Unmarshal([]byte(raw), &doc); err != nil {
+			return fmt.Errorf("invalid settings document: %w", err)
+		}
+		list, _ := doc.Settings["syncPaths"].([]interface{})
+		for _, v := range list {
+			if path, ok := v.(string); ok && path != "" {
+				paths = append(paths, path)
+			}
+		}
+		mapping, _ := doc.Settings["folderCategories"].(map[string]interface{})
+		for path, v := range mapping {
+			if strategy, ok := v.(string); ok {
+				strategies[path] = strategy
+			}
+		}
+		delete(doc.Settings, "syncPaths")
+		delete(doc.Settings, "folderCategories")
+		data, err := json.Marshal(doc)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec("UPDATE settings SET value = ? WHERE key = ?", string(data), settingsKey); err != nil {
+			return err
+		}
+	}
+
+	for i, path := range paths {
+		if _, err := tx.Exec(`
+			INSERT OR IGNORE INTO sync_paths (path, recursive, mode, category_strategy, position)
+			VALUES (?, 1, ?, ?, ?)
+		`, path, SyncModeLink, strategies[path], i+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stripShareCredentials removes the user info of the URLs of share files
+// in the tables keyed by file, which syncs used to record with the password
+// of the share. A file recorded both ways keeps the row without them.
+func stripShareCredentials(tx *sql.Tx) error {
+	for _, table := range []string{"copied_files", "pending_conflicts"} {
+		rows, err := tx.Query(fmt.Sprintf("SELECT path FROM %s WHERE path LIKE '%%://%%@%%'", table))
+		if err != nil {
+			return err
+		}
+		var paths []string
+		for rows.Next() {
+			var path string
+			if err := rows.Scan(&path); err != nil {
+				rows.Close()
+				return err
+			}
+			paths = append(paths, path)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, path := range paths {
+			u, err := url.Parse(path)
+			if err != nil || u.User == nil {
+				continue
+			}
+			u.User = nil
+			if _, err := tx.Exec(fmt.Sprintf("UPDATE OR IGNORE %s SET path = ? WHERE path = ?", table), u.String(), path); err != nil {
+				return err
+			}
+			if _, err := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE path = ?", table), path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// addColumn returns a step adding a column unless the table already has it
+func addColumn(table, column, definition string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		exists, err := columnExists(tx, table, column)
+		if err != nil || exists {
+			return err
+		}
+		_, err = tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+		return err
+	}
+}
+
+// columnExists reports whether table has the given column
+func columnExists(tx *sql.Tx, table, column string) (bool, error) {
+	rows, err := tx.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var defaultValue sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
@@ -0,0 +1,269 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// settingsKey is the row of the settings table holding the settings document
+const settingsKey = "settings"
+
+// settingsVersion is the current version of the settings document. When a
+// setting is renamed or changes meaning, bump it and add a step to
+// settingsMigrations.
+const settingsVersion = 2
+
+// settingsMigrations upgrade the values of a settings document by one
+// version, keyed by the version they upgrade from. Version 1 is the first
+// document; older databases store one row per key (see legacySettings).
+var settingsMigrations = map[int]func(values map[string]interface{}){
+	// Covers are re-encoded as JPEG, which the name now says
+	1: func(values map[string]interface{}) { renameSetting(values, "coverQuality", "coverJpegQuality") },
+}
+
+// renameSetting moves the value of setting from to to, if it has one
+func renameSetting(values map[string]interface{}, from, to string) {
+	if v, ok := values[from]; ok {
+		values[to] = v
+		delete(values, from)
+	}
+}
+
+// settingsDocument is the JSON stored under settingsKey
+type settingsDocument struct {
+	Version  int                    `json:"version"`
+	Settings map[string]interface{} `json:"settings"`
+}
+
+// loadSettings reads the settings document over the defaults. Settings
+// missing from the document keep their default value.
+func (s *DBStore) loadSettings() error {
+	var raw string
+	err := s.db.QueryRow("SELECT value FROM settings WHERE key = ?", settingsKey).Scan(&raw)
+	if err == sql.ErrNoRows {
+		// New database, or one from before the settings document
+		values, err := s.legacySettings()
+		if err != nil {
+			return err
+		}
+		if err := s.applySettings(values); err != nil {
+			return err
+		}
+		if err := s.saveSettings(); err != nil {
+			return err
+		}
+		_, err = s.exec("DELETE FROM settings WHERE key != ?", settingsKey)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	var doc settingsDocument
+	if err := json.Unmarshal([]byte(raw), &doc); err != nil {
+		return fmt.Errorf("invalid settings document: %w", err)
+	}
+	if doc.Settings == nil {
+		doc.Settings = map[string]interface{}{}
+	}
+	for v := doc.Version; v < settingsVersion; v++ {
+		if migrate, ok := settingsMigrations[v]; ok {
+			migrate(doc.Settings)
+		}
+	}
+	if err := s.applySettings(doc.Settings); err != nil {
+		return err
+	}
+	if doc.Version < settingsVersion {
+		return s.saveSettings()
+	}
+	return nil
+}
+
+// applySettings decodes values over the current settings. Keys that are not
+// fields of Settings are kept in settingsExtra so they survive a save.
+func (s *DBStore) applySettings(values map[string]interface{}) error {
+	data, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+
+	settings := s.Settings
+	// Decoding reuses the backing array of slices; don't share it with
+	// copies returned by GetSettings
+	settings.CoverRegions = append([]CoverRegion{}, settings.CoverRegions...)
+	settings.KeyBindings = maps.Clone(settings.KeyBindings)
+	// Decoding merges into maps; replace the pedal bindings instead so
+	// removed pedals stay removed (key bindings get their defaults back)
+	settings.PedalBindings = nil
+	settings.PedalProfiles = nil
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return err
+	}
+	settings.KeyBindings = withDefaultKeys(settings.KeyBindings)
+	if settings.PedalBindings == nil {
+		settings.PedalBindings = PedalBindings{}
+	}
+	settings.PedalProfiles = clonePedalProfiles(settings.PedalProfiles)
+
+	known := settingsValues(settings)
+	extra := make(map[string]interface{})
+	for k, v := range values {
+		if _, ok := known[k]; !ok {
+			extra[k] = v
+		}
+	}
+
+	s.Settings = settings
+	s.settingsExtra = extra
+	return nil
+}
+
+// saveSettings writes the current settings as the settings document
+func (s *DBStore) saveSettings() error {
+	data, err := json.Marshal(settingsDocument{Version: settingsVersion, Settings: s.allSettings()})
+	if err != nil {
+		return err
+	}
+	_, err = s.exec("INSERT OR REPLACE INTO settings (key, value) VALUES (?, ?)", settingsKey, string(data))
+	return err
+}
+
+// allSettings returns the Settings fields and the extra keys as document values
+func (s *DBStore) allSettings() map[string]interface{} {
+	values := settingsValues(s.Settings)
+	for k, v := range s.settingsExtra {
+		if _, ok := values[k]; !ok {
+			values[k] = v
+		}
+	}
+	return values
+}
+
+// settingsValues converts settings to document values
+func settingsValues(settings Settings) map[string]interface{} {
+	values := make(map[string]interface{})
+	data, err := json.Marshal(settings)
+	if err == nil {
+		json.Unmarshal(data, &values)
+	}
+	return values
+}
+
+// legacySettings converts the per-key rows used before the settings
+// document into document values
+func (s *DBStore) legacySettings() (map[string]interface{}, error) {
+	rows, err := s.db.Query("SELECT key, value FROM settings")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	legacy := make(map[string]string)
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, err
+		}
+		legacy[key] = value
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return legacyValues(reflect.TypeOf(Settings{}), "", legacy), nil
+}
+
+// legacyValues reads the fields of t from per-key rows. Nested structs were
+// stored as "parent.child" keys and lists as "|" separated values; empty
+// values and non-positive numbers meant "use the default".
+func legacyValues(t reflect.Type, prefix string, rows map[string]string) map[string]interface{} {
+	values := make(map[string]interface{})
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		if f.Type.Kind() == reflect.Struct {
+			if nested := legacyValues(f.Type, prefix+name+".", rows); len(nested) > 0 {
+				values[name] = nested
+			}
+			continue
+		}
+		if f.Type.Kind() == reflect.Map {
+			// Key bindings were stored as "keyBindings.<action>"
+			nested := make(map[string]interface{})
+			for key, v := range rows {
+				if action, ok := strings.CutPrefix(key, prefix+name+"."); ok && v != "" {
+					nested[action] = v
+				}
+			}
+			if len(nested) > 0 {
+				values[name] = nested
+			}
+			continue
+		}
+
+		v, ok := rows[prefix+name]
+		if !ok || v == "" {
+			continue
+		}
+		switch f.Type.Kind() {
+		case reflect.String:
+			values[name] = v
+		case reflect.Bool:
+			values[name] = v == "true"
+		case reflect.Int, reflect.Int64:
+			if n, err := strconv.ParseInt(v, 10, 64); err == nil && n > 0 {
+				values[name] = n
+			}
+		case reflect.Slice:
+			if f.Type.Elem().Kind() == reflect.String {
+				values[name] = strings.Split(v, "|")
+			}
+		}
+	}
+	return values
+}
+
+// lookupSetting returns the value at a dotted key path, e.g.
+// "keyBindings.playPause"
+func lookupSetting(values map[string]interface{}, key string) (interface{}, bool) {
+	parts := strings.Split(key, ".")
+	var current interface{} = values
+	for _, part := range parts {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if current, ok = m[part]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// assignSetting sets the value at a dotted key path, creating intermediate
+// objects as needed
+func assignSetting(values map[string]interface{}, key string, value interface{}) error {
+	parts := strings.Split(key, ".")
+	m := values
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := m[part]
+		if !ok {
+			next = make(map[string]interface{})
+			m[part] = next
+		}
+		if m, ok = next.(map[string]interface{}); !ok {
+			return fmt.Errorf("setting %s is not an object", part)
+		}
+	}
+	m[parts[len(parts)-1]] = value
+	return nil
+}
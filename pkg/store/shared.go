@@ -0,0 +1,37 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// OpenReadOnly opens the library database at dbPath read-only, e.g. the
+// library of a band leader on a shared drive. Its tabs are read with the
+// methods of DBStore, and writes fail. The library must have been opened
+// by this version of the app, so its schema is the current one.
+func OpenReadOnly(dbPath string) (*DBStore, error) {
+	if _, err := os.Stat(dbPath); err != nil {
+		return nil, fmt.Errorf("library not found: %s", dbPath)
+	}
+
+	dsn := "file:" + dbPath + "?mode=ro&_pragma=" + strings.Join(append(connPragmas, "query_only(ON)"), "&_pragma=")
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open library: %w", err)
+	}
+
+	var version int
+	if err := db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_version").Scan(&version); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("not a library database: %w", err)
+	}
+	if latest := schemaMigrations[len(schemaMigrations)-1].Version; version < latest {
+		db.Close()
+		return nil, fmt.Errorf("the library was saved by an older version of the app (schema %d, need %d)", version, latest)
+	}
+
+	// Both pools are the read-only one, so that writes fail
+	return &DBStore{dbPath: dbPath, db: db, rdb: db, Settings: NewDBStore(dbPath).Settings}, nil
+}
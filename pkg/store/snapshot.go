@@ -0,0 +1,141 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"modernc.org/sqlite"
+)
+
+// backuper is implemented by the connections of the SQLite driver
+type backuper interface {
+	NewBackup(dstURI string) (*sqlite.Backup, error)
+	NewRestore(srcURI string) (*sqlite.Backup, error)
+}
+
+// Snapshot writes a consistent copy of the database to path, which must not
+// exist. Writes wait until the copy is done.
+func (s *DBStore) Snapshot(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.exec("VACUUM INTO ?", path)
+	return err
+}
+
+// Backup copies the database to path, which must not exist, with the
+// SQLite backup API: unlike copying the file, it includes the writes still
+// in the WAL. Writes wait until the copy is done.
+func (s *DBStore) Backup(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.runBackup(func(c backuper) (*sqlite.Backup, error) {
+		return c.NewBackup(path)
+	})
+}
+
+// Restore replaces the content of the database by the database at path,
+// e.g. one written by Backup, with the SQLite backup API. The restored
+// database is migrated to the current schema and its settings are loaded.
+func (s *DBStore) Restore(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.runBackup(func(c backuper) (*sqlite.Backup, error) {
+		return c.NewRestore(path)
+	}); err != nil {
+		return err
+	}
+	if err := s.createTables(); err != nil {
+		return fmt.Errorf("failed to create tables: %w", err)
+	}
+	if err := s.runMigrations(); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+	return s.loadSettings()
+}
+
+// runBackup copies all pages of the backup started by start on a write
+// connection
+func (s *DBStore) runBackup(start func(c backuper) (*sqlite.Backup, error)) error {
+	conn, err := s.db.Conn(context.Background())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		c, ok := driverConn.(backuper)
+		if !ok {
+			return fmt.Errorf("the database driver does not support backups")
+		}
+		b, err := start(c)
+		if err != nil {
+			return err
+		}
+		for more := true; more; {
+			if more, err = b.Step(-1); err != nil {
+				b.Finish()
+				return err
+			}
+		}
+		return b.Finish()
+	})
+}
+
+// RelocateFiles points the files and covers under oldDir to the same files
+// under newDir, e.g. after restoring the library of another machine. It
+// returns the number of paths changed.
+func (s *DBStore) RelocateFiles(oldDir, newDir string) (int, error) {
+	oldDir, newDir = filepath.Clean(oldDir), filepath.Clean(newDir)
+	if oldDir == newDir {
+		return 0, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	changed := 0
+	for _, column := range []struct{ table, name string }{
+		{"tabs", "file_path"},
+		{"tabs", "cover_path"},
+		{"categories", "cover_path"},
+	} {
+		rows, err := tx.Query("SELECT id, " + column.name + " FROM " + column.table + " WHERE " + column.name + " != ''")
+		if err != nil {
+			return 0, err
+		}
+		moved := map[string]string{}
+		for rows.Next() {
+			var id, path string
+			if err := rows.Scan(&id, &path); err != nil {
+				rows.Close()
+				return 0, err
+			}
+			if rest, ok := strings.CutPrefix(path, oldDir); ok && (rest == "" || rest[0] == '/' || rest[0] == '\\') {
+				moved[id] = newDir + rest
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return 0, err
+		}
+
+		for id, path := range moved {
+			if _, err := tx.Exec("UPDATE "+column.table+" SET "+column.name+" = ? WHERE id = ?", path, id); err != nil {
+				return 0, err
+			}
+		}
+		changed += len(moved)
+	}
+	return changed, tx.Commit()
+}
@@ -8,20 +8,264 @@ import (
 )
 
 type Tab struct {
-	ID         string `json:"id"`
-	Title      string `json:"title"`
-	Artist     string `json:"artist"`
-	Album      string `json:"album"`
-	FilePath   string `json:"filePath"` // Absolute path or relative to app
-	Type       string `json:"type"`     // "pdf" or "gp"
-	IsManaged  bool   `json:"isManaged"`
-	CoverPath  string   `json:"coverPath"`
-	CategoryIDs []string `json:"categoryIds"` // List of Category IDs
-	Country    string   `json:"country"`    // e.g. "US", "JP"
-	Language   string `json:"language"`   // e.g. "ja_jp"
-	Tag        string `json:"tag"`        // e.g. "Lead Guitar", "First Version"
-	AddedAt    int64  `json:"addedAt"`    // Unix timestamp
-	LastOpened int64  `json:"lastOpened"` // Unix timestamp
+	ID             string     `json:"id"`
+	Title          string     `json:"title"`
+	Artist         string     `json:"artist"`
+	Album          string     `json:"album"`
+	FilePath       string     `json:"filePath"` // Absolute path or relative to app
+	Type           string     `json:"type"`     // "pdf", "gp", "text" or "chordpro"
+	IsManaged      bool       `json:"isManaged"`
+	CoverPath      string     `json:"coverPath"`
+	CategoryIDs    []string   `json:"categoryIds"`    // List of Category IDs
+	Country        string     `json:"country"`        // e.g. "US", "JP"
+	Language       string     `json:"language"`       // e.g. "ja_jp"
+	Tag            string     `json:"tag"`            // e.g. "Lead Guitar", "First Version"
+	AddedAt        int64      `json:"addedAt"`        // Unix timestamp
+	LastOpened     int64      `json:"lastOpened"`     // Unix timestamp
+	FileHash       string     `json:"fileHash"`       // SHA-256 of the file, empty until computed
+	IsMissing      bool       `json:"isMissing"`      // File was removed from a sync directory
+	PracticeStatus string     `json:"practiceStatus"` // "", "learning" or "mastered"
+	Rating         int        `json:"rating"`         // 1-5, 0 if not rated
+	Difficulty     string     `json:"difficulty"`     // "beginner", "intermediate", "advanced" or ""
+	NeedsReview    bool       `json:"needsReview"`    // Imported from the inbox, metadata not confirmed yet
+	Key            string     `json:"key"`            // Key of a chord sheet or GP file, e.g. "G" or "Em"; empty if unknown
+	Capo           int        `json:"capo"`           // Capo fret of a chord sheet, 0 for none
+	Script         string     `json:"script"`         // Writing system of title and artist: "latin", "cyrillic", "cjk" or "" if unknown
+	Genre          string     `json:"genre"`          // e.g. "Classic Rock"; empty if unknown
+	Year           int        `json:"year"`           // Release year, 0 if unknown
+	FileSize       int64      `json:"fileSize"`       // Size of the file in bytes, 0 if unknown
+	CoverColor     string     `json:"coverColor"`     // Dominant color of the cover as "#rrggbb", empty if unknown
+	Bpm            int        `json:"bpm"`            // Initial tempo of a GP file, 0 if unknown
+	Duration       int        `json:"duration"`       // Estimated playing time of a GP file in seconds, 0 if unknown
+	SourcePath     string     `json:"sourcePath"`     // File the managed file was converted from on import; empty if not converted
+	Shared         bool       `json:"shared"`         // Of the shared library, read-only; not stored
+	Tracks         []TabTrack `json:"tracks"`         // Filled by GetTab and when parsing a file, empty in lists
+	Credits        TabCredits `json:"credits"`        // Of GP files; filled by GetTab and when parsing a file, empty in lists
+
+	rank float64 // bm25 rank in the search that found it, lower is better; 0 outside searches
+}
+
+// TabCredits are the credits written in the header of a Guitar Pro file
+type TabCredits struct {
+	Words     string `json:"words"` // Lyricist; GP3 and GP4 files credit words and music together here
+	Music     string `json:"music"` // Composer
+	Copyright string `json:"copyright"`
+	TabAuthor string `json:"tabAuthor"` // Who transcribed the tab
+	Notice    string `json:"notice"`    // Lines of the notice of the file
+}
+
+// TabTrack describes one part (track) of a Guitar Pro tab
+type TabTrack struct {
+	Index        int    `json:"index"`
+	Name         string `json:"name"`
+	Instrument   string `json:"instrument"`
+	Program      int    `json:"program"` // General MIDI program, -1 if unknown
+	Kind         string `json:"kind"`    // "guitar", "bass", "drums", "keys", "vocals", "other"
+	StringCount  int    `json:"stringCount"`
+	Tuning       []int  `json:"tuning"`     // MIDI note per string, highest string first
+	TuningName   string `json:"tuningName"` // e.g. "E Standard", "Drop D"
+	Capo         int    `json:"capo"`
+	IsPercussion bool   `json:"isPercussion"`
+}
+
+// YearReview summarizes the use of the library during a year, from local
+// data only
+type YearReview struct {
+	Year            int            `json:"year"`
+	TabsAdded       int            `json:"tabsAdded"`
+	TabsOpened      int            `json:"tabsOpened"`      // Tabs last opened during the year
+	TabsPracticed   int            `json:"tabsPracticed"`   // Tabs with practice time during the year
+	PracticeSeconds int64          `json:"practiceSeconds"` // Time tabs were open in a viewer
+	PracticeDays    int            `json:"practiceDays"`    // Days with practice time
+	NotesWritten    int            `json:"notesWritten"`
+	MonthlyAdded    []int          `json:"monthlyAdded"` // Tabs added per month, January first
+	TopArtists      []ArtistReview `json:"topArtists"`   // Most practiced first, then most added
+	TopTabs         []TabReview    `json:"topTabs"`      // Most practiced first
+}
+
+// LibraryStats summarizes the whole library
+type LibraryStats struct {
+	Tabs            int            `json:"tabs"`
+	Types           map[string]int `json:"types"`        // Tabs per type, e.g. "pdf", "gp"
+	TopArtists      []ArtistCount  `json:"topArtists"`   // Most tabs first
+	ManagedBytes    int64          `json:"managedBytes"` // Files copied to app storage
+	ManagedSize     string         `json:"managedSize"`  // ManagedBytes formatted for the locale
+	LinkedBytes     int64          `json:"linkedBytes"`  // Files left in place, e.g. in sync folders
+	LinkedSize      string         `json:"linkedSize"`   // LinkedBytes formatted for the locale
+	Covers          int            `json:"covers"`       // Tabs with a cover
+	OpenedThisMonth int            `json:"openedThisMonth"`
+}
+
+// ArtistCount is an artist of LibraryStats
+type ArtistCount struct {
+	Artist string `json:"artist"`
+	Tabs   int    `json:"tabs"`
+}
+
+// ArtistReview is an artist of a YearReview
+type ArtistReview struct {
+	Artist          string `json:"artist"`
+	TabsAdded       int    `json:"tabsAdded"`
+	PracticeSeconds int64  `json:"practiceSeconds"`
+}
+
+// TabReview is a tab of a YearReview
+type TabReview struct {
+	ID              string `json:"id"`
+	Title           string `json:"title"`
+	Artist          string `json:"artist"`
+	PracticeSeconds int64  `json:"practiceSeconds"`
+}
+
+// TabOpen is an open of a tab recorded in its history
+type TabOpen struct {
+	ID       int64  `json:"id"`
+	TabID    string `json:"tabId"`
+	Title    string `json:"title"`
+	Artist   string `json:"artist"`
+	OpenedAt int64  `json:"openedAt"` // Unix timestamp
+	Seconds  int    `json:"seconds"`  // Time the tab was open in a viewer, 0 if unknown
+}
+
+// OpenedTab is a tab of GetMostOpenedTabs and how much it was opened
+type OpenedTab struct {
+	Tab     Tab   `json:"tab"`
+	Opens   int   `json:"opens"`
+	Seconds int64 `json:"seconds"` // Time open in a viewer
+}
+
+// PracticeFilter selects the tabs of a practice queue. Empty fields match
+// every tab.
+type PracticeFilter struct {
+	CategoryID string `json:"categoryId"`
+	Status     string `json:"status"` // Practice status, e.g. "learning"
+	Type       string `json:"type"`   // "pdf", "gp", "text" or "chordpro"
+
+	// Difficulty range, inclusive; tabs without a difficulty only match
+	// when MinDifficulty is empty
+	MinDifficulty string `json:"minDifficulty"`
+	MaxDifficulty string `json:"maxDifficulty"`
+}
+
+// TabFilters narrows the results of GetTabsPaginated. Zero values match
+// every tab.
+type TabFilters struct {
+	MinRating    int      `json:"minRating"`    // 1-5
+	Difficulties []string `json:"difficulties"` // e.g. ["beginner", "intermediate"]
+	NeedsReview  bool     `json:"needsReview"`  // Only tabs imported from the inbox and not reviewed yet
+	Scripts      []string `json:"scripts"`      // Writing systems of title and artist, e.g. ["cjk"]
+	Genres       []string `json:"genres"`       // Case-insensitive, e.g. ["Classic Rock"]
+	Keys         []string `json:"keys"`         // Case-insensitive, e.g. ["G", "Em"]
+	Tunings      []string `json:"tunings"`      // Tabs with a track in one of these tunings, e.g. ["Drop D"]
+	MinBpm       int      `json:"minBpm"`       // 0 for no bound
+	MaxBpm       int      `json:"maxBpm"`       // 0 for no bound; tabs of unknown tempo are left out
+	MinDuration  int      `json:"minDuration"`  // Seconds, 0 for no bound
+	MaxDuration  int      `json:"maxDuration"`  // Seconds, 0 for no bound; tabs of unknown length are left out
+}
+
+// GenreCount is a genre and its number of tabs, see GetGenres
+type GenreCount struct {
+	Genre string `json:"genre"`
+	Tabs  int    `json:"tabs"`
+}
+
+// SearchSuggestion is a title or artist of the library completing what is
+// typed in the search box, see GetSearchSuggestions
+type SearchSuggestion struct {
+	Value string `json:"value"`
+	Field string `json:"field"` // "title" or "artist"
+	Tabs  int    `json:"tabs"`  // Tabs with this title or artist
+
+	rank float64 // Best bm25 rank of its tabs, lower is better
+}
+
+// TabNote is a free-text annotation on a tab, e.g. practice advice
+type TabNote struct {
+	ID        int64  `json:"id"`
+	TabID     string `json:"tabId"`
+	Content   string `json:"content"`
+	CreatedAt int64  `json:"createdAt"` // Unix timestamp
+	UpdatedAt int64  `json:"updatedAt"` // Unix timestamp
+}
+
+// TabLink is a reference URL of a tab, e.g. a video lesson or the original song
+type TabLink struct {
+	ID      int64  `json:"id"`
+	TabID   string `json:"tabId"`
+	Title   string `json:"title"`
+	URL     string `json:"url"`
+	AddedAt int64  `json:"addedAt"` // Unix timestamp
+}
+
+// Student is a pupil of a teacher, with tabs assigned to practice
+type Student struct {
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	Notes     string `json:"notes"`
+	CreatedAt int64  `json:"createdAt"` // Unix timestamp
+}
+
+// Assignment is a tab assigned to a student
+type Assignment struct {
+	ID         int64  `json:"id"`
+	StudentID  int64  `json:"studentId"`
+	TabID      string `json:"tabId"`
+	TabTitle   string `json:"tabTitle"`   // Read from the tab
+	TabArtist  string `json:"tabArtist"`  // Read from the tab
+	Status     string `json:"status"`     // "assigned", "practicing" or "done"
+	DueAt      int64  `json:"dueAt"`      // Unix timestamp, 0 for no due date
+	Note       string `json:"note"`       // Instructions for the student
+	AssignedAt int64  `json:"assignedAt"` // Unix timestamp
+}
+
+// Reminder schedules a tab or a category for practice
+type Reminder struct {
+	ID         int64  `json:"id"`
+	TabID      string `json:"tabId"`      // Tab to practice, "" for a category
+	CategoryID string `json:"categoryId"` // Category to practice, "" for a tab
+	Name       string `json:"name"`       // Title of the tab or name of the category, read from it
+	Weekdays   []int  `json:"weekdays"`   // Days it repeats on, 0 for Sunday; empty for once on Date
+	Date       string `json:"date"`       // Day of a one-time reminder, e.g. "2024-06-01"
+	Time       string `json:"time"`       // Local time of day, e.g. "18:30"
+	Note       string `json:"note"`
+	NextAt     int64  `json:"nextAt"`    // Unix timestamp of the next notification, 0 if none
+	CreatedAt  int64  `json:"createdAt"` // Unix timestamp
+}
+
+// Attachment is an audio file attached to a tab, e.g. a backing track
+type Attachment struct {
+	ID       int64  `json:"id"`
+	TabID    string `json:"tabId"`
+	Name     string `json:"name"`     // Display name, defaults to the file name
+	FilePath string `json:"filePath"` // Absolute path, the file is not copied
+	AddedAt  int64  `json:"addedAt"`  // Unix timestamp
+}
+
+// PrintSettings are the print/export layout preferences of a tab, so that
+// repeated printouts of the same tab come out identical
+type PrintSettings struct {
+	TabID         string  `json:"tabId"`
+	PaperSize     string  `json:"paperSize"`     // "A4", "A3", "A5", "Letter", "Legal"
+	MarginTop     float64 `json:"marginTop"`     // Millimetres
+	MarginRight   float64 `json:"marginRight"`   // Millimetres
+	MarginBottom  float64 `json:"marginBottom"`  // Millimetres
+	MarginLeft    float64 `json:"marginLeft"`    // Millimetres
+	PagesPerSheet int     `json:"pagesPerSheet"` // 1, 2 or 4
+	Tracks        []int   `json:"tracks"`        // GP track indexes to render, empty for all
+}
+
+// DefaultPrintSettings returns the print settings used until a tab has its own
+func DefaultPrintSettings(tabID string) PrintSettings {
+	return PrintSettings{
+		TabID:         tabID,
+		PaperSize:     "A4",
+		MarginTop:     10,
+		MarginRight:   10,
+		MarginBottom:  10,
+		MarginLeft:    10,
+		PagesPerSheet: 1,
+		Tracks:        []int{},
+	}
 }
 
 type Category struct {
@@ -30,38 +274,191 @@ type Category struct {
 	ParentID           string `json:"parentId"`           // Empty if root
 	CoverPath          string `json:"coverPath"`          // Custom cover path (raw)
 	EffectiveCoverPath string `json:"effectiveCoverPath"` // Derived or custom
+	OpenMethod         string `json:"openMethod"`         // Overrides Settings.OpenMethod for its tabs: "system", "inner"; empty to inherit
+	OpenGpMethod       string `json:"openGpMethod"`       // Overrides Settings.OpenGpMethod, as OpenMethod
+	Color              string `json:"color"`              // "#rrggbb" the folder is drawn with; empty for the theme color
+	Icon               string `json:"icon"`               // Name of the folder icon, e.g. "music"; empty for a folder
+	SortOrder          int    `json:"sortOrder"`          // Position among its siblings, see DBStore.ReorderCategories
+	Pinned             bool   `json:"pinned"`             // Shown before its unpinned siblings
+}
+
+// Operation is one step of a batch applied by DBStore.ApplyOperations. Op
+// selects the fields used:
+//   - "createCategory": CategoryID, Name, ParentID
+//   - "renameCategory": CategoryID, Name
+//   - "moveCategory": CategoryID, ParentID ("" for the root)
+//   - "deleteCategory": CategoryID
+//   - "moveTabs": TabIDs, CategoryID ("" for none), replacing their categories
+//   - "addTabsToCategory", "removeTabsFromCategory": TabIDs, CategoryID
+//   - "setRating": TabIDs, Rating
+//   - "setPracticeStatus": TabIDs, PracticeStatus
+//   - "setDifficulty": TabIDs, Difficulty
+type Operation struct {
+	Op             string   `json:"op"`
+	CategoryID     string   `json:"categoryId,omitempty"`
+	Name           string   `json:"name,omitempty"`
+	ParentID       string   `json:"parentId,omitempty"`
+	TabIDs         []string `json:"tabIds,omitempty"`
+	Rating         int      `json:"rating,omitempty"`
+	PracticeStatus string   `json:"practiceStatus,omitempty"`
+	Difficulty     string   `json:"difficulty,omitempty"`
+}
+
+// CategoryStats summarizes the tabs directly in a category
+type CategoryStats struct {
+	CategoryID   string         `json:"categoryId"`
+	Tabs         int            `json:"tabs"`
+	Types        map[string]int `json:"types"`        // Tabs per type, e.g. "pdf", "gp"
+	TotalBytes   int64          `json:"totalBytes"`   // Size of the tab files
+	TotalSize    string         `json:"totalSize"`    // TotalBytes formatted for the locale
+	LastActivity int64          `json:"lastActivity"` // Latest tab added, opened or filed into it (Unix timestamp)
+}
+
+// SyncPath is a folder scanned by syncs and watched for changes
+type SyncPath struct {
+	Path             string   `json:"path"`
+	Recursive        bool     `json:"recursive"`        // Scan subfolders too
+	Mode             string   `json:"mode"`             // SyncModeLink or SyncModeCopy
+	CategoryStrategy string   `json:"categoryStrategy"` // How subfolders map to categories of new tabs, e.g. FolderCategoriesNested; empty for none
+	CategoryRoot     string   `json:"categoryRoot"`     // Category holding the folder categories; empty for the root
+	Include          []string `json:"include"`          // Name patterns of the files to add, e.g. "*.gp5"; all supported files if empty
+	Exclude          []string `json:"exclude"`          // Patterns of the files and folders to skip, e.g. "*.tmp", "**/Backups/**"
 }
 
-type KeyBindings struct {
-	ScrollDown      string `json:"scrollDown"`
-	ScrollUp        string `json:"scrollUp"`
-	Metronome       string `json:"metronome"`
-	PlayPause       string `json:"playPause"`
-	Stop            string `json:"stop"`
-	BpmPlus         string `json:"bpmPlus"`
-	BpmMinus        string `json:"bpmMinus"`
-	ToggleLoop      string `json:"toggleLoop"`
-	ClearSelection  string `json:"clearSelection"`
-	JumpToBar       string `json:"jumpToBar"`
-	JumpToStart     string `json:"jumpToStart"`
-	AutoScroll      string `json:"autoScroll"`
-	ScrollSpeedUp   string `json:"scrollSpeedUp"`
-	ScrollSpeedDown string `json:"scrollSpeedDown"`
+// PendingConflict is a new file a sync with the "ask" strategy did not add,
+// because a tab already has its title, until the user resolves it
+type PendingConflict struct {
+	Path         string `json:"path"` // Inside a zip archive: the archive path joined with the entry name
+	Title        string `json:"title"`
+	ExistingPath string `json:"existingPath"` // File of the tab with the title
+	FoundAt      int64  `json:"foundAt"`      // Unix timestamp
+}
+
+// SyncRun is a full sync recorded in the sync history
+type SyncRun struct {
+	ID         int64           `json:"id"`
+	StartedAt  int64           `json:"startedAt"` // Unix timestamp
+	DurationMs int64           `json:"durationMs"`
+	Total      int             `json:"total"` // Files found
+	Added      int             `json:"added"`
+	Updated    int             `json:"updated"` // Tabs whose file was found again
+	Skipped    int             `json:"skipped"`
+	Errors     int             `json:"errors"`
+	Pending    int             `json:"pending"`    // Title conflicts queued for the user
+	Conflicts  int             `json:"conflicts"`  // Files skipped or retitled for their title
+	Report     string          `json:"report"`     // Name of the conflict report, empty if none
+	FileErrors []SyncFileError `json:"fileErrors"` // First errors of the run, see MaxSyncFileErrors
+}
+
+// SyncFileError is a file a sync failed on
+type SyncFileError struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// CategoryTemplate is a folder structure created in one step, e.g. for the
+// library of each student of a teacher
+type CategoryTemplate struct {
+	ID      string           `json:"id"`
+	Name    string           `json:"name"`
+	Folders []TemplateFolder `json:"folders"`
+	BuiltIn bool             `json:"builtIn"` // Shipped with the app; cannot be deleted
+}
+
+// TemplateFolder is a category created by a template
+type TemplateFolder struct {
+	Name     string           `json:"name"`
+	Children []TemplateFolder `json:"children"`
+}
+
+// KeyBindings maps action names (see KeyActions) to keys, as lower case
+// KeyboardEvent.key values. An empty key leaves the action unbound.
+type KeyBindings map[string]string
+
+// PedalBindings maps foot controller pedals (see pedal.Press.Control, e.g.
+// "cc:64") to action names (see KeyActions)
+type PedalBindings map[string]string
+
+// PedalProfile binds the pedals of the controllers matching Device. Page
+// turners send different keys for the same pedal (arrows, Page Up/Down),
+// so each gets a profile of its own.
+type PedalProfile struct {
+	Name     string        `json:"name"`
+	Device   string        `json:"device"` // Part of the controller name, case-insensitive; empty for any controller
+	Bindings PedalBindings `json:"bindings"`
+}
+
+// DefaultKeyProfile is the key binding profile of a new installation
+const DefaultKeyProfile = "Default"
+
+// KeyBindingProfile is a named set of key bindings, e.g. "Laptop" or
+// "Foot Controller". The active one is Settings.KeyProfile.
+type KeyBindingProfile struct {
+	Name        string      `json:"name"`
+	KeyBindings KeyBindings `json:"keyBindings"`
+	UpdatedAt   int64       `json:"updatedAt"`
+	Active      bool        `json:"active"`
 }
 
 type Settings struct {
-	Theme             string      `json:"theme"`        // "dark", "light", "system"
-	Background        string      `json:"background"`   // URL or path
-	BgType            string      `json:"bgType"`       // "url", "local"
-	OpenMethod        string      `json:"openMethod"`   // "system", "inner"
-	OpenGpMethod      string      `json:"openGpMethod"` // "system", "inner"
-	AudioDevice       string      `json:"audioDevice"`  // Device ID for audio output
-	SyncPaths         []string    `json:"syncPaths"`
-	SyncStrategy      string      `json:"syncStrategy"` // "skip", "overwrite"
-	AutoSyncEnabled   bool        `json:"autoSyncEnabled"`
-	AutoSyncFrequency string      `json:"autoSyncFrequency"` // "startup", "weekly", "monthly", "yearly"
-	LastSyncTime      int64       `json:"lastSyncTime"`      // Unix timestamp
-	KeyBindings       KeyBindings `json:"keyBindings"`
+	Theme              string         `json:"theme"`        // "dark", "light", "system"
+	Background         string         `json:"background"`   // URL or path
+	BgType             string         `json:"bgType"`       // "url", "local"
+	OpenMethod         string         `json:"openMethod"`   // "system", "inner"
+	OpenGpMethod       string         `json:"openGpMethod"` // "system", "inner"
+	AudioDevice        string         `json:"audioDevice"`  // Device ID for audio output
+	SyncStrategy       string         `json:"syncStrategy"` // "skip", "overwrite", "ask"
+	AutoSyncEnabled    bool           `json:"autoSyncEnabled"`
+	AutoSyncFrequency  string         `json:"autoSyncFrequency"` // "startup", "weekly", "monthly", "yearly", "interval"
+	AutoSyncInterval   int            `json:"autoSyncInterval"`  // Hours between syncs when frequency is "interval"
+	SyncZipArchives    bool           `json:"syncZipArchives"`   // Import the tab files inside .zip archives found by a sync
+	LastSyncTime       int64          `json:"lastSyncTime"`      // Unix timestamp
+	KeyBindings        KeyBindings    `json:"keyBindings"`
+	KeyProfile         string         `json:"keyProfile"`         // Name of the active key binding profile
+	CoverRegions       []CoverRegion  `json:"coverRegions"`       // iTunes regions tried in order when searching covers
+	CoverDailyBudget   int            `json:"coverDailyBudget"`   // Covers fetched per day for large imports; 0 for no limit
+	CoverRefreshMonths int            `json:"coverRefreshMonths"` // Re-fetch covers this old when their tab's metadata changed; 0 to never
+	CoverMaxSize       int            `json:"coverMaxSize"`       // Width and height downloaded covers are scaled down to; 0 to keep their size
+	CoverJPEGQuality   int            `json:"coverJpegQuality"`   // JPEG quality 1-100 downloaded covers are re-encoded with; 0 with CoverMaxSize 0 to keep them as downloaded
+	AccessLogEnabled   bool           `json:"accessLogEnabled"`   // Write file server requests to logs/access-*.log
+	Locale             string         `json:"locale"`             // BCP 47 tag for dates and numbers in reports, e.g. "ja-JP"; empty for the system locale
+	PedalEnabled       bool           `json:"pedalEnabled"`       // Read MIDI and HID foot controllers
+	PedalBindings      PedalBindings  `json:"pedalBindings"`      // Pedals no matching profile binds
+	PedalProfiles      []PedalProfile `json:"pedalProfiles"`      // Checked in order; the first matching profile binding the pedal wins
+	CacheLimitMB       int            `json:"cacheLimitMB"`       // Size cap of derived images such as category collages; 0 for no limit
+	OfflineMode        bool           `json:"offlineMode"`        // Hold cover downloads and make no network requests
+	HTTPProxy          string         `json:"httpProxy"`          // Proxy of metadata requests, e.g. "http://proxy:8080"; empty for the system proxy
+	HTTPTimeout        int            `json:"httpTimeout"`        // Seconds per metadata request; 0 for the default
+	TLSCAFile          string         `json:"tlsCaFile"`          // PEM certificates trusted besides the system ones
+	TLSSkipVerify      bool           `json:"tlsSkipVerify"`      // Accept any certificate; only for debugging
+	EnrichProvider     string         `json:"enrichProvider"`     // Fills missing album, genre and year: "lastfm", "spotify" or "" for none
+	LastFMAPIKey       string         `json:"lastfmApiKey"`
+	SpotifyClientID    string         `json:"spotifyClientId"`
+	SpotifySecret      string         `json:"spotifySecret"`    // Client secret of the Spotify app
+	CloudBackupHours   int            `json:"cloudBackupHours"` // Hours between backups to the bucket; 0 for manual backups only
+	CloudEndpoint      string         `json:"cloudEndpoint"`    // S3-compatible endpoint, e.g. "https://s3.us-west-004.backblazeb2.com"
+	CloudRegion        string         `json:"cloudRegion"`      // "us-east-1" if empty
+	CloudBucket        string         `json:"cloudBucket"`
+	CloudPrefix        string         `json:"cloudPrefix"` // Folder of the backup in the bucket
+	CloudAccessKey     string         `json:"cloudAccessKey"`
+	CloudSecretKey     string         `json:"cloudSecretKey"`
+	LastCloudBackup    int64          `json:"lastCloudBackup"`   // Unix timestamp of the last complete backup
+	MaintenanceDays    int            `json:"maintenanceDays"`   // Days between automatic database maintenance; 0 for manual only
+	LastMaintenance    int64          `json:"lastMaintenance"`   // Unix timestamp of the last maintenance
+	LANServerEnabled   bool           `json:"lanServerEnabled"`  // Serve the library read-only to devices on the LAN
+	LANServerPort      int            `json:"lanServerPort"`     // 0 for the default
+	LANServerToken     string         `json:"lanServerToken"`    // Required by LAN requests; only changes with SetSetting
+	FuzzySearch        bool           `json:"fuzzySearch"`       // Show the tabs of the corrected query when a search finds nothing
+	SharedLibraryPath  string         `json:"sharedLibraryPath"` // Folder or database of a library attached read-only; empty for none
+	ConverterCommand   string         `json:"converterCommand"`  // Converts files of ConverterExts on import, e.g. `mscore -o "{output}" "{input}"`; empty for none
+	ConverterExts      string         `json:"converterExts"`     // Converted extensions, e.g. ".mscz .mxl .musicxml"
+	ConverterFormat    string         `json:"converterFormat"`   // Extension of the converted files, e.g. "pdf" or "gp"; "pdf" if empty
+}
+
+// CoverRegion is an iTunes storefront searched for covers
+type CoverRegion struct {
+	Country string `json:"country"` // e.g. "US", "JP"
+	Lang    string `json:"lang"`    // e.g. "en_us", "ja_jp"
 }
 
 // Deprecated: Use DBStore instead
@@ -91,26 +488,10 @@ func NewStore(dataPath string) *Store {
 			OpenMethod:        "inner",
 			OpenGpMethod:      "system",
 			SyncStrategy:      "skip",
-			SyncPaths:         []string{},
 			AutoSyncEnabled:   false,
 			AutoSyncFrequency: "startup",
 			LastSyncTime:      0,
-			KeyBindings: KeyBindings{
-				ScrollDown:      "j",
-				ScrollUp:        "k",
-				Metronome:       "m",
-				PlayPause:       "p",
-				Stop:            "o",
-				BpmPlus:         "l",
-				BpmMinus:        "h",
-				ToggleLoop:      "r",
-				ClearSelection:  "escape",
-				JumpToBar:       "t",
-				JumpToStart:     "i",
-				AutoScroll:      "n",
-				ScrollSpeedUp:   ".",
-				ScrollSpeedDown: ",",
-			},
+			KeyBindings:       DefaultKeyBindings(),
 		},
 	}
 }
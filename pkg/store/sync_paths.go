@@ -0,0 +1,152 @@
+package store
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Modes of SyncPath
+const (
+	SyncModeLink = "link" // Tabs point to the files in the sync path
+	SyncModeCopy = "copy" // Files are copied once to app storage as managed tabs
+)
+
+// Strategies of SyncPath.CategoryStrategy
+const (
+	FolderCategoriesNested = "nested" // Each folder is a category inside the one of its parent folder
+	FolderCategoriesTop    = "top"    // Only the first folder under the sync path
+	FolderCategoriesParent = "parent" // Only the folder holding the file
+)
+
+// Includes reports whether a file named name is added by syncs of p
+func (p SyncPath) Includes(name string) bool {
+	if len(p.Include) == 0 {
+		return true
+	}
+	name = strings.ToLower(name)
+	for _, pattern := range p.Include {
+		if ok, _ := filepath.Match(strings.ToLower(pattern), name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Excludes reports whether the file or folder at path matches one of the
+// exclude patterns of p. Syncs skip excluded files and the whole of excluded
+// folders.
+func (p SyncPath) Excludes(filePath string) bool {
+	if len(p.Exclude) == 0 || !p.Contains(filePath) {
+		return false
+	}
+	rel, _ := filepath.Rel(p.Path, filePath)
+	rel = strings.ToLower(filepath.ToSlash(rel))
+	for _, pattern := range p.Exclude {
+		if matchGlob(strings.ToLower(filepath.ToSlash(pattern)), rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob reports whether rel, a slash separated path, matches pattern.
+// "**" matches any number of folders, e.g. "**/Backups/**". A pattern
+// without a slash is matched against each name in rel, so "*.tmp" matches
+// .tmp files in any folder.
+func matchGlob(pattern, rel string) bool {
+	names := strings.Split(rel, "/")
+	if !strings.Contains(pattern, "/") {
+		for _, name := range names {
+			if ok, _ := path.Match(pattern, name); ok {
+				return true
+			}
+		}
+		return false
+	}
+	return matchNames(strings.Split(strings.Trim(pattern, "/"), "/"), names)
+}
+
+// matchNames matches the names of a path against the parts of a pattern
+func matchNames(parts, names []string) bool {
+	for len(parts) > 0 {
+		if parts[0] == "**" {
+			for i := 0; i <= len(names); i++ {
+				if matchNames(parts[1:], names[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(names) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(parts[0], names[0]); !ok {
+			return false
+		}
+		parts, names = parts[1:], names[1:]
+	}
+	return len(names) == 0
+}
+
+// FindSyncPath returns the innermost of paths holding path, or nil
+func FindSyncPath(paths []SyncPath, path string) *SyncPath {
+	var found *SyncPath
+	for i, p := range paths {
+		if !p.Contains(path) {
+			continue
+		}
+		if found == nil || len(p.Path) > len(found.Path) {
+			found = &paths[i]
+		}
+	}
+	return found
+}
+
+// Contains reports whether path is in p or one of its subfolders, whether
+// p is recursive or not
+func (p SyncPath) Contains(path string) bool {
+	rel, err := filepath.Rel(p.Path, path)
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// Scans reports whether syncs of p look at the file at path: it must be in
+// p, or in a subfolder if p is recursive. See Includes for its name.
+func (p SyncPath) Scans(path string) bool {
+	return p.Contains(path) && (p.Recursive || filepath.Dir(path) == filepath.Clean(p.Path))
+}
+
+// Validate checks the options of p
+func (p SyncPath) Validate() error {
+	if p.Path == "" {
+		return fmt.Errorf("empty sync path")
+	}
+	// Syncs could only ever fail on them
+	if scheme, _, ok := strings.Cut(p.Path, "://"); ok && strings.EqualFold(scheme, "smb") {
+		return fmt.Errorf("SMB shares are not supported, mount the share and add it as a folder")
+	}
+	switch p.Mode {
+	case SyncModeLink, SyncModeCopy:
+	default:
+		return fmt.Errorf("invalid sync mode: %q", p.Mode)
+	}
+	switch p.CategoryStrategy {
+	case "", FolderCategoriesNested, FolderCategoriesTop, FolderCategoriesParent:
+	default:
+		return fmt.Errorf("invalid folder category strategy: %q", p.CategoryStrategy)
+	}
+	for _, pattern := range p.Include {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid include pattern %q: %w", pattern, err)
+		}
+	}
+	for _, pattern := range p.Exclude {
+		for _, part := range strings.Split(filepath.ToSlash(pattern), "/") {
+			if _, err := path.Match(part, ""); err != nil {
+				return fmt.Errorf("invalid exclude pattern %q: %w", pattern, err)
+			}
+		}
+	}
+	return nil
+}
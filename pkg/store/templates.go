@@ -0,0 +1,33 @@
+package store
+
+import "fmt"
+
+// builtinCategoryTemplates are the templates shipped with the app. Their
+// IDs start with "builtin:" so they never collide with saved templates.
+var builtinCategoryTemplates = []CategoryTemplate{
+	{
+		ID:   "builtin:lessons",
+		Name: "Lessons",
+		Folders: []TemplateFolder{
+			{Name: "Technique"},
+			{Name: "Repertoire"},
+			{Name: "Theory"},
+		},
+		BuiltIn: true,
+	},
+	{
+		ID:      "builtin:grades",
+		Name:    "Grades 1-8",
+		Folders: gradeFolders(8),
+		BuiltIn: true,
+	},
+}
+
+// gradeFolders returns the folders "Grade 1" to "Grade n"
+func gradeFolders(n int) []TemplateFolder {
+	folders := make([]TemplateFolder, n)
+	for i := range folders {
+		folders[i] = TemplateFolder{Name: fmt.Sprintf("Grade %d", i+1)}
+	}
+	return folders
+}
@@ -0,0 +1,125 @@
+package sync
+
+import (
+	"archive/zip"
+	"fmt"
+	"haya-tab/pkg/store"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// maxArchiveEntrySize caps the size of a file extracted from a zip archive
+const maxArchiveEntrySize = 200 << 20
+
+// importArchive imports the supported files in a zip archive as managed
+// tabs: each entry is extracted to app storage and gets its own tab. Entries
+// imported by an earlier sync are left alone, so the archive can stay in the
+// sync directory. Title conflicts are resolved with strategy like for files,
+// and entries are included or excluded by the patterns of root as if the
+// archive were a folder.
+func (s *SyncService) importArchive(archivePath string, root *store.SyncPath, strategy string, result *SyncResult, batch *tabBatch) {
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		s.logger.Info("Failed to open archive %s: %v", archivePath, err)
+		result.fail(archivePath, err)
+		return
+	}
+	defer zr.Close()
+
+	imported, err := s.store.GetArchiveEntries(archivePath)
+	if err != nil {
+		s.logger.Error("Failed to read the imported entries of %s: %v", archivePath, err)
+		result.fail(archivePath, err)
+		return
+	}
+
+	var tmpDir string
+	defer func() {
+		if tmpDir != "" {
+			os.RemoveAll(tmpDir)
+		}
+	}()
+
+	for _, f := range zr.File {
+		name := filepath.Base(filepath.FromSlash(f.Name))
+		if f.FileInfo().IsDir() || imported[f.Name] ||
+			strings.HasPrefix(f.Name, "__MACOSX/") || strings.HasPrefix(name, ".") ||
+			!s.isSupportedExtension(strings.ToLower(filepath.Ext(name))) {
+			continue
+		}
+		entryPath := filepath.Join(archivePath, filepath.FromSlash(f.Name))
+		if root != nil && (!root.Includes(name) || root.Excludes(entryPath)) {
+			continue
+		}
+
+		result.Total++
+		s.emitter.Emit("sync-progress", map[string]interface{}{
+			"message":  fmt.Sprintf("Extracting: %s", name),
+			"count":    result.Total,
+			"filePath": entryPath,
+		})
+
+		if tmpDir == "" {
+			if tmpDir, err = os.MkdirTemp("", "haya-tab-zip-*"); err != nil {
+				s.logger.Error("Failed to create a directory to extract %s: %v", archivePath, err)
+				result.fail(archivePath, err)
+				return
+			}
+		}
+		tabID, err := s.importArchiveEntry(archivePath, f, filepath.Join(tmpDir, name), strategy, result, batch)
+		if err != nil {
+			s.logger.Info("Failed to import %s: %v", entryPath, err)
+			result.fail(entryPath, err)
+			continue
+		}
+		if tabID == "" {
+			continue // Skipped on a title conflict, reported again by the next sync
+		}
+		if err := s.store.AddArchiveEntry(archivePath, f.Name, tabID, time.Now().Unix()); err != nil {
+			s.logger.Info("Failed to record %s as imported: %v", entryPath, err)
+		}
+	}
+}
+
+// importArchiveEntry extracts an archive entry to dst, copies it to storage
+// and adds its tab. It returns the ID of the tab, or "" if it was skipped.
+func (s *SyncService) importArchiveEntry(archivePath string, f *zip.File, dst, strategy string, result *SyncResult, batch *tabBatch) (string, error) {
+	if err := extractEntry(f, dst); err != nil {
+		return "", err
+	}
+	defer os.Remove(dst)
+
+	entryPath := filepath.Join(archivePath, filepath.FromSlash(f.Name))
+	return s.addCopiedTab(s.ProcessFile(dst), dst, entryPath, archiveCategoryPath(archivePath, f.Name), strategy, result, batch)
+}
+
+// archiveCategoryPath returns the path folder categories are mapped from for
+// an archive entry: the archive counts as a folder holding its entries
+func archiveCategoryPath(archivePath, entry string) string {
+	return filepath.Join(strings.TrimSuffix(archivePath, filepath.Ext(archivePath)), filepath.FromSlash(entry))
+}
+
+// extractEntry writes the content of an archive entry to dst
+func extractEntry(f *zip.File, dst string) error {
+	if f.UncompressedSize64 > maxArchiveEntrySize {
+		return fmt.Errorf("entry is larger than %d MB", maxArchiveEntrySize>>20)
+	}
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, io.LimitReader(r, maxArchiveEntrySize)); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
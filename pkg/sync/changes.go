@@ -0,0 +1,240 @@
+package sync
+
+import (
+	"haya-tab/pkg/metadata"
+	"haya-tab/pkg/store"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ProcessChanges applies the file changes reported by the file watcher
+// instead of rescanning every sync path: new files are added, modified files
+// get their track list refreshed and tabs of removed files are marked
+// missing. A removed file and a new file with the same content (or the same
+// name, if the old file was never hashed) are treated as a rename, and the
+// tab is moved to the new path, as is a missing tab whose content turns up
+// in a later batch.
+func (s *SyncService) ProcessChanges(changed, removed []string) SyncResult {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	result := SyncResult{}
+	settings := s.store.GetSettings()
+	strategy := settings.SyncStrategy
+	syncPaths, err := s.store.GetSyncPaths()
+	if err != nil {
+		s.logger.Error("Failed to read sync paths: %v", err)
+		return result
+	}
+	s.resetFolderCategories(syncPaths)
+	conv := s.converter()
+
+	// Tabs whose file disappeared; entries are consumed by renames
+	var removedTabs []*store.Tab
+	for _, path := range removed {
+		if !s.isSupportedExtension(strings.ToLower(filepath.Ext(path))) {
+			continue
+		}
+		tab, err := s.store.GetTabByPath(path)
+		if err != nil || tab == nil || tab.IsMissing {
+			continue
+		}
+		removedTabs = append(removedTabs, tab)
+	}
+
+	for _, path := range changed {
+		// Paths outside the sync paths are linked, as by a recursive one
+		root := store.FindSyncPath(syncPaths, path)
+		if root == nil {
+			root = &store.SyncPath{Path: filepath.Dir(path), Mode: store.SyncModeLink}
+		}
+		if !root.Scans(path) || root.Excludes(path) {
+			continue
+		}
+		if settings.SyncZipArchives && strings.EqualFold(filepath.Ext(path), ".zip") {
+			s.importArchive(path, root, strategy, &result, nil)
+			continue
+		}
+		if conv.converts(strings.ToLower(filepath.Ext(path))) && root.Includes(filepath.Base(path)) {
+			// Converted once, whatever the mode of the sync path
+			result.Total++
+			if copied, err := s.store.IsFileCopied(path); err == nil && !copied {
+				s.importConverted(conv, path, strategy, &result, nil)
+			}
+			continue
+		}
+		if !s.isSupportedExtension(strings.ToLower(filepath.Ext(path))) || !root.Includes(filepath.Base(path)) {
+			continue
+		}
+		result.Total++
+
+		if root.Mode == store.SyncModeCopy {
+			// Changes to copied files are not picked up
+			if copied, err := s.store.IsFileCopied(path); err == nil && !copied {
+				s.copySyncFile(s.ProcessFile(path), path, strategy, &result, nil)
+			}
+			continue
+		}
+
+		existingTab, err := s.store.GetTabByPath(path)
+		if err != nil {
+			result.Errors++
+			continue
+		}
+		if existingTab != nil {
+			s.refreshFile(existingTab, &result)
+			continue
+		}
+
+		if i := s.findRenamedTab(path, removedTabs); i >= 0 {
+			tab := removedTabs[i]
+			removedTabs = append(removedTabs[:i], removedTabs[i+1:]...)
+			if err := s.store.UpdateTabPath(tab.ID, path); err != nil {
+				result.Errors++
+				continue
+			}
+			s.logger.Info("Tab %s moved: %s -> %s", tab.Title, tab.FilePath, path)
+			result.Renamed++
+			s.emitTabUpdated(tab.ID)
+			continue
+		}
+
+		s.addFile(path, strategy, &result)
+	}
+
+	for _, tab := range removedTabs {
+		if err := s.store.SetTabMissing(tab.ID, true); err != nil {
+			result.Errors++
+			continue
+		}
+		s.logger.Info("Tab %s is missing its file: %s", tab.Title, tab.FilePath)
+		result.Missing++
+		s.emitTabUpdated(tab.ID)
+	}
+
+	report := s.writeConflictReport(result.Conflicts)
+	if result.Total+result.Missing > 0 {
+		s.emitter.Emit("file-changes-processed", map[string]interface{}{
+			"added":   result.Added,
+			"updated": result.Updated,
+			"renamed": result.Renamed,
+			"missing": result.Missing,
+			"skipped": result.Skipped,
+			"errors":  result.Errors,
+			"pending": result.Pending,
+			"report":  report,
+		})
+	}
+	if result.Added > 0 || result.Updated > 0 {
+		go s.BackfillHashes()
+	}
+	if result.Added > 0 {
+		go s.BackfillContent()
+	}
+
+	s.logger.Info("Incremental sync: Added: %d, Updated: %d, Renamed: %d, Missing: %d, Errors: %d",
+		result.Added, result.Updated, result.Renamed, result.Missing, result.Errors)
+	return result
+}
+
+// refreshFile updates a tab after its file was modified: the title, artist,
+// album, track list and credits of GP files and the content of text and GP files are
+// read again, and the stale hash is cleared for the backfill to recompute
+func (s *SyncService) refreshFile(tab *store.Tab, result *SyncResult) {
+	if tab.IsMissing {
+		if err := s.store.SetTabMissing(tab.ID, false); err != nil {
+			result.Errors++
+			return
+		}
+	}
+	if err := s.store.SetTabHash(tab.ID, tab.FilePath, ""); err != nil {
+		result.Errors++
+		return
+	}
+	if info, err := os.Stat(tab.FilePath); err == nil {
+		if err := s.store.SetTabFileSize(tab.ID, tab.FilePath, info.Size()); err != nil {
+			s.logger.Error("Failed to save the file size of %s: %v", tab.Title, err)
+		}
+	}
+	if tab.Type == "gp" {
+		meta, err := metadata.ParseFile(tab.FilePath)
+		if err != nil {
+			s.logger.Info("Could not read %s: %v", tab.FilePath, err)
+		}
+		if err := s.store.SetTabTracks(tab.ID, tabTracks(meta.Tracks)); err != nil {
+			s.logger.Error("Failed to save tracks for %s: %v", tab.Title, err)
+		}
+		if err := s.store.SetTabCredits(tab.ID, tabCredits(meta.Score)); err != nil {
+			s.logger.Error("Failed to save credits for %s: %v", tab.Title, err)
+		}
+		if err := s.store.FillTabTempoKey(tab.ID, meta.Score.Tempo, meta.Score.Key); err != nil {
+			s.logger.Error("Failed to save the tempo and key of %s: %v", tab.Title, err)
+		}
+		if err := s.store.SetTabDuration(tab.ID, meta.Score.Duration); err != nil {
+			s.logger.Error("Failed to save the duration of %s: %v", tab.Title, err)
+		}
+		s.refreshInfo(tab, meta.Score)
+	}
+	switch tab.Type {
+	case "gp", "text", "chordpro":
+		if err := s.refreshContent(*tab); err != nil {
+			s.logger.Error("Failed to save the content of %s: %v", tab.Title, err)
+		}
+	}
+	result.Updated++
+	s.emitTabUpdated(tab.ID)
+}
+
+// refreshInfo applies the song info stored in a modified GP file. Empty
+// fields keep their current value.
+func (s *SyncService) refreshInfo(tab *store.Tab, score metadata.ScoreInfo) {
+	title, artist, album := tab.Title, tab.Artist, tab.Album
+	if t := strings.TrimSpace(score.Title); t != "" {
+		title = t
+	}
+	if a := strings.TrimSpace(score.Artist); a != "" {
+		artist = a
+	}
+	if a := strings.TrimSpace(score.Album); a != "" {
+		album = a
+	}
+	if title == tab.Title && artist == tab.Artist && album == tab.Album {
+		return
+	}
+	if err := s.store.SetTabInfo(tab.ID, title, artist, album); err != nil {
+		s.logger.Error("Failed to update info of %s: %v", tab.Title, err)
+	}
+}
+
+// findRenamedTab returns the index in removedTabs of the tab whose file was
+// renamed or moved to path, or -1
+func (s *SyncService) findRenamedTab(path string, removedTabs []*store.Tab) int {
+	if len(removedTabs) == 0 {
+		return -1
+	}
+
+	hash, err := HashFile(path)
+	if err == nil {
+		for i, tab := range removedTabs {
+			if tab.FileHash != "" && tab.FileHash == hash {
+				return i
+			}
+		}
+	}
+
+	// Unhashed tabs: only a move to another folder keeps the file name
+	for i, tab := range removedTabs {
+		if tab.FileHash == "" && filepath.Base(tab.FilePath) == filepath.Base(path) {
+			return i
+		}
+	}
+	return -1
+}
+
+// emitTabUpdated sends the current state of a tab to the frontend
+func (s *SyncService) emitTabUpdated(id string) {
+	if tab, err := s.store.GetTab(id); err == nil && tab != nil {
+		s.emitter.Emit("tab-updated", *tab)
+	}
+}
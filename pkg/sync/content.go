@@ -0,0 +1,76 @@
+package sync
+
+import (
+	"context"
+	"haya-tab/pkg/jobpool"
+	"haya-tab/pkg/metadata"
+	"haya-tab/pkg/store"
+	"os"
+	"sync/atomic"
+)
+
+// BackfillContent extracts the lyrics of the text and Guitar Pro tabs not
+// indexed yet, in the background job pool, so a search of a lyric line finds
+// the song. Files that cannot be opened are retried on the next run; files
+// that fail to parse are stored with an empty content. If a backfill is
+// already running, another pass runs once it ends.
+func (s *SyncService) BackfillContent() {
+	if !s.contentBackfill.start() {
+		return
+	}
+
+	tabs, err := s.store.GetTabsMissingContent()
+	if err != nil {
+		s.logger.Info("Content backfill: failed to list tabs: %v", err)
+		s.finishContentBackfill()
+		return
+	}
+	if len(tabs) == 0 {
+		s.finishContentBackfill()
+		return
+	}
+
+	total := len(tabs)
+	s.logger.Info("Content backfill started for %d tabs", total)
+
+	var done atomic.Int32
+	for _, tab := range tabs {
+		tab := tab
+		submitted := s.jobPool.Submit(jobpool.Job{
+			Name: "content:" + tab.ID,
+			Run: func(ctx context.Context) error {
+				if _, err := os.Stat(tab.FilePath); err != nil {
+					return err
+				}
+				return s.refreshContent(tab)
+			},
+			OnComplete: func(error) {
+				if int(done.Add(1)) == total {
+					s.logger.Info("Content backfill completed for %d tabs", total)
+					s.finishContentBackfill()
+				}
+			},
+		})
+		if !submitted {
+			// Pool is shutting down
+			return
+		}
+	}
+}
+
+// refreshContent extracts the content of the file of tab again
+func (s *SyncService) refreshContent(tab store.Tab) error {
+	content, err := metadata.ExtractContent(tab.FilePath)
+	if err != nil {
+		s.logger.Info("Could not read the content of %s: %v", tab.FilePath, err)
+	}
+	return s.store.SetTabContent(tab.ID, content)
+}
+
+// finishContentBackfill ends a content backfill pass, starting another if
+// new files were queued while it ran
+func (s *SyncService) finishContentBackfill() {
+	if s.contentBackfill.finish() {
+		go s.BackfillContent()
+	}
+}
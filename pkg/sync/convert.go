@@ -0,0 +1,182 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"haya-tab/pkg/metadata"
+	"haya-tab/pkg/store"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// converterTimeout bounds a run of the converter command
+const converterTimeout = 5 * time.Minute
+
+// converter is the external command of the settings converting files of
+// formats the app cannot open, e.g. MuseScore or MusicXML files, to one it
+// can, such as PDF
+type converter struct {
+	args       []string        // Command and arguments, with {input} and {output} placeholders
+	extensions map[string]bool // Converted extensions, lower case with the dot
+	format     string          // Extension of the converted files, with the dot
+}
+
+// converter returns the converter of the settings, or nil if none is set
+func (s *SyncService) converter() *converter {
+	settings := s.store.GetSettings()
+	args := splitCommand(settings.ConverterCommand)
+	if len(args) == 0 {
+		return nil
+	}
+	format := "." + strings.TrimPrefix(strings.ToLower(strings.TrimSpace(settings.ConverterFormat)), ".")
+	if format == "." {
+		format = ".pdf"
+	}
+	if !s.isSupportedExtension(format) {
+		return nil // The settings only offer supported formats
+	}
+
+	c := &converter{args: args, extensions: map[string]bool{}, format: format}
+	fields := strings.FieldsFunc(strings.ToLower(settings.ConverterExts), func(r rune) bool {
+		return r == ',' || r == ';' || unicode.IsSpace(r)
+	})
+	for _, ext := range fields {
+		// Supported files are imported as they are
+		if ext = "." + strings.TrimPrefix(ext, "."); !s.isSupportedExtension(ext) {
+			c.extensions[ext] = true
+		}
+	}
+	if len(c.extensions) == 0 {
+		return nil
+	}
+	return c
+}
+
+// ConvertsExtension reports whether files of ext, e.g. ".mscz", are
+// converted on import
+func (s *SyncService) ConvertsExtension(ext string) bool {
+	return s.converter().converts(ext)
+}
+
+// converts reports whether files of ext are converted; a nil converter
+// converts none
+func (c *converter) converts(ext string) bool {
+	return c != nil && c.extensions[ext]
+}
+
+// convert runs the command on the file at path, writing the converted file
+// to dir under the name of path with the extension of the format, which
+// keeps the title and artist of the file name. Returns the converted file.
+func (c *converter) convert(path, dir string) (string, error) {
+	out := filepath.Join(dir, strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))+c.format)
+	args := make([]string, len(c.args))
+	for i, arg := range c.args {
+		arg = strings.ReplaceAll(arg, "{input}", path)
+		args[i] = strings.ReplaceAll(arg, "{output}", out)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), converterTimeout)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, args[0], args[1:]...).CombinedOutput()
+	if ctx.Err() != nil {
+		return "", fmt.Errorf("converter did not finish within %v", converterTimeout)
+	}
+	if err != nil {
+		if msg := lastLine(string(output)); msg != "" {
+			return "", fmt.Errorf("converter failed: %w: %s", err, msg)
+		}
+		return "", fmt.Errorf("converter failed: %w", err)
+	}
+	if info, err := os.Stat(out); err != nil || info.Size() == 0 {
+		return "", fmt.Errorf("converter wrote no %s file", c.format)
+	}
+	return out, nil
+}
+
+// importConverted converts the file at path and adds the converted file as
+// a managed tab, keeping path as the source of the tab. The original stays
+// where it is and is recorded as copied, so later syncs do not convert it
+// again. Titles come from the file name, so title conflicts are resolved
+// before the conversion.
+func (s *SyncService) importConverted(c *converter, path, strategy string, result *SyncResult, batch *tabBatch) {
+	meta := metadata.ParseFilename(path)
+	titled := store.Tab{Title: meta.Title}
+	if !s.resolveTitle(&titled, path, strategy, result, batch) {
+		return
+	}
+
+	dir, err := os.MkdirTemp("", "haya-tab-convert-*")
+	if err != nil {
+		s.logger.Error("Failed to create a directory to convert %s: %v", path, err)
+		result.fail(path, err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	converted, err := c.convert(path, dir)
+	if err != nil {
+		s.logger.Info("Failed to convert %s: %v", path, err)
+		result.fail(path, err)
+		return
+	}
+	tab := s.ProcessFile(converted)
+	tab.Title = titled.Title
+	tab.SourcePath = path
+	id, err := s.importCopiedTab(tab, converted, path, result)
+	if err != nil {
+		s.logger.Info("Failed to import the conversion of %s: %v", path, err)
+		result.fail(path, err)
+		return
+	}
+	s.logger.Info("Converted %s to %s", path, filepath.Base(converted))
+	s.recordConverted(path, id)
+}
+
+// recordConverted records that the file at path was converted to the tab
+// id, so later syncs leave it alone
+func (s *SyncService) recordConverted(path, id string) {
+	if err := s.store.AddCopiedFile(path, id, time.Now().Unix()); err != nil {
+		s.logger.Info("Failed to record %s as converted: %v", path, err)
+	}
+}
+
+// splitCommand splits a command line into its arguments at spaces outside
+// double or single quotes, which are removed
+func splitCommand(command string) []string {
+	var args []string
+	var arg strings.Builder
+	var quote rune // Quote of the quoted part being read, 0 outside quotes
+	inArg := false
+	for _, r := range command {
+		switch {
+		case r == quote:
+			quote = 0
+		case quote == 0 && (r == '"' || r == '\''):
+			quote = r
+			inArg = true
+		case unicode.IsSpace(r) && quote == 0:
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args
+}
+
+// lastLine returns the last non-empty line of the output of a command
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
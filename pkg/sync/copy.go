@@ -0,0 +1,110 @@
+package sync
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// copyTempPrefix starts the names of the temporary files of CopyFile, so
+// the ones left by a crash can be found (see RemoveStaleCopies)
+const copyTempPrefix = ".haya-copy-"
+
+// CopyFile copies src to dst so that dst is either complete or absent,
+// even if the app or the system crashes during the copy. The data goes to
+// a temporary file next to dst, is flushed to disk and checked against
+// src, then the file is renamed to dst. An existing dst is replaced.
+// Returns the hex encoded SHA-256 of the file, as HashFile does.
+func CopyFile(src, dst string) (string, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Dir(dst)
+	// Not named with the extension of dst, so watchers of dir ignore it
+	tmp, err := os.CreateTemp(dir, copyTempPrefix+"*.tmp")
+	if err != nil {
+		return "", err
+	}
+	tmpPath := tmp.Name()
+	committed := false
+	defer func() {
+		if !committed {
+			tmp.Close()
+			os.Remove(tmpPath)
+		}
+	}()
+
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tmp, h), in)
+	if err != nil {
+		return "", err
+	}
+	if err := tmp.Sync(); err != nil {
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+
+	// Check what reached the disk: the size read against the source, and
+	// the temporary file read back against the data written
+	if n != info.Size() {
+		return "", fmt.Errorf("copied %d of %d bytes of %s", n, info.Size(), filepath.Base(src))
+	}
+	hash := hex.EncodeToString(h.Sum(nil))
+	written, err := HashFile(tmpPath)
+	if err != nil {
+		return "", err
+	}
+	if written != hash {
+		return "", fmt.Errorf("copy of %s does not match the original", filepath.Base(src))
+	}
+
+	if err := os.Rename(tmpPath, dst); err != nil {
+		return "", err
+	}
+	committed = true
+	syncDir(dir)
+	return hash, nil
+}
+
+// syncDir flushes a directory so a rename in it survives a crash. Not
+// supported on Windows, where renames are durable once they return.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
+
+// RemoveStaleCopies deletes the temporary files left in dir by copies that
+// were interrupted by a crash. Returns the number of files removed.
+func RemoveStaleCopies(dir string) int {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0
+	}
+	removed := 0
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), copyTempPrefix) {
+			continue
+		}
+		if os.Remove(filepath.Join(dir, entry.Name())) == nil {
+			removed++
+		}
+	}
+	return removed
+}
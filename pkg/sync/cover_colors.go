@@ -0,0 +1,35 @@
+package sync
+
+import "haya-tab/pkg/palette"
+
+// UpdateCoverColor computes the dominant color of the cover of a tab and
+// stores it; a cover that cannot be decoded gets none. Returns the color.
+func (s *SyncService) UpdateCoverColor(tabID, coverPath string) string {
+	color, err := palette.DominantFile(coverPath)
+	if err != nil {
+		s.logger.Info("Failed to compute the cover color of %s: %v", tabID, err)
+		color = ""
+	}
+	if err := s.store.SetTabCoverColor(tabID, coverPath, color); err != nil {
+		s.logger.Info("Failed to store the cover color of %s: %v", tabID, err)
+	}
+	return color
+}
+
+// BackfillCoverColors computes the colors of the covers saved before cover
+// colors were stored
+func (s *SyncService) BackfillCoverColors() {
+	tabs, err := s.store.GetTabsMissingCoverColor()
+	if err != nil {
+		s.logger.Info("Cover color backfill: failed to list tabs: %v", err)
+		return
+	}
+	if len(tabs) == 0 {
+		return
+	}
+
+	for _, tab := range tabs {
+		s.UpdateCoverColor(tab.ID, tab.CoverPath)
+	}
+	s.logger.Info("Cover color backfill completed: %d covers", len(tabs))
+}
@@ -0,0 +1,125 @@
+package sync
+
+import (
+	"errors"
+	"haya-tab/pkg/metadata"
+	gosync "sync"
+	"time"
+)
+
+// coverSweepChunk is the number of covers of a sweep submitted to the cover
+// pool at once; the next ones wait for these to finish
+const coverSweepChunk = 20
+
+// CoverSweepStatus is the progress of FetchMissingCovers
+type CoverSweepStatus struct {
+	Active bool `json:"active"` // The sweep is running
+	Total  int  `json:"total"`  // Tabs without a cover when the sweep started
+	Done   int  `json:"done"`   // Tabs whose cover was searched
+	Found  int  `json:"found"`  // Covers downloaded
+}
+
+// FetchMissingCovers searches the covers of every tab having an artist but
+// no cover, through the cover pool, ignoring the daily budget of the cover
+// bootstrap. Emits "cover-sweep-progress" with the CoverSweepStatus as
+// covers finish. The tabs left when the app closes or goes offline are
+// searched when the sweep is resumed, see ResumeCoverSweep.
+func (s *SyncService) FetchMissingCovers() (CoverSweepStatus, error) {
+	if s.coverPool.IsPaused() {
+		return s.CoverSweepStatus(), metadata.ErrOffline
+	}
+	if !s.coverSweeping.CompareAndSwap(false, true) {
+		return s.CoverSweepStatus(), nil
+	}
+	if err := s.store.StartCoverSweep(); err != nil {
+		s.coverSweeping.Store(false)
+		return CoverSweepStatus{}, err
+	}
+	status := s.CoverSweepStatus()
+	if status.Done == status.Total {
+		s.coverSweeping.Store(false)
+		status.Active = false
+		return status, nil
+	}
+	s.logger.Info("Cover sweep: %d of %d cover(s) left to search", status.Total-status.Done, status.Total)
+	go s.runCoverSweep()
+	return status, nil
+}
+
+// ResumeCoverSweep continues a cover sweep interrupted by closing the app or
+// by offline mode, if any
+func (s *SyncService) ResumeCoverSweep() {
+	if s.coverPool.IsPaused() {
+		return
+	}
+	status := s.CoverSweepStatus()
+	if status.Done < status.Total && s.coverSweeping.CompareAndSwap(false, true) {
+		s.logger.Info("Cover sweep resumed: %d of %d cover(s) left", status.Total-status.Done, status.Total)
+		go s.runCoverSweep()
+	}
+}
+
+// CoverSweepStatus returns the progress of the last cover sweep
+func (s *SyncService) CoverSweepStatus() CoverSweepStatus {
+	status := CoverSweepStatus{Active: s.coverSweeping.Load()}
+	var err error
+	if status.Total, status.Done, status.Found, err = s.store.CoverSweepCounts(); err != nil {
+		s.logger.Info("Cover sweep: failed to read the progress: %v", err)
+	}
+	return status
+}
+
+// runCoverSweep searches the covers of the sweep chunk by chunk until none
+// is left or the cover pool is paused
+func (s *SyncService) runCoverSweep() {
+	defer func() {
+		s.coverSweeping.Store(false)
+		status := s.CoverSweepStatus()
+		s.logger.Info("Cover sweep stopped: %d of %d searched, %d found", status.Done, status.Total, status.Found)
+		s.emitter.Emit("cover-sweep-progress", status)
+	}()
+
+	for !s.coverPool.IsPaused() {
+		ids, err := s.store.NextCoverSweep(coverSweepChunk)
+		if err != nil {
+			s.logger.Info("Cover sweep: failed to read the tabs left: %v", err)
+			return
+		}
+		if len(ids) == 0 {
+			return
+		}
+
+		var wg gosync.WaitGroup
+		for _, id := range ids {
+			tab, err := s.store.GetTab(id)
+			if err != nil {
+				s.logger.Info("Cover sweep: failed to get %s: %v", id, err)
+				return
+			}
+			if tab == nil || tab.CoverPath != "" || !canFetchCover(*tab) {
+				// Deleted, given a cover or its artist removed meanwhile
+				s.markCoverSwept(id, tab != nil && tab.CoverPath != "")
+				continue
+			}
+			wg.Add(1)
+			s.fetchCover(*tab, func(err error) {
+				defer wg.Done()
+				if errors.Is(err, metadata.ErrOffline) {
+					return // Searched again when the sweep resumes
+				}
+				s.markCoverSwept(id, err == nil)
+			})
+		}
+		wg.Wait()
+	}
+}
+
+// markCoverSwept records the search of a cover of the sweep and emits the
+// progress
+func (s *SyncService) markCoverSwept(tabID string, found bool) {
+	if err := s.store.MarkCoverSweepDone(tabID, time.Now().Unix(), found); err != nil {
+		s.logger.Info("Cover sweep: failed to update %s: %v", tabID, err)
+		return
+	}
+	s.emitter.Emit("cover-sweep-progress", s.CoverSweepStatus())
+}
@@ -0,0 +1,264 @@
+package sync
+
+import (
+	"errors"
+	"haya-tab/pkg/metadata"
+	"haya-tab/pkg/store"
+	"time"
+)
+
+// coverBootstrapMin is the number of tabs added by a full sync from which
+// their covers are left to the cover bootstrap, so a first sync of a large
+// library does not flood the network for hours
+const coverBootstrapMin = 200
+
+// coverBootstrapChunk is the most covers one RunCoverBootstrap call requests
+const coverBootstrapChunk = 5
+
+// coverRefreshChunk is the most stale covers one RefreshStaleCovers call
+// requests
+const coverRefreshChunk = 2
+
+// coverRetryChunk is the most failed covers one RetryCovers call requests
+// again
+const coverRetryChunk = 5
+
+// coverRetryAttempts is the number of failed downloads after which a cover
+// is only retried by RetryFailedCovers
+const coverRetryAttempts = 8
+
+// coverRetryDelay is the delay after the first failed download of a cover;
+// it doubles with each failure, up to coverRetryMaxDelay
+const (
+	coverRetryDelay    = 5 * time.Minute
+	coverRetryMaxDelay = 24 * time.Hour
+)
+
+// CoverBootstrapStatus is the progress of the cover bootstrap
+type CoverBootstrapStatus struct {
+	Active       bool `json:"active"`       // Tabs are waiting in the cover queue
+	Pending      int  `json:"pending"`      // Tabs waiting for a cover
+	FetchedToday int  `json:"fetchedToday"` // Covers requested since midnight
+	DailyBudget  int  `json:"dailyBudget"`  // 0 for no limit
+	Failed       int  `json:"failed"`       // Tabs whose download failed on a network error
+}
+
+// fetchCovers fetches the covers of tabs added by a full sync. Large imports
+// are queued for the cover bootstrap instead, as are the tabs of any sync
+// while the bootstrap has covers left, to keep their order.
+func (s *SyncService) fetchCovers(tabs []store.Tab) {
+	var wanted []store.Tab
+	for _, tab := range tabs {
+		if canFetchCover(tab) {
+			wanted = append(wanted, tab)
+		}
+	}
+	if len(wanted) == 0 {
+		return
+	}
+
+	if s.store.GetSettings().CoverDailyBudget > 0 {
+		pending, _, err := s.store.CoverQueueCounts(startOfDay(time.Now()))
+		if err == nil && (len(wanted) >= coverBootstrapMin || pending > 0) {
+			ids := make([]string, len(wanted))
+			for i, tab := range wanted {
+				ids[i] = tab.ID
+			}
+			if err := s.store.QueueCoverFetches(ids); err != nil {
+				s.logger.Info("Failed to queue covers, fetching them now: %v", err)
+			} else {
+				s.logger.Info("Queued %d cover(s) for the cover bootstrap", len(ids))
+				return
+			}
+		}
+	}
+
+	for _, tab := range wanted {
+		s.FetchCoverAsync(tab)
+	}
+}
+
+// RunCoverBootstrap requests up to coverBootstrapChunk queued covers,
+// within the daily budget. The app calls it periodically, which spreads the
+// covers of a large library over days. Emits "cover-bootstrap-progress"
+// with the CoverBootstrapStatus after requesting covers. Does nothing in
+// offline mode, while the cover pool is paused.
+func (s *SyncService) RunCoverBootstrap() {
+	if s.coverPool.IsPaused() || !s.coverBootstrapMu.TryLock() {
+		return
+	}
+	defer s.coverBootstrapMu.Unlock()
+
+	today := startOfDay(time.Now())
+	if err := s.store.PruneCoverQueue(today); err != nil {
+		s.logger.Info("Cover bootstrap: failed to prune the queue: %v", err)
+	}
+
+	limit := coverBootstrapChunk
+	if budget := s.store.GetSettings().CoverDailyBudget; budget > 0 {
+		_, fetched, err := s.store.CoverQueueCounts(today)
+		if err != nil {
+			s.logger.Info("Cover bootstrap: failed to read the queue: %v", err)
+			return
+		}
+		limit = min(limit, budget-fetched)
+	}
+	if limit <= 0 {
+		return
+	}
+
+	ids, err := s.store.NextCoverFetches(limit)
+	if err != nil {
+		s.logger.Info("Cover bootstrap: failed to read the queue: %v", err)
+		return
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	for _, id := range ids {
+		if err := s.store.MarkCoverFetched(id, time.Now().Unix()); err != nil {
+			s.logger.Info("Cover bootstrap: failed to update the queue: %v", err)
+			return
+		}
+		if tab, err := s.store.GetTab(id); err == nil && tab != nil {
+			s.FetchCoverAsync(*tab)
+		}
+	}
+
+	status := s.CoverBootstrapStatus()
+	if !status.Active {
+		s.logger.Info("Cover bootstrap completed")
+	}
+	s.emitter.Emit("cover-bootstrap-progress", status)
+}
+
+// RefreshStaleCovers re-fetches up to coverRefreshChunk covers older than
+// months whose tab's artist, album or title changed since the download, so
+// corrected metadata eventually gets the right artwork. It runs at low
+// priority: only while no other cover is being fetched and the cover
+// bootstrap has nothing left.
+func (s *SyncService) RefreshStaleCovers(months int) {
+	if s.coverPool.QueueSize() > 0 || !s.coverBootstrapMu.TryLock() {
+		return
+	}
+	defer s.coverBootstrapMu.Unlock()
+
+	pending, _, err := s.store.CoverQueueCounts(startOfDay(time.Now()))
+	if err != nil || pending > 0 {
+		return
+	}
+
+	ids, err := s.store.StaleCovers(time.Now().AddDate(0, -months, 0).Unix(), coverRefreshChunk)
+	if err != nil {
+		s.logger.Info("Cover refresh: failed to find stale covers: %v", err)
+		return
+	}
+	for _, id := range ids {
+		// A failed fetch keeps the old cover and is retried after months
+		if err := s.store.MarkCoverChecked(id, time.Now().Unix()); err != nil {
+			s.logger.Info("Cover refresh: failed to update %s: %v", id, err)
+			return
+		}
+		if tab, err := s.store.GetTab(id); err == nil && tab != nil && canFetchCover(*tab) {
+			s.logger.Info("Refreshing the cover of %s", tab.Title)
+			s.FetchCoverAsync(*tab)
+		}
+	}
+}
+
+// CoverBootstrapStatus returns the progress of the cover bootstrap
+func (s *SyncService) CoverBootstrapStatus() CoverBootstrapStatus {
+	status := CoverBootstrapStatus{DailyBudget: s.store.GetSettings().CoverDailyBudget}
+	pending, fetched, err := s.store.CoverQueueCounts(startOfDay(time.Now()))
+	if err != nil {
+		s.logger.Info("Cover bootstrap: failed to read the queue: %v", err)
+		return status
+	}
+	status.Active = pending > 0
+	status.Pending = pending
+	status.FetchedToday = fetched
+	if status.Failed, err = s.store.CountCoverFailures(); err != nil {
+		s.logger.Info("Failed to count failed covers: %v", err)
+	}
+	return status
+}
+
+// recordCoverFailure schedules another download of the cover of a tab
+// after a network error. Covers that were not found are not retried.
+func (s *SyncService) recordCoverFailure(tab store.Tab, err error) {
+	if errors.Is(err, metadata.ErrNoCover) {
+		return
+	}
+	if errors.Is(err, metadata.ErrOffline) {
+		// Offline mode was turned on during the download: the paused pool
+		// holds it until it is turned off
+		s.FetchCoverAsync(tab)
+		return
+	}
+	attempts, recErr := s.store.RecordCoverFailure(tab.ID, err.Error(), time.Now().Unix(), coverRetryBackoff)
+	if recErr != nil {
+		s.logger.Info("Failed to record the cover failure of %s: %v", tab.Title, recErr)
+		return
+	}
+	if attempts >= coverRetryAttempts {
+		s.logger.Info("Gave up the cover of %s after %d attempts", tab.Title, attempts)
+	}
+}
+
+// coverRetryBackoff returns the delay before the next download of a cover
+// that failed attempts times
+func coverRetryBackoff(attempts int) time.Duration {
+	delay := coverRetryDelay
+	for i := 1; i < attempts && delay < coverRetryMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, coverRetryMaxDelay)
+}
+
+// RetryCovers downloads again up to coverRetryChunk covers that failed on a
+// network error and are due. The app calls it periodically; it does
+// nothing in offline mode.
+func (s *SyncService) RetryCovers() {
+	if s.coverPool.IsPaused() {
+		return
+	}
+	ids, err := s.store.DueCoverRetries(time.Now().Unix(), coverRetryAttempts, coverRetryChunk)
+	if err != nil {
+		s.logger.Info("Cover retry: failed to read the failed covers: %v", err)
+		return
+	}
+	for _, id := range ids {
+		// Not due again while the download is queued; a failure sets the
+		// next attempt
+		if err := s.store.DeferCoverRetry(id, time.Now().Add(coverRetryDelay).Unix()); err != nil {
+			s.logger.Info("Cover retry: failed to update %s: %v", id, err)
+			return
+		}
+		if tab, err := s.store.GetTab(id); err == nil && tab != nil && canFetchCover(*tab) {
+			s.FetchCoverAsync(*tab)
+		} else {
+			// Nothing to search with anymore
+			s.store.ClearCoverFailure(id)
+		}
+	}
+}
+
+// RetryFailedCovers makes all covers that failed on a network error due
+// again, including those given up, and starts downloading them. Returns
+// their number.
+func (s *SyncService) RetryFailedCovers() (int, error) {
+	n, err := s.store.ResetCoverFailures()
+	if err != nil {
+		return 0, err
+	}
+	s.logger.Info("Retrying %d failed cover(s)", n)
+	s.RetryCovers()
+	return n, nil
+}
+
+// startOfDay returns the Unix time of the last local midnight before t
+func startOfDay(t time.Time) int64 {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location()).Unix()
+}
@@ -0,0 +1,83 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"haya-tab/pkg/enrich"
+	"haya-tab/pkg/jobpool"
+	"haya-tab/pkg/metadata"
+	"time"
+)
+
+// enrichChunk is the most tabs one EnrichTabs call looks up
+const enrichChunk = 20
+
+// EnrichTabs looks up, in the background job pool, up to enrichChunk tabs
+// lacking an album, genre or year with p and fills the empty fields. The
+// app calls it periodically, so tabs are enriched some time after their
+// import. Tabs the provider does not know are not looked up again; on
+// other errors the pass stops and the tab is tried on the next call.
+// Emits "tabs-enriched" with the number of tabs changed, if any.
+func (s *SyncService) EnrichTabs(p enrich.Provider) {
+	if time.Now().Before(s.enrichPausedUntil()) || !s.enriching.CompareAndSwap(false, true) {
+		return
+	}
+
+	tabs, err := s.store.GetTabsToEnrich(enrichChunk)
+	if err != nil || len(tabs) == 0 {
+		if err != nil {
+			s.logger.Info("Enrichment: failed to list tabs: %v", err)
+		}
+		s.enriching.Store(false)
+		return
+	}
+
+	var changed int
+	submitted := s.jobPool.Submit(jobpool.Job{
+		Name: "enrich",
+		Run: func(ctx context.Context) error {
+			for _, tab := range tabs {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				info, err := p.Lookup(tab.Artist, tab.Title)
+				if err != nil && !errors.Is(err, enrich.ErrNotFound) {
+					var limited *enrich.RateLimitError
+					if errors.As(err, &limited) {
+						s.pauseEnrichment(limited.RetryAfter)
+					}
+					return err
+				}
+				filled, err := s.store.SetTabEnrichment(tab.ID, info.Album, info.Genre, info.Year)
+				if err != nil {
+					return err
+				}
+				if filled {
+					changed++
+				}
+			}
+			return nil
+		},
+		OnComplete: func(err error) {
+			defer s.enriching.Store(false)
+			if err != nil && !errors.Is(err, metadata.ErrOffline) {
+				s.logger.Info("Enrichment with %s stopped: %v", p.Name(), err)
+			}
+			if changed > 0 {
+				s.logger.Info("Enriched %d tab(s) with %s", changed, p.Name())
+				s.emitter.Emit("tabs-enriched", changed)
+			}
+		},
+	})
+	if !submitted {
+		s.enriching.Store(false)
+	}
+}
+
+func (s *SyncService) pauseEnrichment(d time.Duration) {
+	s.enrichPause.Store(time.Now().Add(d).Unix())
+}
+
+func (s *SyncService) enrichPausedUntil() time.Time {
+	return time.Unix(s.enrichPause.Load(), 0)
+}
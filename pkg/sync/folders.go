@@ -0,0 +1,102 @@
+package sync
+
+import (
+	"fmt"
+	"haya-tab/pkg/store"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// folderCategories maps the folders of new files to categories during a
+// sync, creating the missing ones. A folder reuses the category with its name
+// (ignoring case) under the same parent.
+type folderCategories struct {
+	paths    []store.SyncPath  // Sync paths with a category strategy
+	ids      map[string]string // Parent ID and lower-case name to category ID, loaded on first use
+	existing map[string]bool   // IDs of the categories, loaded with ids
+	nextID   int64             // Suffix of the next created category ID
+}
+
+// resetFolderCategories starts mapping folders with the strategies of
+// paths. Called with syncMu held at the start of a sync.
+func (s *SyncService) resetFolderCategories(paths []store.SyncPath) {
+	s.folders = nil
+	var mapped []store.SyncPath
+	for _, p := range paths {
+		if p.CategoryStrategy != "" {
+			mapped = append(mapped, p)
+		}
+	}
+	if len(mapped) > 0 {
+		s.folders = &folderCategories{paths: mapped, nextID: time.Now().UnixNano()}
+	}
+}
+
+// folderCategoryIDs returns the category of a new file according to the
+// strategy of its sync path, creating it and its parents if needed. Files
+// directly in a sync path, or in one without a strategy, get none.
+func (s *SyncService) folderCategoryIDs(path string) []string {
+	f := s.folders
+	if f == nil {
+		return nil
+	}
+	root := store.FindSyncPath(f.paths, path)
+	if root == nil {
+		return nil
+	}
+	rel, err := filepath.Rel(root.Path, filepath.Dir(path))
+	if err != nil || rel == "." {
+		return nil
+	}
+	folders := strings.Split(rel, string(filepath.Separator))
+	switch root.CategoryStrategy {
+	case store.FolderCategoriesNested:
+	case store.FolderCategoriesTop:
+		folders = folders[:1]
+	case store.FolderCategoriesParent:
+		folders = folders[len(folders)-1:]
+	default:
+		return nil
+	}
+
+	if f.ids == nil {
+		cats, err := s.store.GetCategories()
+		if err != nil {
+			s.logger.Info("Failed to load categories for folder mapping: %v", err)
+			return nil
+		}
+		f.ids = make(map[string]string, len(cats))
+		f.existing = make(map[string]bool, len(cats))
+		for _, cat := range cats {
+			f.ids[categoryKey(cat.ParentID, cat.Name)] = cat.ID
+			f.existing[cat.ID] = true
+		}
+	}
+
+	// Fall back to the root if the chosen category was deleted
+	parentID := root.CategoryRoot
+	if !f.existing[parentID] {
+		parentID = ""
+	}
+	for _, name := range folders {
+		key := categoryKey(parentID, name)
+		id, ok := f.ids[key]
+		if !ok {
+			id = fmt.Sprintf("cat_%d", f.nextID)
+			f.nextID++
+			if err := s.store.AddCategory(store.Category{ID: id, Name: name, ParentID: parentID}); err != nil {
+				s.logger.Info("Failed to create category %s for %s: %v", name, path, err)
+				return nil
+			}
+			f.ids[key] = id
+		}
+		parentID = id
+	}
+	return []string{parentID}
+}
+
+// categoryKey identifies a category by its parent and name, ignoring case
+func categoryKey(parentID, name string) string {
+	return parentID + "\x00" + strings.ToLower(name)
+}
@@ -0,0 +1,133 @@
+package sync
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"haya-tab/pkg/jobpool"
+	"io"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// hashBackfillDelay throttles the backfill so hashing a large library does
+// not saturate the disk while the app is in use
+const hashBackfillDelay = 100 * time.Millisecond
+
+// backfillGate lets one backfill of a kind run at a time. A backfill
+// requested while one is running is started again once it ends, so files
+// added in the meantime are not left for the next startup.
+type backfillGate struct {
+	running atomic.Bool
+	pending atomic.Bool
+}
+
+// start reports whether the caller may run the backfill. Otherwise the
+// request is remembered for when the running pass ends.
+func (g *backfillGate) start() bool {
+	g.pending.Store(true)
+	if !g.running.CompareAndSwap(false, true) {
+		return false
+	}
+	g.pending.Store(false)
+	return true
+}
+
+// finish ends the running pass and reports whether another was requested
+func (g *backfillGate) finish() bool {
+	g.running.Store(false)
+	return g.pending.Load()
+}
+
+// HashFile returns the hex encoded SHA-256 of a file
+func HashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// BackfillHashes computes the missing file hashes of the library in the
+// background job pool, emitting "hash-backfill-progress" after each file and
+// "hash-backfill-completed" at the end. Files that cannot be read are
+// retried on the next run. If a backfill is already running, another pass
+// runs once it ends.
+func (s *SyncService) BackfillHashes() {
+	if !s.hashBackfill.start() {
+		return
+	}
+
+	tabs, err := s.store.GetTabsMissingHash()
+	if err != nil {
+		s.logger.Info("Hash backfill: failed to list tabs: %v", err)
+		s.finishHashBackfill()
+		return
+	}
+	if len(tabs) == 0 {
+		s.finishHashBackfill()
+		return
+	}
+
+	total := len(tabs)
+	s.logger.Info("Hash backfill started for %d tabs", total)
+
+	var done, failed atomic.Int32
+	for _, tab := range tabs {
+		tab := tab
+		submitted := s.jobPool.Submit(jobpool.Job{
+			Name: "hash:" + tab.ID,
+			Run: func(ctx context.Context) error {
+				hash, err := HashFile(tab.FilePath)
+				if err != nil {
+					return err
+				}
+				if err := s.store.SetTabHash(tab.ID, tab.FilePath, hash); err != nil {
+					return err
+				}
+				select {
+				case <-time.After(hashBackfillDelay):
+				case <-ctx.Done():
+				}
+				return nil
+			},
+			OnComplete: func(err error) {
+				if err != nil {
+					failed.Add(1)
+				}
+				n := int(done.Add(1))
+				s.emitter.Emit("hash-backfill-progress", map[string]interface{}{
+					"done":  n,
+					"total": total,
+				})
+				if n == total {
+					s.logger.Info("Hash backfill completed: %d hashed, %d failed", total-int(failed.Load()), failed.Load())
+					s.emitter.Emit("hash-backfill-completed", map[string]interface{}{
+						"total":  total,
+						"failed": int(failed.Load()),
+					})
+					s.finishHashBackfill()
+				}
+			},
+		})
+		if !submitted {
+			// Pool is shutting down
+			return
+		}
+	}
+}
+
+// finishHashBackfill ends a hash backfill pass, starting another if new
+// files were queued while it ran
+func (s *SyncService) finishHashBackfill() {
+	if s.hashBackfill.finish() {
+		go s.BackfillHashes()
+	}
+}
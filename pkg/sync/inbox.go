@@ -0,0 +1,88 @@
+package sync
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ImportInbox imports the tab files in dir as managed tabs flagged for
+// review. Each imported file is moved to app storage, leaving the inbox
+// empty; files that fail to import stay in the inbox and are retried on
+// the next call. Subdirectories are not scanned.
+func (s *SyncService) ImportInbox(dir string) SyncResult {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	result := SyncResult{}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		s.logger.Error("Failed to read inbox %s: %v", dir, err)
+		return result
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !s.isSupportedExtension(strings.ToLower(filepath.Ext(entry.Name()))) {
+			continue
+		}
+		result.Total++
+		path := filepath.Join(dir, entry.Name())
+		if err := s.importInboxFile(path); err != nil {
+			s.logger.Info("Failed to import %s from the inbox: %v", entry.Name(), err)
+			result.Errors++
+			continue
+		}
+		result.Added++
+	}
+
+	if result.Total > 0 {
+		s.emitter.Emit("inbox-imported", map[string]interface{}{
+			"added":  result.Added,
+			"errors": result.Errors,
+		})
+		s.logger.Info("Inbox import: Added: %d, Errors: %d", result.Added, result.Errors)
+	}
+	if result.Added > 0 {
+		go s.BackfillHashes()
+		go s.BackfillContent()
+	}
+	return result
+}
+
+// importInboxFile moves a file from the inbox to storage and adds its tab
+func (s *SyncService) importInboxFile(path string) error {
+	tab := s.ProcessFile(path)
+	if conflict, _ := s.store.GetTabByTitle(tab.Title); conflict != nil {
+		tab.Title = s.generateUniqueTitle(tab.Title, nil)
+	}
+
+	locator, hash, err := s.storage.Import(path, tab.ID+strings.ToLower(filepath.Ext(path)))
+	if err != nil {
+		return fmt.Errorf("failed to copy to storage: %w", err)
+	}
+	// Keep a single copy, e.g. when the file is still locked by the writer
+	if err := os.Remove(path); err != nil {
+		s.storage.Remove(locator)
+		return err
+	}
+	tab.FilePath = locator
+	tab.FileHash = hash
+	tab.IsManaged = true
+	tab.NeedsReview = true
+	tab.AddedAt = time.Now().Unix()
+
+	if err := s.store.AddTab(tab); err != nil {
+		// Put the file back so it is retried
+		if err := s.storage.Export(locator, path); err != nil {
+			s.logger.Error("Failed to move %s back to the inbox: %v", locator, err)
+		} else {
+			s.storage.Remove(locator)
+		}
+		return err
+	}
+	s.saveTracks(tab)
+	s.FetchCoverAsync(tab)
+	return nil
+}
@@ -0,0 +1,277 @@
+package sync
+
+import (
+	"archive/zip"
+	"fmt"
+	"haya-tab/pkg/remotefs"
+	"haya-tab/pkg/store"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Resolutions of a pending conflict, see ResolveConflict
+const (
+	ResolveMerge  = "merge"  // Keep the existing tab, filling its missing details from the file
+	ResolveRename = "rename" // Add the file under another title
+	ResolveSkip   = "skip"   // Leave the file out of the library
+)
+
+// queueConflict queues a title conflict found with the "ask" strategy for
+// the user to resolve. Files queued or resolved before are skipped.
+func (s *SyncService) queueConflict(c Conflict, result *SyncResult) {
+	if known, err := s.store.IsConflictKnown(c.Path); err == nil && known {
+		result.Skipped++
+		return
+	}
+	err := s.store.AddPendingConflict(store.PendingConflict{
+		Path:         c.Path,
+		Title:        c.Title,
+		ExistingPath: c.ExistingPath,
+		FoundAt:      time.Now().Unix(),
+	})
+	if err != nil {
+		s.logger.Error("Failed to queue the conflict of %s: %v", c.Path, err)
+		result.fail(c.Path, err)
+		return
+	}
+	result.Pending++
+}
+
+// ResolveConflict resolves the pending conflict of a file with action:
+// ResolveMerge, ResolveRename to add it as title, or ResolveSkip. Merged and
+// skipped files are left alone by later syncs. Returns ErrSyncInProgress if
+// a sync is running.
+func (s *SyncService) ResolveConflict(path, action, title string) error {
+	if !s.syncMu.TryLock() {
+		return ErrSyncInProgress
+	}
+	defer s.syncMu.Unlock()
+
+	c, err := s.store.GetPendingConflict(path)
+	if err != nil {
+		return fmt.Errorf("failed to read the conflict: %w", err)
+	}
+	if c == nil {
+		return fmt.Errorf("no pending conflict for %s", path)
+	}
+
+	switch action {
+	case ResolveSkip:
+		return s.store.ResolvePendingConflict(path, ResolveSkip)
+	case ResolveMerge:
+		return s.mergeConflict(c)
+	case ResolveRename:
+		return s.renameConflict(c, strings.TrimSpace(title))
+	}
+	return fmt.Errorf("unknown conflict resolution %q", action)
+}
+
+// mergeConflict fills the empty details of the tab holding the title of a
+// conflict from the conflicting file, which is not added
+func (s *SyncService) mergeConflict(c *store.PendingConflict) error {
+	owner, err := s.store.GetTabByTitle(c.Title)
+	if err != nil || owner == nil {
+		return fmt.Errorf("no tab is titled %q anymore", c.Title)
+	}
+	tab, err := s.store.GetTab(owner.ID)
+	if err != nil || tab == nil {
+		return fmt.Errorf("failed to read %s: %v", c.Title, err)
+	}
+
+	file, cleanup, err := s.conflictFile(c.Path)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	found := s.ProcessFile(file.path)
+
+	changed := false
+	fill := func(field *string, value string) {
+		if *field == "" && value != "" {
+			*field = value
+			changed = true
+		}
+	}
+	fill(&tab.Artist, found.Artist)
+	fill(&tab.Album, found.Album)
+	fill(&tab.Key, found.Key)
+	if tab.Capo == 0 && found.Capo != 0 {
+		tab.Capo = found.Capo
+		changed = true
+	}
+	if changed {
+		if err := s.store.UpdateTab(*tab); err != nil {
+			return fmt.Errorf("failed to update %s: %w", tab.Title, err)
+		}
+		s.emitter.Emit("tab-updated", *tab)
+	}
+	return s.store.ResolvePendingConflict(c.Path, ResolveMerge)
+}
+
+// renameConflict adds the file of a conflict as a tab titled title, linked
+// or copied like the sync would have
+func (s *SyncService) renameConflict(c *store.PendingConflict, title string) error {
+	if title == "" {
+		return fmt.Errorf("empty title")
+	}
+	if s.titleTaken(title, nil) {
+		return fmt.Errorf("a tab is already titled %q", title)
+	}
+
+	file, cleanup, err := s.conflictFile(c.Path)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	syncPaths, err := s.store.GetSyncPaths()
+	if err != nil {
+		return fmt.Errorf("failed to read sync paths: %w", err)
+	}
+	s.resetFolderCategories(syncPaths)
+	defer func() { s.folders = nil }()
+
+	tab := s.ProcessFile(file.path)
+	tab.Title = title
+	result := SyncResult{}
+	root := store.FindSyncPath(syncPaths, c.Path)
+	switch {
+	case file.converted:
+		tab.SourcePath = c.Path
+		id, err := s.addCopiedTab(tab, file.path, c.Path, c.Path, "skip", &result, nil)
+		if err != nil {
+			return err
+		}
+		s.recordConverted(c.Path, id)
+	case file.archive != "":
+		id, err := s.addCopiedTab(tab, file.path, c.Path, archiveCategoryPath(file.archive, file.entry), "skip", &result, nil)
+		if err != nil {
+			return err
+		}
+		if err := s.store.AddArchiveEntry(file.archive, file.entry, id, time.Now().Unix()); err != nil {
+			s.logger.Info("Failed to record %s as imported: %v", c.Path, err)
+		}
+	case file.share != nil:
+		id, err := s.addCopiedTab(tab, file.path, c.Path, remoteFilePath(file.share.Path, file.sharePath), "skip", &result, nil)
+		if err != nil {
+			return err
+		}
+		if id != "" {
+			if err := s.store.AddCopiedFile(c.Path, id, time.Now().Unix()); err != nil {
+				s.logger.Info("Failed to record %s as copied: %v", c.Path, err)
+			}
+		}
+	case root != nil && root.Mode == store.SyncModeCopy:
+		s.copySyncFile(tab, c.Path, "skip", &result, nil)
+	default:
+		s.addTab(tab, "skip", &result, nil)
+	}
+	if result.Added == 0 {
+		return fmt.Errorf("failed to add %s", c.Path)
+	}
+	s.emitter.Emit("tab-updated", tab)
+	return s.store.RemovePendingConflict(c.Path)
+}
+
+// conflictSource is the file of a pending conflict
+type conflictSource struct {
+	path      string // File to read
+	archive   string // For a zip archive entry: the archive, and the entry name
+	entry     string
+	converted bool            // path is the conversion of the file of the conflict
+	share     *store.SyncPath // For a file of a network share: its share, and the path of the file in it
+	sharePath string
+}
+
+// conflictFile locates the file of a pending conflict. A file of a network
+// share is fetched, an entry of a zip archive is extracted, and a file of a
+// format the converter converts is converted, to a temporary file removed
+// by the returned cleanup function.
+func (s *SyncService) conflictFile(path string) (conflictSource, func(), error) {
+	if remotefs.IsRemote(path) {
+		return s.shareConflictFile(path)
+	}
+	archivePath, entry, ok := splitArchivePath(path)
+	if !ok {
+		if _, err := os.Stat(path); err != nil {
+			return conflictSource{}, nil, fmt.Errorf("file not found: %s", path)
+		}
+		conv := s.converter()
+		if !conv.converts(strings.ToLower(filepath.Ext(path))) {
+			return conflictSource{path: path}, func() {}, nil
+		}
+		tmpDir, err := os.MkdirTemp("", "haya-tab-convert-*")
+		if err != nil {
+			return conflictSource{}, nil, err
+		}
+		cleanup := func() { os.RemoveAll(tmpDir) }
+		converted, err := conv.convert(path, tmpDir)
+		if err != nil {
+			cleanup()
+			return conflictSource{}, nil, fmt.Errorf("failed to convert %s: %w", path, err)
+		}
+		return conflictSource{path: converted, converted: true}, cleanup, nil
+	}
+
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return conflictSource{}, nil, fmt.Errorf("failed to open archive %s: %w", archivePath, err)
+	}
+	defer zr.Close()
+	for _, f := range zr.File {
+		if f.Name != entry {
+			continue
+		}
+		tmpDir, err := os.MkdirTemp("", "haya-tab-zip-*")
+		if err != nil {
+			return conflictSource{}, nil, err
+		}
+		cleanup := func() { os.RemoveAll(tmpDir) }
+		dst := filepath.Join(tmpDir, filepath.Base(filepath.FromSlash(f.Name)))
+		if err := extractEntry(f, dst); err != nil {
+			cleanup()
+			return conflictSource{}, nil, fmt.Errorf("failed to extract %s: %w", path, err)
+		}
+		return conflictSource{path: dst, archive: archivePath, entry: entry}, cleanup, nil
+	}
+	return conflictSource{}, nil, fmt.Errorf("%s is no longer in %s", entry, archivePath)
+}
+
+// shareConflictFile fetches the file of a network share of a pending
+// conflict, known by its remotefs.FileID
+func (s *SyncService) shareConflictFile(fileID string) (conflictSource, func(), error) {
+	syncPaths, err := s.store.GetSyncPaths()
+	if err != nil {
+		return conflictSource{}, nil, fmt.Errorf("failed to read sync paths: %w", err)
+	}
+	tmpDir, err := os.MkdirTemp("", "haya-tab-share-*")
+	if err != nil {
+		return conflictSource{}, nil, err
+	}
+	cleanup := func() { os.RemoveAll(tmpDir) }
+	share, rel, local, err := fetchShareFile(syncPaths, fileID, tmpDir)
+	if err != nil {
+		cleanup()
+		return conflictSource{}, nil, err
+	}
+	return conflictSource{path: local, share: share, sharePath: rel}, cleanup, nil
+}
+
+// splitArchivePath splits the path of a zip archive entry, as recorded by a
+// sync, into the archive and the entry name. ok is false for other files.
+func splitArchivePath(path string) (archivePath, entry string, ok bool) {
+	for dir := filepath.Dir(path); dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+		if !strings.EqualFold(filepath.Ext(dir), ".zip") {
+			continue
+		}
+		if info, err := os.Stat(dir); err == nil && info.Mode().IsRegular() {
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return "", "", false
+			}
+			return dir, filepath.ToSlash(rel), true
+		}
+	}
+	return "", "", false
+}
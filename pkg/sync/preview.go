@@ -0,0 +1,173 @@
+package sync
+
+import (
+	"archive/zip"
+	"fmt"
+	"haya-tab/pkg/metadata"
+	"haya-tab/pkg/store"
+	"path/filepath"
+	"strings"
+)
+
+// Actions of a PreviewFile
+const (
+	PreviewAdd     = "add"     // Added as a new tab
+	PreviewRetitle = "retitle" // Added as NewTitle, its title being taken
+	PreviewSkip    = "skip"    // Skipped, its title being taken
+	PreviewRestore = "restore" // Found again for a tab marked missing
+	PreviewAsk     = "ask"     // Queued for the user, its title being taken
+	PreviewMove    = "move"    // New place of the file of a tab, ExistingPath
+)
+
+// PreviewFile is a file a sync would change the library for
+type PreviewFile struct {
+	Path         string `json:"path"` // Inside a zip archive: the archive path joined with the entry name
+	Title        string `json:"title"`
+	Artist       string `json:"artist"`
+	Action       string `json:"action"`                 // PreviewAdd, PreviewRetitle, PreviewSkip, PreviewRestore, PreviewAsk or PreviewMove
+	Copy         bool   `json:"copy"`                   // Copied to app storage instead of linked
+	Convert      bool   `json:"convert,omitempty"`      // Converted by the converter command first, then copied
+	NewTitle     string `json:"newTitle,omitempty"`     // For PreviewRetitle
+	ExistingPath string `json:"existingPath,omitempty"` // File of the tab holding the title, for conflicts; former file, for PreviewMove
+}
+
+// SyncPreview lists what a sync would do, see PreviewSync
+type SyncPreview struct {
+	Files     []PreviewFile `json:"files"`
+	Added     int           `json:"added"`     // Files added, retitled or not
+	Conflicts int           `json:"conflicts"` // Files retitled, skipped or queued
+	Skipped   int           `json:"skipped"`
+	Pending   int           `json:"pending"` // Files queued for the user
+	Restored  int           `json:"restored"`
+	Moved     int           `json:"moved"`     // Files of tabs moved on disk
+	Unchanged int           `json:"unchanged"` // Files already in the library, not listed
+}
+
+// PreviewSync scans the sync paths like TriggerSync without changing
+// anything, and returns what the sync would do. The preview is also emitted
+// as "sync-preview". Titles of files inside zip archives come from their
+// file name, as the entries are not extracted. Returns ErrSyncInProgress if
+// a sync is running.
+func (s *SyncService) PreviewSync() (SyncPreview, error) {
+	if !s.syncMu.TryLock() {
+		return SyncPreview{}, ErrSyncInProgress
+	}
+	defer s.syncMu.Unlock()
+
+	preview := SyncPreview{Files: []PreviewFile{}}
+	settings := s.store.GetSettings()
+	syncPaths, err := s.store.GetSyncPaths()
+	if err != nil {
+		return preview, fmt.Errorf("failed to read sync paths: %w", err)
+	}
+
+	// Titles of the files the sync would add, for conflicts between them
+	batch := &tabBatch{titles: map[string]string{}}
+	for file := range s.scanPaths(syncPaths, settings.SyncZipArchives, s.converter()) {
+		switch {
+		case file.archive:
+			s.previewArchive(file.path, file.root, settings.SyncStrategy, &preview, batch)
+		case file.copied:
+			preview.Unchanged++
+		case file.convert:
+			// Titles come from the file name, as the file is not converted
+			meta := metadata.ParseFilename(file.path)
+			s.previewTab(file.path, meta.Title, meta.Artist, true, settings.SyncStrategy, &preview, batch)
+			preview.Files[len(preview.Files)-1].Convert = true
+		case file.existing != nil:
+			if !file.existing.IsMissing {
+				preview.Unchanged++
+				continue
+			}
+			preview.Restored++
+			preview.Files = append(preview.Files, PreviewFile{
+				Path:   file.path,
+				Title:  file.existing.Title,
+				Artist: file.existing.Artist,
+				Action: PreviewRestore,
+			})
+		default:
+			if tab := s.movedTab(file.tab); tab != nil {
+				preview.Moved++
+				preview.Files = append(preview.Files, PreviewFile{
+					Path:         file.path,
+					Title:        tab.Title,
+					Action:       PreviewMove,
+					ExistingPath: tab.FilePath,
+				})
+				continue
+			}
+			copied := file.root.Mode == store.SyncModeCopy
+			s.previewTab(file.path, file.tab.Title, file.tab.Artist, copied, settings.SyncStrategy, &preview, batch)
+		}
+	}
+
+	s.emitter.Emit("sync-preview", preview)
+	return preview, nil
+}
+
+// previewArchive previews the entries of a zip archive not imported yet
+func (s *SyncService) previewArchive(archivePath string, root *store.SyncPath, strategy string, preview *SyncPreview, batch *tabBatch) {
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		s.logger.Info("Failed to open archive %s: %v", archivePath, err)
+		return
+	}
+	defer zr.Close()
+
+	imported, err := s.store.GetArchiveEntries(archivePath)
+	if err != nil {
+		s.logger.Info("Failed to read the imported entries of %s: %v", archivePath, err)
+		return
+	}
+	for _, f := range zr.File {
+		name := filepath.Base(filepath.FromSlash(f.Name))
+		if f.FileInfo().IsDir() || strings.HasPrefix(f.Name, "__MACOSX/") || strings.HasPrefix(name, ".") ||
+			!s.isSupportedExtension(strings.ToLower(filepath.Ext(name))) {
+			continue
+		}
+		entryPath := filepath.Join(archivePath, filepath.FromSlash(f.Name))
+		if root != nil && (!root.Includes(name) || root.Excludes(entryPath)) {
+			continue
+		}
+		if imported[f.Name] {
+			preview.Unchanged++
+			continue
+		}
+		meta := metadata.ParseFilename(name)
+		s.previewTab(entryPath, meta.Title, meta.Artist, true, strategy, preview, batch)
+	}
+}
+
+// previewTab adds a new file to preview, resolving its title like addTab
+func (s *SyncService) previewTab(path, title, artist string, copied bool, strategy string, preview *SyncPreview, batch *tabBatch) {
+	file := PreviewFile{Path: path, Title: title, Artist: artist, Action: PreviewAdd, Copy: copied}
+	if existingPath, taken := s.titleOwner(title, batch); taken {
+		file.ExistingPath = existingPath
+		preview.Conflicts++
+		switch strategy {
+		case "skip":
+			file.Action = PreviewSkip
+			preview.Skipped++
+			preview.Files = append(preview.Files, file)
+			return
+		case "overwrite":
+			file.Action = PreviewRetitle
+			file.NewTitle = s.generateUniqueTitle(title, batch)
+			title = file.NewTitle
+		case "ask":
+			if known, err := s.store.IsConflictKnown(path); err == nil && known {
+				file.Action = PreviewSkip
+				preview.Skipped++
+			} else {
+				file.Action = PreviewAsk
+				preview.Pending++
+			}
+			preview.Files = append(preview.Files, file)
+			return
+		}
+	}
+	batch.titles[title] = path
+	preview.Added++
+	preview.Files = append(preview.Files, file)
+}
@@ -0,0 +1,145 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"haya-tab/pkg/metadata"
+	"haya-tab/pkg/remotefs"
+	"haya-tab/pkg/store"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// remoteCacheLimit bounds the local copies of the files of network shares.
+// Files are copied into app storage once imported, so the cache only spares
+// fetching them again after a failed import.
+const remoteCacheLimit = 256 << 20
+
+// remoteCache returns the cache of the files of network shares
+func (s *SyncService) remoteCache() *remotefs.Cache {
+	return remotefs.NewCache(filepath.Join(s.appDir, "remote-cache"))
+}
+
+// syncRemote mirrors the new files of a sync path on a network share into
+// app storage, like a local sync path in copy mode: each file is fetched to
+// the remote cache and copied once as a managed tab. Zip archives are not
+// imported from shares. Shares are skipped in offline mode.
+func (s *SyncService) syncRemote(root *store.SyncPath, strategy string, result *SyncResult, batch *tabBatch) {
+	shareURL := remotefs.Redact(root.Path)
+	if metadata.Offline() {
+		s.logger.Info("Offline mode is on, skipping share: %s", shareURL)
+		result.SkippedShares = append(result.SkippedShares, shareURL)
+		return
+	}
+	fsys, err := remotefs.Open(root.Path)
+	if err != nil {
+		s.logger.Error("Failed to open %s: %v", shareURL, err)
+		result.fail(shareURL, err)
+		return
+	}
+	s.logger.Info("Scanning share: %s", shareURL)
+
+	ctx := context.Background()
+	cache := s.remoteCache()
+	err = fsys.Walk(ctx, func(f remotefs.File) error {
+		// The file is recorded by its ID; its path under root holds the
+		// password of the share and is only matched against the options of root
+		fileID := remotefs.FileID(root.Path, f.Path)
+		filePath := remoteFilePath(root.Path, f.Path)
+		name := path.Base(f.Path)
+		if strings.HasPrefix(name, ".") || (!root.Recursive && strings.Contains(f.Path, "/")) ||
+			!s.isSupportedExtension(strings.ToLower(path.Ext(name))) ||
+			!root.Includes(name) || root.Excludes(filePath) {
+			return nil
+		}
+		result.Total++
+		if copied, err := s.store.IsFileCopied(fileID); err != nil || copied {
+			return nil
+		}
+		s.emitter.Emit("sync-progress", map[string]interface{}{
+			"message":  fmt.Sprintf("Downloading: %s", name),
+			"count":    result.Total,
+			"filePath": fileID,
+		})
+
+		local, err := cache.Get(ctx, fsys, root.Path, f)
+		if err != nil {
+			s.logger.Info("Failed to fetch %s: %v", fileID, err)
+			result.fail(fileID, err)
+			return nil
+		}
+		id, err := s.addCopiedTab(s.ProcessFile(local), local, fileID, filePath, strategy, result, batch)
+		if err != nil {
+			s.logger.Info("Failed to copy %s to storage: %v", fileID, err)
+			result.fail(fileID, err)
+			return nil
+		}
+		if id == "" {
+			return nil // Skipped on a title conflict, reported again by the next sync
+		}
+		if err := s.store.AddCopiedFile(fileID, id, time.Now().Unix()); err != nil {
+			s.logger.Info("Failed to record %s as copied: %v", fileID, err)
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.Error("Failed to scan %s: %v", shareURL, err)
+		result.fail(shareURL, err)
+	}
+	cache.Evict(remoteCacheLimit)
+}
+
+// remoteFilePath returns the URL of a file of a share: the URL of the share,
+// with its credentials, joined with the path of the file in it. It is not
+// stored, see remotefs.FileID.
+func remoteFilePath(shareURL, filePath string) string {
+	return strings.TrimSuffix(shareURL, "/") + "/" + filePath
+}
+
+// findShare returns the sync path of the share holding the file of ID
+// fileID (see remotefs.FileID) and the path of the file in the share, nil
+// if no share holds it
+func findShare(paths []store.SyncPath, fileID string) (*store.SyncPath, string) {
+	var found *store.SyncPath
+	var rel string
+	for i, p := range paths {
+		if !remotefs.IsRemote(p.Path) {
+			continue
+		}
+		prefix := strings.TrimSuffix(remotefs.StripUser(p.Path), "/") + "/"
+		if r, ok := strings.CutPrefix(fileID, prefix); ok && r != "" && (found == nil || len(p.Path) > len(found.Path)) {
+			found, rel = &paths[i], r
+		}
+	}
+	return found, rel
+}
+
+// fetchShareFile fetches the file of ID fileID of a share in paths to dir,
+// under its own name. It returns the sync path of the share, the path of
+// the file in the share and its local copy.
+func fetchShareFile(paths []store.SyncPath, fileID, dir string) (*store.SyncPath, string, string, error) {
+	if metadata.Offline() {
+		return nil, "", "", fmt.Errorf("cannot fetch %s: %w", fileID, metadata.ErrOffline)
+	}
+	root, rel := findShare(paths, fileID)
+	if root == nil {
+		return nil, "", "", fmt.Errorf("%s is not in a synced network share anymore", fileID)
+	}
+	fsys, err := remotefs.Open(root.Path)
+	if err != nil {
+		return nil, "", "", err
+	}
+	dst := filepath.Join(dir, path.Base(rel))
+	f, err := os.Create(dst)
+	if err != nil {
+		return nil, "", "", err
+	}
+	if err := fsys.Fetch(context.Background(), rel, f); err != nil {
+		f.Close()
+		return nil, "", "", err
+	}
+	return root, rel, dst, f.Close()
+}
@@ -0,0 +1,114 @@
+package sync_test
+
+import (
+	"fmt"
+	"haya-tab/pkg/store"
+	syncpkg "haya-tab/pkg/sync"
+	"haya-tab/pkg/testutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// shareUser and sharePassword are the credentials of newShare
+const (
+	shareUser     = "tabs"
+	sharePassword = "s3cret"
+)
+
+// newShare serves files, keyed by name, as a WebDAV folder at /dav/tabs/
+// to shareUser, and returns its URL with the credentials
+func newShare(t *testing.T, files map[string][]byte) string {
+	t.Helper()
+	const base = "/dav/tabs/"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, ok := r.BasicAuth(); !ok || user != shareUser || password != sharePassword {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		name, ok := strings.CutPrefix(r.URL.Path, base)
+		switch {
+		case r.Method == "PROPFIND" && strings.TrimSuffix(r.URL.Path, "/")+"/" == base:
+			var b strings.Builder
+			b.WriteString(`<?xml version="1.0" encoding="utf-8"?><multistatus xmlns="DAV:">`)
+			fmt.Fprintf(&b, `<response><href>%s</href><propstat><prop><resourcetype><collection/></resourcetype></prop><status>HTTP/1.1 200 OK</status></propstat></response>`, base)
+			for name, data := range files {
+				fmt.Fprintf(&b, `<response><href>%s</href><propstat><prop><resourcetype/><getcontentlength>%d</getcontentlength><getetag>"%x"</getetag></prop><status>HTTP/1.1 200 OK</status></propstat></response>`,
+					base+url.PathEscape(name), len(data), len(data))
+			}
+			b.WriteString(`</multistatus>`)
+			w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+			w.WriteHeader(http.StatusMultiStatus)
+			w.Write([]byte(b.String()))
+		case r.Method == http.MethodGet && ok && files[name] != nil:
+			w.Write(files[name])
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	u, err := url.Parse(server.URL + path.Clean(base))
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.User = url.UserPassword(shareUser, sharePassword)
+	return u.String()
+}
+
+func TestShareConflict(t *testing.T) {
+	s := testutil.NewStore(t)
+	svc := testutil.NewSyncService(t, s, testutil.NoCovers())
+	paths := addSyncPath(t, svc, "tabs", 3)
+	sync(t, svc)
+
+	// The share holds another version of a local tab
+	data, err := os.ReadFile(paths[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := filepath.Base(paths[0])
+	shareURL := newShare(t, map[string][]byte{name: append(data, "\n[Chorus]\nC G\n"...)})
+	if err := s.AddSyncPath(store.SyncPath{Path: shareURL, Recursive: true, Mode: store.SyncModeCopy}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetSetting("syncStrategy", "ask"); err != nil {
+		t.Fatal(err)
+	}
+
+	if counts := sync(t, svc); counts["pending"] != 1 || counts["errors"] != 0 {
+		t.Fatalf("sync-completed = %v, want 1 pending", counts)
+	}
+	pending, err := s.GetPendingConflicts()
+	if err != nil || len(pending) != 1 {
+		t.Fatalf("pending conflicts = %v (%v), want 1", pending, err)
+	}
+	fileID := pending[0].Path
+	if strings.Contains(fileID, sharePassword) || strings.Contains(fileID, shareUser+"@") || path.Base(fileID) != name {
+		t.Errorf("the conflict is recorded as %s, want the file without the credentials of the share", fileID)
+	}
+	for _, ev := range svc.Events.Events("") {
+		if strings.Contains(fmt.Sprint(ev.Data), sharePassword) {
+			t.Errorf("event %s carries the password: %v", ev.Name, ev.Data)
+		}
+	}
+
+	// The file is fetched from the share again to resolve the conflict
+	if err := svc.ResolveConflict(fileID, syncpkg.ResolveRename, "From the share"); err != nil {
+		t.Fatalf("ResolveConflict: %v", err)
+	}
+	tab, ok := tabs(t, svc)["From the share"]
+	if !ok || !tab.IsManaged {
+		t.Fatalf("the file of the share was not copied as a managed tab: %+v", tab)
+	}
+	if copied, err := s.IsFileCopied(fileID); err != nil || !copied {
+		t.Errorf("%s is not recorded as copied", fileID)
+	}
+	if counts := sync(t, svc); counts["added"] != 0 || counts["pending"] != 0 {
+		t.Errorf("sync after resolving = %v, want nothing new", counts)
+	}
+}
@@ -0,0 +1,126 @@
+package sync
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Conflict is a new file a sync did not add as is, because a tab already
+// has its title
+type Conflict struct {
+	Kind         string `json:"kind"` // "skipped", or "retitled" when added as NewTitle
+	Title        string `json:"title"`
+	NewTitle     string `json:"newTitle,omitempty"`
+	Path         string `json:"path"`
+	ExistingPath string `json:"existingPath"` // File of the tab with the title
+}
+
+// ConflictReport is a conflict report written to the logs directory
+type ConflictReport struct {
+	Name      string `json:"name"`
+	Path      string `json:"path"`
+	CreatedAt int64  `json:"createdAt"`
+}
+
+const (
+	conflictReportPrefix = "sync-conflicts-"
+	// maxConflictReports is the number of reports kept, the oldest being
+	// removed first
+	maxConflictReports = 20
+)
+
+var conflictReportHeader = []string{"Kind", "Title", "New Title", "File", "Existing File"}
+
+// writeConflictReport writes conflicts to a CSV file in the logs directory
+// and returns its name, or "" if there are none or the file could not be
+// written
+func (s *SyncService) writeConflictReport(conflicts []Conflict) string {
+	if len(conflicts) == 0 {
+		return ""
+	}
+	dir := filepath.Join(s.appDir, "logs")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		s.logger.Info("Failed to create log directory: %v", err)
+		return ""
+	}
+	name := conflictReportPrefix + time.Now().Format("2006-01-02-150405") + ".csv"
+	f, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		s.logger.Info("Failed to write conflict report: %v", err)
+		return ""
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	w.Write(conflictReportHeader)
+	for _, c := range conflicts {
+		w.Write([]string{c.Kind, c.Title, c.NewTitle, c.Path, c.ExistingPath})
+	}
+	if w.Flush(); w.Error() != nil {
+		s.logger.Info("Failed to write conflict report: %v", w.Error())
+		return ""
+	}
+
+	reports := s.ConflictReports()
+	for _, r := range reports[min(len(reports), maxConflictReports):] {
+		os.Remove(r.Path)
+	}
+	s.logger.Info("Wrote %d sync conflict(s) to %s", len(conflicts), name)
+	return name
+}
+
+// ConflictReports returns the conflict reports, newest first
+func (s *SyncService) ConflictReports() []ConflictReport {
+	dir := filepath.Join(s.appDir, "logs")
+	matches, _ := filepath.Glob(filepath.Join(dir, conflictReportPrefix+"*.csv"))
+	reports := []ConflictReport{}
+	for _, m := range matches {
+		info, err := os.Stat(m)
+		if err != nil {
+			continue
+		}
+		reports = append(reports, ConflictReport{Name: filepath.Base(m), Path: m, CreatedAt: info.ModTime().Unix()})
+	}
+	// Names sort by time
+	slices.SortFunc(reports, func(a, b ConflictReport) int { return strings.Compare(b.Name, a.Name) })
+	return reports
+}
+
+// ConflictReportPath returns the path of the conflict report named name
+func (s *SyncService) ConflictReportPath(name string) (string, error) {
+	if filepath.Base(name) != name || !strings.HasPrefix(name, conflictReportPrefix) || filepath.Ext(name) != ".csv" {
+		return "", fmt.Errorf("invalid conflict report name: %s", name)
+	}
+	return filepath.Join(s.appDir, "logs", name), nil
+}
+
+// ReadConflictReport returns the conflicts listed in the report named name
+func (s *SyncService) ReadConflictReport(name string) ([]Conflict, error) {
+	path, err := s.ConflictReportPath(name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read conflict report: %w", err)
+	}
+	conflicts := []Conflict{}
+	for _, r := range records[min(len(records), 1):] {
+		if len(r) != len(conflictReportHeader) {
+			continue
+		}
+		conflicts = append(conflicts, Conflict{Kind: r[0], Title: r[1], NewTitle: r[2], Path: r[3], ExistingPath: r[4]})
+	}
+	return conflicts, nil
+}
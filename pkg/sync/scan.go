@@ -0,0 +1,130 @@
+package sync
+
+import (
+	"haya-tab/pkg/remotefs"
+	"haya-tab/pkg/store"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	gosync "sync"
+)
+
+// scanWorkers is the number of files parsed at once by a full sync.
+// Parsing is mostly disk bound, so more workers than this do not help.
+var scanWorkers = min(runtime.NumCPU(), 8)
+
+// scannedFile is a supported file found by a full sync
+type scannedFile struct {
+	path     string
+	root     *store.SyncPath // Sync path the file was found in
+	existing *store.Tab      // Tab already pointing to path; the file is not parsed then
+	copied   bool            // Copied to storage by an earlier sync; the file is not parsed then
+	tab      store.Tab       // Parsed from the file, for new files
+	archive  bool            // A .zip archive, whose entries are imported by importArchive
+	convert  bool            // Of a format the converter converts, see importConverted; the file is not parsed then
+}
+
+// scanJob is a file to scan and its sync path
+type scanJob struct {
+	path    string
+	root    *store.SyncPath
+	convert bool
+}
+
+// scanPaths walks roots and parses the supported files not known yet on
+// scanWorkers workers, honoring the options of each root such as its
+// include and exclude patterns. The files are sent
+// in no particular order to the returned channel, which is closed once all
+// roots were walked. Zip archives are sent unparsed if archives is set, as
+// are the files conv converts. Roots on network shares are skipped.
+func (s *SyncService) scanPaths(roots []store.SyncPath, archives bool, conv *converter) <-chan scannedFile {
+	paths := make(chan scanJob, scanWorkers)
+	files := make(chan scannedFile, scanWorkers)
+
+	go func() {
+		defer close(paths)
+		for i := range roots {
+			root := &roots[i]
+			if remotefs.IsRemote(root.Path) {
+				continue // See syncRemote
+			}
+			s.logger.Info("Scanning path: %s", root.Path)
+			err := filepath.Walk(root.Path, func(path string, info os.FileInfo, err error) error {
+				if err != nil {
+					s.logger.Error("Error accessing path %s: %v", path, err)
+					return nil // Skip unreadable
+				}
+				if info.IsDir() {
+					if path != root.Path && (!root.Recursive || root.Excludes(path)) {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				if root.Excludes(path) {
+					return nil
+				}
+				ext := strings.ToLower(filepath.Ext(path))
+				if ((s.isSupportedExtension(ext) || conv.converts(ext)) && root.Includes(info.Name())) || (archives && ext == ".zip") {
+					paths <- scanJob{path: path, root: root, convert: conv.converts(ext)}
+				}
+				return nil
+			})
+			if err != nil {
+				s.logger.Error("Error walking %s: %v", root.Path, err)
+			}
+		}
+	}()
+
+	var wg gosync.WaitGroup
+	for range scanWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range paths {
+				files <- s.scanFile(job)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(files)
+	}()
+
+	return files
+}
+
+// scanFile looks up the tab of the file of job, and parses the file if
+// there is none
+func (s *SyncService) scanFile(job scanJob) scannedFile {
+	path, root := job.path, job.root
+	file := scannedFile{path: path, root: root}
+	if strings.EqualFold(filepath.Ext(path), ".zip") {
+		file.archive = true
+		return file
+	}
+	if job.convert {
+		// Converted files are copied to storage whatever the mode
+		if copied, err := s.store.IsFileCopied(path); err == nil && copied {
+			file.copied = true
+		} else {
+			file.convert = true
+		}
+		return file
+	}
+	if root.Mode == store.SyncModeCopy {
+		if copied, err := s.store.IsFileCopied(path); err == nil && copied {
+			file.copied = true
+			return file
+		}
+	} else if existing, err := s.store.GetTabByPath(path); err == nil && existing != nil {
+		file.existing = existing
+		return file
+	}
+	file.tab = s.ProcessFile(path)
+	if root.Mode != store.SyncModeCopy {
+		// Hashed now to find the tab of a moved file; saves the backfill too
+		file.tab.FileHash, _ = HashFile(path)
+	}
+	return file
+}
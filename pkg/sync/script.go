@@ -0,0 +1,24 @@
+package sync
+
+import "haya-tab/pkg/metadata"
+
+// BackfillScripts detects the script of the tabs added before scripts were
+// stored. Tabs without letters to detect are stored with an empty script,
+// so each tab is checked once.
+func (s *SyncService) BackfillScripts() {
+	tabs, err := s.store.GetTabsMissingScript()
+	if err != nil {
+		s.logger.Info("Script backfill: failed to list tabs: %v", err)
+		return
+	}
+	if len(tabs) == 0 {
+		return
+	}
+
+	for _, tab := range tabs {
+		if err := s.store.SetTabScript(tab.ID, metadata.DetectScript(tab.Title, tab.Artist)); err != nil {
+			s.logger.Info("Script backfill: failed to update %s: %v", tab.Title, err)
+		}
+	}
+	s.logger.Info("Script backfill completed for %d tabs", len(tabs))
+}
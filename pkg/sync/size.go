@@ -0,0 +1,31 @@
+package sync
+
+import "os"
+
+// BackfillFileSizes measures the files of the tabs added before file sizes
+// were stored, so they can be sorted by size. Files that cannot be read are
+// retried on the next run.
+func (s *SyncService) BackfillFileSizes() {
+	tabs, err := s.store.GetTabsMissingFileSize()
+	if err != nil {
+		s.logger.Info("File size backfill: failed to list tabs: %v", err)
+		return
+	}
+	if len(tabs) == 0 {
+		return
+	}
+
+	measured := 0
+	for _, tab := range tabs {
+		info, err := os.Stat(tab.FilePath)
+		if err != nil {
+			continue
+		}
+		if err := s.store.SetTabFileSize(tab.ID, tab.FilePath, info.Size()); err != nil {
+			s.logger.Info("File size backfill: failed to update %s: %v", tab.FilePath, err)
+			continue
+		}
+		measured++
+	}
+	s.logger.Info("File size backfill completed: %d of %d tabs measured", measured, len(tabs))
+}
@@ -3,17 +3,26 @@
 package sync
 
 import (
+	"errors"
 	"fmt"
 	"haya-tab/pkg/coverpool"
+	"haya-tab/pkg/jobpool"
 	"haya-tab/pkg/logger"
 	"haya-tab/pkg/metadata"
+	"haya-tab/pkg/remotefs"
 	"haya-tab/pkg/store"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
+	gosync "sync"
+	"sync/atomic"
 	"time"
 )
 
+// ErrSyncInProgress is returned by TriggerSync while another sync runs
+var ErrSyncInProgress = errors.New("a sync is already in progress")
+
 // EventEmitter is an abstraction for emitting events to the frontend.
 // This allows SyncService to be decoupled from wails runtime.
 type EventEmitter interface {
@@ -27,175 +36,550 @@ type SyncResult struct {
 	Skipped int
 	Errors  int
 	Total   int
+	Renamed int // Tabs whose file was moved or renamed
+	Missing int // Incremental sync only
+	Pending int // New files whose title conflict waits for the user ("ask" strategy)
+
+	SkippedShares []string // Network shares not scanned as offline mode is on, without passwords
+
+	Conflicts  []Conflict            // New files skipped or retitled for their title
+	FileErrors []store.SyncFileError // Files failed on, up to store.MaxSyncFileErrors
+}
+
+// fail counts an error on a file, keeping it for the sync history
+func (r *SyncResult) fail(path string, err error) {
+	r.Errors++
+	if len(r.FileErrors) < store.MaxSyncFileErrors {
+		r.FileErrors = append(r.FileErrors, store.SyncFileError{Path: path, Error: err.Error()})
+	}
+}
+
+// ManagedStorage stores the files of managed tabs (see storage.Backend)
+type ManagedStorage interface {
+	Import(src, name string) (locator, hash string, err error)
+	Export(locator, dst string) error
+	Remove(locator string) error
 }
 
 // SyncService handles file synchronization operations
 type SyncService struct {
 	store     *store.DBStore
+	storage   ManagedStorage
 	logger    *logger.Logger
 	coverPool *coverpool.CoverPool
+	jobPool   *jobpool.Pool
 	emitter   EventEmitter
 	appDir    string
+
+	// syncMu serializes full and incremental syncs, which both check
+	// whether a path is known before adding it
+	syncMu gosync.Mutex
+
+	hashBackfill    backfillGate
+	trackBackfill   backfillGate
+	contentBackfill backfillGate
+
+	coverBootstrapMu gosync.Mutex
+	coverSweeping    atomic.Bool // A FetchMissingCovers sweep is running
+
+	folders *folderCategories // Folder mapping of the running sync, guarded by syncMu
+
+	enriching   atomic.Bool  // An EnrichTabs pass is queued or running
+	enrichPause atomic.Int64 // Unix time the provider asked to wait until
 }
 
 // NewSyncService creates a new SyncService instance
 func NewSyncService(
 	store *store.DBStore,
+	storage ManagedStorage,
 	logger *logger.Logger,
 	coverPool *coverpool.CoverPool,
+	jobPool *jobpool.Pool,
 	emitter EventEmitter,
 	appDir string,
 ) *SyncService {
 	return &SyncService{
 		store:     store,
+		storage:   storage,
 		logger:    logger,
 		coverPool: coverPool,
+		jobPool:   jobPool,
 		emitter:   emitter,
 		appDir:    appDir,
 	}
 }
 
-// TriggerSync scans configured sync paths and adds/updates tabs based on strategy
+// TriggerSync scans configured sync paths and adds/updates tabs based on strategy.
+// Returns ErrSyncInProgress if a sync is already running.
 func (s *SyncService) TriggerSync() (string, error) {
+	if !s.syncMu.TryLock() {
+		return "", ErrSyncInProgress
+	}
+	defer s.syncMu.Unlock()
+
 	s.logger.Info("Starting TriggerSync...")
+	started := time.Now()
 	settings := s.store.GetSettings()
-	if len(settings.SyncPaths) == 0 {
+	syncPaths, err := s.store.GetSyncPaths()
+	if err != nil {
+		return "", fmt.Errorf("failed to read sync paths: %w", err)
+	}
+	if len(syncPaths) == 0 {
 		return "No sync paths configured", nil
 	}
 
 	result := SyncResult{}
-	strategy := settings.SyncStrategy // "skip" or "overwrite"
+	strategy := settings.SyncStrategy // "skip", "overwrite" or "ask"
+	s.resetFolderCategories(syncPaths)
 
 	s.emitter.Emit("sync-started", nil)
 
-	for _, root := range settings.SyncPaths {
-		s.logger.Info("Scanning path: %s", root)
-		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				s.logger.Error("Error accessing path %s: %v", path, err)
-				return nil // Skip unreadable
-			}
-			if info.IsDir() {
-				return nil
-			}
-
-			// Check extension
-			ext := strings.ToLower(filepath.Ext(path))
-			if !s.isSupportedExtension(ext) {
-				return nil
-			}
-
-			result.Total++
-			// Emit progress for every file processed
-			s.emitter.Emit("sync-progress", map[string]interface{}{
-				"message":  fmt.Sprintf("Processing: %s", filepath.Base(path)),
-				"count":    result.Total,
-				"filePath": path,
-			})
-
-			// 1. Check if EXACT path exists using DB
-			existingTab, err := s.store.GetTabByPath(path)
-			if err == nil && existingTab != nil {
-				return nil // Already exists
-			}
-
-			// 2. Parse Metadata to check Title conflict
-			newTab := s.ProcessFile(path)
-
-			// Check Title conflict using DB
-			conflictTab, _ := s.store.GetTabByTitle(newTab.Title)
-
-			if conflictTab != nil {
-				switch strategy {
-				case "skip":
-					result.Skipped++
-					return nil
-				case "overwrite":
-					// Non-destructive overwrite: Keep old file, rename new title
-					uniqueTitle := s.generateUniqueTitle(newTab.Title)
-					newTab.Title = uniqueTitle
-
-					// Add as new tab with renamed title
-					if err := s.store.AddTab(newTab); err == nil {
-						result.Added++
-						s.FetchCoverAsync(newTab)
-					} else {
-						result.Errors++
-					}
-					return nil
-				}
-			}
-
-			// No conflict, add as new
-			if err := s.store.AddTab(newTab); err == nil {
-				result.Added++
-				s.FetchCoverAsync(newTab)
-			} else {
-				result.Errors++
-			}
-
-			return nil
+	// Files are parsed in parallel; new tabs are collected here, one at a
+	// time, so title conflicts between new files are still detected
+	batch := &tabBatch{titles: map[string]string{}}
+	conv := s.converter()
+	for file := range s.scanPaths(syncPaths, settings.SyncZipArchives, conv) {
+		if file.archive {
+			s.importArchive(file.path, file.root, strategy, &result, batch)
+			continue
+		}
+		result.Total++
+		// Emit progress for every file processed
+		s.emitter.Emit("sync-progress", map[string]interface{}{
+			"message":  fmt.Sprintf("Processing: %s", filepath.Base(file.path)),
+			"count":    result.Total,
+			"filePath": file.path,
 		})
-		if err != nil {
-			s.logger.Error("Error walking %s: %v", root, err)
+
+		if file.copied {
+			continue
+		}
+		if file.convert {
+			s.importConverted(conv, file.path, strategy, &result, batch)
+			continue
+		}
+		if file.existing != nil {
+			s.restoreTab(file.existing, &result)
+			continue
+		}
+		// IDs are based on the time; workers parsing at once could get the same
+		file.tab.ID = fmt.Sprintf("%d", time.Now().UnixNano())
+		if file.root.Mode == store.SyncModeCopy {
+			s.copySyncFile(file.tab, file.path, strategy, &result, batch)
+			continue
+		}
+		if s.moveTab(file.tab, &result) {
+			continue
+		}
+		s.addTab(file.tab, strategy, &result, batch)
+		if len(batch.tabs) >= syncBatchSize {
+			s.flushBatch(batch, &result)
 		}
 	}
+	for i := range syncPaths {
+		if remotefs.IsRemote(syncPaths[i].Path) {
+			s.syncRemote(&syncPaths[i], strategy, &result, batch)
+		}
+	}
+	s.flushBatch(batch, &result)
+	s.fetchCovers(batch.added)
+	report := s.writeConflictReport(result.Conflicts)
 
 	s.emitter.Emit("sync-completed", map[string]interface{}{
 		"added":   result.Added,
 		"updated": result.Updated,
 		"skipped": result.Skipped,
+		"renamed": result.Renamed,
 		"errors":  result.Errors,
 		"total":   result.Total,
+		"pending": result.Pending,
+		"report":  report,
+		"offline": len(result.SkippedShares),
+	})
+
+	s.recordSyncRun(started, result, report)
+
+	// Update Last Sync Time only: settings may have changed during the sync
+	if err := s.store.SetSetting("lastSyncTime", time.Now().Unix()); err != nil {
+		s.logger.Info("Failed to save last sync time: %v", err)
+	}
+
+	msg := fmt.Sprintf("Sync complete. Added: %d, Updated: %d, Skipped: %d, Errors: %d",
+		result.Added, result.Updated, result.Skipped, result.Errors)
+	if result.Renamed > 0 {
+		msg += fmt.Sprintf(", Moved: %d", result.Renamed)
+	}
+	if report != "" {
+		msg += fmt.Sprintf(". %d conflict(s) listed in %s", len(result.Conflicts), report)
+	}
+	if result.Pending > 0 {
+		msg += fmt.Sprintf(". %d conflict(s) waiting for you", result.Pending)
+	}
+	if len(result.SkippedShares) > 0 {
+		msg += fmt.Sprintf(". %d network share(s) skipped while offline", len(result.SkippedShares))
+	}
+	return msg, nil
+}
+
+// recordSyncRun adds a full sync started at started to the sync history
+func (s *SyncService) recordSyncRun(started time.Time, result SyncResult, report string) {
+	err := s.store.AddSyncRun(store.SyncRun{
+		StartedAt:  started.Unix(),
+		DurationMs: time.Since(started).Milliseconds(),
+		Total:      result.Total,
+		Added:      result.Added,
+		Updated:    result.Updated,
+		Skipped:    result.Skipped,
+		Errors:     result.Errors,
+		Pending:    result.Pending,
+		Conflicts:  len(result.Conflicts),
+		Report:     report,
+		FileErrors: result.FileErrors,
 	})
+	if err != nil {
+		s.logger.Info("Failed to record the sync in the history: %v", err)
+	}
+}
+
+// addFile adds a file found in a sync directory, unless a tab already points
+// to it. Title conflicts are resolved with strategy ("skip", "overwrite" or
+// "ask").
+func (s *SyncService) addFile(path, strategy string, result *SyncResult) {
+	existingTab, err := s.store.GetTabByPath(path)
+	if err == nil && existingTab != nil {
+		s.restoreTab(existingTab, result)
+		return
+	}
+	tab := s.ProcessFile(path)
+	tab.FileHash, _ = HashFile(path)
+	if s.moveTab(tab, result) {
+		return
+	}
+	s.addTab(tab, strategy, result, nil)
+}
+
+// moveTab points the tab of a file moved or renamed on disk to newTab, the
+// file at its new place, matching them by content: the linked tab with the
+// same hash whose file is gone. It reports whether a tab was moved; newTab
+// is new otherwise.
+func (s *SyncService) moveTab(newTab store.Tab, result *SyncResult) bool {
+	tab := s.movedTab(newTab)
+	if tab == nil {
+		return false
+	}
+	if err := s.store.UpdateTabPath(tab.ID, newTab.FilePath); err != nil {
+		result.fail(newTab.FilePath, err)
+		return true
+	}
+	s.logger.Info("Tab %s moved: %s -> %s", tab.Title, tab.FilePath, newTab.FilePath)
+	result.Renamed++
+	s.emitTabUpdated(tab.ID)
+	return true
+}
+
+// movedTab returns the tab newTab is the moved file of, see moveTab, or nil
+func (s *SyncService) movedTab(newTab store.Tab) *store.Tab {
+	if newTab.FileHash == "" {
+		return nil
+	}
+	tabs, err := s.store.GetLinkedTabsByHash(newTab.FileHash)
+	if err != nil {
+		s.logger.Info("Failed to look for the tab of %s: %v", newTab.FilePath, err)
+		return nil
+	}
+	for i := range tabs {
+		// Otherwise newTab is a copy of a file still in place
+		if _, err := os.Stat(tabs[i].FilePath); errors.Is(err, fs.ErrNotExist) {
+			return &tabs[i]
+		}
+	}
+	return nil
+}
 
-	// Update Last Sync Time
-	settings.LastSyncTime = time.Now().Unix()
-	s.store.UpdateSettings(settings)
+// restoreTab clears the missing flag of a tab whose file was found again
+func (s *SyncService) restoreTab(tab *store.Tab, result *SyncResult) {
+	if !tab.IsMissing {
+		return
+	}
+	if err := s.store.SetTabMissing(tab.ID, false); err == nil {
+		result.Updated++
+	}
+}
+
+// syncBatchSize is the number of new tabs a full sync stores per transaction
+const syncBatchSize = 200
+
+// tabBatch collects the new tabs of a full sync, to store them together
+type tabBatch struct {
+	tabs   []store.Tab
+	titles map[string]string // Titles of tabs, which are not in the database yet, to their file
+	added  []store.Tab       // Tabs stored so far, whose covers are fetched at the end
+}
+
+// addTab stores a tab parsed from a new file, or adds it to batch if one is
+// given. Title conflicts are resolved with strategy ("skip", "overwrite" or
+// "ask").
+func (s *SyncService) addTab(newTab store.Tab, strategy string, result *SyncResult, batch *tabBatch) {
+	if !s.resolveTitle(&newTab, newTab.FilePath, strategy, result, batch) {
+		return
+	}
+	if len(newTab.CategoryIDs) == 0 {
+		newTab.CategoryIDs = s.folderCategoryIDs(newTab.FilePath)
+	}
+
+	if batch != nil {
+		batch.tabs = append(batch.tabs, newTab)
+		batch.titles[newTab.Title] = newTab.FilePath
+		return
+	}
+	if err := s.store.AddTab(newTab); err == nil {
+		result.Added++
+		s.saveTracks(newTab)
+		s.FetchCoverAsync(newTab)
+	} else {
+		result.fail(newTab.FilePath, err)
+	}
+}
+
+// copySyncFile copies a new file of a sync path in copy mode to storage as
+// a managed tab, and records it so later syncs leave it alone
+func (s *SyncService) copySyncFile(tab store.Tab, path, strategy string, result *SyncResult, batch *tabBatch) {
+	id, err := s.addCopiedTab(tab, path, path, path, strategy, result, batch)
+	if err != nil {
+		s.logger.Info("Failed to copy %s to storage: %v", path, err)
+		result.fail(path, err)
+		return
+	}
+	if id == "" {
+		return // Skipped on a title conflict, reported again by the next sync
+	}
+	if err := s.store.AddCopiedFile(path, id, time.Now().Unix()); err != nil {
+		s.logger.Info("Failed to record %s as copied: %v", path, err)
+	}
+}
+
+// addCopiedTab copies src, the file of a new tab found at path, to storage
+// and adds the tab as a managed tab. Title conflicts are resolved as for a
+// file linked at path, and folder categories as for one at categoryPath. It
+// returns the ID of the tab, or "" if it was skipped.
+func (s *SyncService) addCopiedTab(tab store.Tab, src, path, categoryPath, strategy string, result *SyncResult, batch *tabBatch) (string, error) {
+	if !s.resolveTitle(&tab, path, strategy, result, batch) {
+		return "", nil
+	}
+	return s.importCopiedTab(tab, src, categoryPath, result)
+}
+
+// importCopiedTab copies src to storage and adds tab, whose title conflict
+// was resolved, as a managed tab in the folder categories of categoryPath.
+// It returns the ID of the tab.
+func (s *SyncService) importCopiedTab(tab store.Tab, src, categoryPath string, result *SyncResult) (string, error) {
+	tab.CategoryIDs = s.folderCategoryIDs(categoryPath)
+
+	locator, hash, err := s.storage.Import(src, tab.ID+strings.ToLower(filepath.Ext(src)))
+	if err != nil {
+		return "", fmt.Errorf("failed to copy to storage: %w", err)
+	}
+	tab.FilePath = locator
+	tab.FileHash = hash
+	tab.IsManaged = true
+	tab.AddedAt = time.Now().Unix()
+
+	if err := s.store.AddTab(tab); err != nil {
+		s.storage.Remove(locator)
+		return "", err
+	}
+	result.Added++
+	s.saveTracks(tab)
+	s.FetchCoverAsync(tab)
+	return tab.ID, nil
+}
+
+// resolveTitle handles a title conflict of a new tab read from path with
+// strategy ("skip", "overwrite" or "ask"), recording it in result. It returns
+// false when the tab is skipped, or queued for the user with "ask".
+func (s *SyncService) resolveTitle(newTab *store.Tab, path, strategy string, result *SyncResult, batch *tabBatch) bool {
+	existingPath, taken := s.titleOwner(newTab.Title, batch)
+	if !taken {
+		return true
+	}
+	conflict := Conflict{Title: newTab.Title, Path: path, ExistingPath: existingPath}
+	switch strategy {
+	case "skip":
+		result.Skipped++
+		conflict.Kind = "skipped"
+		result.Conflicts = append(result.Conflicts, conflict)
+		return false
+	case "overwrite":
+		// Non-destructive overwrite: Keep old file, rename new title
+		newTab.Title = s.generateUniqueTitle(newTab.Title, batch)
+		conflict.Kind = "retitled"
+		conflict.NewTitle = newTab.Title
+		result.Conflicts = append(result.Conflicts, conflict)
+	case "ask":
+		s.queueConflict(conflict, result)
+		return false
+	}
+	return true
+}
 
-	return fmt.Sprintf("Sync complete. Added: %d, Updated: %d, Skipped: %d, Errors: %d",
-		result.Added, result.Updated, result.Skipped, result.Errors), nil
+// flushBatch stores the tabs collected in batch and empties it
+func (s *SyncService) flushBatch(batch *tabBatch, result *SyncResult) {
+	if len(batch.tabs) == 0 {
+		return
+	}
+	if err := s.store.AddTabsBatch(batch.tabs); err != nil {
+		s.logger.Error("Failed to add %d tab(s): %v", len(batch.tabs), err)
+		for _, tab := range batch.tabs {
+			result.fail(tab.FilePath, err)
+		}
+	} else {
+		result.Added += len(batch.tabs)
+		batch.added = append(batch.added, batch.tabs...)
+	}
+	batch.tabs = batch.tabs[:0]
+	clear(batch.titles)
+}
+
+// titleTaken reports whether a stored tab, or a tab in batch, has title
+func (s *SyncService) titleTaken(title string, batch *tabBatch) bool {
+	_, taken := s.titleOwner(title, batch)
+	return taken
+}
+
+// titleOwner returns the file of the stored tab, or tab in batch, with
+// title, and whether there is one
+func (s *SyncService) titleOwner(title string, batch *tabBatch) (string, bool) {
+	if batch != nil {
+		if path, ok := batch.titles[title]; ok {
+			return path, true
+		}
+	}
+	existing, _ := s.store.GetTabByTitle(title)
+	if existing == nil {
+		return "", false
+	}
+	return existing.FilePath, true
 }
 
 // ProcessFile takes a file path and returns a pre-filled Tab struct
 func (s *SyncService) ProcessFile(path string) store.Tab {
 	meta, err := metadata.ParseFile(path)
 	if err != nil {
-		s.logger.Error("Error parsing file metadata for %s: %v", path, err)
-		meta = metadata.ParseFilename(path)
+		// Not fatal: the tab is still usable without a track list
+		s.logger.Info("Could not read tracks from %s: %v", path, err)
 	}
 
 	ext := strings.ToLower(filepath.Ext(path))
 	typeStr := s.getFileType(ext)
 
-	return store.Tab{
+	tab := store.Tab{
 		ID:       fmt.Sprintf("%d", time.Now().UnixNano()),
 		Title:    meta.Title,
 		Artist:   meta.Artist,
 		Album:    meta.Album,
 		FilePath: path,
 		Type:     typeStr,
+		Key:      meta.Key,
+		Capo:     meta.Capo,
+		Bpm:      meta.Score.Tempo,
+		Duration: meta.Score.Duration,
+		Tracks:   tabTracks(meta.Tracks),
+		Credits:  tabCredits(meta.Score),
+		Script:   metadata.DetectScript(meta.Title, meta.Artist),
+	}
+	if r, ok := metadata.ScriptCoverRegion(meta.Title, meta.Artist); ok {
+		tab.Country = r.Country
+		tab.Language = r.Lang
+	}
+	if info, err := os.Stat(path); err == nil {
+		tab.FileSize = info.Size()
+	}
+
+	return tab
+}
+
+// tabTracks converts parsed tracks to their stored form
+func tabTracks(tracks []metadata.TrackInfo) []store.TabTrack {
+	var result []store.TabTrack
+	for _, t := range tracks {
+		result = append(result, store.TabTrack{
+			Index:        t.Index,
+			Name:         t.Name,
+			Instrument:   t.Instrument,
+			Program:      t.Program,
+			Kind:         t.Kind,
+			StringCount:  t.StringCount,
+			Tuning:       t.Tuning,
+			TuningName:   t.TuningName,
+			Capo:         t.Capo,
+			IsPercussion: t.IsPercussion,
+		})
+	}
+	return result
+}
+
+// tabCredits converts the credits of a parsed score to their stored form
+func tabCredits(score metadata.ScoreInfo) store.TabCredits {
+	return store.TabCredits{
+		Words:     score.Words,
+		Music:     score.Music,
+		Copyright: score.Copyright,
+		TabAuthor: score.TabAuthor,
+		Notice:    strings.Join(score.Notice, "\n"),
+	}
+}
+
+// saveTracks stores the parsed track list and credits of a newly added GP
+// tab. An empty list is stored too, so the track backfill does not parse
+// the file again.
+func (s *SyncService) saveTracks(tab store.Tab) {
+	if tab.Type != "gp" {
+		return
+	}
+	if err := s.store.SetTabCredits(tab.ID, tab.Credits); err != nil {
+		s.logger.Error("Failed to save credits for %s: %v", tab.Title, err)
+	}
+	if err := s.store.SetTabTracks(tab.ID, tab.Tracks); err != nil {
+		s.logger.Error("Failed to save tracks for %s: %v", tab.Title, err)
 	}
 }
 
 // FetchCoverAsync downloads album cover art asynchronously for a tab using worker pool
 func (s *SyncService) FetchCoverAsync(tab store.Tab) {
-	if tab.Artist == "" || (tab.Album == "" && tab.Title == "") {
-		return // Not enough info to search for cover
+	s.fetchCover(tab, nil)
+}
+
+// fetchCover is FetchCoverAsync calling done, if not nil, once the download
+// finished with its error. Tabs without an artist are skipped, without
+// calling done.
+func (s *SyncService) fetchCover(tab store.Tab, done func(err error)) {
+	if !canFetchCover(tab) {
+		return
 	}
 
 	coverFilename := tab.ID + ".jpg"
 	coverPath := filepath.Join(s.appDir, "covers", coverFilename)
 
+	// Tabs added before the region was guessed at import have none
+	country, language := tab.Country, tab.Language
+	if country == "" {
+		if r, ok := metadata.ScriptCoverRegion(tab.Title, tab.Artist); ok {
+			country, language = r.Country, r.Lang
+		}
+	}
+
 	s.coverPool.Submit(coverpool.CoverJob{
 		TabID:     tab.ID,
 		Artist:    tab.Artist,
 		Album:     tab.Album,
 		Title:     tab.Title,
-		Country:   tab.Country,
-		Language:  tab.Language,
+		Country:   country,
+		Language:  language,
 		CoverPath: coverPath,
 		OnComplete: func(tabID, coverPath string, err error) {
+			if done != nil {
+				defer done(err)
+			}
 			if err == nil {
 				s.logger.Info("Cover downloaded successfully to: %s", coverPath)
 				currentTab, getErr := s.store.GetTab(tabID)
@@ -203,24 +587,37 @@ func (s *SyncService) FetchCoverAsync(tab store.Tab) {
 					s.logger.Error("Failed to get tab after cover download: %v", getErr)
 					return
 				}
+				if err := s.store.SetCoverSource(tabID, tab.Artist, tab.Album, tab.Title, time.Now().Unix()); err != nil {
+					s.logger.Info("Failed to record the cover source of %s: %v", tab.Title, err)
+				}
+				if err := s.store.ClearCoverFailure(tabID); err != nil {
+					s.logger.Info("Failed to clear the cover failures of %s: %v", tab.Title, err)
+				}
 				currentTab.CoverPath = coverPath
 				s.store.AddTab(*currentTab)
+				currentTab.CoverColor = s.UpdateCoverColor(tabID, coverPath)
 				s.emitter.Emit("tab-updated", *currentTab)
 			} else {
 				s.logger.Error("Failed to download cover: %v", err)
+				s.recordCoverFailure(tab, err)
 			}
 		},
 	})
 }
 
+// canFetchCover reports whether a tab has enough info to search its cover
+func canFetchCover(tab store.Tab) bool {
+	return tab.Artist != "" && (tab.Album != "" || tab.Title != "")
+}
+
 // generateUniqueTitle creates a unique title by appending _copy1, _copy2, etc.
-func (s *SyncService) generateUniqueTitle(baseTitle string) string {
+// Titles of the tabs in batch, if given, are avoided too.
+func (s *SyncService) generateUniqueTitle(baseTitle string, batch *tabBatch) string {
 	copyNum := 1
 	candidate := fmt.Sprintf("%s_copy%d", baseTitle, copyNum)
 
 	for {
-		existing, _ := s.store.GetTabByTitle(candidate)
-		if existing == nil {
+		if !s.titleTaken(candidate, batch) {
 			return candidate
 		}
 		copyNum++
@@ -236,7 +633,7 @@ func (s *SyncService) generateUniqueTitle(baseTitle string) string {
 // isSupportedExtension checks if the file extension is supported
 func (s *SyncService) isSupportedExtension(ext string) bool {
 	switch ext {
-	case ".pdf", ".gp", ".gp3", ".gp4", ".gp5", ".gpx":
+	case ".pdf", ".gp", ".gp3", ".gp4", ".gp5", ".gpx", ".txt", ".tab", ".crd", ".cho", ".pro", ".chopro", ".chordpro":
 		return true
 	default:
 		return false
@@ -250,6 +647,10 @@ func (s *SyncService) getFileType(ext string) string {
 		return "pdf"
 	case ".gp", ".gp3", ".gp4", ".gp5", ".gpx":
 		return "gp"
+	case ".txt", ".tab", ".crd":
+		return "text"
+	case ".cho", ".pro", ".chopro", ".chordpro":
+		return "chordpro"
 	default:
 		return "unknown"
 	}
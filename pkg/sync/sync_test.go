@@ -0,0 +1,190 @@
+package sync_test
+
+import (
+	"haya-tab/pkg/store"
+	syncpkg "haya-tab/pkg/sync"
+	"haya-tab/pkg/testutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// addSyncPath writes n text tabs into a new folder and adds it as a linked
+// sync path of svc. Returns the paths of the tabs.
+func addSyncPath(t testing.TB, svc *testutil.Sync, name string, n int) []string {
+	t.Helper()
+	dir := filepath.Join(svc.AppDir, name)
+	paths, err := testutil.WriteTextTabs(dir, n, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.Store.AddSyncPath(store.SyncPath{Path: dir, Recursive: true, Mode: store.SyncModeLink}); err != nil {
+		t.Fatal(err)
+	}
+	return paths
+}
+
+// sync runs a sync and returns the counts of its "sync-completed" event
+func sync(t *testing.T, svc *testutil.Sync) map[string]interface{} {
+	t.Helper()
+	if _, err := svc.TriggerSync(); err != nil {
+		t.Fatalf("TriggerSync: %v", err)
+	}
+	counts, ok := svc.Events.Last("sync-completed").(map[string]interface{})
+	if !ok {
+		t.Fatalf("no sync-completed event")
+	}
+	return counts
+}
+
+// tabs returns the tabs of the store of svc, keyed by title
+func tabs(t *testing.T, svc *testutil.Sync) map[string]store.Tab {
+	t.Helper()
+	list, err := svc.Store.GetTabs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	byTitle := map[string]store.Tab{}
+	for _, tab := range list {
+		byTitle[tab.Title] = tab
+	}
+	return byTitle
+}
+
+func TestFirstSync(t *testing.T) {
+	const n = 20
+	covers := testutil.NewCovers()
+	svc := testutil.NewSyncService(t, testutil.NewStore(t), covers)
+	addSyncPath(t, svc, "tabs", n)
+
+	counts := sync(t, svc)
+	if counts["added"] != n || counts["total"] != n || counts["errors"] != 0 {
+		t.Errorf("sync-completed = %v, want %d added of %d", counts, n, n)
+	}
+	if len(svc.Events.Events("sync-started")) != 1 {
+		t.Errorf("sync-started emitted %d times, want once", len(svc.Events.Events("sync-started")))
+	}
+	if got := len(svc.Events.Events("sync-progress")); got != n {
+		t.Errorf("%d sync-progress events, want %d", got, n)
+	}
+	added := tabs(t, svc)
+	if len(added) != n {
+		t.Fatalf("%d tabs, want %d", len(added), n)
+	}
+
+	// Covers of the new tabs are downloaded in the background
+	deadline := time.Now().Add(5 * time.Second)
+	for len(covers.Requests()) < n && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	requests := covers.Requests()
+	if len(requests) != n {
+		t.Fatalf("%d cover requests, want %d", len(requests), n)
+	}
+	for _, req := range requests {
+		tab, ok := added[req.Title]
+		if !ok || req.Artist != tab.Artist || filepath.Base(req.DstPath) != tab.ID+".jpg" {
+			t.Errorf("cover request %+v matches no tab", req)
+		}
+	}
+
+	// Nothing changed, nothing is added
+	if counts := sync(t, svc); counts["added"] != 0 {
+		t.Errorf("second sync added %v tabs", counts["added"])
+	}
+}
+
+func TestTitleConflicts(t *testing.T) {
+	for _, strategy := range []string{"skip", "overwrite", "ask"} {
+		t.Run(strategy, func(t *testing.T) {
+			s := testutil.NewStore(t)
+			svc := testutil.NewSyncService(t, s, testutil.NoCovers())
+			paths := addSyncPath(t, svc, "tabs", 3)
+			sync(t, svc)
+			before := tabs(t, svc)
+
+			// The same tab, with other content, in another sync path
+			dir := filepath.Join(svc.AppDir, "more")
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(paths[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			dup := filepath.Join(dir, filepath.Base(paths[0]))
+			if err := os.WriteFile(dup, append(data, "\n[Chorus]\nC G\n"...), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.AddSyncPath(store.SyncPath{Path: dir, Recursive: true, Mode: store.SyncModeLink}); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.SetSetting("syncStrategy", strategy); err != nil {
+				t.Fatal(err)
+			}
+
+			counts := sync(t, svc)
+			after := tabs(t, svc)
+			switch strategy {
+			case "skip":
+				if counts["skipped"] != 1 || len(after) != len(before) {
+					t.Errorf("sync-completed = %v with %d tabs, want 1 skipped and %d tabs", counts, len(after), len(before))
+				}
+			case "overwrite":
+				if counts["added"] != 1 || len(after) != len(before)+1 {
+					t.Errorf("sync-completed = %v with %d tabs, want 1 added", counts, len(after))
+				}
+				for title, tab := range after {
+					if _, ok := before[title]; !ok && tab.FilePath != dup {
+						t.Errorf("retitled tab %q has file %s, want %s", title, tab.FilePath, dup)
+					}
+				}
+			case "ask":
+				if counts["pending"] != 1 || len(after) != len(before) {
+					t.Fatalf("sync-completed = %v with %d tabs, want 1 pending", counts, len(after))
+				}
+				pending, err := s.GetPendingConflicts()
+				if err != nil || len(pending) != 1 || pending[0].Path != dup {
+					t.Fatalf("pending conflicts = %v (%v), want %s", pending, err, dup)
+				}
+				if err := svc.ResolveConflict(dup, syncpkg.ResolveRename, "Renamed"); err != nil {
+					t.Fatalf("ResolveConflict: %v", err)
+				}
+				if tab, ok := tabs(t, svc)["Renamed"]; !ok || tab.FilePath != dup {
+					t.Errorf("the renamed tab was not added from %s", dup)
+				}
+				// Resolved conflicts are not asked again
+				if counts := sync(t, svc); counts["pending"] != 0 || counts["added"] != 0 {
+					t.Errorf("sync after resolving = %v", counts)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkTriggerSync measures a first sync of 500 text tabs into an
+// empty library
+func BenchmarkTriggerSync(b *testing.B) {
+	const n = 500
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		svc := testutil.NewSyncService(b, testutil.NewStore(b), testutil.NoCovers())
+		addSyncPath(b, svc, "tabs", n)
+		b.StartTimer()
+
+		if _, err := svc.TriggerSync(); err != nil {
+			b.Fatal(err)
+		}
+
+		b.StopTimer()
+		added, err := svc.Store.GetTabs()
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(added) != n {
+			b.Fatalf("%d tabs added, want %d", len(added), n)
+		}
+		b.StartTimer()
+	}
+}
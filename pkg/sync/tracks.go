@@ -0,0 +1,92 @@
+package sync
+
+import (
+	"context"
+	"haya-tab/pkg/jobpool"
+	"haya-tab/pkg/metadata"
+	"os"
+	"sync/atomic"
+)
+
+// BackfillTracks reads the track list and credits of GP tabs added before
+// they were stored, in the background job pool. Emits "track-backfill-progress"
+// after each file and "track-backfill-completed" at the end. Files that
+// cannot be opened are retried on the next run; files that fail to parse
+// are stored with an empty track list. If a backfill is already running,
+// another pass runs once it ends.
+func (s *SyncService) BackfillTracks() {
+	if !s.trackBackfill.start() {
+		return
+	}
+
+	tabs, err := s.store.GetTabsMissingTracks()
+	if err != nil {
+		s.logger.Info("Track backfill: failed to list tabs: %v", err)
+		s.finishTrackBackfill()
+		return
+	}
+	if len(tabs) == 0 {
+		s.finishTrackBackfill()
+		return
+	}
+
+	total := len(tabs)
+	s.logger.Info("Track backfill started for %d tabs", total)
+
+	var done, failed atomic.Int32
+	for _, tab := range tabs {
+		tab := tab
+		submitted := s.jobPool.Submit(jobpool.Job{
+			Name: "tracks:" + tab.ID,
+			Run: func(ctx context.Context) error {
+				if _, err := os.Stat(tab.FilePath); err != nil {
+					return err
+				}
+				meta, err := metadata.ParseFile(tab.FilePath)
+				if err != nil {
+					s.logger.Info("Could not read tracks from %s: %v", tab.FilePath, err)
+				}
+				if err := s.store.SetTabCredits(tab.ID, tabCredits(meta.Score)); err != nil {
+					return err
+				}
+				if err := s.store.FillTabTempoKey(tab.ID, meta.Score.Tempo, meta.Score.Key); err != nil {
+					return err
+				}
+				if err := s.store.SetTabDuration(tab.ID, meta.Score.Duration); err != nil {
+					return err
+				}
+				return s.store.SetTabTracks(tab.ID, tabTracks(meta.Tracks))
+			},
+			OnComplete: func(err error) {
+				if err != nil {
+					failed.Add(1)
+				}
+				n := int(done.Add(1))
+				s.emitter.Emit("track-backfill-progress", map[string]interface{}{
+					"done":  n,
+					"total": total,
+				})
+				if n == total {
+					s.logger.Info("Track backfill completed: %d read, %d failed", total-int(failed.Load()), failed.Load())
+					s.emitter.Emit("track-backfill-completed", map[string]interface{}{
+						"total":  total,
+						"failed": int(failed.Load()),
+					})
+					s.finishTrackBackfill()
+				}
+			},
+		})
+		if !submitted {
+			// Pool is shutting down
+			return
+		}
+	}
+}
+
+// finishTrackBackfill ends a track backfill pass, starting another if new
+// files were queued while it ran
+func (s *SyncService) finishTrackBackfill() {
+	if s.trackBackfill.finish() {
+		go s.BackfillTracks()
+	}
+}
@@ -0,0 +1,145 @@
+package testutil
+
+import (
+	"fmt"
+	"haya-tab/pkg/store"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// LibrarySpec describes a synthetic library for Generate
+type LibrarySpec struct {
+	Tabs       int
+	Categories int     // Root categories get up to 4 children, then grandchildren
+	Artists    int     // Distinct artists the tabs are spread over, default Tabs/10
+	Filed      float64 // Share of tabs filed into 1-3 categories, default 0.7
+	Seed       int64   // Same seed, same library
+	Dir        string  // Directory of the tab paths, default "/library"
+}
+
+// Small and Large are the usual library sizes
+var (
+	Small = LibrarySpec{Tabs: 200, Categories: 10, Seed: 1}
+	Large = LibrarySpec{Tabs: 5000, Categories: 120, Seed: 1}
+)
+
+// Library is a generated library
+type Library struct {
+	Categories []store.Category // Parents before their children
+	Tabs       []store.Tab
+	Artists    []string
+}
+
+var (
+	tabTypes      = []string{"pdf", "pdf", "gp", "gp", "gp", "text", "chordpro"}
+	tabExts       = map[string]string{"pdf": ".pdf", "gp": ".gp5", "text": ".txt", "chordpro": ".cho"}
+	statuses      = []string{"", "", "", "learning", "mastered"}
+	difficulties  = []string{"", "", "beginner", "intermediate", "advanced"}
+	titleWords    = []string{"Blue", "Night", "River", "Fire", "Golden", "Rain", "Highway", "Heart", "Silver", "Moon", "Road", "Summer", "Ghost", "Ocean", "Wild"}
+	artistWords   = []string{"The", "Electric", "Stone", "Velvet", "Iron", "Crystal", "Lonely", "Northern", "Black", "Paper"}
+	categoryNames = []string{"Rock", "Jazz", "Blues", "Metal", "Folk", "Pop", "Classical", "Funk", "Lessons", "Setlist"}
+	generatedAt   = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Unix()
+)
+
+// Generate returns the library described by spec. It only depends on spec:
+// IDs, names, paths and timestamps are the same for the same spec.
+func Generate(spec LibrarySpec) Library {
+	r := rand.New(rand.NewSource(spec.Seed))
+	if spec.Artists <= 0 {
+		spec.Artists = max(spec.Tabs/10, 1)
+	}
+	if spec.Filed == 0 {
+		spec.Filed = 0.7
+	}
+	if spec.Dir == "" {
+		spec.Dir = "/library"
+	}
+
+	var lib Library
+	for i := 0; i < spec.Artists; i++ {
+		lib.Artists = append(lib.Artists, fmt.Sprintf("%s %s %d",
+			artistWords[r.Intn(len(artistWords))], titleWords[r.Intn(len(titleWords))], i+1))
+	}
+
+	// Categories form a tree: each one after the roots goes under an earlier one
+	roots := max(spec.Categories/5, min(spec.Categories, 1))
+	for i := 0; i < spec.Categories; i++ {
+		cat := store.Category{
+			ID:   fmt.Sprintf("cat_%04d", i+1),
+			Name: fmt.Sprintf("%s %d", categoryNames[i%len(categoryNames)], i+1),
+		}
+		if i >= roots {
+			cat.ParentID = lib.Categories[(i-roots)/4].ID
+		}
+		lib.Categories = append(lib.Categories, cat)
+	}
+
+	for i := 0; i < spec.Tabs; i++ {
+		typ := tabTypes[r.Intn(len(tabTypes))]
+		artist := lib.Artists[r.Intn(len(lib.Artists))]
+		title := fmt.Sprintf("%s %s %d", titleWords[r.Intn(len(titleWords))], titleWords[r.Intn(len(titleWords))], i+1)
+		tab := store.Tab{
+			ID:             fmt.Sprintf("tab_%06d", i+1),
+			Title:          title,
+			Artist:         artist,
+			Album:          fmt.Sprintf("%s Album %d", artist, r.Intn(3)+1),
+			FilePath:       filepath.Join(spec.Dir, artist, title+tabExts[typ]),
+			Type:           typ,
+			CategoryIDs:    []string{},
+			AddedAt:        generatedAt + int64(i)*3600,
+			PracticeStatus: statuses[r.Intn(len(statuses))],
+			Rating:         r.Intn(6),
+			Difficulty:     difficulties[r.Intn(len(difficulties))],
+			Script:         "latin",
+		}
+		if r.Intn(3) == 0 {
+			tab.LastOpened = tab.AddedAt + int64(r.Intn(90*24))*3600
+		}
+		if len(lib.Categories) > 0 && r.Float64() < spec.Filed {
+			for n := r.Intn(3) + 1; n > 0; n-- {
+				id := lib.Categories[r.Intn(len(lib.Categories))].ID
+				if !slices.Contains(tab.CategoryIDs, id) {
+					tab.CategoryIDs = append(tab.CategoryIDs, id)
+				}
+			}
+		}
+		lib.Tabs = append(lib.Tabs, tab)
+	}
+	return lib
+}
+
+// Load adds the categories and tabs of the library to s
+func (lib Library) Load(s *store.DBStore) error {
+	if err := s.AddCategories(lib.Categories); err != nil {
+		return fmt.Errorf("failed to add categories: %w", err)
+	}
+	const chunk = 1000
+	for i := 0; i < len(lib.Tabs); i += chunk {
+		if err := s.AddTabsBatch(lib.Tabs[i:min(i+chunk, len(lib.Tabs))]); err != nil {
+			return fmt.Errorf("failed to add tabs: %w", err)
+		}
+	}
+	return nil
+}
+
+// WriteTextTabs writes n text tabs named "Artist - Title.txt" into dir for
+// a sync to find, and returns their paths
+func WriteTextTabs(dir string, n int, seed int64) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	lib := Generate(LibrarySpec{Tabs: n, Seed: seed})
+	paths := make([]string, 0, n)
+	for _, tab := range lib.Tabs {
+		path := filepath.Join(dir, tab.Artist+" - "+tab.Title+".txt")
+		content := fmt.Sprintf("%s by %s\n\n[Verse]\nG        C        D\ne|-----0-----3-----2---|\n", tab.Title, tab.Artist)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
@@ -0,0 +1,112 @@
+package testutil
+
+import (
+	"haya-tab/pkg/metadata"
+	"os"
+	gosync "sync"
+)
+
+// coverImage is a 1x1 JPEG, written as every fake cover
+var coverImage = []byte{
+	0xff, 0xd8, 0xff, 0xdb, 0x00, 0x43, 0x00, 0x03, 0x02, 0x02, 0x02, 0x02, 0x02, 0x03, 0x02, 0x02,
+	0x02, 0x03, 0x03, 0x03, 0x03, 0x04, 0x06, 0x04, 0x04, 0x04, 0x04, 0x04, 0x08, 0x06, 0x06, 0x05,
+	0x06, 0x09, 0x08, 0x0a, 0x0a, 0x09, 0x08, 0x09, 0x09, 0x0a, 0x0c, 0x0f, 0x0c, 0x0a, 0x0b, 0x0e,
+	0x0b, 0x09, 0x09, 0x0d, 0x11, 0x0d, 0x0e, 0x0f, 0x10, 0x10, 0x11, 0x10, 0x0a, 0x0c, 0x12, 0x13,
+	0x12, 0x10, 0x13, 0x0f, 0x10, 0x10, 0x10, 0xff, 0xc9, 0x00, 0x0b, 0x08, 0x00, 0x01, 0x00, 0x01,
+	0x01, 0x01, 0x11, 0x00, 0xff, 0xcc, 0x00, 0x06, 0x00, 0x10, 0x10, 0x05, 0xff, 0xda, 0x00, 0x08,
+	0x01, 0x01, 0x00, 0x00, 0x3f, 0x00, 0xd2, 0xcf, 0x20, 0xff, 0xd9,
+}
+
+// CoverRequest is a cover download asked of Covers
+type CoverRequest struct {
+	Artist, Album, Title string
+	Country, Lang        string
+	DstPath              string
+}
+
+// Covers is a fake cover provider: its Download, in place of
+// metadata.DownloadCover, writes a small image without going to the
+// network and records the request.
+type Covers struct {
+	mu       gosync.Mutex
+	requests []CoverRequest
+
+	// Fail, if set, is called for every download; a non-nil error fails it,
+	// e.g. metadata.ErrNoCover or a network error
+	Fail func(req CoverRequest) error
+}
+
+// NewCovers returns a Covers finding a cover for every tab
+func NewCovers() *Covers {
+	return &Covers{}
+}
+
+// NoCovers returns a Covers finding no cover for any tab
+func NoCovers() *Covers {
+	return &Covers{Fail: func(CoverRequest) error { return metadata.ErrNoCover }}
+}
+
+// Download has the signature of metadata.DownloadCover
+func (c *Covers) Download(artist, album, title, country, lang, dstPath string) error {
+	req := CoverRequest{artist, album, title, country, lang, dstPath}
+	c.mu.Lock()
+	c.requests = append(c.requests, req)
+	fail := c.Fail
+	c.mu.Unlock()
+
+	if fail != nil {
+		if err := fail(req); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(dstPath, coverImage, 0644)
+}
+
+// Requests returns the downloads asked so far, in order
+func (c *Covers) Requests() []CoverRequest {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]CoverRequest(nil), c.requests...)
+}
+
+// Event is an event recorded by Emitter
+type Event struct {
+	Name string
+	Data interface{}
+}
+
+// Emitter records the events emitted to the frontend
+type Emitter struct {
+	mu     gosync.Mutex
+	events []Event
+}
+
+// Emit implements sync.EventEmitter
+func (e *Emitter) Emit(eventName string, data interface{}) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.events = append(e.events, Event{eventName, data})
+}
+
+// Events returns the events named name emitted so far, all of them if name
+// is empty
+func (e *Emitter) Events(name string) []Event {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var events []Event
+	for _, ev := range e.events {
+		if name == "" || ev.Name == name {
+			events = append(events, ev)
+		}
+	}
+	return events
+}
+
+// Last returns the data of the last event named name, nil if none
+func (e *Emitter) Last(name string) interface{} {
+	events := e.Events(name)
+	if len(events) == 0 {
+		return nil
+	}
+	return events[len(events)-1].Data
+}
@@ -0,0 +1,92 @@
+// Package testutil helps tests and benchmarks of the store and sync
+// packages: in-memory stores, synthetic libraries of any size and fakes
+// of the network providers.
+package testutil
+
+import (
+	"fmt"
+	"haya-tab/pkg/coverpool"
+	"haya-tab/pkg/jobpool"
+	"haya-tab/pkg/logger"
+	"haya-tab/pkg/storage"
+	"haya-tab/pkg/store"
+	syncpkg "haya-tab/pkg/sync"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+var storeSeq atomic.Int64
+
+// NewStore returns an initialized in-memory store, closed when tb ends.
+// Every call returns a new, empty database.
+func NewStore(tb testing.TB) *store.DBStore {
+	tb.Helper()
+	s := store.NewMemoryDBStore(fmt.Sprintf("testutil-%d", storeSeq.Add(1)))
+	if err := s.Initialize(); err != nil {
+		tb.Fatalf("failed to initialize store: %v", err)
+	}
+	tb.Cleanup(func() { s.Close() })
+	return s
+}
+
+// NewLibraryStore returns a store (see NewStore) filled with the library
+// generated from spec
+func NewLibraryStore(tb testing.TB, spec LibrarySpec) (*store.DBStore, Library) {
+	tb.Helper()
+	s := NewStore(tb)
+	lib := Generate(spec)
+	if err := lib.Load(s); err != nil {
+		tb.Fatalf("failed to load library: %v", err)
+	}
+	return s, lib
+}
+
+// Sync is a sync service wired to fakes, see NewSyncService
+type Sync struct {
+	*syncpkg.SyncService
+	Store   *store.DBStore
+	Covers  *Covers
+	Events  *Emitter
+	AppDir  string // Logs, covers and reports
+	DataDir string // Managed tab files
+}
+
+// NewSyncService returns a sync service over s whose covers come from
+// covers (a new Covers if nil) and whose events are recorded. Its worker
+// pools are stopped when tb ends.
+func NewSyncService(tb testing.TB, s *store.DBStore, covers *Covers) *Sync {
+	tb.Helper()
+	if covers == nil {
+		covers = NewCovers()
+	}
+	appDir := tb.TempDir()
+	dataDir := filepath.Join(appDir, "storage")
+	for _, dir := range []string{dataDir, filepath.Join(appDir, "covers")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			tb.Fatalf("failed to create %s: %v", dir, err)
+		}
+	}
+
+	coverPool := coverpool.NewCoverPool(3, covers.Download)
+	coverPool.Start()
+	jobPool := jobpool.NewPool(2)
+	jobPool.Start()
+	tb.Cleanup(func() {
+		coverPool.Stop()
+		jobPool.Stop()
+	})
+
+	events := &Emitter{}
+	service := syncpkg.NewSyncService(s, storage.NewLocal(dataDir), logger.NewLogger(appDir),
+		coverPool, jobPool, events, appDir)
+	return &Sync{
+		SyncService: service,
+		Store:       s,
+		Covers:      covers,
+		Events:      events,
+		AppDir:      appDir,
+		DataDir:     dataDir,
+	}
+}
@@ -2,7 +2,9 @@ package watcher
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -16,20 +18,29 @@ type Logger interface {
 	Error(format string, args ...interface{})
 }
 
+// Change is a file that changed during a debounce window
+type Change struct {
+	Path    string
+	Removed bool // File no longer exists (deleted, or renamed/moved away)
+}
+
 // FileWatcher watches directories for file changes
 type FileWatcher struct {
 	watcher    *fsnotify.Watcher
 	paths      []string
-	onChange   func()
+	onChange   func(changes []Change)
 	mu         sync.Mutex
 	running    bool
 	debounceMs int
 	stopChan   chan struct{}
 	logger     Logger
+	ignore     func(path string) bool // Reports paths excluded from syncs, see SetIgnore
+	extensions func(ext string) bool  // Reports further relevant extensions, see SetExtensions
 }
 
-// NewFileWatcher creates a new file watcher
-func NewFileWatcher(onChange func()) *FileWatcher {
+// NewFileWatcher creates a new file watcher. onChange receives the files
+// changed since the last call, once changes have settled.
+func NewFileWatcher(onChange func(changes []Change)) *FileWatcher {
 	return &FileWatcher{
 		onChange:   onChange,
 		debounceMs: 500, // 0.5 second debounce
@@ -42,6 +53,14 @@ func (w *FileWatcher) SetLogger(l Logger) {
 	w.logger = l
 }
 
+// SetIgnore sets the function reporting the files whose changes are not
+// passed on, e.g. ones matching the exclude patterns of their sync path
+func (w *FileWatcher) SetIgnore(ignore func(path string) bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.ignore = ignore
+}
+
 // Start initializes and starts the file watcher
 func (w *FileWatcher) Start() error {
 	w.mu.Lock()
@@ -181,15 +200,49 @@ func (w *FileWatcher) IsRunning() bool {
 	return w.running
 }
 
-// isRelevantFile checks if the file is a tab file we care about
-func isRelevantFile(path string) bool {
+// SetExtensions sets the function reporting further extensions, lowercase
+// with the dot, whose files are relevant, e.g. those of a converter
+func (w *FileWatcher) SetExtensions(extensions func(ext string) bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.extensions = extensions
+}
+
+// isRelevantFile checks if the file is a tab file we care about, or a zip
+// archive which may hold some, and is not ignored
+func (w *FileWatcher) isRelevantFile(path string) bool {
+	w.mu.Lock()
+	ignore, extensions := w.ignore, w.extensions
+	w.mu.Unlock()
+
 	ext := strings.ToLower(filepath.Ext(path))
-	return ext == ".pdf" || ext == ".gp" || ext == ".gp5" || ext == ".gpx"
+	switch ext {
+	case ".pdf", ".gp", ".gp3", ".gp4", ".gp5", ".gpx", ".txt", ".tab", ".crd", ".cho", ".pro", ".chopro", ".chordpro", ".zip":
+	default:
+		if extensions == nil || !extensions(ext) {
+			return false
+		}
+	}
+	return ignore == nil || !ignore(path)
+}
+
+// collectChanges turns the changed paths into Changes, checking the disk
+// for the final state of each file: a rename shows up as a removal of the
+// old path and a creation of the new one
+func collectChanges(paths map[string]bool) []Change {
+	changes := make([]Change, 0, len(paths))
+	for path := range paths {
+		_, err := os.Stat(path)
+		changes = append(changes, Change{Path: path, Removed: os.IsNotExist(err)})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
 }
 
 func (w *FileWatcher) watchLoop() {
 	var debounceTimer *time.Timer
-	var pendingChange bool
+	var pendingMu sync.Mutex
+	pending := make(map[string]bool)
 
 	for {
 		select {
@@ -205,7 +258,7 @@ func (w *FileWatcher) watchLoop() {
 			}
 
 			// Only care about relevant file types
-			if !isRelevantFile(event.Name) {
+			if !w.isRelevantFile(event.Name) {
 				continue
 			}
 
@@ -219,14 +272,19 @@ func (w *FileWatcher) watchLoop() {
 			}
 
 			// Debounce: wait for changes to settle
-			pendingChange = true
+			pendingMu.Lock()
+			pending[event.Name] = true
+			pendingMu.Unlock()
 			if debounceTimer != nil {
 				debounceTimer.Stop()
 			}
 			debounceTimer = time.AfterFunc(time.Duration(w.debounceMs)*time.Millisecond, func() {
-				if pendingChange && w.onChange != nil {
-					w.onChange()
-					pendingChange = false
+				pendingMu.Lock()
+				paths := pending
+				pending = make(map[string]bool)
+				pendingMu.Unlock()
+				if len(paths) > 0 && w.onChange != nil {
+					w.onChange(collectChanges(paths))
 				}
 			})
 
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"haya-tab/pkg/store"
+)
+
+// practiceStatuses are the valid values of Tab.PracticeStatus
+var practiceStatuses = map[string]bool{
+	"":         true,
+	"learning": true,
+	"mastered": true,
+}
+
+// SetPracticeStatus sets the practice status of a tab ("", "learning" or "mastered")
+func (a *App) SetPracticeStatus(id string, status string) error {
+	if isSharedTab(id) {
+		return errSharedReadOnly
+	}
+	if !practiceStatuses[status] {
+		return fmt.Errorf("invalid practice status: %s", status)
+	}
+	return a.store.SetPracticeStatus(id, status)
+}
+
+// SetTabRating sets the rating of a tab (1-5, 0 to clear it)
+func (a *App) SetTabRating(id string, rating int) error {
+	if isSharedTab(id) {
+		return errSharedReadOnly
+	}
+	if rating < 0 || rating > 5 {
+		return fmt.Errorf("rating must be between 0 and 5 (0 clears it)")
+	}
+	return a.store.SetTabRating(id, rating)
+}
+
+// SetTabDifficulty sets the difficulty of a tab ("beginner", "intermediate",
+// "advanced", or "" to clear it)
+func (a *App) SetTabDifficulty(id string, difficulty string) error {
+	if isSharedTab(id) {
+		return errSharedReadOnly
+	}
+	if difficulty != "" && store.DifficultyLevel(difficulty) == 0 {
+		return fmt.Errorf("invalid difficulty: %s", difficulty)
+	}
+	return a.store.SetTabDifficulty(id, difficulty)
+}
+
+// BuildPracticeQueue replaces the practice queue with up to count shuffled
+// tabs matching filter. The queue is saved, so it survives restarts.
+func (a *App) BuildPracticeQueue(filter store.PracticeFilter, count int) ([]string, error) {
+	if count <= 0 {
+		return nil, fmt.Errorf("invalid queue size: %d", count)
+	}
+	if !practiceStatuses[filter.Status] {
+		return nil, fmt.Errorf("invalid practice status: %s", filter.Status)
+	}
+	for _, d := range []string{filter.MinDifficulty, filter.MaxDifficulty} {
+		if d != "" && store.DifficultyLevel(d) == 0 {
+			return nil, fmt.Errorf("invalid difficulty: %s", d)
+		}
+	}
+
+	ids, err := a.store.BuildPracticeQueue(filter, count)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build practice queue: %w", err)
+	}
+	a.logger.Info("Practice queue built with %d tab(s)", len(ids))
+	return ids, nil
+}
+
+// GetPracticeQueue returns the IDs of the tabs left in the practice queue,
+// the next one first
+func (a *App) GetPracticeQueue() []string {
+	ids, err := a.store.GetPracticeQueue()
+	if err != nil {
+		a.logger.Error("Error getting practice queue: %v", err)
+		return []string{}
+	}
+	return ids
+}
+
+// MarkPracticeDone advances the practice queue past a tab and returns the
+// tabs left
+func (a *App) MarkPracticeDone(id string) ([]string, error) {
+	if err := a.store.MarkPracticeDone(id); err != nil {
+		return nil, err
+	}
+	return a.GetPracticeQueue(), nil
+}
+
+// ClearPracticeQueue empties the practice queue
+func (a *App) ClearPracticeQueue() error {
+	return a.store.ClearPracticeQueue()
+}
+
+// GetRandomTabFromCategory returns a random tab of a category (any tab if
+// categoryID is empty), or nil if there is none
+func (a *App) GetRandomTabFromCategory(categoryID string) (*store.Tab, error) {
+	ids, err := a.store.RandomTabIDs(store.PracticeFilter{CategoryID: categoryID}, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return a.store.GetTab(ids[0])
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"haya-tab/pkg/store"
+	"strings"
+	"time"
+
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// defaultSnoozeMinutes is how long SnoozeReminder delays a reminder when
+// no duration is given
+const defaultSnoozeMinutes = 10
+
+// GetReminders returns the practice reminders, the next due first
+func (a *App) GetReminders() []store.Reminder {
+	reminders, err := a.store.GetReminders()
+	if err != nil {
+		a.logger.Error("Error getting reminders: %v", err)
+		return []store.Reminder{}
+	}
+	return reminders
+}
+
+// SaveReminder schedules a tab or a category for practice at r.Time, on the
+// days of the week in r.Weekdays or once on r.Date, or updates r.ID if it
+// is not 0. The next notification is computed from now.
+func (a *App) SaveReminder(r store.Reminder) (store.Reminder, error) {
+	if (r.TabID == "") == (r.CategoryID == "") {
+		return store.Reminder{}, fmt.Errorf("a reminder needs either a tab or a category")
+	}
+	if r.TabID != "" {
+		tab, err := a.store.GetTab(r.TabID)
+		if err != nil {
+			return store.Reminder{}, fmt.Errorf("failed to get tab: %w", err)
+		}
+		if tab == nil {
+			return store.Reminder{}, fmt.Errorf("tab not found: %s", r.TabID)
+		}
+		r.Name = tab.Title
+	} else {
+		categories, err := a.store.GetCategories()
+		if err != nil {
+			return store.Reminder{}, fmt.Errorf("failed to get categories: %w", err)
+		}
+		r.Name = ""
+		for _, c := range categories {
+			if c.ID == r.CategoryID {
+				r.Name = c.Name
+			}
+		}
+		if r.Name == "" {
+			return store.Reminder{}, fmt.Errorf("category not found: %s", r.CategoryID)
+		}
+	}
+	for _, d := range r.Weekdays {
+		if d < 0 || d > 6 {
+			return store.Reminder{}, fmt.Errorf("invalid weekday: %d", d)
+		}
+	}
+	if len(r.Weekdays) > 0 {
+		r.Date = ""
+	}
+	r.Note = strings.TrimSpace(r.Note)
+
+	next, err := nextReminderTime(r, time.Now())
+	if err != nil {
+		return store.Reminder{}, err
+	}
+	if next == 0 {
+		return store.Reminder{}, fmt.Errorf("the reminder time has passed")
+	}
+	r.NextAt = next
+	return a.store.SaveReminder(r)
+}
+
+// DeleteReminder removes a reminder
+func (a *App) DeleteReminder(id int64) error {
+	return a.store.DeleteReminder(id)
+}
+
+// SnoozeReminder notifies a reminder again in minutes, 10 if minutes is 0
+func (a *App) SnoozeReminder(id int64, minutes int) error {
+	if minutes <= 0 {
+		minutes = defaultSnoozeMinutes
+	}
+	return a.store.SetReminderNextAt(id, time.Now().Add(time.Duration(minutes)*time.Minute).Unix())
+}
+
+// DismissReminder acknowledges a notification: a one-time reminder is
+// removed, a repeating one waits for its next day, even if it was snoozed
+func (a *App) DismissReminder(id int64) error {
+	r, err := a.store.GetReminder(id)
+	if err != nil {
+		return fmt.Errorf("failed to get reminder: %w", err)
+	}
+	if r == nil {
+		return fmt.Errorf("reminder not found: %d", id)
+	}
+	if len(r.Weekdays) == 0 {
+		return a.store.DeleteReminder(id)
+	}
+	next, err := nextReminderTime(*r, time.Now())
+	if err != nil {
+		return err
+	}
+	return a.store.SetReminderNextAt(id, next)
+}
+
+// runReminders emits "reminder-due" for each reminder whose time has come,
+// including those missed while the app was closed, and schedules its next
+// notification. The frontend shows it as a desktop notification.
+func (a *App) runReminders() {
+	now := time.Now()
+	due, err := a.store.GetDueReminders(now.Unix())
+	if err != nil {
+		a.logger.Error("Failed to get due reminders: %v", err)
+		return
+	}
+	for _, r := range due {
+		next, err := nextReminderTime(r, now)
+		if err != nil {
+			a.logger.Error("Invalid reminder %d: %v", r.ID, err)
+			next = 0
+		}
+		if err := a.store.SetReminderNextAt(r.ID, next); err != nil {
+			a.logger.Error("Failed to reschedule reminder %d: %v", r.ID, err)
+			continue
+		}
+		wailsRuntime.EventsEmit(a.ctx, "reminder-due", r)
+	}
+}
+
+// nextReminderTime returns the first time of r after now as a Unix
+// timestamp, or 0 if a one-time reminder has passed
+func nextReminderTime(r store.Reminder, now time.Time) (int64, error) {
+	clock, err := time.ParseInLocation("15:04", r.Time, time.Local)
+	if err != nil {
+		return 0, fmt.Errorf("invalid reminder time: %q", r.Time)
+	}
+	at := func(day time.Time) time.Time {
+		return time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), 0, 0, time.Local)
+	}
+
+	if len(r.Weekdays) == 0 {
+		day, err := time.ParseInLocation("2006-01-02", r.Date, time.Local)
+		if err != nil {
+			return 0, fmt.Errorf("invalid reminder date: %q", r.Date)
+		}
+		if t := at(day); t.After(now) {
+			return t.Unix(), nil
+		}
+		return 0, nil
+	}
+
+	days := make(map[time.Weekday]bool, len(r.Weekdays))
+	for _, d := range r.Weekdays {
+		days[time.Weekday(d)] = true
+	}
+	// Today may still be ahead, and a week later is the same weekday
+	for i := 0; i <= 7; i++ {
+		t := at(now.AddDate(0, 0, i))
+		if days[t.Weekday()] && t.After(now) {
+			return t.Unix(), nil
+		}
+	}
+	return 0, nil
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ServerMetrics summarizes the traffic of the file server since startup
+type ServerMetrics struct {
+	Requests      int64  `json:"requests"`
+	BytesServed   int64  `json:"bytesServed"`
+	ServedSize    string `json:"servedSize"`    // BytesServed formatted for the locale
+	ActiveStreams int64  `json:"activeStreams"` // Tab files and attachments being sent right now
+	Errors        int64  `json:"errors"`        // Responses with a 4xx or 5xx status
+	Since         int64  `json:"since"`         // Unix timestamp the counters started at
+	SinceDate     string `json:"sinceDate"`     // Since formatted for the locale
+}
+
+// serverMetrics holds the file server counters. The zero value is ready to use.
+type serverMetrics struct {
+	requests      atomic.Int64
+	bytesServed   atomic.Int64
+	activeStreams atomic.Int64
+	errors        atomic.Int64
+	since         atomic.Int64
+}
+
+// accessLogEntry is one line of the access log
+type accessLogEntry struct {
+	Time       string `json:"time"`
+	Method     string `json:"method"`
+	Path       string `json:"path"`
+	TabID      string `json:"tabId,omitempty"`
+	Status     int    `json:"status"`
+	Bytes      int64  `json:"bytes"`
+	DurationMs int64  `json:"durationMs"`
+	Client     string `json:"client"`
+}
+
+// accessLog appends JSON lines to logs/access-YYYY-MM-DD.log, opening the
+// file on first use and switching files when the day changes
+type accessLog struct {
+	enabled atomic.Bool // Mirrors Settings.AccessLogEnabled
+
+	mu   sync.Mutex
+	file *os.File
+	date string
+}
+
+// countingWriter records the status and size of a response
+type countingWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *countingWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// GetServerMetrics returns the request counters of the file server
+func (a *App) GetServerMetrics() ServerMetrics {
+	m := &a.serverMetrics
+	f := a.formatter()
+	metrics := ServerMetrics{
+		Requests:      m.requests.Load(),
+		BytesServed:   m.bytesServed.Load(),
+		ActiveStreams: m.activeStreams.Load(),
+		Errors:        m.errors.Load(),
+		Since:         m.since.Load(),
+	}
+	metrics.ServedSize = f.Bytes(metrics.BytesServed)
+	metrics.SinceDate = f.DateTime(metrics.Since)
+	return metrics
+}
+
+// ResetServerMetrics sets the file server counters back to zero. Streams in
+// progress are still counted as active.
+func (a *App) ResetServerMetrics() {
+	m := &a.serverMetrics
+	m.requests.Store(0)
+	m.bytesServed.Store(0)
+	m.errors.Store(0)
+	m.since.Store(time.Now().Unix())
+}
+
+// applyAccessLog turns the access log on or off according to the settings
+func (a *App) applyAccessLog() {
+	a.accessLog.enabled.Store(a.store.GetSettings().AccessLogEnabled)
+}
+
+// serveWithMetrics runs serve, counting the request in the server metrics
+// and writing it to the access log when enabled in the settings
+func (a *App) serveWithMetrics(w http.ResponseWriter, r *http.Request, serve func(w http.ResponseWriter)) {
+	m := &a.serverMetrics
+	m.since.CompareAndSwap(0, time.Now().Unix())
+
+	path := r.URL.Path
+	stream := strings.HasPrefix(path, "/api/file/") || strings.HasPrefix(path, "/api/attachment/")
+	if stream {
+		m.activeStreams.Add(1)
+		defer m.activeStreams.Add(-1)
+	}
+
+	start := time.Now()
+	cw := &countingWriter{ResponseWriter: w}
+	serve(cw)
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+
+	m.requests.Add(1)
+	m.bytesServed.Add(cw.bytes)
+	if cw.status >= 400 {
+		m.errors.Add(1)
+	}
+
+	if !a.accessLog.enabled.Load() {
+		return
+	}
+	err := a.accessLog.write(accessLogEntry{
+		Time:       start.Format(time.RFC3339),
+		Method:     r.Method,
+		Path:       path,
+		TabID:      a.requestTabID(path),
+		Status:     cw.status,
+		Bytes:      cw.bytes,
+		DurationMs: time.Since(start).Milliseconds(),
+		Client:     r.RemoteAddr,
+	})
+	if err != nil {
+		a.logger.Info("Failed to write access log: %v", err)
+	}
+}
+
+// requestTabID returns the tab ID of a /api/{kind}/{id} request, or "".
+// Attachments are looked up to find the tab they belong to.
+func (a *App) requestTabID(path string) string {
+	for _, prefix := range []string{"/api/file/", "/api/cover/", "/api/summary/"} {
+		if strings.HasPrefix(path, prefix) {
+			return strings.TrimPrefix(path, prefix)
+		}
+	}
+	if idStr, ok := strings.CutPrefix(path, "/api/attachment/"); ok {
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			return ""
+		}
+		if attachment, err := a.store.GetAttachment(id); err == nil && attachment != nil {
+			return attachment.TabID
+		}
+	}
+	return ""
+}
+
+func (l *accessLog) write(entry accessLogEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	date := time.Now().Format("2006-01-02")
+	if l.file == nil || l.date != date {
+		if l.file != nil {
+			l.file.Close()
+			l.file = nil
+		}
+		logDir := filepath.Join(getAppDir(), "logs")
+		if err := os.MkdirAll(logDir, 0755); err != nil {
+			return fmt.Errorf("failed to create log directory: %w", err)
+		}
+		file, err := os.OpenFile(filepath.Join(logDir, fmt.Sprintf("access-%s.log", date)), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open log file: %w", err)
+		}
+		l.file = file
+		l.date = date
+	}
+	_, err = l.file.Write(append(data, '\n'))
+	return err
+}
+
+func (l *accessLog) Close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file != nil {
+		l.file.Close()
+		l.file = nil
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"errors"
+	"haya-tab/pkg/store"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// sharedTabPrefix starts the IDs of the tabs of the shared library, which
+// could otherwise be those of local tabs
+const sharedTabPrefix = "shared:"
+
+// errSharedReadOnly is returned when changing a tab of the shared library
+var errSharedReadOnly = errors.New("tabs of the shared library are read-only")
+
+// sharedLibrary is a library attached read-only, see applySharedLibrary
+type sharedLibrary struct {
+	store *store.DBStore
+	path  string // Settings.SharedLibraryPath it was opened from
+	root  string // Library folder holding data/, storage/ and covers/
+	err   error  // Of the last attach
+}
+
+// SharedLibraryStatus is the state of the library attached read-only
+type SharedLibraryStatus struct {
+	Attached bool   `json:"attached"`
+	Path     string `json:"path"`
+	Tabs     int    `json:"tabs"`
+	Error    string `json:"error,omitempty"`
+}
+
+// GetSharedLibraryStatus returns whether the shared library of the
+// settings is attached, and its number of tabs
+func (a *App) GetSharedLibraryStatus() SharedLibraryStatus {
+	a.sharedMu.RLock()
+	defer a.sharedMu.RUnlock()
+
+	status := SharedLibraryStatus{Attached: a.shared.store != nil, Path: a.shared.path}
+	if a.shared.err != nil {
+		status.Error = a.shared.err.Error()
+	}
+	if a.shared.store != nil {
+		if _, total, err := a.shared.store.GetTabsPaginated("", 1, 1, "", nil, true, "title", false, store.TabFilters{}); err == nil {
+			status.Tabs = total
+		}
+	}
+	return status
+}
+
+// applySharedLibrary attaches the library of Settings.SharedLibraryPath
+// read-only, e.g. one a band leader keeps on a shared drive, or detaches it
+// when the setting is cleared or changed. The path is the folder of the
+// library or its database file.
+func (a *App) applySharedLibrary() {
+	path := strings.TrimSpace(a.store.GetSettings().SharedLibraryPath)
+
+	a.sharedMu.Lock()
+	defer a.sharedMu.Unlock()
+	if path == a.shared.path && (a.shared.store != nil || path == "") {
+		return
+	}
+	if a.shared.store != nil {
+		a.shared.store.Close()
+		a.logger.Info("Detached the shared library %s", a.shared.path)
+	}
+	a.shared = sharedLibrary{path: path}
+	if path == "" {
+		return
+	}
+
+	dbPath := path
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		dbPath = filepath.Join(path, "data", "haya-tab.db")
+	}
+	s, err := store.OpenReadOnly(dbPath)
+	if err != nil {
+		a.shared.err = err
+		a.logger.Error("Failed to attach the shared library %s: %v", path, err)
+		return
+	}
+	a.shared.store = s
+	a.shared.root = filepath.Dir(filepath.Dir(dbPath))
+	a.logger.Info("Attached the shared library %s read-only", path)
+}
+
+// closeSharedLibrary detaches the shared library on shutdown
+func (a *App) closeSharedLibrary() {
+	a.sharedMu.Lock()
+	defer a.sharedMu.Unlock()
+	if a.shared.store != nil {
+		a.shared.store.Close()
+	}
+	a.shared = sharedLibrary{}
+}
+
+// isSharedTab reports whether id is the ID of a tab of the shared library
+func isSharedTab(id string) bool {
+	return strings.HasPrefix(id, sharedTabPrefix)
+}
+
+// findTab returns the tab id of the library or, for the IDs starting with
+// sharedTabPrefix, of the shared library. Returns nil if there is no such
+// tab.
+func (a *App) findTab(id string) (*store.Tab, error) {
+	sharedID, ok := strings.CutPrefix(id, sharedTabPrefix)
+	if !ok {
+		return a.store.GetTab(id)
+	}
+
+	a.sharedMu.RLock()
+	defer a.sharedMu.RUnlock()
+	if a.shared.store == nil {
+		return nil, nil
+	}
+	tab, err := a.shared.store.GetTab(sharedID)
+	if err != nil || tab == nil {
+		return nil, err
+	}
+	tabs := a.asSharedTabs([]store.Tab{*tab})
+	return &tabs[0], nil
+}
+
+// getTabsPage is store.GetTabsPaginated with the tabs of the shared library
+// merged in, except inside a category: categories are those of this
+// library. Both libraries are read from their first tab to the end of the
+// page, so later pages cost more.
+func (a *App) getTabsPage(categoryId string, page, pageSize int, searchQuery string, filterBy []string, isGlobal bool, sortBy string, sortDesc bool, filters store.TabFilters) ([]store.Tab, int, error) {
+	a.sharedMu.RLock()
+	defer a.sharedMu.RUnlock()
+	if a.shared.store == nil || (categoryId != "" && !isGlobal) {
+		return a.store.GetTabsPaginated(categoryId, page, pageSize, searchQuery, filterBy, isGlobal, sortBy, sortDesc, filters)
+	}
+
+	tabs, total, err := a.store.GetTabsPaginated(categoryId, 1, page*pageSize, searchQuery, filterBy, isGlobal, sortBy, sortDesc, filters)
+	if err != nil {
+		return nil, 0, err
+	}
+	shared, sharedTotal, err := a.shared.store.GetTabsPaginated("", 1, page*pageSize, searchQuery, filterBy, true, sortBy, sortDesc, filters)
+	if err != nil {
+		// The shared drive may be unreachable; the local tabs are still shown
+		a.logger.Error("Error reading the shared library: %v", err)
+		shared, sharedTotal = nil, 0
+	}
+
+	tabs = append(tabs, a.asSharedTabs(shared)...)
+	slices.SortStableFunc(tabs, func(x, y store.Tab) int {
+		return store.CompareTabs(x, y, sortBy, sortDesc)
+	})
+	start := min((page-1)*pageSize, len(tabs))
+	end := min(start+pageSize, len(tabs))
+	return tabs[start:end], total + sharedTotal, nil
+}
+
+// asSharedTabs marks tabs of the shared library as such: their IDs get
+// sharedTabPrefix, they are in no local category, and their files and
+// covers are found under the library folder as seen from this machine.
+// Must be called with sharedMu held.
+func (a *App) asSharedTabs(tabs []store.Tab) []store.Tab {
+	for i := range tabs {
+		t := &tabs[i]
+		t.ID = sharedTabPrefix + t.ID
+		t.Shared = true
+		t.IsManaged = false
+		t.CategoryIDs = []string{}
+		t.FilePath = sharedFilePath(t.FilePath, a.shared.root, "storage")
+		if t.CoverPath != "" {
+			t.CoverPath = sharedFilePath(t.CoverPath, a.shared.root, "covers")
+		}
+	}
+	return tabs
+}
+
+// sharedFilePath returns path as seen from this machine. Relative paths
+// are relative to the library folder root. Absolute paths in its dir
+// subfolder are those of the machine that keeps the library, e.g.
+// "D:\Band\storage\x.pdf", and are looked up under root instead. Other
+// paths are kept as they are.
+func sharedFilePath(path, root, dir string) string {
+	if !filepath.IsAbs(path) && !strings.Contains(path, ":") {
+		return filepath.Join(root, path)
+	}
+	if _, err := os.Stat(path); err == nil {
+		return path
+	}
+	// The path may use the separators of another OS
+	slashed := strings.ReplaceAll(path, "\\", "/")
+	i := strings.LastIndex(slashed, "/"+dir+"/")
+	if i < 0 {
+		return path
+	}
+	local := filepath.Join(root, dir, filepath.FromSlash(slashed[i+len(dir)+2:]))
+	if _, err := os.Stat(local); err != nil {
+		return path
+	}
+	return local
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"haya-tab/pkg/store"
+	"haya-tab/pkg/testutil"
+	"slices"
+	"testing"
+)
+
+// addTabs adds a tab of each title to s, with IDs starting with prefix
+func addTabs(t *testing.T, s *store.DBStore, prefix string, titles ...string) {
+	t.Helper()
+	for i, title := range titles {
+		tab := store.Tab{ID: fmt.Sprintf("%s%d", prefix, i), Title: title, Artist: "Band", Type: "text", FilePath: title + ".txt", CategoryIDs: []string{}}
+		if err := s.AddTab(tab); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSharedSearchOrder(t *testing.T) {
+	// Both libraries hold a match and the same other tabs, so that the
+	// matches are ranked alike
+	local, shared := testutil.NewStore(t), testutil.NewStore(t)
+	addTabs(t, local, "local", "A long ballad about the river and the sea", "Blue", "Green")
+	addTabs(t, shared, "shared", "River", "Blue", "Green")
+	a := &App{store: local, shared: sharedLibrary{store: shared, root: t.TempDir()}}
+
+	tabs, total, err := a.getTabsPage("", 1, 10, "river", []string{"title", "artist"}, true, "", false, store.TabFilters{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var titles []string
+	for _, tab := range tabs {
+		titles = append(titles, tab.Title)
+	}
+	// The shorter title is the better match
+	want := []string{"River", "A long ballad about the river and the sea"}
+	if total != 2 || !slices.Equal(titles, want) {
+		t.Errorf("searching both libraries = %q of %d, want %q", titles, total, want)
+	}
+
+	// A sort key still wins over relevance
+	tabs, _, err = a.getTabsPage("", 1, 10, "river", []string{"title", "artist"}, true, "title", false, store.TabFilters{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tabs) != 2 || tabs[0].Title != want[1] || !tabs[1].Shared {
+		t.Errorf("sorting by title = %+v, want the local tab first", tabs)
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"haya-tab/pkg/storage"
+	"haya-tab/pkg/store"
+	syncpkg "haya-tab/pkg/sync"
+	"os"
+	"path/filepath"
+	"strings"
+
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// StorageStats summarizes where the files of the library are stored
+type StorageStats struct {
+	ManagedCount int    `json:"managedCount"` // Files copied into app storage
+	ManagedBytes int64  `json:"managedBytes"`
+	ManagedSize  string `json:"managedSize"` // ManagedBytes formatted for the locale, e.g. "1.5 MB"
+	LinkedCount  int    `json:"linkedCount"` // Files left in place
+	LinkedBytes  int64  `json:"linkedBytes"`
+	LinkedSize   string `json:"linkedSize"`
+	MissingCount int    `json:"missingCount"` // Files that could not be found
+}
+
+// GetStorageStats returns the number and size of managed and linked files
+func (a *App) GetStorageStats() StorageStats {
+	stats := StorageStats{}
+	tabs, err := a.store.GetTabs()
+	if err != nil {
+		a.logger.Error("Error getting tabs: %v", err)
+		return stats
+	}
+
+	for _, tab := range tabs {
+		info, err := os.Stat(tab.FilePath)
+		if err != nil {
+			stats.MissingCount++
+			continue
+		}
+		if tab.IsManaged {
+			stats.ManagedCount++
+			stats.ManagedBytes += info.Size()
+		} else {
+			stats.LinkedCount++
+			stats.LinkedBytes += info.Size()
+		}
+	}
+	stats.ManagedSize = a.formatter().Bytes(stats.ManagedBytes)
+	stats.LinkedSize = a.formatter().Bytes(stats.LinkedBytes)
+	return stats
+}
+
+// errSkipConvert marks a tab left in its current mode on purpose
+var errSkipConvert = errors.New("skipped")
+
+// ConvertToManaged copies the files of linked tabs into app storage. The
+// original files are left untouched. Tabs inside a sync path are skipped,
+// since the next sync would import their original file again. Emits
+// "storage-convert-progress" per tab and "storage-convert-completed" at the
+// end. Returns the number of converted tabs.
+func (a *App) ConvertToManaged(ids []string) (int, error) {
+	syncPaths := a.syncPathList()
+	return a.convertStorage(ids, true, func(tab store.Tab) error {
+		if inSyncPath(tab.FilePath, syncPaths) {
+			return errSkipConvert
+		}
+		locator, _, err := a.storage.Import(tab.FilePath, tab.ID+filepath.Ext(tab.FilePath))
+		if err != nil {
+			return err
+		}
+		if err := a.store.SetTabStorage(tab.ID, locator, true); err != nil {
+			a.storage.Remove(locator)
+			return err
+		}
+		return nil
+	})
+}
+
+// ConvertToLinked exports the files of managed tabs to destFolder, re-links
+// the tabs to the exported files and removes them from app storage.
+// Emits the same events as ConvertToManaged.
+func (a *App) ConvertToLinked(ids []string, destFolder string) (int, error) {
+	if info, err := os.Stat(destFolder); err != nil || !info.IsDir() {
+		return 0, fmt.Errorf("destination folder not found: %s", destFolder)
+	}
+
+	return a.convertStorage(ids, false, func(tab store.Tab) error {
+		// Managed files are named after the tab ID; use the title instead
+		name := sanitizeFileName(tab.Title)
+		if name == "" {
+			name = tab.ID
+		}
+		destPath := uniquePath(destFolder, name, filepath.Ext(tab.FilePath))
+		if err := a.storage.Export(tab.FilePath, destPath); err != nil {
+			return err
+		}
+		if err := a.store.SetTabStorage(tab.ID, destPath, false); err != nil {
+			os.Remove(destPath)
+			return err
+		}
+		if err := a.storage.Remove(tab.FilePath); err != nil {
+			a.logger.Info("Failed to remove managed file %s: %v", tab.FilePath, err)
+		}
+		return nil
+	})
+}
+
+// convertStorage runs convert on each tab not yet in the target mode,
+// reporting progress to the frontend. The returned error joins the errors
+// of all tabs that failed.
+func (a *App) convertStorage(ids []string, toManaged bool, convert func(tab store.Tab) error) (int, error) {
+	mode := "linked"
+	if toManaged {
+		mode = "managed"
+	}
+
+	converted, skipped := 0, 0
+	var errs []error
+	for i, id := range ids {
+		tab, err := a.store.GetTab(id)
+		if err != nil || tab == nil {
+			errs = append(errs, fmt.Errorf("tab not found: %s", id))
+			continue
+		}
+
+		if tab.IsManaged == toManaged {
+			// Already in the target mode
+		} else if err := convert(*tab); errors.Is(err, errSkipConvert) {
+			skipped++
+		} else if err != nil {
+			a.logger.Info("Failed to convert %s to %s: %v", tab.Title, mode, err)
+			errs = append(errs, fmt.Errorf("%s: %w", tab.Title, err))
+		} else {
+			converted++
+		}
+
+		wailsRuntime.EventsEmit(a.ctx, "storage-convert-progress", map[string]interface{}{
+			"mode":  mode,
+			"done":  i + 1,
+			"total": len(ids),
+			"title": tab.Title,
+		})
+	}
+
+	a.logger.Info("Converted %d tab(s) to %s (%d skipped, %d failed)", converted, mode, skipped, len(errs))
+	wailsRuntime.EventsEmit(a.ctx, "storage-convert-completed", map[string]interface{}{
+		"mode":      mode,
+		"converted": converted,
+		"skipped":   skipped,
+		"failed":    len(errs),
+	})
+	return converted, errors.Join(errs...)
+}
+
+// openTabFile opens the file of a tab, from app storage if it is managed
+func (a *App) openTabFile(tab store.Tab) (storage.File, error) {
+	if tab.IsManaged {
+		return a.storage.Open(tab.FilePath)
+	}
+	return os.Open(tab.FilePath)
+}
+
+// exportTabFile copies the file of a tab to the local path dst
+func (a *App) exportTabFile(tab store.Tab, dst string) error {
+	if tab.IsManaged {
+		return a.storage.Export(tab.FilePath, dst)
+	}
+	return copyFile(tab.FilePath, dst)
+}
+
+// removeManagedFiles deletes the file and the cover of a managed tab, which
+// belong to it alone. Failures are logged.
+func (a *App) removeManagedFiles(tab store.Tab) {
+	if !tab.IsManaged {
+		return
+	}
+	if err := a.storage.Remove(tab.FilePath); err != nil {
+		a.logger.Error("Warning: Failed to delete managed file %s: %v", tab.FilePath, err)
+	}
+	if tab.CoverPath != "" {
+		os.Remove(tab.CoverPath)
+	}
+}
+
+// inSyncPath reports whether path is inside one of the sync paths
+func inSyncPath(path string, syncPaths []string) bool {
+	for _, root := range syncPaths {
+		rel, err := filepath.Rel(root, path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// copyFile copies src to dst, leaving no partial dst if the copy fails or
+// is interrupted (see syncpkg.CopyFile)
+func copyFile(src, dst string) error {
+	_, err := syncpkg.CopyFile(src, dst)
+	return err
+}
+
+// sanitizeFileName replaces the characters that are invalid in file names
+// on Windows (and "/" everywhere) with "-"
+func sanitizeFileName(name string) string {
+	return strings.NewReplacer(
+		"/", "-", "\\", "-", ":", "-", "*", "-", "?", "-",
+		"\"", "-", "<", "-", ">", "-", "|", "-",
+	).Replace(strings.TrimSpace(name))
+}
+
+// uniquePath returns dir/name+ext, adding " (2)", " (3)", ... to the name
+// if the file already exists
+func uniquePath(dir, name, ext string) string {
+	path := filepath.Join(dir, name+ext)
+	for n := 2; ; n++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return path
+		}
+		path = filepath.Join(dir, fmt.Sprintf("%s (%d)%s", name, n, ext))
+	}
+}
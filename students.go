@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"haya-tab/pkg/store"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// assignmentStatuses are the valid values of Assignment.Status
+var assignmentStatuses = map[string]bool{
+	"assigned":   true,
+	"practicing": true,
+	"done":       true,
+}
+
+// GetStudents returns the students by name
+func (a *App) GetStudents() []store.Student {
+	students, err := a.store.GetStudents()
+	if err != nil {
+		a.logger.Error("Error getting students: %v", err)
+		return []store.Student{}
+	}
+	return students
+}
+
+// SaveStudent adds a student, or updates student.ID if it is not 0
+func (a *App) SaveStudent(student store.Student) (store.Student, error) {
+	student.Name = strings.TrimSpace(student.Name)
+	if student.Name == "" {
+		return store.Student{}, fmt.Errorf("student name is required")
+	}
+	student.Notes = strings.TrimSpace(student.Notes)
+	return a.store.SaveStudent(student)
+}
+
+// DeleteStudent removes a student and its assignments. The assigned tabs
+// stay in the library.
+func (a *App) DeleteStudent(id int64) error {
+	return a.store.DeleteStudent(id)
+}
+
+// GetStudentAssignments returns the tabs assigned to a student, the next
+// due first
+func (a *App) GetStudentAssignments(studentID int64) []store.Assignment {
+	assignments, err := a.store.GetAssignments(studentID)
+	if err != nil {
+		a.logger.Error("Error getting assignments: %v", err)
+		return []store.Assignment{}
+	}
+	return assignments
+}
+
+// AssignTab assigns a tab to a student, with an optional due date (Unix
+// timestamp, 0 for none) and instructions. Assigning a tab again updates
+// the due date and instructions.
+func (a *App) AssignTab(studentID int64, tabID string, dueAt int64, note string) (store.Assignment, error) {
+	student, err := a.store.GetStudent(studentID)
+	if err != nil {
+		return store.Assignment{}, fmt.Errorf("failed to get student: %w", err)
+	}
+	if student == nil {
+		return store.Assignment{}, fmt.Errorf("student not found: %d", studentID)
+	}
+	tab, err := a.store.GetTab(tabID)
+	if err != nil {
+		return store.Assignment{}, fmt.Errorf("failed to get tab: %w", err)
+	}
+	if tab == nil {
+		return store.Assignment{}, fmt.Errorf("tab not found: %s", tabID)
+	}
+	if dueAt < 0 {
+		return store.Assignment{}, fmt.Errorf("invalid due date")
+	}
+
+	assignment, err := a.store.SaveAssignment(store.Assignment{
+		StudentID:  studentID,
+		TabID:      tabID,
+		DueAt:      dueAt,
+		Note:       strings.TrimSpace(note),
+		AssignedAt: time.Now().Unix(),
+	})
+	if err != nil {
+		return store.Assignment{}, fmt.Errorf("failed to assign tab: %w", err)
+	}
+	assignment.TabTitle = tab.Title
+	assignment.TabArtist = tab.Artist
+	return assignment, nil
+}
+
+// SetAssignmentStatus sets the status of an assignment ("assigned",
+// "practicing" or "done")
+func (a *App) SetAssignmentStatus(id int64, status string) error {
+	if !assignmentStatuses[status] {
+		return fmt.Errorf("invalid assignment status: %s", status)
+	}
+	return a.store.SetAssignmentStatus(id, status)
+}
+
+// RemoveAssignment removes a tab from the assignments of a student
+func (a *App) RemoveAssignment(id int64) error {
+	return a.store.DeleteAssignment(id)
+}
+
+// ExportStudentPacket copies the files of a student's open assignments to
+// a new folder in destFolder, named after the student, with an
+// Assignments.txt listing each tab with its due date, instructions and
+// notes. Returns the path of the folder.
+func (a *App) ExportStudentPacket(studentID int64, destFolder string) (string, error) {
+	student, err := a.store.GetStudent(studentID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get student: %w", err)
+	}
+	if student == nil {
+		return "", fmt.Errorf("student not found: %d", studentID)
+	}
+	assignments, err := a.store.GetAssignments(studentID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get assignments: %w", err)
+	}
+
+	var open []store.Assignment
+	for _, as := range assignments {
+		if as.Status != "done" {
+			open = append(open, as)
+		}
+	}
+	if len(open) == 0 {
+		return "", fmt.Errorf("%s has no open assignments", student.Name)
+	}
+
+	folder := uniquePath(destFolder, sanitizeFileName(student.Name), "")
+	if err := os.MkdirAll(folder, 0755); err != nil {
+		return "", fmt.Errorf("failed to create folder: %w", err)
+	}
+
+	f := a.formatter()
+	var sheet strings.Builder
+	fmt.Fprintf(&sheet, "Assignments for %s\n%s\n", student.Name, f.Date(time.Now().Unix()))
+	for i, as := range open {
+		tab, err := a.store.GetTab(as.TabID)
+		if err != nil || tab == nil {
+			return "", fmt.Errorf("failed to get tab %s: %v", as.TabTitle, err)
+		}
+
+		ext := filepath.Ext(tab.FilePath)
+		destPath := uniquePath(folder, sanitizeFileName(tab.Title), ext)
+		if err := copyFile(tab.FilePath, destPath); err != nil {
+			return "", fmt.Errorf("failed to copy %s: %w", tab.Title, err)
+		}
+		if err := a.exportTabLinks(tab.ID, destPath); err != nil {
+			a.logger.Info("Failed to export links of %s: %v", tab.Title, err)
+		}
+
+		fmt.Fprintf(&sheet, "\n%d. %s", i+1, tab.Title)
+		if tab.Artist != "" {
+			fmt.Fprintf(&sheet, " - %s", tab.Artist)
+		}
+		fmt.Fprintf(&sheet, "\n   File: %s\n", filepath.Base(destPath))
+		if as.DueAt != 0 {
+			fmt.Fprintf(&sheet, "   Due: %s\n", f.Date(as.DueAt))
+		}
+		if as.Note != "" {
+			fmt.Fprintf(&sheet, "   Instructions: %s\n", indentLines(as.Note, "                 "))
+		}
+		notes, err := a.store.GetTabNotes(tab.ID)
+		if err != nil {
+			a.logger.Info("Failed to get notes of %s: %v", tab.Title, err)
+		}
+		for _, n := range notes {
+			fmt.Fprintf(&sheet, "   Note: %s\n", indentLines(n.Content, "         "))
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(folder, "Assignments.txt"), []byte(sheet.String()), 0644); err != nil {
+		return "", fmt.Errorf("failed to write assignment sheet: %w", err)
+	}
+	a.logger.Info("Exported %d assignment(s) of %s to %s", len(open), student.Name, folder)
+	return folder, nil
+}
+
+// indentLines indents every line of text but the first with prefix
+func indentLines(text, prefix string) string {
+	return strings.ReplaceAll(strings.TrimSpace(text), "\n", "\n"+prefix)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"haya-tab/pkg/remotefs"
+	"haya-tab/pkg/store"
+	"haya-tab/pkg/watcher"
+	"path/filepath"
+	"strings"
+)
+
+// GetSyncPaths returns the sync paths with their options
+func (a *App) GetSyncPaths() ([]store.SyncPath, error) {
+	return a.store.GetSyncPaths()
+}
+
+// AddSyncPath adds a folder to sync, scanned recursively and linked, and
+// starts watching it. The folder may be the URL of a network share (see
+// remotefs), whose files are copied to app storage instead.
+func (a *App) AddSyncPath(path string) (store.SyncPath, error) {
+	if path == "" {
+		return store.SyncPath{}, fmt.Errorf("empty sync path")
+	}
+	p := store.SyncPath{
+		Path:      filepath.Clean(path),
+		Recursive: true,
+		Mode:      store.SyncModeLink,
+		Include:   []string{},
+		Exclude:   []string{},
+	}
+	if remotefs.IsRemote(path) {
+		if _, err := remotefs.Open(path); err != nil {
+			return store.SyncPath{}, err
+		}
+		p.Path = strings.TrimSuffix(path, "/")
+		p.Mode = store.SyncModeCopy
+	}
+	if err := a.store.AddSyncPath(p); err != nil {
+		return store.SyncPath{}, err
+	}
+	a.applySyncPaths()
+	return p, nil
+}
+
+// UpdateSyncPath changes the options of a sync path. They apply to files
+// found from then on; tabs already added are left as they are.
+func (a *App) UpdateSyncPath(p store.SyncPath) error {
+	if remotefs.IsRemote(p.Path) && p.Mode != store.SyncModeCopy {
+		return fmt.Errorf("files of network shares can only be copied")
+	}
+	if err := a.store.UpdateSyncPath(p); err != nil {
+		return err
+	}
+	a.applySyncPaths()
+	return nil
+}
+
+// RemoveSyncPath stops syncing a folder. Its tabs are kept.
+func (a *App) RemoveSyncPath(path string) error {
+	if err := a.store.RemoveSyncPath(path); err != nil {
+		return err
+	}
+	a.applySyncPaths()
+	return nil
+}
+
+// syncPathList returns the local folders of the sync paths
+func (a *App) syncPathList() []string {
+	paths, err := a.store.GetSyncPaths()
+	if err != nil {
+		a.logger.Info("Failed to read sync paths: %v", err)
+	}
+	return syncPathFolders(paths)
+}
+
+// syncPathFolders returns the local folders of paths, leaving out network
+// shares
+func syncPathFolders(paths []store.SyncPath) []string {
+	list := make([]string, 0, len(paths))
+	for _, p := range paths {
+		if !remotefs.IsRemote(p.Path) {
+			list = append(list, p.Path)
+		}
+	}
+	return list
+}
+
+// applySyncPaths watches the sync paths for changes, starting the file
+// watcher for the first one and stopping it after the last one is removed.
+// Files excluded by their sync path are ignored.
+func (a *App) applySyncPaths() {
+	syncPaths, err := a.store.GetSyncPaths()
+	if err != nil {
+		a.logger.Info("Failed to read sync paths: %v", err)
+	}
+	paths := syncPathFolders(syncPaths)
+	if len(paths) == 0 {
+		if a.fileWatcher != nil {
+			a.fileWatcher.Stop()
+			a.fileWatcher = nil
+		}
+		return
+	}
+
+	if a.fileWatcher == nil {
+		a.fileWatcher = watcher.NewFileWatcher(a.handleFileChanges)
+		a.fileWatcher.SetLogger(a.logger)
+		if err := a.fileWatcher.Start(); err != nil {
+			a.logger.Error("Failed to start file watcher: %v", err)
+		}
+	}
+	a.fileWatcher.SetIgnore(func(path string) bool {
+		root := store.FindSyncPath(syncPaths, path)
+		return root != nil && root.Excludes(path)
+	})
+	a.fileWatcher.SetExtensions(a.syncService.ConvertsExtension)
+	if a.fileWatcher.IsRunning() {
+		if err := a.fileWatcher.SetPaths(paths); err != nil {
+			a.logger.Error("Failed to update watcher paths: %v", err)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"haya-tab/pkg/locale"
+	"haya-tab/pkg/store"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// maxPracticeSeconds bounds one recorded practice period, so a viewer left
+// open overnight does not count as a day of practice
+const maxPracticeSeconds = 4 * 60 * 60
+
+// RecordPracticeTime records that a tab was open in a viewer for seconds
+// from startedAt (Unix timestamp). Periods longer than four hours are
+// counted as four hours.
+func (a *App) RecordPracticeTime(tabID string, startedAt int64, seconds int) error {
+	// The shared library is read-only
+	if isSharedTab(tabID) {
+		return nil
+	}
+	if seconds <= 0 || startedAt <= 0 {
+		return fmt.Errorf("invalid practice time: %d seconds at %d", seconds, startedAt)
+	}
+	return a.store.AddPracticeTime(tabID, startedAt, min(seconds, maxPracticeSeconds))
+}
+
+// GetYearReview returns the statistics of a year, or of the current year
+// for 0, computed from the library alone
+func (a *App) GetYearReview(year int) (store.YearReview, error) {
+	if year == 0 {
+		year = time.Now().Year()
+	}
+	return a.store.GetYearReview(year, time.Local)
+}
+
+// ExportYearReview writes the statistics of a year (0 for the current one)
+// to a text file in destFolder. Returns the path of the file.
+func (a *App) ExportYearReview(year int, destFolder string) (string, error) {
+	review, err := a.GetYearReview(year)
+	if err != nil {
+		return "", fmt.Errorf("failed to compute the year review: %w", err)
+	}
+
+	f := a.formatter()
+	var sheet strings.Builder
+	fmt.Fprintf(&sheet, "HAYA-TAB: %d in Review\n%s\n\n", review.Year, f.Date(time.Now().Unix()))
+	fmt.Fprintf(&sheet, "Tabs added: %s\n", f.Number(int64(review.TabsAdded)))
+	fmt.Fprintf(&sheet, "Tabs opened: %s\n", f.Number(int64(review.TabsOpened)))
+	fmt.Fprintf(&sheet, "Practice: %s on %s day(s), %s tab(s)\n",
+		formatPracticeTime(f, review.PracticeSeconds), f.Number(int64(review.PracticeDays)), f.Number(int64(review.TabsPracticed)))
+	fmt.Fprintf(&sheet, "Notes written: %s\n", f.Number(int64(review.NotesWritten)))
+
+	busiest := 0
+	for m, n := range review.MonthlyAdded {
+		if n > review.MonthlyAdded[busiest] {
+			busiest = m
+		}
+	}
+	if review.MonthlyAdded[busiest] > 0 {
+		fmt.Fprintf(&sheet, "Busiest month: %s (%s tab(s) added)\n", time.Month(busiest+1), f.Number(int64(review.MonthlyAdded[busiest])))
+	}
+
+	if len(review.TopArtists) > 0 {
+		sheet.WriteString("\nTop artists\n")
+		for i, ar := range review.TopArtists {
+			fmt.Fprintf(&sheet, "%d. %s: %s practiced, %s tab(s) added\n",
+				i+1, ar.Artist, formatPracticeTime(f, ar.PracticeSeconds), f.Number(int64(ar.TabsAdded)))
+		}
+	}
+	if len(review.TopTabs) > 0 {
+		sheet.WriteString("\nMost practiced tabs\n")
+		for i, t := range review.TopTabs {
+			title := t.Title
+			if t.Artist != "" {
+				title += " - " + t.Artist
+			}
+			fmt.Fprintf(&sheet, "%d. %s: %s\n", i+1, title, formatPracticeTime(f, t.PracticeSeconds))
+		}
+	}
+
+	path := uniquePath(destFolder, fmt.Sprintf("HAYA-TAB %d in Review", review.Year), ".txt")
+	if err := os.WriteFile(path, []byte(sheet.String()), 0644); err != nil {
+		return "", fmt.Errorf("failed to write the year review: %w", err)
+	}
+	a.logger.Info("Exported the %d review to %s", review.Year, filepath.Base(path))
+	return path, nil
+}
+
+// formatPracticeTime formats seconds as hours and minutes, e.g. "12 h 05 min"
+func formatPracticeTime(f *locale.Formatter, seconds int64) string {
+	minutes := seconds / 60
+	if minutes < 60 {
+		return fmt.Sprintf("%d min", minutes)
+	}
+	return fmt.Sprintf("%s h %02d min", f.Number(minutes/60), minutes%60)
+}